@@ -5,6 +5,9 @@
 // Usage:
 //
 //	go run ./cmd/context_demo/
+//
+// Set IRONCLAW_BPE_DIR to a directory holding cl100k_base.tiktoken to run
+// it offline instead of downloading the rank file.
 package main
 
 import (
@@ -22,6 +25,9 @@ import (
 var exitFunc = os.Exit
 
 func main() {
+	if dir := os.Getenv("IRONCLAW_BPE_DIR"); dir != "" {
+		tokenizer.UseBPEDir(dir, true)
+	}
 	if err := runDemo(); err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		exitFunc(1)
@@ -3,6 +3,7 @@ package main
 import (
 	"errors"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -11,6 +12,13 @@ import (
 	"ironclaw/internal/tokenizer"
 )
 
+// TestMain points the tokenizer at the cl100k_base stand-in in the tokenizer
+// package's testdata, so the demo runs without downloading rank files.
+func TestMain(m *testing.M) {
+	tokenizer.UseBPEDir("../../internal/tokenizer/testdata", true)
+	os.Exit(m.Run())
+}
+
 // =============================================================================
 // main / runDemo
 // =============================================================================
@@ -37,6 +45,37 @@ func TestMain_ShouldRunWithoutError(t *testing.T) {
 	}
 }
 
+func TestMain_WhenBPEDirSet_ShouldLoadRankFilesFromIt(t *testing.T) {
+	dir := t.TempDir()
+	ranks, err := os.ReadFile("../../internal/tokenizer/testdata/cl100k_base.tiktoken")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "o200k_base.tiktoken"), ranks, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IRONCLAW_BPE_DIR", dir)
+	defer tokenizer.UseBPEDir("../../internal/tokenizer/testdata", true)
+	oldExit := exitFunc
+	oldTok := newTokenizerFn
+	defer func() {
+		exitFunc = oldExit
+		newTokenizerFn = oldTok
+	}()
+	// o200k_base is only in dir, not in the tokenizer testdata.
+	newTokenizerFn = func() (*tokenizer.TikToken, error) {
+		return tokenizer.NewTikToken(tokenizer.EncodingO200K)
+	}
+	exitCode := -1
+	exitFunc = func(code int) { exitCode = code }
+
+	main()
+
+	if exitCode != -1 {
+		t.Errorf("want main to succeed with the rank file from IRONCLAW_BPE_DIR, got exit code %d", exitCode)
+	}
+}
+
 func TestMain_WhenRunDemoFails_ShouldCallExitWithOne(t *testing.T) {
 	oldExit := exitFunc
 	oldTok := newTokenizerFn
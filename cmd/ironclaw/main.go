@@ -3,24 +3,43 @@ package main
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/spf13/cobra"
 
+	"ironclaw/internal/agent"
+	"ironclaw/internal/approval"
 	"ironclaw/internal/banner"
 	"ironclaw/internal/brain"
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
 	"ironclaw/internal/cli"
 	"ironclaw/internal/config"
+	ironctx "ironclaw/internal/context"
+	"ironclaw/internal/db"
+	"ironclaw/internal/domain"
 	"ironclaw/internal/gateway"
 	"ironclaw/internal/llm"
 	"ironclaw/internal/memory"
 	"ironclaw/internal/prefs"
+	"ironclaw/internal/ratelimit"
+	"ironclaw/internal/router"
 	"ironclaw/internal/scheduler"
 	"ironclaw/internal/secrets"
 	"ironclaw/internal/security"
+	"ironclaw/internal/session"
+	"ironclaw/internal/telegram"
+	"ironclaw/internal/tokenizer"
+	"ironclaw/internal/tooling"
+	"ironclaw/internal/usage"
+	"ironclaw/internal/vectorstore"
 )
 
 // buildMeta holds version and build metadata (injectable via ldflags).
@@ -179,6 +198,52 @@ func newRootCommand(bm buildMeta) *cobra.Command {
 	configCmd.AddCommand(configGetCmd, configSetCmd, configUnsetCmd)
 	root.AddCommand(configCmd)
 
+	jobsCmd := &cobra.Command{Use: "jobs", Short: "Manage scheduled jobs on the running daemon"}
+	jobsCmd.PersistentFlags().String("url", "", "Gateway URL (default http://127.0.0.1:<gateway.port> from config)")
+	jobsCmd.PersistentFlags().String("token", "", "Gateway auth token (default gateway.auth.authToken from config)")
+	jobsListCmd := &cobra.Command{Use: "list", Short: "List jobs and their last run", RunE: runJobs("list"), Args: cobra.NoArgs}
+	jobsShowCmd := &cobra.Command{Use: "show <id>", Short: "Show a job and its run history", RunE: runJobs("show"), Args: cobra.ExactArgs(1)}
+	jobsAddCmd := &cobra.Command{Use: "add <id>", Short: "Add a cron or one-shot job", RunE: runJobs("add"), Args: cobra.ExactArgs(1)}
+	jobsAddCmd.Flags().String("cron", "", "Cron expression (e.g. \"0 9 * * *\" or \"@every 1h\")")
+	jobsAddCmd.Flags().String("at", "", "Run once at this RFC 3339 time instead of on a cron schedule")
+	jobsAddCmd.Flags().Duration("jitter", 0, "Delay each firing by a random duration up to this")
+	jobsAddCmd.Flags().String("overlap", "", "When a run is still going: skip (default), queue or replace")
+	jobsAddCmd.Flags().String("deliver", "", "Deliver the response to kind:to (channel, telegram, whatsapp, webhook) or memory")
+	jobsAddCmd.Flags().String("prompt", "", "Prompt injected into the agent when the job fires")
+	jobsAddCmd.Flags().String("model", "", "Model, alias or profile the job is answered with (default agents.defaultModel)")
+	jobsAddCmd.Flags().String("name", "", "Human-readable name")
+	jobsAddCmd.Flags().Bool("paused", false, "Add the job paused")
+	jobsRemoveCmd := &cobra.Command{Use: "remove <id>", Short: "Remove a job and its run history", RunE: runJobs("remove"), Args: cobra.ExactArgs(1)}
+	jobsRunNowCmd := &cobra.Command{Use: "run-now <id>", Short: "Run a job immediately and print the response", RunE: runJobs("run-now"), Args: cobra.ExactArgs(1)}
+	jobsPauseCmd := &cobra.Command{Use: "pause <id>", Short: "Stop a job from firing until resumed", RunE: runJobs("pause"), Args: cobra.ExactArgs(1)}
+	jobsResumeCmd := &cobra.Command{Use: "resume <id>", Short: "Let a paused job fire again", RunE: runJobs("resume"), Args: cobra.ExactArgs(1)}
+	jobsCmd.AddCommand(jobsListCmd, jobsShowCmd, jobsAddCmd, jobsRemoveCmd, jobsRunNowCmd, jobsPauseCmd, jobsResumeCmd)
+	root.AddCommand(jobsCmd)
+
+	agentsCmd := &cobra.Command{Use: "agents", Short: "Manage named agents (personas) in the agent workspace"}
+	agentsListCmd := &cobra.Command{Use: "list", Short: "List agents with their model, tools and channels", RunE: runAgents("list"), Args: cobra.NoArgs}
+	agentsShowCmd := &cobra.Command{Use: "show <name>", Short: "Show an agent's settings and system prompt", RunE: runAgents("show"), Args: cobra.ExactArgs(1)}
+	agentsCreateCmd := &cobra.Command{Use: "create <name>", Short: "Create an agent directory with starter persona files", RunE: runAgents("create"), Args: cobra.ExactArgs(1)}
+	agentsCreateCmd.Flags().String("provider", "", "LLM provider (default agents.provider)")
+	agentsCreateCmd.Flags().String("model", "", "Model or alias (default agents.defaultModel)")
+	agentsCreateCmd.Flags().String("memory", "", "Memory directory, relative to the agent directory (default memory)")
+	agentsCmd.AddCommand(agentsListCmd, agentsShowCmd, agentsCreateCmd)
+	root.AddCommand(agentsCmd)
+
+	usageCmd := &cobra.Command{Use: "usage", Short: "Token usage and spend per channel, agent, model and API key"}
+	usageReportCmd := &cobra.Command{Use: "report", Short: "Summarise token usage, cost and budgets", RunE: runUsageReport, Args: cobra.NoArgs}
+	usageReportCmd.Flags().String("period", "month", "Period to summarise: today, month or all")
+	usageReportCmd.Flags().String("by", "model", "Group by channel, agent, provider, model or key")
+	usageReportCmd.Flags().String("channel", "", "Only usage of this channel")
+	usageReportCmd.Flags().String("agent", "", "Only usage of this agent")
+	usageCmd.AddCommand(usageReportCmd)
+	root.AddCommand(usageCmd)
+
+	memoryCmd := &cobra.Command{Use: "memory", Short: "Manage the memory store used for retrieval"}
+	memoryReindexCmd := &cobra.Command{Use: "reindex", Short: "Rebuild the nearest-neighbour index of the memory store", RunE: runMemoryReindex, Args: cobra.NoArgs}
+	memoryCmd.AddCommand(memoryReindexCmd)
+	root.AddCommand(memoryCmd)
+
 	doctorCmd := &cobra.Command{
 		Use:   "doctor",
 		Short: "Health checks and quick fixes",
@@ -426,6 +491,147 @@ func runDoctor(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+// runAgents returns the RunE for an agents subcommand. The workspace root,
+// bindings and defaults come from the daemon's config, or the default config
+// when there is none.
+func runAgents(action string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		opts := cli.AgentsOptions{Action: action, Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Root: "agents"}}}
+		if cfg, err := config.Load(daemonConfigPath()); err == nil {
+			opts.Agents = cfg.Agents
+		}
+		if len(args) > 0 {
+			opts.Name = args[0]
+		}
+		if action == "create" {
+			opts.Profile.Provider, _ = cmd.Flags().GetString("provider")
+			opts.Profile.Model, _ = cmd.Flags().GetString("model")
+			opts.Profile.Memory, _ = cmd.Flags().GetString("memory")
+		}
+		code := cli.RunAgents(opts, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		if code != 0 {
+			return exitCodeErr(code)
+		}
+		return nil
+	}
+}
+
+// runUsageReport prints the usage report from the usage database of the
+// daemon's config (usage.dbUrl, else <memory>/usage.db).
+func runUsageReport(cmd *cobra.Command, args []string) error {
+	cfg := &domain.Config{Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: "memory"}}}
+	if loaded, err := config.Load(daemonConfigPath()); err == nil {
+		cfg = loaded
+	}
+	dbURL := usage.DBURL(cfg.Usage, cfg.Agents.Paths.Memory)
+	if dbURL == "" {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", usage.ErrNoDatabase)
+		return exitCodeErr(1)
+	}
+	store, closeStore, err := openUsageStoreFn(dbURL)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		return exitCodeErr(1)
+	}
+	defer closeStore()
+
+	opts := cli.UsageOptions{Store: store, Config: cfg.Usage}
+	opts.Period, _ = cmd.Flags().GetString("period")
+	by, _ := cmd.Flags().GetString("by")
+	opts.By = usage.Dimension(by)
+	opts.Channel, _ = cmd.Flags().GetString("channel")
+	opts.Agent, _ = cmd.Flags().GetString("agent")
+	if code := cli.RunUsage(opts, cmd.OutOrStdout(), cmd.ErrOrStderr()); code != 0 {
+		return exitCodeErr(code)
+	}
+	return nil
+}
+
+// openUsageStoreFn opens the usage store at dbURL; tests replace it.
+var openUsageStoreFn = func(dbURL string) (usage.Store, func() error, error) {
+	return usage.OpenStore(dbURL)
+}
+
+// runMemoryReindex rebuilds the HNSW index of the retrieval memory store
+// from the stored embeddings.
+func runMemoryReindex(cmd *cobra.Command, args []string) error {
+	cfg := &domain.Config{Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: "memory"}}}
+	if loaded, err := config.Load(daemonConfigPath()); err == nil {
+		cfg = loaded
+	}
+	if cfg.Retrieval.Index == nil {
+		fmt.Fprintln(cmd.ErrOrStderr(), "Error: retrieval.index is not configured")
+		return exitCodeErr(1)
+	}
+	store, closeStore, err := openVectorStoreFn(cfg.Retrieval, cfg.Agents.Paths.Memory)
+	if err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		return exitCodeErr(1)
+	}
+	defer closeStore()
+	if err := store.Rebuild(context.Background()); err != nil {
+		fmt.Fprintf(cmd.ErrOrStderr(), "Error: %v\n", err)
+		return exitCodeErr(1)
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "Indexed %d memories.\n", store.IndexLen())
+	return nil
+}
+
+// openVectorStoreFn opens the retrieval memory store; tests replace it.
+var openVectorStoreFn = vectorstore.OpenStore
+
+// runJobs returns the RunE for a jobs subcommand. The gateway URL and token
+// default to the daemon's config (IRONCLAW_CONFIG or ironclaw.json).
+func runJobs(action string) func(cmd *cobra.Command, args []string) error {
+	return func(cmd *cobra.Command, args []string) error {
+		opts := cli.JobsOptions{Action: action}
+		opts.URL, _ = cmd.Flags().GetString("url")
+		opts.Token, _ = cmd.Flags().GetString("token")
+		if len(args) > 0 {
+			opts.ID = args[0]
+		}
+		if action == "add" {
+			opts.Cron, _ = cmd.Flags().GetString("cron")
+			opts.Prompt, _ = cmd.Flags().GetString("prompt")
+			opts.Model, _ = cmd.Flags().GetString("model")
+			opts.Name, _ = cmd.Flags().GetString("name")
+			opts.Paused, _ = cmd.Flags().GetBool("paused")
+			opts.At, _ = cmd.Flags().GetString("at")
+			opts.Jitter, _ = cmd.Flags().GetDuration("jitter")
+			opts.Overlap, _ = cmd.Flags().GetString("overlap")
+			opts.Deliver, _ = cmd.Flags().GetString("deliver")
+		}
+		if opts.URL == "" || opts.Token == "" {
+			port := 8080
+			cfg, err := config.Load(daemonConfigPath())
+			if err == nil {
+				if cfg.Gateway.Port != 0 {
+					port = cfg.Gateway.Port
+				}
+				if opts.Token == "" {
+					opts.Token = cfg.Gateway.Auth.AuthToken
+				}
+			}
+			if opts.URL == "" {
+				opts.URL = fmt.Sprintf("http://127.0.0.1:%d", port)
+			}
+		}
+		code := cli.RunJobs(opts, cmd.OutOrStdout(), cmd.ErrOrStderr())
+		if code != 0 {
+			return exitCodeErr(code)
+		}
+		return nil
+	}
+}
+
+// daemonConfigPath returns the config file the daemon reads: $IRONCLAW_CONFIG or ./ironclaw.json.
+func daemonConfigPath() string {
+	if p := os.Getenv("IRONCLAW_CONFIG"); p != "" {
+		return p
+	}
+	return "ironclaw.json"
+}
+
 // runDaemon runs the daemon loop. If shutdownCh is non-nil, it returns when shutdownCh is closed (for tests).
 // Otherwise it blocks on OS signals.
 func runDaemon(cmd *cobra.Command, args []string, shutdownCh <-chan struct{}) error {
@@ -444,11 +650,7 @@ func runDaemon(cmd *cobra.Command, args []string, shutdownCh <-chan struct{}) er
 		_ = prefs.NewManager(path).Load()
 	}
 
-	cfgPath := os.Getenv("IRONCLAW_CONFIG")
-	if cfgPath == "" {
-		cfgPath = "ironclaw.json"
-	}
-	cfg, err := config.Load(cfgPath)
+	cfg, err := config.Load(daemonConfigPath())
 	if err != nil {
 		fmt.Println("  (no config file, using defaults)")
 	} else {
@@ -457,37 +659,71 @@ func runDaemon(cmd *cobra.Command, args []string, shutdownCh <-chan struct{}) er
 
 	var gatewayShutdown chan struct{}
 	var sched *scheduler.Scheduler
+	closeJobStore := func() {}
+	closeUsage := func() {}
+	closeCache := func() {}
+	closeRetriever := func() {}
 	if cfg != nil {
+		configureTokenizer(cfg)
 		var chatBrain *brain.Brain
-		if sm, err := secrets.DefaultManager(); err == nil {
-			getSecret := sm.Get
-			provider, err := llm.NewProvider(&cfg.Agents, getSecret, &cfg.Retry)
-			if err == nil {
-				var opts []brain.Option
-				if cfg.Agents.Paths.Memory != "" {
-					memStore := memory.NewFileMemoryStore(cfg.Agents.Paths.Memory)
-					opts = append(opts, brain.WithMemory(memStore))
-				}
-				if len(cfg.Agents.Fallbacks) > 0 {
-					fallbacks := llm.NewFallbackProviders(cfg.Agents.Fallbacks, getSecret, &cfg.Retry)
-					if len(fallbacks) > 0 {
-						opts = append(opts, brain.WithFallbacks(fallbacks...))
-					}
-				}
-				chatBrain = brain.NewBrain(provider, opts...)
+		gate := newApprovalGate(cfg)
+		var meter *usage.Meter
+		meter, closeUsage = newUsageMeter(cfg)
+		var responses *cache.Cache
+		responses, closeCache = newResponseCache(cfg)
+		sm, smErr := secrets.DefaultManager()
+		if smErr != nil {
+			sm = nil
+		}
+		tools := newToolRegistry(cfg, sm)
+		brainOpts := []brain.Option{
+			brain.WithApprovals(gate), brain.WithUsage(meter),
+			brain.WithTools(brain.NewToolDispatcher(tools)), brain.WithChannelToolPolicy(channelToolPolicy(cfg)),
+		}
+		var memories *vectorstore.Retriever
+		if memories, closeRetriever = newRetriever(cfg); memories != nil {
+			// Retrieved memories are budgeted in the default model's tokens.
+			tok, _ := newTokenizerFn(cfg.Agents.ResolveProfile("").Model)
+			brainOpts = append(brainOpts, brain.WithRetriever(memories, tok, cfg.Retrieval.Budget))
+		}
+		// One circuit per provider, shared by every brain and reported by the gateway.
+		breakers := breaker.NewSet(breaker.FromConfig(cfg.CircuitBreaker))
+		// Likewise one set of rate limits per provider and key.
+		limits := ratelimit.NewSet(cfg.RateLimits)
+		gwOpts := append(gatewayOptions(cfg), gateway.WithApprovals(gate), gateway.WithProviderHealth(breakers), gateway.WithProviderLimits(limits))
+		if responses != nil {
+			gwOpts = append(gwOpts, gateway.WithCacheStats(responses))
+		}
+		rtOpts := routerOptions(cfg)
+		if sm != nil {
+			chatBrain, _ = newChatBrain(cfg, cfg.Agents, sm.Get, breakers, limits, responses, brainOpts...)
+			if resolve := newAgentResolver(cfg, sm.Get, breakers, limits, responses, brainOpts...); resolve != nil {
+				gwOpts = append(gwOpts, gateway.WithRouterOptions(router.WithAgents(resolve)))
+				rtOpts = append(rtOpts, router.WithAgents(resolve))
+			}
+		}
+		if chatBrain != nil {
+			// Sub-agents answer through the default brain, without tools.
+			_ = tools.Register(tooling.NewSpawnAgentTool(brain.NewSubAgentRunner(chatBrain)))
+			if cfg.Agents.Paths.Memory != "" || memories != nil {
+				// Memories go to the default brain's memory.md and index.
+				_ = tools.Register(tooling.NewRememberTool(chatBrain))
 			}
 		}
 
 		// Initialize the scheduler with the brain as the event handler.
 		if chatBrain != nil {
-			engine := scheduler.NewRobfigCronEngine()
-			handler := makeSchedulerHandler(chatBrain, schedulerPrintFn)
-			sched = scheduler.NewScheduler(engine, handler)
+			// The OpenAI-compatible API and job delivery share one router,
+			// so a delivery waits for the channel's in-flight turn.
+			rt := router.NewRouter(chatBrain, channelHistoryFactory(cfg), rtOpts...)
+			gwOpts = append(gwOpts, gateway.WithRouter(rt))
+			sched, closeJobStore = newDaemonScheduler(cfg, chatBrain, rt)
 			sched.Start()
 			fmt.Println("  scheduler started")
+			gwOpts = append(gwOpts, gateway.WithJobs(sched))
 		}
 
-		srv, srvErr := gateway.NewServer(&cfg.Gateway, chatBrain)
+		srv, srvErr := gateway.NewServer(&cfg.Gateway, chatBrain, gwOpts...)
 		if srvErr != nil {
 			fmt.Fprintf(gatewayBindErrWriter, "  gateway start: %v\n", srvErr)
 		} else {
@@ -525,6 +761,10 @@ func runDaemon(cmd *cobra.Command, args []string, shutdownCh <-chan struct{}) er
 		if sched != nil {
 			sched.Stop()
 		}
+		closeJobStore()
+		closeUsage()
+		closeCache()
+		closeRetriever()
 		if gatewayShutdown != nil {
 			close(gatewayShutdown)
 		}
@@ -534,29 +774,431 @@ func runDaemon(cmd *cobra.Command, args []string, shutdownCh <-chan struct{}) er
 	if sched != nil {
 		sched.Stop()
 	}
+	closeJobStore()
+	closeUsage()
+	closeCache()
+	closeRetriever()
 	if gatewayShutdown != nil {
 		close(gatewayShutdown)
 	}
 	return nil
 }
 
+// newChatBrain builds a brain for agents (the global agents config, or a named
+// agent's settings): its provider and fallbacks guarded by their circuits in
+// breakers, rate limited by limits and cached in responses (nil for none),
+// memory store and context manager (sized to the default model's context
+// window, summarising with agents.compaction), plus any extra options.
+func newChatBrain(cfg *domain.Config, agents domain.AgentsConfig, getSecret func(string) (string, error), breakers *breaker.Set, limits *ratelimit.Set, responses *cache.Cache, extra ...brain.Option) (*brain.Brain, error) {
+	provider, fallbacks, err := llm.NewGuardedProviders(&agents, getSecret, breakers, limits, responses, &cfg.Retry)
+	if err != nil {
+		return nil, err
+	}
+	opts := append([]brain.Option(nil), extra...)
+	if agents.Paths.Memory != "" {
+		opts = append(opts, brain.WithMemory(memory.NewFileMemoryStore(agents.Paths.Memory)))
+	}
+	if len(fallbacks) > 0 {
+		opts = append(opts, brain.WithFallbacks(fallbacks...))
+	}
+	profile := agents.ResolveProfile("")
+	tok, info := newTokenizerFn(profile.Model)
+	window := profile.ContextWindow
+	if window <= 0 {
+		window = info.ContextWindow
+	}
+	if window <= 0 {
+		window = daemonContextWindow
+	}
+	fitting := ironctx.WithImageProvider(imageProvider(agents, profile.Provider))
+	var cm domain.ContextManager = ironctx.NewManager(tok, window, fitting)
+	if agents.Compaction != nil {
+		cm = ironctx.NewCompactor(tok, window, provider, ironctx.WithCompaction(*agents.Compaction), ironctx.WithFitting(fitting))
+	}
+	opts = append(opts, brain.WithContextManager(cm))
+	return brain.NewBrain(provider, opts...), nil
+}
+
+// imageProvider names the provider whose image pricing applies to provider:
+// the type of an agents.providers entry, or provider itself.
+func imageProvider(agents domain.AgentsConfig, provider string) string {
+	if pc, ok := agents.Providers[provider]; ok {
+		return pc.Type
+	}
+	return provider
+}
+
+// newAgentResolver loads the named agents under agents.paths.root, builds a
+// brain for each and returns a resolver that binds channels to them per
+// agents.bindings and agents.defaultAgent. It returns nil when there are no
+// agents or the bindings are invalid; agents whose provider cannot be built
+// are skipped. breakers, limits, responses and extra options are passed to
+// every agent's brain, whose tools are limited by the agent's TOOLS.md and
+// agent.json rules on top of the channel's.
+func newAgentResolver(cfg *domain.Config, getSecret func(string) (string, error), breakers *breaker.Set, limits *ratelimit.Set, responses *cache.Cache, extra ...brain.Option) router.AgentResolver {
+	reg, err := agent.LoadRegistry(cfg.Agents.Paths.Root)
+	if err == nil {
+		err = reg.Bind(cfg.Agents.Bindings, cfg.Agents.DefaultAgent)
+	}
+	if err != nil {
+		fmt.Printf("  agents: %v\n", err)
+		return nil
+	}
+	if len(reg.List()) == 0 {
+		return nil
+	}
+	fmt.Printf("  agents: %d loaded\n", len(reg.List()))
+	policy := brain.WithChannelToolPolicy(func(channelID string) tooling.Policy {
+		return reg.ToolPolicy(channelID, cfg.Tools, cfg.Gateway.Auth)
+	})
+	opts := append(append([]brain.Option(nil), extra...), policy)
+	return reg.Resolver(func(a *agent.Agent) (router.Generator, error) {
+		return newChatBrain(cfg, a.Settings(cfg.Agents), getSecret, breakers, limits, responses, opts...)
+	}, func(a *agent.Agent, err error) {
+		fmt.Printf("  agent %s: %v (skipped)\n", a.Name, err)
+	})
+}
+
+// newApprovalGate builds the approval gate shared by every brain and the
+// gateway, falling back to the defaults when the approval config is invalid.
+func newApprovalGate(cfg *domain.Config) *approval.Gate {
+	gate, err := approval.NewGateFromConfig(cfg.Approval, cfg.Agents.Paths.Memory)
+	if err != nil {
+		fmt.Printf("  approvals: %v (using defaults)\n", err)
+		return approval.NewGate()
+	}
+	return gate
+}
+
+// newToolRegistry registers the tools every brain offers the model: shell
+// (limited to allowedCommands), scrape, image, iot over HTTP, the Docker
+// sandbox when a Docker client can be created, filesystem jailed to the agent
+// workspace (agents.paths.root) when set, and git when sm is non-nil. The
+// browser, which needs a local Chrome, is not registered. Which tools a
+// channel may use is decided per turn by its tool policy, and dangerous
+// calls wait for approval.
+func newToolRegistry(cfg *domain.Config, sm secrets.SecretsManager) *tooling.ToolRegistry {
+	reg := tooling.NewToolRegistry()
+	_ = reg.Register(tooling.NewShellTool(cfg, &tooling.ExecCommandRunner{}))
+	_ = reg.Register(tooling.NewScrapeTool(tooling.NewDefaultHTTPFetcher()))
+	_ = reg.Register(tooling.NewImageTool(&tooling.RealImageProcessor{}))
+	_ = reg.Register(tooling.NewIoTTool(nil, &tooling.RealHTTPDoer{Client: &http.Client{Timeout: toolHTTPTimeout}}))
+	if rt, err := newContainerRuntimeFn(); err == nil {
+		_ = reg.Register(tooling.NewDockerSandboxTool(rt))
+	}
+	if cfg.Agents.Paths.Root != "" {
+		_ = reg.Register(tooling.NewFileSystemTool(cfg.Agents.Paths.Root, &tooling.OsFileSystem{}))
+	}
+	if sm != nil {
+		_ = reg.Register(tooling.NewGitTool(sm, tooling.NewGoGitRepo(), tooling.DefaultGitProviderFactory))
+	}
+	return reg
+}
+
+// channelToolPolicy returns the tool policy of a routed turn's channel for
+// the default brain: the rules of tools for the channel and, for external
+// channels (gateway.auth.externalChannels), the external rule.
+func channelToolPolicy(cfg *domain.Config) func(channelID string) tooling.Policy {
+	return func(channelID string) tooling.Policy {
+		return tooling.ChannelPolicy(cfg.Tools, cfg.Gateway.Auth, channelID)
+	}
+}
+
+// toolHTTPTimeout bounds the HTTP requests tools make.
+const toolHTTPTimeout = 30 * time.Second
+
+// newContainerRuntimeFn creates the Docker sandbox's runtime; tests replace it.
+var newContainerRuntimeFn = func() (tooling.ContainerRuntime, error) {
+	return tooling.NewDockerContainerRuntime()
+}
+
+// newUsageMeter opens the usage meter shared by every brain, recording into
+// usage.dbUrl or <memory>/usage.db. Budget warnings are printed. When the
+// store cannot be opened usage is not recorded and the meter is nil. The
+// returned func closes the store.
+func newUsageMeter(cfg *domain.Config) (*usage.Meter, func()) {
+	m, closeFn, err := openUsageMeterFn(cfg.Usage, cfg.Agents.Paths.Memory, usage.WithWarnFunc(func(st usage.BudgetStatus) {
+		fmt.Printf("  usage: %s %s has spent $%.2f of its %s budget of $%.2f\n", st.Scope, st.Name, st.Spent, st.Period, st.Limit)
+	}))
+	if err != nil {
+		fmt.Printf("  usage: %v (usage not recorded)\n", err)
+		return nil, func() {}
+	}
+	return m, func() { _ = closeFn() }
+}
+
+// openUsageMeterFn opens the usage meter; tests replace it.
+var openUsageMeterFn = usage.Open
+
+// newResponseCache opens the LLM response cache shared by every brain when
+// cache.enabled is set, storing into cache.dbUrl or <memory>/cache.db. It
+// returns nil when the cache is disabled or cannot be opened.
+func newResponseCache(cfg *domain.Config) (*cache.Cache, func()) {
+	if !cfg.Cache.Enabled {
+		return nil, func() {}
+	}
+	c, closeFn, err := openCacheFn(cfg.Cache, cfg.Agents.Paths.Memory)
+	if err != nil {
+		fmt.Printf("  cache: %v (responses not cached)\n", err)
+		return nil, func() {}
+	}
+	return c, func() { _ = closeFn() }
+}
+
+// openCacheFn opens the response cache; tests replace it.
+var openCacheFn = cache.Open
+
+// newRetriever opens the memory retriever shared by every brain when
+// retrieval.enabled is set, storing into retrieval.dbUrl or
+// <memory>/memories.db. It returns nil when retrieval is disabled or the
+// store cannot be opened.
+func newRetriever(cfg *domain.Config) (*vectorstore.Retriever, func()) {
+	if !cfg.Retrieval.Enabled {
+		return nil, func() {}
+	}
+	r, closeFn, err := openRetrieverFn(cfg.Retrieval, cfg.Agents.Paths.Memory)
+	if err != nil {
+		fmt.Printf("  retrieval: %v (memories not retrieved)\n", err)
+		return nil, func() {}
+	}
+	return r, func() { _ = closeFn() }
+}
+
+// openRetrieverFn opens the memory retriever; tests replace it.
+var openRetrieverFn = vectorstore.Open
+
+// daemonContextWindow is the token budget the context manager fits replayed
+// chat history into when the default model's profile sets no contextWindow
+// and the model's window is unknown.
+const daemonContextWindow = 8192
+
+// newTokenizerFn returns the tokenizer counting a model's tokens for context
+// fitting, with the model's info; tests replace it.
+var newTokenizerFn = tokenizer.ForModel
+
+// configureTokenizer makes token counting load its BPE files from
+// tokenizer.bpeDir, or <memory>/tiktoken, before downloading them, and
+// never download them with tokenizer.offline.
+func configureTokenizer(cfg *domain.Config) {
+	dir := cfg.Tokenizer.BPEDir
+	if dir == "" && cfg.Agents.Paths.Memory != "" {
+		dir = filepath.Join(cfg.Agents.Paths.Memory, "tiktoken")
+	}
+	tokenizer.UseBPEDir(dir, cfg.Tokenizer.Offline)
+}
+
+// gatewayOptions persists each channel's history as JSONL under
+// <memory>/sessions, builds the system prompt from the agent workspace root and
+// exposes the configured models to the OpenAI-compatible API.
+func gatewayOptions(cfg *domain.Config) []gateway.ServerOption {
+	opts := []gateway.ServerOption{gateway.WithModels(cfg.Agents)}
+	if f := channelHistoryFactory(cfg); f != nil {
+		opts = append(opts, gateway.WithHistoryFactory(f))
+	}
+	if rtOpts := routerOptions(cfg); len(rtOpts) > 0 {
+		opts = append(opts, gateway.WithRouterOptions(rtOpts...))
+	}
+	return opts
+}
+
+// routerOptions builds the system prompt from the agent workspace root, when
+// it is set and loads.
+func routerOptions(cfg *domain.Config) []router.Option {
+	if cfg.Agents.Paths.Root == "" {
+		return nil
+	}
+	ac, err := agent.LoadAgentContext(cfg.Agents.Paths.Root)
+	if err != nil {
+		return nil
+	}
+	return []router.Option{router.WithSystemPrompt(agent.BuildSystemPrompt(ac))}
+}
+
+// channelHistoryFactory returns the JSONL history factory rooted at
+// <memory>/sessions, or nil when no memory path is configured.
+func channelHistoryFactory(cfg *domain.Config) router.HistoryFactory {
+	if cfg.Agents.Paths.Memory == "" {
+		return nil
+	}
+	return session.NewChannelHistoryFactory(filepath.Join(cfg.Agents.Paths.Memory, "sessions"))
+}
+
+// newJobStoreFn opens the scheduler's job store at dbURL, creating the parent
+// directory of a local file: database, and returns it with a func that closes
+// the connection; tests replace it.
+var newJobStoreFn = func(dbURL string) (scheduler.JobStore, func() error, error) {
+	if path, ok := strings.CutPrefix(dbURL, "file:"); ok {
+		path, _, _ = strings.Cut(path, "?")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("create job store dir: %w", err)
+		}
+	}
+	conn, err := db.Connect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := scheduler.NewSQLiteJobStore(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return store, conn.Close, nil
+}
+
+// newDaemonScheduler builds the daemon's scheduler on top of the job store
+// (scheduler.dbUrl, else <memory>/scheduler.db). Stored jobs are registered
+// first, then config-declared jobs whose ID is not stored yet, so runtime edits
+// made through the jobs API survive restarts. If the store cannot be opened,
+// jobs are kept in memory. Jobs deliver to channels through rt. The returned
+// func closes the store.
+func newDaemonScheduler(cfg *domain.Config, b *brain.Brain, rt *router.Router) (*scheduler.Scheduler, func()) {
+	opts := schedulerSenders(b, rt)
+	closeStore := func() {}
+	dbURL := cfg.Scheduler.DBURL
+	if dbURL == "" && cfg.Agents.Paths.Memory != "" {
+		dbURL = "file:" + filepath.Join(cfg.Agents.Paths.Memory, "scheduler.db")
+	}
+	var store scheduler.JobStore
+	if dbURL != "" {
+		s, closeFn, err := newJobStoreFn(dbURL)
+		if err != nil {
+			fmt.Printf("  scheduler store: %v (jobs kept in memory)\n", err)
+		} else {
+			store = s
+			opts = append(opts, scheduler.WithStore(s))
+			closeStore = func() { _ = closeFn() }
+		}
+	}
+
+	sched := scheduler.NewScheduler(scheduler.NewRobfigCronEngine(), makeSchedulerHandler(b, schedulerPrintFn), opts...)
+	if store != nil {
+		if err := sched.LoadJobs(context.Background()); err != nil {
+			fmt.Printf("  scheduler: %v\n", err)
+		}
+	}
+	for _, jc := range cfg.Scheduler.Jobs {
+		if _, ok := sched.GetJob(jc.ID); ok {
+			continue
+		}
+		job, err := jobFromConfig(jc)
+		if err == nil {
+			err = sched.AddJob(job)
+		}
+		if err != nil {
+			fmt.Printf("  scheduler: config job %q: %v\n", jc.ID, err)
+		}
+	}
+	return sched, closeStore
+}
+
+// jobFromConfig converts a config-declared job, parsing its at time, jitter
+// and delivery target.
+func jobFromConfig(jc domain.JobConfig) (scheduler.Job, error) {
+	job := scheduler.Job{
+		ID:       jc.ID,
+		Name:     jc.Name,
+		CronExpr: jc.Cron,
+		Overlap:  scheduler.OverlapPolicy(jc.Overlap),
+		Prompt:   jc.Prompt,
+		Model:    jc.Model,
+		Paused:   jc.Paused,
+	}
+	var err error
+	if jc.At != "" {
+		if job.At, err = time.Parse(time.RFC3339, jc.At); err != nil {
+			return job, fmt.Errorf("at: %w", err)
+		}
+	}
+	if jc.Jitter != "" {
+		if job.Jitter, err = time.ParseDuration(jc.Jitter); err != nil {
+			return job, fmt.Errorf("jitter: %w", err)
+		}
+	}
+	if job.Deliver, err = scheduler.ParseTarget(jc.Deliver); err != nil {
+		return job, err
+	}
+	return job, nil
+}
+
+// schedulerSenders registers the delivery targets the daemon can reach:
+// channels of rt, the gateway's router (recorded in channel history; skipped
+// when rt is nil), webhooks, b's long-term memory (see brain.Brain.Remember)
+// and Telegram chats (using the bridge's bot token, resolved on first use).
+// WhatsApp sessions live in the separate bridge process, so jobs targeting
+// WhatsApp fail delivery with scheduler.ErrNoSender.
+func schedulerSenders(b *brain.Brain, rt *router.Router) []scheduler.Option {
+	opts := []scheduler.Option{
+		scheduler.WithSender(scheduler.TargetWebhook, scheduler.NewWebhookSender(nil)),
+		scheduler.WithSender(scheduler.TargetTelegram, telegramSender()),
+	}
+	if rt != nil {
+		opts = append(opts, scheduler.WithSender(scheduler.TargetChannel, scheduler.SenderFunc(
+			func(ctx context.Context, job scheduler.Job, text string) error {
+				return rt.Deliver(ctx, job.Deliver.To, text)
+			})))
+	}
+	opts = append(opts, scheduler.WithSender(scheduler.TargetMemory, scheduler.SenderFunc(
+		func(ctx context.Context, job scheduler.Job, text string) error {
+			return b.Remember(ctx, fmt.Sprintf("Scheduled job %q: %s", job.ID, text))
+		})))
+	return opts
+}
+
+// newTelegramBotFn creates the Telegram bot used for job delivery; tests replace it.
+var newTelegramBotFn = func() (telegram.BotAPI, error) {
+	token := os.Getenv("TELEGRAM_BOT_TOKEN")
+	if token == "" {
+		sm, err := secrets.DefaultManager()
+		if err != nil {
+			return nil, fmt.Errorf("secrets manager: %w", err)
+		}
+		if token, err = sm.Get("telegram_bot_token"); err != nil {
+			return nil, fmt.Errorf("telegram token: set TELEGRAM_BOT_TOKEN or the telegram_bot_token secret: %w", err)
+		}
+	}
+	return tgbotapi.NewBotAPI(token)
+}
+
+// telegramSender sends job output to Telegram, creating the bot on first
+// use so daemons without Telegram jobs never need a token.
+func telegramSender() scheduler.Sender {
+	var mu sync.Mutex
+	var bot telegram.BotAPI
+	return scheduler.SenderFunc(func(ctx context.Context, job scheduler.Job, text string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if bot == nil {
+			b, err := newTelegramBotFn()
+			if err != nil {
+				return err
+			}
+			bot = b
+		}
+		return telegram.SendText(bot, job.Deliver.To, text)
+	})
+}
+
 // schedulerPrintFn controls where scheduler handler output goes. Tests override this.
 var schedulerPrintFn = func(format string, args ...any) {
 	fmt.Printf(format, args...)
 }
 
 // makeSchedulerHandler creates an EventHandler that injects the cron job's prompt
-// into the brain as a system event. printFn is used for output (testable).
+// into the brain as a system event and returns the brain's response. Token
+// usage is attributed to the channel "job:<id>", and the job's model (if any)
+// answers. printFn is used for output (testable).
 func makeSchedulerHandler(b *brain.Brain, printFn func(string, ...any)) scheduler.EventHandler {
-	return func(ctx context.Context, job scheduler.Job) error {
+	return func(ctx context.Context, job scheduler.Job) (string, error) {
+		ctx = usage.WithAttribution(ctx, usage.Attribution{Channel: "job:" + job.ID})
+		ctx = domain.WithModel(ctx, job.Model)
 		systemPrompt := fmt.Sprintf("[System Event: Scheduled Job %q]\n%s", job.Name, job.Prompt)
 		resp, err := b.Generate(ctx, systemPrompt)
 		if err != nil {
 			printFn("  scheduler: job %q error: %v\n", job.ID, err)
-			return err
+			return "", err
 		}
 		printFn("  scheduler: job %q response: %s\n", job.ID, resp)
-		return nil
+		return resp, nil
 	}
 }
 
@@ -3,21 +3,89 @@ package main
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"syscall"
 	"testing"
 	"time"
 
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"ironclaw/internal/agent"
 	"ironclaw/internal/brain"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/memory"
+	"ironclaw/internal/router"
 	"ironclaw/internal/scheduler"
+	"ironclaw/internal/session"
+	"ironclaw/internal/telegram"
+	"ironclaw/internal/tokenizer"
+	"ironclaw/internal/tooling"
+	"ironclaw/internal/usage"
+	"ironclaw/internal/vectorstore"
 )
 
+func init() {
+	// Daemon tests run with relative memory paths; keep them from creating
+	// scheduler.db or usage.db files in the package directory.
+	newJobStoreFn = func(string) (scheduler.JobStore, func() error, error) {
+		return newFakeJobStore(), func() error { return nil }, nil
+	}
+	openUsageMeterFn = func(domain.UsageConfig, string, ...usage.Option) (*usage.Meter, func() error, error) {
+		return nil, nil, usage.ErrNoDatabase
+	}
+}
+
+// fakeJobStore is an in-memory scheduler.JobStore.
+type fakeJobStore struct {
+	jobs map[string]scheduler.Job
+	runs []scheduler.Run
+}
+
+func newFakeJobStore(jobs ...scheduler.Job) *fakeJobStore {
+	f := &fakeJobStore{jobs: make(map[string]scheduler.Job)}
+	for _, j := range jobs {
+		f.jobs[j.ID] = j
+	}
+	return f
+}
+
+func (f *fakeJobStore) LoadJobs(context.Context) ([]scheduler.Job, error) {
+	var out []scheduler.Job
+	for _, j := range f.jobs {
+		out = append(out, j)
+	}
+	return out, nil
+}
+
+func (f *fakeJobStore) SaveJob(_ context.Context, job scheduler.Job) error {
+	f.jobs[job.ID] = job
+	return nil
+}
+
+func (f *fakeJobStore) DeleteJob(_ context.Context, id string) error {
+	delete(f.jobs, id)
+	return nil
+}
+
+func (f *fakeJobStore) RecordRun(_ context.Context, run scheduler.Run) error {
+	f.runs = append(f.runs, run)
+	return nil
+}
+
+func (f *fakeJobStore) Runs(context.Context, string, int) ([]scheduler.Run, error) {
+	return f.runs, nil
+}
+
 func TestRootCommand_WhenVersionFlag_ShouldPrintBuildMetadata(t *testing.T) {
 	out := &bytes.Buffer{}
 	errOut := &bytes.Buffer{}
@@ -1069,10 +1137,12 @@ type testProvider struct {
 	response string
 	err      error
 	prompt   string
+	model    string
 }
 
 func (m *testProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	m.prompt = prompt
+	m.model = domain.ModelFrom(ctx)
 	return m.response, m.err
 }
 
@@ -1087,7 +1157,7 @@ func TestMakeSchedulerHandler_WhenBrainSucceeds_ShouldReturnNilAndPrintResponse(
 	handler := makeSchedulerHandler(b, printFn)
 	job := scheduler.Job{ID: "health-check", Name: "Health", CronExpr: "@every 1m", Prompt: "Check system health."}
 
-	err := handler(context.Background(), job)
+	_, err := handler(context.Background(), job)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -1110,7 +1180,7 @@ func TestMakeSchedulerHandler_WhenBrainFails_ShouldReturnErrorAndPrintIt(t *test
 	handler := makeSchedulerHandler(b, printFn)
 	job := scheduler.Job{ID: "failing-job", Name: "Fail", CronExpr: "@every 1m", Prompt: "Do something."}
 
-	err := handler(context.Background(), job)
+	_, err := handler(context.Background(), job)
 	if err == nil {
 		t.Fatal("expected error when brain fails")
 	}
@@ -1130,7 +1200,7 @@ func TestMakeSchedulerHandler_ShouldFormatSystemEventPrompt(t *testing.T) {
 	handler := makeSchedulerHandler(b, printFn)
 	job := scheduler.Job{ID: "j1", Name: "Nightly Backup", CronExpr: "@daily", Prompt: "Run backup now."}
 
-	_ = handler(context.Background(), job)
+	_, _ = handler(context.Background(), job)
 
 	if provider.prompt == "" {
 		t.Fatal("expected prompt to be passed to brain")
@@ -1146,7 +1216,474 @@ func TestMakeSchedulerHandler_ShouldFormatSystemEventPrompt(t *testing.T) {
 	}
 }
 
+func TestMakeSchedulerHandler_WhenJobNamesModel_ShouldSelectItInContext(t *testing.T) {
+	provider := &testProvider{response: "ok"}
+	handler := makeSchedulerHandler(brain.NewBrain(provider), func(string, ...any) {})
+
+	job := scheduler.Job{ID: "j1", Name: "Digest", CronExpr: "@daily", Prompt: "Summarize.", Model: "fast"}
+	if _, err := handler(context.Background(), job); err != nil {
+		t.Fatalf("handler: %v", err)
+	}
+	if provider.model != "fast" {
+		t.Errorf("model in context = %q, want %q", provider.model, "fast")
+	}
+}
+
 func TestSchedulerPrintFn_ShouldNotPanic(t *testing.T) {
 	// Smoke test: the default schedulerPrintFn should not panic.
 	schedulerPrintFn("test %s\n", "ok")
 }
+
+func TestGatewayOptions_WhenPathsSet_ShouldReturnHistoryAndPromptOptions(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "SOUL.md"), []byte("be kind"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &domain.Config{}
+	cfg.Agents.Paths = domain.AgentPaths{Root: root, Memory: t.TempDir()}
+	if got := len(gatewayOptions(cfg)); got != 3 {
+		t.Errorf("expected models + history + system prompt options, got %d", got)
+	}
+	if got := len(gatewayOptions(&domain.Config{})); got != 1 {
+		t.Errorf("expected only the models option for empty paths, got %d", got)
+	}
+}
+
+func TestNewDaemonScheduler_ShouldLoadStoredJobsThenMissingConfigJobs(t *testing.T) {
+	store := newFakeJobStore(scheduler.Job{ID: "digest", CronExpr: "@daily", Prompt: "stored prompt", Paused: true})
+	var gotURL string
+	old := newJobStoreFn
+	newJobStoreFn = func(url string) (scheduler.JobStore, func() error, error) {
+		gotURL = url
+		return store, func() error { return nil }, nil
+	}
+	defer func() { newJobStoreFn = old }()
+
+	cfg := &domain.Config{
+		Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: "mem"}},
+		Scheduler: domain.SchedulerConfig{Jobs: []domain.JobConfig{
+			{ID: "digest", Cron: "@hourly", Prompt: "config prompt"},
+			{ID: "health", Name: "Health", Cron: "@every 1h", Prompt: "check"},
+		}},
+	}
+	sched, closeStore := newDaemonScheduler(cfg, brain.NewBrain(&testProvider{}), nil)
+	defer closeStore()
+
+	if gotURL != "file:"+filepath.Join("mem", "scheduler.db") {
+		t.Errorf("unexpected store URL %q", gotURL)
+	}
+	digest, _ := sched.GetJob("digest")
+	if digest.Prompt != "stored prompt" || !digest.Paused {
+		t.Errorf("stored job should win over config, got %+v", digest)
+	}
+	if _, ok := store.jobs["health"]; !ok {
+		t.Error("expected config-only job to be registered and persisted")
+	}
+}
+
+func TestJobFromConfig_ShouldParseTriggersAndTarget(t *testing.T) {
+	job, err := jobFromConfig(domain.JobConfig{ID: "remind", At: "2026-03-01T09:00:00Z", Jitter: "2m", Overlap: "replace", Deliver: "channel:general", Prompt: "p"})
+	if err != nil {
+		t.Fatalf("jobFromConfig: %v", err)
+	}
+	if !job.At.Equal(time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)) || job.Jitter != 2*time.Minute ||
+		job.Overlap != scheduler.OverlapReplace || job.Deliver != (scheduler.Target{Kind: scheduler.TargetChannel, To: "general"}) {
+		t.Errorf("unexpected job %+v", job)
+	}
+	for _, bad := range []domain.JobConfig{{At: "tomorrow"}, {Jitter: "a bit"}, {Deliver: "pigeon:home"}} {
+		if _, err := jobFromConfig(bad); err == nil {
+			t.Errorf("expected error for %+v", bad)
+		}
+	}
+}
+
+func TestNewDaemonScheduler_ShouldDeliverToMemoryChannelAndTelegram(t *testing.T) {
+	mem := t.TempDir()
+	bot := &fakeTelegramBot{}
+	old := newTelegramBotFn
+	newTelegramBotFn = func() (telegram.BotAPI, error) { return bot, nil }
+	defer func() { newTelegramBotFn = old }()
+
+	cfg := &domain.Config{
+		Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: mem}},
+		Scheduler: domain.SchedulerConfig{Jobs: []domain.JobConfig{
+			{ID: "note", Cron: "@daily", Prompt: "p", Deliver: "memory"},
+			{ID: "chan", Cron: "@daily", Prompt: "p", Deliver: "channel:general"},
+			{ID: "tg", Cron: "@daily", Prompt: "p", Deliver: "telegram:42"},
+		}},
+	}
+	b := brain.NewBrain(&testProvider{response: "briefing"}, brain.WithMemory(memory.NewFileMemoryStore(mem)))
+	sched, closeStore := newDaemonScheduler(cfg, b, router.NewRouter(b, channelHistoryFactory(cfg)))
+	defer closeStore()
+
+	for _, id := range []string{"note", "chan", "tg"} {
+		if _, err := sched.RunNow(context.Background(), id); err != nil {
+			t.Fatalf("RunNow %s: %v", id, err)
+		}
+	}
+	remembered, _ := os.ReadFile(filepath.Join(mem, "memory.md"))
+	if !strings.Contains(string(remembered), `Scheduled job "note": briefing`) {
+		t.Errorf("expected response in long-term memory, got %q", remembered)
+	}
+	past, _ := session.NewChannelHistoryFactory(filepath.Join(mem, "sessions"))("general").LoadHistory(10)
+	if len(past) != 1 || past[0].Role != domain.RoleAssistant {
+		t.Errorf("expected delivered message in channel history, got %+v", past)
+	}
+	if len(bot.sent) != 1 {
+		t.Errorf("expected one Telegram message, got %d", len(bot.sent))
+	}
+}
+
+// fakeTelegramBot records messages sent through it.
+type fakeTelegramBot struct {
+	sent []tgbotapi.Chattable
+}
+
+func (b *fakeTelegramBot) Send(c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	b.sent = append(b.sent, c)
+	return tgbotapi.Message{}, nil
+}
+
+func (b *fakeTelegramBot) GetUpdatesChan(tgbotapi.UpdateConfig) tgbotapi.UpdatesChannel { return nil }
+
+func (b *fakeTelegramBot) StopReceivingUpdates() {}
+
+func TestNewAgentResolver_ShouldBindChannelsToAgentBrains(t *testing.T) {
+	root := t.TempDir()
+	if _, err := agent.Create(root, "ops", agent.Profile{Provider: "local"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := agent.Create(root, "broken", agent.Profile{Provider: "nope"}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &domain.Config{Agents: domain.AgentsConfig{
+		Paths:    domain.AgentPaths{Root: root},
+		Bindings: map[string]string{"telegram-*": "ops", "general": "broken"},
+	}}
+	resolve := newAgentResolver(cfg, func(string) (string, error) { return "", errors.New("no secrets") }, nil, nil, nil)
+	if resolve == nil {
+		t.Fatal("expected resolver")
+	}
+	if a, ok := resolve("telegram-123"); !ok || a.Name != "ops" || !strings.Contains(a.SystemPrompt, "You are ops.") {
+		t.Errorf("unexpected agent for telegram-123: %+v, %v", a, ok)
+	}
+	if _, ok := resolve("general"); ok {
+		t.Error("agent with a failing provider should be skipped")
+	}
+	if _, ok := resolve("openai"); ok {
+		t.Error("unbound channel should use the default brain")
+	}
+
+	cfg.Agents.Bindings = map[string]string{"general": "ghost"}
+	if newAgentResolver(cfg, nil, nil, nil, nil) != nil {
+		t.Error("expected nil resolver for invalid bindings")
+	}
+}
+
+func TestRootCommand_Agents_ShouldCreateAndListFromConfigRoot(t *testing.T) {
+	dir := t.TempDir()
+	cfgPath := filepath.Join(dir, "ironclaw.json")
+	cfgJSON := fmt.Sprintf(`{"agents":{"provider":"local","paths":{"root":%q},"bindings":{"telegram-1":"ops"}}}`, filepath.Join(dir, "agents"))
+	if err := os.WriteFile(cfgPath, []byte(cfgJSON), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IRONCLAW_CONFIG", cfgPath)
+
+	for _, args := range [][]string{{"agents", "create", "ops", "--model", "gpt-4o-mini"}, {"agents", "list"}} {
+		out := &bytes.Buffer{}
+		root := newRootCommand(newBuildMeta("dev", "", ""))
+		root.SetOut(out)
+		root.SetArgs(args)
+		if err := root.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		if args[1] == "list" && (!strings.Contains(out.String(), "gpt-4o-mini") || !strings.Contains(out.String(), "telegram-1")) {
+			t.Errorf("unexpected list output:\n%s", out.String())
+		}
+	}
+}
+
+func TestRootCommand_Jobs_ShouldCallGatewayJobsAPI(t *testing.T) {
+	var gotAuth, gotPath string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth, gotPath = r.Header.Get("Authorization"), r.Method+" "+r.URL.Path
+		w.Write([]byte(`{"jobId":"digest","response":"all good"}`))
+	}))
+	defer ts.Close()
+
+	out := &bytes.Buffer{}
+	root := newRootCommand(newBuildMeta("dev", "", ""))
+	root.SetOut(out)
+	root.SetArgs([]string{"jobs", "run-now", "digest", "--url", ts.URL, "--token", "tok"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if gotPath != "POST /api/jobs/digest/run" || gotAuth != "Bearer tok" {
+		t.Errorf("unexpected request %q auth %q", gotPath, gotAuth)
+	}
+	if !strings.Contains(out.String(), "all good") {
+		t.Errorf("expected response printed, got %q", out.String())
+	}
+}
+
+func TestRootCommand_UsageReport_ShouldReadConfiguredDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbURL := "file:" + filepath.Join(dir, "data", "usage.db")
+	store, closeStore, err := usage.OpenStore(dbURL)
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	_ = store.Add(context.Background(), usage.Record{Time: time.Now(), Channel: "telegram-1", Model: "gpt-4o", InputTokens: 42, Cost: 1.5})
+	closeStore()
+
+	cfgPath := filepath.Join(dir, "ironclaw.json")
+	if err := os.WriteFile(cfgPath, []byte(fmt.Sprintf(`{"usage":{"dbUrl":%q}}`, dbURL)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IRONCLAW_CONFIG", cfgPath)
+
+	out := &bytes.Buffer{}
+	root := newRootCommand(newBuildMeta("dev", "", ""))
+	root.SetOut(out)
+	root.SetArgs([]string{"usage", "report", "--by", "channel"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out.String(), "telegram-1") || !strings.Contains(out.String(), "$1.5000") {
+		t.Errorf("unexpected report:\n%s", out.String())
+	}
+}
+
+func TestRootCommand_MemoryReindex_ShouldIndexStoredMemories(t *testing.T) {
+	dir := t.TempDir()
+	dbURL := "file:" + filepath.Join(dir, "data", "memories.db")
+	cfg := domain.RetrievalConfig{DBURL: dbURL}
+	store, closeStore, err := vectorstore.OpenStore(cfg, "")
+	if err != nil {
+		t.Fatalf("OpenStore: %v", err)
+	}
+	_ = store.Store(context.Background(), "likes tea", []float64{1, 0})
+	_ = store.Store(context.Background(), "likes jazz", []float64{0, 1})
+	closeStore()
+
+	cfgPath := filepath.Join(dir, "ironclaw.json")
+	if err := os.WriteFile(cfgPath, []byte(fmt.Sprintf(`{"retrieval":{"dbUrl":%q,"index":{}}}`, dbURL)), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IRONCLAW_CONFIG", cfgPath)
+
+	out := &bytes.Buffer{}
+	root := newRootCommand(newBuildMeta("dev", "", ""))
+	root.SetOut(out)
+	root.SetArgs([]string{"memory", "reindex"})
+	if err := root.Execute(); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if !strings.Contains(out.String(), "Indexed 2 memories") {
+		t.Errorf("unexpected output %q", out.String())
+	}
+	if _, err := os.Stat(filepath.Join(dir, "data", "memories.db.hnsw")); err != nil {
+		t.Errorf("index not saved: %v", err)
+	}
+}
+
+func TestRootCommand_MemoryReindex_WhenIndexNotConfigured_ShouldFail(t *testing.T) {
+	cfgPath := filepath.Join(t.TempDir(), "ironclaw.json")
+	if err := os.WriteFile(cfgPath, []byte(`{}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("IRONCLAW_CONFIG", cfgPath)
+
+	errOut := &bytes.Buffer{}
+	root := newRootCommand(newBuildMeta("dev", "", ""))
+	root.SetErr(errOut)
+	root.SetArgs([]string{"memory", "reindex"})
+	if err := root.Execute(); err == nil {
+		t.Fatal("expected error")
+	}
+	if !strings.Contains(errOut.String(), "retrieval.index is not configured") {
+		t.Errorf("unexpected stderr %q", errOut.String())
+	}
+}
+
+func TestNewResponseCache_ShouldOpenOnlyWhenEnabled(t *testing.T) {
+	cfg := &domain.Config{Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: t.TempDir()}}}
+	if c, closeFn := newResponseCache(cfg); c != nil {
+		closeFn()
+		t.Fatal("disabled cache should not be opened")
+	}
+
+	cfg.Cache.Enabled = true
+	c, closeFn := newResponseCache(cfg)
+	defer closeFn()
+	if c == nil {
+		t.Fatal("enabled cache not opened")
+	}
+
+	cfg.Cache.TTL = "soon"
+	if c, _ := newResponseCache(cfg); c != nil {
+		t.Error("invalid cache config should leave responses uncached")
+	}
+}
+
+func TestNewRetriever_ShouldOpenOnlyWhenEnabled(t *testing.T) {
+	cfg := &domain.Config{Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Memory: t.TempDir()}}}
+	if r, closeFn := newRetriever(cfg); r != nil {
+		closeFn()
+		t.Fatal("disabled retrieval should not be opened")
+	}
+
+	cfg.Retrieval.Enabled = true
+	r, closeFn := newRetriever(cfg)
+	defer closeFn()
+	if r == nil {
+		t.Fatal("enabled retrieval not opened")
+	}
+
+	cfg.Retrieval.MinScore = 2
+	if r, _ := newRetriever(cfg); r != nil {
+		t.Error("invalid retrieval config should leave memories unretrieved")
+	}
+}
+
+func TestImageProvider_WhenNamedProvider_ShouldUseItsType(t *testing.T) {
+	agents := domain.AgentsConfig{Providers: map[string]domain.ProviderConfig{"lan": {Type: domain.ProviderTypeOpenAICompatible}}}
+	if got := imageProvider(agents, "lan"); got != domain.ProviderTypeOpenAICompatible {
+		t.Errorf("imageProvider(lan) = %q", got)
+	}
+	if got := imageProvider(agents, "anthropic"); got != "anthropic" {
+		t.Errorf("imageProvider(anthropic) = %q", got)
+	}
+}
+
+func TestNewChatBrain_ShouldCountTokensForDefaultModel(t *testing.T) {
+	old := newTokenizerFn
+	defer func() { newTokenizerFn = old }()
+	var model string
+	newTokenizerFn = func(m string) (domain.Tokenizer, tokenizer.ModelInfo) {
+		model = m
+		return tokenizer.NewEstimator(nil, 1), tokenizer.ModelInfo{Factor: 1, ContextWindow: 128000}
+	}
+
+	cfg := &domain.Config{}
+	agents := domain.AgentsConfig{Provider: "local", DefaultModel: "smart", Models: map[string]domain.ModelProfile{"smart": {Model: "gpt-4o"}}}
+	if _, err := newChatBrain(cfg, agents, func(string) (string, error) { return "", nil }, nil, nil, nil); err != nil {
+		t.Fatalf("newChatBrain: %v", err)
+	}
+	if model != "gpt-4o" {
+		t.Errorf("tokenizer built for %q, want the default profile's model", model)
+	}
+}
+
+// staticSecrets is a secrets.SecretsManager answering every key with "".
+type staticSecrets struct{}
+
+func (staticSecrets) Get(string) (string, error) { return "", nil }
+func (staticSecrets) Set(string, string) error   { return nil }
+func (staticSecrets) Delete(string) error        { return nil }
+
+func TestNewToolRegistry_ShouldRegisterToolsTheConfigAllows(t *testing.T) {
+	orig := newContainerRuntimeFn
+	defer func() { newContainerRuntimeFn = orig }()
+	newContainerRuntimeFn = func() (tooling.ContainerRuntime, error) { return nil, errors.New("no docker") }
+
+	names := func(reg *tooling.ToolRegistry) map[string]bool {
+		out := map[string]bool{}
+		for _, tool := range reg.List() {
+			out[tool.Name()] = true
+		}
+		return out
+	}
+
+	got := names(newToolRegistry(&domain.Config{}, nil))
+	for _, name := range []string{"shell", "scrape", "image", "iot"} {
+		if !got[name] {
+			t.Errorf("%s not registered: %v", name, got)
+		}
+	}
+	for _, name := range []string{"docker_sandbox", "filesystem", "git"} {
+		if got[name] {
+			t.Errorf("%s registered without its dependency", name)
+		}
+	}
+
+	cfg := &domain.Config{Agents: domain.AgentsConfig{Paths: domain.AgentPaths{Root: t.TempDir()}}}
+	got = names(newToolRegistry(cfg, staticSecrets{}))
+	if !got["filesystem"] || !got["git"] {
+		t.Errorf("filesystem and git should be registered with a workspace and secrets: %v", got)
+	}
+}
+
+// toolsOfferedServer is an OpenAI-compatible endpoint answering "ok" and
+// recording the names of the tools offered with each request.
+func toolsOfferedServer(t *testing.T) (*httptest.Server, *[][]string) {
+	t.Helper()
+	var offered [][]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Tools []struct {
+				Function struct {
+					Name string `json:"name"`
+				} `json:"function"`
+			} `json:"tools"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		var names []string
+		for _, tool := range req.Tools {
+			names = append(names, tool.Function.Name)
+		}
+		offered = append(offered, names)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"ok"},"finish_reason":"stop"}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &offered
+}
+
+func TestNewAgentResolver_ShouldLimitAgentToolsToItsAndTheChannelsRules(t *testing.T) {
+	srv, offered := toolsOfferedServer(t)
+	root := t.TempDir()
+	if _, err := agent.Create(root, "ops", agent.Profile{Provider: "fake", Tools: domain.ToolRule{Deny: []string{"scrape"}}}); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &domain.Config{
+		Agents: domain.AgentsConfig{
+			Paths:     domain.AgentPaths{Root: root},
+			Bindings:  map[string]string{"telegram-*": "ops"},
+			Providers: map[string]domain.ProviderConfig{"fake": {Type: domain.ProviderTypeOpenAICompatible, BaseURL: srv.URL, Tools: true}},
+		},
+		Tools:   domain.ToolsConfig{Channels: map[string]domain.ToolRule{"telegram-1": {Deny: []string{"image"}}}},
+		Gateway: domain.GatewayConfig{Auth: domain.AuthConfig{ExternalChannels: []string{"telegram"}}},
+	}
+	reg := tooling.NewToolRegistry()
+	for _, tool := range []tooling.SchemaTool{tooling.NewShellTool(cfg, nil), tooling.NewScrapeTool(nil), tooling.NewImageTool(nil), tooling.NewIoTTool(nil, nil)} {
+		_ = reg.Register(tool)
+	}
+	tools := brain.WithTools(brain.NewToolDispatcher(reg))
+
+	resolve := newAgentResolver(cfg, func(string) (string, error) { return "", nil }, nil, nil, nil, tools, brain.WithChannelToolPolicy(channelToolPolicy(cfg)))
+	a, ok := resolve("telegram-1")
+	if !ok {
+		t.Fatal("expected the ops agent")
+	}
+	runner, ok := a.Brain.(router.Runner)
+	if !ok {
+		t.Fatalf("agent brain %T does not run the tool loop", a.Brain)
+	}
+	if _, _, err := runner.RunMessages(context.Background(), "telegram-1", []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}, "", nil); err != nil {
+		t.Fatalf("RunMessages: %v", err)
+	}
+	// scrape is denied by the agent, image by the channel, shell and iot
+	// by the external rule.
+	if len(*offered) != 1 || len((*offered)[0]) != 0 {
+		t.Errorf("expected no tools offered, got %v", *offered)
+	}
+
+	cfg.Gateway.Auth.ExternalChannels = nil
+	if _, _, err := runner.RunMessages(context.Background(), "telegram-1", []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}, "", nil); err != nil {
+		t.Fatalf("RunMessages: %v", err)
+	}
+	if got := (*offered)[1]; len(got) != 2 || !slices.Contains(got, "shell") || !slices.Contains(got, "iot") {
+		t.Errorf("expected shell and iot offered, got %v", got)
+	}
+}
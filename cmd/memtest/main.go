@@ -21,11 +21,10 @@ func main() {
 	os.MkdirAll(dir, 0755)
 	defer os.RemoveAll(dir)
 
-	store := memory.NewFileMemoryStore(dir)
-	store.Remember("My favorite color is blue.")
-
-	b := brain.NewBrain(&echoProvider{}, brain.WithMemory(store))
-	result, _ := b.Generate(context.Background(), "What is my favorite color?")
+	b := brain.NewBrain(&echoProvider{}, brain.WithMemory(memory.NewFileMemoryStore(dir)))
+	ctx := context.Background()
+	b.Remember(ctx, "My favorite color is blue.")
+	result, _ := b.Generate(ctx, "What is my favorite color?")
 	fmt.Println("=== Prompt sent to LLM ===")
 	fmt.Println(result)
 }
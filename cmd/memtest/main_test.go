@@ -55,7 +55,7 @@ func TestMain_ShouldRunWithoutError(t *testing.T) {
 	if !strings.Contains(output, "Prompt sent to LLM") {
 		t.Errorf("Expected header in output, got: %s", output)
 	}
-	if !strings.Contains(output, "favorite color") {
+	if !strings.Contains(output, "My favorite color is blue.") {
 		t.Errorf("Expected memory context in output, got: %s", output)
 	}
 }
@@ -39,7 +39,7 @@ func run(w io.Writer, shutdownCh <-chan struct{}) int {
 	logger := slog.New(slog.NewTextHandler(w, &slog.HandlerOptions{Level: slog.LevelDebug}))
 
 	engine := newEngine()
-	handler := func(ctx context.Context, job scheduler.Job) error {
+	handler := func(ctx context.Context, job scheduler.Job) (string, error) {
 		logger.Info("SYSTEM EVENT received",
 			"job_id", job.ID,
 			"job_name", job.Name,
@@ -47,7 +47,7 @@ func run(w io.Writer, shutdownCh <-chan struct{}) int {
 			"time", time.Now().Format(time.RFC3339),
 		)
 		fmt.Fprintf(w, "\n  >>> [System Event: %s] %s\n\n", job.Name, job.Prompt)
-		return nil
+		return "", nil
 	}
 
 	sched := scheduler.NewScheduler(engine, handler, scheduler.WithLogger(logger))
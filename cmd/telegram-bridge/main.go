@@ -10,13 +10,21 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"ironclaw/internal/agent"
+	"ironclaw/internal/approval"
 	"ironclaw/internal/brain"
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
 	"ironclaw/internal/config"
+	"ironclaw/internal/domain"
 	"ironclaw/internal/llm"
 	"ironclaw/internal/memory"
+	"ironclaw/internal/ratelimit"
 	"ironclaw/internal/router"
 	"ironclaw/internal/secrets"
 	"ironclaw/internal/telegram"
+	"ironclaw/internal/tooling"
+	"ironclaw/internal/usage"
 )
 
 // exitFunc is the function used by main to exit; tests replace it to cover main().
@@ -47,6 +55,12 @@ var signalContextFn = func() (context.Context, context.CancelFunc) {
 // secretsManagerFn returns a secrets manager; tests replace it.
 var secretsManagerFn = secrets.DefaultManager
 
+// openUsageMeterFn opens the usage meter; tests replace it.
+var openUsageMeterFn = usage.Open
+
+// openCacheFn opens the response cache; tests replace it.
+var openCacheFn = cache.Open
+
 func run() error {
 	// 1. Load Telegram bot token from secrets store or environment.
 	token, err := loadToken()
@@ -61,16 +75,21 @@ func run() error {
 	}
 
 	// 3. Load config and build the brain.
-	chatBrain, err := buildBrain()
+	chatBrain, routerOpts, err := buildBrain()
 	if err != nil {
 		return fmt.Errorf("brain setup: %w", err)
 	}
 
-	// 4. Create router wrapping the brain.
-	rt := router.NewRouter(chatBrain, nil)
+	// 4. Create router wrapping the brain and any channel-bound agents.
+	rt := router.NewRouter(chatBrain, nil, routerOpts...)
 
-	// 5. Create and start the Telegram adapter.
-	adapter := telegram.NewAdapter(bot, rt)
+	// 5. Create and start the Telegram adapter. Photos are attached to the
+	// user message when the bot can resolve file downloads.
+	opts := []telegram.Option{telegram.WithApprovals(chatBrain.Approvals())}
+	if files, ok := bot.(telegram.FileURLGetter); ok {
+		opts = append(opts, telegram.WithPhotos(files, &tooling.RealImageProcessor{}))
+	}
+	adapter := telegram.NewAdapter(bot, rt, opts...)
 
 	ctx, cancel := signalContextFn()
 	defer cancel()
@@ -100,37 +119,86 @@ func loadToken() (string, error) {
 	return token, nil
 }
 
-// buildBrain creates a Brain from ironclaw config + secrets (same as the daemon).
-func buildBrain() (*brain.Brain, error) {
+// buildBrain creates a Brain from ironclaw config + secrets (same as the daemon),
+// plus router options binding channels to the named agents under
+// agents.paths.root. Agent problems are logged, not fatal.
+func buildBrain() (*brain.Brain, []router.Option, error) {
 	cfgPath := os.Getenv("IRONCLAW_CONFIG")
 	if cfgPath == "" {
 		cfgPath = "ironclaw.json"
 	}
 	cfg, err := config.Load(cfgPath)
 	if err != nil {
-		return nil, fmt.Errorf("config load (%s): %w", cfgPath, err)
+		return nil, nil, fmt.Errorf("config load (%s): %w", cfgPath, err)
 	}
 
 	sm, err := secretsManagerFn()
 	if err != nil {
-		return nil, fmt.Errorf("secrets manager: %w", err)
+		return nil, nil, fmt.Errorf("secrets manager: %w", err)
+	}
+
+	gate, err := approval.NewGateFromConfig(cfg.Approval, cfg.Agents.Paths.Memory)
+	if err != nil {
+		log.Printf("approvals: %v (using defaults)", err)
+		gate = approval.NewGate()
+	}
+	opts := []brain.Option{brain.WithApprovals(gate)}
+	// The store stays open for the life of the bridge.
+	if meter, _, err := openUsageMeterFn(cfg.Usage, cfg.Agents.Paths.Memory); err != nil {
+		log.Printf("usage: %v (usage not recorded)", err)
+	} else {
+		opts = append(opts, brain.WithUsage(meter))
+	}
+	breakers := breaker.NewSet(breaker.FromConfig(cfg.CircuitBreaker))
+	limits := ratelimit.NewSet(cfg.RateLimits)
+	var responses *cache.Cache
+	if cfg.Cache.Enabled {
+		// Like the usage store, the cache stays open for the life of the bridge.
+		if responses, _, err = openCacheFn(cfg.Cache, cfg.Agents.Paths.Memory); err != nil {
+			log.Printf("cache: %v (responses not cached)", err)
+		}
+	}
+	b, err := newBrain(cfg, cfg.Agents, sm.Get, breakers, limits, responses, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("llm provider: %w", err)
 	}
 
-	provider, err := llm.NewProvider(&cfg.Agents, sm.Get, &cfg.Retry)
+	reg, err := agent.LoadRegistry(cfg.Agents.Paths.Root)
+	if err == nil {
+		err = reg.Bind(cfg.Agents.Bindings, cfg.Agents.DefaultAgent)
+	}
 	if err != nil {
-		return nil, fmt.Errorf("llm provider: %w", err)
+		log.Printf("agents: %v", err)
+		return b, nil, nil
 	}
+	if len(reg.List()) == 0 {
+		return b, nil, nil
+	}
+	resolve := reg.Resolver(func(a *agent.Agent) (router.Generator, error) {
+		return newBrain(cfg, a.Settings(cfg.Agents), sm.Get, breakers, limits, responses, opts...)
+	}, func(a *agent.Agent, err error) {
+		log.Printf("agent %s: %v (skipped)", a.Name, err)
+	})
+	return b, []router.Option{router.WithAgents(resolve)}, nil
+}
 
-	var opts []brain.Option
-	if cfg.Agents.Paths.Memory != "" {
-		memStore := memory.NewFileMemoryStore(cfg.Agents.Paths.Memory)
+// newBrain builds a brain for agents (the global agents config or a named
+// agent's settings), its providers guarded by their circuits in breakers and
+// their rate limits in limits and their responses cached in responses (nil
+// for none); extra options (e.g. the shared approval gate) are appended.
+func newBrain(cfg *domain.Config, agents domain.AgentsConfig, getSecret llm.SecretGetter, breakers *breaker.Set, limits *ratelimit.Set, responses *cache.Cache, extra ...brain.Option) (*brain.Brain, error) {
+	provider, fallbacks, err := llm.NewGuardedProviders(&agents, getSecret, breakers, limits, responses, &cfg.Retry)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append([]brain.Option(nil), extra...)
+	if agents.Paths.Memory != "" {
+		memStore := memory.NewFileMemoryStore(agents.Paths.Memory)
 		opts = append(opts, brain.WithMemory(memStore))
 	}
-	if len(cfg.Agents.Fallbacks) > 0 {
-		fallbacks := llm.NewFallbackProviders(cfg.Agents.Fallbacks, sm.Get, &cfg.Retry)
-		if len(fallbacks) > 0 {
-			opts = append(opts, brain.WithFallbacks(fallbacks...))
-		}
+	if len(fallbacks) > 0 {
+		opts = append(opts, brain.WithFallbacks(fallbacks...))
 	}
 
 	return brain.NewBrain(provider, opts...), nil
@@ -11,11 +11,22 @@ import (
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 
+	"ironclaw/internal/agent"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
 	"ironclaw/internal/secrets"
 	"ironclaw/internal/telegram"
+	"ironclaw/internal/usage"
 )
 
+func init() {
+	// Bridge tests run with relative memory paths; keep them from creating
+	// usage.db files in the package directory.
+	openUsageMeterFn = func(domain.UsageConfig, string, ...usage.Option) (*usage.Meter, func() error, error) {
+		return nil, nil, usage.ErrNoDatabase
+	}
+}
+
 // =============================================================================
 // Test Doubles
 // =============================================================================
@@ -218,7 +229,7 @@ func TestLoadToken_WhenSecretNotFound_ShouldReturnError(t *testing.T) {
 func TestBuildBrain_WhenConfigMissing_ShouldReturnError(t *testing.T) {
 	t.Setenv("IRONCLAW_CONFIG", "/nonexistent/ironclaw.json")
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error for missing config")
 	}
@@ -249,7 +260,7 @@ func TestBuildBrain_WhenLocalProvider_ShouldSucceed(t *testing.T) {
 
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -281,7 +292,7 @@ func TestBuildBrain_WhenMemoryPathSet_ShouldConfigureMemory(t *testing.T) {
 
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -311,7 +322,7 @@ func TestBuildBrain_WhenSecretsManagerFails_ShouldReturnError(t *testing.T) {
 		return nil, errors.New("keyring broken")
 	}
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error when secrets manager fails")
 	}
@@ -342,7 +353,7 @@ func TestBuildBrain_WhenProviderNeedsApiKey_ShouldReturnError(t *testing.T) {
 		return &mockSecretsManager{store: map[string]string{}}, nil
 	}
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error when provider needs API key")
 	}
@@ -375,7 +386,7 @@ func TestBuildBrain_WhenFallbacksConfigured_ShouldSucceed(t *testing.T) {
 	os.WriteFile(cfgPath, data, 0644)
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -415,7 +426,7 @@ func TestBuildBrain_WhenFallbacksAllInvalid_ShouldStillSucceed(t *testing.T) {
 	os.WriteFile(cfgPath, data, 0644)
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -433,7 +444,7 @@ func TestBuildBrain_WhenDefaultConfigPath_ShouldUseIronclawJson(t *testing.T) {
 	defer os.Chdir(oldWd)
 	os.Chdir(t.TempDir())
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error for missing default config")
 	}
@@ -540,3 +551,32 @@ func TestRun_WhenAllDepsValid_ShouldStartAndReturn(t *testing.T) {
 		t.Fatalf("unexpected error: %v", err)
 	}
 }
+
+func TestBuildBrain_WhenAgentsBound_ShouldRouteTelegramChannelToAgent(t *testing.T) {
+	dir := t.TempDir()
+	root := filepath.Join(dir, "agents")
+	if _, err := agent.Create(root, "ops", agent.Profile{}); err != nil {
+		t.Fatal(err)
+	}
+	cfgPath := filepath.Join(dir, "test-config.json")
+	cfg := domain.Config{Agents: domain.AgentsConfig{
+		Provider: "local",
+		Paths:    domain.AgentPaths{Root: root},
+		Bindings: map[string]string{"telegram-*": "ops"},
+	}}
+	data, _ := json.MarshalIndent(cfg, "", "  ")
+	os.WriteFile(cfgPath, data, 0644)
+	t.Setenv("IRONCLAW_CONFIG", cfgPath)
+
+	b, opts, err := buildBrain()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	rt := router.NewRouter(b, nil, opts...)
+	if _, err := rt.Route(context.Background(), telegram.ChatIDToChannelID(7), "hi"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if ch, _ := rt.GetChannel("telegram-7"); ch.Agent != "ops" {
+		t.Errorf("expected telegram-7 bound to ops, got %q", ch.Agent)
+	}
+}
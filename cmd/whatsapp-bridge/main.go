@@ -8,12 +8,20 @@ import (
 	"os/signal"
 	"syscall"
 
+	"ironclaw/internal/agent"
+	"ironclaw/internal/approval"
 	"ironclaw/internal/brain"
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
 	"ironclaw/internal/config"
+	"ironclaw/internal/domain"
 	"ironclaw/internal/llm"
 	"ironclaw/internal/memory"
+	"ironclaw/internal/ratelimit"
 	"ironclaw/internal/router"
 	"ironclaw/internal/secrets"
+	"ironclaw/internal/tooling"
+	"ironclaw/internal/usage"
 	wa "ironclaw/internal/whatsapp"
 
 	qrterminal "github.com/mdp/qrterminal/v3"
@@ -47,6 +55,12 @@ var signalContextFn = func() (context.Context, context.CancelFunc) {
 // secretsManagerFn returns a secrets manager; tests replace it.
 var secretsManagerFn = secrets.DefaultManager
 
+// openUsageMeterFn opens the usage meter; tests replace it.
+var openUsageMeterFn = usage.Open
+
+// openCacheFn opens the response cache; tests replace it.
+var openCacheFn = cache.Open
+
 // qrHandlerFn creates the QR code handler; tests replace it.
 var qrHandlerFn = func() wa.QRHandler {
 	return func(code string) {
@@ -70,19 +84,19 @@ func run() error {
 	}
 
 	// 3. Load config and build the brain.
-	chatBrain, err := buildBrain()
+	chatBrain, routerOpts, err := buildBrain()
 	if err != nil {
 		return fmt.Errorf("brain setup: %w", err)
 	}
 
-	// 4. Create router wrapping the brain.
-	rt := router.NewRouter(chatBrain, nil)
+	// 4. Create router wrapping the brain and any channel-bound agents.
+	rt := router.NewRouter(chatBrain, nil, routerOpts...)
 
 	// 5. Create QR handler for terminal display.
 	qrHandler := qrHandlerFn()
 
 	// 6. Create and start the WhatsApp adapter.
-	adapter := wa.NewAdapter(client, rt, qrHandler)
+	adapter := wa.NewAdapter(client, rt, qrHandler, wa.WithApprovals(chatBrain.Approvals()), wa.WithImages(&tooling.RealImageProcessor{}))
 
 	ctx, cancel := signalContextFn()
 	defer cancel()
@@ -95,37 +109,86 @@ func run() error {
 	return nil
 }
 
-// buildBrain creates a Brain from ironclaw config + secrets (same as the daemon).
-func buildBrain() (*brain.Brain, error) {
+// buildBrain creates a Brain from ironclaw config + secrets (same as the daemon),
+// plus router options binding channels to the named agents under
+// agents.paths.root. Agent problems are logged, not fatal.
+func buildBrain() (*brain.Brain, []router.Option, error) {
 	cfgPath := os.Getenv("IRONCLAW_CONFIG")
 	if cfgPath == "" {
 		cfgPath = "ironclaw.json"
 	}
 	cfg, err := config.Load(cfgPath)
 	if err != nil {
-		return nil, fmt.Errorf("config load (%s): %w", cfgPath, err)
+		return nil, nil, fmt.Errorf("config load (%s): %w", cfgPath, err)
 	}
 
 	sm, err := secretsManagerFn()
 	if err != nil {
-		return nil, fmt.Errorf("secrets manager: %w", err)
+		return nil, nil, fmt.Errorf("secrets manager: %w", err)
 	}
 
-	provider, err := llm.NewProvider(&cfg.Agents, sm.Get, &cfg.Retry)
+	gate, err := approval.NewGateFromConfig(cfg.Approval, cfg.Agents.Paths.Memory)
 	if err != nil {
-		return nil, fmt.Errorf("llm provider: %w", err)
+		log.Printf("approvals: %v (using defaults)", err)
+		gate = approval.NewGate()
+	}
+	opts := []brain.Option{brain.WithApprovals(gate)}
+	// The store stays open for the life of the bridge.
+	if meter, _, err := openUsageMeterFn(cfg.Usage, cfg.Agents.Paths.Memory); err != nil {
+		log.Printf("usage: %v (usage not recorded)", err)
+	} else {
+		opts = append(opts, brain.WithUsage(meter))
+	}
+	breakers := breaker.NewSet(breaker.FromConfig(cfg.CircuitBreaker))
+	limits := ratelimit.NewSet(cfg.RateLimits)
+	var responses *cache.Cache
+	if cfg.Cache.Enabled {
+		// Like the usage store, the cache stays open for the life of the bridge.
+		if responses, _, err = openCacheFn(cfg.Cache, cfg.Agents.Paths.Memory); err != nil {
+			log.Printf("cache: %v (responses not cached)", err)
+		}
+	}
+	b, err := newBrain(cfg, cfg.Agents, sm.Get, breakers, limits, responses, opts...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("llm provider: %w", err)
 	}
 
-	var opts []brain.Option
-	if cfg.Agents.Paths.Memory != "" {
-		memStore := memory.NewFileMemoryStore(cfg.Agents.Paths.Memory)
+	reg, err := agent.LoadRegistry(cfg.Agents.Paths.Root)
+	if err == nil {
+		err = reg.Bind(cfg.Agents.Bindings, cfg.Agents.DefaultAgent)
+	}
+	if err != nil {
+		log.Printf("agents: %v", err)
+		return b, nil, nil
+	}
+	if len(reg.List()) == 0 {
+		return b, nil, nil
+	}
+	resolve := reg.Resolver(func(a *agent.Agent) (router.Generator, error) {
+		return newBrain(cfg, a.Settings(cfg.Agents), sm.Get, breakers, limits, responses, opts...)
+	}, func(a *agent.Agent, err error) {
+		log.Printf("agent %s: %v (skipped)", a.Name, err)
+	})
+	return b, []router.Option{router.WithAgents(resolve)}, nil
+}
+
+// newBrain builds a brain for agents (the global agents config or a named
+// agent's settings), its providers guarded by their circuits in breakers and
+// their rate limits in limits and their responses cached in responses (nil
+// for none); extra options (e.g. the shared approval gate) are appended.
+func newBrain(cfg *domain.Config, agents domain.AgentsConfig, getSecret llm.SecretGetter, breakers *breaker.Set, limits *ratelimit.Set, responses *cache.Cache, extra ...brain.Option) (*brain.Brain, error) {
+	provider, fallbacks, err := llm.NewGuardedProviders(&agents, getSecret, breakers, limits, responses, &cfg.Retry)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := append([]brain.Option(nil), extra...)
+	if agents.Paths.Memory != "" {
+		memStore := memory.NewFileMemoryStore(agents.Paths.Memory)
 		opts = append(opts, brain.WithMemory(memStore))
 	}
-	if len(cfg.Agents.Fallbacks) > 0 {
-		fallbacks := llm.NewFallbackProviders(cfg.Agents.Fallbacks, sm.Get, &cfg.Retry)
-		if len(fallbacks) > 0 {
-			opts = append(opts, brain.WithFallbacks(fallbacks...))
-		}
+	if len(fallbacks) > 0 {
+		opts = append(opts, brain.WithFallbacks(fallbacks...))
 	}
 
 	return brain.NewBrain(provider, opts...), nil
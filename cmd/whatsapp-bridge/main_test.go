@@ -16,8 +16,17 @@ import (
 	"ironclaw/internal/domain"
 	"ironclaw/internal/secrets"
 	wa "ironclaw/internal/whatsapp"
+	"ironclaw/internal/usage"
 )
 
+func init() {
+	// Bridge tests run with relative memory paths; keep them from creating
+	// usage.db files in the package directory.
+	openUsageMeterFn = func(domain.UsageConfig, string, ...usage.Option) (*usage.Meter, func() error, error) {
+		return nil, nil, usage.ErrNoDatabase
+	}
+}
+
 // =============================================================================
 // Test Doubles
 // =============================================================================
@@ -291,7 +300,7 @@ func TestMain_WhenRunFails_ShouldCallExitWithOne(t *testing.T) {
 func TestBuildBrain_WhenConfigMissing_ShouldReturnError(t *testing.T) {
 	t.Setenv("IRONCLAW_CONFIG", "/nonexistent/ironclaw.json")
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error for missing config")
 	}
@@ -321,7 +330,7 @@ func TestBuildBrain_WhenLocalProvider_ShouldSucceed(t *testing.T) {
 
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -353,7 +362,7 @@ func TestBuildBrain_WhenMemoryPathSet_ShouldConfigureMemory(t *testing.T) {
 
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -383,7 +392,7 @@ func TestBuildBrain_WhenSecretsManagerFails_ShouldReturnError(t *testing.T) {
 		return nil, errors.New("keyring broken")
 	}
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error when secrets manager fails")
 	}
@@ -413,7 +422,7 @@ func TestBuildBrain_WhenProviderNeedsApiKey_ShouldReturnError(t *testing.T) {
 		return &mockSecretsManager{store: map[string]string{}}, nil
 	}
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error when provider needs API key")
 	}
@@ -446,7 +455,7 @@ func TestBuildBrain_WhenFallbacksConfigured_ShouldSucceed(t *testing.T) {
 	os.WriteFile(cfgPath, data, 0644)
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -486,7 +495,7 @@ func TestBuildBrain_WhenFallbacksAllInvalid_ShouldStillSucceed(t *testing.T) {
 	os.WriteFile(cfgPath, data, 0644)
 	t.Setenv("IRONCLAW_CONFIG", cfgPath)
 
-	b, err := buildBrain()
+	b, _, err := buildBrain()
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -502,7 +511,7 @@ func TestBuildBrain_WhenDefaultConfigPath_ShouldUseIronclawJson(t *testing.T) {
 	defer os.Chdir(oldWd)
 	os.Chdir(t.TempDir())
 
-	_, err := buildBrain()
+	_, _, err := buildBrain()
 	if err == nil {
 		t.Fatal("expected error for missing default config")
 	}
@@ -31,8 +31,7 @@ func LoadAgentContext(root string) (*domain.AgentContext, error) {
 		ctx.Identity = strings.TrimSpace(string(b))
 	}
 	if b, err := os.ReadFile(filepath.Join(root, "AGENTS.md")); err == nil {
-		_ = b
-		// AGENTS.md is high-level directives; could be merged into Soul or kept separate. Leave Soul as primary.
+		ctx.Directives = strings.TrimSpace(string(b))
 	}
 	if b, err := os.ReadFile(filepath.Join(root, "TOOLS.md")); err == nil {
 		ctx.Tools = parseToolNames(string(b))
@@ -41,13 +40,14 @@ func LoadAgentContext(root string) (*domain.AgentContext, error) {
 	return ctx, nil
 }
 
-// parseToolNames extracts tool names from TOOLS.md (lines like "- name" or "name").
+// parseToolNames extracts tool names from TOOLS.md (lines like "- name" or
+// "name"). Markdown headings ("# Tools") are skipped.
 func parseToolNames(content string) []string {
 	var names []string
 	sc := bufio.NewScanner(strings.NewReader(content))
 	for sc.Scan() {
 		line := strings.TrimSpace(sc.Text())
-		if line == "" {
+		if line == "" || strings.HasPrefix(line, "#") {
 			continue
 		}
 		if strings.HasPrefix(line, "- ") {
@@ -59,3 +59,26 @@ func parseToolNames(content string) []string {
 	}
 	return names
 }
+
+// BuildSystemPrompt renders an AgentContext as a system prompt: identity first,
+// then soul, then directives, then the enabled tool list. Empty sections are omitted; a nil
+// context yields "".
+func BuildSystemPrompt(ac *domain.AgentContext) string {
+	if ac == nil {
+		return ""
+	}
+	var parts []string
+	if ac.Identity != "" {
+		parts = append(parts, ac.Identity)
+	}
+	if ac.Soul != "" {
+		parts = append(parts, ac.Soul)
+	}
+	if ac.Directives != "" {
+		parts = append(parts, ac.Directives)
+	}
+	if len(ac.Tools) > 0 {
+		parts = append(parts, "Available tools: "+strings.Join(ac.Tools, ", "))
+	}
+	return strings.Join(parts, "\n\n")
+}
@@ -4,6 +4,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+
+	"ironclaw/internal/domain"
 )
 
 func TestLoadAgentContext_WhenRootDoesNotExist_ShouldReturnError(t *testing.T) {
@@ -89,17 +91,17 @@ func TestLoadAgentContext_WhenTOOLSMdHasBlankLines_ShouldSkipThem(t *testing.T)
 	}
 }
 
-func TestLoadAgentContext_WhenAGENTSMdExists_ShouldNotError(t *testing.T) {
+func TestLoadAgentContext_WhenAGENTSMdExists_ShouldPopulateDirectives(t *testing.T) {
 	root := t.TempDir()
-	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("# Directives"), 0644); err != nil {
+	if err := os.WriteFile(filepath.Join(root, "AGENTS.md"), []byte("# Directives\n"), 0644); err != nil {
 		t.Fatal(err)
 	}
 	ctx, err := LoadAgentContext(root)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if ctx == nil {
-		t.Fatal("expected non-nil context")
+	if ctx.Directives != "# Directives" {
+		t.Errorf("directives: want %q, got %q", "# Directives", ctx.Directives)
 	}
 }
 
@@ -116,3 +118,20 @@ func TestLoadAgentContext_WhenPathContainsTraversal_ShouldNotEscapeRoot(t *testi
 	// LoadAgentContext must clean root internally; reading only from root
 	_ = ctx
 }
+
+func TestBuildSystemPrompt_WhenAllFieldsSet_ShouldJoinSectionsInOrder(t *testing.T) {
+	got := BuildSystemPrompt(&domain.AgentContext{Identity: "I am Iron.", Soul: "Be concise.", Directives: "Never push to main.", Tools: []string{"shell", "web"}})
+	want := "I am Iron.\n\nBe concise.\n\nNever push to main.\n\nAvailable tools: shell, web"
+	if got != want {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestBuildSystemPrompt_WhenNilOrEmpty_ShouldReturnEmpty(t *testing.T) {
+	if got := BuildSystemPrompt(nil); got != "" {
+		t.Errorf("nil context: want empty, got %q", got)
+	}
+	if got := BuildSystemPrompt(&domain.AgentContext{}); got != "" {
+		t.Errorf("empty context: want empty, got %q", got)
+	}
+}
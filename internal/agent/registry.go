@@ -0,0 +1,325 @@
+package agent
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
+	"ironclaw/internal/tooling"
+)
+
+// ProfileFile is the per-agent settings file inside agents/<name>/.
+const ProfileFile = "agent.json"
+
+// Profile holds an agent's overrides of the global agents config. Empty
+// fields inherit the global value.
+type Profile struct {
+	Provider string `json:"provider,omitempty"` // e.g. "anthropic"; empty uses agents.provider
+	Model    string `json:"model,omitempty"`    // Model or alias; empty uses agents.defaultModel
+	Memory   string `json:"memory,omitempty"`   // Memory directory, relative to the agent dir; default "memory"
+	// Tools further restricts the tools the agent may use, on top of TOOLS.md.
+	Tools domain.ToolRule `json:"tools,omitempty"`
+}
+
+// Agent is a named persona loaded from agents/<name>/: its profile plus the
+// SOUL.md, IDENTITY.md, AGENTS.md and TOOLS.md context.
+type Agent struct {
+	Name    string
+	Dir     string
+	Profile Profile
+	Context *domain.AgentContext
+
+	// restrictTools is true when TOOLS.md exists; Context.Tools is then an
+	// allowlist of names or globs. Without TOOLS.md every tool is allowed.
+	restrictTools bool
+}
+
+// ErrAgentNotFound is returned when no agent with the requested name exists.
+var ErrAgentNotFound = errors.New("agent: not found")
+
+// ErrAgentExists is returned by Create when the agent directory already exists.
+var ErrAgentExists = errors.New("agent: already exists")
+
+// ErrInvalidName is returned for agent names that are not usable as a directory name.
+var ErrInvalidName = errors.New("agent: name must be lowercase letters, digits, '-' or '_'")
+
+var validName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// LoadAgent reads the agent in dir. The agent is named after the directory.
+func LoadAgent(dir string) (*Agent, error) {
+	dir = filepath.Clean(dir)
+	ac, err := LoadAgentContext(dir)
+	if err != nil {
+		return nil, err
+	}
+	a := &Agent{Name: filepath.Base(dir), Dir: dir, Context: ac}
+	if b, err := os.ReadFile(filepath.Join(dir, ProfileFile)); err == nil {
+		if err := json.Unmarshal(b, &a.Profile); err != nil {
+			return nil, fmt.Errorf("agent %s: parse %s: %w", a.Name, ProfileFile, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "TOOLS.md")); err == nil {
+		a.restrictTools = true
+	}
+	return a, nil
+}
+
+// SystemPrompt renders the agent's context with BuildSystemPrompt.
+func (a *Agent) SystemPrompt() string {
+	return BuildSystemPrompt(a.Context)
+}
+
+// MemoryDir returns the agent's memory directory (Profile.Memory resolved
+// against the agent dir, default <dir>/memory).
+func (a *Agent) MemoryDir() string {
+	dir := a.Profile.Memory
+	if dir == "" {
+		dir = "memory"
+	}
+	if filepath.IsAbs(dir) {
+		return filepath.Clean(dir)
+	}
+	return filepath.Join(a.Dir, dir)
+}
+
+// HasToolsFile reports whether the agent has a TOOLS.md allowlist.
+func (a *Agent) HasToolsFile() bool {
+	return a.restrictTools
+}
+
+// RestrictsTools reports whether the agent limits its tools, through TOOLS.md
+// or the tools rule in agent.json.
+func (a *Agent) RestrictsTools() bool {
+	return a.restrictTools || len(a.Profile.Tools.Allow) > 0 || len(a.Profile.Tools.Deny) > 0
+}
+
+// ToolPolicy returns the agent's tool policy: the TOOLS.md allowlist (an empty
+// TOOLS.md allows nothing) followed by the agent.json tools rule.
+func (a *Agent) ToolPolicy() tooling.Policy {
+	var p tooling.Policy
+	layer := "agent " + a.Name
+	if a.restrictTools {
+		rule := domain.ToolRule{Allow: a.Context.Tools}
+		if len(rule.Allow) == 0 {
+			rule.Deny = []string{"*"}
+		}
+		p = p.With(layer+" TOOLS.md", rule)
+	}
+	if len(a.Profile.Tools.Allow) > 0 || len(a.Profile.Tools.Deny) > 0 {
+		p = p.With(layer+" "+ProfileFile, a.Profile.Tools)
+	}
+	return p
+}
+
+// AllowsTool reports whether the agent's ToolPolicy allows the named tool.
+func (a *Agent) AllowsTool(name string) bool {
+	return a.ToolPolicy().Allows(name)
+}
+
+// Tools returns the subset of registry the agent is allowed to use.
+func (a *Agent) Tools(registry *tooling.ToolRegistry) *tooling.ToolRegistry {
+	return registry.Filter(a.AllowsTool)
+}
+
+// Settings returns global with the agent's provider and model overrides applied.
+func (a *Agent) Settings(global domain.AgentsConfig) domain.AgentsConfig {
+	if a.Profile.Provider != "" {
+		global.Provider = a.Profile.Provider
+	}
+	if a.Profile.Model != "" {
+		global.DefaultModel = global.ResolveModel(a.Profile.Model)
+	}
+	global.Paths.Memory = a.MemoryDir()
+	return global
+}
+
+// Registry holds the agents of a workspace and the channel bindings that
+// select between them.
+type Registry struct {
+	agents       map[string]*Agent
+	bindings     map[string]string
+	defaultAgent string
+}
+
+// LoadRegistry loads every agent directory under root: each subdirectory that
+// contains agent.json, SOUL.md or IDENTITY.md. A missing root yields an empty
+// registry.
+func LoadRegistry(root string) (*Registry, error) {
+	r := &Registry{agents: make(map[string]*Agent)}
+	entries, err := os.ReadDir(filepath.Clean(root))
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("agent: read %s: %w", root, err)
+	}
+	for _, e := range entries {
+		dir := filepath.Join(root, e.Name())
+		if !e.IsDir() || !isAgentDir(dir) {
+			continue
+		}
+		a, err := LoadAgent(dir)
+		if err != nil {
+			return nil, err
+		}
+		r.agents[a.Name] = a
+	}
+	return r, nil
+}
+
+func isAgentDir(dir string) bool {
+	for _, f := range []string{ProfileFile, "SOUL.md", "IDENTITY.md"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// Bind sets the channel bindings (channel ID or path.Match glob -> agent
+// name) and the default agent for unbound channels. Every named agent must
+// exist.
+func (r *Registry) Bind(bindings map[string]string, defaultAgent string) error {
+	for pattern, name := range bindings {
+		if _, ok := r.agents[name]; !ok {
+			return fmt.Errorf("%w: %q (bound to %q)", ErrAgentNotFound, name, pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("agent: binding %q: %w", pattern, err)
+		}
+	}
+	if defaultAgent != "" {
+		if _, ok := r.agents[defaultAgent]; !ok {
+			return fmt.Errorf("%w: default agent %q", ErrAgentNotFound, defaultAgent)
+		}
+	}
+	r.bindings = bindings
+	r.defaultAgent = defaultAgent
+	return nil
+}
+
+// Get returns the named agent.
+func (r *Registry) Get(name string) (*Agent, bool) {
+	a, ok := r.agents[name]
+	return a, ok
+}
+
+// List returns all agents sorted by name.
+func (r *Registry) List() []*Agent {
+	out := make([]*Agent, 0, len(r.agents))
+	for _, a := range r.agents {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// ForChannel returns the agent bound to channelID: an exact binding first,
+// then the longest matching glob, then the default agent. It returns false
+// when none applies.
+func (r *Registry) ForChannel(channelID string) (*Agent, bool) {
+	if name, ok := r.bindings[channelID]; ok {
+		return r.Get(name)
+	}
+	best := ""
+	for pattern := range r.bindings {
+		if ok, _ := path.Match(pattern, channelID); ok && (len(pattern) > len(best) || len(pattern) == len(best) && pattern < best) {
+			best = pattern
+		}
+	}
+	if best != "" {
+		return r.Get(r.bindings[best])
+	}
+	if r.defaultAgent != "" {
+		return r.Get(r.defaultAgent)
+	}
+	return nil, false
+}
+
+// Resolver builds a brain for every agent with build and returns a
+// router.AgentResolver that binds channels to them via ForChannel. Agents
+// whose brain cannot be built are passed to skip (if non-nil) and left
+// unbound, so their channels fall back to the router's default brain.
+func (r *Registry) Resolver(build func(*Agent) (router.Generator, error), skip func(*Agent, error)) router.AgentResolver {
+	built := make(map[string]router.Agent, len(r.agents))
+	for _, a := range r.List() {
+		b, err := build(a)
+		if err != nil {
+			if skip != nil {
+				skip(a, err)
+			}
+			continue
+		}
+		built[a.Name] = router.Agent{Name: a.Name, Brain: b, SystemPrompt: a.SystemPrompt()}
+	}
+	return func(channelID string) (router.Agent, bool) {
+		a, ok := r.ForChannel(channelID)
+		if !ok {
+			return router.Agent{}, false
+		}
+		ra, ok := built[a.Name]
+		return ra, ok
+	}
+}
+
+// ToolPolicy returns the tool policy for channelID: the policy of the agent
+// bound to it (if any) followed by the channel and external-channel rules of
+// tools and auth.
+func (r *Registry) ToolPolicy(channelID string, tools domain.ToolsConfig, auth domain.AuthConfig) tooling.Policy {
+	var p tooling.Policy
+	if a, ok := r.ForChannel(channelID); ok {
+		p = a.ToolPolicy()
+	}
+	return p.Merge(tooling.ChannelPolicy(tools, auth, channelID))
+}
+
+// Channels returns the binding patterns that select the named agent, sorted.
+func (r *Registry) Channels(name string) []string {
+	var out []string
+	for pattern, n := range r.bindings {
+		if n == name {
+			out = append(out, pattern)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Create scaffolds agents/<name>/ under root with an agent.json holding p and
+// starter IDENTITY.md, SOUL.md and AGENTS.md files. TOOLS.md is not created,
+// so the new agent may use every tool until one is added.
+func Create(root, name string, p Profile) (*Agent, error) {
+	if !validName.MatchString(name) {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidName, name)
+	}
+	dir := filepath.Join(filepath.Clean(root), name)
+	if _, err := os.Stat(dir); err == nil {
+		return nil, fmt.Errorf("%w: %s", ErrAgentExists, dir)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("agent: create %s: %w", dir, err)
+	}
+	profile, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]string{
+		ProfileFile:   string(profile) + "\n",
+		"IDENTITY.md": "You are " + name + ".\n",
+		"SOUL.md":     "# Soul\n\nDescribe " + name + "'s personality and tone here.\n",
+		"AGENTS.md":   "# Directives\n\nList the rules " + name + " must follow here.\n",
+	}
+	for file, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(content), 0o644); err != nil {
+			return nil, fmt.Errorf("agent: write %s: %w", file, err)
+		}
+	}
+	return LoadAgent(dir)
+}
@@ -0,0 +1,200 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
+)
+
+// writeAgent creates root/name with the given files.
+func writeAgent(t *testing.T, root, name string, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for f, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, f), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLoadRegistry_ShouldLoadAgentDirsAndSkipOthers(t *testing.T) {
+	root := t.TempDir()
+	writeAgent(t, root, "ops", map[string]string{ProfileFile: `{"provider":"anthropic","model":"fast"}`, "TOOLS.md": "# Tools\n- shell\n"})
+	writeAgent(t, root, "home", map[string]string{"SOUL.md": "Warm."})
+	writeAgent(t, root, "notes", map[string]string{"README.md": "not an agent"})
+
+	reg, err := LoadRegistry(root)
+	if err != nil {
+		t.Fatalf("LoadRegistry: %v", err)
+	}
+	list := reg.List()
+	if len(list) != 2 || list[0].Name != "home" || list[1].Name != "ops" {
+		t.Fatalf("unexpected agents %v", list)
+	}
+	ops, _ := reg.Get("ops")
+	if ops.Profile.Provider != "anthropic" || !ops.AllowsTool("shell") || ops.AllowsTool("web") {
+		t.Errorf("unexpected ops agent %+v", ops)
+	}
+	home, _ := reg.Get("home")
+	if !home.AllowsTool("web") {
+		t.Error("agent without TOOLS.md should allow every tool")
+	}
+	if home.MemoryDir() != filepath.Join(root, "home", "memory") {
+		t.Errorf("unexpected memory dir %q", home.MemoryDir())
+	}
+}
+
+func TestLoadRegistry_WhenRootMissing_ShouldReturnEmptyRegistry(t *testing.T) {
+	reg, err := LoadRegistry(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(reg.List()) != 0 {
+		t.Errorf("expected empty registry, got %v, %v", reg, err)
+	}
+}
+
+func TestLoadRegistry_WhenProfileInvalid_ShouldReturnError(t *testing.T) {
+	root := t.TempDir()
+	writeAgent(t, root, "bad", map[string]string{ProfileFile: "{"})
+	if _, err := LoadRegistry(root); err == nil {
+		t.Fatal("expected parse error")
+	}
+}
+
+func TestRegistry_ForChannel_ShouldPreferExactThenLongestGlobThenDefault(t *testing.T) {
+	root := t.TempDir()
+	for _, n := range []string{"ops", "home", "coder"} {
+		writeAgent(t, root, n, map[string]string{"SOUL.md": n})
+	}
+	reg, _ := LoadRegistry(root)
+	err := reg.Bind(map[string]string{"telegram-42": "ops", "telegram-*": "home", "*": "coder"}, "home")
+	if err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	cases := map[string]string{"telegram-42": "ops", "telegram-7": "home", "general": "coder"}
+	for channel, want := range cases {
+		if a, ok := reg.ForChannel(channel); !ok || a.Name != want {
+			t.Errorf("%s: want %s, got %v", channel, want, a)
+		}
+	}
+	if got := reg.Channels("home"); len(got) != 1 || got[0] != "telegram-*" {
+		t.Errorf("unexpected channels %v", got)
+	}
+}
+
+func TestRegistry_Bind_WhenAgentUnknown_ShouldReturnErrAgentNotFound(t *testing.T) {
+	reg, _ := LoadRegistry(t.TempDir())
+	if err := reg.Bind(map[string]string{"general": "ghost"}, ""); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("expected ErrAgentNotFound, got %v", err)
+	}
+	if err := reg.Bind(nil, "ghost"); !errors.Is(err, ErrAgentNotFound) {
+		t.Errorf("expected ErrAgentNotFound for default, got %v", err)
+	}
+	if _, ok := reg.ForChannel("general"); ok {
+		t.Error("expected no agent for unbound channel")
+	}
+}
+
+func TestCreate_ShouldScaffoldLoadableAgent(t *testing.T) {
+	root := t.TempDir()
+	a, err := Create(root, "coder", Profile{Model: "gpt-4o"})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if a.Profile.Model != "gpt-4o" || a.Context.Identity == "" || a.Context.Directives == "" {
+		t.Errorf("unexpected agent %+v %+v", a, a.Context)
+	}
+	if _, err := Create(root, "coder", Profile{}); !errors.Is(err, ErrAgentExists) {
+		t.Errorf("expected ErrAgentExists, got %v", err)
+	}
+	if _, err := Create(root, "../escape", Profile{}); !errors.Is(err, ErrInvalidName) {
+		t.Errorf("expected ErrInvalidName, got %v", err)
+	}
+}
+
+func TestAgent_Settings_ShouldApplyProfileOverrides(t *testing.T) {
+	a := &Agent{Name: "ops", Dir: "/agents/ops", Profile: Profile{Provider: "anthropic", Model: "fast"}}
+	global := domain.AgentsConfig{Provider: "openai", DefaultModel: "gpt-4o", ModelAliases: map[string]string{"fast": "claude-haiku"}}
+	got := a.Settings(global)
+	if got.Provider != "anthropic" || got.DefaultModel != "claude-haiku" || got.Paths.Memory != filepath.Join("/agents/ops", "memory") {
+		t.Errorf("unexpected settings %+v", got)
+	}
+}
+
+// echoBrain answers with a fixed reply.
+type echoBrain string
+
+func (e echoBrain) Generate(ctx context.Context, prompt string) (string, error) {
+	return string(e), nil
+}
+
+func TestRegistry_Resolver_ShouldBindBuiltAgentsAndSkipFailures(t *testing.T) {
+	root := t.TempDir()
+	writeAgent(t, root, "ops", map[string]string{"IDENTITY.md": "Ops bot."})
+	writeAgent(t, root, "home", map[string]string{"IDENTITY.md": "Home."})
+	reg, _ := LoadRegistry(root)
+	_ = reg.Bind(map[string]string{"telegram-*": "ops", "general": "home"}, "")
+
+	var skipped []string
+	resolve := reg.Resolver(func(a *Agent) (router.Generator, error) {
+		if a.Name == "home" {
+			return nil, errors.New("no key")
+		}
+		return echoBrain(a.Name), nil
+	}, func(a *Agent, err error) { skipped = append(skipped, a.Name) })
+
+	if a, ok := resolve("telegram-5"); !ok || a.Name != "ops" || a.SystemPrompt != "Ops bot." {
+		t.Errorf("unexpected agent %+v, %v", a, ok)
+	}
+	if _, ok := resolve("general"); ok {
+		t.Error("agent that failed to build should be unbound")
+	}
+	if len(skipped) != 1 || skipped[0] != "home" {
+		t.Errorf("expected home skipped, got %v", skipped)
+	}
+}
+
+func TestAgent_ToolPolicy_ShouldCombineToolsFileAndProfileRule(t *testing.T) {
+	root := t.TempDir()
+	writeAgent(t, root, "ops", map[string]string{
+		ProfileFile: `{"tools":{"deny":["git.push"]}}`,
+		"TOOLS.md":  "# Tools\n- git.*\n- web\n",
+	})
+	writeAgent(t, root, "mute", map[string]string{"SOUL.md": "Quiet.", "TOOLS.md": "# Tools\n"})
+	reg, _ := LoadRegistry(root)
+
+	ops, _ := reg.Get("ops")
+	for tool, want := range map[string]bool{"git.commit": true, "web": true, "git.push": false, "shell": false} {
+		if got := ops.AllowsTool(tool); got != want {
+			t.Errorf("ops %s: want %v, got %v", tool, want, got)
+		}
+	}
+	mute, _ := reg.Get("mute")
+	if mute.AllowsTool("web") || !mute.RestrictsTools() {
+		t.Error("empty TOOLS.md should allow no tools")
+	}
+}
+
+func TestRegistry_ToolPolicy_ShouldStackAgentAndChannelRules(t *testing.T) {
+	root := t.TempDir()
+	writeAgent(t, root, "ops", map[string]string{"SOUL.md": "ops", "TOOLS.md": "- shell\n- web\n"})
+	reg, _ := LoadRegistry(root)
+	if err := reg.Bind(map[string]string{"whatsapp-*": "ops"}, ""); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	auth := domain.AuthConfig{ExternalChannels: []string{"whatsapp"}}
+
+	p := reg.ToolPolicy("whatsapp-1", domain.ToolsConfig{}, auth)
+	if p.Allows("shell") || !p.Allows("web") || p.Allows("calculator") {
+		t.Error("external tier should deny shell on top of the agent allowlist")
+	}
+	if p := reg.ToolPolicy("general", domain.ToolsConfig{}, auth); !p.Allows("shell") {
+		t.Error("unbound internal channel should allow every tool")
+	}
+}
@@ -0,0 +1,402 @@
+// Package approval pauses dangerous tool calls until a human approves them in
+// the channel the request came from.
+package approval
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// DefaultTimeout is how long a request waits for a decision before it is denied.
+const DefaultTimeout = 5 * time.Minute
+
+// DefaultRequire lists the tool calls that need approval when none are configured:
+// shell commands, pushing and opening pull requests, writing files and IoT actions.
+var DefaultRequire = []string{"shell", "git.push", "git.create_pr", "filesystem.write_file", "iot"}
+
+// Sentinel errors returned by Gate.Authorize and Gate.Resolve.
+var (
+	ErrDenied         = errors.New("approval: denied")
+	ErrTimeout        = errors.New("approval: timed out")
+	ErrNoPrompter     = errors.New("approval: channel cannot ask for approval")
+	ErrUnknownRequest = errors.New("approval: no pending request with that ID")
+)
+
+// Decision is a human's answer to a Request.
+type Decision string
+
+const (
+	Approve Decision = "approve" // Run this call once
+	Deny    Decision = "deny"    // Refuse this call
+	Always  Decision = "always"  // Run this call and identical ones in the same channel without asking
+)
+
+// ParseDecision parses "approve", "deny" or "always" and the synonyms
+// "yes"/"y" and "no"/"n" (case-insensitive).
+func ParseDecision(s string) (Decision, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "approve", "yes", "y":
+		return Approve, true
+	case "deny", "no", "n":
+		return Deny, true
+	case "always":
+		return Always, true
+	}
+	return "", false
+}
+
+// Request is a tool call waiting for a decision.
+type Request struct {
+	ID        string          `json:"id"`
+	ChannelID string          `json:"channelId"`
+	Tool      string          `json:"tool"`
+	Operation string          `json:"operation,omitempty"` // The call's "operation" or "action" argument, if any
+	Args      json.RawMessage `json:"args"`
+	Expires   time.Time       `json:"expires"`
+}
+
+// Text renders the request for text-only channels, with the exact arguments
+// and the keywords that answer it.
+func (r Request) Text() string {
+	name := r.Tool
+	if r.Operation != "" {
+		name += "." + r.Operation
+	}
+	var args bytes.Buffer
+	if json.Indent(&args, r.Args, "", "  ") != nil {
+		args.Reset()
+		args.Write(r.Args)
+	}
+	return fmt.Sprintf("Approval needed for %s:\n%s\n\nReply \"approve %s\", \"deny %s\" or \"always %s\" (allow identical calls for this session). Expires at %s.",
+		name, args.String(), r.ID, r.ID, r.ID, r.Expires.Format(time.Kitchen))
+}
+
+// Prompter asks the user of a channel to decide on a request, e.g. by sending
+// a WebSocket message or a Telegram message with buttons. The answer arrives
+// later through Gate.Resolve; Prompt must not wait for it.
+type Prompter interface {
+	Prompt(ctx context.Context, req Request) error
+}
+
+// PrompterFunc adapts a function to the Prompter interface.
+type PrompterFunc func(ctx context.Context, req Request) error
+
+// Prompt implements Prompter.
+func (f PrompterFunc) Prompt(ctx context.Context, req Request) error {
+	return f(ctx, req)
+}
+
+type contextKey struct{}
+
+type channelPrompter struct {
+	channelID string
+	prompter  Prompter
+}
+
+// NewContext returns a context carrying the channel a request is being handled
+// for and the Prompter that reaches its user. Channel adapters set it before
+// routing a message so tool calls made while answering can ask for approval.
+func NewContext(ctx context.Context, channelID string, p Prompter) context.Context {
+	return context.WithValue(ctx, contextKey{}, channelPrompter{channelID: channelID, prompter: p})
+}
+
+// FromContext returns the channel ID and Prompter stored by NewContext.
+func FromContext(ctx context.Context) (channelID string, p Prompter, ok bool) {
+	cp, ok := ctx.Value(contextKey{}).(channelPrompter)
+	if !ok || cp.prompter == nil {
+		return "", nil, false
+	}
+	return cp.channelID, cp.prompter, true
+}
+
+// Option configures a Gate.
+type Option func(*Gate)
+
+// WithRequire sets the tool names or "tool.operation" globs that need
+// approval, replacing DefaultRequire. An empty list disables approvals.
+func WithRequire(patterns ...string) Option {
+	return func(g *Gate) { g.require = patterns }
+}
+
+// WithTimeout sets how long a request waits for a decision. Non-positive
+// values are ignored.
+func WithTimeout(d time.Duration) Option {
+	return func(g *Gate) {
+		if d > 0 {
+			g.timeout = d
+		}
+	}
+}
+
+// WithAuditLog records every request and its outcome to a. If a is nil it is ignored.
+func WithAuditLog(a AuditLog) Option {
+	return func(g *Gate) {
+		if a != nil {
+			g.audit = a
+		}
+	}
+}
+
+// Gate holds tool calls that require approval until the channel's user
+// decides, the request times out or the caller gives up. One Gate is shared
+// by all channels; "always" decisions are remembered per channel for the
+// lifetime of the Gate.
+type Gate struct {
+	require []string
+	timeout time.Duration
+	audit   AuditLog
+
+	// Injectable for tests.
+	newID func() string
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+
+	mu      sync.Mutex
+	pending map[string]*pending
+	always  map[string]bool // allowKey -> true
+}
+
+type pending struct {
+	req      Request
+	decision chan resolution
+}
+
+type resolution struct {
+	decision Decision
+	by       string
+}
+
+// NewGate returns a Gate requiring approval for DefaultRequire with DefaultTimeout.
+func NewGate(opts ...Option) *Gate {
+	g := &Gate{
+		require: DefaultRequire,
+		timeout: DefaultTimeout,
+		audit:   nopAuditLog{},
+		newID:   randomID,
+		now:     time.Now,
+		after:   time.After,
+		pending: make(map[string]*pending),
+		always:  make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// NewGateFromConfig builds a Gate from cfg. The audit log is cfg.AuditLog, or
+// approvals.jsonl in memoryDir when unset; with neither there is no audit log.
+func NewGateFromConfig(cfg domain.ApprovalConfig, memoryDir string) (*Gate, error) {
+	var opts []Option
+	if cfg.Require != nil {
+		opts = append(opts, WithRequire(cfg.Require...))
+	}
+	if cfg.Timeout != "" {
+		d, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("approval: invalid timeout %q", cfg.Timeout)
+		}
+		opts = append(opts, WithTimeout(d))
+	}
+	auditPath := cfg.AuditLog
+	if auditPath == "" && memoryDir != "" {
+		auditPath = filepath.Join(memoryDir, "approvals.jsonl")
+	}
+	if auditPath != "" {
+		opts = append(opts, WithAuditLog(NewFileAuditLog(auditPath)))
+	}
+	return NewGate(opts...), nil
+}
+
+// Requires reports whether a call of tool with args needs approval: a
+// pattern matches the tool name or "tool.operation".
+func (g *Gate) Requires(tool string, args json.RawMessage) bool {
+	name := tool
+	if op := operation(args); op != "" {
+		name += "." + op
+	}
+	for _, p := range g.require {
+		if match(p, tool) || match(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Authorize asks the user of channelID, through p, to approve the call and
+// blocks until they decide. It returns nil when the call may run, ErrDenied,
+// ErrTimeout, ErrNoPrompter when p is nil, or ctx's error. Calls identical
+// to one answered with Always in the same channel are allowed without asking.
+func (g *Gate) Authorize(ctx context.Context, channelID, tool string, args json.RawMessage, p Prompter) error {
+	req := Request{ID: g.newID(), ChannelID: channelID, Tool: tool, Operation: operation(args), Args: args}
+	key := allowKey(channelID, tool, args)
+
+	g.mu.Lock()
+	allowed := g.always[key]
+	g.mu.Unlock()
+	if allowed {
+		g.record(req, EventAllowedForSession, "")
+		return nil
+	}
+	if p == nil {
+		g.record(req, EventDenied, "")
+		return ErrNoPrompter
+	}
+
+	req.Expires = g.now().Add(g.timeout)
+	pend := &pending{req: req, decision: make(chan resolution, 1)}
+	g.mu.Lock()
+	g.pending[req.ID] = pend
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.pending, req.ID)
+		g.mu.Unlock()
+	}()
+
+	g.record(req, EventRequested, "")
+	if err := p.Prompt(ctx, req); err != nil {
+		g.record(req, EventDenied, "")
+		return fmt.Errorf("approval: prompt %s: %w", channelID, err)
+	}
+
+	select {
+	case r := <-pend.decision:
+		switch r.decision {
+		case Approve:
+			g.record(req, EventApproved, r.by)
+			return nil
+		case Always:
+			g.mu.Lock()
+			g.always[key] = true
+			g.mu.Unlock()
+			g.record(req, EventAlwaysAllowed, r.by)
+			return nil
+		default:
+			g.record(req, EventDenied, r.by)
+			return ErrDenied
+		}
+	case <-g.after(g.timeout):
+		g.record(req, EventTimedOut, "")
+		return ErrTimeout
+	case <-ctx.Done():
+		g.record(req, EventCanceled, "")
+		return ctx.Err()
+	}
+}
+
+// Resolve delivers decision for the pending request id. The request must
+// belong to channelID, so a user can only answer requests from their own
+// channel. by identifies who decided, for the audit log.
+func (g *Gate) Resolve(channelID, id string, decision Decision, by string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	p, ok := g.pending[id]
+	if !ok || p.req.ChannelID != channelID {
+		return fmt.Errorf("%w: %q", ErrUnknownRequest, id)
+	}
+	select {
+	case p.decision <- resolution{decision: decision, by: by}:
+	default: // already decided
+	}
+	return nil
+}
+
+// Pending returns the requests of channelID still waiting for a decision.
+func (g *Gate) Pending(channelID string) []Request {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	var out []Request
+	for _, p := range g.pending {
+		if p.req.ChannelID == channelID {
+			out = append(out, p.req)
+		}
+	}
+	return out
+}
+
+// ResolveReply treats text as an answer to a pending request of channelID:
+// "<decision> <id>", or just "<decision>" when exactly one request is
+// pending. It reports whether text was such an answer; other text is left
+// for normal routing.
+func (g *Gate) ResolveReply(channelID, text, by string) bool {
+	fields := strings.Fields(text)
+	if len(fields) == 0 || len(fields) > 2 {
+		return false
+	}
+	decision, ok := ParseDecision(fields[0])
+	if !ok {
+		return false
+	}
+	if len(fields) == 2 {
+		return g.Resolve(channelID, fields[1], decision, by) == nil
+	}
+	pend := g.Pending(channelID)
+	if len(pend) != 1 {
+		return false
+	}
+	return g.Resolve(channelID, pend[0].ID, decision, by) == nil
+}
+
+func (g *Gate) record(req Request, event Event, by string) {
+	_ = g.audit.Record(AuditRecord{
+		Time:      g.now().UTC(),
+		ID:        req.ID,
+		ChannelID: req.ChannelID,
+		Tool:      req.Tool,
+		Operation: req.Operation,
+		Args:      req.Args,
+		Event:     event,
+		By:        by,
+	})
+}
+
+// operation returns the "operation" (git, filesystem) or "action" (iot)
+// argument of a tool call, or "".
+func operation(args json.RawMessage) string {
+	var v struct {
+		Operation string `json:"operation"`
+		Action    string `json:"action"`
+	}
+	if json.Unmarshal(args, &v) != nil {
+		return ""
+	}
+	if v.Operation != "" {
+		return v.Operation
+	}
+	return v.Action
+}
+
+// allowKey identifies an exact call in a channel; arguments are compacted so
+// whitespace differences do not matter.
+func allowKey(channelID, tool string, args json.RawMessage) string {
+	var buf bytes.Buffer
+	if json.Compact(&buf, args) != nil {
+		buf.Reset()
+		buf.Write(args)
+	}
+	return channelID + "\x00" + tool + "\x00" + buf.String()
+}
+
+func match(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func randomID() string {
+	b := make([]byte, 4)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,256 @@
+package approval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// memAudit collects audit records in memory.
+type memAudit struct {
+	mu      sync.Mutex
+	records []AuditRecord
+}
+
+func (m *memAudit) Record(rec AuditRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records = append(m.records, rec)
+	return nil
+}
+
+func (m *memAudit) events() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]Event, len(m.records))
+	for i, r := range m.records {
+		out[i] = r.Event
+	}
+	return out
+}
+
+// answeringPrompter resolves every request with decision as soon as it is prompted.
+func answeringPrompter(g *Gate, decision Decision, prompts *int) Prompter {
+	return PrompterFunc(func(ctx context.Context, req Request) error {
+		*prompts++
+		go func() { _ = g.Resolve(req.ChannelID, req.ID, decision, "tester") }()
+		return nil
+	})
+}
+
+func TestGate_Requires_ShouldMatchToolAndOperationPatterns(t *testing.T) {
+	g := NewGate()
+	cases := []struct {
+		tool, args string
+		want       bool
+	}{
+		{"shell", `{"command":"ls"}`, true},
+		{"git", `{"operation":"push"}`, true},
+		{"git", `{"operation":"status"}`, false},
+		{"filesystem", `{"operation":"write_file","path":"a"}`, true},
+		{"filesystem", `{"operation":"read_file","path":"a"}`, false},
+		{"iot", `{"action":"mqtt_publish"}`, true},
+		{"web", `{}`, false},
+	}
+	for _, c := range cases {
+		if got := g.Requires(c.tool, json.RawMessage(c.args)); got != c.want {
+			t.Errorf("%s %s: want %v, got %v", c.tool, c.args, c.want, got)
+		}
+	}
+	if NewGate(WithRequire("git.*")).Requires("shell", nil) {
+		t.Error("WithRequire should replace the defaults")
+	}
+	if NewGate(WithRequire()).Requires("shell", nil) {
+		t.Error("an empty require list should disable approvals")
+	}
+}
+
+func TestGate_Authorize_WhenApproved_ShouldReturnNilAndAudit(t *testing.T) {
+	audit := &memAudit{}
+	g := NewGate(WithAuditLog(audit))
+	prompts := 0
+	err := g.Authorize(context.Background(), "ws-1", "shell", json.RawMessage(`{"command":"ls"}`), answeringPrompter(g, Approve, &prompts))
+	if err != nil || prompts != 1 {
+		t.Fatalf("want approval after one prompt, got %v (%d prompts)", err, prompts)
+	}
+	got := audit.events()
+	if len(got) != 2 || got[0] != EventRequested || got[1] != EventApproved {
+		t.Errorf("unexpected audit trail %v", got)
+	}
+	if audit.records[1].By != "tester" || string(audit.records[1].Args) != `{"command":"ls"}` {
+		t.Errorf("audit record should carry decider and args: %+v", audit.records[1])
+	}
+	if len(g.Pending("ws-1")) != 0 {
+		t.Error("decided request should no longer be pending")
+	}
+}
+
+func TestGate_Authorize_WhenDenied_ShouldReturnErrDenied(t *testing.T) {
+	g := NewGate()
+	prompts := 0
+	err := g.Authorize(context.Background(), "ws-1", "shell", json.RawMessage(`{}`), answeringPrompter(g, Deny, &prompts))
+	if !errors.Is(err, ErrDenied) {
+		t.Errorf("want ErrDenied, got %v", err)
+	}
+}
+
+func TestGate_Authorize_WhenAlways_ShouldSkipPromptForIdenticalCallsInChannel(t *testing.T) {
+	audit := &memAudit{}
+	g := NewGate(WithAuditLog(audit))
+	prompts := 0
+	p := answeringPrompter(g, Always, &prompts)
+	ctx := context.Background()
+	if err := g.Authorize(ctx, "ws-1", "shell", json.RawMessage(`{"command": "ls"}`), p); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	if err := g.Authorize(ctx, "ws-1", "shell", json.RawMessage(`{"command":"ls"}`), p); err != nil || prompts != 1 {
+		t.Errorf("identical call should be allowed without a prompt, got %v (%d prompts)", err, prompts)
+	}
+	if audit.events()[2] != EventAllowedForSession {
+		t.Errorf("session allowance should be audited, got %v", audit.events())
+	}
+
+	_ = g.Authorize(ctx, "ws-1", "shell", json.RawMessage(`{"command":"rm -rf /"}`), answeringPrompter(g, Deny, &prompts))
+	_ = g.Authorize(ctx, "ws-2", "shell", json.RawMessage(`{"command":"ls"}`), answeringPrompter(g, Deny, &prompts))
+	if prompts != 3 {
+		t.Errorf("different args or channel should prompt again, got %d prompts", prompts)
+	}
+}
+
+func TestGate_Authorize_WhenNoDecision_ShouldTimeOut(t *testing.T) {
+	audit := &memAudit{}
+	g := NewGate(WithAuditLog(audit), WithTimeout(time.Minute))
+	fire := make(chan time.Time, 1)
+	var waited time.Duration
+	g.after = func(d time.Duration) <-chan time.Time { waited = d; return fire }
+	p := PrompterFunc(func(ctx context.Context, req Request) error {
+		fire <- time.Now()
+		return nil
+	})
+	err := g.Authorize(context.Background(), "ws-1", "shell", json.RawMessage(`{}`), p)
+	if !errors.Is(err, ErrTimeout) || waited != time.Minute {
+		t.Errorf("want ErrTimeout after 1m, got %v after %v", err, waited)
+	}
+	if ev := audit.events(); ev[len(ev)-1] != EventTimedOut {
+		t.Errorf("timeout should be audited, got %v", ev)
+	}
+}
+
+func TestGate_Authorize_WhenContextCanceled_ShouldReturnContextError(t *testing.T) {
+	g := NewGate()
+	ctx, cancel := context.WithCancel(context.Background())
+	p := PrompterFunc(func(context.Context, Request) error { cancel(); return nil })
+	if err := g.Authorize(ctx, "ws-1", "shell", json.RawMessage(`{}`), p); !errors.Is(err, context.Canceled) {
+		t.Errorf("want context.Canceled, got %v", err)
+	}
+}
+
+func TestGate_Authorize_WhenNoPrompterOrPromptFails_ShouldRefuse(t *testing.T) {
+	g := NewGate()
+	if err := g.Authorize(context.Background(), "job", "shell", json.RawMessage(`{}`), nil); !errors.Is(err, ErrNoPrompter) {
+		t.Errorf("want ErrNoPrompter, got %v", err)
+	}
+	failing := PrompterFunc(func(context.Context, Request) error { return errors.New("offline") })
+	if err := g.Authorize(context.Background(), "ws-1", "shell", json.RawMessage(`{}`), failing); err == nil {
+		t.Error("want error when the prompt cannot be sent")
+	}
+}
+
+func TestGate_Resolve_WhenOtherChannelOrUnknownID_ShouldReturnErrUnknownRequest(t *testing.T) {
+	g := NewGate()
+	g.newID = func() string { return "abc" }
+	prompted := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Authorize(context.Background(), "telegram-1", "shell", json.RawMessage(`{}`), PrompterFunc(func(context.Context, Request) error {
+			close(prompted)
+			return nil
+		}))
+	}()
+	<-prompted
+	if err := g.Resolve("whatsapp-2", "abc", Approve, "x"); !errors.Is(err, ErrUnknownRequest) {
+		t.Errorf("other channel: want ErrUnknownRequest, got %v", err)
+	}
+	if err := g.Resolve("telegram-1", "zzz", Approve, "x"); !errors.Is(err, ErrUnknownRequest) {
+		t.Errorf("unknown ID: want ErrUnknownRequest, got %v", err)
+	}
+	if err := g.Resolve("telegram-1", "abc", Deny, "x"); err != nil {
+		t.Fatalf("Resolve: %v", err)
+	}
+	if err := <-done; !errors.Is(err, ErrDenied) {
+		t.Errorf("want ErrDenied, got %v", err)
+	}
+}
+
+func TestGate_ResolveReply_ShouldAcceptKeywordsOnlyWhilePending(t *testing.T) {
+	g := NewGate()
+	if g.ResolveReply("whatsapp-1", "yes", "u") {
+		t.Error("reply without a pending request should not be consumed")
+	}
+	g.newID = func() string { return "abc" }
+	prompted := make(chan struct{})
+	done := make(chan error, 1)
+	go func() {
+		done <- g.Authorize(context.Background(), "whatsapp-1", "shell", json.RawMessage(`{}`), PrompterFunc(func(context.Context, Request) error {
+			close(prompted)
+			return nil
+		}))
+	}()
+	<-prompted
+	for _, text := range []string{"what is this?", "approve xyz", "approve abc now"} {
+		if g.ResolveReply("whatsapp-1", text, "u") {
+			t.Errorf("%q should not be consumed", text)
+		}
+	}
+	if !g.ResolveReply("whatsapp-1", "Approve", "u") {
+		t.Fatal("bare keyword with one pending request should be consumed")
+	}
+	if err := <-done; err != nil {
+		t.Errorf("want approval, got %v", err)
+	}
+}
+
+func TestNewGateFromConfig_ShouldApplyConfig(t *testing.T) {
+	if _, err := NewGateFromConfig(domain.ApprovalConfig{Timeout: "soon"}, ""); err == nil {
+		t.Error("want error for invalid timeout")
+	}
+	dir := t.TempDir()
+	g, err := NewGateFromConfig(domain.ApprovalConfig{Require: []string{"git.push"}, Timeout: "30s"}, dir)
+	if err != nil {
+		t.Fatalf("NewGateFromConfig: %v", err)
+	}
+	if g.timeout != 30*time.Second || g.Requires("shell", nil) || !g.Requires("git", json.RawMessage(`{"operation":"push"}`)) {
+		t.Errorf("config not applied: timeout %v, require %v", g.timeout, g.require)
+	}
+	if fl, ok := g.audit.(*FileAuditLog); !ok || fl.path != filepath.Join(dir, "approvals.jsonl") {
+		t.Errorf("want audit log in memory dir, got %#v", g.audit)
+	}
+}
+
+func TestRequest_Text_ShouldIncludeArgsAndKeywords(t *testing.T) {
+	req := Request{ID: "abc", Tool: "git", Operation: "push", Args: json.RawMessage(`{"operation":"push"}`)}
+	text := req.Text()
+	for _, want := range []string{"git.push", `"operation": "push"`, "approve abc", "deny abc", "always abc"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("text missing %q:\n%s", want, text)
+		}
+	}
+}
+
+func TestFromContext_ShouldReturnValuesSetByNewContext(t *testing.T) {
+	if _, _, ok := FromContext(context.Background()); ok {
+		t.Error("empty context should have no prompter")
+	}
+	p := PrompterFunc(func(context.Context, Request) error { return nil })
+	ch, got, ok := FromContext(NewContext(context.Background(), "ws-1", p))
+	if !ok || ch != "ws-1" || got == nil {
+		t.Errorf("unexpected %q %v %v", ch, got, ok)
+	}
+}
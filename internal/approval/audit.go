@@ -0,0 +1,83 @@
+package approval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Event is a step in the life of an approval request.
+type Event string
+
+const (
+	EventRequested         Event = "requested"          // Sent to the channel
+	EventApproved          Event = "approved"           // Approved once
+	EventAlwaysAllowed     Event = "always_allowed"     // Approved for identical calls in the channel
+	EventAllowedForSession Event = "allowed_by_session" // Allowed without asking by an earlier "always"
+	EventDenied            Event = "denied"             // Denied by the user, or no way to ask
+	EventTimedOut          Event = "timed_out"          // No decision before the timeout
+	EventCanceled          Event = "canceled"           // The caller stopped waiting
+)
+
+// AuditRecord is one entry of the approval audit trail.
+type AuditRecord struct {
+	Time      time.Time       `json:"time"`
+	ID        string          `json:"id"`
+	ChannelID string          `json:"channelId"`
+	Tool      string          `json:"tool"`
+	Operation string          `json:"operation,omitempty"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	Event     Event           `json:"event"`
+	By        string          `json:"by,omitempty"` // Who decided, when the channel reports it
+}
+
+// AuditLog records approval requests and their outcomes.
+type AuditLog interface {
+	Record(rec AuditRecord) error
+}
+
+// FileAuditLog appends audit records as JSON lines to a file.
+type FileAuditLog struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewFileAuditLog returns an audit log writing to path. The file and its
+// directory are created on the first record.
+func NewFileAuditLog(path string) *FileAuditLog {
+	return &FileAuditLog{path: path}
+}
+
+// Record implements AuditLog.
+func (l *FileAuditLog) Record(rec AuditRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("approval: audit log: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("approval: audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("approval: audit log: %w", err)
+	}
+	return nil
+}
+
+type nopAuditLog struct{}
+
+func (nopAuditLog) Record(AuditRecord) error { return nil }
+
+var (
+	_ AuditLog = (*FileAuditLog)(nil)
+	_ Prompter = PrompterFunc(nil)
+)
@@ -0,0 +1,42 @@
+package approval
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileAuditLog_Record_ShouldAppendJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "approvals.jsonl")
+	log := NewFileAuditLog(path)
+	recs := []AuditRecord{
+		{Time: time.Unix(0, 0).UTC(), ID: "a", ChannelID: "ws-1", Tool: "shell", Args: json.RawMessage(`{"command":"ls"}`), Event: EventRequested},
+		{Time: time.Unix(1, 0).UTC(), ID: "a", ChannelID: "ws-1", Tool: "shell", Event: EventApproved, By: "ws"},
+	}
+	for _, r := range recs {
+		if err := log.Record(r); err != nil {
+			t.Fatalf("Record: %v", err)
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var got []AuditRecord
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		var r AuditRecord
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			t.Fatalf("line %q: %v", sc.Text(), err)
+		}
+		got = append(got, r)
+	}
+	if len(got) != 2 || got[0].Event != EventRequested || string(got[0].Args) != `{"command":"ls"}` || got[1].By != "ws" {
+		t.Errorf("unexpected records %+v", got)
+	}
+}
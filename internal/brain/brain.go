@@ -7,8 +7,12 @@ import (
 	"log/slog"
 	"strings"
 
+	"ironclaw/internal/approval"
+	"ironclaw/internal/breaker"
 	ironctx "ironclaw/internal/context"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
+	"ironclaw/internal/usage"
 )
 
 // Option is a functional option for configuring Brain.
@@ -44,7 +48,9 @@ func WithLogger(l *slog.Logger) Option {
 }
 
 // WithFallbacks adds fallback LLM providers that are tried in order if the
-// primary provider fails. Nil entries are silently skipped.
+// primary provider fails. Nil entries are silently skipped. Providers wrapped
+// with a breaker.Set fail fast while their circuit is open, so failover skips
+// them.
 func WithFallbacks(providers ...domain.LLMProvider) Option {
 	return func(b *Brain) {
 		for _, p := range providers {
@@ -55,14 +61,39 @@ func WithFallbacks(providers ...domain.LLMProvider) Option {
 	}
 }
 
+// WithUsage records the token usage of every provider call with m and refuses
+// calls once the channel or agent they are made for has spent its budget
+// (see usage.WithAttribution). If m is nil it is ignored.
+func WithUsage(m *usage.Meter) Option {
+	return func(b *Brain) {
+		if m != nil {
+			b.usage = m
+		}
+	}
+}
+
 // Brain holds an LLM provider and exposes Generate to application logic.
 // Callers are unaware of the underlying implementation (OpenAI, Anthropic, local).
 type Brain struct {
 	provider   domain.LLMProvider
-	fallbacks  []domain.LLMProvider   // optional; tried in order when provider fails
-	memory     domain.MemoryStore     // optional; nil means no persistent memory
-	contextMgr domain.ContextManager  // optional; nil means no context window management
-	logger     *slog.Logger           // optional; nil uses slog.Default()
+	fallbacks  []domain.LLMProvider  // optional; tried in order when provider fails
+	memory     domain.MemoryStore    // optional; nil means no persistent memory
+	retriever  domain.Retriever      // optional; nil means no retrieved memories
+	contextMgr domain.ContextManager // optional; nil means no context window management
+	logger     *slog.Logger          // optional; nil uses slog.Default()
+	usage      *usage.Meter          // optional; records token usage and enforces budgets
+
+	tools        *ToolDispatcher // optional; tools offered to the model by Run
+	approvals    *approval.Gate  // optional; holds dangerous tool calls for human approval
+	maxTurns     int             // Run turn budget; 0 means defaultMaxTurns
+	maxToolCalls int             // Run tool-call budget; 0 means defaultMaxToolCalls
+
+	toolPolicy func(channelID string) tooling.Policy // optional; restricts RunMessages' tools per channel
+
+	structuredAttempts int // GenerateStructured tries; 0 means defaultStructuredAttempts
+
+	retrievalTok    domain.Tokenizer // counts retrieved memories; nil estimates
+	retrievalBudget int              // tokens retrieved memories may take
 }
 
 // NewBrain returns a Brain that uses the given provider. Provider must not be nil.
@@ -79,10 +110,15 @@ func NewBrain(provider domain.LLMProvider, opts ...Option) *Brain {
 }
 
 // Generate calls the underlying LLM provider with the given prompt and returns the response.
-// If a MemoryStore is configured, its content is prepended to the prompt as context.
+// If a MemoryStore is configured, its content is prepended to the prompt as context, as are
+// the memories a Retriever finds for the prompt.
 // When fallbacks are configured, they are tried in order if the primary provider fails.
 func (b *Brain) Generate(ctx context.Context, prompt string) (string, error) {
-	enriched := b.enrichPrompt(prompt)
+	ctx, err := b.metered(ctx)
+	if err != nil {
+		return "", err
+	}
+	enriched := b.enrichPrompt(ctx, prompt, prompt)
 	return b.generateWithFailover(ctx, enriched)
 }
 
@@ -97,14 +133,107 @@ func (b *Brain) log() *slog.Logger {
 // generateWithFailover tries the primary provider, then each fallback in order.
 // Returns the first successful response, or an aggregated error if all fail.
 func (b *Brain) generateWithFailover(ctx context.Context, prompt string) (string, error) {
-	result, err := b.provider.Generate(ctx, prompt)
+	var result string
+	err := b.withFailover(ctx, func(p domain.LLMProvider) error {
+		var genErr error
+		result, genErr = p.Generate(ctx, prompt)
+		return genErr
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Chat sends a structured request (messages with roles, system prompt, tools)
+// through the provider chain with the same failover as Generate. Providers
+// without native chat support receive the conversation flattened into a single
+// prompt; tools are not offered to them.
+func (b *Brain) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	ctx, err := b.metered(ctx)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	var result domain.ChatResponse
+	err = b.withFailover(ctx, func(p domain.LLMProvider) error {
+		var chatErr error
+		result, chatErr = chatOnce(ctx, p, req)
+		return chatErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// ChatStream is the streaming counterpart of Chat: text is passed to onDelta as
+// it is generated. Providers that cannot stream are called via Chat and their
+// reply is delivered as a single delta. Failover to the next provider happens
+// only before the first delta, so onDelta never sees text from two providers.
+func (b *Brain) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	ctx, err := b.metered(ctx)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	var result domain.ChatResponse
+	streamed := false
+	err = b.withFailover(ctx, func(p domain.LLMProvider) error {
+		var streamErr error
+		result, streamErr = streamOnce(ctx, p, req, func(delta string) {
+			streamed = true
+			onDelta(delta)
+		})
+		if streamErr != nil && streamed {
+			return noFailover{streamErr}
+		}
+		return streamErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// metered checks the budgets of the channel and agent ctx is attributed to
+// and returns ctx carrying the usage meter, so providers report their usage
+// to it and to any recorder ctx already carries. Without a meter ctx is
+// returned unchanged.
+func (b *Brain) metered(ctx context.Context) (context.Context, error) {
+	if b.usage == nil {
+		return ctx, nil
+	}
+	if err := b.usage.Check(ctx); err != nil {
+		return ctx, err
+	}
+	return usage.WithChainedRecorder(ctx, b.usage), nil
+}
+
+// noFailover marks an error after which withFailover must not try the next provider.
+type noFailover struct{ err error }
+
+func (n noFailover) Error() string { return n.err.Error() }
+func (n noFailover) Unwrap() error { return n.err }
+
+// withFailover calls call with the primary provider, then with each fallback in
+// order until one succeeds. Returns an aggregated error if all fail.
+// domain.ErrUnknownModel is returned as is: the fallbacks would ignore the
+// requested model and answer with their own.
+func (b *Brain) withFailover(ctx context.Context, call func(domain.LLMProvider) error) error {
+	err := call(b.provider)
 	if err == nil {
-		return result, nil
+		return nil
+	}
+	if errors.Is(err, domain.ErrUnknownModel) {
+		err = noFailover{err}
+	}
+	var stop noFailover
+	if errors.As(err, &stop) {
+		return stop.err
 	}
 
 	// No fallbacks configured — return primary error directly.
 	if len(b.fallbacks) == 0 {
-		return "", err
+		return err
 	}
 
 	// Collect all errors for aggregated reporting.
@@ -113,50 +242,165 @@ func (b *Brain) generateWithFailover(ctx context.Context, prompt string) (string
 	for i, fb := range b.fallbacks {
 		// Stop iterating if the context has been canceled.
 		if ctx.Err() != nil {
-			return "", ctx.Err()
+			return ctx.Err()
 		}
 
-		b.log().Warn("provider failed, trying fallback",
-			"provider_index", i,
-			"error", err,
-		)
+		if errors.Is(err, breaker.ErrOpen) {
+			// The provider was skipped without being called.
+			b.log().Info("provider circuit open, trying fallback",
+				"provider_index", i,
+				"error", err,
+			)
+		} else {
+			b.log().Warn("provider failed, trying fallback",
+				"provider_index", i,
+				"error", err,
+			)
+		}
 
-		result, fbErr := fb.Generate(ctx, prompt)
+		fbErr := call(fb)
 		if fbErr == nil {
-			return result, nil
+			return nil
+		}
+		if errors.As(fbErr, &stop) {
+			return stop.err
 		}
 		errs = append(errs, fbErr)
 		err = fbErr
 	}
 
-	return "", fmt.Errorf("brain: all %d providers failed: %w", len(errs), errors.Join(errs...))
+	return fmt.Errorf("brain: all %d providers failed: %w", len(errs), errors.Join(errs...))
+}
+
+// chatOnce calls p.Chat when p implements domain.ChatProvider, otherwise (or
+// when a decorator reports domain.ErrChatNotSupported) it flattens the request
+// with buildPrompt and calls Generate.
+func chatOnce(ctx context.Context, p domain.LLMProvider, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if cp, ok := p.(domain.ChatProvider); ok {
+		resp, err := cp.Chat(ctx, req)
+		if !errors.Is(err, domain.ErrChatNotSupported) {
+			return resp, err
+		}
+	}
+	text, err := p.Generate(ctx, buildPrompt(req.System, req.Messages))
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return domain.ChatResponse{
+		Content:    []domain.ContentBlock{domain.TextBlock{Text: text}},
+		StopReason: domain.StopEndTurn,
+	}, nil
+}
+
+// streamOnce calls p.ChatStream when p implements domain.StreamingProvider,
+// otherwise (or on domain.ErrStreamNotSupported) it falls back to chatOnce and
+// emits the whole reply text as one delta.
+func streamOnce(ctx context.Context, p domain.LLMProvider, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if sp, ok := p.(domain.StreamingProvider); ok {
+		resp, err := sp.ChatStream(ctx, req, onDelta)
+		if !errors.Is(err, domain.ErrStreamNotSupported) {
+			return resp, err
+		}
+	}
+	resp, err := chatOnce(ctx, p, req)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	if text := resp.Text(); text != "" {
+		onDelta(text)
+	}
+	return resp, nil
 }
 
 // GenerateWithContext takes a message history and system prompt, applies adaptive
 // context chunking (if a ContextManager is configured), then sends the result to
-// the LLM provider. Memory is injected into the system prompt before chunking.
+// the LLM provider as a structured chat request. Memory, and the memories
+// retrieved for the last user message, are injected into the system prompt
+// before chunking.
 func (b *Brain) GenerateWithContext(ctx context.Context, messages []domain.Message, systemPrompt string) (string, error) {
 	// Enrich the system prompt with long-term memory.
-	enrichedSystem := b.enrichPrompt(systemPrompt)
+	enrichedSystem := b.enrichPrompt(ctx, systemPrompt, lastUserText(messages))
 
 	// Apply context window management if configured.
-	fittedMessages := messages
-	if b.contextMgr != nil && len(messages) > 0 {
-		fitted, err := b.contextMgr.FitToWindow(messages, enrichedSystem)
-		if err != nil {
-			return "", fmt.Errorf("brain: context fitting failed: %w", err)
+	fittedMessages, err := b.fitMessages(ctx, messages, enrichedSystem)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := b.Chat(ctx, domain.ChatRequest{System: enrichedSystem, Messages: fittedMessages})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+
+// GenerateStream is the streaming counterpart of GenerateWithContext: memory
+// enrichment and context fitting are applied the same way, and reply text is
+// passed to onDelta as it arrives. Returns the complete reply text.
+func (b *Brain) GenerateStream(ctx context.Context, messages []domain.Message, systemPrompt string, onDelta func(string)) (string, error) {
+	enrichedSystem := b.enrichPrompt(ctx, systemPrompt, lastUserText(messages))
+	fittedMessages, err := b.fitMessages(ctx, messages, enrichedSystem)
+	if err != nil {
+		return "", err
+	}
+	resp, err := b.ChatStream(ctx, domain.ChatRequest{System: enrichedSystem, Messages: fittedMessages}, onDelta)
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+
+// fitMessages applies the ContextManager (if configured) to messages. A
+// domain.ContextCompactor is given ctx, metered like provider calls, for the
+// summaries it writes.
+func (b *Brain) fitMessages(ctx context.Context, messages []domain.Message, systemPrompt string) ([]domain.Message, error) {
+	if b.contextMgr == nil || len(messages) == 0 {
+		return messages, nil
+	}
+	var fitted []domain.Message
+	var err error
+	if cc, ok := b.contextMgr.(domain.ContextCompactor); ok {
+		if ctx, err = b.metered(ctx); err != nil {
+			return nil, err
 		}
-		fittedMessages = fitted
+		fitted, err = cc.FitContext(ctx, messages, systemPrompt)
+	} else {
+		fitted, err = b.contextMgr.FitToWindow(messages, systemPrompt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("brain: context fitting failed: %w", err)
 	}
+	return fitted, nil
+}
 
-	// Build the final prompt from system prompt + fitted messages.
-	prompt := buildPrompt(enrichedSystem, fittedMessages)
-	return b.generateWithFailover(ctx, prompt)
+// Compact summarises messages into the conversation summary kept in ctx's
+// domain.SummaryStore and returns how many messages were summarised. Returns
+// domain.ErrCompactionNotSupported unless the ContextManager is a
+// domain.ContextCompactor.
+func (b *Brain) Compact(ctx context.Context, messages []domain.Message) (int, error) {
+	cc, ok := b.contextMgr.(domain.ContextCompactor)
+	if !ok {
+		return 0, domain.ErrCompactionNotSupported
+	}
+	ctx, err := b.metered(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return cc.Compact(ctx, messages)
 }
 
-// enrichPrompt prepends long-term memory to the prompt when available.
-// On load error the memory is silently skipped (best-effort).
-func (b *Brain) enrichPrompt(prompt string) string {
+// enrichPrompt prepends long-term memory to the prompt when available. With
+// a retriever, only the memories retrieved for query are added: Remember
+// indexes everything it writes to memory.md, so injecting the whole file too
+// would repeat each note. On load or retrieval error the memory is silently
+// skipped (best-effort).
+func (b *Brain) enrichPrompt(ctx context.Context, prompt, query string) string {
+	if b.retriever != nil {
+		if relevant := b.relevantMemories(ctx, query); relevant != "" {
+			prompt = relevant + "\n\n" + prompt
+		}
+		return prompt
+	}
 	if b.memory == nil {
 		return prompt
 	}
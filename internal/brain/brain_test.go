@@ -5,11 +5,17 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"log/slog"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/db"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
 )
 
 // mockProvider implements domain.LLMProvider for tests.
@@ -401,6 +407,58 @@ func TestWithContextManager_WhenNil_ShouldBeIgnored(t *testing.T) {
 	}
 }
 
+// mockCompactor is a mockContextManager that is also a
+// domain.ContextCompactor, recording the contexts it is called with.
+type mockCompactor struct {
+	mockContextManager
+	fitCtx     context.Context
+	compactCtx context.Context
+	compacted  int
+}
+
+func (m *mockCompactor) FitContext(ctx context.Context, msgs []domain.Message, systemPrompt string) ([]domain.Message, error) {
+	m.fitCtx = ctx
+	return m.FitToWindow(msgs, systemPrompt)
+}
+
+func (m *mockCompactor) Compact(ctx context.Context, msgs []domain.Message) (int, error) {
+	m.compactCtx = ctx
+	return m.compacted, nil
+}
+
+type ctxKey struct{}
+
+func TestBrain_GenerateWithContext_WhenContextCompactor_ShouldFitWithCallerContext(t *testing.T) {
+	cm := &mockCompactor{mockContextManager: mockContextManager{fitResult: []domain.Message{textMsg(domain.RoleUser, "hi")}}}
+	brain := NewBrain(&mockProvider{response: "ok"}, WithContextManager(cm))
+	ctx := context.WithValue(context.Background(), ctxKey{}, "channel")
+
+	if _, err := brain.GenerateWithContext(ctx, []domain.Message{textMsg(domain.RoleUser, "hi")}, "sys"); err != nil {
+		t.Fatalf("GenerateWithContext: %v", err)
+	}
+	if cm.fitCtx == nil || cm.fitCtx.Value(ctxKey{}) != "channel" {
+		t.Error("expected FitContext to receive the caller's context")
+	}
+}
+
+func TestBrain_Compact_WhenContextCompactor_ShouldDelegate(t *testing.T) {
+	cm := &mockCompactor{compacted: 4}
+	brain := NewBrain(&mockProvider{response: "ok"}, WithContextManager(cm))
+
+	n, err := brain.Compact(context.Background(), []domain.Message{textMsg(domain.RoleUser, "hi")})
+	if err != nil || n != 4 || cm.compactCtx == nil {
+		t.Errorf("Compact = %d, %v, want 4 from the compactor", n, err)
+	}
+}
+
+func TestBrain_Compact_WhenNotContextCompactor_ShouldReturnErrCompactionNotSupported(t *testing.T) {
+	brain := NewBrain(&mockProvider{response: "ok"}, WithContextManager(&mockContextManager{}))
+
+	if _, err := brain.Compact(context.Background(), nil); !errors.Is(err, domain.ErrCompactionNotSupported) {
+		t.Errorf("expected ErrCompactionNotSupported, got %v", err)
+	}
+}
+
 // =============================================================================
 // Failover Tests
 // =============================================================================
@@ -584,6 +642,22 @@ func TestBrain_Generate_WhenContextCanceled_ShouldStopFailoverAndReturnContextEr
 	}
 }
 
+func TestBrain_Generate_WhenModelUnknown_ShouldReturnErrorWithoutFailover(t *testing.T) {
+	ctx := domain.WithModel(context.Background(), "gpt-9")
+	primary := &failoverMock{err: fmt.Errorf("llm: %w %q", domain.ErrUnknownModel, "gpt-9")}
+	fallback := &failoverMock{response: "answered by the wrong model"}
+
+	brain := NewBrain(primary, WithFallbacks(fallback))
+	_, err := brain.Generate(ctx, "hello")
+
+	if !errors.Is(err, domain.ErrUnknownModel) {
+		t.Errorf("err = %v, want ErrUnknownModel", err)
+	}
+	if fallback.called {
+		t.Error("expected fallback NOT to be called for an unknown model")
+	}
+}
+
 func TestBrain_Generate_WhenNoFallbacksConfigured_ShouldReturnPrimaryErrorDirectly(t *testing.T) {
 	ctx := context.Background()
 	wantErr := errors.New("only provider down")
@@ -678,3 +752,288 @@ func TestBrain_Generate_WhenPrimaryFailsWithNilFallbacksOption_ShouldReturnError
 		t.Errorf("expected 'down', got %q", err.Error())
 	}
 }
+
+// =============================================================================
+// Chat Tests
+// =============================================================================
+
+// mockChatProvider implements domain.LLMProvider and domain.ChatProvider.
+type mockChatProvider struct {
+	resp    domain.ChatResponse
+	err     error
+	req     domain.ChatRequest // last request passed to Chat
+	genUsed bool
+}
+
+func (m *mockChatProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	m.genUsed = true
+	return "", errors.New("generate should not be called")
+}
+
+func (m *mockChatProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	m.req = req
+	return m.resp, m.err
+}
+
+func TestBrain_Chat_WhenProviderSupportsChat_ShouldPassStructuredRequest(t *testing.T) {
+	provider := &mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "hi there"}}}}
+	brain := NewBrain(provider)
+
+	req := domain.ChatRequest{
+		System:   "sys",
+		Messages: []domain.Message{textMsg(domain.RoleUser, "hello")},
+		Tools:    []domain.ToolDefinition{{Name: "calculator"}},
+	}
+	resp, err := brain.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "hi there" {
+		t.Errorf("want %q, got %q", "hi there", resp.Text())
+	}
+	if provider.genUsed {
+		t.Error("Generate should not be used for chat-capable providers")
+	}
+	if provider.req.System != "sys" || len(provider.req.Tools) != 1 || len(provider.req.Messages) != 1 {
+		t.Errorf("request not passed through: %+v", provider.req)
+	}
+}
+
+func TestBrain_Chat_WhenProviderLacksChat_ShouldFlattenIntoGenerate(t *testing.T) {
+	provider := &mockProvider{response: "flat"}
+	brain := NewBrain(provider)
+
+	resp, err := brain.Chat(context.Background(), domain.ChatRequest{
+		System:   "sys",
+		Messages: []domain.Message{textMsg(domain.RoleUser, "question")},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "flat" || resp.StopReason != domain.StopEndTurn {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if !strings.Contains(provider.prompt, "[System]\nsys") || !strings.Contains(provider.prompt, "[user]\nquestion") {
+		t.Errorf("expected flattened prompt, got %q", provider.prompt)
+	}
+}
+
+func TestBrain_Chat_WhenDecoratorReportsNotSupported_ShouldFlattenIntoGenerate(t *testing.T) {
+	provider := &notSupportedChatProvider{mockProvider{response: "fallback text"}}
+	brain := NewBrain(provider)
+
+	resp, err := brain.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{textMsg(domain.RoleUser, "q")}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "fallback text" {
+		t.Errorf("want %q, got %q", "fallback text", resp.Text())
+	}
+}
+
+// notSupportedChatProvider mimics a decorator wrapping a Generate-only provider.
+type notSupportedChatProvider struct {
+	mockProvider
+}
+
+func (n *notSupportedChatProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	return domain.ChatResponse{}, domain.ErrChatNotSupported
+}
+
+func TestBrain_Chat_WhenPrimaryFails_ShouldFallback(t *testing.T) {
+	primary := &mockChatProvider{err: errors.New("primary down")}
+	fallback := &mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "backup"}}}}
+	brain := NewBrain(primary, WithFallbacks(fallback))
+
+	resp, err := brain.Chat(context.Background(), domain.ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "backup" {
+		t.Errorf("want %q, got %q", "backup", resp.Text())
+	}
+}
+
+func TestBrain_GenerateWithContext_WhenProviderSupportsChat_ShouldSendRolesAndSystem(t *testing.T) {
+	provider := &mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "answer"}}}}
+	brain := NewBrain(provider)
+
+	msgs := []domain.Message{textMsg(domain.RoleUser, "q1"), textMsg(domain.RoleAssistant, "a1"), textMsg(domain.RoleUser, "q2")}
+	got, err := brain.GenerateWithContext(context.Background(), msgs, "be nice")
+	if err != nil {
+		t.Fatalf("GenerateWithContext: %v", err)
+	}
+	if got != "answer" {
+		t.Errorf("want %q, got %q", "answer", got)
+	}
+	if provider.req.System != "be nice" || len(provider.req.Messages) != 3 || provider.req.Messages[1].Role != domain.RoleAssistant {
+		t.Errorf("unexpected request: %+v", provider.req)
+	}
+}
+
+// =============================================================================
+// Streaming Tests
+// =============================================================================
+
+// mockStreamProvider streams deltas then returns err (if any).
+type mockStreamProvider struct {
+	mockChatProvider
+	deltas []string
+}
+
+func (m *mockStreamProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	m.req = req
+	for _, d := range m.deltas {
+		onDelta(d)
+	}
+	return m.resp, m.err
+}
+
+func TestBrain_ChatStream_WhenProviderCannotStream_ShouldEmitWholeReplyOnce(t *testing.T) {
+	provider := &mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "whole"}}}}
+	b := NewBrain(provider)
+
+	var deltas []string
+	resp, err := b.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if resp.Text() != "whole" || len(deltas) != 1 || deltas[0] != "whole" {
+		t.Errorf("unexpected response %q / deltas %q", resp.Text(), deltas)
+	}
+}
+
+func TestBrain_ChatStream_WhenPrimaryFailsBeforeDelta_ShouldFailOver(t *testing.T) {
+	primary := &mockStreamProvider{mockChatProvider: mockChatProvider{err: errors.New("down")}}
+	fallback := &mockStreamProvider{
+		mockChatProvider: mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "ok"}}}},
+		deltas:           []string{"o", "k"},
+	}
+	b := NewBrain(primary, WithFallbacks(fallback))
+
+	var deltas []string
+	resp, err := b.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if resp.Text() != "ok" || strings.Join(deltas, "") != "ok" {
+		t.Errorf("unexpected response %q / deltas %q", resp.Text(), deltas)
+	}
+}
+
+func TestBrain_ChatStream_WhenPrimaryFailsMidStream_ShouldNotFailOver(t *testing.T) {
+	midErr := errors.New("connection reset")
+	primary := &mockStreamProvider{mockChatProvider: mockChatProvider{err: midErr}, deltas: []string{"par"}}
+	fallback := &mockStreamProvider{deltas: []string{"should not run"}}
+	b := NewBrain(primary, WithFallbacks(fallback))
+
+	var deltas []string
+	_, err := b.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if !errors.Is(err, midErr) {
+		t.Errorf("expected mid-stream error, got %v", err)
+	}
+	if len(deltas) != 1 {
+		t.Errorf("fallback must not stream after a partial reply, got deltas %q", deltas)
+	}
+}
+
+func TestBrain_GenerateStream_ShouldEnrichSystemPromptAndReturnText(t *testing.T) {
+	provider := &mockStreamProvider{
+		mockChatProvider: mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "hey"}}}},
+		deltas:           []string{"he", "y"},
+	}
+	b := NewBrain(provider, WithMemory(&mockMemoryStore{memory: "likes tea"}))
+
+	got, err := b.GenerateStream(context.Background(), []domain.Message{textMsg(domain.RoleUser, "hi")}, "sys", func(string) {})
+	if err != nil {
+		t.Fatalf("GenerateStream: %v", err)
+	}
+	if got != "hey" || !strings.Contains(provider.req.System, "likes tea") || !strings.Contains(provider.req.System, "sys") {
+		t.Errorf("unexpected text %q / system %q", got, provider.req.System)
+	}
+}
+
+func newTestUsageMeter(t *testing.T, opts ...usage.Option) *usage.Meter {
+	t.Helper()
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	store, err := usage.NewSQLiteStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	return usage.NewMeter(store, opts...)
+}
+
+// usageReportingProvider reports fixed usage for every call, like the llm providers.
+type usageReportingProvider struct {
+	calls int
+}
+
+func (p *usageReportingProvider) Generate(ctx context.Context, _ string) (string, error) {
+	p.calls++
+	usage.Report(ctx, usage.Call{Provider: "fake", Model: "m", InputTokens: 1_000_000})
+	return "ok", nil
+}
+
+func TestBrain_WithUsage_WhenBudgetSpent_ShouldRefuseCalls(t *testing.T) {
+	meter := newTestUsageMeter(t,
+		usage.WithPrices(usage.Prices{"m": {Input: 1}}),
+		usage.WithBudgets(domain.BudgetConfig{Channel: "c", Daily: 1}),
+	)
+	p := &usageReportingProvider{}
+	fb := &usageReportingProvider{}
+	b := NewBrain(p, WithUsage(meter), WithFallbacks(fb))
+	ctx := usage.WithAttribution(context.Background(), usage.Attribution{Channel: "c"})
+
+	if _, err := b.Generate(ctx, "first"); err != nil {
+		t.Fatalf("first call: %v", err)
+	}
+	_, err := b.Chat(ctx, domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "second")}})
+	if !errors.Is(err, usage.ErrBudgetExceeded) {
+		t.Fatalf("second call: err = %v, want ErrBudgetExceeded", err)
+	}
+	if p.calls != 1 || fb.calls != 0 {
+		t.Errorf("provider calls = %d, fallback calls = %d; want 1 and 0", p.calls, fb.calls)
+	}
+	if _, err := b.Generate(usage.WithAttribution(context.Background(), usage.Attribution{Channel: "other"}), "hi"); err != nil {
+		t.Errorf("other channel: %v", err)
+	}
+}
+
+func TestBrain_Generate_WhenPrimaryCircuitOpen_ShouldSkipItInFailover(t *testing.T) {
+	var logs bytes.Buffer
+	breakers := breaker.NewSet(breaker.Config{FailureThreshold: 2, ProbeInterval: time.Minute}, breaker.WithStateChange(func(breaker.Status) {}))
+	primary := &countingProvider{err: errors.New("503 service unavailable")}
+	fallback := &countingProvider{response: "fallback"}
+	b := NewBrain(breakers.Wrap("primary", primary),
+		WithFallbacks(breakers.Wrap("fallback", fallback)),
+		WithLogger(slog.New(slog.NewTextHandler(&logs, nil))))
+
+	for i := 0; i < 4; i++ {
+		got, err := b.Generate(context.Background(), "hi")
+		if err != nil || got != "fallback" {
+			t.Fatalf("call %d: %q, %v", i, got, err)
+		}
+	}
+	if primary.calls != 2 || fallback.calls != 4 {
+		t.Errorf("primary calls = %d, fallback calls = %d; want 2 and 4", primary.calls, fallback.calls)
+	}
+	if !strings.Contains(logs.String(), "provider circuit open") {
+		t.Errorf("expected skipped provider to be logged, got:\n%s", logs.String())
+	}
+}
+
+// countingProvider counts Generate calls.
+type countingProvider struct {
+	response string
+	err      error
+	calls    int
+}
+
+func (p *countingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	return p.response, p.err
+}
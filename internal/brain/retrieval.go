@@ -0,0 +1,126 @@
+package brain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// defaultRetrievalBudget is the tokens retrieved memories may take when
+// WithRetriever is given no budget.
+const defaultRetrievalBudget = 1024
+
+// ErrNoMemory is returned by Remember when the Brain has neither a memory
+// store nor a retriever.
+var ErrNoMemory = errors.New("brain: no memory configured")
+
+// WithRetriever adds the memories r retrieves for each incoming message to
+// the system prompt, as a numbered "Relevant Memories" section the model is
+// asked to cite, in place of the whole of memory.md, and makes Remember
+// store memories in r too. The memories are taken in order of relevance
+// while they fit in budget tokens (default 1024), counted with tok, or at
+// four bytes a token when tok is nil. If r is nil it is ignored.
+func WithRetriever(r domain.Retriever, tok domain.Tokenizer, budget int) Option {
+	return func(b *Brain) {
+		if r == nil {
+			return
+		}
+		if budget <= 0 {
+			budget = defaultRetrievalBudget
+		}
+		b.retriever, b.retrievalTok, b.retrievalBudget = r, tok, budget
+	}
+}
+
+// Remember stores content in long-term memory: appended to the memory
+// store's memory.md and indexed by the retriever, when configured. Both are
+// attempted; their errors are joined. Returns ErrNoMemory when neither is
+// configured.
+func (b *Brain) Remember(ctx context.Context, content string) error {
+	content = strings.TrimSpace(content)
+	if b.memory == nil && b.retriever == nil {
+		return ErrNoMemory
+	}
+	if content == "" {
+		return nil
+	}
+	var errs []error
+	if b.memory != nil {
+		if err := b.memory.Remember(content); err != nil {
+			errs = append(errs, fmt.Errorf("brain: remember: %w", err))
+		}
+	}
+	if b.retriever != nil {
+		if err := b.retriever.Store(ctx, content); err != nil {
+			errs = append(errs, fmt.Errorf("brain: remember: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// relevantMemories returns the section of memories relevant to query, or ""
+// when there is no retriever or nothing relevant. Retrieval is best-effort:
+// on error the section is left out.
+func (b *Brain) relevantMemories(ctx context.Context, query string) string {
+	if b.retriever == nil || strings.TrimSpace(query) == "" {
+		return ""
+	}
+	memories, err := b.retriever.Retrieve(ctx, query)
+	if err != nil {
+		b.log().Warn("memory retrieval failed", "error", err)
+		return ""
+	}
+	var sb strings.Builder
+	used, n := 0, 0
+	for _, m := range memories {
+		line := fmt.Sprintf("[%d] %s", n+1, strings.TrimSpace(m.Content))
+		if !m.CreatedAt.IsZero() {
+			line += " (" + m.CreatedAt.Format("2006-01-02") + ")"
+		}
+		tokens, err := b.countRetrievalTokens(line)
+		if err != nil || used+tokens > b.retrievalBudget {
+			continue
+		}
+		used += tokens
+		n++
+		sb.WriteString(line)
+		sb.WriteString("\n")
+	}
+	if n == 0 {
+		return ""
+	}
+	return "[Relevant Memories]\nNotes from long-term memory that may bear on this message. Cite the ones you rely on by number, e.g. [1].\n" +
+		sb.String() + "[End Relevant Memories]"
+}
+
+// countRetrievalTokens counts text with the retrieval tokenizer, or
+// estimates four bytes a token without one.
+func (b *Brain) countRetrievalTokens(text string) (int, error) {
+	if b.retrievalTok == nil {
+		return (len(text) + 3) / 4, nil
+	}
+	return b.retrievalTok.CountTokens(text)
+}
+
+// lastUserText returns the text of the last user message, the one memories
+// are retrieved for.
+func lastUserText(messages []domain.Message) string {
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].Role != domain.RoleUser {
+			continue
+		}
+		var parts []string
+		for _, block := range messages[i].Blocks() {
+			if tb, ok := block.(domain.TextBlock); ok {
+				parts = append(parts, tb.Text)
+			}
+		}
+		if len(parts) > 0 {
+			return strings.Join(parts, "\n")
+		}
+	}
+	return ""
+}
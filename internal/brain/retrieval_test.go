@@ -0,0 +1,178 @@
+package brain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// mockRetriever implements domain.Retriever for tests.
+type mockRetriever struct {
+	memories []domain.SemanticMemory
+	err      error
+	query    string   // last query passed to Retrieve
+	stored   []string // contents passed to Store
+	storeErr error
+}
+
+func (m *mockRetriever) Retrieve(ctx context.Context, query string) ([]domain.SemanticMemory, error) {
+	m.query = query
+	return m.memories, m.err
+}
+
+func (m *mockRetriever) Store(ctx context.Context, content string) error {
+	if m.storeErr != nil {
+		return m.storeErr
+	}
+	m.stored = append(m.stored, content)
+	return nil
+}
+
+func TestBrain_Generate_WithRetriever_ShouldInjectCitedRelevantMemories(t *testing.T) {
+	provider := &mockProvider{response: "ok"}
+	day := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	r := &mockRetriever{memories: []domain.SemanticMemory{
+		{Content: "User drinks coffee black", CreatedAt: day},
+		{Content: "User is allergic to oat milk"},
+	}}
+	b := NewBrain(provider, WithMemory(&mockMemoryStore{memory: "- Name is Sam\n"}), WithRetriever(r, nil, 0))
+
+	if _, err := b.Generate(context.Background(), "How do I take my coffee?"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if r.query != "How do I take my coffee?" {
+		t.Errorf("query = %q", r.query)
+	}
+	for _, want := range []string{
+		"[Relevant Memories]",
+		"[1] User drinks coffee black (2026-03-14)\n",
+		"[2] User is allergic to oat milk\n",
+		"[End Relevant Memories]",
+	} {
+		if !strings.Contains(provider.prompt, want) {
+			t.Errorf("prompt missing %q:\n%s", want, provider.prompt)
+		}
+	}
+	if strings.Contains(provider.prompt, "Name is Sam") {
+		t.Errorf("prompt should leave out memory.md when retrieving, got %q", provider.prompt)
+	}
+	if !strings.HasSuffix(provider.prompt, "How do I take my coffee?") {
+		t.Errorf("prompt should end with the question, got %q", provider.prompt)
+	}
+}
+
+func TestBrain_Generate_WithRetriever_ShouldKeepMemoriesWithinBudget(t *testing.T) {
+	provider := &mockProvider{response: "ok"}
+	r := &mockRetriever{memories: []domain.SemanticMemory{
+		{Content: "short"},
+		{Content: strings.Repeat("long ", 100)},
+		{Content: "tiny"},
+	}}
+	b := NewBrain(provider, WithRetriever(r, nil, 10))
+
+	if _, err := b.Generate(context.Background(), "q"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !strings.Contains(provider.prompt, "[1] short\n[2] tiny\n") {
+		t.Errorf("want the memories that fit, numbered in order, got %q", provider.prompt)
+	}
+	if strings.Contains(provider.prompt, "long long") {
+		t.Errorf("memory over budget injected: %q", provider.prompt)
+	}
+}
+
+func TestBrain_Generate_WhenRetrievalFailsOrFindsNothing_ShouldPassPromptUnchanged(t *testing.T) {
+	for name, r := range map[string]*mockRetriever{
+		"error":   {err: errors.New("db closed")},
+		"nothing": {},
+	} {
+		provider := &mockProvider{response: "ok"}
+		b := NewBrain(provider, WithRetriever(r, nil, 0))
+		if _, err := b.Generate(context.Background(), "hello"); err != nil {
+			t.Fatalf("%s: Generate: %v", name, err)
+		}
+		if provider.prompt != "hello" {
+			t.Errorf("%s: prompt = %q, want unchanged", name, provider.prompt)
+		}
+	}
+}
+
+func TestBrain_GenerateWithContext_WithRetriever_ShouldQueryLastUserMessage(t *testing.T) {
+	provider := &mockChatProvider{resp: domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "ok"}}}}
+	r := &mockRetriever{memories: []domain.SemanticMemory{{Content: "Garden is in the back"}}}
+	b := NewBrain(provider, WithRetriever(r, nil, 0))
+
+	messages := []domain.Message{
+		textMsg(domain.RoleUser, "first"),
+		textMsg(domain.RoleAssistant, "reply"),
+		textMsg(domain.RoleUser, "where is the garden?"),
+	}
+	if _, err := b.GenerateWithContext(context.Background(), messages, "sys"); err != nil {
+		t.Fatalf("GenerateWithContext: %v", err)
+	}
+	if r.query != "where is the garden?" {
+		t.Errorf("query = %q", r.query)
+	}
+	if !strings.Contains(provider.req.System, "[1] Garden is in the back") || !strings.HasSuffix(provider.req.System, "sys") {
+		t.Errorf("system = %q", provider.req.System)
+	}
+}
+
+func TestBrain_Remember_ShouldWriteMemoryFileAndRetriever(t *testing.T) {
+	mem := &mockMemoryStore{}
+	r := &mockRetriever{}
+	b := NewBrain(&mockProvider{}, WithMemory(mem), WithRetriever(r, nil, 0))
+
+	if err := b.Remember(context.Background(), "Likes jazz"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	if len(mem.remembered) != 1 || mem.remembered[0] != "Likes jazz" {
+		t.Errorf("memory.md got %v", mem.remembered)
+	}
+	if len(r.stored) != 1 || r.stored[0] != "Likes jazz" {
+		t.Errorf("retriever got %v", r.stored)
+	}
+}
+
+func TestBrain_Generate_WithRetriever_ShouldShowARememberedNoteOnce(t *testing.T) {
+	provider := &mockProvider{response: "ok"}
+	mem := &mockMemoryStore{}
+	r := &mockRetriever{}
+	b := NewBrain(provider, WithMemory(mem), WithRetriever(r, nil, 0))
+
+	if err := b.Remember(context.Background(), "Likes jazz"); err != nil {
+		t.Fatalf("Remember: %v", err)
+	}
+	mem.memory = "- " + strings.Join(mem.remembered, "\n- ") + "\n"
+	r.memories = []domain.SemanticMemory{{Content: r.stored[0]}}
+	if _, err := b.Generate(context.Background(), "What music do I like?"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if n := strings.Count(provider.prompt, "Likes jazz"); n != 1 {
+		t.Errorf("note appears %d times, want once:\n%s", n, provider.prompt)
+	}
+}
+
+func TestBrain_Remember_WhenOneStoreFails_ShouldStillWriteTheOther(t *testing.T) {
+	mem := &mockMemoryStore{rememberErr: errors.New("disk full")}
+	r := &mockRetriever{}
+	b := NewBrain(&mockProvider{}, WithMemory(mem), WithRetriever(r, nil, 0))
+
+	if err := b.Remember(context.Background(), "Likes jazz"); err == nil {
+		t.Error("expected error")
+	}
+	if len(r.stored) != 1 {
+		t.Errorf("retriever got %v, want the memory stored", r.stored)
+	}
+}
+
+func TestBrain_Remember_WhenNoMemoryConfigured_ShouldReturnErrNoMemory(t *testing.T) {
+	b := NewBrain(&mockProvider{})
+	if err := b.Remember(context.Background(), "x"); !errors.Is(err, ErrNoMemory) {
+		t.Errorf("err = %v, want ErrNoMemory", err)
+	}
+}
@@ -0,0 +1,334 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
+)
+
+// Default budgets for Run when WithRunLimits is not used.
+const (
+	defaultMaxTurns     = 10
+	defaultMaxToolCalls = 25
+)
+
+// Sentinel errors returned by Run when a budget is exhausted.
+var (
+	ErrMaxTurnsExceeded     = errors.New("brain: max turns exceeded")
+	ErrMaxToolCallsExceeded = errors.New("brain: max tool calls exceeded")
+	ErrNilSession           = errors.New("brain: session must not be nil")
+)
+
+// RunEventType identifies the kind of progress event emitted by Run.
+type RunEventType string
+
+const (
+	EventTextDelta    RunEventType = "text_delta"
+	EventToolStarted  RunEventType = "tool_started"
+	EventToolFinished RunEventType = "tool_finished"
+)
+
+// RunEvent reports progress of an agent loop so callers (gateway, bridges) can
+// surface it. EventTextDelta carries a fragment of streamed reply text; ToolUse
+// is set for tool events; Result only for EventToolFinished.
+type RunEvent struct {
+	Type    RunEventType
+	Turn    int
+	Text    string
+	ToolUse *domain.ToolUseBlock
+	Result  *domain.ToolResultBlock
+}
+
+// RunOption configures a single Run invocation.
+type RunOption func(*runConfig)
+
+type runConfig struct {
+	system  string
+	onEvent func(RunEvent)
+	policy  tooling.Policy
+}
+
+// WithSystemPrompt sets the system prompt for a Run. Long-term memory is still
+// prepended when a MemoryStore is configured, as are retrieved memories.
+func WithSystemPrompt(system string) RunOption {
+	return func(c *runConfig) { c.system = system }
+}
+
+// WithEventHandler registers a callback for RunEvents. If fn is nil it is ignored.
+// The callback runs synchronously on the loop goroutine and must not block.
+func WithEventHandler(fn func(RunEvent)) RunOption {
+	return func(c *runConfig) {
+		if fn != nil {
+			c.onEvent = fn
+		}
+	}
+}
+
+// WithToolPolicy restricts the tools offered and executed during a Run, e.g.
+// to the bound agent's TOOLS.md and the channel's rules. It stacks with any
+// policy already on the dispatcher.
+func WithToolPolicy(p tooling.Policy) RunOption {
+	return func(c *runConfig) { c.policy = c.policy.Merge(p) }
+}
+
+// WithTools sets the dispatcher whose tools are offered to the model by Run.
+// If d is nil it is ignored and Run behaves as a single chat turn.
+func WithTools(d *ToolDispatcher) Option {
+	return func(b *Brain) {
+		if d != nil {
+			b.tools = d
+		}
+	}
+}
+
+// WithChannelToolPolicy restricts the tools RunMessages offers and executes
+// to the policy fn returns for the routed channel, e.g. the rules of the
+// agent bound to it and of the channel itself. If fn is nil it is ignored.
+func WithChannelToolPolicy(fn func(channelID string) tooling.Policy) Option {
+	return func(b *Brain) {
+		if fn != nil {
+			b.toolPolicy = fn
+		}
+	}
+}
+
+// WithApprovals holds tool calls that g requires approval for until the user
+// of the originating channel decides. The channel and its Prompter are taken
+// from the Run context (see approval.NewContext); without them such calls are
+// refused. If g is nil it is ignored.
+func WithApprovals(g *approval.Gate) Option {
+	return func(b *Brain) {
+		if g != nil {
+			b.approvals = g
+		}
+	}
+}
+
+// Approvals returns the gate set with WithApprovals, or nil.
+func (b *Brain) Approvals() *approval.Gate {
+	return b.approvals
+}
+
+// WithRunLimits sets the agent loop budgets: the maximum number of model turns
+// and the maximum number of tool calls per Run. Non-positive values are ignored.
+func WithRunLimits(maxTurns, maxToolCalls int) Option {
+	return func(b *Brain) {
+		if maxTurns > 0 {
+			b.maxTurns = maxTurns
+		}
+		if maxToolCalls > 0 {
+			b.maxToolCalls = maxToolCalls
+		}
+	}
+}
+
+// Run drives an agentic tool-use loop over session.History. Each turn sends the
+// history and tool definitions to the model; any ToolUseBlocks in the reply are
+// executed via the ToolDispatcher and their ToolResultBlocks (IsError on
+// failure) are appended before the next turn. The loop ends when the model
+// replies without tool calls, returning that reply's text.
+//
+// Assistant and tool-result messages are appended to session.History as the
+// loop progresses, so the caller can persist them. Every tool call in the
+// history is answered: calls left unexecuted because a budget ran out, ctx
+// was cancelled or no tools are configured get an IsError result. When the
+// turn or tool-call budget is exhausted, the text so far is returned
+// together with ErrMaxTurnsExceeded or ErrMaxToolCallsExceeded.
+func (b *Brain) Run(ctx context.Context, session *domain.Session, opts ...RunOption) (string, error) {
+	if session == nil {
+		return "", ErrNilSession
+	}
+	cfg := runConfig{onEvent: func(RunEvent) {}}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	system := b.enrichPrompt(ctx, cfg.system, lastUserText(session.History))
+	var dispatcher *ToolDispatcher
+	var tools []domain.ToolDefinition
+	if b.tools != nil {
+		dispatcher = b.tools.WithPolicy(cfg.policy)
+		tools = dispatcher.FormatToolsForLLM()
+	}
+
+	maxTurns, maxToolCalls := b.runLimits()
+	toolCalls := 0
+	var text string
+
+	for turn := 1; turn <= maxTurns; turn++ {
+		if err := ctx.Err(); err != nil {
+			return text, err
+		}
+
+		messages, err := b.fitMessages(ctx, session.History, system)
+		if err != nil {
+			return text, err
+		}
+		resp, err := b.ChatStream(ctx, domain.ChatRequest{System: system, Messages: messages, Tools: tools}, func(delta string) {
+			cfg.onEvent(RunEvent{Type: EventTextDelta, Turn: turn, Text: delta})
+		})
+		if err != nil {
+			return text, err
+		}
+
+		text = resp.Text()
+		if len(resp.Content) > 0 {
+			session.History = append(session.History, domain.NewMessage(domain.RoleAssistant, resp.Content...))
+		}
+
+		uses := resp.ToolUses()
+		if len(uses) == 0 {
+			return text, nil
+		}
+		if dispatcher == nil {
+			session.History = append(session.History, domain.NewMessage(domain.RoleTool, notRunResults(uses, "no tools are available")...))
+			return text, nil
+		}
+
+		results := make([]domain.ContentBlock, 0, len(uses))
+		for i := range uses {
+			stop := ctx.Err()
+			if toolCalls >= maxToolCalls {
+				stop = fmt.Errorf("%w (%d)", ErrMaxToolCallsExceeded, maxToolCalls)
+			}
+			if stop != nil {
+				results = append(results, notRunResults(uses[i:], stop.Error())...)
+				session.History = append(session.History, domain.NewMessage(domain.RoleTool, results...))
+				return text, stop
+			}
+			toolCalls++
+			use := uses[i]
+			cfg.onEvent(RunEvent{Type: EventToolStarted, Turn: turn, ToolUse: &use})
+			result := b.executeTool(ctx, dispatcher, use)
+			cfg.onEvent(RunEvent{Type: EventToolFinished, Turn: turn, ToolUse: &use, Result: &result})
+			results = append(results, result)
+		}
+		session.History = append(session.History, domain.NewMessage(domain.RoleTool, results...))
+	}
+
+	return text, fmt.Errorf("%w (%d)", ErrMaxTurnsExceeded, maxTurns)
+}
+
+// RunMessages answers a routed conversation (see router.Runner) with Run,
+// under the tool policy of channelID (see WithChannelToolPolicy): reply text
+// is passed to onDelta, which may be nil, and the tool calls the loop starts
+// and finishes to the handler in ctx (see domain.WithToolEvents).
+// It returns the reply and the messages Run added after messages, ending
+// with the reply, for the caller to record. When Run fails, the messages it
+// added before failing, such as tool calls that already ran and their
+// results, are returned with the error and the partial reply. Without tools it answers like
+// GenerateStream, or GenerateWithContext when onDelta is nil.
+func (b *Brain) RunMessages(ctx context.Context, channelID string, messages []domain.Message, system string, onDelta func(string)) (string, []domain.Message, error) {
+	if b.tools == nil {
+		var reply string
+		var err error
+		if onDelta != nil {
+			reply, err = b.GenerateStream(ctx, messages, system, onDelta)
+		} else {
+			reply, err = b.GenerateWithContext(ctx, messages, system)
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		return reply, []domain.Message{domain.NewTextMessage(domain.RoleAssistant, reply)}, nil
+	}
+
+	session := &domain.Session{ChannelID: channelID, History: append([]domain.Message(nil), messages...)}
+	toolEvents := domain.ToolEventsFrom(ctx)
+	opts := []RunOption{WithSystemPrompt(system), WithEventHandler(func(ev RunEvent) {
+		switch {
+		case ev.Type == EventTextDelta && onDelta != nil:
+			onDelta(ev.Text)
+		case ev.Type == EventToolStarted && toolEvents != nil:
+			toolEvents(domain.ToolEvent{Tool: ev.ToolUse.Name, Input: ev.ToolUse.Input})
+		case ev.Type == EventToolFinished && toolEvents != nil:
+			toolEvents(domain.ToolEvent{Tool: ev.ToolUse.Name, Input: ev.ToolUse.Input, Finished: true, Result: ev.Result.Content, IsError: ev.Result.IsError})
+		}
+	})}
+	if b.toolPolicy != nil {
+		opts = append(opts, WithToolPolicy(b.toolPolicy(channelID)))
+	}
+	reply, err := b.Run(ctx, session, opts...)
+	return reply, session.History[len(messages):], err
+}
+
+// executeTool runs a single tool call and converts the outcome into a
+// ToolResultBlock. Errors (unknown tool, schema validation, execution) are
+// reported to the model with IsError set rather than aborting the loop. A
+// policy denial or a call refused by the approval gate is reported as a JSON
+// object (see toolErrorContent) so the model can tell it apart from a failed
+// execution.
+func (b *Brain) executeTool(ctx context.Context, d *ToolDispatcher, use domain.ToolUseBlock) domain.ToolResultBlock {
+	fail := func(content string) domain.ToolResultBlock {
+		return domain.ToolResultBlock{ToolUseID: use.ToolUseID, Content: content, IsError: true}
+	}
+	tool, err := d.Prepare(use.Name, use.Input)
+	var denied *tooling.DeniedError
+	if errors.As(err, &denied) {
+		b.log().Warn("tool call denied", "tool", use.Name, "policy", denied.Layer)
+		return fail(toolErrorContent("tool_denied", use.Name, denied.Error()+"; do not retry it, use another tool or answer without it", "policy", denied.Layer))
+	}
+	if err != nil {
+		b.log().Warn("tool call failed", "tool", use.Name, "error", err)
+		return fail(err.Error())
+	}
+	if b.approvals != nil && b.approvals.Requires(use.Name, use.Input) {
+		channelID, prompter, _ := approval.FromContext(ctx)
+		if err := b.approvals.Authorize(ctx, channelID, use.Name, use.Input, prompter); err != nil {
+			b.log().Warn("tool call not approved", "tool", use.Name, "channel", channelID, "error", err)
+			return fail(toolErrorContent("approval_denied", use.Name, "the user did not approve this call ("+err.Error()+"); do not retry it unless they ask"))
+		}
+	}
+	res, err := tooling.CallTool(ctx, tool, use.Input)
+	if err != nil {
+		b.log().Warn("tool call failed", "tool", use.Name, "error", err)
+		return fail(err.Error())
+	}
+	content := ""
+	if res != nil {
+		content = res.Data
+	}
+	return domain.ToolResultBlock{ToolUseID: use.ToolUseID, Content: content}
+}
+
+// notRunResults answers tool calls the loop did not execute, so that no call
+// in the history is left without a result.
+func notRunResults(uses []domain.ToolUseBlock, reason string) []domain.ContentBlock {
+	results := make([]domain.ContentBlock, 0, len(uses))
+	for _, use := range uses {
+		results = append(results, domain.ToolResultBlock{
+			ToolUseID: use.ToolUseID,
+			Content:   toolErrorContent("tool_not_run", use.Name, "the call was not run: "+reason),
+			IsError:   true,
+		})
+	}
+	return results
+}
+
+// toolErrorContent renders a refused tool call as the tool result content:
+// {"error":code,"tool":...,"message":...} plus any extra key/value pairs.
+func toolErrorContent(code, tool, message string, extra ...string) string {
+	m := map[string]string{"error": code, "tool": tool, "message": message}
+	for i := 0; i+1 < len(extra); i += 2 {
+		m[extra[i]] = extra[i+1]
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+// runLimits returns the configured budgets, falling back to defaults.
+func (b *Brain) runLimits() (maxTurns, maxToolCalls int) {
+	maxTurns, maxToolCalls = b.maxTurns, b.maxToolCalls
+	if maxTurns <= 0 {
+		maxTurns = defaultMaxTurns
+	}
+	if maxToolCalls <= 0 {
+		maxToolCalls = defaultMaxToolCalls
+	}
+	return maxTurns, maxToolCalls
+}
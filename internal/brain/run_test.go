@@ -0,0 +1,433 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
+)
+
+// scriptedChatProvider returns one scripted response per Chat call and records
+// every request it receives.
+type scriptedChatProvider struct {
+	responses []domain.ChatResponse
+	requests  []domain.ChatRequest
+}
+
+func (s *scriptedChatProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	return "", errors.New("generate should not be called")
+}
+
+func (s *scriptedChatProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	s.requests = append(s.requests, req)
+	idx := len(s.requests) - 1
+	if idx >= len(s.responses) {
+		idx = len(s.responses) - 1
+	}
+	return s.responses[idx], nil
+}
+
+func toolUseResponse(id, name, input string) domain.ChatResponse {
+	return domain.ChatResponse{
+		Content:    []domain.ContentBlock{domain.ToolUseBlock{ToolUseID: id, Name: name, Input: json.RawMessage(input)}},
+		StopReason: domain.StopToolUse,
+	}
+}
+
+func textResponse(text string) domain.ChatResponse {
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: text}}, StopReason: domain.StopEndTurn}
+}
+
+func newRunDispatcher(tools ...tooling.SchemaTool) *ToolDispatcher {
+	reg := tooling.NewToolRegistry()
+	for _, t := range tools {
+		_ = reg.Register(t)
+	}
+	return NewToolDispatcher(reg)
+}
+
+func TestBrain_Run_WhenModelCallsTool_ShouldExecuteAndFeedResultBack(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{
+		toolUseResponse("tu_1", "calc", `{"x":2}`),
+		textResponse("the answer is calc-result"),
+	}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"))))
+	session := &domain.Session{History: []domain.Message{domain.NewTextMessage(domain.RoleUser, "compute")}}
+
+	var events []RunEventType
+	got, err := b.Run(context.Background(), session,
+		WithSystemPrompt("sys"),
+		WithEventHandler(func(e RunEvent) { events = append(events, e.Type) }),
+	)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got != "the answer is calc-result" {
+		t.Errorf("want final text, got %q", got)
+	}
+	if len(provider.requests) != 2 {
+		t.Fatalf("expected 2 model turns, got %d", len(provider.requests))
+	}
+	if len(provider.requests[0].Tools) != 1 || provider.requests[0].System != "sys" {
+		t.Errorf("first request should carry tools and system: %+v", provider.requests[0])
+	}
+	second := provider.requests[1].Messages
+	if len(second) != 3 {
+		t.Fatalf("expected user+assistant+tool_result messages, got %d", len(second))
+	}
+	tr, ok := second[2].Blocks()[0].(domain.ToolResultBlock)
+	if !ok || tr.ToolUseID != "tu_1" || tr.Content != "calc-result" || tr.IsError {
+		t.Errorf("unexpected tool result: %#v", second[2].Blocks())
+	}
+	if len(session.History) != 4 {
+		t.Errorf("session history should include the final assistant reply, got %d messages", len(session.History))
+	}
+	want := []RunEventType{EventToolStarted, EventToolFinished, EventTextDelta}
+	if len(events) != len(want) {
+		t.Fatalf("events: want %v, got %v", want, events)
+	}
+	for i := range want {
+		if events[i] != want[i] {
+			t.Errorf("events[%d]: want %s, got %s", i, want[i], events[i])
+		}
+	}
+}
+
+func TestBrain_Run_WhenToolFails_ShouldReturnErrorResultToModel(t *testing.T) {
+	failing := newFake("calc")
+	failing.callErr = errors.New("kaboom")
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{
+		toolUseResponse("tu_1", "calc", `{"x":1}`),
+		toolUseResponse("tu_2", "missing", `{}`),
+		textResponse("gave up"),
+	}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(failing)))
+	session := &domain.Session{History: []domain.Message{domain.NewTextMessage(domain.RoleUser, "go")}}
+
+	got, err := b.Run(context.Background(), session)
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got != "gave up" {
+		t.Errorf("want %q, got %q", "gave up", got)
+	}
+	for _, idx := range []int{2, 4} {
+		tr := session.History[idx].Blocks()[0].(domain.ToolResultBlock)
+		if !tr.IsError {
+			t.Errorf("history[%d]: expected IsError tool result, got %#v", idx, tr)
+		}
+	}
+}
+
+func TestBrain_Run_WhenToolDeniedByPolicy_ShouldReturnStructuredErrorWithoutExecuting(t *testing.T) {
+	shell := newFake("shell")
+	shell.callErr = errors.New("shell must not run")
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{
+		toolUseResponse("tu_1", "shell", `{"x":1}`),
+		textResponse("ok"),
+	}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(shell, newFake("web"))))
+	session := &domain.Session{History: []domain.Message{domain.NewTextMessage(domain.RoleUser, "run ls")}}
+
+	policy := tooling.Policy{}.With("external channel", domain.ToolRule{Deny: []string{"shell"}})
+	if _, err := b.Run(context.Background(), session, WithToolPolicy(policy)); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if tools := provider.requests[0].Tools; len(tools) != 1 || tools[0].Name != "web" {
+		t.Errorf("denied tool should not be offered, got %v", tools)
+	}
+	tr := provider.requests[1].Messages[2].Blocks()[0].(domain.ToolResultBlock)
+	var content struct{ Error, Tool, Policy string }
+	if err := json.Unmarshal([]byte(tr.Content), &content); err != nil {
+		t.Fatalf("tool result is not JSON: %q", tr.Content)
+	}
+	if !tr.IsError || content.Error != "tool_denied" || content.Tool != "shell" || content.Policy != "external channel" {
+		t.Errorf("unexpected denial result %#v", tr)
+	}
+}
+
+func TestBrain_Run_WhenToolNeedsApproval_ShouldAskChannelBeforeExecuting(t *testing.T) {
+	gate := approval.NewGate(approval.WithRequire("calc"))
+	for _, tc := range []struct {
+		name     string
+		prompter approval.Prompter
+		wantErr  bool
+	}{
+		{"approved", approval.PrompterFunc(func(ctx context.Context, req approval.Request) error {
+			go func() { _ = gate.Resolve(req.ChannelID, req.ID, approval.Approve, "test") }()
+			return nil
+		}), false},
+		{"denied", approval.PrompterFunc(func(ctx context.Context, req approval.Request) error {
+			go func() { _ = gate.Resolve(req.ChannelID, req.ID, approval.Deny, "test") }()
+			return nil
+		}), true},
+		{"no prompter", nil, true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			provider := &scriptedChatProvider{responses: []domain.ChatResponse{
+				toolUseResponse("tu_1", "calc", `{"x":2}`),
+				textResponse("done"),
+			}}
+			b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"))), WithApprovals(gate))
+			session := &domain.Session{History: []domain.Message{domain.NewTextMessage(domain.RoleUser, "compute")}}
+			ctx := context.Background()
+			if tc.prompter != nil {
+				ctx = approval.NewContext(ctx, "ws-1", tc.prompter)
+			}
+			if _, err := b.Run(ctx, session); err != nil {
+				t.Fatalf("Run: %v", err)
+			}
+			tr := provider.requests[1].Messages[2].Blocks()[0].(domain.ToolResultBlock)
+			if tc.wantErr {
+				if !tr.IsError || !strings.Contains(tr.Content, `"error":"approval_denied"`) {
+					t.Errorf("want structured approval error, got %#v", tr)
+				}
+			} else if tr.IsError || tr.Content != "calc-result" {
+				t.Errorf("want executed tool, got %#v", tr)
+			}
+		})
+	}
+}
+
+func TestBrain_Run_WhenMaxTurnsExceeded_ShouldReturnErrMaxTurns(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{toolUseResponse("tu", "calc", `{"x":1}`)}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"))), WithRunLimits(3, 100))
+
+	_, err := b.Run(context.Background(), &domain.Session{})
+	if !errors.Is(err, ErrMaxTurnsExceeded) {
+		t.Errorf("expected ErrMaxTurnsExceeded, got %v", err)
+	}
+	if len(provider.requests) != 3 {
+		t.Errorf("expected 3 turns, got %d", len(provider.requests))
+	}
+}
+
+func TestBrain_Run_WhenMaxToolCallsExceeded_ShouldStopBeforeExecuting(t *testing.T) {
+	tool := newFake("calc")
+	calls := 0
+	counting := &countingTool{fakeSchemaTool: tool, calls: &calls}
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{{
+		Content: []domain.ContentBlock{
+			domain.ToolUseBlock{ToolUseID: "a", Name: "calc", Input: json.RawMessage(`{"x":1}`)},
+			domain.ToolUseBlock{ToolUseID: "b", Name: "calc", Input: json.RawMessage(`{"x":2}`)},
+		},
+	}}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(counting)), WithRunLimits(10, 1))
+	session := &domain.Session{}
+
+	_, err := b.Run(context.Background(), session)
+	if !errors.Is(err, ErrMaxToolCallsExceeded) {
+		t.Errorf("expected ErrMaxToolCallsExceeded, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected exactly 1 tool execution, got %d", calls)
+	}
+	assertToolCallsAnswered(t, session.History, "b")
+}
+
+func TestBrain_Run_WhenContextCanceledBetweenToolCalls_ShouldAnswerTheRest(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	cancelling := &cancellingTool{fakeSchemaTool: newFake("calc"), calls: &calls, cancel: cancel}
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{{
+		Content: []domain.ContentBlock{
+			domain.ToolUseBlock{ToolUseID: "a", Name: "calc", Input: json.RawMessage(`{"x":1}`)},
+			domain.ToolUseBlock{ToolUseID: "b", Name: "calc", Input: json.RawMessage(`{"x":2}`)},
+		},
+	}}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(cancelling)))
+	session := &domain.Session{}
+
+	_, err := b.Run(ctx, session)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected exactly 1 tool execution, got %d", calls)
+	}
+	assertToolCallsAnswered(t, session.History, "b")
+}
+
+func TestBrain_Run_WhenModelCallsToolWithoutDispatcher_ShouldAnswerWithError(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{toolUseResponse("tu", "calc", `{"x":1}`)}}
+	b := NewBrain(provider)
+	session := &domain.Session{}
+
+	if _, err := b.Run(context.Background(), session); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	assertToolCallsAnswered(t, session.History, "tu")
+}
+
+// assertToolCallsAnswered checks that every tool call in history has a
+// result, and that the calls in notRun were answered with a not-run error.
+func assertToolCallsAnswered(t *testing.T, history []domain.Message, notRun ...string) {
+	t.Helper()
+	results := map[string]domain.ToolResultBlock{}
+	for _, m := range history {
+		for _, blk := range m.Blocks() {
+			if r, ok := blk.(domain.ToolResultBlock); ok {
+				results[r.ToolUseID] = r
+			}
+		}
+	}
+	for _, m := range history {
+		for _, blk := range m.Blocks() {
+			if u, ok := blk.(domain.ToolUseBlock); ok {
+				if _, ok := results[u.ToolUseID]; !ok {
+					t.Errorf("tool call %q has no result", u.ToolUseID)
+				}
+			}
+		}
+	}
+	for _, id := range notRun {
+		if r := results[id]; !r.IsError || !strings.Contains(r.Content, "tool_not_run") {
+			t.Errorf("tool call %q: expected a not-run error result, got %+v", id, r)
+		}
+	}
+}
+
+func TestBrain_Run_WhenNoTools_ShouldReturnSingleReply(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse("hello")}}
+	b := NewBrain(provider)
+
+	got, err := b.Run(context.Background(), &domain.Session{History: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if got != "hello" || len(provider.requests[0].Tools) != 0 {
+		t.Errorf("unexpected result %q / tools %v", got, provider.requests[0].Tools)
+	}
+}
+
+func TestBrain_Run_WhenSessionNil_ShouldReturnError(t *testing.T) {
+	b := NewBrain(&mockProvider{})
+	if _, err := b.Run(context.Background(), nil); !errors.Is(err, ErrNilSession) {
+		t.Errorf("expected ErrNilSession, got %v", err)
+	}
+}
+
+func TestBrain_Run_WhenContextCanceled_ShouldReturnContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	b := NewBrain(&scriptedChatProvider{responses: []domain.ChatResponse{textResponse("x")}})
+	if _, err := b.Run(ctx, &domain.Session{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+// countingTool wraps fakeSchemaTool and counts Call invocations.
+type countingTool struct {
+	*fakeSchemaTool
+	calls *int
+}
+
+func (c *countingTool) Call(args json.RawMessage) (*domain.ToolResult, error) {
+	*c.calls++
+	return c.fakeSchemaTool.Call(args)
+}
+
+// cancellingTool wraps fakeSchemaTool, counts Call invocations and cancels
+// the Run context on the first one.
+type cancellingTool struct {
+	*fakeSchemaTool
+	calls  *int
+	cancel context.CancelFunc
+}
+
+func (c *cancellingTool) Call(args json.RawMessage) (*domain.ToolResult, error) {
+	*c.calls++
+	c.cancel()
+	return c.fakeSchemaTool.Call(args)
+}
+
+func TestBrain_RunMessages_WhenModelCallsTool_ShouldReportEventsAndReturnAddedMessages(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{
+		toolUseResponse("tu_1", "calc", `{"x":2}`),
+		textResponse("it is calc-result"),
+	}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"))))
+	var events []domain.ToolEvent
+	ctx := domain.WithToolEvents(context.Background(), func(ev domain.ToolEvent) { events = append(events, ev) })
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "compute")}
+
+	var deltas []string
+	reply, added, err := b.RunMessages(ctx, "general", messages, "be brief", func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("RunMessages: %v", err)
+	}
+	if reply != "it is calc-result" || len(deltas) == 0 {
+		t.Errorf("reply %q, deltas %q", reply, deltas)
+	}
+	if len(added) != 3 || added[0].Role != domain.RoleAssistant || added[1].Role != domain.RoleTool || added[2].Role != domain.RoleAssistant {
+		t.Errorf("expected tool call, tool result and reply, got %+v", added)
+	}
+	if len(messages) != 1 {
+		t.Errorf("caller's messages were modified: %+v", messages)
+	}
+	if len(events) != 2 || events[0].Tool != "calc" || events[0].Finished || !events[1].Finished || events[1].Result != "calc-result" {
+		t.Errorf("unexpected tool events %+v", events)
+	}
+	if provider.requests[0].System != "be brief" {
+		t.Errorf("system = %q", provider.requests[0].System)
+	}
+}
+
+func TestBrain_RunMessages_WhenRunFails_ShouldReturnToolCallsAlreadyMade(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{toolUseResponse("tu_1", "calc", `{"x":2}`)}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"))), WithRunLimits(1, 0))
+
+	_, added, err := b.RunMessages(context.Background(), "general", []domain.Message{domain.NewTextMessage(domain.RoleUser, "compute")}, "", nil)
+	if !errors.Is(err, ErrMaxTurnsExceeded) {
+		t.Fatalf("err = %v, want ErrMaxTurnsExceeded", err)
+	}
+	if len(added) != 2 || added[0].Role != domain.RoleAssistant || added[1].Role != domain.RoleTool {
+		t.Errorf("expected the tool call and its result, got %+v", added)
+	}
+}
+
+func TestBrain_RunMessages_WhenNoTools_ShouldAnswerWithReply(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse("hello")}}
+	b := NewBrain(provider)
+
+	reply, added, err := b.RunMessages(context.Background(), "general", []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}, "", nil)
+	if err != nil {
+		t.Fatalf("RunMessages: %v", err)
+	}
+	if reply != "hello" || len(added) != 1 || added[0].Role != domain.RoleAssistant {
+		t.Errorf("reply %q, added %+v", reply, added)
+	}
+	if len(provider.requests[0].Tools) != 0 {
+		t.Errorf("no tools should be offered, got %v", provider.requests[0].Tools)
+	}
+}
+
+func TestBrain_RunMessages_WhenChannelToolPolicySet_ShouldApplyItPerChannel(t *testing.T) {
+	provider := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse("ok")}}
+	b := NewBrain(provider, WithTools(newRunDispatcher(newFake("calc"), newFake("shell"))),
+		WithChannelToolPolicy(func(channelID string) tooling.Policy {
+			var p tooling.Policy
+			if channelID == "whatsapp-1" {
+				p = p.With("external channel", domain.ToolRule{Deny: []string{"shell"}})
+			}
+			return p
+		}))
+	messages := []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}
+
+	for channelID, want := range map[string]int{"general": 2, "whatsapp-1": 1} {
+		provider.requests = nil
+		if _, _, err := b.RunMessages(context.Background(), channelID, messages, "", nil); err != nil {
+			t.Fatalf("RunMessages: %v", err)
+		}
+		tools := provider.requests[0].Tools
+		if len(tools) != want || (channelID == "whatsapp-1" && tools[0].Name != "calc") {
+			t.Errorf("%s: offered %v, want %d tools", channelID, tools, want)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package brain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
+)
+
+// defaultStructuredAttempts is the number of tries GenerateStructured makes
+// when WithStructuredAttempts is not used: the first reply plus two repairs.
+const defaultStructuredAttempts = 3
+
+// ErrInvalidStructuredOutput is returned by GenerateStructured when no reply
+// within the attempt budget conforms to the schema. The last validation error
+// is wrapped alongside it.
+var ErrInvalidStructuredOutput = errors.New("brain: structured output does not match schema")
+
+// WithStructuredAttempts sets how many replies GenerateStructured asks for
+// before giving up, counting the first. Values below 1 are ignored.
+func WithStructuredAttempts(n int) Option {
+	return func(b *Brain) {
+		if n > 0 {
+			b.structuredAttempts = n
+		}
+	}
+}
+
+// GenerateStructured asks the model for a JSON value conforming to schema (a
+// JSON Schema document) and returns it once it validates. The schema is sent
+// both in the system prompt and as ChatRequest.ResponseSchema, so providers
+// with a native JSON mode enforce it. A reply that fails validation is sent
+// back with the validator's errors and the model asked to correct it, up to
+// the WithStructuredAttempts budget.
+func (b *Brain) GenerateStructured(ctx context.Context, prompt string, schema string) (json.RawMessage, error) {
+	attempts := b.structuredAttempts
+	if attempts == 0 {
+		attempts = defaultStructuredAttempts
+	}
+	req := domain.ChatRequest{
+		System:         structuredSystemPrompt(schema),
+		Messages:       []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)},
+		ResponseSchema: json.RawMessage(schema),
+	}
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		resp, err := b.Chat(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		text := resp.Text()
+		out := json.RawMessage(tooling.ExtractJSON(text))
+		if len(out) == 0 {
+			// Validate the reply itself, so the repair prompt says why it is not JSON.
+			out = json.RawMessage(text)
+		}
+		lastErr = tooling.ValidateAgainstSchema(out, schema)
+		if lastErr == nil {
+			return out, nil
+		}
+		if errors.Is(lastErr, tooling.ErrInvalidSchema) {
+			return nil, fmt.Errorf("brain: %w", lastErr)
+		}
+		b.log().Warn("structured output rejected", "attempt", attempt, "error", lastErr)
+		req.Messages = append(req.Messages,
+			domain.NewTextMessage(domain.RoleAssistant, text),
+			domain.NewTextMessage(domain.RoleUser, repairPrompt(lastErr)),
+		)
+	}
+	return nil, fmt.Errorf("%w after %d attempts: %w", ErrInvalidStructuredOutput, attempts, lastErr)
+}
+
+// GenerateInto is GenerateStructured for a Go type: the schema is reflected
+// from T (see tooling.GenerateSchema) and the validated reply decoded into it.
+func GenerateInto[T any](ctx context.Context, b *Brain, prompt string) (T, error) {
+	var out T
+	schema := tooling.GenerateSchema(&out)
+	if schema == "" {
+		return out, fmt.Errorf("brain: cannot generate schema for %T", out)
+	}
+	raw, err := b.GenerateStructured(ctx, prompt, schema)
+	if err != nil {
+		return out, err
+	}
+	if err := json.Unmarshal(raw, &out); err != nil {
+		return out, fmt.Errorf("brain: decode structured output: %w", err)
+	}
+	return out, nil
+}
+
+// structuredSystemPrompt asks for a bare JSON reply conforming to schema, for
+// providers that have no native JSON mode.
+func structuredSystemPrompt(schema string) string {
+	return "Reply with only a JSON value that conforms to this JSON Schema. " +
+		"Do not add prose or code fences.\n\n" + schema
+}
+
+// repairPrompt reports a rejected reply's validation error back to the model.
+func repairPrompt(err error) string {
+	return fmt.Sprintf("Your reply does not conform to the schema: %v\n\nReply again with only the corrected JSON.", err)
+}
@@ -0,0 +1,114 @@
+package brain
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	ironctx "ironclaw/internal/context"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
+)
+
+const personSchema = `{"type":"object","properties":{"name":{"type":"string"},"age":{"type":"integer"}},"required":["name","age"]}`
+
+func TestGenerateStructured_WhenReplyValid_ShouldSendSchemaAndReturnJSON(t *testing.T) {
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse(`{"name":"Ada","age":36}`)}}
+	b := NewBrain(p)
+
+	out, err := b.GenerateStructured(context.Background(), "who?", personSchema)
+	if err != nil {
+		t.Fatalf("GenerateStructured: %v", err)
+	}
+	if string(out) != `{"name":"Ada","age":36}` {
+		t.Errorf("out = %s", out)
+	}
+	req := p.requests[0]
+	if string(req.ResponseSchema) != personSchema || !strings.Contains(req.System, personSchema) {
+		t.Errorf("request = %+v, want schema in ResponseSchema and System", req)
+	}
+}
+
+func TestGenerateStructured_WhenReplyFenced_ShouldExtractJSON(t *testing.T) {
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse("Here:\n```json\n{\"name\":\"Ada\",\"age\":36}\n```")}}
+
+	out, err := NewBrain(p).GenerateStructured(context.Background(), "who?", personSchema)
+	if err != nil || string(out) != `{"name":"Ada","age":36}` {
+		t.Errorf("GenerateStructured = %s, %v", out, err)
+	}
+}
+
+func TestGenerateStructured_WhenReplyInvalid_ShouldRepromptWithValidationError(t *testing.T) {
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{
+		textResponse(`{"name":"Ada"}`),
+		textResponse(`{"name":"Ada","age":36}`),
+	}}
+
+	out, err := NewBrain(p).GenerateStructured(context.Background(), "who?", personSchema)
+	if err != nil || string(out) != `{"name":"Ada","age":36}` {
+		t.Fatalf("GenerateStructured = %s, %v", out, err)
+	}
+	if len(p.requests) != 2 {
+		t.Fatalf("calls = %d, want 2", len(p.requests))
+	}
+	msgs := p.requests[1].Messages
+	if len(msgs) != 3 || msgs[1].Role != domain.RoleAssistant || !strings.Contains(ironctx.MessageText(msgs[2]), "age") {
+		t.Errorf("repair messages = %+v", msgs)
+	}
+}
+
+func TestGenerateStructured_WhenAttemptsExhausted_ShouldReturnErrInvalidStructuredOutput(t *testing.T) {
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse("not json")}}
+
+	_, err := NewBrain(p, WithStructuredAttempts(2)).GenerateStructured(context.Background(), "who?", personSchema)
+	if !errors.Is(err, ErrInvalidStructuredOutput) {
+		t.Errorf("err = %v, want ErrInvalidStructuredOutput", err)
+	}
+	if len(p.requests) != 2 {
+		t.Errorf("calls = %d, want 2", len(p.requests))
+	}
+}
+
+func TestGenerateStructured_WhenSchemaInvalid_ShouldNotRetry(t *testing.T) {
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse(`{}`)}}
+
+	_, err := NewBrain(p).GenerateStructured(context.Background(), "who?", `{"type":"invalid"}`)
+	if !errors.Is(err, tooling.ErrInvalidSchema) {
+		t.Errorf("err = %v, want ErrInvalidSchema", err)
+	}
+	if len(p.requests) != 1 {
+		t.Errorf("calls = %d, want 1", len(p.requests))
+	}
+}
+
+func TestGenerateStructured_WhenProviderHasNoChat_ShouldUsePromptInstructions(t *testing.T) {
+	p := &mockProvider{response: `{"name":"Ada","age":36}`}
+
+	out, err := NewBrain(p).GenerateStructured(context.Background(), "who?", personSchema)
+	if err != nil || string(out) != `{"name":"Ada","age":36}` {
+		t.Fatalf("GenerateStructured = %s, %v", out, err)
+	}
+	if !strings.Contains(p.prompt, personSchema) {
+		t.Errorf("prompt = %q, want schema", p.prompt)
+	}
+}
+
+func TestGenerateInto_ShouldDecodeIntoStruct(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+	p := &scriptedChatProvider{responses: []domain.ChatResponse{textResponse(`{"name":"Ada","age":36}`)}}
+
+	got, err := GenerateInto[person](context.Background(), NewBrain(p), "who?")
+	if err != nil {
+		t.Fatalf("GenerateInto: %v", err)
+	}
+	if got != (person{Name: "Ada", Age: 36}) {
+		t.Errorf("got %+v", got)
+	}
+	if !strings.Contains(string(p.requests[0].ResponseSchema), `"age"`) {
+		t.Errorf("schema = %s", p.requests[0].ResponseSchema)
+	}
+}
@@ -13,6 +13,7 @@ import (
 // returned JSON arguments against each tool's schema before execution.
 type ToolDispatcher struct {
 	registry *tooling.ToolRegistry
+	policy   tooling.Policy
 }
 
 // NewToolDispatcher creates a dispatcher backed by the given registry.
@@ -24,17 +25,46 @@ func NewToolDispatcher(registry *tooling.ToolRegistry) *ToolDispatcher {
 	return &ToolDispatcher{registry: registry}
 }
 
+// WithPolicy returns a dispatcher over the same registry that additionally
+// enforces p: denied tools are hidden from FormatToolsForLLM and calls of
+// denied tools or operations refused by HandleToolCall. The receiver is not modified.
+func (d *ToolDispatcher) WithPolicy(p tooling.Policy) *ToolDispatcher {
+	return &ToolDispatcher{registry: d.registry, policy: d.policy.Merge(p)}
+}
+
 // FormatToolsForLLM returns domain.ToolDefinition slices ready to be serialised
-// into the LLM function-calling request (e.g. Anthropic tools array).
+// into the LLM function-calling request (e.g. Anthropic tools array). Tools
+// denied by the dispatcher's policy are omitted.
 func (d *ToolDispatcher) FormatToolsForLLM() []domain.ToolDefinition {
-	return d.registry.Definitions()
+	defs := d.registry.Definitions()
+	out := defs[:0]
+	for _, def := range defs {
+		if d.policy.Allows(def.Name) {
+			out = append(out, def)
+		}
+	}
+	return out
 }
 
 // HandleToolCall looks up the tool by name, validates the raw JSON arguments
 // against the tool's JSON Schema, and only then calls the tool. If the tool is
 // unknown or validation fails, a descriptive error is returned and the tool is
-// never invoked.
+// never invoked. A tool denied by the policy yields a *tooling.DeniedError.
 func (d *ToolDispatcher) HandleToolCall(name string, args json.RawMessage) (*domain.ToolResult, error) {
+	tool, err := d.Prepare(name, args)
+	if err != nil {
+		return nil, err
+	}
+	return tool.Call(args)
+}
+
+// Prepare performs HandleToolCall's checks (policy, lookup, schema
+// validation) without calling the tool, so callers can gate the call, e.g.
+// on human approval, before running it.
+func (d *ToolDispatcher) Prepare(name string, args json.RawMessage) (tooling.SchemaTool, error) {
+	if err := d.policy.CheckCall(name, args); err != nil {
+		return nil, err
+	}
 	tool, err := d.registry.Get(name)
 	if err != nil {
 		return nil, err // "unknown tool: ..."
@@ -45,6 +75,5 @@ func (d *ToolDispatcher) HandleToolCall(name string, args json.RawMessage) (*dom
 	if err := tooling.ValidateAgainstSchema(args, schema); err != nil {
 		return nil, fmt.Errorf("schema validation failed for tool %q: %w", name, err)
 	}
-
-	return tool.Call(args)
+	return tool, nil
 }
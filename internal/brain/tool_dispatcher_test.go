@@ -350,3 +350,45 @@ func TestToolDispatcher_Integration_CalculatorToolThroughDispatcher(t *testing.T
 		t.Error("Expected validation error for wrong type")
 	}
 }
+
+// =============================================================================
+// WithPolicy
+// =============================================================================
+
+func TestToolDispatcher_WithPolicy_ShouldHideAndRefuseDeniedTools(t *testing.T) {
+	reg := tooling.NewToolRegistry()
+	shell := newFake("shell")
+	shell.callResult = nil
+	shell.callErr = errors.New("shell must not run")
+	_ = reg.Register(shell)
+	_ = reg.Register(newFake("web"))
+	base := NewToolDispatcher(reg)
+	d := base.WithPolicy(tooling.Policy{}.With("external channel", domain.ToolRule{Deny: []string{"shell"}}))
+
+	defs := d.FormatToolsForLLM()
+	if len(defs) != 1 || defs[0].Name != "web" {
+		t.Errorf("expected only web, got %v", defs)
+	}
+	if _, err := d.HandleToolCall("shell", json.RawMessage(`{"x":1}`)); !errors.Is(err, tooling.ErrToolDenied) {
+		t.Errorf("expected ErrToolDenied, got %v", err)
+	}
+	if len(base.FormatToolsForLLM()) != 2 {
+		t.Error("WithPolicy must not modify the receiver")
+	}
+}
+
+func TestToolDispatcher_WithPolicy_ShouldRefuseDeniedOperationsOnly(t *testing.T) {
+	reg := tooling.NewToolRegistry()
+	_ = reg.Register(newFake("git"))
+	d := NewToolDispatcher(reg).WithPolicy(tooling.Policy{}.With("channel", domain.ToolRule{Deny: []string{"git.push"}}))
+
+	if defs := d.FormatToolsForLLM(); len(defs) != 1 {
+		t.Errorf("expected git listed, got %v", defs)
+	}
+	if _, err := d.HandleToolCall("git", json.RawMessage(`{"x":1,"operation":"push"}`)); !errors.Is(err, tooling.ErrToolDenied) {
+		t.Errorf("expected git push refused, got %v", err)
+	}
+	if _, err := d.HandleToolCall("git", json.RawMessage(`{"x":1,"operation":"status"}`)); err != nil {
+		t.Errorf("expected git status allowed, got %v", err)
+	}
+}
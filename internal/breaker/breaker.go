@@ -0,0 +1,290 @@
+// Package breaker implements per-provider circuit breakers. A provider that
+// fails FailureThreshold times in a row has its circuit opened: calls to it
+// fail immediately with ErrOpen, so brain failover moves straight on to the
+// next provider, until ProbeInterval has passed and a single probe request is
+// let through (half-open). The probe closes the circuit on success and
+// reopens it on failure.
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// ErrOpen is matched (via errors.Is) by the error returned for calls to a
+// provider whose circuit is open.
+var ErrOpen = errors.New("breaker: circuit open")
+
+// State is the state of a circuit.
+type State int
+
+const (
+	Closed   State = iota // Calls pass through
+	Open                  // Calls are refused until the probe interval has passed
+	HalfOpen              // One probe call is in flight or allowed
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the state as its String form.
+func (s State) MarshalText() ([]byte, error) { return []byte(s.String()), nil }
+
+// Config controls when circuits open and how often they are probed.
+type Config struct {
+	FailureThreshold int           // Consecutive failures that open the circuit
+	ProbeInterval    time.Duration // How long an open circuit waits before a probe
+}
+
+// DefaultConfig returns sensible breaker defaults.
+func DefaultConfig() Config {
+	return Config{
+		FailureThreshold: 5,
+		ProbeInterval:    30 * time.Second,
+	}
+}
+
+// FromConfig converts the JSON config, using DefaultConfig for zero fields.
+func FromConfig(c domain.BreakerConfig) Config {
+	cfg := DefaultConfig()
+	if c.FailureThreshold > 0 {
+		cfg.FailureThreshold = c.FailureThreshold
+	}
+	if c.ProbeInterval > 0 {
+		cfg.ProbeInterval = time.Duration(c.ProbeInterval) * time.Millisecond
+	}
+	return cfg
+}
+
+// Validate checks that all Config fields are within acceptable ranges.
+func (c Config) Validate() error {
+	if c.FailureThreshold < 1 {
+		return errors.New("breaker: FailureThreshold must be >= 1")
+	}
+	if c.ProbeInterval <= 0 {
+		return errors.New("breaker: ProbeInterval must be > 0")
+	}
+	return nil
+}
+
+// Status is a snapshot of one circuit, as served by the gateway.
+type Status struct {
+	Name      string    `json:"name"`
+	State     State     `json:"state"`
+	Failures  int       `json:"failures"`            // Consecutive failures
+	LastError string    `json:"lastError,omitempty"` // Most recent failure
+	Since     time.Time `json:"since"`               // When the circuit entered State
+}
+
+// Breaker is the circuit of one provider. It is safe for concurrent use.
+type Breaker struct {
+	name     string
+	cfg      Config
+	now      func() time.Time
+	onChange func(Status)
+
+	mu       sync.Mutex
+	state    State
+	failures int
+	lastErr  string
+	since    time.Time
+	probing  bool // HalfOpen: the probe call has been handed out
+}
+
+// Allow reports whether a call may be made now. It returns an error matching
+// ErrOpen while the circuit is open, or half-open with its probe in flight.
+// Every nil return must be followed by exactly one of Success, Failure and
+// Release.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	var changed bool
+	var err error
+	switch b.state {
+	case Open:
+		if wait := b.cfg.ProbeInterval - b.now().Sub(b.since); wait > 0 {
+			err = fmt.Errorf("%w: %s (next probe in %s)", ErrOpen, b.name, wait.Round(time.Second))
+			break
+		}
+		changed = b.setState(HalfOpen)
+		b.probing = true
+	case HalfOpen:
+		if b.probing {
+			err = fmt.Errorf("%w: %s (probe in progress)", ErrOpen, b.name)
+			break
+		}
+		b.probing = true
+	}
+	b.unlock(changed)
+	return err
+}
+
+// Success records a successful call and closes the circuit.
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	b.failures, b.lastErr, b.probing = 0, "", false
+	b.unlock(b.setState(Closed))
+}
+
+// Failure records a failed call. It opens the circuit once FailureThreshold
+// consecutive calls have failed, and reopens it when a probe fails.
+func (b *Breaker) Failure(err error) {
+	b.mu.Lock()
+	b.failures++
+	if err != nil {
+		b.lastErr = err.Error()
+	}
+	b.probing = false
+	var changed bool
+	if b.state == HalfOpen || b.failures >= b.cfg.FailureThreshold {
+		changed = b.setState(Open)
+	}
+	b.unlock(changed)
+}
+
+// Release ends an allowed call whose outcome says nothing about the
+// provider's health (e.g. the caller canceled it). A half-open circuit lets
+// the next call probe instead.
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Status returns a snapshot of the circuit.
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.status()
+}
+
+func (b *Breaker) status() Status {
+	return Status{Name: b.name, State: b.state, Failures: b.failures, LastError: b.lastErr, Since: b.since}
+}
+
+// setState moves the circuit to s, reporting whether that changed it.
+// Callers hold mu.
+func (b *Breaker) setState(s State) bool {
+	if b.state == s {
+		return false
+	}
+	b.state, b.since = s, b.now()
+	return true
+}
+
+// unlock releases mu, then reports a state change to onChange.
+func (b *Breaker) unlock(changed bool) {
+	st := b.status()
+	b.mu.Unlock()
+	if changed && b.onChange != nil {
+		b.onChange(st)
+	}
+}
+
+// Option configures a Set.
+type Option func(*Set)
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Set) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// WithStateChange calls fn whenever a circuit changes state. If fn is nil it
+// is ignored and changes are logged with slog.
+func WithStateChange(fn func(Status)) Option {
+	return func(s *Set) {
+		if fn != nil {
+			s.onChange = fn
+		}
+	}
+}
+
+// Set holds the circuits of all providers, keyed by name, so brains that
+// share a provider (e.g. named agents) share its circuit. It is safe for
+// concurrent use.
+type Set struct {
+	cfg      Config
+	now      func() time.Time
+	onChange func(Status)
+
+	mu       sync.Mutex
+	breakers map[string]*Breaker
+}
+
+// NewSet returns an empty Set whose circuits use cfg. Invalid fields are
+// replaced with DefaultConfig values.
+func NewSet(cfg Config, opts ...Option) *Set {
+	def := DefaultConfig()
+	if cfg.FailureThreshold < 1 {
+		cfg.FailureThreshold = def.FailureThreshold
+	}
+	if cfg.ProbeInterval <= 0 {
+		cfg.ProbeInterval = def.ProbeInterval
+	}
+	s := &Set{
+		cfg:      cfg,
+		now:      time.Now,
+		onChange: logStateChange,
+		breakers: make(map[string]*Breaker),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Get returns the circuit named name, creating it closed on first use.
+func (s *Set) Get(name string) *Breaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b, ok := s.breakers[name]
+	if !ok {
+		b = &Breaker{name: name, cfg: s.cfg, now: s.now, onChange: s.onChange, since: s.now()}
+		s.breakers[name] = b
+	}
+	return b
+}
+
+// Status returns a snapshot of every circuit, sorted by name.
+func (s *Set) Status() []Status {
+	s.mu.Lock()
+	breakers := make([]*Breaker, 0, len(s.breakers))
+	for _, b := range s.breakers {
+		breakers = append(breakers, b)
+	}
+	s.mu.Unlock()
+
+	out := make([]Status, 0, len(breakers))
+	for _, b := range breakers {
+		out = append(out, b.Status())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func logStateChange(st Status) {
+	if st.State == Closed {
+		slog.Info("breaker: circuit closed", "provider", st.Name)
+		return
+	}
+	slog.Warn("breaker: circuit "+st.State.String(), "provider", st.Name, "failures", st.Failures, "error", st.LastError)
+}
@@ -0,0 +1,176 @@
+package breaker
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// fakeClock is a settable clock for WithClock.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestSet(cfg Config) (*Set, *fakeClock, *[]Status) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	var changes []Status
+	s := NewSet(cfg, WithClock(clock.now), WithStateChange(func(st Status) { changes = append(changes, st) }))
+	return s, clock, &changes
+}
+
+var errDown = errors.New("503 service unavailable")
+
+func TestBreaker_WhenThresholdReached_ShouldOpenAndRefuseCalls(t *testing.T) {
+	s, _, changes := newTestSet(Config{FailureThreshold: 3, ProbeInterval: time.Minute})
+	b := s.Get("openai/gpt-4o")
+
+	for i := 0; i < 2; i++ {
+		if err := b.Allow(); err != nil {
+			t.Fatalf("call %d refused: %v", i, err)
+		}
+		b.Failure(errDown)
+	}
+	if st := b.Status(); st.State != Closed || st.Failures != 2 {
+		t.Fatalf("after 2 failures: %+v, want closed", st)
+	}
+	_ = b.Allow()
+	b.Failure(errDown)
+
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("Allow = %v, want ErrOpen", err)
+	}
+	if len(*changes) != 1 || (*changes)[0].State != Open || (*changes)[0].LastError != errDown.Error() {
+		t.Errorf("changes = %+v, want one open", *changes)
+	}
+}
+
+func TestBreaker_WhenSuccessBetweenFailures_ShouldResetCount(t *testing.T) {
+	s, _, _ := newTestSet(Config{FailureThreshold: 2, ProbeInterval: time.Minute})
+	b := s.Get("p")
+
+	_ = b.Allow()
+	b.Failure(errDown)
+	_ = b.Allow()
+	b.Success()
+	_ = b.Allow()
+	b.Failure(errDown)
+
+	if st := b.Status(); st.State != Closed || st.Failures != 1 {
+		t.Errorf("status = %+v, want closed with 1 failure", st)
+	}
+}
+
+func TestBreaker_AfterProbeInterval_ShouldAllowOneProbe(t *testing.T) {
+	s, clock, changes := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+	b := s.Get("p")
+	_ = b.Allow()
+	b.Failure(errDown)
+
+	clock.advance(59 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("before interval: Allow = %v, want ErrOpen", err)
+	}
+	clock.advance(time.Second)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("second call during probe: Allow = %v, want ErrOpen", err)
+	}
+	b.Success()
+
+	if err := b.Allow(); err != nil {
+		t.Fatalf("after successful probe: %v", err)
+	}
+	var states []State
+	for _, c := range *changes {
+		states = append(states, c.State)
+	}
+	if len(states) != 3 || states[0] != Open || states[1] != HalfOpen || states[2] != Closed {
+		t.Errorf("transitions = %v, want open, half-open, closed", states)
+	}
+}
+
+func TestBreaker_WhenProbeFails_ShouldReopenForAnotherInterval(t *testing.T) {
+	s, clock, _ := newTestSet(Config{FailureThreshold: 3, ProbeInterval: time.Minute})
+	b := s.Get("p")
+	for i := 0; i < 3; i++ {
+		_ = b.Allow()
+		b.Failure(errDown)
+	}
+	clock.advance(time.Minute)
+	if err := b.Allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	b.Failure(errDown)
+
+	if st := b.Status(); st.State != Open || !st.Since.Equal(clock.now()) {
+		t.Fatalf("status = %+v, want reopened now", st)
+	}
+	clock.advance(30 * time.Second)
+	if err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Errorf("Allow = %v, want ErrOpen until the next interval", err)
+	}
+}
+
+func TestBreaker_WhenProbeReleased_ShouldLetNextCallProbe(t *testing.T) {
+	s, clock, _ := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+	b := s.Get("p")
+	_ = b.Allow()
+	b.Failure(errDown)
+	clock.advance(time.Minute)
+
+	_ = b.Allow()
+	b.Release()
+
+	if err := b.Allow(); err != nil {
+		t.Errorf("Allow after released probe = %v, want nil", err)
+	}
+	if st := b.Status(); st.State != HalfOpen {
+		t.Errorf("state = %v, want half-open", st.State)
+	}
+}
+
+func TestSet_Get_ShouldShareCircuitByName(t *testing.T) {
+	s, _, _ := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+	_ = s.Get("b").Allow()
+	s.Get("b").Failure(errDown)
+	s.Get("a")
+
+	got := s.Status()
+	if len(got) != 2 || got[0].Name != "a" || got[1].Name != "b" || got[1].State != Open {
+		t.Errorf("Status = %+v, want a closed then b open", got)
+	}
+}
+
+func TestStatus_ShouldMarshalStateAsText(t *testing.T) {
+	raw, err := json.Marshal(Status{Name: "p", State: HalfOpen})
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var m map[string]any
+	_ = json.Unmarshal(raw, &m)
+	if m["state"] != "half-open" {
+		t.Errorf("state = %v, want half-open", m["state"])
+	}
+}
+
+func TestFromConfig_ShouldDefaultZeroFields(t *testing.T) {
+	if got := FromConfig(domain.BreakerConfig{}); got != DefaultConfig() {
+		t.Errorf("FromConfig(zero) = %+v, want defaults", got)
+	}
+	got := FromConfig(domain.BreakerConfig{FailureThreshold: 2, ProbeInterval: 1500})
+	if got.FailureThreshold != 2 || got.ProbeInterval != 1500*time.Millisecond {
+		t.Errorf("FromConfig = %+v", got)
+	}
+	if err := got.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+	if err := (Config{}).Validate(); err == nil {
+		t.Error("expected Validate error for zero Config")
+	}
+}
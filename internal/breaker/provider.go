@@ -0,0 +1,126 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/retry"
+)
+
+// Provider wraps an LLMProvider with a circuit breaker: while the circuit is
+// open, calls fail immediately with an error matching ErrOpen instead of
+// reaching the provider (and its retries).
+type Provider struct {
+	inner   domain.LLMProvider
+	breaker *Breaker
+}
+
+// Wrap returns inner guarded by the circuit named name, typically
+// "<provider>/<model>". inner must not be nil.
+func (s *Set) Wrap(name string, inner domain.LLMProvider) *Provider {
+	if inner == nil {
+		panic("breaker: inner provider must not be nil")
+	}
+	return &Provider{inner: inner, breaker: s.Get(name)}
+}
+
+// Generate implements domain.LLMProvider.
+func (p *Provider) Generate(ctx context.Context, prompt string) (string, error) {
+	var result string
+	err := p.do(ctx, func() error {
+		var genErr error
+		result, genErr = p.inner.Generate(ctx, prompt)
+		return genErr
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Chat implements domain.ChatProvider. Returns domain.ErrChatNotSupported,
+// without touching the circuit, when the inner provider cannot chat.
+func (p *Provider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	cp, ok := p.inner.(domain.ChatProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	var result domain.ChatResponse
+	err := p.do(ctx, func() error {
+		var chatErr error
+		result, chatErr = cp.Chat(ctx, req)
+		return chatErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// ChatStream implements domain.StreamingProvider. Returns
+// domain.ErrStreamNotSupported, without touching the circuit, when the inner
+// provider cannot stream.
+func (p *Provider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	sp, ok := p.inner.(domain.StreamingProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	var result domain.ChatResponse
+	err := p.do(ctx, func() error {
+		var streamErr error
+		result, streamErr = sp.ChatStream(ctx, req, onDelta)
+		return streamErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// do runs call if the circuit allows it and records the outcome. Errors that
+// say nothing about the provider's health (the caller's context ending, a
+// decorator reporting an unsupported call, or a client error such as a 400
+// for a malformed request) release the call instead.
+func (p *Provider) do(ctx context.Context, call func() error) error {
+	if err := p.breaker.Allow(); err != nil {
+		return err
+	}
+	err := call()
+	switch {
+	case err == nil:
+		p.breaker.Success()
+	case ctx.Err() != nil,
+		errors.Is(err, domain.ErrChatNotSupported),
+		errors.Is(err, domain.ErrStreamNotSupported),
+		isClientError(err):
+		p.breaker.Release()
+	default:
+		p.breaker.Failure(err)
+	}
+	return err
+}
+
+// isClientError reports whether err is a 4xx API error caused by the
+// request rather than the provider. Bad credentials (401, 403) and rate
+// limiting (429) still count as failures: every later call would fail too.
+func isClientError(err error) bool {
+	var statusErr retry.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+	switch code := statusErr.HTTPStatus(); code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	default:
+		return code >= 400 && code < 500
+	}
+}
+
+// Compile-time check that Provider implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*Provider)(nil)
+	_ domain.ChatProvider      = (*Provider)(nil)
+	_ domain.StreamingProvider = (*Provider)(nil)
+)
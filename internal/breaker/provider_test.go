@@ -0,0 +1,120 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// fakeProvider fails while err is set and counts its calls.
+type fakeProvider struct {
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Generate(ctx context.Context, _ string) (string, error) {
+	p.calls++
+	if p.err != nil {
+		return "", p.err
+	}
+	return "ok", nil
+}
+
+func TestProvider_WhenCircuitOpen_ShouldNotCallInner(t *testing.T) {
+	s, clock, _ := newTestSet(Config{FailureThreshold: 2, ProbeInterval: time.Minute})
+	inner := &fakeProvider{err: errDown}
+	p := s.Wrap("fake", inner)
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Generate(context.Background(), "hi"); !errors.Is(err, errDown) {
+			t.Fatalf("call %d: err = %v, want provider error", i, err)
+		}
+	}
+	if _, err := p.Generate(context.Background(), "hi"); !errors.Is(err, ErrOpen) {
+		t.Fatalf("err = %v, want ErrOpen", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("inner calls = %d, want 2", inner.calls)
+	}
+
+	inner.err = nil
+	clock.advance(time.Minute)
+	if got, err := p.Generate(context.Background(), "hi"); err != nil || got != "ok" {
+		t.Fatalf("probe: %q, %v", got, err)
+	}
+	if st := s.Get("fake").Status(); st.State != Closed {
+		t.Errorf("state = %v, want closed", st.State)
+	}
+}
+
+func TestProvider_WhenCallerCancels_ShouldNotCountFailure(t *testing.T) {
+	s, _, _ := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+	p := s.Wrap("fake", &fakeProvider{err: context.Canceled})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _ = p.Generate(ctx, "hi")
+
+	if st := s.Get("fake").Status(); st.State != Closed || st.Failures != 0 {
+		t.Errorf("status = %+v, want closed without failures", st)
+	}
+}
+
+func TestProvider_WhenInnerCannotChat_ShouldReturnNotSupportedWithoutCounting(t *testing.T) {
+	s, _, _ := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+	p := s.Wrap("fake", &fakeProvider{})
+
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{}); !errors.Is(err, domain.ErrChatNotSupported) {
+		t.Errorf("Chat err = %v, want ErrChatNotSupported", err)
+	}
+	if _, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("ChatStream err = %v, want ErrStreamNotSupported", err)
+	}
+	if st := s.Get("fake").Status(); st.Failures != 0 {
+		t.Errorf("failures = %d, want 0", st.Failures)
+	}
+}
+
+// statusError is an API error carrying an HTTP status, like *llm.APIError.
+type statusError int
+
+func (e statusError) Error() string   { return fmt.Sprintf("api: %d", int(e)) }
+func (e statusError) HTTPStatus() int { return int(e) }
+
+func TestProvider_WhenClientError_ShouldCountOnlyAuthAndRateLimits(t *testing.T) {
+	for _, tc := range []struct {
+		status int
+		counts bool
+	}{
+		{http.StatusBadRequest, false},
+		{http.StatusNotFound, false},
+		{http.StatusRequestEntityTooLarge, false},
+		{http.StatusUnauthorized, true},
+		{http.StatusForbidden, true},
+		{http.StatusTooManyRequests, true},
+		{http.StatusInternalServerError, true},
+	} {
+		s, _, _ := newTestSet(Config{FailureThreshold: 1, ProbeInterval: time.Minute})
+		p := s.Wrap("fake", &fakeProvider{err: fmt.Errorf("wrapped: %w", statusError(tc.status))})
+
+		_, _ = p.Generate(context.Background(), "hi")
+
+		if got := s.Get("fake").Status().State == Open; got != tc.counts {
+			t.Errorf("status %d: circuit open = %v, want %v", tc.status, got, tc.counts)
+		}
+	}
+}
+
+func TestSet_Wrap_WhenNilInner_ShouldPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for nil inner provider")
+		}
+	}()
+	NewSet(DefaultConfig()).Wrap("p", nil)
+}
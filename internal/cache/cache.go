@@ -0,0 +1,320 @@
+// Package cache answers repeated LLM requests from stored responses, so jobs
+// and skills that send the same prompt again (a daily summary, a skill re-run
+// on the same page) do not pay for it twice. Requests match exactly, by
+// provider, model and normalised request, or, in semantic mode, by the
+// similarity of their text's embedding to a cached request's.
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+const (
+	// DefaultTTL is how long a response is served when no TTL is configured.
+	DefaultTTL = 24 * time.Hour
+	// DefaultThreshold is the cosine similarity a semantic match needs when
+	// no threshold is configured.
+	DefaultThreshold = 0.95
+	// pruneInterval is how often stores remove expired entries.
+	pruneInterval = time.Hour
+)
+
+// Stats counts the cache's activity since startup, as served by the gateway.
+type Stats struct {
+	Hits         int64 `json:"hits"`         // Requests answered from the cache, including SemanticHits
+	SemanticHits int64 `json:"semanticHits"` // Hits on a similar rather than identical request
+	Misses       int64 `json:"misses"`       // Requests the provider answered
+	Bypassed     int64 `json:"bypassed"`     // Requests made with domain.WithoutCache
+	Errors       int64 `json:"errors"`       // Store or embedder failures; the request proceeds uncached
+}
+
+// Option configures a Cache.
+type Option func(*Cache)
+
+// WithTTL sets how long responses are served (default DefaultTTL).
+func WithTTL(d time.Duration) Option {
+	return func(c *Cache) {
+		if d > 0 {
+			c.ttl = d
+		}
+	}
+}
+
+// WithSemantic enables semantic matching: a request whose text's embedding
+// by e has at least threshold cosine similarity to a cached request's gets
+// its response. A threshold of 0 means DefaultThreshold. Only text requests
+// and text responses take part, so tool calls are never reused for a
+// different request.
+func WithSemantic(e domain.Embedder, threshold float64) Option {
+	return func(c *Cache) {
+		c.embedder = e
+		if threshold > 0 {
+			c.threshold = threshold
+		}
+	}
+}
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(c *Cache) {
+		if now != nil {
+			c.now = now
+		}
+	}
+}
+
+// Cache looks up and stores responses for the providers it wraps (see Wrap).
+// A nil *Cache caches nothing. It is safe for concurrent use.
+type Cache struct {
+	store     Store
+	ttl       time.Duration
+	embedder  domain.Embedder // nil: exact matches only
+	threshold float64
+	now       func() time.Time
+
+	mu        sync.Mutex
+	stats     Stats
+	lastPrune time.Time
+}
+
+// New returns a Cache storing responses in store. store must not be nil.
+func New(store Store, opts ...Option) *Cache {
+	if store == nil {
+		panic("cache: store must not be nil")
+	}
+	c := &Cache{
+		store:     store,
+		ttl:       DefaultTTL,
+		threshold: DefaultThreshold,
+		now:       time.Now,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Stats returns the cache's counters. A nil Cache reports zeros.
+func (c *Cache) Stats() Stats {
+	if c == nil {
+		return Stats{}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// count applies fn to the counters.
+func (c *Cache) count(fn func(*Stats)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn(&c.stats)
+}
+
+// lookup is a request as the cache sees it.
+type lookup struct {
+	key       string    // exact-match key
+	scope     string    // requests this one may semantically match
+	text      string    // text to embed; "" when the request is not semantically matchable
+	embedding []float64 // embedding of text, once computed
+}
+
+// response is the stored form of a response.
+type response struct {
+	Content    json.RawMessage   `json:"content"`
+	StopReason domain.StopReason `json:"stopReason,omitempty"`
+}
+
+// get returns the cached response for l, exact matches first. Requests made
+// with domain.WithoutCache always miss.
+func (c *Cache) get(ctx context.Context, l *lookup) (domain.ChatResponse, bool) {
+	if domain.CacheBypassed(ctx) {
+		c.count(func(s *Stats) { s.Bypassed++ })
+		return domain.ChatResponse{}, false
+	}
+	now := c.now()
+	e, ok, err := c.store.Get(ctx, l.key, now)
+	if err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+	}
+	semantic := false
+	if !ok && c.embed(ctx, l) {
+		var score float64
+		e, score, ok, err = c.store.Nearest(ctx, l.scope, l.embedding, now)
+		if err != nil {
+			c.count(func(s *Stats) { s.Errors++ })
+		}
+		ok = ok && score >= c.threshold
+		semantic = true
+	}
+	if !ok {
+		return domain.ChatResponse{}, false
+	}
+	var r response
+	if err := json.Unmarshal(e.Response, &r); err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+		return domain.ChatResponse{}, false
+	}
+	c.count(func(s *Stats) {
+		s.Hits++
+		if semantic {
+			s.SemanticHits++
+		}
+	})
+	return domain.ChatResponse{Content: domain.Message{RawContent: r.Content}.Blocks(), StopReason: r.StopReason}, true
+}
+
+// put stores resp, which the provider returned for l. Only text responses
+// are semantically matchable.
+func (c *Cache) put(ctx context.Context, l *lookup, resp domain.ChatResponse) {
+	c.count(func(s *Stats) { s.Misses++ })
+	content, err := domain.EncodeContentBlocks(resp.Content)
+	if err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+		return
+	}
+	raw, err := json.Marshal(response{Content: content, StopReason: resp.StopReason})
+	if err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+		return
+	}
+	now := c.now()
+	e := Entry{Key: l.key, Scope: l.scope, Response: raw, Created: now, Expires: now.Add(c.ttl)}
+	if len(resp.ToolUses()) == 0 && c.embed(ctx, l) {
+		e.Embedding = l.embedding
+	}
+	if err := c.store.Put(ctx, e); err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+		return
+	}
+	c.prune(ctx, now)
+}
+
+// embed computes l's embedding unless it has one, reporting whether l can be
+// semantically matched.
+func (c *Cache) embed(ctx context.Context, l *lookup) bool {
+	if c.embedder == nil || l.text == "" {
+		return false
+	}
+	if l.embedding == nil {
+		vec, err := c.embedder.Embed(ctx, l.text)
+		if err != nil || len(vec) == 0 {
+			c.count(func(s *Stats) { s.Errors++ })
+			l.text = "" // don't retry for this request
+			return false
+		}
+		l.embedding = vec
+	}
+	return true
+}
+
+// prune removes expired entries at most once per pruneInterval.
+func (c *Cache) prune(ctx context.Context, now time.Time) {
+	c.mu.Lock()
+	due := now.Sub(c.lastPrune) >= pruneInterval
+	if due {
+		c.lastPrune = now
+	}
+	c.mu.Unlock()
+	if !due {
+		return
+	}
+	if _, err := c.store.DeleteExpired(ctx, now); err != nil {
+		c.count(func(s *Stats) { s.Errors++ })
+	}
+}
+
+// Kinds of request, so a prompt and a one-message chat do not share entries.
+// Chat and streamed chat requests do share them.
+const (
+	kindGenerate = "generate"
+	kindChat     = "chat"
+)
+
+// generateLookup returns the lookup of a Generate call to provider name.
+func generateLookup(name, prompt string) *lookup {
+	prompt = normaliseText(prompt)
+	return &lookup{
+		key:   hashKey(kindGenerate, name, prompt),
+		scope: hashKey(kindGenerate, name),
+		text:  prompt,
+	}
+}
+
+// chatLookup returns the lookup of a Chat or ChatStream call to provider
+// name. Its semantic scope is everything but the last message, which is
+// matchable when it is a user message of text only.
+func chatLookup(name string, req domain.ChatRequest) *lookup {
+	req = normaliseRequest(req)
+	full, _ := json.Marshal(req)
+	l := &lookup{key: hashKey(kindChat, name, string(full))}
+	n := len(req.Messages)
+	if n == 0 {
+		return l
+	}
+	last := req.Messages[n-1]
+	req.Messages = req.Messages[:n-1]
+	rest, _ := json.Marshal(req)
+	l.scope = hashKey(kindChat, name, string(rest))
+	if last.Role == domain.RoleUser {
+		l.text = textOnly(last.Blocks())
+	}
+	return l
+}
+
+// normaliseRequest returns req with the fields that differ between otherwise
+// identical requests (message IDs, timestamps, surrounding whitespace and
+// line endings) normalised.
+func normaliseRequest(req domain.ChatRequest) domain.ChatRequest {
+	req.System = normaliseText(req.System)
+	msgs := make([]domain.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		// Blocks may be the caller's own slice; normalise a copy.
+		blocks := append([]domain.ContentBlock(nil), m.Blocks()...)
+		for j, b := range blocks {
+			if t, ok := b.(domain.TextBlock); ok {
+				blocks[j] = domain.TextBlock{Text: normaliseText(t.Text)}
+			}
+		}
+		raw, _ := domain.EncodeContentBlocks(blocks)
+		msgs[i] = domain.Message{Role: m.Role, RawContent: raw}
+	}
+	req.Messages = msgs
+	return req
+}
+
+// normaliseText trims s and converts its line endings to "\n".
+func normaliseText(s string) string {
+	return strings.TrimSpace(strings.ReplaceAll(s, "\r\n", "\n"))
+}
+
+// textOnly joins the text of blocks, or returns "" if any block is not text.
+func textOnly(blocks []domain.ContentBlock) string {
+	parts := make([]string, 0, len(blocks))
+	for _, b := range blocks {
+		t, ok := b.(domain.TextBlock)
+		if !ok {
+			return ""
+		}
+		parts = append(parts, t.Text)
+	}
+	return strings.Join(parts, "\n")
+}
+
+// hashKey returns the hex SHA-256 of parts, NUL-separated.
+func hashKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"ironclaw/internal/db"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/embedding"
+)
+
+// ErrNoDatabase is returned by Open when neither cache.dbUrl nor a memory
+// directory is configured.
+var ErrNoDatabase = errors.New("cache: no database configured (set cache.dbUrl or agents.paths.memory)")
+
+// DefaultEmbeddingModel is the Ollama model semantic mode embeds requests
+// with when none is configured.
+const DefaultEmbeddingModel = "nomic-embed-text"
+
+// newEmbedder builds the embedder of semantic mode; tests replace it.
+var newEmbedder = func(model string) domain.Embedder {
+	return embedding.NewOllamaEmbedder(model)
+}
+
+// DBURL returns cfg.DBURL, or a file: URL for cache.db in memoryDir when it
+// is unset. Returns "" when both are empty.
+func DBURL(cfg domain.CacheConfig, memoryDir string) string {
+	if cfg.DBURL != "" {
+		return cfg.DBURL
+	}
+	if memoryDir == "" {
+		return ""
+	}
+	return "file:" + filepath.Join(memoryDir, "cache.db")
+}
+
+// Open builds the Cache described by cfg on the store at DBURL(cfg,
+// memoryDir) and returns it with a func that closes the store. It does not
+// check cfg.Enabled; callers that find the cache disabled should not open it.
+func Open(cfg domain.CacheConfig, memoryDir string, opts ...Option) (*Cache, func() error, error) {
+	ttl := DefaultTTL
+	if cfg.TTL != "" {
+		d, err := time.ParseDuration(cfg.TTL)
+		if err != nil || d <= 0 {
+			return nil, nil, fmt.Errorf("cache: invalid ttl %q", cfg.TTL)
+		}
+		ttl = d
+	}
+	opts = append([]Option{WithTTL(ttl)}, opts...)
+	if sc := cfg.Semantic; sc != nil {
+		if sc.Threshold < 0 || sc.Threshold > 1 {
+			return nil, nil, fmt.Errorf("cache: semantic threshold %v is not between 0 and 1", sc.Threshold)
+		}
+		model := sc.Model
+		if model == "" {
+			model = DefaultEmbeddingModel
+		}
+		opts = append([]Option{WithSemantic(newEmbedder(model), sc.Threshold)}, opts...)
+	}
+
+	dbURL := DBURL(cfg, memoryDir)
+	if dbURL == "" {
+		return nil, nil, ErrNoDatabase
+	}
+	if path, ok := strings.CutPrefix(dbURL, "file:"); ok {
+		path, _, _ = strings.Cut(path, "?")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("cache: create store dir: %w", err)
+		}
+	}
+	conn, err := db.Connect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := NewSQLiteStore(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return New(store, opts...), conn.Close, nil
+}
@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+func TestDBURL_ShouldPreferConfigThenMemoryDir(t *testing.T) {
+	if got := DBURL(domain.CacheConfig{DBURL: "libsql://x"}, "/mem"); got != "libsql://x" {
+		t.Errorf("DBURL = %q", got)
+	}
+	if got := DBURL(domain.CacheConfig{}, "/mem"); got != "file:/mem/cache.db" {
+		t.Errorf("DBURL = %q", got)
+	}
+	if got := DBURL(domain.CacheConfig{}, ""); got != "" {
+		t.Errorf("DBURL = %q, want empty", got)
+	}
+}
+
+func TestOpen_ShouldApplyConfig(t *testing.T) {
+	var model string
+	orig := newEmbedder
+	newEmbedder = func(m string) domain.Embedder { model = m; return wordEmbedder{} }
+	t.Cleanup(func() { newEmbedder = orig })
+
+	c, closeFn, err := Open(domain.CacheConfig{TTL: "2h", Semantic: &domain.SemanticCacheConfig{Threshold: 0.8}}, filepath.Join(t.TempDir(), "memory"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer closeFn()
+	if c.ttl.Hours() != 2 || c.threshold != 0.8 || c.embedder == nil || model != DefaultEmbeddingModel {
+		t.Errorf("cache = ttl %v, threshold %v, embedder %v, model %q", c.ttl, c.threshold, c.embedder, model)
+	}
+}
+
+func TestOpen_WhenConfigInvalid_ShouldReturnError(t *testing.T) {
+	dir := t.TempDir()
+	for name, cfg := range map[string]domain.CacheConfig{
+		"bad ttl":       {TTL: "tomorrow"},
+		"bad threshold": {Semantic: &domain.SemanticCacheConfig{Threshold: 1.5}},
+	} {
+		if _, _, err := Open(cfg, dir); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, _, err := Open(domain.CacheConfig{}, ""); err != ErrNoDatabase {
+		t.Errorf("err = %v, want ErrNoDatabase", err)
+	}
+}
@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"context"
+
+	"ironclaw/internal/domain"
+)
+
+// Provider answers the requests to an LLMProvider from a Cache when it can,
+// and caches the provider's responses otherwise. Responses served from the
+// cache report no usage, as no tokens were spent on them.
+type Provider struct {
+	cache *Cache
+	name  string
+	inner domain.LLMProvider
+}
+
+// Wrap returns inner with its responses cached under name, which identifies
+// the provider and model (e.g. llm.ProviderName). A nil Cache returns inner
+// itself. inner must not be nil.
+func (c *Cache) Wrap(name string, inner domain.LLMProvider) domain.LLMProvider {
+	if inner == nil {
+		panic("cache: inner provider must not be nil")
+	}
+	if c == nil {
+		return inner
+	}
+	return &Provider{cache: c, name: name, inner: inner}
+}
+
+// Generate implements domain.LLMProvider.
+func (p *Provider) Generate(ctx context.Context, prompt string) (string, error) {
+	l := generateLookup(p.name, prompt)
+	if resp, ok := p.cache.get(ctx, l); ok {
+		return resp.Text(), nil
+	}
+	out, err := p.inner.Generate(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	p.cache.put(ctx, l, domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: out}}, StopReason: domain.StopEndTurn})
+	return out, nil
+}
+
+// Chat implements domain.ChatProvider. Returns domain.ErrChatNotSupported
+// when the inner provider cannot chat.
+func (p *Provider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	cp, ok := p.inner.(domain.ChatProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	l := chatLookup(p.name, req)
+	if resp, ok := p.cache.get(ctx, l); ok {
+		return resp, nil
+	}
+	resp, err := cp.Chat(ctx, req)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	p.cache.put(ctx, l, resp)
+	return resp, nil
+}
+
+// ChatStream implements domain.StreamingProvider. A cached response is
+// delivered as a single delta. Returns domain.ErrStreamNotSupported when the
+// inner provider cannot stream.
+func (p *Provider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	sp, ok := p.inner.(domain.StreamingProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	l := chatLookup(p.name, req)
+	if resp, ok := p.cache.get(ctx, l); ok {
+		if text := resp.Text(); text != "" {
+			onDelta(text)
+		}
+		return resp, nil
+	}
+	resp, err := sp.ChatStream(ctx, req, onDelta)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	p.cache.put(ctx, l, resp)
+	return resp, nil
+}
+
+// Compile-time check that Provider implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*Provider)(nil)
+	_ domain.ChatProvider      = (*Provider)(nil)
+	_ domain.StreamingProvider = (*Provider)(nil)
+)
@@ -0,0 +1,267 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// countingProvider answers every call with its prompt or last message and
+// counts its calls.
+type countingProvider struct {
+	calls int
+	tool  bool // answer chats with a tool call
+}
+
+func (p *countingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	p.calls++
+	return "re: " + prompt, nil
+}
+
+func (p *countingProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	p.calls++
+	if p.tool {
+		return domain.ChatResponse{
+			Content:    []domain.ContentBlock{domain.ToolUseBlock{ToolUseID: "t1", Name: "read_file", Input: json.RawMessage(`{}`)}},
+			StopReason: domain.StopToolUse,
+		}, nil
+	}
+	text := req.Messages[len(req.Messages)-1].Blocks()[0].(domain.TextBlock).Text
+	return domain.ChatResponse{
+		Content:    []domain.ContentBlock{domain.TextBlock{Text: "re: " + text}},
+		StopReason: domain.StopEndTurn,
+		Usage:      domain.Usage{InputTokens: 10, OutputTokens: 5},
+	}, nil
+}
+
+func (p *countingProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	resp, err := p.Chat(ctx, req)
+	onDelta(resp.Text())
+	return resp, err
+}
+
+// failingProvider fails every call.
+type failingProvider struct{}
+
+func (failingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	return "", errors.New("503 service unavailable")
+}
+
+// wordEmbedder embeds text as counts of a few words, so texts sharing them
+// are similar.
+type wordEmbedder struct{ err error }
+
+func (e wordEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vec := make([]float64, 3)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		switch strings.Trim(w, "?.!") {
+		case "summarize":
+			vec[0]++
+		case "news":
+			vec[1]++
+		case "weather":
+			vec[2]++
+		}
+	}
+	return vec, nil
+}
+
+// fakeClock is a settable clock.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestCache(t *testing.T, opts ...Option) (*Cache, *fakeClock) {
+	t.Helper()
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	return New(newTestStore(t), append([]Option{WithClock(clock.now)}, opts...)...), clock
+}
+
+func userChat(system, text string) domain.ChatRequest {
+	msg := domain.NewTextMessage(domain.RoleUser, text)
+	msg.Timestamp = time.Now()
+	return domain.ChatRequest{System: system, Messages: []domain.Message{msg}}
+}
+
+func TestProvider_WhenRequestRepeated_ShouldAnswerFromCache(t *testing.T) {
+	c, _ := newTestCache(t)
+	inner := &countingProvider{}
+	p := c.Wrap("openai/gpt-4o", inner).(*Provider)
+	ctx := context.Background()
+
+	for _, text := range []string{"hello", "  hello\r\n"} {
+		resp, err := p.Chat(ctx, userChat("sys", text))
+		if err != nil || resp.Text() != "re: hello" {
+			t.Fatalf("Chat(%q) = %q, %v", text, resp.Text(), err)
+		}
+	}
+	if resp, _ := p.Chat(ctx, userChat("sys", "hello")); resp.Usage != (domain.Usage{}) {
+		t.Errorf("cached response usage = %+v, want none", resp.Usage)
+	}
+	if out, err := p.Generate(ctx, "hello"); err != nil || out != "re: hello" {
+		t.Fatalf("Generate = %q, %v", out, err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("provider calls = %d, want 2 (one chat, one generate)", inner.calls)
+	}
+	if st := c.Stats(); st.Hits != 2 || st.Misses != 2 {
+		t.Errorf("stats = %+v, want 2 hits and 2 misses", st)
+	}
+}
+
+func TestProvider_WhenRequestDiffers_ShouldMiss(t *testing.T) {
+	c, _ := newTestCache(t)
+	inner := &countingProvider{}
+	ctx := context.Background()
+
+	_, _ = c.Wrap("openai/gpt-4o", inner).(*Provider).Chat(ctx, userChat("sys", "hello"))
+	_, _ = c.Wrap("openai/gpt-4o", inner).(*Provider).Chat(ctx, userChat("other sys", "hello"))
+	_, _ = c.Wrap("anthropic/claude", inner).(*Provider).Chat(ctx, userChat("sys", "hello"))
+	if inner.calls != 3 {
+		t.Errorf("provider calls = %d, want 3", inner.calls)
+	}
+}
+
+func TestProvider_WhenEntryExpired_ShouldCallProvider(t *testing.T) {
+	c, clock := newTestCache(t, WithTTL(time.Hour))
+	inner := &countingProvider{}
+	p := c.Wrap("local", inner)
+
+	_, _ = p.Generate(context.Background(), "hi")
+	clock.advance(time.Hour)
+	_, _ = p.Generate(context.Background(), "hi")
+	if inner.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", inner.calls)
+	}
+}
+
+func TestProvider_WhenBypassed_ShouldCallProviderAndRefreshEntry(t *testing.T) {
+	c, _ := newTestCache(t)
+	inner := &countingProvider{}
+	p := c.Wrap("local", inner)
+
+	_, _ = p.Generate(context.Background(), "hi")
+	_, _ = p.Generate(domain.WithoutCache(context.Background()), "hi")
+	_, _ = p.Generate(context.Background(), "hi")
+	if inner.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", inner.calls)
+	}
+	if st := c.Stats(); st.Bypassed != 1 || st.Hits != 1 || st.Misses != 2 {
+		t.Errorf("stats = %+v", st)
+	}
+}
+
+func TestProvider_WhenProviderFails_ShouldNotCache(t *testing.T) {
+	c, _ := newTestCache(t)
+	p := c.Wrap("local", failingProvider{})
+
+	if _, err := p.Generate(context.Background(), "hi"); err == nil {
+		t.Fatal("expected provider error")
+	}
+	if _, ok := p.(*Provider).Chat(context.Background(), userChat("", "hi")); !errors.Is(ok, domain.ErrChatNotSupported) {
+		t.Errorf("Chat err = %v, want ErrChatNotSupported", ok)
+	}
+	if st := c.Stats(); st.Misses != 0 {
+		t.Errorf("misses = %d, want 0", st.Misses)
+	}
+}
+
+func TestProvider_WhenStreamHits_ShouldDeliverOneDelta(t *testing.T) {
+	c, _ := newTestCache(t)
+	p := c.Wrap("local", &countingProvider{}).(*Provider)
+	_, _ = p.Chat(context.Background(), userChat("", "hello"))
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), userChat("", "hello"), func(d string) { deltas = append(deltas, d) })
+	if err != nil || resp.Text() != "re: hello" || len(deltas) != 1 || deltas[0] != "re: hello" {
+		t.Errorf("ChatStream = %q, %v, deltas %q", resp.Text(), err, deltas)
+	}
+}
+
+func TestProvider_WhenSemantic_ShouldAnswerSimilarRequests(t *testing.T) {
+	c, _ := newTestCache(t, WithSemantic(wordEmbedder{}, 0.9))
+	inner := &countingProvider{}
+	p := c.Wrap("local", inner).(*Provider)
+	ctx := context.Background()
+
+	_, _ = p.Chat(ctx, userChat("sys", "Summarize the news"))
+	resp, err := p.Chat(ctx, userChat("sys", "Please summarize today's news!"))
+	if err != nil || resp.Text() != "re: Summarize the news" {
+		t.Errorf("similar request = %q, %v, want cached reply", resp.Text(), err)
+	}
+	_, _ = p.Chat(ctx, userChat("sys", "What's the weather?"))
+	_, _ = p.Chat(ctx, userChat("other sys", "Summarize the news"))
+	if inner.calls != 3 {
+		t.Errorf("provider calls = %d, want 3", inner.calls)
+	}
+	if st := c.Stats(); st.SemanticHits != 1 || st.Hits != 1 {
+		t.Errorf("stats = %+v, want 1 semantic hit", st)
+	}
+}
+
+func TestProvider_WhenSemanticAndToolCall_ShouldOnlyMatchExactly(t *testing.T) {
+	c, _ := newTestCache(t, WithSemantic(wordEmbedder{}, 0.9))
+	inner := &countingProvider{tool: true}
+	p := c.Wrap("local", inner).(*Provider)
+	ctx := context.Background()
+
+	_, _ = p.Chat(ctx, userChat("sys", "summarize news.txt"))
+	_, _ = p.Chat(ctx, userChat("sys", "summarize news.txt"))
+	_, _ = p.Chat(ctx, userChat("sys", "summarize news-old.txt"))
+	if inner.calls != 2 {
+		t.Errorf("provider calls = %d, want 2 (tool calls reused only for identical requests)", inner.calls)
+	}
+}
+
+func TestProvider_WhenEmbedderFails_ShouldFallBackToExactMatching(t *testing.T) {
+	c, _ := newTestCache(t, WithSemantic(wordEmbedder{err: errors.New("ollama down")}, 0))
+	inner := &countingProvider{}
+	p := c.Wrap("local", inner)
+
+	for i := 0; i < 2; i++ {
+		if out, err := p.Generate(context.Background(), "summarize"); err != nil || out != "re: summarize" {
+			t.Fatalf("Generate = %q, %v", out, err)
+		}
+	}
+	if st := c.Stats(); inner.calls != 1 || st.Hits != 1 || st.Errors == 0 {
+		t.Errorf("calls = %d, stats = %+v", inner.calls, st)
+	}
+}
+
+func TestProvider_Chat_ShouldNotModifyRequestMessages(t *testing.T) {
+	c, _ := newTestCache(t)
+	inner := &countingProvider{}
+	p := c.Wrap("openai/gpt-4o", inner).(*Provider)
+	req := userChat("sys", "  hello\r\nworld  ")
+
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got := req.Messages[0].Blocks()[0].(domain.TextBlock).Text; got != "  hello\r\nworld  " {
+		t.Errorf("request message changed to %q", got)
+	}
+	if resp.Text() != "re:   hello\r\nworld  " {
+		t.Errorf("provider got a normalised request: %q", resp.Text())
+	}
+}
+
+func TestWrap_WhenCacheNil_ShouldReturnInner(t *testing.T) {
+	var c *Cache
+	inner := &countingProvider{}
+	if got := c.Wrap("local", inner); got != domain.LLMProvider(inner) {
+		t.Errorf("Wrap = %T, want inner", got)
+	}
+	if c.Stats() != (Stats{}) {
+		t.Error("nil cache should report zero stats")
+	}
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"ironclaw/internal/vectorstore"
+)
+
+// Entry is one cached response.
+type Entry struct {
+	Key       string    // Exact-match key of the request
+	Scope     string    // Requests whose texts may be compared for a semantic match
+	Response  []byte    // Encoded response
+	Embedding []float64 // Embedding of the request text; nil when not semantically matchable
+	Created   time.Time
+	Expires   time.Time
+}
+
+// Store persists cached responses.
+type Store interface {
+	// Get returns the entry stored under key, if it has not expired by now.
+	Get(ctx context.Context, key string, now time.Time) (Entry, bool, error)
+	// Nearest returns the unexpired entry in scope whose embedding is most
+	// similar to embedding, with its cosine similarity.
+	Nearest(ctx context.Context, scope string, embedding []float64, now time.Time) (Entry, float64, bool, error)
+	// Put stores e, replacing any entry with the same key.
+	Put(ctx context.Context, e Entry) error
+	// DeleteExpired removes the entries expired by now and returns how many
+	// were removed.
+	DeleteExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// SQLiteStore stores cached responses in a SQLite/libSQL table. Open the
+// connection with db.Connect. Semantic matches are found by comparing the
+// embeddings of a scope in memory, like vectorstore.SQLiteVectorStore.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a cache store and initializes the schema.
+// Returns an error if the db is nil or if the migration fails.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db must not be nil")
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("cache migrate: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the llm_cache table if it doesn't exist.
+func (s *SQLiteStore) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS llm_cache (
+			key TEXT PRIMARY KEY,
+			scope TEXT NOT NULL,
+			response BLOB NOT NULL,
+			embedding BLOB,
+			created_at INTEGER NOT NULL,
+			expires_at INTEGER NOT NULL
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	for _, idx := range []string{
+		`CREATE INDEX IF NOT EXISTS llm_cache_scope ON llm_cache (scope, expires_at)`,
+		`CREATE INDEX IF NOT EXISTS llm_cache_expires ON llm_cache (expires_at)`,
+	} {
+		if _, err := s.db.Exec(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get implements Store.
+func (s *SQLiteStore) Get(ctx context.Context, key string, now time.Time) (Entry, bool, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT key, scope, response, embedding, created_at, expires_at FROM llm_cache
+		WHERE key = ? AND expires_at > ?
+	`, key, now.UnixNano())
+	e, err := scanEntry(row.Scan)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Entry{}, false, nil
+	}
+	if err != nil {
+		return Entry{}, false, err
+	}
+	return e, true, nil
+}
+
+// Nearest implements Store.
+func (s *SQLiteStore) Nearest(ctx context.Context, scope string, embedding []float64, now time.Time) (Entry, float64, bool, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT key, scope, response, embedding, created_at, expires_at FROM llm_cache
+		WHERE scope = ? AND expires_at > ? AND embedding IS NOT NULL
+	`, scope, now.UnixNano())
+	if err != nil {
+		return Entry{}, 0, false, err
+	}
+	defer rows.Close()
+
+	var best Entry
+	bestScore, found := 0.0, false
+	for rows.Next() {
+		e, err := scanEntry(rows.Scan)
+		if err != nil {
+			return Entry{}, 0, false, err
+		}
+		if score := vectorstore.CosineSimilarity(embedding, e.Embedding); !found || score > bestScore {
+			best, bestScore, found = e, score, true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return Entry{}, 0, false, err
+	}
+	return best, bestScore, found, nil
+}
+
+// Put implements Store.
+func (s *SQLiteStore) Put(ctx context.Context, e Entry) error {
+	var embedding []byte
+	if len(e.Embedding) > 0 {
+		embedding = vectorstore.EncodeEmbedding(e.Embedding)
+	}
+	_, err := s.db.ExecContext(ctx, `
+		INSERT OR REPLACE INTO llm_cache (key, scope, response, embedding, created_at, expires_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`, e.Key, e.Scope, e.Response, embedding, e.Created.UnixNano(), e.Expires.UnixNano())
+	return err
+}
+
+// DeleteExpired implements Store.
+func (s *SQLiteStore) DeleteExpired(ctx context.Context, now time.Time) (int64, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM llm_cache WHERE expires_at <= ?`, now.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// scanEntry reads one llm_cache row with scan.
+func scanEntry(scan func(dest ...any) error) (Entry, error) {
+	var e Entry
+	var embedding []byte
+	var created, expires int64
+	if err := scan(&e.Key, &e.Scope, &e.Response, &embedding, &created, &expires); err != nil {
+		return Entry{}, err
+	}
+	if len(embedding) > 0 {
+		e.Embedding = vectorstore.DecodeEmbedding(embedding)
+	}
+	e.Created = time.Unix(0, created)
+	e.Expires = time.Unix(0, expires)
+	return e, nil
+}
+
+var _ Store = (*SQLiteStore)(nil)
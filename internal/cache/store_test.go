@@ -0,0 +1,79 @@
+package cache
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ironclaw/internal/db"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "cache.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	store, err := NewSQLiteStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	return store
+}
+
+func TestNewSQLiteStore_WhenNilDB_ShouldReturnError(t *testing.T) {
+	if _, err := NewSQLiteStore(nil); err == nil {
+		t.Fatal("expected error for nil db")
+	}
+}
+
+func TestSQLiteStore_ShouldGetUnexpiredEntriesAndDeleteExpired(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Key: "fresh", Scope: "s", Response: []byte(`{}`), Created: now, Expires: now.Add(time.Hour)},
+		{Key: "stale", Scope: "s", Response: []byte(`{}`), Created: now.Add(-2 * time.Hour), Expires: now.Add(-time.Hour)},
+	} {
+		if err := store.Put(ctx, e); err != nil {
+			t.Fatalf("Put %s: %v", e.Key, err)
+		}
+	}
+
+	if e, ok, err := store.Get(ctx, "fresh", now); err != nil || !ok || !e.Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Get(fresh) = %+v, %v, %v", e, ok, err)
+	}
+	if _, ok, err := store.Get(ctx, "stale", now); err != nil || ok {
+		t.Errorf("Get(stale) = %v, %v, want miss", ok, err)
+	}
+	if n, err := store.DeleteExpired(ctx, now); err != nil || n != 1 {
+		t.Errorf("DeleteExpired = %d, %v, want 1", n, err)
+	}
+}
+
+func TestSQLiteStore_Nearest_ShouldReturnMostSimilarInScope(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, e := range []Entry{
+		{Key: "a", Scope: "s", Embedding: []float64{1, 0}},
+		{Key: "b", Scope: "s", Embedding: []float64{0.7, 0.7}},
+		{Key: "c", Scope: "other", Embedding: []float64{0, 1}},
+		{Key: "d", Scope: "s"}, // not semantically matchable
+	} {
+		e.Response, e.Created, e.Expires = []byte(`{}`), now, now.Add(time.Hour)
+		if err := store.Put(ctx, e); err != nil {
+			t.Fatalf("Put %s: %v", e.Key, err)
+		}
+	}
+
+	e, score, ok, err := store.Nearest(ctx, "s", []float64{0, 1}, now)
+	if err != nil || !ok || e.Key != "b" || score < 0.7 || score > 0.71 {
+		t.Errorf("Nearest = %s, %v, %v, %v; want b", e.Key, score, ok, err)
+	}
+	if _, _, ok, _ := store.Nearest(ctx, "empty", []float64{0, 1}, now); ok {
+		t.Error("Nearest in empty scope should find nothing")
+	}
+}
@@ -0,0 +1,127 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"ironclaw/internal/agent"
+	"ironclaw/internal/domain"
+)
+
+// AgentsOptions holds options for the agents command.
+type AgentsOptions struct {
+	Agents  domain.AgentsConfig // Global agents config: workspace root, bindings, default provider/model
+	Action  string              // "list", "show" or "create"
+	Name    string              // show/create: agent name
+	Profile agent.Profile       // create: provider, model and memory overrides
+}
+
+// RunAgents lists, shows or creates the named agents under Agents.Paths.Root.
+// Returns exit code (0 for success, 1 for error).
+func RunAgents(opts AgentsOptions, stdout, stderr io.Writer) int {
+	root := opts.Agents.Paths.Root
+	if root == "" {
+		fmt.Fprintln(stderr, "Error: agents.paths.root is not configured")
+		return 1
+	}
+	if opts.Action == "create" {
+		a, err := agent.Create(root, opts.Name, opts.Profile)
+		if err != nil {
+			fmt.Fprintf(stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "created agent %s in %s\n", a.Name, a.Dir)
+		fmt.Fprintf(stdout, "Bind channels to it in config, e.g. \"agents\": {\"bindings\": {\"telegram-*\": %q}}\n", a.Name)
+		return 0
+	}
+
+	reg, err := agent.LoadRegistry(root)
+	if err == nil {
+		err = reg.Bind(opts.Agents.Bindings, opts.Agents.DefaultAgent)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	switch opts.Action {
+	case "list":
+		printAgentList(stdout, reg, opts.Agents)
+	case "show":
+		a, ok := reg.Get(opts.Name)
+		if !ok {
+			fmt.Fprintf(stderr, "Error: %v: %q\n", agent.ErrAgentNotFound, opts.Name)
+			return 1
+		}
+		printAgentDetail(stdout, reg, a, opts.Agents)
+	default:
+		fmt.Fprintf(stderr, "Error: unknown action %q (use 'list', 'show' or 'create')\n", opts.Action)
+		return 1
+	}
+	return 0
+}
+
+func printAgentList(w io.Writer, reg *agent.Registry, global domain.AgentsConfig) {
+	agents := reg.List()
+	if len(agents) == 0 {
+		fmt.Fprintf(w, "no agents in %s (create one with 'ironclaw agents create <name>')\n", global.Paths.Root)
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tPROVIDER\tMODEL\tTOOLS\tCHANNELS")
+	for _, a := range agents {
+		s := a.Settings(global)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", a.Name, s.Provider, s.DefaultModel, agentTools(a), agentChannels(reg, a, global))
+	}
+	tw.Flush()
+}
+
+func printAgentDetail(w io.Writer, reg *agent.Registry, a *agent.Agent, global domain.AgentsConfig) {
+	s := a.Settings(global)
+	fmt.Fprintf(w, "Name:     %s\n", a.Name)
+	fmt.Fprintf(w, "Dir:      %s\n", a.Dir)
+	fmt.Fprintf(w, "Provider: %s\n", s.Provider)
+	fmt.Fprintf(w, "Model:    %s\n", s.DefaultModel)
+	fmt.Fprintf(w, "Memory:   %s\n", a.MemoryDir())
+	fmt.Fprintf(w, "Tools:    %s\n", agentTools(a))
+	fmt.Fprintf(w, "Channels: %s\n", agentChannels(reg, a, global))
+	if prompt := a.SystemPrompt(); prompt != "" {
+		fmt.Fprintf(w, "\nSystem prompt:\n  %s\n", strings.ReplaceAll(prompt, "\n", "\n  "))
+	}
+}
+
+// agentTools renders the agent's tool restrictions: "all" when unrestricted,
+// otherwise the TOOLS.md allowlist ("none" when empty) and the agent.json
+// allow/deny rule.
+func agentTools(a *agent.Agent) string {
+	if !a.RestrictsTools() {
+		return "all"
+	}
+	var parts []string
+	if a.HasToolsFile() {
+		if len(a.Context.Tools) == 0 {
+			return "none"
+		}
+		parts = append(parts, strings.Join(a.Context.Tools, ", "))
+	}
+	if allow := a.Profile.Tools.Allow; len(allow) > 0 {
+		parts = append(parts, "allow "+strings.Join(allow, ", "))
+	}
+	if deny := a.Profile.Tools.Deny; len(deny) > 0 {
+		parts = append(parts, "deny "+strings.Join(deny, ", "))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// agentChannels renders the agent's channel bindings, "-" when there are none.
+func agentChannels(reg *agent.Registry, a *agent.Agent, global domain.AgentsConfig) string {
+	channels := reg.Channels(a.Name)
+	if a.Name == global.DefaultAgent {
+		channels = append(channels, "(default)")
+	}
+	if len(channels) == 0 {
+		return "-"
+	}
+	return strings.Join(channels, ", ")
+}
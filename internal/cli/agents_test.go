@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/agent"
+	"ironclaw/internal/domain"
+)
+
+func TestRunAgents_CreateListShow(t *testing.T) {
+	cfg := domain.AgentsConfig{
+		Provider:     "openai",
+		DefaultModel: "gpt-4o",
+		Paths:        domain.AgentPaths{Root: filepath.Join(t.TempDir(), "agents")},
+		Bindings:     map[string]string{"telegram-*": "ops"},
+	}
+	var out, errOut bytes.Buffer
+	create := AgentsOptions{Agents: cfg, Action: "create", Name: "ops", Profile: agent.Profile{Provider: "anthropic", Model: "claude-sonnet", Tools: domain.ToolRule{Deny: []string{"docker_*"}}}}
+	if code := RunAgents(create, &out, &errOut); code != 0 {
+		t.Fatalf("create: exit %d: %s", code, errOut.String())
+	}
+	if err := os.WriteFile(filepath.Join(cfg.Paths.Root, "ops", "TOOLS.md"), []byte("- shell\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	out.Reset()
+	if code := RunAgents(AgentsOptions{Agents: cfg, Action: "list"}, &out, &errOut); code != 0 {
+		t.Fatalf("list: exit %d: %s", code, errOut.String())
+	}
+	for _, want := range []string{"ops", "anthropic", "claude-sonnet", "shell; deny docker_*", "telegram-*"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("list missing %q:\n%s", want, out.String())
+		}
+	}
+
+	out.Reset()
+	if code := RunAgents(AgentsOptions{Agents: cfg, Action: "show", Name: "ops"}, &out, &errOut); code != 0 {
+		t.Fatalf("show: exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "System prompt:") || !strings.Contains(out.String(), "You are ops.") {
+		t.Errorf("show missing system prompt:\n%s", out.String())
+	}
+}
+
+func TestRunAgents_WhenAgentMissingOrBindingInvalid_ShouldReturnOne(t *testing.T) {
+	cfg := domain.AgentsConfig{Paths: domain.AgentPaths{Root: t.TempDir()}}
+	var out, errOut bytes.Buffer
+	if code := RunAgents(AgentsOptions{Agents: cfg, Action: "show", Name: "ghost"}, &out, &errOut); code != 1 {
+		t.Errorf("show missing agent: want 1, got %d", code)
+	}
+	cfg.Bindings = map[string]string{"general": "ghost"}
+	errOut.Reset()
+	if code := RunAgents(AgentsOptions{Agents: cfg, Action: "list"}, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "ghost") {
+		t.Errorf("invalid binding: want 1 with message, got %d %q", code, errOut.String())
+	}
+}
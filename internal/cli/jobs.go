@@ -0,0 +1,248 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ironclaw/internal/scheduler"
+)
+
+// JobsOptions holds options for the jobs command. The command is a client of
+// the running daemon's /api/jobs endpoints.
+type JobsOptions struct {
+	URL     string        // Gateway base URL, e.g. "http://127.0.0.1:8080"
+	Token   string        // Gateway bearer token; empty when auth is off
+	Action  string        // "list", "show", "add", "remove", "run-now", "pause" or "resume"
+	ID      string        // Job ID (all actions except list)
+	Name    string        // add: human-readable name
+	Cron    string        // add: cron expression
+	At      string        // add: one-shot RFC 3339 time, instead of Cron
+	Jitter  time.Duration // add: max random delay per firing
+	Overlap string        // add: "skip", "queue" or "replace"
+	Deliver string        // add: delivery target "kind:to"
+	Prompt  string        // add: prompt injected when the job fires
+	Model   string        // add: model, alias or profile to answer with
+	Paused  bool          // add: register the job paused
+}
+
+// jobsHTTPClient is used for gateway requests; tests may replace it.
+var jobsHTTPClient = &http.Client{Timeout: 5 * time.Minute}
+
+// RunJobs runs the jobs subcommand against the gateway's job API.
+// Returns exit code (0 for success, 1 for error).
+func RunJobs(opts JobsOptions, stdout, stderr io.Writer) int {
+	if opts.URL == "" {
+		fmt.Fprintln(stderr, "Error: gateway URL must not be empty")
+		return 1
+	}
+	c := jobsClient{base: strings.TrimRight(opts.URL, "/"), token: opts.Token}
+	path := "/api/jobs/" + url.PathEscape(opts.ID)
+
+	var err error
+	switch opts.Action {
+	case "list":
+		var jobs []jobView
+		if err = c.do(http.MethodGet, "/api/jobs", nil, &jobs); err == nil {
+			printJobList(stdout, jobs)
+		}
+	case "show":
+		var detail jobDetail
+		if err = c.do(http.MethodGet, path, nil, &detail); err == nil {
+			printJobDetail(stdout, detail)
+		}
+	case "add":
+		var job scheduler.Job
+		if job, err = newJob(opts); err == nil {
+			if err = c.do(http.MethodPost, "/api/jobs", job, nil); err == nil {
+				fmt.Fprintf(stdout, "added %s\n", opts.ID)
+			}
+		}
+	case "remove":
+		if err = c.do(http.MethodDelete, path, nil, nil); err == nil {
+			fmt.Fprintf(stdout, "removed %s\n", opts.ID)
+		}
+	case "run-now":
+		var run scheduler.Run
+		if err = c.do(http.MethodPost, path+"/run", nil, &run); err == nil {
+			if run.Error != "" {
+				fmt.Fprintf(stderr, "Error: job %s failed after %s: %s\n", opts.ID, run.Duration.Round(time.Millisecond), run.Error)
+				return 1
+			}
+			fmt.Fprintln(stdout, run.Response)
+		}
+	case "pause", "resume":
+		if err = c.do(http.MethodPost, path+"/"+opts.Action, nil, nil); err == nil {
+			fmt.Fprintf(stdout, "%sd %s\n", opts.Action, opts.ID)
+		}
+	default:
+		err = fmt.Errorf("unknown action %q (use 'list', 'show', 'add', 'remove', 'run-now', 'pause' or 'resume')", opts.Action)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// newJob builds the job for the add action, parsing the at time and target.
+func newJob(opts JobsOptions) (scheduler.Job, error) {
+	job := scheduler.Job{
+		ID:       opts.ID,
+		Name:     opts.Name,
+		CronExpr: opts.Cron,
+		Jitter:   opts.Jitter,
+		Overlap:  scheduler.OverlapPolicy(opts.Overlap),
+		Prompt:   opts.Prompt,
+		Model:    opts.Model,
+		Paused:   opts.Paused,
+	}
+	var err error
+	if opts.At != "" {
+		if job.At, err = time.Parse(time.RFC3339, opts.At); err != nil {
+			return job, fmt.Errorf("--at: %w", err)
+		}
+	}
+	if job.Deliver, err = scheduler.ParseTarget(opts.Deliver); err != nil {
+		return job, err
+	}
+	return job, nil
+}
+
+// jobView and jobDetail mirror gateway.JobView and gateway.JobDetail.
+type jobView struct {
+	scheduler.Job
+	LastRun *scheduler.Run `json:"lastRun"`
+}
+
+type jobDetail struct {
+	scheduler.Job
+	Runs []scheduler.Run `json:"runs"`
+}
+
+type jobsClient struct {
+	base  string
+	token string
+}
+
+// do sends a JSON request and decodes a JSON response into out (if non-nil).
+// Non-2xx responses are returned as errors carrying the gateway's message.
+func (c jobsClient) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		raw, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := jobsHTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot reach gateway at %s (is the daemon running?): %w", c.base, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error string `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) == nil && e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return fmt.Errorf("gateway: %s", resp.Status)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func printJobList(w io.Writer, jobs []jobView) {
+	if len(jobs) == 0 {
+		fmt.Fprintln(w, "no jobs")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAME\tSCHEDULE\tSTATE\tLAST RUN")
+	for _, j := range jobs {
+		last := "never"
+		if j.LastRun != nil {
+			last = formatRun(*j.LastRun)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", j.ID, j.Name, jobSchedule(j.Job), jobState(j.Job), last)
+	}
+	tw.Flush()
+}
+
+func printJobDetail(w io.Writer, d jobDetail) {
+	fmt.Fprintf(w, "ID:     %s\n", d.ID)
+	if d.Name != "" {
+		fmt.Fprintf(w, "Name:   %s\n", d.Name)
+	}
+	fmt.Fprintf(w, "When:   %s\n", jobSchedule(d.Job))
+	fmt.Fprintf(w, "State:  %s\n", jobState(d.Job))
+	if d.Overlap != "" {
+		fmt.Fprintf(w, "Overlap: %s\n", d.Overlap)
+	}
+	if d.Deliver.Kind != "" {
+		fmt.Fprintf(w, "Deliver: %s\n", d.Deliver)
+	}
+	if d.Model != "" {
+		fmt.Fprintf(w, "Model:  %s\n", d.Model)
+	}
+	fmt.Fprintf(w, "Prompt: %s\n", d.Prompt)
+	if len(d.Runs) == 0 {
+		fmt.Fprintln(w, "\nNo runs recorded.")
+		return
+	}
+	fmt.Fprintln(w, "\nRuns (newest first):")
+	for _, r := range d.Runs {
+		fmt.Fprintf(w, "  %s\n", formatRun(r))
+		if r.Response != "" {
+			fmt.Fprintf(w, "    %s\n", strings.ReplaceAll(strings.TrimSpace(r.Response), "\n", "\n    "))
+		}
+	}
+}
+
+func jobState(j scheduler.Job) string {
+	if j.Paused {
+		return "paused"
+	}
+	return "active"
+}
+
+// jobSchedule renders the cron expression or one-shot time, plus any jitter.
+func jobSchedule(j scheduler.Job) string {
+	s := j.CronExpr
+	if !j.At.IsZero() {
+		s = "at " + j.At.Local().Format(time.DateTime)
+	}
+	if j.Jitter > 0 {
+		s += " ±" + j.Jitter.String()
+	}
+	return s
+}
+
+// formatRun renders a run as "<start> (<duration>) ok|error: <msg>".
+func formatRun(r scheduler.Run) string {
+	status := "ok"
+	if r.Error != "" {
+		status = "error: " + r.Error
+	}
+	return fmt.Sprintf("%s (%s) %s", r.StartedAt.Local().Format(time.DateTime), r.Duration.Round(time.Millisecond), status)
+}
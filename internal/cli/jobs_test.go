@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/scheduler"
+)
+
+func TestRunJobs_List_ShouldPrintJobsWithLastRun(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/jobs" || r.Header.Get("Authorization") != "Bearer tok" {
+			t.Errorf("unexpected request %s %s", r.URL.Path, r.Header.Get("Authorization"))
+		}
+		json.NewEncoder(w).Encode([]jobView{
+			{Job: scheduler.Job{ID: "digest", CronExpr: "@daily"}, LastRun: &scheduler.Run{StartedAt: time.Now(), Duration: 1500 * time.Millisecond, Error: "llm down"}},
+			{Job: scheduler.Job{ID: "idle", CronExpr: "@hourly", Paused: true}},
+		})
+	}))
+	defer ts.Close()
+
+	var out, errOut bytes.Buffer
+	code := RunJobs(JobsOptions{URL: ts.URL, Token: "tok", Action: "list"}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	for _, want := range []string{"digest", "active", "1.5s", "error: llm down", "idle", "paused", "never"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output missing %q:\n%s", want, out.String())
+		}
+	}
+}
+
+func TestRunJobs_Add_ShouldPostJobAndReportGatewayErrors(t *testing.T) {
+	var got scheduler.Job
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(`{"error":"scheduler: job with this ID already exists: digest"}`))
+	}))
+	defer ts.Close()
+
+	var out, errOut bytes.Buffer
+	code := RunJobs(JobsOptions{URL: ts.URL, Action: "add", ID: "digest", Cron: "@daily", Prompt: "p"}, &out, &errOut)
+	if code != 1 || !strings.Contains(errOut.String(), "already exists") {
+		t.Errorf("expected gateway error surfaced, code=%d stderr=%q", code, errOut.String())
+	}
+	if got.ID != "digest" || got.CronExpr != "@daily" || got.Prompt != "p" {
+		t.Errorf("unexpected job posted: %+v", got)
+	}
+}
+
+func TestRunJobs_RunNow_WhenRunFailed_ShouldReturnOne(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"jobId":"j","error":"llm down"}`))
+	}))
+	defer ts.Close()
+
+	var out, errOut bytes.Buffer
+	if code := RunJobs(JobsOptions{URL: ts.URL, Action: "run-now", ID: "j"}, &out, &errOut); code != 1 {
+		t.Errorf("want exit 1, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "llm down") {
+		t.Errorf("expected run error on stderr, got %q", errOut.String())
+	}
+}
+
+func TestRunJobs_WhenGatewayUnreachable_ShouldHintDaemon(t *testing.T) {
+	var out, errOut bytes.Buffer
+	if code := RunJobs(JobsOptions{URL: "http://127.0.0.1:1", Action: "list"}, &out, &errOut); code != 1 {
+		t.Errorf("want exit 1, got %d", code)
+	}
+	if !strings.Contains(errOut.String(), "is the daemon running?") {
+		t.Errorf("expected daemon hint, got %q", errOut.String())
+	}
+}
+
+func TestRunJobs_Add_ShouldSendOneShotTriggerAndTarget(t *testing.T) {
+	var got scheduler.Job
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer ts.Close()
+
+	var out, errOut bytes.Buffer
+	opts := JobsOptions{URL: ts.URL, Action: "add", ID: "remind", At: "2026-03-01T09:00:00Z", Jitter: time.Minute,
+		Overlap: "queue", Deliver: "telegram:42", Prompt: "p"}
+	if code := RunJobs(opts, &out, &errOut); code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	want := scheduler.Job{ID: "remind", At: time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC), Jitter: time.Minute,
+		Overlap: scheduler.OverlapQueue, Deliver: scheduler.Target{Kind: scheduler.TargetTelegram, To: "42"}, Prompt: "p"}
+	if !got.At.Equal(want.At) || got.Jitter != want.Jitter || got.Overlap != want.Overlap || got.Deliver != want.Deliver {
+		t.Errorf("unexpected job posted: %+v", got)
+	}
+
+	opts.Deliver = "telegram"
+	if code := RunJobs(opts, &out, &errOut); code != 1 || !strings.Contains(errOut.String(), "needs a destination") {
+		t.Errorf("expected invalid target rejected locally, code=%d stderr=%q", code, errOut.String())
+	}
+}
@@ -0,0 +1,126 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+// UsageOptions holds options for the usage command.
+type UsageOptions struct {
+	Store   usage.Store        // Usage records, opened by the caller
+	Config  domain.UsageConfig // Budgets to report on
+	Period  string             // "today", "month" (default) or "all"
+	By      usage.Dimension    // Grouping; default usage.ByModel
+	Channel string             // Only this channel
+	Agent   string             // Only this agent
+	Now     time.Time          // Reference time for Period; zero means time.Now
+}
+
+// RunUsage prints token usage and spend for the period grouped by opts.By,
+// followed by the state of every configured budget.
+// Returns exit code (0 for success, 1 for error).
+func RunUsage(opts UsageOptions, stdout, stderr io.Writer) int {
+	if opts.Store == nil {
+		fmt.Fprintln(stderr, "Error: usage store must not be nil")
+		return 1
+	}
+	now := opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	f := usage.Filter{Channel: opts.Channel, Agent: opts.Agent}
+	switch opts.Period {
+	case "today":
+		f.Since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	case "", "month":
+		f.Since = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	case "all":
+	default:
+		fmt.Fprintf(stderr, "Error: unknown period %q (use 'today', 'month' or 'all')\n", opts.Period)
+		return 1
+	}
+	by := opts.By
+	if by == "" {
+		by = usage.ByModel
+	}
+
+	ctx := context.Background()
+	groups, err := opts.Store.Summarize(ctx, f, by)
+	if err == nil {
+		var total []usage.Summary
+		if total, err = opts.Store.Summarize(ctx, f, ""); err == nil {
+			printUsage(stdout, f, by, groups, total)
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+
+	if len(opts.Config.Budgets) == 0 {
+		return 0
+	}
+	m, err := usage.NewMeterFromConfig(opts.Config, opts.Store)
+	var budgets []usage.BudgetStatus
+	if err == nil {
+		budgets, err = m.Budgets(ctx)
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return 1
+	}
+	printBudgets(stdout, budgets)
+	return 0
+}
+
+func printUsage(w io.Writer, f usage.Filter, by usage.Dimension, groups, total []usage.Summary) {
+	since := "all time"
+	if !f.Since.IsZero() {
+		since = "since " + f.Since.Format(time.DateOnly)
+	}
+	fmt.Fprintf(w, "Usage %s\n\n", since)
+	if len(total) == 0 {
+		fmt.Fprintln(w, "no usage recorded")
+		return
+	}
+	sum := total[0]
+	sum.Group = "TOTAL"
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "%s\tCALLS\tINPUT\tOUTPUT\tCOST\n", strings.ToUpper(string(by)))
+	for _, g := range append(groups, sum) {
+		name := g.Group
+		if name == "" {
+			name = "-"
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t$%.4f\n", name, g.Calls, g.InputTokens, g.OutputTokens, g.Cost)
+	}
+	tw.Flush()
+}
+
+func printBudgets(w io.Writer, budgets []usage.BudgetStatus) {
+	fmt.Fprintln(w, "\nBudgets")
+	if len(budgets) == 0 {
+		fmt.Fprintln(w, "no budgeted channel or agent has usage this month")
+		return
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "SCOPE\tNAME\tPERIOD\tSPENT\tLIMIT\tSTATE")
+	for _, b := range budgets {
+		state := "ok"
+		switch {
+		case b.Exceeded():
+			state = "exceeded"
+		case b.Warning():
+			state = "warning"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t$%.2f\t$%.2f\t%s\n", b.Scope, b.Name, b.Period, b.Spent, b.Limit, state)
+	}
+	tw.Flush()
+}
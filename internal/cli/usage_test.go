@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/db"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+func newTestUsageStore(t *testing.T) *usage.SQLiteStore {
+	t.Helper()
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	store, err := usage.NewSQLiteStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	return store
+}
+
+func TestRunUsage_ShouldPrintGroupsTotalAndBudgets(t *testing.T) {
+	store := newTestUsageStore(t)
+	now := time.Now()
+	for _, rec := range []usage.Record{
+		{Time: now, Channel: "telegram-1", Agent: "ops", Model: "gpt-4o", InputTokens: 100, OutputTokens: 50, Cost: 4},
+		{Time: now, Channel: "general", Model: "claude", InputTokens: 10, OutputTokens: 5, Cost: 0.5},
+		{Time: now.AddDate(0, -2, 0), Channel: "general", Model: "old-model", Cost: 100},
+	} {
+		if err := store.Add(context.Background(), rec); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	cfg := domain.UsageConfig{Budgets: []domain.BudgetConfig{{Channel: "telegram-*", Monthly: 5}}}
+
+	var out, errOut bytes.Buffer
+	code := RunUsage(UsageOptions{Store: store, Config: cfg, Now: now}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	got := out.String()
+	for _, want := range []string{"MODEL", "gpt-4o", "claude", "TOTAL", "$4.5000", "Budgets", "telegram-1", "monthly", "$4.00", "$5.00", "warning"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("output missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "old-model") {
+		t.Errorf("output includes usage from before this month:\n%s", got)
+	}
+}
+
+func TestRunUsage_WhenNoUsage_ShouldSayNoUsageRecorded(t *testing.T) {
+	var out, errOut bytes.Buffer
+	code := RunUsage(UsageOptions{Store: newTestUsageStore(t), Period: "all", By: usage.ByChannel}, &out, &errOut)
+	if code != 0 {
+		t.Fatalf("exit %d: %s", code, errOut.String())
+	}
+	if !strings.Contains(out.String(), "all time") || !strings.Contains(out.String(), "no usage recorded") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
+
+func TestRunUsage_WhenInvalidOptions_ShouldFail(t *testing.T) {
+	store := newTestUsageStore(t)
+	for name, opts := range map[string]UsageOptions{
+		"nil store":      {},
+		"unknown period": {Store: store, Period: "week"},
+		"unknown by":     {Store: store, By: "color"},
+	} {
+		var out, errOut bytes.Buffer
+		if code := RunUsage(opts, &out, &errOut); code != 1 || errOut.Len() == 0 {
+			t.Errorf("%s: exit %d, stderr %q; want 1 with error", name, code, errOut.String())
+		}
+	}
+}
@@ -0,0 +1,311 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// Compaction defaults, used for zero CompactionConfig fields.
+const (
+	DefaultCompactThreshold = 0.8
+	DefaultCompactTarget    = 0.5
+	DefaultReserveOutput    = 1024
+)
+
+// ErrNoSummaryStore is returned by Compact when its context carries no
+// domain.SummaryStore to keep the summary in.
+var ErrNoSummaryStore = errors.New("context: no summary store for this conversation")
+
+// summaryHeader introduces the summary message placed before the recent
+// messages.
+const summaryHeader = "[Summary of the earlier conversation]\n"
+
+// CompactorOption configures a Compactor.
+type CompactorOption func(*Compactor)
+
+// WithCompaction applies cfg. Zero fields, and shares outside (0, 1], keep
+// their defaults.
+func WithCompaction(cfg domain.CompactionConfig) CompactorOption {
+	return func(c *Compactor) {
+		if cfg.Threshold > 0 && cfg.Threshold <= 1 {
+			c.threshold = cfg.Threshold
+		}
+		if cfg.Target > 0 && cfg.Target <= 1 {
+			c.target = cfg.Target
+		}
+		if cfg.ReserveOutput > 0 {
+			c.reserveOutput = cfg.ReserveOutput
+		}
+		c.model = cfg.Model
+	}
+}
+
+// WithFitting configures how messages are counted and fitted, as for
+// NewManager.
+func WithFitting(opts ...FitOption) CompactorOption {
+	return func(c *Compactor) {
+		for _, opt := range opts {
+			opt(&c.fitter)
+		}
+	}
+}
+
+// Compactor implements domain.ContextCompactor. While the messages fit in
+// threshold of the budget they are sent as they are; beyond it, the oldest
+// are summarised with the LLM into a rolling summary, sent as the first
+// message, so that the recent messages fit in target of the budget. The
+// summary is kept in the SummaryStore of the caller's context, so each span
+// is summarised once; without one it is recomputed whenever the messages
+// outgrow the threshold. Pinned messages are never summarised. Whatever
+// still does not fit, or all of it if summarising fails, is dropped as
+// Manager does.
+type Compactor struct {
+	fitter
+	maxTokens     int
+	llm           domain.LLMProvider
+	threshold     float64
+	target        float64
+	reserveOutput int
+	model         string // model or profile summaries are written by; "" for the default
+}
+
+// NewCompactor creates a Compactor fitting messages into maxTokens, counted
+// with tokenizer, that writes summaries with llm. Panics if tokenizer or llm
+// is nil or maxTokens <= 0.
+func NewCompactor(tokenizer domain.Tokenizer, maxTokens int, llm domain.LLMProvider, opts ...CompactorOption) *Compactor {
+	if tokenizer == nil {
+		panic("context: tokenizer must not be nil")
+	}
+	if llm == nil {
+		panic("context: llm must not be nil")
+	}
+	if maxTokens <= 0 {
+		panic("context: maxTokens must be > 0")
+	}
+	c := &Compactor{
+		fitter:        newFitter(tokenizer),
+		maxTokens:     maxTokens,
+		llm:           llm,
+		threshold:     DefaultCompactThreshold,
+		target:        DefaultCompactTarget,
+		reserveOutput: DefaultReserveOutput,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// FitToWindow implements domain.ContextManager, summarising without a
+// context or a SummaryStore.
+func (c *Compactor) FitToWindow(messages []domain.Message, systemPrompt string) ([]domain.Message, error) {
+	return c.FitContext(context.Background(), messages, systemPrompt)
+}
+
+// FitContext implements domain.ContextCompactor.
+//
+// Returns an error if the system prompt and reserved output alone exceed
+// maxTokens or if the tokenizer returns an error.
+func (c *Compactor) FitContext(ctx context.Context, messages []domain.Message, systemPrompt string) ([]domain.Message, error) {
+	if len(messages) == 0 {
+		return []domain.Message{}, nil
+	}
+	budget, err := c.budget(systemPrompt)
+	if err != nil {
+		return nil, err
+	}
+
+	store := domain.SummaryStoreFrom(ctx)
+	var summary domain.ContextSummary
+	if store != nil {
+		// An unreadable summary is treated as none rather than failing the turn.
+		summary, _ = store.LoadSummary()
+	}
+	messages = unsummarised(messages, summary)
+	counts, total, err := c.count(messages)
+	if err != nil {
+		return nil, err
+	}
+	summaryTokens, err := c.summaryTokens(summary)
+	if err != nil {
+		return nil, err
+	}
+
+	if summaryTokens+total > int(c.threshold*float64(budget)) {
+		split := keepFrom(group(messages, counts), int(c.target*float64(budget)))
+		if span, pinned := partition(messages[:split]); len(span) > 0 {
+			if updated, err := c.summarize(ctx, summary, span); err == nil {
+				summary = updated
+				messages = append(pinned, messages[split:]...)
+				if store != nil {
+					// Best effort: an unsaved summary is recomputed next turn.
+					_ = store.SaveSummary(summary)
+				}
+				if summaryTokens, err = c.summaryTokens(summary); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// Fit what is left alongside the summary, dropping the summary itself
+	// if it leaves no room.
+	if summaryTokens >= budget {
+		summary, summaryTokens = domain.ContextSummary{}, 0
+	}
+	fitted, err := c.fit(messages, budget-summaryTokens)
+	if err != nil {
+		return nil, err
+	}
+	return withSummary(summary, fitted), nil
+}
+
+// Compact implements domain.ContextCompactor. Returns ErrNoSummaryStore when
+// ctx carries no SummaryStore.
+func (c *Compactor) Compact(ctx context.Context, messages []domain.Message) (int, error) {
+	store := domain.SummaryStoreFrom(ctx)
+	if store == nil {
+		return 0, ErrNoSummaryStore
+	}
+	summary, err := store.LoadSummary()
+	if err != nil {
+		return 0, fmt.Errorf("context: load summary: %w", err)
+	}
+	span, _ := partition(unsummarised(messages, summary))
+	if len(span) == 0 {
+		return 0, nil
+	}
+	updated, err := c.summarize(ctx, summary, span)
+	if err != nil {
+		return 0, err
+	}
+	if err := store.SaveSummary(updated); err != nil {
+		return 0, fmt.Errorf("context: save summary: %w", err)
+	}
+	return len(span), nil
+}
+
+// budget returns the tokens left for messages once the system prompt and
+// the reserved output are taken from maxTokens.
+func (c *Compactor) budget(systemPrompt string) (int, error) {
+	sysTokens := 0
+	if systemPrompt != "" {
+		n, err := c.tokenizer.CountTokens(systemPrompt)
+		if err != nil {
+			return 0, fmt.Errorf("context: counting system prompt tokens: %w", err)
+		}
+		sysTokens = n
+	}
+	budget := c.maxTokens - c.reserveOutput - sysTokens
+	if budget <= 0 {
+		return 0, fmt.Errorf("context: system prompt (%d tokens) and reserved output (%d tokens) exceed limit (%d tokens)", sysTokens, c.reserveOutput, c.maxTokens)
+	}
+	return budget, nil
+}
+
+// summaryTokens returns the tokens of the message carrying s, 0 for none.
+func (c *Compactor) summaryTokens(s domain.ContextSummary) (int, error) {
+	if s.Text == "" {
+		return 0, nil
+	}
+	n, err := c.tokenizer.CountTokens(summaryHeader + s.Text)
+	if err != nil {
+		return 0, fmt.Errorf("context: counting summary tokens: %w", err)
+	}
+	return n, nil
+}
+
+// summarize asks the LLM to fold span into prev and returns the new summary.
+func (c *Compactor) summarize(ctx context.Context, prev domain.ContextSummary, span []domain.Message) (domain.ContextSummary, error) {
+	text, err := c.llm.Generate(domain.WithModel(ctx, c.model), summaryPrompt(prev.Text, span))
+	if err != nil {
+		return domain.ContextSummary{}, fmt.Errorf("context: summarize: %w", err)
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return domain.ContextSummary{}, fmt.Errorf("context: summarize: empty summary")
+	}
+	return domain.ContextSummary{
+		Text:     text,
+		Through:  span[len(span)-1].Timestamp,
+		Messages: prev.Messages + len(span),
+		Updated:  time.Now(),
+	}, nil
+}
+
+// summaryPrompt asks for prev and span to be summarised together.
+func summaryPrompt(prev string, span []domain.Message) string {
+	var sb strings.Builder
+	sb.WriteString("Summarize the conversation below so the summary can replace it in the assistant's context. " +
+		"Keep the user's original request and goals, decisions made, facts, names and numbers, open questions, " +
+		"and anything the assistant has promised to do. Be concise and reply with the summary only.\n\n")
+	if prev != "" {
+		sb.WriteString("[Summary so far]\n")
+		sb.WriteString(prev)
+		sb.WriteString("\n\n")
+	}
+	sb.WriteString("[Conversation]\n")
+	for _, msg := range span {
+		fmt.Fprintf(&sb, "%s: %s\n", msg.Role, MessageText(msg))
+	}
+	return sb.String()
+}
+
+// keepFrom returns the index of the first message of the most recent units
+// that fit in target tokens, always keeping the last unit, so a tool call is
+// never summarised apart from its result.
+func keepFrom(units []unit, target int) int {
+	i := len(units) - 1
+	for total := units[i].tokens; i > 0 && total+units[i-1].tokens <= target; i-- {
+		total += units[i-1].tokens
+	}
+	return units[i].start
+}
+
+// partition splits messages into those to summarise and the pinned ones,
+// which are kept as they are.
+func partition(messages []domain.Message) (span, pinned []domain.Message) {
+	for _, msg := range messages {
+		if msg.Pinned {
+			pinned = append(pinned, msg)
+		} else {
+			span = append(span, msg)
+		}
+	}
+	return span, pinned
+}
+
+// unsummarised drops the messages that s already summarises: those up to
+// its Through time that are not pinned. Messages without a timestamp are
+// never considered summarised.
+func unsummarised(messages []domain.Message, s domain.ContextSummary) []domain.Message {
+	if s.Through.IsZero() {
+		return messages
+	}
+	out := make([]domain.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Pinned || msg.Timestamp.IsZero() || msg.Timestamp.After(s.Through) {
+			out = append(out, msg)
+		}
+	}
+	return out
+}
+
+// withSummary returns messages preceded by a user message carrying s, or
+// messages itself when s is empty.
+func withSummary(s domain.ContextSummary, messages []domain.Message) []domain.Message {
+	if s.Text == "" {
+		return messages
+	}
+	msg := domain.NewTextMessage(domain.RoleUser, summaryHeader+s.Text)
+	msg.Timestamp = s.Through
+	return append([]domain.Message{msg}, messages...)
+}
+
+// Ensure Compactor implements domain.ContextCompactor.
+var _ domain.ContextCompactor = (*Compactor)(nil)
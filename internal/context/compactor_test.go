@@ -0,0 +1,213 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// summaryLLM writes fixed summaries and records the prompts it was sent.
+type summaryLLM struct {
+	reply   string
+	err     error
+	prompts []string
+	models  []string
+}
+
+func (s *summaryLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	s.models = append(s.models, domain.ModelFrom(ctx))
+	return s.reply, s.err
+}
+
+// memSummaryStore is an in-memory domain.SummaryStore.
+type memSummaryStore struct {
+	summary domain.ContextSummary
+	saves   int
+}
+
+func (m *memSummaryStore) LoadSummary() (domain.ContextSummary, error) { return m.summary, nil }
+func (m *memSummaryStore) SaveSummary(s domain.ContextSummary) error {
+	m.summary = s
+	m.saves++
+	return nil
+}
+
+// conversation returns n ten-token messages, alternating user and
+// assistant, a minute apart.
+func conversation(n int) []domain.Message {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	msgs := make([]domain.Message, n)
+	for i := range msgs {
+		role := domain.RoleUser
+		if i%2 == 1 {
+			role = domain.RoleAssistant
+		}
+		msgs[i] = textMsg(role, strings.TrimSpace(strings.Repeat("word ", 9))+" m"+string(rune('a'+i)))
+		msgs[i].Timestamp = base.Add(time.Duration(i) * time.Minute)
+	}
+	return msgs
+}
+
+// newTestCompactor fits messages into a 90-token budget: 100 tokens less 10
+// reserved for output.
+func newTestCompactor(llm domain.LLMProvider, cfg domain.CompactionConfig) *Compactor {
+	cfg.ReserveOutput = 10
+	return NewCompactor(newWordCountTokenizer(), 100, llm, WithCompaction(cfg))
+}
+
+func TestCompactor_WhenUnderThreshold_ShouldNotSummarize(t *testing.T) {
+	llm := &summaryLLM{reply: "unused"}
+	msgs := conversation(7) // 70 tokens, under 0.8 of 90
+
+	got, err := newTestCompactor(llm, domain.CompactionConfig{}).FitContext(context.Background(), msgs, "")
+	if err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	if len(got) != 7 || len(llm.prompts) != 0 {
+		t.Errorf("got %d messages after %d summaries, want 7 and none", len(got), len(llm.prompts))
+	}
+}
+
+func TestCompactor_WhenOverThreshold_ShouldSummarizeOldestAndSaveSummary(t *testing.T) {
+	llm := &summaryLLM{reply: "User asked for a plan."}
+	store := &memSummaryStore{}
+	ctx := domain.WithSummaryStore(context.Background(), store)
+	msgs := conversation(10) // 100 tokens
+	c := newTestCompactor(llm, domain.CompactionConfig{Model: "fast"})
+
+	got, err := c.FitContext(ctx, msgs, "")
+	if err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	// The most recent 40 tokens (target 0.5 of 90) are kept after the summary.
+	if len(got) != 5 || !strings.Contains(MessageText(got[0]), "User asked for a plan.") || MessageText(got[1]) != MessageText(msgs[6]) {
+		t.Fatalf("got %d messages, first %q", len(got), MessageText(got[0]))
+	}
+	if len(llm.prompts) != 1 || !strings.Contains(llm.prompts[0], " ma\n") || strings.Contains(llm.prompts[0], " mg\n") || llm.models[0] != "fast" {
+		t.Errorf("summary prompt = %q with model %q", llm.prompts, llm.models)
+	}
+	if !store.summary.Through.Equal(msgs[5].Timestamp) || store.summary.Messages != 6 {
+		t.Errorf("saved summary = %+v, want through message 6", store.summary)
+	}
+
+	// Next turn the summary is reused rather than recomputed.
+	got, err = c.FitContext(ctx, append(msgs, conversation(12)[10:]...), "")
+	if err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	if len(llm.prompts) != 1 || len(got) != 7 || !strings.Contains(MessageText(got[0]), "User asked for a plan.") {
+		t.Errorf("second turn: %d summaries, %d messages", len(llm.prompts), len(got))
+	}
+}
+
+func TestCompactor_WhenSummaryExists_ShouldFoldItIntoTheNext(t *testing.T) {
+	llm := &summaryLLM{reply: "new summary"}
+	msgs := conversation(14)
+	store := &memSummaryStore{summary: domain.ContextSummary{Text: "old summary", Through: msgs[1].Timestamp, Messages: 2}}
+
+	if _, err := newTestCompactor(llm, domain.CompactionConfig{}).FitContext(domain.WithSummaryStore(context.Background(), store), msgs, ""); err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	if len(llm.prompts) != 1 || !strings.Contains(llm.prompts[0], "old summary") || strings.Contains(llm.prompts[0], " ma\n") {
+		t.Errorf("prompt = %q, want the old summary and no summarised message", llm.prompts)
+	}
+	if store.summary.Text != "new summary" || store.summary.Messages <= 2 {
+		t.Errorf("summary = %+v", store.summary)
+	}
+}
+
+func TestCompactor_WhenSummarizeFails_ShouldDropOldestMessages(t *testing.T) {
+	llm := &summaryLLM{err: errors.New("503 service unavailable")}
+	store := &memSummaryStore{}
+
+	got, err := newTestCompactor(llm, domain.CompactionConfig{}).FitContext(domain.WithSummaryStore(context.Background(), store), conversation(10), "")
+	if err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	if len(got) != 9 || store.saves != 0 {
+		t.Errorf("got %d messages, %d saves; want the 9 that fit and no summary", len(got), store.saves)
+	}
+}
+
+func TestCompactor_WhenSplitAtToolResult_ShouldKeepItWithItsCall(t *testing.T) {
+	msgs := conversation(10)
+	msgs[5] = domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: "t1", Name: "search", Input: []byte(`{}`)})
+	msgs[6] = domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: "t1", Content: "found"})
+	counts := []int{10, 10, 10, 10, 10, 10, 10, 10, 10, 10}
+
+	if split := keepFrom(group(msgs, counts), 40); split != 7 {
+		t.Errorf("keepFrom = %d, want 7 (past the tool result)", split)
+	}
+	if split := keepFrom(group(msgs, counts), 50); split != 5 {
+		t.Errorf("keepFrom = %d, want 5 (the call with its result)", split)
+	}
+}
+
+func TestCompactor_WhenPinnedMessageInSpan_ShouldKeepItVerbatim(t *testing.T) {
+	llm := &summaryLLM{reply: "summary"}
+	store := &memSummaryStore{}
+	ctx := domain.WithSummaryStore(context.Background(), store)
+	msgs := conversation(10)
+	msgs[1].Pinned = true
+	c := newTestCompactor(llm, domain.CompactionConfig{})
+
+	got, err := c.FitContext(ctx, msgs, "")
+	if err != nil {
+		t.Fatalf("FitContext: %v", err)
+	}
+	if strings.Contains(llm.prompts[0], " mb\n") || store.summary.Messages != 5 {
+		t.Errorf("pinned message summarised: prompt %q, summary %+v", llm.prompts[0], store.summary)
+	}
+	if len(got) < 2 || MessageText(got[1]) != MessageText(msgs[1]) {
+		t.Fatalf("want the pinned message right after the summary, got %d messages", len(got))
+	}
+
+	// It stays after the summary on later turns too.
+	got, err = c.FitContext(ctx, msgs, "")
+	if err != nil || len(got) < 2 || MessageText(got[1]) != MessageText(msgs[1]) {
+		t.Errorf("second turn lost the pinned message: %d messages, %v", len(got), err)
+	}
+}
+
+func TestCompactor_WhenSystemPromptAndReserveExceedLimit_ShouldReturnError(t *testing.T) {
+	c := NewCompactor(newWordCountTokenizer(), 20, &summaryLLM{}, WithCompaction(domain.CompactionConfig{ReserveOutput: 15}))
+	if _, err := c.FitToWindow(conversation(1), "one two three four five six"); err == nil {
+		t.Fatal("expected error")
+	}
+}
+
+func TestCompactor_Compact_ShouldSummarizeEverythingNotYetSummarized(t *testing.T) {
+	llm := &summaryLLM{reply: "all of it"}
+	store := &memSummaryStore{}
+	c := newTestCompactor(llm, domain.CompactionConfig{})
+	msgs := conversation(3)
+
+	if _, err := c.Compact(context.Background(), msgs); !errors.Is(err, ErrNoSummaryStore) {
+		t.Errorf("Compact without store: err = %v, want ErrNoSummaryStore", err)
+	}
+	ctx := domain.WithSummaryStore(context.Background(), store)
+	if n, err := c.Compact(ctx, msgs); err != nil || n != 3 {
+		t.Fatalf("Compact = %d, %v, want 3", n, err)
+	}
+	if n, err := c.Compact(ctx, msgs); err != nil || n != 0 {
+		t.Errorf("second Compact = %d, %v, want nothing to do", n, err)
+	}
+	got, _ := c.FitContext(ctx, msgs, "")
+	if len(got) != 1 || !strings.Contains(MessageText(got[0]), "all of it") {
+		t.Errorf("after Compact, fitted = %d messages", len(got))
+	}
+}
+
+func TestNewCompactor_WhenLLMNil_ShouldPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+	NewCompactor(newWordCountTokenizer(), 100, nil)
+}
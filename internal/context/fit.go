@@ -0,0 +1,309 @@
+package context
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"ironclaw/internal/domain"
+)
+
+// FitOption configures how Manager and Compactor count and fit messages.
+type FitOption func(*fitter)
+
+// WithImageProvider estimates image tokens as provider charges them (see
+// ImageTokens). Without it images cost DefaultImageTokens.
+func WithImageProvider(provider string) FitOption {
+	return func(f *fitter) { f.provider = provider }
+}
+
+// WithToolResultLimit sets the tokens a tool result is truncated to when
+// the messages do not fit (default a quarter of the tokens left for
+// messages). Non-positive values keep the default.
+func WithToolResultLimit(tokens int) FitOption {
+	return func(f *fitter) {
+		if tokens > 0 {
+			f.toolResultLimit = tokens
+		}
+	}
+}
+
+// fitter counts messages block by block and fits them into a token budget.
+// Messages are kept or dropped in units: a message, or an assistant message
+// calling tools together with the messages carrying their results, so a
+// call is never sent without its result or a result without its call.
+type fitter struct {
+	tokenizer       domain.Tokenizer
+	provider        string // names the image token estimate
+	toolResultLimit int    // 0: a quarter of the budget
+}
+
+// newFitter returns a fitter counting with tokenizer, configured by opts.
+func newFitter(tokenizer domain.Tokenizer, opts ...FitOption) fitter {
+	f := fitter{tokenizer: tokenizer}
+	for _, opt := range opts {
+		opt(&f)
+	}
+	return f
+}
+
+// unit is a run of messages kept or dropped together.
+type unit struct {
+	start, end int // messages[start:end]
+	tokens     int
+	pinned     bool // holds a pinned message; never dropped
+	orphan     bool // tool results without their call, or calls without their results; never sent
+}
+
+// fit returns the messages that fit in budget tokens: every pinned unit,
+// then the most recent units that fit alongside them. When the messages do
+// not all fit, tool results over the tool result limit are truncated first.
+// Orphaned tool results are always left out.
+//
+// Returns an error if the pinned messages alone exceed budget or if the
+// tokenizer returns an error.
+func (f *fitter) fit(messages []domain.Message, budget int) ([]domain.Message, error) {
+	counts, _, err := f.count(messages)
+	if err != nil {
+		return nil, err
+	}
+	units := group(messages, counts)
+	if sendable(units) <= budget {
+		return keep(messages, units, nil), nil
+	}
+
+	limit := f.toolResultLimit
+	if limit <= 0 {
+		limit = max(1, budget/4)
+	}
+	if messages, err = f.truncateToolResults(messages, limit); err != nil {
+		return nil, err
+	}
+	if counts, _, err = f.count(messages); err != nil {
+		return nil, err
+	}
+	units = group(messages, counts)
+
+	pinned := 0
+	for _, u := range units {
+		if u.pinned && !u.orphan {
+			pinned += u.tokens
+		}
+	}
+	if pinned > budget {
+		return nil, fmt.Errorf("context: pinned messages (%d tokens) exceed limit (%d tokens)", pinned, budget)
+	}
+	kept := make([]bool, len(units))
+	avail, full := budget-pinned, false
+	for i := len(units) - 1; i >= 0; i-- {
+		u := units[i]
+		switch {
+		case u.orphan:
+		case u.pinned:
+			kept[i] = true
+		case !full && u.tokens <= avail:
+			kept[i] = true
+			avail -= u.tokens
+		default:
+			// Keep the recent messages contiguous: once one does not fit,
+			// only pinned messages are kept before it.
+			full = true
+		}
+	}
+	return keep(messages, units, kept), nil
+}
+
+// count returns the tokens of each message and their total.
+func (f *fitter) count(messages []domain.Message) ([]int, int, error) {
+	counts := make([]int, len(messages))
+	total := 0
+	for i, msg := range messages {
+		n, err := f.messageTokens(msg)
+		if err != nil {
+			return nil, 0, fmt.Errorf("context: counting tokens for message %d: %w", i, err)
+		}
+		counts[i] = n
+		total += n
+	}
+	return counts, total, nil
+}
+
+// messageTokens counts msg block by block: text and reasoning, tool names
+// and inputs, tool results, and an estimate per image.
+func (f *fitter) messageTokens(msg domain.Message) (int, error) {
+	blocks := msg.Blocks()
+	if blocks == nil {
+		return f.tokenizer.CountTokens(rawContentToText(msg.RawContent))
+	}
+	total := 0
+	for _, block := range blocks {
+		var text string
+		switch b := block.(type) {
+		case domain.TextBlock:
+			text = b.Text
+		case domain.ToolUseBlock:
+			text = b.Name + " " + string(b.Input)
+		case domain.ToolResultBlock:
+			text = b.Content
+		case domain.ThinkingBlock:
+			text = b.Thinking
+		case domain.ImageBlock:
+			total += ImageTokens(f.provider, b)
+			continue
+		}
+		if text == "" {
+			continue
+		}
+		n, err := f.tokenizer.CountTokens(text)
+		if err != nil {
+			return 0, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// truncateToolResults returns messages with every tool result over limit
+// tokens cut down to about limit, ending in a marker saying how much was
+// cut. The messages passed in are not modified.
+func (f *fitter) truncateToolResults(messages []domain.Message, limit int) ([]domain.Message, error) {
+	out, copied := messages, false
+	for i, msg := range messages {
+		blocks := msg.Blocks()
+		var changed []domain.ContentBlock
+		for j, block := range blocks {
+			tr, ok := block.(domain.ToolResultBlock)
+			if !ok {
+				continue
+			}
+			n, err := f.tokenizer.CountTokens(tr.Content)
+			if err != nil {
+				return nil, fmt.Errorf("context: counting tokens for message %d: %w", i, err)
+			}
+			if n <= limit {
+				continue
+			}
+			cut, cutTokens, err := f.truncate(tr.Content, n, limit)
+			if err != nil {
+				return nil, fmt.Errorf("context: counting tokens for message %d: %w", i, err)
+			}
+			if cutTokens >= n {
+				continue // too short for the marker to save anything
+			}
+			tr.Content = cut
+			if changed == nil {
+				changed = append([]domain.ContentBlock(nil), blocks...)
+			}
+			changed[j] = tr
+		}
+		if changed == nil {
+			continue
+		}
+		if !copied {
+			out, copied = append([]domain.Message(nil), messages...), true
+		}
+		raw, err := domain.EncodeContentBlocks(changed)
+		if err != nil {
+			return nil, fmt.Errorf("context: encode truncated message %d: %w", i, err)
+		}
+		out[i].ContentBlocks, out[i].RawContent = changed, raw
+	}
+	return out, nil
+}
+
+// truncate cuts content, counted as n tokens, to at most limit tokens
+// including the marker, shrinking in proportion until it fits, and returns
+// it with its tokens. Content cut to nothing may still exceed limit.
+func (f *fitter) truncate(content string, n, limit int) (string, int, error) {
+	keepLen := len(content)
+	for {
+		keepLen = keepLen * limit / (n + 1)
+		for keepLen > 0 && !utf8.RuneStart(content[keepLen]) {
+			keepLen--
+		}
+		cut := content[:keepLen] + fmt.Sprintf("\n[truncated: %d of %d characters omitted]", len(content)-keepLen, len(content))
+		var err error
+		if n, err = f.tokenizer.CountTokens(cut); err != nil {
+			return "", 0, err
+		}
+		if n <= limit || keepLen == 0 {
+			return cut, n, nil
+		}
+	}
+}
+
+// group splits messages into units. A message calling tools is grouped with
+// the messages after it that carry their results; a message of tool results
+// whose calls are not just before it is an orphan, and so is a call, with
+// whatever results it has, when a result is missing.
+func group(messages []domain.Message, counts []int) []unit {
+	var units []unit
+	for i := 0; i < len(messages); {
+		u := unit{start: i, end: i + 1}
+		blocks := messages[i].Blocks()
+		pending := map[string]bool{}
+		results := false
+		for _, b := range blocks {
+			switch b := b.(type) {
+			case domain.ToolUseBlock:
+				pending[b.ToolUseID] = true
+			case domain.ToolResultBlock:
+				results = true
+			}
+		}
+		u.orphan = results && len(pending) == 0
+		for len(pending) > 0 && u.end < len(messages) && answers(messages[u.end], pending) {
+			u.end++
+		}
+		if len(pending) > 0 {
+			u.orphan = true
+		}
+		for j := u.start; j < u.end; j++ {
+			u.tokens += counts[j]
+			u.pinned = u.pinned || messages[j].Pinned
+		}
+		units = append(units, u)
+		i = u.end
+	}
+	return units
+}
+
+// answers reports whether msg carries results of pending calls, and removes
+// those calls from pending.
+func answers(msg domain.Message, pending map[string]bool) bool {
+	found := false
+	for _, b := range msg.Blocks() {
+		if tr, ok := b.(domain.ToolResultBlock); ok && pending[tr.ToolUseID] {
+			delete(pending, tr.ToolUseID)
+			found = true
+		}
+	}
+	return found
+}
+
+// sendable returns the tokens of the units that are not orphans.
+func sendable(units []unit) int {
+	n := 0
+	for _, u := range units {
+		if !u.orphan {
+			n += u.tokens
+		}
+	}
+	return n
+}
+
+// keep returns the messages of the units marked in kept, in order; a nil
+// kept keeps every unit but the orphans. messages itself is returned when
+// nothing is left out.
+func keep(messages []domain.Message, units []unit, kept []bool) []domain.Message {
+	out := make([]domain.Message, 0, len(messages))
+	for i, u := range units {
+		if u.orphan || (kept != nil && !kept[i]) {
+			continue
+		}
+		out = append(out, messages[u.start:u.end]...)
+	}
+	if len(out) == len(messages) {
+		return messages
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package context
+
+import (
+	"bytes"
+	"encoding/base64"
+	"image"
+	_ "image/gif"  // register GIF for image.DecodeConfig
+	_ "image/jpeg" // register JPEG for image.DecodeConfig
+	_ "image/png"  // register PNG for image.DecodeConfig
+	"math"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// DefaultImageTokens is the estimate for an image sent to a provider whose
+// image pricing is unknown (local and Ollama models, replay), or whose size
+// cannot be read.
+const DefaultImageTokens = 1000
+
+// ImageTokens estimates the input tokens img costs when sent to provider
+// ("anthropic", "openai", "gemini", ...; agents.providers entries are named
+// by their type). The estimate follows the provider's published sizing rules
+// when img's dimensions can be read (PNG, JPEG, GIF), and its cost of a
+// typical photo otherwise.
+func ImageTokens(provider string, img domain.ImageBlock) int {
+	w, h, ok := imageSize(img)
+	switch strings.ToLower(provider) {
+	case "anthropic":
+		if !ok {
+			return 1600
+		}
+		return anthropicImageTokens(w, h)
+	case "openai", "openrouter", domain.ProviderTypeOpenAICompatible:
+		if !ok {
+			return 765
+		}
+		return openAIImageTokens(w, h)
+	case "gemini":
+		if !ok {
+			return 258
+		}
+		return geminiImageTokens(w, h)
+	default:
+		return DefaultImageTokens
+	}
+}
+
+// imageSize returns the dimensions of img, if its data can be decoded.
+func imageSize(img domain.ImageBlock) (int, int, bool) {
+	data, err := base64.StdEncoding.DecodeString(img.Source.Data)
+	if err != nil {
+		return 0, 0, false
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 {
+		return 0, 0, false
+	}
+	return cfg.Width, cfg.Height, true
+}
+
+// anthropicImageTokens applies Anthropic's rule: images are scaled to fit
+// 1568 pixels on the long edge and 1.15 megapixels, then cost w*h/750.
+func anthropicImageTokens(w, h int) int {
+	scale := math.Min(1, 1568/float64(max(w, h)))
+	scale = math.Min(scale, math.Sqrt(1.15e6/(float64(w)*float64(h))))
+	fw, fh := float64(w)*scale, float64(h)*scale
+	return max(1, int(math.Ceil(fw*fh/750)))
+}
+
+// openAIImageTokens applies OpenAI's high-detail rule: images are scaled to
+// fit 2048x2048, then to 768 pixels on the short edge, and cost 85 tokens
+// plus 170 per 512-pixel tile.
+func openAIImageTokens(w, h int) int {
+	fw, fh := float64(w), float64(h)
+	if s := 2048 / math.Max(fw, fh); s < 1 {
+		fw, fh = fw*s, fh*s
+	}
+	if s := 768 / math.Min(fw, fh); s < 1 {
+		fw, fh = fw*s, fh*s
+	}
+	tiles := math.Ceil(fw/512) * math.Ceil(fh/512)
+	return 85 + 170*int(tiles)
+}
+
+// geminiImageTokens applies Gemini's rule: images up to 384 pixels on both
+// edges cost 258 tokens; larger ones 258 per 768-pixel tile.
+func geminiImageTokens(w, h int) int {
+	if w <= 384 && h <= 384 {
+		return 258
+	}
+	tiles := math.Ceil(float64(w)/768) * math.Ceil(float64(h)/768)
+	return 258 * int(tiles)
+}
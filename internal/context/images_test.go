@@ -0,0 +1,49 @@
+package context
+
+import (
+	"bytes"
+	"image"
+	"image/png"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+func pngBlock(t *testing.T, w, h int) domain.ImageBlock {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return domain.NewImageBlock("image/png", buf.Bytes())
+}
+
+func TestImageTokens_ShouldFollowEachProvidersSizing(t *testing.T) {
+	tests := []struct {
+		provider string
+		w, h     int
+		want     int
+	}{
+		{"anthropic", 1000, 1000, 1334},
+		{"anthropic", 4000, 3000, 1534},
+		{"openai", 1000, 1000, 765},
+		{"openai-compatible", 300, 200, 255},
+		{"gemini", 300, 200, 258},
+		{"gemini", 1000, 1000, 1032},
+		{"ollama", 1000, 1000, DefaultImageTokens},
+	}
+	for _, tt := range tests {
+		if got := ImageTokens(tt.provider, pngBlock(t, tt.w, tt.h)); got != tt.want {
+			t.Errorf("ImageTokens(%q, %dx%d) = %d, want %d", tt.provider, tt.w, tt.h, got, tt.want)
+		}
+	}
+}
+
+func TestImageTokens_WhenSizeUnreadable_ShouldUseProviderEstimate(t *testing.T) {
+	img := domain.NewImageBlock("image/webp", []byte("RIFF...."))
+	for provider, want := range map[string]int{"anthropic": 1600, "openai": 765, "gemini": 258, "": DefaultImageTokens} {
+		if got := ImageTokens(provider, img); got != want {
+			t.Errorf("ImageTokens(%q) = %d, want %d", provider, got, want)
+		}
+	}
+}
@@ -7,16 +7,19 @@ import (
 )
 
 // Manager implements domain.ContextManager using a sliding-window strategy.
-// It counts tokens for each message and drops the oldest messages first
-// when the total exceeds the configured maximum token budget.
+// It counts tokens for each message, block by block, and drops the oldest
+// messages first when the total exceeds the configured maximum token budget.
+// Tool calls and their results are kept or dropped together, pinned messages
+// are never dropped, and oversized tool results are truncated before whole
+// messages are dropped.
 type Manager struct {
-	tokenizer domain.Tokenizer
+	fitter
 	maxTokens int
 }
 
 // NewManager creates a Manager with the given tokenizer and max token limit.
 // Panics if tokenizer is nil or maxTokens <= 0.
-func NewManager(tokenizer domain.Tokenizer, maxTokens int) *Manager {
+func NewManager(tokenizer domain.Tokenizer, maxTokens int, opts ...FitOption) *Manager {
 	if tokenizer == nil {
 		panic("context: tokenizer must not be nil")
 	}
@@ -24,17 +27,18 @@ func NewManager(tokenizer domain.Tokenizer, maxTokens int) *Manager {
 		panic("context: maxTokens must be > 0")
 	}
 	return &Manager{
-		tokenizer: tokenizer,
+		fitter:    newFitter(tokenizer, opts...),
 		maxTokens: maxTokens,
 	}
 }
 
 // FitToWindow applies a sliding-window strategy: it reserves tokens for the
 // system prompt, then walks messages from newest to oldest, keeping as many
-// recent messages as fit within the remaining budget.
+// recent messages as fit within the remaining budget, along with every
+// pinned message.
 //
-// Returns an error if the system prompt alone exceeds maxTokens or if the
-// tokenizer returns an error.
+// Returns an error if the system prompt alone, or with the pinned messages,
+// exceeds maxTokens or if the tokenizer returns an error.
 func (m *Manager) FitToWindow(messages []domain.Message, systemPrompt string) ([]domain.Message, error) {
 	if len(messages) == 0 {
 		return []domain.Message{}, nil
@@ -49,32 +53,7 @@ func (m *Manager) FitToWindow(messages []domain.Message, systemPrompt string) ([
 		return nil, fmt.Errorf("context: system prompt (%d tokens) exceeds limit (%d tokens)", sysTokens, m.maxTokens)
 	}
 
-	budget := m.maxTokens - sysTokens
-
-	// Count tokens for each message.
-	tokenCounts := make([]int, len(messages))
-	for i, msg := range messages {
-		text := MessageText(msg)
-		count, err := m.tokenizer.CountTokens(text)
-		if err != nil {
-			return nil, fmt.Errorf("context: counting tokens for message %d: %w", i, err)
-		}
-		tokenCounts[i] = count
-	}
-
-	// Walk from the end (most recent) backwards, accumulating messages that fit.
-	total := 0
-	startIdx := len(messages) // will be decremented
-	for i := len(messages) - 1; i >= 0; i-- {
-		if total+tokenCounts[i] > budget {
-			break
-		}
-		total += tokenCounts[i]
-		startIdx = i
-	}
-
-	// Return the kept slice (preserves original order).
-	return messages[startIdx:], nil
+	return m.fit(messages, m.maxTokens-sysTokens)
 }
 
 // countPromptTokens counts tokens for a system prompt. Empty prompt = 0 tokens.
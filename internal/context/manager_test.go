@@ -3,6 +3,7 @@ package context
 import (
 	"encoding/json"
 	"errors"
+	"strings"
 	"testing"
 
 	"ironclaw/internal/domain"
@@ -348,6 +349,148 @@ func TestFitToWindow_WhenTokenizerFailsOnMessage_ShouldReturnError(t *testing.T)
 	}
 }
 
+// =============================================================================
+// Block-aware fitting
+// =============================================================================
+
+// toolCall returns an assistant message calling a tool and the tool message
+// carrying its result.
+func toolCall(id, input, result string) (domain.Message, domain.Message) {
+	return domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: id, Name: "search", Input: []byte(input)}),
+		domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: id, Content: result})
+}
+
+func TestFitToWindow_WhenBlocksOnlyInRawContent_ShouldCountEachBlock(t *testing.T) {
+	mgr := NewManager(newWordCountTokenizer(), 2000, WithImageProvider("ollama"))
+	img := domain.NewMessage(domain.RoleUser, domain.NewImageBlock("image/png", []byte("not really a png")), domain.TextBlock{Text: "what is it?"})
+	img.ContentBlocks = nil // as loaded without parsing
+
+	n, err := mgr.messageTokens(img)
+	if err != nil {
+		t.Fatalf("messageTokens: %v", err)
+	}
+	if n != DefaultImageTokens+3 {
+		t.Errorf("tokens = %d, want the image estimate plus 3 words", n)
+	}
+}
+
+func TestFitToWindow_WhenCutWouldSplitToolPair_ShouldDropCallAndResultTogether(t *testing.T) {
+	call, result := toolCall("t1", `{"q": "weather in lisbon"}`, "sunny and warm")
+	msgs := []domain.Message{
+		textMsg(domain.RoleUser, "how is the weather"),
+		call,   // 5 tokens
+		result, // 3 tokens
+		textMsg(domain.RoleAssistant, "it is sunny"),
+	}
+	mgr := NewManager(newWordCountTokenizer(), 8)
+
+	got, err := mgr.FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 1 || MessageText(got[0]) != "it is sunny" {
+		t.Errorf("got %d messages, want only the final reply without the orphaned result", len(got))
+	}
+
+	got, err = NewManager(newWordCountTokenizer(), 11).FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 3 || got[0].Role != domain.RoleAssistant || got[1].Role != domain.RoleTool {
+		t.Errorf("got %d messages, want the call, its result and the reply", len(got))
+	}
+}
+
+func TestFitToWindow_WhenHistoryStartsWithOrphanedResult_ShouldLeaveItOut(t *testing.T) {
+	_, result := toolCall("gone", `{}`, "stale result")
+	msgs := []domain.Message{result, textMsg(domain.RoleUser, "hi")}
+
+	got, err := NewManager(newWordCountTokenizer(), 100).FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 1 || got[0].Role != domain.RoleUser {
+		t.Errorf("got %d messages, want the orphaned result left out", len(got))
+	}
+}
+
+func TestFitToWindow_WhenToolCallHasNoResult_ShouldLeaveItOut(t *testing.T) {
+	call, _ := toolCall("lost", `{"q": "x"}`, "")
+	both := domain.NewMessage(domain.RoleAssistant,
+		domain.ToolUseBlock{ToolUseID: "a", Name: "search", Input: []byte(`{}`)},
+		domain.ToolUseBlock{ToolUseID: "b", Name: "search", Input: []byte(`{}`)})
+	onlyA := domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: "a", Content: "half"})
+	msgs := []domain.Message{
+		textMsg(domain.RoleUser, "first"),
+		call,
+		textMsg(domain.RoleUser, "second"),
+		both,
+		onlyA,
+		textMsg(domain.RoleUser, "third"),
+	}
+
+	got, err := NewManager(newWordCountTokenizer(), 100).FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("got %d messages, want only the 3 user messages", len(got))
+	}
+	for _, m := range got {
+		if m.Role != domain.RoleUser {
+			t.Errorf("unanswered call or its partial results sent: %+v", m)
+		}
+	}
+}
+
+func TestFitToWindow_WhenMessagePinned_ShouldNeverDropIt(t *testing.T) {
+	pinned := textMsg(domain.RoleUser, "always answer in french")
+	pinned.Pinned = true
+	msgs := []domain.Message{
+		pinned, // 4 tokens
+		textMsg(domain.RoleAssistant, "one two three four five"),
+		textMsg(domain.RoleUser, "six seven"),
+	}
+
+	got, err := NewManager(newWordCountTokenizer(), 8).FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 2 || !got[0].Pinned || MessageText(got[1]) != "six seven" {
+		t.Errorf("got %d messages, want the pinned message and the latest", len(got))
+	}
+
+	if _, err := NewManager(newWordCountTokenizer(), 3).FitToWindow(msgs, ""); err == nil {
+		t.Error("expected error when pinned messages exceed the limit")
+	}
+}
+
+func TestFitToWindow_WhenToolResultHuge_ShouldTruncateItBeforeDroppingTurns(t *testing.T) {
+	huge := strings.TrimSpace(strings.Repeat("line of output ", 100)) // 300 tokens
+	call, result := toolCall("t1", `{}`, huge)
+	msgs := []domain.Message{textMsg(domain.RoleUser, "read the log"), call, result, textMsg(domain.RoleAssistant, "done")}
+	mgr := NewManager(newWordCountTokenizer(), 60, WithToolResultLimit(20))
+
+	got, err := mgr.FitToWindow(msgs, "")
+	if err != nil {
+		t.Fatalf("FitToWindow: %v", err)
+	}
+	if len(got) != 4 {
+		t.Fatalf("got %d messages, want all 4 with the result truncated", len(got))
+	}
+	tr := got[2].Blocks()[0].(domain.ToolResultBlock)
+	if n, _ := newWordCountTokenizer().CountTokens(tr.Content); n > 20 || !strings.Contains(tr.Content, "[truncated:") || tr.ToolUseID != "t1" {
+		t.Errorf("truncated result (%d tokens) = %q", n, tr.Content)
+	}
+	var raw domain.Message
+	if err := raw.UnmarshalJSON([]byte(`{"content":` + string(got[2].RawContent) + `}`)); err != nil || raw.Blocks()[0].(domain.ToolResultBlock).Content != tr.Content {
+		t.Errorf("RawContent not updated with the truncated result: %v", err)
+	}
+	if msgs[2].Blocks()[0].(domain.ToolResultBlock).Content != huge {
+		t.Error("caller's message was modified")
+	}
+}
+
 // =============================================================================
 // Interface compliance
 // =============================================================================
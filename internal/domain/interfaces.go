@@ -1,6 +1,9 @@
 package domain
 
-import "context"
+import (
+	"context"
+	"encoding/json"
+)
 
 // LLMProvider is the model-agnostic interface for text generation.
 // Implementations may be OpenAI, Anthropic, local models, or mocks.
@@ -8,6 +11,84 @@ type LLMProvider interface {
 	Generate(ctx context.Context, prompt string) (string, error)
 }
 
+// ChatProvider is implemented by providers that accept a structured
+// conversation (messages with real roles, a system prompt and tool
+// definitions) and return content blocks instead of a flat string.
+// Decorators that wrap a provider without native chat support return
+// ErrChatNotSupported so callers can fall back to Generate.
+type ChatProvider interface {
+	Chat(ctx context.Context, req ChatRequest) (ChatResponse, error)
+}
+
+// StreamingProvider is implemented by providers that can stream the assistant
+// reply as it is generated. onDelta receives text fragments in order; the
+// returned ChatResponse holds the complete reply, including tool calls.
+// Canceling ctx aborts the stream. Decorators whose wrapped provider cannot
+// stream return ErrStreamNotSupported so callers can fall back to Chat.
+type StreamingProvider interface {
+	ChatStream(ctx context.Context, req ChatRequest, onDelta func(delta string)) (ChatResponse, error)
+}
+
+type modelKey struct{}
+
+// WithModel returns a context whose provider calls use the named model,
+// alias or profile (see AgentsConfig.ResolveProfile) instead of the brain's
+// default model. An empty name returns ctx unchanged.
+func WithModel(ctx context.Context, name string) context.Context {
+	if name == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, modelKey{}, name)
+}
+
+// ModelFrom returns the name stored by WithModel, or "".
+func ModelFrom(ctx context.Context) string {
+	name, _ := ctx.Value(modelKey{}).(string)
+	return name
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context whose provider calls bypass the response
+// cache: they always reach the provider, and their responses replace the
+// cached ones.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// CacheBypassed reports whether ctx was returned by WithoutCache.
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(noCacheKey{}).(bool)
+	return bypass
+}
+
+// ToolEvent reports a tool call an agent loop starts or finishes, so the
+// transport the message came in on can show the user what is happening.
+type ToolEvent struct {
+	Tool     string
+	Input    json.RawMessage
+	Finished bool   // False when the call starts
+	Result   string // Content of the call's result; set when Finished
+	IsError  bool   // The call failed or was denied or refused
+}
+
+type toolEventsKey struct{}
+
+// WithToolEvents returns a context whose agent loops pass each tool call
+// they start and finish to fn. A nil fn returns ctx unchanged.
+func WithToolEvents(ctx context.Context, fn func(ToolEvent)) context.Context {
+	if fn == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, toolEventsKey{}, fn)
+}
+
+// ToolEventsFrom returns the func stored by WithToolEvents, or nil.
+func ToolEventsFrom(ctx context.Context) func(ToolEvent) {
+	fn, _ := ctx.Value(toolEventsKey{}).(func(ToolEvent))
+	return fn
+}
+
 // SessionHistoryStore persists session messages to a JSONL file and supports
 // loading the last N messages to restore context on restart.
 type SessionHistoryStore interface {
@@ -33,6 +114,47 @@ type ContextManager interface {
 	FitToWindow(messages []Message, systemPrompt string) ([]Message, error)
 }
 
+// ContextCompactor is a ContextManager that calls a model to fit messages,
+// summarising those that do not fit rather than dropping them. The brain
+// uses FitContext in place of FitToWindow when its ContextManager implements
+// it. When ctx carries a SummaryStore (see WithSummaryStore), the summary is
+// kept there between turns.
+type ContextCompactor interface {
+	ContextManager
+	// FitContext is FitToWindow with the summarising calls made under ctx.
+	FitContext(ctx context.Context, messages []Message, systemPrompt string) ([]Message, error)
+	// Compact summarises every message not yet summarised into the summary
+	// in ctx's SummaryStore, regardless of the window, and returns how many
+	// messages it summarised.
+	Compact(ctx context.Context, messages []Message) (int, error)
+}
+
+// SummaryStore persists the rolling summary of one conversation.
+type SummaryStore interface {
+	// LoadSummary returns the stored summary, or a zero ContextSummary when
+	// there is none.
+	LoadSummary() (ContextSummary, error)
+	// SaveSummary replaces the stored summary.
+	SaveSummary(ContextSummary) error
+}
+
+type summaryStoreKey struct{}
+
+// WithSummaryStore returns a context whose context fitting keeps the summary
+// of the conversation in s. A nil s returns ctx unchanged.
+func WithSummaryStore(ctx context.Context, s SummaryStore) context.Context {
+	if s == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, summaryStoreKey{}, s)
+}
+
+// SummaryStoreFrom returns the store set by WithSummaryStore, or nil.
+func SummaryStoreFrom(ctx context.Context) SummaryStore {
+	s, _ := ctx.Value(summaryStoreKey{}).(SummaryStore)
+	return s
+}
+
 // HistorySyncer watches a history JSONL file for external changes (e.g. from
 // Syncthing, Dropbox, or another device) and delivers newly appended messages
 // via a callback so they can be merged into runtime memory.
@@ -52,6 +174,16 @@ type Embedder interface {
 	Embed(ctx context.Context, text string) ([]float64, error)
 }
 
+// Retriever finds the stored memories relevant to a query and stores new
+// ones so they can be found.
+type Retriever interface {
+	// Retrieve returns the memories relevant to query, most relevant first.
+	Retrieve(ctx context.Context, query string) ([]SemanticMemory, error)
+
+	// Store indexes content so later queries can retrieve it.
+	Store(ctx context.Context, content string) error
+}
+
 // SubAgentRunner runs a specialist sub-agent in isolation with a custom system
 // prompt (role) and a task. Implementations create a secondary LLM loop that
 // does not share the parent's memory, history, or context.
@@ -60,3 +192,10 @@ type SubAgentRunner interface {
 	// the LLM response. The sub-agent runs in isolation from the parent.
 	RunSubAgent(ctx context.Context, systemPrompt string, task string) (string, error)
 }
+
+// Rememberer stores facts in an agent's long-term memory, where later turns
+// load or retrieve them.
+type Rememberer interface {
+	// Remember stores content in long-term memory.
+	Remember(ctx context.Context, content string) error
+}
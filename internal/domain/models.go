@@ -1,7 +1,10 @@
 package domain
 
 import (
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"strings"
 	"time"
 )
 
@@ -10,15 +13,34 @@ import (
 // =============================================================================
 
 type Config struct {
-	Gateway         GatewayConfig `json:"gateway"`
-	Agents          AgentsConfig  `json:"agents"`
-	Infra           InfraConfig   `json:"infra"`
-	Retry           RetryConfig   `json:"retry"`
-	AllowedCommands []string      `json:"allowedCommands"` // If non-empty, only these command binaries may be executed
-	Mode            string        `json:"mode,omitempty"`  // Setup mode: "local", "server", "remote"
-	RemoteURL       string        `json:"remoteUrl,omitempty"`
-	RemoteToken     string        `json:"remoteToken,omitempty"`
-	Channels        []string      `json:"channels,omitempty"` // Enabled channels (e.g., telegram, discord)
+	Gateway         GatewayConfig   `json:"gateway"`
+	Agents          AgentsConfig    `json:"agents"`
+	Infra           InfraConfig     `json:"infra"`
+	Retry           RetryConfig     `json:"retry"`
+	CircuitBreaker  BreakerConfig   `json:"circuitBreaker"`
+	Scheduler       SchedulerConfig `json:"scheduler"`
+	Tools           ToolsConfig     `json:"tools"`
+	Approval        ApprovalConfig  `json:"approval"`
+	Usage           UsageConfig     `json:"usage"`
+	AllowedCommands []string        `json:"allowedCommands"` // If non-empty, only these command binaries may be executed
+	Mode            string          `json:"mode,omitempty"`  // Setup mode: "local", "server", "remote"
+	RemoteURL       string          `json:"remoteUrl,omitempty"`
+	RemoteToken     string          `json:"remoteToken,omitempty"`
+	Channels        []string        `json:"channels,omitempty"` // Enabled channels (e.g., telegram, discord)
+
+	// RateLimits caps the load on each provider, keyed by provider name
+	// ("openai", "anthropic", a Providers name).
+	RateLimits map[string]RateLimitConfig `json:"rateLimits,omitempty"`
+
+	// Cache answers repeated LLM requests from stored responses.
+	Cache CacheConfig `json:"cache"`
+
+	// Tokenizer says where the BPE files used to count tokens are found.
+	Tokenizer TokenizerConfig `json:"tokenizer"`
+
+	// Retrieval adds the stored memories relevant to each message to the
+	// prompt.
+	Retrieval RetrievalConfig `json:"retrieval"`
 }
 
 // RetryConfig controls retry behaviour for external API calls (LLM, webhooks).
@@ -29,6 +51,147 @@ type RetryConfig struct {
 	Multiplier     int `json:"multiplier"`     // Backoff multiplier (e.g. 2 for exponential doubling)
 }
 
+// BreakerConfig controls the per-provider circuit breakers that let failover
+// skip a provider that keeps failing.
+type BreakerConfig struct {
+	FailureThreshold int `json:"failureThreshold"` // Consecutive failures that open a provider's circuit (0 = 5)
+	ProbeInterval    int `json:"probeInterval"`    // Milliseconds an open circuit waits before letting one probe request through (0 = 30000)
+}
+
+// RateLimitConfig caps the load put on a provider so that concurrent channels
+// and jobs queue for it instead of triggering rate-limit errors. Zero fields
+// are unlimited.
+type RateLimitConfig struct {
+	MaxConcurrent     int              `json:"maxConcurrent,omitempty"`     // Requests in flight at once
+	RequestsPerMinute int              `json:"requestsPerMinute,omitempty"` // Requests started in any minute
+	TokensPerMinute   int              `json:"tokensPerMinute,omitempty"`   // Input plus output tokens used in any minute
+	PerKey            *RateLimitConfig `json:"perKey,omitempty"`            // Limits for each of the provider's API keys; its own PerKey is ignored
+}
+
+// CacheConfig controls the LLM response cache. Responses are keyed by
+// provider, model and the normalised request; with Semantic set, requests
+// whose text embeds close enough to a cached request's are answered too.
+type CacheConfig struct {
+	Enabled  bool                 `json:"enabled,omitempty"`
+	DBURL    string               `json:"dbUrl,omitempty"`    // libSQL URL (e.g. "file:cache.db"); defaults to <memory>/cache.db
+	TTL      string               `json:"ttl,omitempty"`      // Go duration a response is served for (default "24h")
+	Semantic *SemanticCacheConfig `json:"semantic,omitempty"` // Nil matches exact requests only
+}
+
+// TokenizerConfig locates the tiktoken BPE rank files (cl100k_base.tiktoken,
+// o200k_base.tiktoken) that token counting needs. Files embedded in the
+// binary are used first, then BPEDir, then a download.
+type TokenizerConfig struct {
+	BPEDir  string `json:"bpeDir,omitempty"`  // Directory holding the .tiktoken files; defaults to <memory>/tiktoken
+	Offline bool   `json:"offline,omitempty"` // Never download BPE files; models whose files are missing are estimated
+}
+
+// RetrievalConfig controls retrieval-augmented prompts: memories are stored
+// with their embeddings, and those relevant to each incoming message are
+// added to the system prompt with citations.
+type RetrievalConfig struct {
+	Enabled  bool    `json:"enabled,omitempty"`
+	DBURL    string  `json:"dbUrl,omitempty"`    // libSQL URL (e.g. "file:memories.db"); defaults to <memory>/memories.db
+	Model    string  `json:"model,omitempty"`    // Ollama embedding model (default "nomic-embed-text")
+	TopK     int     `json:"topK,omitempty"`     // Memories retrieved per message (default 5)
+	MinScore float64 `json:"minScore,omitempty"` // Cosine similarity a memory needs unless it matches the message's keywords (default 0.5)
+	Budget   int     `json:"budget,omitempty"`   // Tokens the retrieved memories may take in the prompt (default 1024)
+
+	// Index, when set, searches memories through an approximate nearest
+	// neighbour index instead of comparing the query with every embedding.
+	Index *VectorIndexConfig `json:"index,omitempty"`
+}
+
+// VectorIndexConfig tunes the HNSW index of the memory store. Larger values
+// trade memory and speed for recall; zero fields keep their defaults.
+type VectorIndexConfig struct {
+	Path           string `json:"path,omitempty"`           // Index file; defaults to the database file plus ".hnsw", or memory only for remote databases
+	M              int    `json:"m,omitempty"`              // Links per node and layer (default 16; twice that on the bottom layer)
+	EfConstruction int    `json:"efConstruction,omitempty"` // Candidates considered when inserting (default 200)
+	EfSearch       int    `json:"efSearch,omitempty"`       // Candidates considered when searching (default 64)
+}
+
+// SemanticCacheConfig enables similarity matching in the response cache.
+type SemanticCacheConfig struct {
+	Model     string  `json:"model,omitempty"`     // Ollama embedding model (default "nomic-embed-text")
+	Threshold float64 `json:"threshold,omitempty"` // Cosine similarity a cached request needs to match (default 0.95)
+}
+
+// SchedulerConfig declares cron jobs and where jobs and their run history are stored.
+type SchedulerConfig struct {
+	DBURL string      `json:"dbUrl,omitempty"` // libSQL URL (e.g. "file:jobs.db"); defaults to <memory>/scheduler.db
+	Jobs  []JobConfig `json:"jobs,omitempty"`  // Registered on startup unless a job with the same ID is already stored
+}
+
+// JobConfig is a scheduled job declared in config. Exactly one of Cron and At
+// must be set.
+type JobConfig struct {
+	ID      string `json:"id"`
+	Name    string `json:"name,omitempty"`
+	Cron    string `json:"cron,omitempty"`    // Cron expression or descriptor (e.g. "@every 1h")
+	At      string `json:"at,omitempty"`      // One-shot RFC 3339 time (e.g. "2026-03-01T09:00:00Z")
+	Jitter  string `json:"jitter,omitempty"`  // Max random delay per firing, as a Go duration (e.g. "5m")
+	Overlap string `json:"overlap,omitempty"` // "skip" (default), "queue" or "replace" when still running
+	Prompt  string `json:"prompt"`            // Injected into the brain as a system event
+	Model   string `json:"model,omitempty"`   // Model, alias or profile to answer with (e.g. "fast"); empty uses the default
+	Deliver string `json:"deliver,omitempty"` // Delivery target "kind:to" (e.g. "telegram:12345", "memory")
+	Paused  bool   `json:"paused,omitempty"`
+}
+
+// ToolsConfig restricts which tools the model may see and call per channel.
+// Rules stack with the bound agent's TOOLS.md: a tool must pass every layer.
+type ToolsConfig struct {
+	// Channels maps a channel ID or glob (e.g. "telegram-*") to its rule; the
+	// exact ID wins over globs, and the longest matching glob over shorter ones.
+	Channels map[string]ToolRule `json:"channels,omitempty"`
+	// External applies to channels of the platforms in
+	// gateway.auth.externalChannels (e.g. "whatsapp" covers "whatsapp-<jid>").
+	// When unset, a default rule denies tools that touch the host.
+	External *ToolRule `json:"external,omitempty"`
+}
+
+// ApprovalConfig selects the tool calls that wait for a human to approve them
+// in the originating channel.
+type ApprovalConfig struct {
+	// Require lists tool names or "tool.operation" globs (e.g. "shell",
+	// "git.push", "filesystem.write_file"). Nil uses the built-in defaults;
+	// an empty list disables approvals.
+	Require  []string `json:"require"`
+	Timeout  string   `json:"timeout,omitempty"`  // Go duration; unanswered requests are denied after it (default 5m)
+	AuditLog string   `json:"auditLog,omitempty"` // JSONL audit file; defaults to <memory>/approvals.jsonl
+}
+
+// UsageConfig controls token usage accounting: where usage is stored, how it
+// is priced and the spend budgets of channels and agents.
+type UsageConfig struct {
+	DBURL   string                `json:"dbUrl,omitempty"`   // libSQL URL (e.g. "file:usage.db"); defaults to <memory>/usage.db
+	Prices  map[string]ModelPrice `json:"prices,omitempty"`  // Keyed by model name or glob (e.g. "claude-3-5-*"); unpriced models cost 0
+	Budgets []BudgetConfig        `json:"budgets,omitempty"` // Checked before every provider call
+}
+
+// ModelPrice is what a model costs, in USD per million tokens.
+type ModelPrice struct {
+	Input  float64 `json:"input"`
+	Output float64 `json:"output"`
+}
+
+// BudgetConfig caps the spend of each channel or agent matching a glob.
+// Exactly one of Channel and Agent must be set.
+type BudgetConfig struct {
+	Channel string  `json:"channel,omitempty"` // Channel ID glob (e.g. "telegram-*"); each matching channel has its own cap
+	Agent   string  `json:"agent,omitempty"`   // Agent name glob
+	Daily   float64 `json:"daily,omitempty"`   // USD per calendar day; 0 means no daily cap
+	Monthly float64 `json:"monthly,omitempty"` // USD per calendar month; 0 means no monthly cap
+	WarnAt  float64 `json:"warnAt,omitempty"`  // Fraction of a cap that triggers a warning (default 0.8)
+}
+
+// ToolRule is an allow/deny list of tool name globs (e.g. "git.*", "shell").
+// Deny wins; an empty Allow allows everything not denied.
+type ToolRule struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
 type GatewayConfig struct {
 	Port         int        `json:"port"`
 	Auth         AuthConfig `json:"auth"`
@@ -48,18 +211,141 @@ type AgentsConfig struct {
 	Provider     string            `json:"provider"` // "openai" | "anthropic" | "local"
 	DefaultModel string            `json:"defaultModel"`
 	ModelAliases map[string]string `json:"modelAliases"`
-	Paths        AgentPaths        `json:"paths"`
-	Fallbacks    []FallbackConfig  `json:"fallbacks,omitempty"` // optional failover providers
+	// Models maps a profile name (e.g. "fast", "smart") to the provider,
+	// model and generation parameters it stands for. Profile names may be
+	// used wherever a model is named, and as ModelAliases targets.
+	Models map[string]ModelProfile `json:"models,omitempty"`
+	// Providers declares named endpoints (e.g. a LAN vLLM server). A name may
+	// be used wherever a provider is named: Provider, a profile's Provider or
+	// a fallback's Provider.
+	Providers map[string]ProviderConfig `json:"providers,omitempty"`
+	Paths     AgentPaths                `json:"paths"`
+	Fallbacks []FallbackConfig          `json:"fallbacks,omitempty"` // optional failover providers
+
+	// Named agents live in <paths.root>/<name>/. Bindings maps a channel ID or
+	// glob (e.g. "telegram-123", "telegram-*") to an agent name; channels
+	// without a binding use DefaultAgent, or the workspace root persona when
+	// that is empty.
+	Bindings     map[string]string `json:"bindings,omitempty"`
+	DefaultAgent string            `json:"defaultAgent,omitempty"`
+
+	// Replay configures the "replay" provider, for offline runs and tests.
+	Replay *ReplayConfig `json:"replay,omitempty"`
+
+	// Compaction summarises chat history that outgrows the context window
+	// instead of dropping it. Nil drops the oldest messages.
+	Compaction *CompactionConfig `json:"compaction,omitempty"`
+}
+
+// ResolveModel maps a requested model name to a concrete model: "" yields
+// DefaultModel, a key of ModelAliases yields its target, anything else is
+// returned unchanged.
+func (a AgentsConfig) ResolveModel(name string) string {
+	if name == "" {
+		return a.DefaultModel
+	}
+	if target, ok := a.ModelAliases[name]; ok && target != "" {
+		return target
+	}
+	return name
+}
+
+// ResolveProfile returns the profile a requested model name stands for: the
+// name is resolved with ResolveModel, then looked up in Models. A name that
+// is not a profile yields a profile calling that model with default
+// parameters. Empty Provider and Model fields are filled in from Provider
+// and the resolved name.
+func (a AgentsConfig) ResolveProfile(name string) ModelProfile {
+	name = a.ResolveModel(name)
+	p := a.Models[name]
+	if p.Model == "" {
+		p.Model = name
+	}
+	if p.Provider == "" {
+		p.Provider = a.Provider
+	}
+	return p
+}
+
+// HasModel reports whether name is configured: empty (the default model),
+// DefaultModel, a ModelAliases key or target, or a Models key.
+func (a AgentsConfig) HasModel(name string) bool {
+	if name == "" || name == a.DefaultModel {
+		return true
+	}
+	if _, ok := a.Models[name]; ok {
+		return true
+	}
+	for alias, target := range a.ModelAliases {
+		if name == alias || name == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ModelProfile is a named model configuration: the provider and model to
+// call and how to sample from them. Zero fields use the provider's defaults.
+type ModelProfile struct {
+	Provider       string   `json:"provider,omitempty"`       // Empty uses agents.provider
+	Model          string   `json:"model,omitempty"`          // Empty uses the profile name
+	MaxTokens      int      `json:"maxTokens,omitempty"`      // Max output tokens per call
+	Temperature    *float64 `json:"temperature,omitempty"`    // Sampling temperature
+	TopP           *float64 `json:"topP,omitempty"`           // Nucleus sampling probability mass
+	Stop           []string `json:"stop,omitempty"`           // Stop sequences
+	ThinkingBudget int      `json:"thinkingBudget,omitempty"` // Reasoning tokens per call (Anthropic extended thinking, Gemini thinking); 0 disables
+	ContextWindow  int      `json:"contextWindow,omitempty"`  // Tokens of chat history replayed to the model; 0 uses the daemon default
+}
+
+// CompactionConfig controls summarising context compaction. Budgets are
+// fractions of the tokens left for history once the system prompt and
+// ReserveOutput are taken from the context window.
+type CompactionConfig struct {
+	Threshold     float64 `json:"threshold,omitempty"`     // Share of the budget history may fill before it is compacted (default 0.8)
+	Target        float64 `json:"target,omitempty"`        // Share of the budget the recent messages kept verbatim may fill (default 0.5)
+	ReserveOutput int     `json:"reserveOutput,omitempty"` // Tokens left free for the reply (default 1024)
+	Model         string  `json:"model,omitempty"`         // Model or profile that writes summaries; empty uses the default model
 }
 
 // FallbackConfig describes an alternative LLM provider for failover.
 type FallbackConfig struct {
-	Provider     string `json:"provider"` // "openai" | "anthropic" | "local" | "ollama" | "gemini" | "openrouter"
+	Provider     string `json:"provider"` // "openai" | "anthropic" | "local" | "ollama" | "gemini" | "openrouter" | a Providers name
 	DefaultModel string `json:"defaultModel"`
 }
 
+// ProviderTypeOpenAICompatible is the ProviderConfig type of endpoints that
+// speak the OpenAI Chat Completions API (vLLM, LM Studio, llama.cpp server).
+const ProviderTypeOpenAICompatible = "openai-compatible"
+
+// ProviderConfig describes a named provider endpoint. Capabilities the
+// endpoint does not declare are never used: requests needing them fall back
+// to plainer calls.
+type ProviderConfig struct {
+	Type         string            `json:"type"`                   // "openai-compatible"
+	BaseURL      string            `json:"baseUrl"`                // e.g. "http://10.0.0.5:8000/v1"; "/chat/completions" is appended unless present
+	Headers      map[string]string `json:"headers,omitempty"`      // Extra headers sent with every request
+	APIKeySecret string            `json:"apiKeySecret,omitempty"` // Secret holding the API key (comma-separated for a key pool); no key is sent when empty
+	Tools        bool              `json:"tools,omitempty"`        // Endpoint supports tool calling
+	Streaming    bool              `json:"streaming,omitempty"`    // Endpoint supports streamed responses
+	Vision       bool              `json:"vision,omitempty"`       // Endpoint accepts image input
+}
+
+// Matching modes of ReplayConfig.
+const (
+	ReplayMatchStrict  = "strict"
+	ReplayMatchLenient = "lenient"
+)
+
+// ReplayConfig configures the "replay" provider, which answers from a
+// cassette file of recorded calls instead of calling a model.
+type ReplayConfig struct {
+	Cassette string `json:"cassette"`         // Path of the cassette file
+	Record   string `json:"record,omitempty"` // Provider to call, recording its replies into a new cassette; empty replays
+	Match    string `json:"match,omitempty"`  // "strict" (default) matches the whole request; "lenient" falls back to the last message, then to recording order
+}
+
 type AgentPaths struct {
-	Root   string `json:"root"`   // Path to agent workspace (contains AGENTS.md)
+	Root   string `json:"root"`   // Path to agent workspace (contains AGENTS.md and one directory per named agent)
 	Memory string `json:"memory"` // Path to durable memory logs
 }
 
@@ -106,9 +392,10 @@ type SessionAuthState struct {
 }
 
 type AgentContext struct {
-	Identity string   `json:"identity"` // Content of IDENTITY.md
-	Soul     string   `json:"soul"`     // Content of SOUL.md
-	Tools    []string `json:"tools"`    // List of enabled tools from TOOLS.md
+	Identity   string   `json:"identity"`   // Content of IDENTITY.md
+	Soul       string   `json:"soul"`       // Content of SOUL.md
+	Directives string   `json:"directives"` // Content of AGENTS.md
+	Tools      []string `json:"tools"`      // List of enabled tools from TOOLS.md
 }
 
 // MemoryStore persists agent memory entries. Implementations must append only (no overwrite).
@@ -148,6 +435,10 @@ type Message struct {
 	RawContent json.RawMessage `json:"content"`
 	// Parsed blocks (populated after Unmarshal)
 	ContentBlocks []ContentBlock `json:"-"`
+
+	// Pinned messages are never dropped when history is fitted into the
+	// context window, nor summarised away by compaction.
+	Pinned bool `json:"pinned,omitempty"`
 }
 
 // UnmarshalJSON implements custom unmarshaling for polymorphic content.
@@ -166,6 +457,7 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 	m.ID = a.ID
 	m.Role = a.Role
 	m.Timestamp = a.Timestamp
+	m.Pinned = a.Pinned
 	m.RawContent = a.Content
 	m.ContentBlocks = nil
 
@@ -180,6 +472,90 @@ func (m *Message) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// Blocks returns the message content as ContentBlocks. ContentBlocks is used
+// when populated; otherwise RawContent is parsed. Unparseable content yields nil.
+func (m Message) Blocks() []ContentBlock {
+	if len(m.ContentBlocks) > 0 {
+		return m.ContentBlocks
+	}
+	if len(m.RawContent) == 0 {
+		return nil
+	}
+	blocks, err := parseMessageContent(m.RawContent)
+	if err != nil {
+		return nil
+	}
+	return blocks
+}
+
+// NewMessage builds a Message with both ContentBlocks and RawContent populated
+// so it round-trips through JSON (e.g. the JSONL history). A single text block
+// is encoded as a plain string; anything else as a typed block array.
+func NewMessage(role MessageRole, blocks ...ContentBlock) Message {
+	raw, _ := EncodeContentBlocks(blocks)
+	return Message{
+		Role:          role,
+		Timestamp:     time.Now(),
+		RawContent:    raw,
+		ContentBlocks: blocks,
+	}
+}
+
+// NewTextMessage builds a Message holding a single TextBlock.
+func NewTextMessage(role MessageRole, text string) Message {
+	return NewMessage(role, TextBlock{Text: text})
+}
+
+// EncodeContentBlocks serialises blocks into the content wire format accepted by
+// Message.UnmarshalJSON: a JSON string for a single text block, otherwise an
+// array of objects carrying a "type" discriminator.
+func EncodeContentBlocks(blocks []ContentBlock) (json.RawMessage, error) {
+	if len(blocks) == 1 {
+		if tb, ok := blocks[0].(TextBlock); ok {
+			return json.Marshal(tb.Text)
+		}
+	}
+	out := make([]json.RawMessage, 0, len(blocks))
+	for _, block := range blocks {
+		var v any
+		switch b := block.(type) {
+		case TextBlock:
+			v = struct {
+				Type BlockType `json:"type"`
+				TextBlock
+			}{BlockText, b}
+		case ImageBlock:
+			v = struct {
+				Type BlockType `json:"type"`
+				ImageBlock
+			}{BlockImage, b}
+		case ToolUseBlock:
+			v = struct {
+				Type BlockType `json:"type"`
+				ToolUseBlock
+			}{BlockToolUse, b}
+		case ToolResultBlock:
+			v = struct {
+				Type BlockType `json:"type"`
+				ToolResultBlock
+			}{BlockToolResult, b}
+		case ThinkingBlock:
+			v = struct {
+				Type BlockType `json:"type"`
+				ThinkingBlock
+			}{BlockThinking, b}
+		default:
+			continue
+		}
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, raw)
+	}
+	return json.Marshal(out)
+}
+
 // parseMessageContent decodes content (string or array of blocks) into ContentBlocks.
 // Used by Message.UnmarshalJSON and by tests to cover the array-unmarshal error path.
 func parseMessageContent(content json.RawMessage) ([]ContentBlock, error) {
@@ -220,11 +596,25 @@ func parseMessageContent(content json.RawMessage) ([]ContentBlock, error) {
 			if err := json.Unmarshal(r, &b); err == nil {
 				blocks = append(blocks, b)
 			}
+		case BlockThinking:
+			var b ThinkingBlock
+			if err := json.Unmarshal(r, &b); err == nil {
+				blocks = append(blocks, b)
+			}
 		}
 	}
 	return blocks, nil
 }
 
+// ContextSummary is the rolling summary of a conversation's compacted
+// messages: every message up to and including Through is summarised by Text.
+type ContextSummary struct {
+	Text     string    `json:"text"`
+	Through  time.Time `json:"through"`  // Timestamp of the last summarised message
+	Messages int       `json:"messages"` // Messages summarised so far
+	Updated  time.Time `json:"updated"`
+}
+
 type BlockType string
 
 const (
@@ -232,6 +622,7 @@ const (
 	BlockImage      BlockType = "image"
 	BlockToolUse    BlockType = "tool_use"
 	BlockToolResult BlockType = "tool_result"
+	BlockThinking   BlockType = "thinking"
 )
 
 type ContentBlock interface {
@@ -256,6 +647,11 @@ type MediaType struct {
 
 func (ImageBlock) Type() BlockType { return BlockImage }
 
+// NewImageBlock returns an ImageBlock holding data base64-encoded.
+func NewImageBlock(mediaType string, data []byte) ImageBlock {
+	return ImageBlock{Source: MediaType{Type: "base64", MediaType: mediaType, Data: base64.StdEncoding.EncodeToString(data)}}
+}
+
 type ToolUseBlock struct {
 	ToolUseID string          `json:"id"`
 	Name      string          `json:"name"`
@@ -272,6 +668,105 @@ type ToolResultBlock struct {
 
 func (ToolResultBlock) Type() BlockType { return BlockToolResult }
 
+// ThinkingBlock is the model's reasoning, returned when a thinking budget is
+// set. Providers that require it (Anthropic, during tool use) get it back
+// verbatim in the assistant turn; it is never shown as reply text. Redacted
+// reasoning carries only the opaque Data.
+type ThinkingBlock struct {
+	Thinking  string `json:"thinking,omitempty"`
+	Signature string `json:"signature,omitempty"`
+	Data      string `json:"data,omitempty"` // Redacted reasoning
+}
+
+func (ThinkingBlock) Type() BlockType { return BlockThinking }
+
+// =============================================================================
+// Structured Chat Protocol
+// =============================================================================
+
+// ErrChatNotSupported is returned by ChatProvider decorators whose wrapped
+// provider only implements LLMProvider.Generate.
+var ErrChatNotSupported = errors.New("chat not supported by provider")
+
+// ErrStreamNotSupported is returned by StreamingProvider decorators whose
+// wrapped provider does not implement ChatStream.
+var ErrStreamNotSupported = errors.New("streaming not supported by provider")
+
+// ErrCompactionNotSupported is returned when asked to compact a conversation
+// whose brain has no ContextCompactor.
+var ErrCompactionNotSupported = errors.New("context compaction not enabled")
+
+// ErrUnknownModel is returned by providers asked, via WithModel, for a model
+// that is not configured. Fallback providers would ignore the name, so the
+// brain returns it without failing over.
+var ErrUnknownModel = errors.New("unknown model")
+
+// ChatRequest is a provider-agnostic structured chat call. Messages keep their
+// roles; tool results may be sent as RoleUser or RoleTool messages carrying
+// ToolResultBlocks and each provider maps them to its native wire format.
+type ChatRequest struct {
+	System    string           `json:"system,omitempty"`
+	Messages  []Message        `json:"messages"`
+	Tools     []ToolDefinition `json:"tools,omitempty"`
+	MaxTokens int              `json:"maxTokens,omitempty"` // 0 = provider default
+
+	// Sampling parameters; nil or zero uses the provider default.
+	Temperature    *float64 `json:"temperature,omitempty"`
+	TopP           *float64 `json:"topP,omitempty"`
+	Stop           []string `json:"stop,omitempty"`
+	ThinkingBudget int      `json:"thinkingBudget,omitempty"` // Reasoning tokens; honoured by Anthropic and Gemini
+
+	// ResponseSchema, when set, is a JSON Schema the reply must conform to.
+	// Providers with a native JSON mode (OpenAI-style, Gemini, Ollama) enforce
+	// it; others rely on the prompt asking for it.
+	ResponseSchema json.RawMessage `json:"responseSchema,omitempty"`
+}
+
+// StopReason explains why the model stopped generating.
+type StopReason string
+
+const (
+	StopEndTurn   StopReason = "end_turn"
+	StopToolUse   StopReason = "tool_use"
+	StopMaxTokens StopReason = "max_tokens"
+	StopSequence  StopReason = "stop_sequence"
+)
+
+// Usage reports token consumption for a single provider call.
+type Usage struct {
+	InputTokens  int `json:"inputTokens"`
+	OutputTokens int `json:"outputTokens"`
+}
+
+// ChatResponse is the structured result of a ChatProvider call.
+type ChatResponse struct {
+	Content    []ContentBlock `json:"-"`
+	StopReason StopReason     `json:"stopReason"`
+	Usage      Usage          `json:"usage"`
+}
+
+// Text concatenates all TextBlocks in the response.
+func (r ChatResponse) Text() string {
+	var sb strings.Builder
+	for _, block := range r.Content {
+		if tb, ok := block.(TextBlock); ok {
+			sb.WriteString(tb.Text)
+		}
+	}
+	return sb.String()
+}
+
+// ToolUses returns the ToolUseBlocks in the response, in order.
+func (r ChatResponse) ToolUses() []ToolUseBlock {
+	var out []ToolUseBlock
+	for _, block := range r.Content {
+		if tu, ok := block.(ToolUseBlock); ok {
+			out = append(out, tu)
+		}
+	}
+	return out
+}
+
 // =============================================================================
 // Tooling & Skills
 // =============================================================================
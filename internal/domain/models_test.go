@@ -1,7 +1,9 @@
 package domain
 
 import (
+	"context"
 	"encoding/json"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -275,3 +277,143 @@ func TestSession_JSONRoundtrip(t *testing.T) {
 		t.Error("auth state not preserved")
 	}
 }
+
+func TestNewMessage_WhenSingleText_ShouldEncodeAsString(t *testing.T) {
+	msg := NewTextMessage(RoleUser, "hello")
+	if string(msg.RawContent) != `"hello"` {
+		t.Errorf("RawContent: want %q, got %s", `"hello"`, msg.RawContent)
+	}
+}
+
+func TestNewMessage_WhenToolBlocks_ShouldRoundTripThroughJSON(t *testing.T) {
+	msg := NewMessage(RoleAssistant,
+		TextBlock{Text: "running"},
+		ToolUseBlock{ToolUseID: "tu_1", Name: "shell", Input: json.RawMessage(`{"command":"ls"}`)},
+		ToolResultBlock{ToolUseID: "tu_1", Content: "boom", IsError: true},
+	)
+	data, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+	var got Message
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(got.ContentBlocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(got.ContentBlocks))
+	}
+	tu, ok := got.ContentBlocks[1].(ToolUseBlock)
+	if !ok || tu.ToolUseID != "tu_1" || tu.Name != "shell" || string(tu.Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected tool_use block: %#v", got.ContentBlocks[1])
+	}
+	tr, ok := got.ContentBlocks[2].(ToolResultBlock)
+	if !ok || !tr.IsError || tr.Content != "boom" {
+		t.Errorf("unexpected tool_result block: %#v", got.ContentBlocks[2])
+	}
+}
+
+func TestMessage_Blocks_WhenOnlyRawContent_ShouldParse(t *testing.T) {
+	msg := Message{Role: RoleUser, RawContent: json.RawMessage(`"plain"`)}
+	blocks := msg.Blocks()
+	if len(blocks) != 1 || blocks[0].(TextBlock).Text != "plain" {
+		t.Errorf("unexpected blocks: %#v", blocks)
+	}
+	if (Message{}).Blocks() != nil {
+		t.Error("expected nil blocks for empty message")
+	}
+}
+
+func TestChatResponse_TextAndToolUses(t *testing.T) {
+	resp := ChatResponse{Content: []ContentBlock{
+		TextBlock{Text: "a"},
+		ToolUseBlock{ToolUseID: "1", Name: "x"},
+		TextBlock{Text: "b"},
+	}}
+	if resp.Text() != "ab" {
+		t.Errorf("Text: want %q, got %q", "ab", resp.Text())
+	}
+	if uses := resp.ToolUses(); len(uses) != 1 || uses[0].Name != "x" {
+		t.Errorf("ToolUses: unexpected %#v", uses)
+	}
+}
+
+func TestAgentsConfig_ResolveModel(t *testing.T) {
+	a := AgentsConfig{DefaultModel: "base", ModelAliases: map[string]string{"fast": "small"}}
+	for in, want := range map[string]string{"": "base", "fast": "small", "other": "other"} {
+		if got := a.ResolveModel(in); got != want {
+			t.Errorf("ResolveModel(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAgentsConfig_ResolveProfile(t *testing.T) {
+	a := AgentsConfig{
+		Provider:     "openai",
+		DefaultModel: "smart",
+		ModelAliases: map[string]string{"quick": "fast", "mini": "gpt-4o-mini"},
+		Models: map[string]ModelProfile{
+			"fast":  {Provider: "anthropic", Model: "claude-haiku", MaxTokens: 512},
+			"smart": {Model: "gpt-4o", ContextWindow: 128000},
+		},
+	}
+	for in, want := range map[string]ModelProfile{
+		"":      {Provider: "openai", Model: "gpt-4o", ContextWindow: 128000},
+		"quick": {Provider: "anthropic", Model: "claude-haiku", MaxTokens: 512},
+		"mini":  {Provider: "openai", Model: "gpt-4o-mini"},
+		"other": {Provider: "openai", Model: "other"},
+	} {
+		if got := a.ResolveProfile(in); !reflect.DeepEqual(got, want) {
+			t.Errorf("ResolveProfile(%q) = %+v, want %+v", in, got, want)
+		}
+	}
+}
+
+func TestAgentsConfig_HasModel(t *testing.T) {
+	a := AgentsConfig{
+		DefaultModel: "base",
+		ModelAliases: map[string]string{"fast": "small"},
+		Models:       map[string]ModelProfile{"smart": {}},
+	}
+	for name, want := range map[string]bool{"": true, "base": true, "fast": true, "small": true, "smart": true, "other": false} {
+		if got := a.HasModel(name); got != want {
+			t.Errorf("HasModel(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestWithModel_ShouldRoundTripThroughContext(t *testing.T) {
+	ctx := context.Background()
+	if WithModel(ctx, "") != ctx || ModelFrom(ctx) != "" {
+		t.Error("empty name should leave the context unchanged")
+	}
+	if got := ModelFrom(WithModel(ctx, "fast")); got != "fast" {
+		t.Errorf("ModelFrom = %q, want fast", got)
+	}
+}
+
+func TestWithToolEvents_ShouldRoundTripThroughContext(t *testing.T) {
+	ctx := context.Background()
+	if WithToolEvents(ctx, nil) != ctx || ToolEventsFrom(ctx) != nil {
+		t.Error("nil func should leave the context unchanged")
+	}
+	var got ToolEvent
+	ToolEventsFrom(WithToolEvents(ctx, func(ev ToolEvent) { got = ev }))(ToolEvent{Tool: "shell"})
+	if got.Tool != "shell" {
+		t.Errorf("event = %+v, want tool shell", got)
+	}
+}
+
+func TestNewMessage_WhenThinkingBlock_ShouldRoundTripThroughJSON(t *testing.T) {
+	msg := NewMessage(RoleAssistant, ThinkingBlock{Thinking: "hmm", Signature: "sig"}, TextBlock{Text: "hi"})
+	raw, err := json.Marshal(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got Message
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.ContentBlocks, msg.ContentBlocks) {
+		t.Errorf("blocks = %+v, want %+v", got.ContentBlocks, msg.ContentBlocks)
+	}
+}
@@ -0,0 +1,171 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"ironclaw/internal/scheduler"
+)
+
+// JobManager is the scheduler surface served under /api/jobs. It is
+// implemented by *scheduler.Scheduler.
+type JobManager interface {
+	ListJobs() []scheduler.Job
+	GetJob(id string) (scheduler.Job, bool)
+	AddJob(job scheduler.Job) error
+	RemoveJob(id string) error
+	PauseJob(id string) error
+	ResumeJob(id string) error
+	RunNow(ctx context.Context, id string) (scheduler.Run, error)
+	Runs(ctx context.Context, id string, limit int) ([]scheduler.Run, error)
+}
+
+var _ JobManager = (*scheduler.Scheduler)(nil)
+
+// defaultJobRunsLimit is how many runs GET /api/jobs/{id} returns without ?limit=.
+const defaultJobRunsLimit = 20
+
+// JobView is a job with its most recent run, as returned by GET /api/jobs.
+type JobView struct {
+	scheduler.Job
+	LastRun *scheduler.Run `json:"lastRun,omitempty"`
+}
+
+// JobDetail is a job with its run history, as returned by GET /api/jobs/{id}.
+type JobDetail struct {
+	scheduler.Job
+	Runs []scheduler.Run `json:"runs"`
+}
+
+// jobsHandler serves the job management REST API.
+type jobsHandler struct {
+	jobs JobManager
+}
+
+// register adds the /api/jobs routes to mux.
+func (h *jobsHandler) register(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/jobs", h.list)
+	mux.HandleFunc("POST /api/jobs", h.add)
+	mux.HandleFunc("GET /api/jobs/{id}", h.get)
+	mux.HandleFunc("DELETE /api/jobs/{id}", h.remove)
+	mux.HandleFunc("POST /api/jobs/{id}/run", h.runNow)
+	mux.HandleFunc("POST /api/jobs/{id}/pause", h.pause)
+	mux.HandleFunc("POST /api/jobs/{id}/resume", h.resume)
+}
+
+func (h *jobsHandler) list(w http.ResponseWriter, r *http.Request) {
+	jobs := h.jobs.ListJobs()
+	out := make([]JobView, 0, len(jobs))
+	for _, j := range jobs {
+		v := JobView{Job: j}
+		if runs, err := h.jobs.Runs(r.Context(), j.ID, 1); err == nil && len(runs) > 0 {
+			v.LastRun = &runs[0]
+		}
+		out = append(out, v)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *jobsHandler) add(w http.ResponseWriter, r *http.Request) {
+	var job scheduler.Job
+	if err := json.NewDecoder(r.Body).Decode(&job); err != nil {
+		writeJobError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+		return
+	}
+	if err := h.jobs.AddJob(job); err != nil {
+		writeJobError(w, jobErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusCreated, job)
+}
+
+func (h *jobsHandler) get(w http.ResponseWriter, r *http.Request) {
+	job, ok := h.jobs.GetJob(r.PathValue("id"))
+	if !ok {
+		writeJobError(w, http.StatusNotFound, scheduler.ErrJobNotFound.Error())
+		return
+	}
+	limit := defaultJobRunsLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			writeJobError(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+		limit = n
+	}
+	detail := JobDetail{Job: job, Runs: []scheduler.Run{}}
+	runs, err := h.jobs.Runs(r.Context(), job.ID, limit)
+	switch {
+	case err == nil:
+		if runs != nil {
+			detail.Runs = runs
+		}
+	case !errors.Is(err, scheduler.ErrNoStore):
+		writeJobError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (h *jobsHandler) remove(w http.ResponseWriter, r *http.Request) {
+	if err := h.jobs.RemoveJob(r.PathValue("id")); err != nil {
+		writeJobError(w, jobErrorStatus(err), err.Error())
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// runNow runs the job synchronously. A failing handler is still a completed
+// run: it is reported in the run's error field with status 200. A job that is
+// still running under the skip overlap policy is a 409.
+func (h *jobsHandler) runNow(w http.ResponseWriter, r *http.Request) {
+	run, err := h.jobs.RunNow(r.Context(), r.PathValue("id"))
+	if errors.Is(err, scheduler.ErrJobNotFound) || errors.Is(err, scheduler.ErrJobRunning) {
+		writeJobError(w, jobErrorStatus(err), err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, run)
+}
+
+func (h *jobsHandler) pause(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, h.jobs.PauseJob)
+}
+
+func (h *jobsHandler) resume(w http.ResponseWriter, r *http.Request) {
+	h.setPaused(w, r, h.jobs.ResumeJob)
+}
+
+func (h *jobsHandler) setPaused(w http.ResponseWriter, r *http.Request, fn func(string) error) {
+	id := r.PathValue("id")
+	if err := fn(id); err != nil {
+		writeJobError(w, jobErrorStatus(err), err.Error())
+		return
+	}
+	job, _ := h.jobs.GetJob(id)
+	writeJSON(w, http.StatusOK, job)
+}
+
+// jobErrorStatus maps scheduler errors to HTTP status codes.
+func jobErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, scheduler.ErrDuplicateJob), errors.Is(err, scheduler.ErrJobRunning):
+		return http.StatusConflict
+	case errors.Is(err, scheduler.ErrEmptyJobID), errors.Is(err, scheduler.ErrEmptyCron),
+		errors.Is(err, scheduler.ErrEmptyPrompt), errors.Is(err, scheduler.ErrInvalidCron),
+		errors.Is(err, scheduler.ErrBothTriggers), errors.Is(err, scheduler.ErrBadOverlap),
+		errors.Is(err, scheduler.ErrBadTarget):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func writeJobError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
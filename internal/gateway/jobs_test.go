@@ -0,0 +1,119 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/scheduler"
+)
+
+// newJobsTestServer serves a real Scheduler (never started, so jobs only run via the API) through the gateway.
+func newJobsTestServer(t *testing.T, handler scheduler.EventHandler) (*scheduler.Scheduler, http.Handler) {
+	t.Helper()
+	sched := scheduler.NewScheduler(scheduler.NewRobfigCronEngine(), handler)
+	srv, err := NewServer(&domain.GatewayConfig{}, nil, WithJobs(sched))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	return sched, srv.Handler()
+}
+
+func serveJobs(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+	return rec
+}
+
+func TestJobsAPI_AddListPauseRemove(t *testing.T) {
+	sched, h := newJobsTestServer(t, func(ctx context.Context, job scheduler.Job) (string, error) { return "", nil })
+
+	rec := serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"digest","name":"Digest","cron":"@daily","prompt":"summarize"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add: %d %s", rec.Code, rec.Body)
+	}
+	if rec := serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"digest","cron":"@daily","prompt":"x"}`); rec.Code != http.StatusConflict {
+		t.Errorf("duplicate add: want 409, got %d", rec.Code)
+	}
+	if rec := serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"bad","cron":"nope","prompt":"x"}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid cron: want 400, got %d", rec.Code)
+	}
+	if rec := serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"bad","cron":"@daily","prompt":"x","deliver":{"kind":"pigeon"}}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid target: want 400, got %d", rec.Code)
+	}
+
+	rec = serveJobs(h, http.MethodPost, "/api/jobs/digest/pause", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("pause: %d", rec.Code)
+	}
+	if job, _ := sched.GetJob("digest"); !job.Paused {
+		t.Error("expected job paused")
+	}
+
+	var list []JobView
+	_ = json.NewDecoder(serveJobs(h, http.MethodGet, "/api/jobs", "").Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != "digest" || !list[0].Paused || list[0].LastRun != nil {
+		t.Errorf("unexpected list %+v", list)
+	}
+
+	if rec := serveJobs(h, http.MethodDelete, "/api/jobs/digest", ""); rec.Code != http.StatusNoContent {
+		t.Errorf("remove: want 204, got %d", rec.Code)
+	}
+	if rec := serveJobs(h, http.MethodDelete, "/api/jobs/digest", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("remove missing: want 404, got %d", rec.Code)
+	}
+}
+
+func TestJobsAPI_RunNow_ShouldReturnRunIncludingHandlerError(t *testing.T) {
+	_, h := newJobsTestServer(t, func(ctx context.Context, job scheduler.Job) (string, error) {
+		if job.ID == "fail" {
+			return "", errors.New("llm down")
+		}
+		return "report ready", nil
+	})
+	serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"ok","cron":"@daily","prompt":"p"}`)
+	serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"fail","cron":"@daily","prompt":"p"}`)
+
+	var run scheduler.Run
+	rec := serveJobs(h, http.MethodPost, "/api/jobs/ok/run", "")
+	_ = json.NewDecoder(rec.Body).Decode(&run)
+	if rec.Code != http.StatusOK || run.Response != "report ready" {
+		t.Errorf("run ok: %d %+v", rec.Code, run)
+	}
+	rec = serveJobs(h, http.MethodPost, "/api/jobs/fail/run", "")
+	_ = json.NewDecoder(rec.Body).Decode(&run)
+	if rec.Code != http.StatusOK || run.Error != "llm down" {
+		t.Errorf("run fail: %d %+v", rec.Code, run)
+	}
+	if rec := serveJobs(h, http.MethodPost, "/api/jobs/missing/run", ""); rec.Code != http.StatusNotFound {
+		t.Errorf("run missing: want 404, got %d", rec.Code)
+	}
+}
+
+func TestJobsAPI_Get_WhenNoStore_ShouldReturnEmptyRuns(t *testing.T) {
+	_, h := newJobsTestServer(t, func(ctx context.Context, job scheduler.Job) (string, error) { return "", nil })
+	serveJobs(h, http.MethodPost, "/api/jobs", `{"id":"j","cron":"@daily","prompt":"p"}`)
+
+	rec := serveJobs(h, http.MethodGet, "/api/jobs/j", "")
+	var detail JobDetail
+	_ = json.NewDecoder(rec.Body).Decode(&detail)
+	if rec.Code != http.StatusOK || detail.ID != "j" || detail.Runs == nil {
+		t.Errorf("unexpected detail %d %+v", rec.Code, detail)
+	}
+	if rec := serveJobs(h, http.MethodGet, "/api/jobs/j?limit=x", ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("bad limit: want 400, got %d", rec.Code)
+	}
+}
+
+func TestNewServer_WhenNoJobs_ShouldNotServeJobsAPI(t *testing.T) {
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil)
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/jobs", "")
+	if rec.Body.String() != "OK" {
+		t.Errorf("expected fallthrough to root handler, got %q", rec.Body.String())
+	}
+}
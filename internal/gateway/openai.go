@@ -0,0 +1,326 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
+	"ironclaw/internal/usage"
+)
+
+// ChannelHeader selects the router channel for OpenAI-compatible requests. When
+// absent, the request's "user" field is used, then DefaultAPIChannelID.
+const ChannelHeader = "X-Ironclaw-Channel"
+
+// DefaultAPIChannelID is the channel for OpenAI-compatible requests that name none.
+const DefaultAPIChannelID = "openai"
+
+// maxCompletionBodyBytes caps the body of a chat completion request.
+const maxCompletionBodyBytes = 8 << 20
+
+// openAIHandler serves the OpenAI-compatible /v1 endpoints on top of a shared
+// Router, so API requests use the same per-channel lanes as other transports.
+type openAIHandler struct {
+	router *router.Router
+	models domain.AgentsConfig
+	now    func() time.Time
+}
+
+type chatCompletionRequest struct {
+	Model    string                  `json:"model"`
+	Messages []chatCompletionMessage `json:"messages"`
+	Stream   bool                    `json:"stream"`
+	User     string                  `json:"user"`
+}
+
+// chatCompletionMessage accepts content as a string or an array of parts;
+// only "text" parts are used.
+type chatCompletionMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type chatCompletionResponse struct {
+	ID      string                 `json:"id"`
+	Object  string                 `json:"object"`
+	Created int64                  `json:"created"`
+	Model   string                 `json:"model"`
+	Choices []chatCompletionChoice `json:"choices"`
+	Usage   *chatCompletionUsage   `json:"usage,omitempty"`
+}
+
+// chatCompletionChoice is used for both full responses (Message) and stream
+// chunks (Delta).
+type chatCompletionChoice struct {
+	Index        int                  `json:"index"`
+	Message      *chatCompletionDelta `json:"message,omitempty"`
+	Delta        *chatCompletionDelta `json:"delta,omitempty"`
+	FinishReason *string              `json:"finish_reason"`
+}
+
+type chatCompletionDelta struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content,omitempty"`
+}
+
+type chatCompletionUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type modelList struct {
+	Object string      `json:"object"`
+	Data   []modelInfo `json:"data"`
+}
+
+type modelInfo struct {
+	ID      string `json:"id"`
+	Object  string `json:"object"`
+	Created int64  `json:"created"`
+	OwnedBy string `json:"owned_by"`
+}
+
+// apiError is the OpenAI error envelope.
+type apiError struct {
+	Error struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code,omitempty"`
+	} `json:"error"`
+}
+
+// chatCompletions handles POST /v1/chat/completions.
+func (h *openAIHandler) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	var req chatCompletionRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxCompletionBodyBytes)).Decode(&req); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			writeAPIError(w, http.StatusRequestEntityTooLarge, "invalid_request_error", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit))
+			return
+		}
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error())
+		return
+	}
+	// An empty model selects the default; others must be configured.
+	if !h.models.HasModel(req.Model) {
+		var e apiError
+		e.Error.Message = fmt.Sprintf("The model %q does not exist; GET /v1/models lists the available models", req.Model)
+		e.Error.Type = "invalid_request_error"
+		e.Error.Code = "model_not_found"
+		writeJSON(w, http.StatusNotFound, e)
+		return
+	}
+	system, messages, err := toDomainMessages(req.Messages)
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != domain.RoleUser {
+		writeAPIError(w, http.StatusBadRequest, "invalid_request_error", "messages must end with a user message")
+		return
+	}
+
+	channelID := r.Header.Get(ChannelHeader)
+	if channelID == "" {
+		channelID = req.User
+	}
+	if channelID == "" {
+		channelID = DefaultAPIChannelID
+	}
+
+	ctx := domain.WithModel(r.Context(), req.Model)
+	model := req.Model
+	// "Cache-Control: no-cache" asks for a fresh reply from the provider.
+	if cc := r.Header.Get("Cache-Control"); strings.Contains(cc, "no-cache") || strings.Contains(cc, "no-store") {
+		ctx = domain.WithoutCache(ctx)
+	}
+	resp := chatCompletionResponse{
+		ID:      newCompletionID(),
+		Created: h.now().Unix(),
+		Model:   h.models.ResolveProfile(model).Model,
+	}
+	if req.Stream {
+		h.streamCompletion(w, r.WithContext(ctx), resp, channelID, system, messages)
+		return
+	}
+
+	// Count the tokens the providers report for this request.
+	tally := &usage.Tally{}
+	reply, err := h.router.RouteMessages(usage.WithRecorder(ctx, tally), channelID, system, messages, nil)
+	if err != nil {
+		writeAPIError(w, http.StatusBadGateway, "api_error", err.Error())
+		return
+	}
+	stop := "stop"
+	resp.Object = "chat.completion"
+	resp.Choices = []chatCompletionChoice{{
+		Message:      &chatCompletionDelta{Role: "assistant", Content: reply},
+		FinishReason: &stop,
+	}}
+	in, out := tally.Tokens()
+	resp.Usage = &chatCompletionUsage{PromptTokens: in, CompletionTokens: out, TotalTokens: in + out}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// streamCompletion answers with "chat.completion.chunk" SSE events: a role
+// chunk, one chunk per delta, a final chunk with finish_reason, then [DONE].
+// Errors after streaming has started are sent as an error event.
+func (h *openAIHandler) streamCompletion(w http.ResponseWriter, r *http.Request, base chatCompletionResponse, channelID, system string, messages []domain.Message) {
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(v any) {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	chunk := func(delta chatCompletionDelta, finish *string) chatCompletionResponse {
+		c := base
+		c.Object = "chat.completion.chunk"
+		c.Choices = []chatCompletionChoice{{Delta: &delta, FinishReason: finish}}
+		return c
+	}
+
+	send(chunk(chatCompletionDelta{Role: "assistant"}, nil))
+	_, err := h.router.RouteMessages(r.Context(), channelID, system, messages, func(delta string) {
+		send(chunk(chatCompletionDelta{Content: delta}, nil))
+	})
+	if err != nil {
+		var e apiError
+		e.Error.Message = err.Error()
+		e.Error.Type = "api_error"
+		send(e)
+	} else {
+		stop := "stop"
+		send(chunk(chatCompletionDelta{}, &stop))
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// listModels handles GET /v1/models: the default model, every alias and
+// alias target and every model profile, sorted by ID.
+func (h *openAIHandler) listModels(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		writeAPIError(w, http.StatusMethodNotAllowed, "invalid_request_error", "method not allowed")
+		return
+	}
+	seen := make(map[string]bool)
+	add := func(id string) {
+		if id != "" {
+			seen[id] = true
+		}
+	}
+	add(h.models.DefaultModel)
+	for alias, target := range h.models.ModelAliases {
+		add(alias)
+		add(target)
+	}
+	for name := range h.models.Models {
+		add(name)
+	}
+	ids := make([]string, 0, len(seen))
+	for id := range seen {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	list := modelList{Object: "list", Data: make([]modelInfo, 0, len(ids))}
+	for _, id := range ids {
+		list.Data = append(list.Data, modelInfo{ID: id, Object: "model", OwnedBy: "ironclaw"})
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+// toDomainMessages converts OpenAI messages to domain messages. System (and
+// developer) messages are joined into the returned system prompt; tool
+// messages are passed as user text.
+func toDomainMessages(in []chatCompletionMessage) (string, []domain.Message, error) {
+	var system []string
+	var out []domain.Message
+	for i, m := range in {
+		text, err := contentText(m.Content)
+		if err != nil {
+			return "", nil, fmt.Errorf("messages[%d]: %w", i, err)
+		}
+		switch m.Role {
+		case "system", "developer":
+			system = append(system, text)
+		case "assistant":
+			out = append(out, domain.NewTextMessage(domain.RoleAssistant, text))
+		case "user", "tool":
+			out = append(out, domain.NewTextMessage(domain.RoleUser, text))
+		default:
+			return "", nil, fmt.Errorf("messages[%d]: unsupported role %q", i, m.Role)
+		}
+	}
+	return strings.Join(system, "\n\n"), out, nil
+}
+
+// contentText extracts text from a string or an array of content parts.
+func contentText(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(raw, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of parts")
+	}
+	var sb strings.Builder
+	for _, p := range parts {
+		if p.Type == "text" {
+			sb.WriteString(p.Text)
+		}
+	}
+	return sb.String(), nil
+}
+
+// newCompletionID returns a random "chatcmpl-" identifier.
+func newCompletionID() string {
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "chatcmpl-" + hex.EncodeToString(b[:])
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeAPIError(w http.ResponseWriter, status int, typ, msg string) {
+	var e apiError
+	e.Error.Message = msg
+	e.Error.Type = typ
+	writeJSON(w, status, e)
+}
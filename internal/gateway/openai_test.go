@@ -0,0 +1,301 @@
+package gateway
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
+	"ironclaw/internal/usage"
+)
+
+func newAPITestServer(t *testing.T, brain ChatBrain, opts ...ServerOption) *httptest.Server {
+	t.Helper()
+	cfg := &domain.GatewayConfig{Port: 0, Auth: domain.AuthConfig{AuthToken: "secret"}}
+	srv, err := NewServer(cfg, brain, opts...)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	ts := httptest.NewServer(srv.Handler())
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func postCompletion(t *testing.T, url, body string, header map[string]string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(http.MethodPost, url+"/v1/chat/completions", strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestChatCompletions_WhenValidRequest_ShouldReturnCompletionWithResolvedModel(t *testing.T) {
+	brain := &historyChatBrain{mockChatBrain: mockChatBrain{response: "pong"}}
+	ts := newAPITestServer(t, brain, WithModels(domain.AgentsConfig{
+		DefaultModel: "gpt-4o",
+		ModelAliases: map[string]string{"fast": "gpt-4o-mini"},
+	}))
+
+	resp := postCompletion(t, ts.URL, `{"model":"fast","messages":[{"role":"system","content":"be brief"},{"role":"user","content":[{"type":"text","text":"ping"}]}]}`, nil)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status: %d", resp.StatusCode)
+	}
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if out.Object != "chat.completion" || out.Model != "gpt-4o-mini" || !strings.HasPrefix(out.ID, "chatcmpl-") {
+		t.Errorf("unexpected envelope %+v", out)
+	}
+	if len(out.Choices) != 1 || out.Choices[0].Message.Content != "pong" || *out.Choices[0].FinishReason != "stop" {
+		t.Errorf("unexpected choices %+v", out.Choices)
+	}
+	if brain.system != "be brief" || len(brain.lengths) != 1 || brain.lengths[0] != 1 {
+		t.Errorf("brain got system %q, lengths %v", brain.system, brain.lengths)
+	}
+}
+
+func TestChatCompletions_WhenChannelHeaderSet_ShouldRecordInThatChannel(t *testing.T) {
+	brain := &historyChatBrain{mockChatBrain: mockChatBrain{response: "ok"}}
+	stores := map[string]*memHistory{}
+	ts := newAPITestServer(t, brain, WithHistoryFactory(func(id string) domain.SessionHistoryStore {
+		stores[id] = &memHistory{}
+		return stores[id]
+	}))
+
+	postCompletion(t, ts.URL, `{"messages":[{"role":"user","content":"hi"}],"user":"ignored"}`, map[string]string{ChannelHeader: "ide"})
+	postCompletion(t, ts.URL, `{"messages":[{"role":"user","content":"hi"}],"user":"alice"}`, nil)
+
+	if stores["ide"] == nil || len(stores["ide"].msgs) != 2 {
+		t.Errorf("expected user+assistant recorded in header channel, got %+v", stores["ide"])
+	}
+	if stores["alice"] == nil {
+		t.Error("expected the user field to select the channel when no header is set")
+	}
+}
+
+func TestChatCompletions_WhenRouterGiven_ShouldShareItsChannels(t *testing.T) {
+	brain := &historyChatBrain{mockChatBrain: mockChatBrain{response: "ok"}}
+	stores := map[string]*memHistory{}
+	rt := router.NewRouter(brain, func(id string) domain.SessionHistoryStore {
+		stores[id] = &memHistory{}
+		return stores[id]
+	})
+	if err := rt.Deliver(context.Background(), "ide", "daily briefing"); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	ts := newAPITestServer(t, brain, WithRouter(rt))
+
+	postCompletion(t, ts.URL, `{"messages":[{"role":"user","content":"hi"}]}`, map[string]string{ChannelHeader: "ide"})
+
+	if len(stores) != 1 || len(stores["ide"].msgs) != 3 {
+		t.Errorf("expected the delivered message and the turn in one channel history, got %+v", stores)
+	}
+}
+
+func TestChatCompletions_WhenStreamRequested_ShouldSendSSEChunksAndDone(t *testing.T) {
+	brain := &streamingChatBrain{historyChatBrain: historyChatBrain{mockChatBrain: mockChatBrain{response: "Hello"}}, deltas: []string{"Hel", "lo"}}
+	ts := newAPITestServer(t, brain)
+
+	resp := postCompletion(t, ts.URL, `{"stream":true,"messages":[{"role":"user","content":"hi"}]}`, nil)
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content type: %q", ct)
+	}
+	var content strings.Builder
+	var finish string
+	done := false
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := strings.TrimPrefix(sc.Text(), "data: ")
+		if line == "" {
+			continue
+		}
+		if line == "[DONE]" {
+			done = true
+			break
+		}
+		var chunk chatCompletionResponse
+		if err := json.Unmarshal([]byte(line), &chunk); err != nil {
+			t.Fatalf("chunk %q: %v", line, err)
+		}
+		if chunk.Object != "chat.completion.chunk" {
+			t.Errorf("object: %q", chunk.Object)
+		}
+		content.WriteString(chunk.Choices[0].Delta.Content)
+		if chunk.Choices[0].FinishReason != nil {
+			finish = *chunk.Choices[0].FinishReason
+		}
+	}
+	if !done || content.String() != "Hello" || finish != "stop" {
+		t.Errorf("done=%v content=%q finish=%q", done, content.String(), finish)
+	}
+}
+
+func TestChatCompletions_WhenInvalidRequests_ShouldReturnOpenAIErrors(t *testing.T) {
+	ts := newAPITestServer(t, &mockChatBrain{response: "x"})
+	cases := map[string]string{
+		"bad json":        `{`,
+		"no user message": `{"messages":[{"role":"assistant","content":"hi"}]}`,
+		"unknown role":    `{"messages":[{"role":"robot","content":"hi"}]}`,
+	}
+	for name, body := range cases {
+		resp := postCompletion(t, ts.URL, body, nil)
+		var e apiError
+		_ = json.NewDecoder(resp.Body).Decode(&e)
+		if resp.StatusCode != http.StatusBadRequest || e.Error.Type != "invalid_request_error" {
+			t.Errorf("%s: status %d, error %+v", name, resp.StatusCode, e)
+		}
+	}
+}
+
+func TestChatCompletions_WhenUnauthorized_ShouldReturn401(t *testing.T) {
+	ts := newAPITestServer(t, &mockChatBrain{response: "x"})
+	resp, err := http.Post(ts.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("want 401, got %d", resp.StatusCode)
+	}
+}
+
+func TestListModels_ShouldListDefaultAliasesTargetsAndProfiles(t *testing.T) {
+	ts := newAPITestServer(t, &mockChatBrain{}, WithModels(domain.AgentsConfig{
+		DefaultModel: "claude-sonnet",
+		ModelAliases: map[string]string{"smart": "claude-opus"},
+		Models:       map[string]domain.ModelProfile{"fast": {Model: "claude-haiku"}},
+	}))
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/v1/models", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var list modelList
+	if err := json.NewDecoder(resp.Body).Decode(&list); err != nil {
+		t.Fatal(err)
+	}
+	var ids []string
+	for _, m := range list.Data {
+		ids = append(ids, m.ID)
+	}
+	if list.Object != "list" || strings.Join(ids, ",") != "claude-opus,claude-sonnet,fast,smart" {
+		t.Errorf("unexpected models %+v", list)
+	}
+}
+
+func TestNewServer_WhenNoBrain_ShouldNotServeOpenAIAPI(t *testing.T) {
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil)
+	rec := httptest.NewRecorder()
+	srv.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/models", nil))
+	if rec.Body.String() != "OK" {
+		t.Errorf("expected fallthrough to root handler, got %q", rec.Body.String())
+	}
+}
+
+func TestChatCompletions_WhenModelConfigured_ShouldSelectItInContext(t *testing.T) {
+	ts := newAPITestServer(t, modelBrain{}, WithModels(domain.AgentsConfig{
+		DefaultModel: "gpt-4o",
+		Models:       map[string]domain.ModelProfile{"smart": {Model: "claude-opus"}},
+	}))
+
+	for model, want := range map[string][2]string{
+		"smart": {"model=smart", "claude-opus"},
+		"":      {"model=", "gpt-4o"},
+	} {
+		resp := postCompletion(t, ts.URL, `{"model":"`+model+`","messages":[{"role":"user","content":"hi"}]}`, nil)
+		var out chatCompletionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(out.Choices) != 1 || out.Choices[0].Message.Content != want[0] || out.Model != want[1] {
+			t.Errorf("model %q: got %+v, want reply %q from %q", model, out, want[0], want[1])
+		}
+	}
+}
+
+func TestChatCompletions_WhenModelUnknown_ShouldReturn404ModelNotFound(t *testing.T) {
+	ts := newAPITestServer(t, modelBrain{}, WithModels(domain.AgentsConfig{DefaultModel: "gpt-4o"}))
+
+	resp := postCompletion(t, ts.URL, `{"model":"unknown","messages":[{"role":"user","content":"hi"}]}`, nil)
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("status: %d", resp.StatusCode)
+	}
+	var e apiError
+	if err := json.NewDecoder(resp.Body).Decode(&e); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if e.Error.Code != "model_not_found" || !strings.Contains(e.Error.Message, "unknown") {
+		t.Errorf("unexpected error %+v", e.Error)
+	}
+}
+
+func TestChatCompletions_WhenBodyTooLarge_ShouldReturn413(t *testing.T) {
+	ts := newAPITestServer(t, &mockChatBrain{response: "ok"})
+
+	body := `{"messages":[{"role":"user","content":"` + strings.Repeat("a", maxCompletionBodyBytes) + `"}]}`
+	resp := postCompletion(t, ts.URL, body, nil)
+	if resp.StatusCode != http.StatusRequestEntityTooLarge {
+		t.Errorf("status: %d", resp.StatusCode)
+	}
+}
+
+// usageBrain reports the tokens of a provider call, as a metered brain does.
+type usageBrain struct{}
+
+func (usageBrain) Generate(ctx context.Context, _ string) (string, error) {
+	usage.Report(ctx, usage.Call{Provider: "openai", InputTokens: 12, OutputTokens: 5})
+	return "ok", nil
+}
+
+func TestChatCompletions_WhenProviderReportsUsage_ShouldReturnIt(t *testing.T) {
+	ts := newAPITestServer(t, usageBrain{})
+
+	resp := postCompletion(t, ts.URL, `{"messages":[{"role":"user","content":"hi"}]}`, nil)
+	var out chatCompletionResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if u := out.Usage; u == nil || u.PromptTokens != 12 || u.CompletionTokens != 5 || u.TotalTokens != 17 {
+		t.Errorf("usage = %+v", u)
+	}
+}
+
+// cacheBrain reports whether the request asked to bypass the response cache.
+type cacheBrain struct{}
+
+func (cacheBrain) Generate(ctx context.Context, _ string) (string, error) {
+	if domain.CacheBypassed(ctx) {
+		return "bypassed", nil
+	}
+	return "cached", nil
+}
+
+func TestChatCompletions_WhenNoCacheHeader_ShouldBypassCache(t *testing.T) {
+	ts := newAPITestServer(t, cacheBrain{})
+
+	for header, want := range map[string]string{"no-cache": "bypassed", "max-age=0, no-store": "bypassed", "": "cached"} {
+		resp := postCompletion(t, ts.URL, `{"messages":[{"role":"user","content":"hi"}]}`, map[string]string{"Cache-Control": header})
+		var out chatCompletionResponse
+		if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		if len(out.Choices) != 1 || out.Choices[0].Message.Content != want {
+			t.Errorf("Cache-Control %q: got %+v, want %q", header, out, want)
+		}
+	}
+}
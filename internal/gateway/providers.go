@@ -0,0 +1,69 @@
+package gateway
+
+import (
+	"net/http"
+
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
+	"ironclaw/internal/ratelimit"
+)
+
+// ProviderHealth is the circuit breaker state served under /api/providers.
+// It is implemented by *breaker.Set.
+type ProviderHealth interface {
+	Status() []breaker.Status
+}
+
+var _ ProviderHealth = (*breaker.Set)(nil)
+
+// ProviderLimits is the rate limiter state served under
+// /api/providers/limits. It is implemented by *ratelimit.Set.
+type ProviderLimits interface {
+	Status() []ratelimit.Status
+}
+
+var _ ProviderLimits = (*ratelimit.Set)(nil)
+
+// CacheStats is the response cache activity served under /api/cache. It is
+// implemented by *cache.Cache.
+type CacheStats interface {
+	Stats() cache.Stats
+}
+
+var _ CacheStats = (*cache.Cache)(nil)
+
+// providersHandler serves the provider health and limits APIs. Either may be
+// nil, in which case its route is not registered.
+type providersHandler struct {
+	health ProviderHealth
+	limits ProviderLimits
+}
+
+// register adds the /api/providers routes to mux.
+func (h *providersHandler) register(mux *http.ServeMux) {
+	if h.health != nil {
+		mux.HandleFunc("GET /api/providers", h.list)
+	}
+	if h.limits != nil {
+		mux.HandleFunc("GET /api/providers/limits", h.listLimits)
+	}
+}
+
+// list returns the circuit of every provider that has been called, sorted by name.
+func (h *providersHandler) list(w http.ResponseWriter, r *http.Request) {
+	out := h.health.Status()
+	if out == nil {
+		out = []breaker.Status{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// listLimits returns the limiter of every provider and key that has been
+// called, with its usage in the last minute and its queue, sorted by name.
+func (h *providersHandler) listLimits(w http.ResponseWriter, r *http.Request) {
+	out := h.limits.Status()
+	if out == nil {
+		out = []ratelimit.Status{}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
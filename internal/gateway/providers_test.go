@@ -0,0 +1,103 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/ratelimit"
+)
+
+func TestProvidersAPI_ShouldListCircuitState(t *testing.T) {
+	set := breaker.NewSet(breaker.Config{FailureThreshold: 1, ProbeInterval: time.Minute}, breaker.WithStateChange(func(breaker.Status) {}))
+	set.Get("openai/gpt-4o")
+	b := set.Get("anthropic/claude")
+	_ = b.Allow()
+	b.Failure(errors.New("529 overloaded"))
+	srv, err := NewServer(&domain.GatewayConfig{}, nil, WithProviderHealth(set))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/providers", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var got []struct {
+		Name      string `json:"name"`
+		State     string `json:"state"`
+		Failures  int    `json:"failures"`
+		LastError string `json:"lastError"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "anthropic/claude" || got[0].State != "open" || got[0].LastError != "529 overloaded" || got[1].State != "closed" {
+		t.Errorf("providers = %+v", got)
+	}
+}
+
+func TestProvidersAPI_WhenNoCircuits_ShouldReturnEmptyList(t *testing.T) {
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil, WithProviderHealth(breaker.NewSet(breaker.DefaultConfig())))
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/providers", "")
+	if rec.Body.String() != "[]\n" {
+		t.Errorf("body = %q, want []", rec.Body.String())
+	}
+}
+
+func TestNewServer_WhenNoProviderHealth_ShouldNotServeProvidersAPI(t *testing.T) {
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil)
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/providers", "")
+	if rec.Body.String() != "OK" {
+		t.Errorf("expected fallthrough to root handler, got %q", rec.Body.String())
+	}
+}
+
+func TestProvidersAPI_ShouldListRateLimits(t *testing.T) {
+	set := ratelimit.NewSet(map[string]domain.RateLimitConfig{"openai": {MaxConcurrent: 2, RequestsPerMinute: 60}})
+	permit, err := set.Provider("openai").Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	defer permit.Done()
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil, WithProviderLimits(set))
+
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/providers/limits", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", rec.Code, rec.Body.String())
+	}
+	var got []ratelimit.Status
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "openai" || got[0].InFlight != 1 || got[0].Requests != 1 || got[0].MaxConcurrent != 2 {
+		t.Errorf("limits = %+v", got)
+	}
+	if rec := serveJobs(srv.Handler(), http.MethodGet, "/api/providers", ""); rec.Body.String() != "OK" {
+		t.Errorf("/api/providers without health = %q, want fallthrough", rec.Body.String())
+	}
+}
+
+// fixedCacheStats reports fixed cache counters.
+type fixedCacheStats cache.Stats
+
+func (s fixedCacheStats) Stats() cache.Stats { return cache.Stats(s) }
+
+func TestCacheAPI_ShouldReturnStats(t *testing.T) {
+	srv, _ := NewServer(&domain.GatewayConfig{}, nil, WithCacheStats(fixedCacheStats{Hits: 3, SemanticHits: 1, Misses: 2}))
+
+	rec := serveJobs(srv.Handler(), http.MethodGet, "/api/cache", "")
+	var got cache.Stats
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode %q: %v", rec.Body.String(), err)
+	}
+	if got != (cache.Stats{Hits: 3, SemanticHits: 1, Misses: 2}) {
+		t.Errorf("stats = %+v", got)
+	}
+}
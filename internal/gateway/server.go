@@ -9,7 +9,9 @@ import (
 	"sync"
 	"time"
 
+	"ironclaw/internal/approval"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
 )
 
 // ErrInvalidPort is returned when gateway port is not in 0..65535.
@@ -26,22 +28,134 @@ type Server struct {
 	listener  net.Listener
 }
 
+// ServerOption configures optional gateway behaviour.
+type ServerOption func(*serverOptions)
+
+type serverOptions struct {
+	historyFactory router.HistoryFactory
+	routerOpts     []router.Option
+	router         *router.Router
+	models         domain.AgentsConfig
+	jobs           JobManager
+	approvals      *approval.Gate
+	health         ProviderHealth
+	limits         ProviderLimits
+	cache          CacheStats
+}
+
+// WithHistoryFactory sets the per-channel history store used by /ws routers so
+// conversations are persisted and replayed. If f is nil, history is not kept.
+func WithHistoryFactory(f router.HistoryFactory) ServerOption {
+	return func(o *serverOptions) { o.historyFactory = f }
+}
+
+// WithRouterOptions passes options (e.g. router.WithSystemPrompt) to every /ws router.
+func WithRouterOptions(opts ...router.Option) ServerOption {
+	return func(o *serverOptions) { o.routerOpts = append(o.routerOpts, opts...) }
+}
+
+// WithRouter makes /ws connections and the OpenAI-compatible API route
+// through rt instead of routers the server builds from the brain, history
+// factory and router options, so one lane queue orders the turns of each
+// channel across connections and other users of rt (e.g. scheduled job
+// delivery).
+func WithRouter(rt *router.Router) ServerOption {
+	return func(o *serverOptions) { o.router = rt }
+}
+
+// WithModels sets the default model and aliases used by the OpenAI-compatible
+// API to resolve request model names and to list /v1/models.
+func WithModels(agents domain.AgentsConfig) ServerOption {
+	return func(o *serverOptions) { o.models = agents }
+}
+
+// WithJobs serves the job management REST API under /api/jobs backed by jobs
+// (typically the daemon's *scheduler.Scheduler). If jobs is nil the API is not served.
+func WithJobs(jobs JobManager) ServerOption {
+	return func(o *serverOptions) { o.jobs = jobs }
+}
+
+// WithApprovals lets /ws clients approve tool calls that g holds, via
+// approval_request and approval_response messages. g should be the gate
+// passed to brain.WithApprovals; without it, such tool calls are refused.
+func WithApprovals(g *approval.Gate) ServerOption {
+	return func(o *serverOptions) { o.approvals = g }
+}
+
+// WithProviderHealth serves the circuit breaker state of every provider under
+// GET /api/providers (typically the daemon's *breaker.Set). If h is nil the
+// API is not served.
+func WithProviderHealth(h ProviderHealth) ServerOption {
+	return func(o *serverOptions) { o.health = h }
+}
+
+// WithProviderLimits serves the rate limiter state of every provider and key
+// under GET /api/providers/limits (typically the daemon's *ratelimit.Set). If
+// l is nil the API is not served.
+func WithProviderLimits(l ProviderLimits) ServerOption {
+	return func(o *serverOptions) { o.limits = l }
+}
+
+// WithCacheStats serves the hit and miss counts of the LLM response cache
+// under GET /api/cache (typically the daemon's *cache.Cache). If c is nil the
+// API is not served.
+func WithCacheStats(c CacheStats) ServerOption {
+	return func(o *serverOptions) { o.cache = c }
+}
+
 // NewServer builds a gateway server from config. Port 0 means pick a random port.
-// If brain is non-nil, chat messages on /ws use brain.Generate; otherwise replies are echoed.
+// If brain is non-nil, chat messages on /ws are routed to the brain; otherwise replies are echoed.
+// With a brain, the OpenAI-compatible /v1/chat/completions and /v1/models endpoints are
+// also served, sharing one Router (and therefore per-channel lanes) across requests.
 // Returns ErrInvalidPort if port is not in 0..65535.
-func NewServer(cfg *domain.GatewayConfig, brain ChatBrain) (*Server, error) {
+func NewServer(cfg *domain.GatewayConfig, brain ChatBrain, opts ...ServerOption) (*Server, error) {
 	if cfg == nil {
 		cfg = &domain.GatewayConfig{Port: 8080, Auth: domain.AuthConfig{}}
 	}
 	if cfg.Port < 0 || cfg.Port > 65535 {
 		return nil, ErrInvalidPort
 	}
+	var so serverOptions
+	for _, opt := range opts {
+		opt(&so)
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
-	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) { HandleWS(w, r, brain) })
+	// A router given by WithRouter owns its channels for /ws too.
+	var sharedRouter *router.Router
+	if brain != nil {
+		sharedRouter = so.router
+	}
+	mux.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWS(w, r, brain, so.historyFactory, so.approvals, sharedRouter, so.routerOpts...)
+	})
+	if brain != nil {
+		rt := so.router
+		if rt == nil {
+			rt = router.NewRouter(brain, so.historyFactory, so.routerOpts...)
+		}
+		api := &openAIHandler{
+			router: rt,
+			models: so.models,
+			now:    time.Now,
+		}
+		mux.HandleFunc("/v1/chat/completions", api.chatCompletions)
+		mux.HandleFunc("/v1/models", api.listModels)
+	}
+	if so.jobs != nil {
+		(&jobsHandler{jobs: so.jobs}).register(mux)
+	}
+	if so.health != nil || so.limits != nil {
+		(&providersHandler{health: so.health, limits: so.limits}).register(mux)
+	}
+	if so.cache != nil {
+		mux.HandleFunc("GET /api/cache", func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, so.cache.Stats())
+		})
+	}
 	handler := BearerAuth(cfg.Auth.AuthToken)(mux)
 	s := &Server{
 		cfg: cfg,
@@ -3,12 +3,16 @@ package gateway
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
 	"ironclaw/internal/router"
 )
 
@@ -23,12 +27,62 @@ const DefaultChannelID = "default"
 
 // WSMessage is the JSON message protocol for the WebSocket gateway.
 // Example: {"type": "chat", "content": "hello", "channelId": "general"}
+//
+// A chat request with "stream": true is answered with a sequence of
+// "chat_delta" messages (Seq starting at 1) followed by a terminal "chat_done"
+// whose Content is the complete reply, instead of a single "chat" message.
+//
+// A chat request may name a "model": a model, alias or profile from the
+// agents config that answers it instead of the default model.
+//
+// A tool call that needs approval sends an "approval_request" whose Approval
+// carries the request ID, tool and exact arguments (Content is a readable
+// summary). The client answers, at any time while the reply is pending, with
+// {"type": "approval_response", "channelId": "...", "approval": {"id": "...", "decision": "approve"}}
+// ("deny", or "always" to allow identical calls on the channel).
 type WSMessage struct {
-	Type      string `json:"type"`
-	Content   string `json:"content"`
-	ChannelID string `json:"channelId,omitempty"`
+	Type      string      `json:"type"`
+	Content   string      `json:"content"`
+	ChannelID string      `json:"channelId,omitempty"`
+	Stream    bool        `json:"stream,omitempty"`
+	Model     string      `json:"model,omitempty"`
+	Seq       int         `json:"seq,omitempty"`
+	Approval  *WSApproval `json:"approval,omitempty"`
+	Tool      *WSToolCall `json:"tool,omitempty"`
 }
 
+// WSApproval is the payload of approval_request and approval_response messages.
+type WSApproval struct {
+	ID        string          `json:"id"`
+	Tool      string          `json:"tool,omitempty"`
+	Operation string          `json:"operation,omitempty"`
+	Args      json.RawMessage `json:"args,omitempty"`
+	Expires   string          `json:"expires,omitempty"`  // RFC 3339
+	Decision  string          `json:"decision,omitempty"` // Response only: approve, deny or always
+}
+
+// WSToolCall is the payload of tool_started and tool_finished messages.
+type WSToolCall struct {
+	Name    string          `json:"name"`
+	Input   json.RawMessage `json:"input,omitempty"`
+	IsError bool            `json:"isError,omitempty"` // tool_finished only; Content holds the result
+}
+
+// WS message types used for streamed replies, tool progress and tool approvals.
+const (
+	WSTypeChatDelta        = "chat_delta"
+	WSTypeChatDone         = "chat_done"
+	WSTypeToolStarted      = "tool_started"
+	WSTypeToolFinished     = "tool_finished"
+	WSTypeApprovalRequest  = "approval_request"
+	WSTypeApprovalResponse = "approval_response"
+)
+
+// wsInboxSize bounds how many client messages are queued while a reply is
+// being generated. The reader blocks (and stops noticing a closed socket)
+// only once the inbox is full.
+const wsInboxSize = 32
+
 // jsonMarshal is used when encoding WSMessage; tests may replace it to force Marshal errors.
 // Access is protected by jsonMarshalMu for race-safe test swaps.
 var (
@@ -49,7 +103,18 @@ var wsUpgrader = websocket.Upgrader{
 // Messages without a ChannelID are assigned to the "default" channel.
 // Writes are serialized with a mutex so multiple goroutines could write safely.
 // Only GET is accepted for the WebSocket handshake.
-func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain) {
+// factory (may be nil) and opts are passed to the per-connection Router.
+// Closing the socket cancels any reply still being generated.
+func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain, factory router.HistoryFactory, opts ...router.Option) {
+	serveWS(w, r, brain, factory, nil, nil, opts...)
+}
+
+// serveWS implements HandleWS. When approvals is non-nil, approval_request
+// messages are sent for tool calls made while answering and
+// approval_response messages are resolved as soon as they arrive. When shared
+// is non-nil, chats are routed through it instead of a per-connection router,
+// so every connection and other users of shared take turns on a channel.
+func serveWS(w http.ResponseWriter, r *http.Request, brain ChatBrain, factory router.HistoryFactory, approvals *approval.Gate, shared *router.Router, opts ...router.Option) {
 	if r.Method != http.MethodGet {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -62,18 +127,41 @@ func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain) {
 	defer conn.Close()
 
 	// Create a per-connection router that wraps the brain.
-	// Each connection gets its own router so channel state is per-connection.
-	var rt *router.Router
-	if brain != nil {
-		rt = router.NewRouter(brain, nil)
+	// Each connection gets its own router so channel state is per-connection;
+	// persisted history (if any) is shared through the factory.
+	rt := shared
+	if rt == nil && brain != nil {
+		rt = router.NewRouter(brain, factory, opts...)
 	}
 
+	// Generation is canceled as soon as the socket closes: a dedicated reader
+	// goroutine cancels ctx on read error while replies are produced in order.
+	// Approval responses are handled by the reader itself, since the reply
+	// loop is blocked waiting for them.
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
 	var writeMu sync.Mutex
-	for {
-		_, raw, err := conn.ReadMessage()
-		if err != nil {
-			break
+	inbox := make(chan []byte, wsInboxSize)
+	go func() {
+		defer close(inbox)
+		defer cancel()
+		for {
+			_, raw, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if approvals != nil && resolveApproval(conn, &writeMu, approvals, raw) {
+				continue
+			}
+			select {
+			case inbox <- raw:
+			case <-ctx.Done():
+				return
+			}
 		}
+	}()
+
+	for raw := range inbox {
 		var in WSMessage
 		if err := json.Unmarshal(raw, &in); err != nil {
 			reply := WSMessage{Type: "error", Content: "invalid JSON"}
@@ -88,6 +176,13 @@ func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain) {
 		}
 
 		isBrainChat := rt != nil && in.Type == "chat"
+		msgCtx := domain.WithModel(ctx, in.Model)
+		if isBrainChat {
+			msgCtx = domain.WithToolEvents(msgCtx, wsToolEvents(conn, &writeMu, channelID))
+		}
+		if isBrainChat && approvals != nil {
+			msgCtx = approval.NewContext(msgCtx, channelID, wsPrompter(conn, &writeMu))
+		}
 
 		// Send typing_start before brain generation.
 		if isBrainChat {
@@ -95,17 +190,23 @@ func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain) {
 			writeWSMessage(conn, &writeMu, &typingStart)
 		}
 
-		content := "echo: " + in.Content
-		if isBrainChat {
-			reply, err := rt.Route(r.Context(), channelID, in.Content)
+		switch {
+		case isBrainChat && in.Stream:
+			streamReply(msgCtx, conn, &writeMu, rt, channelID, in.Content)
+		case isBrainChat:
+			content := ""
+			reply, err := rt.Route(msgCtx, channelID, in.Content)
 			if err != nil {
 				content = "error: " + err.Error()
 			} else {
 				content = reply
 			}
+			out := WSMessage{Type: in.Type, Content: content, ChannelID: channelID}
+			writeWSMessage(conn, &writeMu, &out)
+		default:
+			out := WSMessage{Type: in.Type, Content: "echo: " + in.Content, ChannelID: channelID}
+			writeWSMessage(conn, &writeMu, &out)
 		}
-		out := WSMessage{Type: in.Type, Content: content, ChannelID: channelID}
-		writeWSMessage(conn, &writeMu, &out)
 
 		// Send typing_stop after brain response is delivered.
 		if isBrainChat {
@@ -115,6 +216,78 @@ func HandleWS(w http.ResponseWriter, r *http.Request, brain ChatBrain) {
 	}
 }
 
+// streamReply routes prompt with streaming, writing one chat_delta per text
+// fragment and a terminal chat_done carrying the full reply (or "error: ...").
+func streamReply(ctx context.Context, conn *websocket.Conn, mu *sync.Mutex, rt *router.Router, channelID, prompt string) {
+	seq := 0
+	reply, err := rt.RouteStream(ctx, channelID, prompt, func(delta string) {
+		seq++
+		msg := WSMessage{Type: WSTypeChatDelta, Content: delta, ChannelID: channelID, Seq: seq}
+		writeWSMessage(conn, mu, &msg)
+	})
+	if err != nil {
+		reply = "error: " + err.Error()
+	}
+	done := WSMessage{Type: WSTypeChatDone, Content: reply, ChannelID: channelID, Seq: seq + 1}
+	writeWSMessage(conn, mu, &done)
+}
+
+// wsToolEvents sends the tool calls made while answering on channelID as
+// tool_started and tool_finished messages, the latter carrying the result.
+func wsToolEvents(conn *websocket.Conn, mu *sync.Mutex, channelID string) func(domain.ToolEvent) {
+	return func(ev domain.ToolEvent) {
+		msg := WSMessage{Type: WSTypeToolStarted, ChannelID: channelID, Tool: &WSToolCall{Name: ev.Tool, Input: ev.Input}}
+		if ev.Finished {
+			msg.Type, msg.Content, msg.Tool.IsError = WSTypeToolFinished, ev.Result, ev.IsError
+		}
+		writeWSMessage(conn, mu, &msg)
+	}
+}
+
+// wsPrompter sends approval requests as approval_request messages.
+func wsPrompter(conn *websocket.Conn, mu *sync.Mutex) approval.Prompter {
+	return approval.PrompterFunc(func(ctx context.Context, req approval.Request) error {
+		msg := WSMessage{Type: WSTypeApprovalRequest, Content: req.Text(), ChannelID: req.ChannelID, Approval: &WSApproval{
+			ID:        req.ID,
+			Tool:      req.Tool,
+			Operation: req.Operation,
+			Args:      req.Args,
+			Expires:   req.Expires.Format(time.RFC3339),
+		}}
+		writeWSMessage(conn, mu, &msg)
+		return nil
+	})
+}
+
+// resolveApproval handles raw if it is an approval_response, replying with an
+// error message when the decision or request ID is invalid. It reports
+// whether raw was an approval_response.
+func resolveApproval(conn *websocket.Conn, mu *sync.Mutex, gate *approval.Gate, raw []byte) bool {
+	var in WSMessage
+	if json.Unmarshal(raw, &in) != nil || in.Type != WSTypeApprovalResponse {
+		return false
+	}
+	var resp WSApproval
+	if in.Approval != nil {
+		resp = *in.Approval
+	}
+	channelID := in.ChannelID
+	if channelID == "" {
+		channelID = DefaultChannelID
+	}
+	var err error
+	if decision, ok := approval.ParseDecision(resp.Decision); ok {
+		err = gate.Resolve(channelID, resp.ID, decision, "ws")
+	} else {
+		err = fmt.Errorf("approval: unknown decision %q (use approve, deny or always)", resp.Decision)
+	}
+	if err != nil {
+		reply := WSMessage{Type: "error", Content: err.Error(), ChannelID: channelID, Approval: &WSApproval{ID: resp.ID}}
+		writeWSMessage(conn, mu, &reply)
+	}
+	return true
+}
+
 func writeWSMessage(conn *websocket.Conn, mu *sync.Mutex, msg *WSMessage) {
 	jsonMarshalMu.RLock()
 	marshal := jsonMarshal
@@ -7,12 +7,17 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/gorilla/websocket"
 
+	"ironclaw/internal/approval"
+	"ironclaw/internal/brain"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/router"
+	"ironclaw/internal/tooling"
 )
 
 func TestHandleWS_WhenValidMessageSent_ShouldEchoResponse(t *testing.T) {
@@ -715,3 +720,433 @@ func TestHandleWS_WhenNonChatTypeWithChannelID_ShouldEchoWithChannelID(t *testin
 		t.Errorf("echo Content: want 'echo: test', got %q", out.Content)
 	}
 }
+
+// historyChatBrain implements router.ContextGenerator and records the number of
+// messages it was given per call.
+type historyChatBrain struct {
+	mockChatBrain
+	lengths []int
+	system  string
+}
+
+func (h *historyChatBrain) GenerateWithContext(_ context.Context, messages []domain.Message, systemPrompt string) (string, error) {
+	h.lengths = append(h.lengths, len(messages))
+	h.system = systemPrompt
+	return h.response, h.err
+}
+
+// memHistory is an in-memory domain.SessionHistoryStore shared across connections.
+type memHistory struct{ msgs []domain.Message }
+
+func (m *memHistory) Append(msg domain.Message) error { m.msgs = append(m.msgs, msg); return nil }
+func (m *memHistory) LoadHistory(n int) ([]domain.Message, error) {
+	if len(m.msgs) > n {
+		return m.msgs[len(m.msgs)-n:], nil
+	}
+	return m.msgs, nil
+}
+
+func TestHandleWS_WhenHistoryFactoryProvided_ShouldReplayHistoryAcrossConnections(t *testing.T) {
+	brain := &historyChatBrain{mockChatBrain: mockChatBrain{response: "ok"}}
+	hist := &memHistory{}
+	srv, err := NewServer(&domain.GatewayConfig{Port: 0, Auth: domain.AuthConfig{}}, brain,
+		WithHistoryFactory(func(string) domain.SessionHistoryStore { return hist }),
+		WithRouterOptions(router.WithSystemPrompt("sys")),
+	)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Dial: %v", err)
+		}
+		if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "hello"}); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		for j := 0; j < 3; j++ { // typing_start, chat, typing_stop
+			var out WSMessage
+			if err := conn.ReadJSON(&out); err != nil {
+				t.Fatalf("ReadJSON: %v", err)
+			}
+		}
+		conn.Close()
+	}
+
+	if len(brain.lengths) != 2 || brain.lengths[0] != 1 || brain.lengths[1] != 3 {
+		t.Errorf("expected history lengths [1 3], got %v", brain.lengths)
+	}
+	if brain.system != "sys" {
+		t.Errorf("system prompt: want %q, got %q", "sys", brain.system)
+	}
+}
+
+// overlapBrain records how many turns it answers at once and the history
+// length each turn sees.
+type overlapBrain struct {
+	mu      sync.Mutex
+	active  int
+	max     int
+	lengths []int
+}
+
+func (o *overlapBrain) Generate(context.Context, string) (string, error) { return "ok", nil }
+
+func (o *overlapBrain) GenerateWithContext(_ context.Context, messages []domain.Message, _ string) (string, error) {
+	o.mu.Lock()
+	o.active++
+	o.max = max(o.max, o.active)
+	o.lengths = append(o.lengths, len(messages))
+	o.mu.Unlock()
+	time.Sleep(50 * time.Millisecond)
+	o.mu.Lock()
+	o.active--
+	o.mu.Unlock()
+	return "ok", nil
+}
+
+func TestHandleWS_WhenRouterGiven_ShouldTakeTurnsOnAChannelAcrossConnections(t *testing.T) {
+	brain := &overlapBrain{}
+	hist := &memHistory{}
+	rt := router.NewRouter(brain, func(string) domain.SessionHistoryStore { return hist })
+	srv, err := NewServer(&domain.GatewayConfig{}, brain, WithRouter(rt))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+
+	var conns []*websocket.Conn
+	for i := 0; i < 2; i++ {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+		if err != nil {
+			t.Fatalf("Dial: %v", err)
+		}
+		defer conn.Close()
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "hello"}); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+	}
+	for _, conn := range conns {
+		for j := 0; j < 3; j++ { // typing_start, chat, typing_stop
+			var out WSMessage
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if err := conn.ReadJSON(&out); err != nil {
+				t.Fatalf("ReadJSON: %v", err)
+			}
+		}
+	}
+
+	if brain.max != 1 || len(brain.lengths) != 2 || brain.lengths[0] != 1 || brain.lengths[1] != 3 {
+		t.Errorf("want one turn at a time seeing history lengths [1 3], got %d at once, %v", brain.max, brain.lengths)
+	}
+	if len(hist.msgs) != 4 {
+		t.Errorf("want both turns recorded, got %d messages", len(hist.msgs))
+	}
+}
+
+// streamingChatBrain implements router.StreamGenerator. When block is set,
+// GenerateStream emits one delta and waits for cancellation, reporting it on canceled.
+type streamingChatBrain struct {
+	historyChatBrain
+	deltas   []string
+	block    bool
+	canceled chan struct{}
+}
+
+func (s *streamingChatBrain) GenerateStream(ctx context.Context, messages []domain.Message, systemPrompt string, onDelta func(string)) (string, error) {
+	for _, d := range s.deltas {
+		onDelta(d)
+	}
+	if s.block {
+		<-ctx.Done()
+		close(s.canceled)
+		return "", ctx.Err()
+	}
+	return s.GenerateWithContext(ctx, messages, systemPrompt)
+}
+
+func dialTestWS(t *testing.T, brain ChatBrain) (*websocket.Conn, func()) {
+	t.Helper()
+	srv, err := NewServer(&domain.GatewayConfig{Port: 0, Auth: domain.AuthConfig{}}, brain)
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server := httptest.NewServer(srv.Handler())
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		server.Close()
+		t.Fatalf("Dial: %v", err)
+	}
+	return conn, func() { conn.Close(); server.Close() }
+}
+
+func TestHandleWS_WhenStreamRequested_ShouldSendDeltasThenDone(t *testing.T) {
+	brain := &streamingChatBrain{historyChatBrain: historyChatBrain{mockChatBrain: mockChatBrain{response: "Hello"}}, deltas: []string{"Hel", "lo"}}
+	conn, cleanup := dialTestWS(t, brain)
+	defer cleanup()
+
+	if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "hi", ChannelID: "c1", Stream: true}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got []WSMessage
+	for len(got) == 0 || got[len(got)-1].Type != "typing_stop" {
+		var m WSMessage
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("ReadJSON: %v (got %+v)", err, got)
+		}
+		got = append(got, m)
+	}
+
+	want := []WSMessage{
+		{Type: "typing_start", ChannelID: "c1"},
+		{Type: WSTypeChatDelta, Content: "Hel", ChannelID: "c1", Seq: 1},
+		{Type: WSTypeChatDelta, Content: "lo", ChannelID: "c1", Seq: 2},
+		{Type: WSTypeChatDone, Content: "Hello", ChannelID: "c1", Seq: 3},
+		{Type: "typing_stop", ChannelID: "c1"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("want %+v, got %+v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("message %d: want %+v, got %+v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestHandleWS_WhenSocketClosesMidStream_ShouldCancelGeneration(t *testing.T) {
+	brain := &streamingChatBrain{deltas: []string{"partial"}, block: true, canceled: make(chan struct{})}
+	conn, cleanup := dialTestWS(t, brain)
+	defer cleanup()
+
+	if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "hi", Stream: true}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	for i := 0; i < 2; i++ { // typing_start, first chat_delta
+		var m WSMessage
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("ReadJSON: %v", err)
+		}
+	}
+	conn.Close()
+
+	select {
+	case <-brain.canceled:
+	case <-time.After(2 * time.Second):
+		t.Fatal("generation was not canceled after the socket closed")
+	}
+}
+
+// toolEventBrain reports a tool call to the context's tool event handler
+// before answering, the way brain.RunMessages does.
+type toolEventBrain struct{}
+
+func (toolEventBrain) Generate(ctx context.Context, _ string) (string, error) {
+	if report := domain.ToolEventsFrom(ctx); report != nil {
+		report(domain.ToolEvent{Tool: "calc", Input: json.RawMessage(`{"x":2}`)})
+		report(domain.ToolEvent{Tool: "calc", Input: json.RawMessage(`{"x":2}`), Finished: true, Result: "4"})
+	}
+	return "it is 4", nil
+}
+
+func TestHandleWS_WhenBrainCallsTools_ShouldSendToolProgress(t *testing.T) {
+	conn, cleanup := dialTestWS(t, toolEventBrain{})
+	defer cleanup()
+
+	if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "add", ChannelID: "c1"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var got []WSMessage
+	for len(got) == 0 || got[len(got)-1].Type != "typing_stop" {
+		var m WSMessage
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		if err := conn.ReadJSON(&m); err != nil {
+			t.Fatalf("ReadJSON: %v (got %+v)", err, got)
+		}
+		got = append(got, m)
+	}
+
+	if len(got) != 5 || got[1].Type != WSTypeToolStarted || got[2].Type != WSTypeToolFinished || got[3].Content != "it is 4" {
+		t.Fatalf("want typing_start, tool_started, tool_finished, chat, typing_stop; got %+v", got)
+	}
+	started, finished := got[1], got[2]
+	if started.ChannelID != "c1" || started.Tool == nil || started.Tool.Name != "calc" || string(started.Tool.Input) != `{"x":2}` {
+		t.Errorf("unexpected tool_started %+v", started)
+	}
+	if finished.Content != "4" || finished.Tool == nil || finished.Tool.IsError {
+		t.Errorf("unexpected tool_finished %+v", finished)
+	}
+}
+
+// approvingBrain asks gate to approve a shell call before answering, the way
+// brain.Run does for tool calls.
+type approvingBrain struct {
+	gate *approval.Gate
+}
+
+func (b *approvingBrain) Generate(ctx context.Context, prompt string) (string, error) {
+	channelID, p, _ := approval.FromContext(ctx)
+	if err := b.gate.Authorize(ctx, channelID, "shell", json.RawMessage(`{"command":"ls"}`), p); err != nil {
+		return "", err
+	}
+	return "ran ls", nil
+}
+
+func TestHandleWS_WhenToolNeedsApproval_ShouldSendRequestAndContinueOnResponse(t *testing.T) {
+	gate := approval.NewGate()
+	srv, err := NewServer(&domain.GatewayConfig{}, &approvingBrain{gate: gate}, WithApprovals(gate))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+
+	if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "list files", ChannelID: "ops"}); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var msg WSMessage
+	for msg.Type != WSTypeApprovalRequest {
+		if err := conn.ReadJSON(&msg); err != nil {
+			t.Fatalf("ReadJSON: %v", err)
+		}
+	}
+	if msg.ChannelID != "ops" || msg.Approval == nil || msg.Approval.Tool != "shell" || string(msg.Approval.Args) != `{"command":"ls"}` {
+		t.Fatalf("unexpected approval request %+v", msg)
+	}
+
+	// A bad decision is rejected without resolving the request.
+	bad := WSMessage{Type: WSTypeApprovalResponse, ChannelID: "ops", Approval: &WSApproval{ID: msg.Approval.ID, Decision: "maybe"}}
+	if err := conn.WriteJSON(bad); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var errMsg WSMessage
+	if err := conn.ReadJSON(&errMsg); err != nil || errMsg.Type != "error" {
+		t.Fatalf("want error for bad decision, got %+v (%v)", errMsg, err)
+	}
+
+	ok := WSMessage{Type: WSTypeApprovalResponse, ChannelID: "ops", Approval: &WSApproval{ID: msg.Approval.ID, Decision: "approve"}}
+	if err := conn.WriteJSON(ok); err != nil {
+		t.Fatalf("WriteJSON: %v", err)
+	}
+	var reply WSMessage
+	if err := conn.ReadJSON(&reply); err != nil {
+		t.Fatalf("ReadJSON: %v", err)
+	}
+	if reply.Type != "chat" || reply.Content != "ran ls" {
+		t.Errorf("want approved reply, got %+v", reply)
+	}
+}
+
+// toolCallingProvider asks for a calculator call, then answers with the
+// result the brain sends back.
+type toolCallingProvider struct{}
+
+func (toolCallingProvider) Generate(context.Context, string) (string, error) {
+	return "", errors.New("generate should not be called")
+}
+
+func (toolCallingProvider) Chat(_ context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	last := req.Messages[len(req.Messages)-1]
+	if tr, ok := last.Blocks()[0].(domain.ToolResultBlock); ok {
+		return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "result: " + tr.Content}}, StopReason: domain.StopEndTurn}, nil
+	}
+	use := domain.ToolUseBlock{ToolUseID: "tu_1", Name: "calculator", Input: json.RawMessage(`{"operation":"add","a":2,"b":2}`)}
+	return domain.ChatResponse{Content: []domain.ContentBlock{use}, StopReason: domain.StopToolUse}, nil
+}
+
+func TestHandleWS_WhenRoutedToolCallNeedsApproval_ShouldRunItOnlyOnceApproved(t *testing.T) {
+	gate := approval.NewGate(approval.WithRequire("calculator"))
+	reg := tooling.NewToolRegistry()
+	_ = reg.Register(&tooling.CalculatorTool{})
+	b := brain.NewBrain(toolCallingProvider{}, brain.WithTools(brain.NewToolDispatcher(reg)), brain.WithApprovals(gate))
+	srv, err := NewServer(&domain.GatewayConfig{}, b, WithApprovals(gate))
+	if err != nil {
+		t.Fatalf("NewServer: %v", err)
+	}
+	server := httptest.NewServer(srv.Handler())
+	defer server.Close()
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	defer conn.Close()
+	read := func(until string) []WSMessage {
+		var got []WSMessage
+		for len(got) == 0 || got[len(got)-1].Type != until {
+			var m WSMessage
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if err := conn.ReadJSON(&m); err != nil {
+				t.Fatalf("ReadJSON: %v (got %+v)", err, got)
+			}
+			got = append(got, m)
+		}
+		return got
+	}
+
+	for _, tc := range []struct{ decision, want string }{{"deny", "approval_denied"}, {"approve", "result: 4.00"}} {
+		if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "add 2 and 2", ChannelID: "ops"}); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		got := read(WSTypeApprovalRequest)
+		req := got[len(got)-1]
+		if got[len(got)-2].Type != WSTypeToolStarted || req.Approval.Tool != "calculator" || string(req.Approval.Args) != `{"operation":"add","a":2,"b":2}` {
+			t.Fatalf("%s: want tool_started then approval_request, got %+v", tc.decision, got)
+		}
+		resp := WSMessage{Type: WSTypeApprovalResponse, ChannelID: "ops", Approval: &WSApproval{ID: req.Approval.ID, Decision: tc.decision}}
+		if err := conn.WriteJSON(resp); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		got = read("typing_stop")
+		finished, reply := got[0], got[1]
+		if finished.Type != WSTypeToolFinished || finished.Tool.IsError != (tc.decision == "deny") {
+			t.Errorf("%s: unexpected tool_finished %+v", tc.decision, finished)
+		}
+		if reply.Type != "chat" || !strings.Contains(reply.Content, tc.want) {
+			t.Errorf("%s: want reply containing %q, got %+v", tc.decision, tc.want, reply)
+		}
+	}
+}
+
+// modelBrain answers with the model selected in the call's context.
+type modelBrain struct{}
+
+func (modelBrain) Generate(ctx context.Context, _ string) (string, error) {
+	return "model=" + domain.ModelFrom(ctx), nil
+}
+
+func TestHandleWS_WhenChatNamesModel_ShouldSelectItInContext(t *testing.T) {
+	conn, cleanup := dialTestWS(t, modelBrain{})
+	defer cleanup()
+
+	for model, want := range map[string]string{"fast": "model=fast", "": "model="} {
+		if err := conn.WriteJSON(WSMessage{Type: "chat", Content: "hi", Model: model}); err != nil {
+			t.Fatalf("WriteJSON: %v", err)
+		}
+		var m WSMessage
+		for m.Type != "chat" {
+			conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+			if err := conn.ReadJSON(&m); err != nil {
+				t.Fatalf("ReadJSON: %v", err)
+			}
+		}
+		if m.Content != want {
+			t.Errorf("model %q: reply %q, want %q", model, m.Content, want)
+		}
+	}
+}
@@ -6,19 +6,25 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 
+	ironctx "ironclaw/internal/context"
 	"ironclaw/internal/domain"
 )
 
 const anthropicAPIBase = "https://api.anthropic.com/v1/messages"
 
+// defaultAnthropicMaxTokens is max_tokens, which the Messages API requires,
+// for requests that do not set one.
+const defaultAnthropicMaxTokens = 4096
+
 // AnthropicProvider calls the Anthropic Messages API.
 type AnthropicProvider struct {
-	apiKey     string
-	model      string
-	client     *http.Client
-	version    string
-	baseURL    string
+	apiKey      string
+	model       string
+	client      *http.Client
+	version     string
+	baseURL     string
 	marshalFunc func(v interface{}) ([]byte, error) // for testing
 }
 
@@ -35,29 +41,61 @@ func NewAnthropicProvider(apiKey, model string) *AnthropicProvider {
 }
 
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	Messages  []anthropicMessage  `json:"messages"`
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Thinking      *anthropicThinking `json:"thinking,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
 }
 
+// anthropicThinking enables extended thinking with a token budget.
+type anthropicThinking struct {
+	Type         string `json:"type"` // "enabled"
+	BudgetTokens int    `json:"budget_tokens"`
+}
 
-
-// Anthropic accepts content as string or array of blocks; we send a single text block.
+// Anthropic accepts content as string or array of blocks; we always send blocks.
 type anthropicMessage struct {
-	Role    string                   `json:"role"`
-	Content []anthropicContentBlock  `json:"content"`
+	Role    string                  `json:"role"`
+	Content []anthropicContentBlock `json:"content"`
 }
 
+// anthropicContentBlock is the union of the block shapes Anthropic accepts and
+// returns (text, tool_use, tool_result, thinking, redacted_thinking); unused
+// fields are omitted.
 type anthropicContentBlock struct {
-	Type string `json:"type"`
-	Text string `json:"text"`
+	Type      string            `json:"type"`
+	Text      string            `json:"text,omitempty"`
+	ID        string            `json:"id,omitempty"`
+	Name      string            `json:"name,omitempty"`
+	Input     json.RawMessage   `json:"input,omitempty"`
+	ToolUseID string            `json:"tool_use_id,omitempty"`
+	Content   string            `json:"content,omitempty"`
+	IsError   bool              `json:"is_error,omitempty"`
+	Thinking  string            `json:"thinking,omitempty"`
+	Signature string            `json:"signature,omitempty"`
+	Data      string            `json:"data,omitempty"`
+	Source    *domain.MediaType `json:"source,omitempty"` // image
+}
+
+type anthropicTool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	InputSchema json.RawMessage `json:"input_schema"`
 }
 
 type anthropicResponse struct {
-	Content []struct {
-		Type string `json:"type"`
-		Text string `json:"text"`
-	} `json:"content"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
 }
 
 // Generate implements domain.LLMProvider.
@@ -67,41 +105,309 @@ func (p *AnthropicProvider) Generate(ctx context.Context, prompt string) (string
 	}
 	body := anthropicRequest{
 		Model:     p.model,
-		MaxTokens: 1024,
+		MaxTokens: defaultAnthropicMaxTokens,
 		Messages: []anthropicMessage{
 			{Role: "user", Content: []anthropicContentBlock{{Type: "text", Text: prompt}}},
 		},
 	}
+	out, err := p.post(ctx, body)
+	if err != nil {
+		return "", err
+	}
+	reportUsage(ctx, "anthropic", p.model, p.apiKey, out.toChatResponse().Usage)
+	var text string
+	for _, c := range out.Content {
+		if c.Type == "text" {
+			text += c.Text
+		}
+	}
+	return text, nil
+}
+
+// Chat implements domain.ChatProvider using the native Messages API: the system
+// prompt goes in "system", tools in "tools", and tool results are sent as
+// tool_result blocks inside user turns.
+func (p *AnthropicProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	out, err := p.post(ctx, p.buildChatRequest(req))
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	resp := out.toChatResponse()
+	reportUsage(ctx, "anthropic", p.model, p.apiKey, resp.Usage)
+	return resp, nil
+}
+
+// anthropicStreamEvent is the union of the Messages API stream events
+// (message_start, content_block_start/delta/stop, message_delta, error).
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *anthropicResponse     `json:"message"`
+	ContentBlock *anthropicContentBlock `json:"content_block"`
+	Delta        struct {
+		Type        string `json:"type"`
+		Text        string `json:"text"`
+		PartialJSON string `json:"partial_json"`
+		Thinking    string `json:"thinking"`
+		Signature   string `json:"signature"`
+		StopReason  string `json:"stop_reason"`
+	} `json:"delta"`
+	Usage *struct {
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// ChatStream implements domain.StreamingProvider using the Messages API with
+// "stream": true. Text deltas are passed to onDelta; tool_use input arrives as
+// partial JSON and is assembled before the final response is returned.
+func (p *AnthropicProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := p.buildChatRequest(req)
+	body.Stream = true
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out anthropicResponse
+	partial := make(map[int]*strings.Builder)
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal(data, &ev); err != nil {
+			return fmt.Errorf("anthropic decode: %w", err)
+		}
+		switch ev.Type {
+		case "message_start":
+			if ev.Message != nil {
+				out.Usage = ev.Message.Usage
+			}
+		case "content_block_start":
+			for len(out.Content) <= ev.Index {
+				out.Content = append(out.Content, anthropicContentBlock{})
+			}
+			if ev.ContentBlock != nil {
+				out.Content[ev.Index] = *ev.ContentBlock
+			}
+		case "content_block_delta":
+			if ev.Index >= len(out.Content) {
+				return nil
+			}
+			switch ev.Delta.Type {
+			case "text_delta":
+				out.Content[ev.Index].Text += ev.Delta.Text
+				if ev.Delta.Text != "" {
+					onDelta(ev.Delta.Text)
+				}
+			case "input_json_delta":
+				if partial[ev.Index] == nil {
+					partial[ev.Index] = &strings.Builder{}
+				}
+				partial[ev.Index].WriteString(ev.Delta.PartialJSON)
+			case "thinking_delta":
+				out.Content[ev.Index].Thinking += ev.Delta.Thinking
+			case "signature_delta":
+				out.Content[ev.Index].Signature += ev.Delta.Signature
+			}
+		case "message_delta":
+			out.StopReason = ev.Delta.StopReason
+			if ev.Usage != nil {
+				out.Usage.OutputTokens = ev.Usage.OutputTokens
+			}
+		case "message_stop":
+			return errStreamDone
+		case "error":
+			if ev.Error != nil {
+				return newStreamError("anthropic", ev.Error.Type, ev.Error.Message)
+			}
+			return fmt.Errorf("anthropic stream: error event")
+		}
+		return nil
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
+	}
+	for idx, buf := range partial {
+		out.Content[idx].Input = json.RawMessage(buf.String())
+	}
+	chatResp := out.toChatResponse()
+	reportUsage(ctx, "anthropic", p.model, p.apiKey, chatResp.Usage)
+	return chatResp, nil
+}
+
+// buildChatRequest maps a domain.ChatRequest onto a Messages API request.
+// With a thinking budget, max_tokens is raised above the budget as the API
+// requires, and temperature and top_p are left out since thinking does not
+// support them. The Messages API has no JSON mode, so ResponseSchema is left
+// to the prompt.
+func (p *AnthropicProvider) buildChatRequest(req domain.ChatRequest) anthropicRequest {
+	body := anthropicRequest{
+		Model:         p.model,
+		MaxTokens:     defaultAnthropicMaxTokens,
+		System:        req.System,
+		Temperature:   req.Temperature,
+		TopP:          req.TopP,
+		StopSequences: req.Stop,
+	}
+	if req.MaxTokens > 0 {
+		body.MaxTokens = req.MaxTokens
+	}
+	if req.ThinkingBudget > 0 {
+		body.Thinking = &anthropicThinking{Type: "enabled", BudgetTokens: req.ThinkingBudget}
+		if body.MaxTokens <= req.ThinkingBudget {
+			body.MaxTokens = req.ThinkingBudget + defaultAnthropicMaxTokens
+		}
+		body.Temperature, body.TopP = nil, nil
+	}
+	for _, msg := range req.Messages {
+		if msg.Role == domain.RoleSystem {
+			body.System = joinSystem(body.System, ironctx.MessageText(msg))
+			continue
+		}
+		role := "user"
+		if msg.Role == domain.RoleAssistant {
+			role = "assistant"
+		}
+		blocks := toAnthropicBlocks(msg.Blocks())
+		if len(blocks) == 0 {
+			continue
+		}
+		// Anthropic requires alternating roles; merge consecutive same-role turns.
+		if n := len(body.Messages); n > 0 && body.Messages[n-1].Role == role {
+			body.Messages[n-1].Content = append(body.Messages[n-1].Content, blocks...)
+			continue
+		}
+		body.Messages = append(body.Messages, anthropicMessage{Role: role, Content: blocks})
+	}
+	for _, t := range req.Tools {
+		body.Tools = append(body.Tools, anthropicTool{
+			Name:        t.Name,
+			Description: t.Description,
+			InputSchema: t.InputSchema,
+		})
+	}
+	return body
+}
+
+// toChatResponse converts a Messages API response into a domain.ChatResponse.
+func (out *anthropicResponse) toChatResponse() domain.ChatResponse {
+	resp := domain.ChatResponse{
+		StopReason: anthropicStopReason(out.StopReason),
+		Usage: domain.Usage{
+			InputTokens:  out.Usage.InputTokens,
+			OutputTokens: out.Usage.OutputTokens,
+		},
+	}
+	for _, c := range out.Content {
+		switch c.Type {
+		case "text":
+			resp.Content = append(resp.Content, domain.TextBlock{Text: c.Text})
+		case "tool_use":
+			resp.Content = append(resp.Content, domain.ToolUseBlock{ToolUseID: c.ID, Name: c.Name, Input: objectOrEmpty(c.Input)})
+		case "thinking":
+			resp.Content = append(resp.Content, domain.ThinkingBlock{Thinking: c.Thinking, Signature: c.Signature})
+		case "redacted_thinking":
+			resp.Content = append(resp.Content, domain.ThinkingBlock{Data: c.Data})
+		}
+	}
+	return resp
+}
+
+// post sends body to the Messages API and decodes the response.
+func (p *AnthropicProvider) post(ctx context.Context, body anthropicRequest) (*anthropicResponse, error) {
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var out anthropicResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("anthropic decode: %w", err)
+	}
+	return &out, nil
+}
+
+// send marshals body and POSTs it to the Messages API. On success the caller
+// owns resp.Body; non-200 responses are closed and returned
+// as an *APIError.
+func (p *AnthropicProvider) send(ctx context.Context, body anthropicRequest) (*http.Response, error) {
 	raw, err := p.marshalFunc(body)
 	if err != nil {
-		return "", fmt.Errorf("anthropic marshal: %w", err)
+		return nil, fmt.Errorf("anthropic marshal: %w", err)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL, bytes.NewReader(raw))
 	if err != nil {
-		return "", fmt.Errorf("anthropic request: %w", err)
+		return nil, fmt.Errorf("anthropic request: %w", err)
 	}
 	req.Header.Set("x-api-key", p.apiKey)
 	req.Header.Set("anthropic-version", p.version)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("anthropic do: %w", err)
+		return nil, fmt.Errorf("anthropic do: %w", err)
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("anthropic api: %s", resp.Status)
+		return nil, newAPIError("anthropic", resp)
 	}
-	var out anthropicResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("anthropic decode: %w", err)
-	}
-	var text string
-	for _, c := range out.Content {
-		if c.Type == "text" {
-			text += c.Text
+	return resp, nil
+}
+
+// toAnthropicBlocks maps domain content blocks to Anthropic content blocks.
+func toAnthropicBlocks(blocks []domain.ContentBlock) []anthropicContentBlock {
+	out := make([]anthropicContentBlock, 0, len(blocks))
+	for _, block := range blocks {
+		switch b := block.(type) {
+		case domain.TextBlock:
+			if b.Text == "" {
+				continue
+			}
+			out = append(out, anthropicContentBlock{Type: "text", Text: b.Text})
+		case domain.ImageBlock:
+			src := b.Source
+			out = append(out, anthropicContentBlock{Type: "image", Source: &src})
+		case domain.ToolUseBlock:
+			out = append(out, anthropicContentBlock{Type: "tool_use", ID: b.ToolUseID, Name: b.Name, Input: objectOrEmpty(b.Input)})
+		case domain.ToolResultBlock:
+			out = append(out, anthropicContentBlock{Type: "tool_result", ToolUseID: b.ToolUseID, Content: b.Content, IsError: b.IsError})
+		case domain.ThinkingBlock:
+			if b.Data != "" {
+				out = append(out, anthropicContentBlock{Type: "redacted_thinking", Data: b.Data})
+				continue
+			}
+			out = append(out, anthropicContentBlock{Type: "thinking", Thinking: b.Thinking, Signature: b.Signature})
 		}
 	}
-	return text, nil
+	return out
 }
 
-var _ domain.LLMProvider = (*AnthropicProvider)(nil)
+// anthropicStopReason maps Anthropic's stop_reason onto domain.StopReason.
+func anthropicStopReason(reason string) domain.StopReason {
+	switch reason {
+	case "tool_use":
+		return domain.StopToolUse
+	case "max_tokens":
+		return domain.StopMaxTokens
+	case "stop_sequence":
+		return domain.StopSequence
+	default:
+		return domain.StopEndTurn
+	}
+}
+
+var (
+	_ domain.LLMProvider       = (*AnthropicProvider)(nil)
+	_ domain.ChatProvider      = (*AnthropicProvider)(nil)
+	_ domain.StreamingProvider = (*AnthropicProvider)(nil)
+)
@@ -3,6 +3,7 @@ package llm
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -256,4 +257,282 @@ func failingMarshalFunc(v interface{}) ([]byte, error) {
 }
 
 
-var _ domain.LLMProvider = (*AnthropicProvider)(nil)
\ No newline at end of file
+var _ domain.LLMProvider = (*AnthropicProvider)(nil)
+func TestAnthropicProvider_Chat_ShouldSendSystemMessagesAndTools(t *testing.T) {
+	var got map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn","usage":{"input_tokens":3,"output_tokens":1}}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	req := domain.ChatRequest{
+		System: "be brief",
+		Messages: []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "list files"),
+			domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: "tu_1", Name: "shell", Input: json.RawMessage(`{"command":"ls"}`)}),
+			domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: "tu_1", Content: "a.txt"}),
+		},
+		Tools:     []domain.ToolDefinition{{Name: "shell", Description: "run", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+		MaxTokens: 256,
+	}
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "ok" || resp.Usage.InputTokens != 3 || resp.Usage.OutputTokens != 1 {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if got["system"] != "be brief" {
+		t.Errorf("system: got %v", got["system"])
+	}
+	if got["max_tokens"] != float64(256) {
+		t.Errorf("max_tokens: got %v", got["max_tokens"])
+	}
+	msgs := got["messages"].([]any)
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(msgs))
+	}
+	third := msgs[2].(map[string]any)
+	if third["role"] != "user" {
+		t.Errorf("tool result should be sent as a user turn, got %v", third["role"])
+	}
+	block := third["content"].([]any)[0].(map[string]any)
+	if block["type"] != "tool_result" || block["tool_use_id"] != "tu_1" {
+		t.Errorf("unexpected tool_result block: %v", block)
+	}
+	tools := got["tools"].([]any)
+	if len(tools) != 1 || tools[0].(map[string]any)["name"] != "shell" {
+		t.Errorf("unexpected tools: %v", tools)
+	}
+}
+
+func TestAnthropicProvider_Chat_WhenToolUseReturned_ShouldReturnToolUseBlock(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content":[{"type":"text","text":"checking"},{"type":"tool_use","id":"tu_9","name":"shell","input":{"command":"pwd"}}],"stop_reason":"tool_use"}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "where am i")}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.StopReason != domain.StopToolUse {
+		t.Errorf("expected tool_use stop reason, got %q", resp.StopReason)
+	}
+	uses := resp.ToolUses()
+	if len(uses) != 1 || uses[0].ToolUseID != "tu_9" || uses[0].Name != "shell" || string(uses[0].Input) != `{"command":"pwd"}` {
+		t.Errorf("unexpected tool uses: %+v", uses)
+	}
+}
+
+func TestAnthropicProvider_Chat_WhenConsecutiveSameRole_ShouldMergeTurns(t *testing.T) {
+	var got anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"content":[]}`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{
+		domain.NewTextMessage(domain.RoleSystem, "extra rules"),
+		domain.NewTextMessage(domain.RoleUser, "one"),
+		domain.NewTextMessage(domain.RoleUser, "two"),
+	}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got.System != "extra rules" {
+		t.Errorf("system role messages should be folded into system, got %q", got.System)
+	}
+	if len(got.Messages) != 1 || len(got.Messages[0].Content) != 2 {
+		t.Errorf("expected one merged user turn with 2 blocks, got %+v", got.Messages)
+	}
+}
+
+func TestAnthropicProvider_Chat_WhenAPIError_ShouldReturnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err == nil || !strings.Contains(err.Error(), "502") {
+		t.Errorf("expected 502 error, got %v", err)
+	}
+}
+
+func TestAnthropicProvider_ChatStream_ShouldEmitTextDeltasAndAssembleToolUse(t *testing.T) {
+	var got anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"usage":{"input_tokens":7}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"shell","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"command\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"ls\"}"}}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":12}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}},
+		func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if !got.Stream {
+		t.Error("expected stream:true in request")
+	}
+	if len(deltas) != 2 || deltas[0] != "Hel" || deltas[1] != "lo" {
+		t.Errorf("unexpected deltas %q", deltas)
+	}
+	if resp.Text() != "Hello" || resp.StopReason != domain.StopToolUse {
+		t.Errorf("unexpected response text %q stop %q", resp.Text(), resp.StopReason)
+	}
+	uses := resp.ToolUses()
+	if len(uses) != 1 || uses[0].ToolUseID != "tu_1" || string(uses[0].Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected tool uses: %+v", uses)
+	}
+	if resp.Usage.InputTokens != 7 || resp.Usage.OutputTokens != 12 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestAnthropicProvider_ChatStream_WhenErrorEvent_ShouldReturnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "overloaded_error") {
+		t.Errorf("expected overloaded error, got %v", err)
+	}
+}
+
+func TestAnthropicProvider_Chat_ShouldReportUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"content":[{"type":"text","text":"hi"}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":4}}`))
+	}))
+	defer server.Close()
+	p := NewAnthropicProvider("test-key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+	ctx, rec := recordingContext()
+
+	if _, err := p.Chat(ctx, domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}}); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(rec.calls) != 1 {
+		t.Fatalf("calls = %d, want 1", len(rec.calls))
+	}
+	if c := rec.calls[0]; c.Provider != "anthropic" || c.Model != "claude-3" || c.Key == "" || c.InputTokens != 12 || c.OutputTokens != 4 {
+		t.Errorf("call = %+v", c)
+	}
+}
+
+func TestAnthropicProvider_buildChatRequest_ShouldMapGenerationParams(t *testing.T) {
+	p := NewAnthropicProvider("key", "claude-3")
+	temp := 0.3
+
+	body := p.buildChatRequest(domain.ChatRequest{Temperature: &temp, Stop: []string{"END"}})
+	if body.MaxTokens != defaultAnthropicMaxTokens || *body.Temperature != 0.3 || body.StopSequences[0] != "END" || body.Thinking != nil {
+		t.Errorf("unexpected request %+v", body)
+	}
+
+	body = p.buildChatRequest(domain.ChatRequest{MaxTokens: 1000, Temperature: &temp, ThinkingBudget: 2000})
+	if body.Thinking == nil || body.Thinking.BudgetTokens != 2000 || body.MaxTokens <= 2000 || body.Temperature != nil {
+		t.Errorf("thinking request %+v, want budget 2000, max_tokens above it and no temperature", body)
+	}
+}
+
+func TestAnthropicProvider_Chat_WhenThinking_ShouldReturnAndResendThinkingBlocks(t *testing.T) {
+	var sent anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&sent)
+		w.Write([]byte(`{"content":[{"type":"thinking","thinking":"hmm","signature":"sig"},{"type":"redacted_thinking","data":"xyz"},{"type":"text","text":"done"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "done" || len(resp.Content) != 3 || resp.Content[0] != (domain.ThinkingBlock{Thinking: "hmm", Signature: "sig"}) {
+		t.Fatalf("unexpected response %+v", resp.Content)
+	}
+
+	_, _ = p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{
+		domain.NewTextMessage(domain.RoleUser, "hi"),
+		domain.NewMessage(domain.RoleAssistant, resp.Content...),
+	}})
+	blocks := sent.Messages[1].Content
+	if len(blocks) != 3 || blocks[0].Type != "thinking" || blocks[0].Signature != "sig" || blocks[1].Type != "redacted_thinking" || blocks[1].Data != "xyz" {
+		t.Errorf("resent blocks = %+v", blocks)
+	}
+}
+
+func TestAnthropicProvider_buildChatRequest_ShouldMapImageBlocks(t *testing.T) {
+	p := NewAnthropicProvider("key", "claude-3")
+	img := domain.NewImageBlock("image/png", []byte("png"))
+
+	raw, _ := json.Marshal(p.buildChatRequest(domain.ChatRequest{Messages: []domain.Message{
+		domain.NewMessage(domain.RoleUser, img, domain.TextBlock{Text: "what is it?"}),
+	}}))
+	want := `{"type":"image","source":{"type":"base64","media_type":"image/png","data":"cG5n"}}`
+	if !strings.Contains(string(raw), want) {
+		t.Errorf("request %s missing %s", raw, want)
+	}
+}
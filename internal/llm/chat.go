@@ -0,0 +1,85 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+// joinSystem appends extra to a system prompt, separated by a blank line.
+func joinSystem(system, extra string) string {
+	if extra == "" {
+		return system
+	}
+	if system == "" {
+		return extra
+	}
+	return system + "\n\n" + extra
+}
+
+// reportUsage reports the token usage of a successful call to the
+// usage.Recorder in ctx, attributed to the provider, model and API key.
+func reportUsage(ctx context.Context, provider, model, apiKey string, u domain.Usage) {
+	usage.Report(ctx, usage.Call{
+		Provider:     provider,
+		Model:        model,
+		Key:          usage.KeyID(apiKey),
+		InputTokens:  u.InputTokens,
+		OutputTokens: u.OutputTokens,
+	})
+}
+
+// toolUseID returns a stable synthetic tool_use ID for providers (Gemini,
+// Ollama) whose function calls carry no identifier of their own.
+func toolUseID(turn, idx int) string {
+	return fmt.Sprintf("call_%d_%d", turn, idx)
+}
+
+// objectOrEmpty returns raw if it is a JSON object, otherwise "{}". Some
+// providers reject null or empty tool arguments.
+func objectOrEmpty(raw json.RawMessage) json.RawMessage {
+	var obj map[string]json.RawMessage
+	if len(raw) == 0 || json.Unmarshal(raw, &obj) != nil || obj == nil {
+		return json.RawMessage("{}")
+	}
+	return raw
+}
+
+// imageFallbackText stands in for an image sent to a model that cannot view
+// images, so the model can tell the user rather than answer as if it saw it.
+const imageFallbackText = "[The user attached an image, but this model cannot view images.]"
+
+// withoutImages returns req with every ImageBlock replaced by
+// imageFallbackText, for providers without vision. req is not modified.
+func withoutImages(req domain.ChatRequest) domain.ChatRequest {
+	var msgs []domain.Message
+	for i, msg := range req.Messages {
+		blocks := msg.Blocks()
+		var replaced []domain.ContentBlock
+		for j, block := range blocks {
+			if _, ok := block.(domain.ImageBlock); !ok {
+				continue
+			}
+			if replaced == nil {
+				replaced = append([]domain.ContentBlock(nil), blocks...)
+			}
+			replaced[j] = domain.TextBlock{Text: imageFallbackText}
+		}
+		if replaced == nil {
+			continue
+		}
+		if msgs == nil {
+			msgs = append([]domain.Message(nil), req.Messages...)
+		}
+		msg.ContentBlocks = replaced
+		msg.RawContent, _ = domain.EncodeContentBlocks(replaced)
+		msgs[i] = msg
+	}
+	if msgs != nil {
+		req.Messages = msgs
+	}
+	return req
+}
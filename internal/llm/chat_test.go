@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+// usageRecorder collects the calls providers report.
+type usageRecorder struct {
+	mu    sync.Mutex
+	calls []usage.Call
+}
+
+func (r *usageRecorder) RecordCall(_ context.Context, c usage.Call) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, c)
+}
+
+// recordingContext returns a context whose provider calls are reported to
+// the returned recorder.
+func recordingContext() (context.Context, *usageRecorder) {
+	r := &usageRecorder{}
+	return usage.WithRecorder(context.Background(), r), r
+}
+
+func TestReportUsage_ShouldReportKeyIDNotKey(t *testing.T) {
+	ctx, rec := recordingContext()
+
+	reportUsage(ctx, "openai", "gpt-4o", "sk-0123456789abcdefWXYZ", domain.Usage{InputTokens: 7, OutputTokens: 3})
+
+	if len(rec.calls) != 1 {
+		t.Fatalf("calls = %d, want 1", len(rec.calls))
+	}
+	want := usage.Call{Provider: "openai", Model: "gpt-4o", Key: "…WXYZ", InputTokens: 7, OutputTokens: 3}
+	if rec.calls[0] != want {
+		t.Errorf("call = %+v, want %+v", rec.calls[0], want)
+	}
+}
@@ -0,0 +1,213 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxErrorBody caps how much of an error response body APIError keeps.
+const maxErrorBody = 4 << 10
+
+// APIError is a failed call to a provider's HTTP API: a non-200 response, or
+// an error event in a stream. Use errors.As to inspect it; retry and the key
+// pool classify failures by StatusCode and honour RetryAfter.
+type APIError struct {
+	Provider   string        // e.g. "anthropic"
+	StatusCode int           // HTTP status (for stream error events, the equivalent status)
+	Status     string        // e.g. "429 Too Many Requests"
+	RequestID  string        // Provider request ID, when sent
+	Type       string        // Provider error type (e.g. "rate_limit_error"), when parsed
+	Message    string        // Provider error message, when parsed
+	Body       string        // Raw error body, truncated to 4 KiB
+	RetryAfter time.Duration // Wait requested by Retry-After or rate-limit reset headers; 0 when absent
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s api: %s", e.Provider, e.Status)
+	if e.Type != "" {
+		sb.WriteString(": " + e.Type)
+	}
+	switch {
+	case e.Message != "":
+		sb.WriteString(": " + e.Message)
+	case e.Body != "":
+		body := e.Body
+		if len(body) > 200 {
+			body = body[:200] + "…"
+		}
+		sb.WriteString(": " + body)
+	}
+	if e.RequestID != "" {
+		fmt.Fprintf(&sb, " (request %s)", e.RequestID)
+	}
+	return sb.String()
+}
+
+// HTTPStatus returns StatusCode; retry uses it to classify the error.
+func (e *APIError) HTTPStatus() int { return e.StatusCode }
+
+// RetryDelay returns RetryAfter; retry waits at least this long before the
+// next attempt.
+func (e *APIError) RetryDelay() time.Duration { return e.RetryAfter }
+
+// newAPIError builds the APIError for a non-200 response and closes its body.
+func newAPIError(provider string, resp *http.Response) *APIError {
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	e := &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RequestID:  firstHeader(resp.Header, "request-id", "x-request-id"),
+		Body:       strings.TrimSpace(string(raw)),
+		RetryAfter: retryAfter(resp.Header, timeNow(), resp.StatusCode == http.StatusTooManyRequests),
+	}
+	if e.Status == "" {
+		e.Status = fmt.Sprintf("%d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	e.Type, e.Message = parseErrorBody(raw)
+	return e
+}
+
+// streamErrorStatus maps the error types providers send in stream error
+// events to the HTTP status a plain response would have had.
+var streamErrorStatus = map[string]int{
+	"invalid_request_error": http.StatusBadRequest,
+	"authentication_error":  http.StatusUnauthorized,
+	"permission_error":      http.StatusForbidden,
+	"not_found_error":       http.StatusNotFound,
+	"rate_limit_error":      http.StatusTooManyRequests,
+	"api_error":             http.StatusInternalServerError,
+	"overloaded_error":      529,
+}
+
+// newStreamError builds the APIError for an error event received mid-stream.
+func newStreamError(provider, typ, message string) *APIError {
+	code, ok := streamErrorStatus[typ]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+	status := http.StatusText(code)
+	if code == 529 {
+		status = "Overloaded"
+	}
+	return &APIError{
+		Provider:   provider,
+		StatusCode: code,
+		Status:     fmt.Sprintf("%d %s", code, status),
+		Type:       typ,
+		Message:    message,
+	}
+}
+
+// parseErrorBody extracts the error type and message from the JSON error
+// shapes providers use: {"error":{"type":..,"message":..}} (Anthropic,
+// OpenAI, OpenRouter), {"error":{"status":..,"message":..}} (Gemini) and
+// {"error":".."} (Ollama).
+func parseErrorBody(raw []byte) (typ, message string) {
+	var body struct {
+		Error json.RawMessage `json:"error"`
+	}
+	if json.Unmarshal(raw, &body) != nil || len(body.Error) == 0 {
+		return "", ""
+	}
+	var obj struct {
+		Type    string `json:"type"`
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(body.Error, &obj) == nil {
+		if obj.Type == "" {
+			obj.Type = obj.Status
+		}
+		return obj.Type, obj.Message
+	}
+	var msg string
+	if json.Unmarshal(body.Error, &msg) == nil {
+		return "", msg
+	}
+	return "", ""
+}
+
+func firstHeader(h http.Header, names ...string) string {
+	for _, name := range names {
+		if v := h.Get(name); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// rateLimitResetHeaders report when a provider's rate limits reset, in the
+// formats parseReset accepts.
+var rateLimitResetHeaders = []string{
+	"anthropic-ratelimit-requests-reset",      // RFC 3339 time
+	"anthropic-ratelimit-tokens-reset",        // RFC 3339 time
+	"anthropic-ratelimit-input-tokens-reset",  // RFC 3339 time
+	"anthropic-ratelimit-output-tokens-reset", // RFC 3339 time
+	"x-ratelimit-reset-requests",              // Duration, e.g. "1s", "6m0s" (OpenAI)
+	"x-ratelimit-reset-tokens",                // Duration (OpenAI)
+	"x-ratelimit-reset",                       // Unix time in milliseconds (OpenRouter)
+}
+
+// retryAfter returns how long the server asked the client to wait: the
+// retry-after-ms or Retry-After header when present, otherwise, for rate
+// limited responses, the latest rate-limit reset. Returns 0 when there is no
+// such header.
+func retryAfter(h http.Header, now time.Time, rateLimited bool) time.Duration {
+	if v := h.Get("retry-after-ms"); v != "" {
+		if ms, err := strconv.ParseFloat(v, 64); err == nil && ms > 0 {
+			return time.Duration(ms * float64(time.Millisecond))
+		}
+	}
+	if v := h.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return max(time.Duration(secs)*time.Second, 0)
+		}
+		if t, err := http.ParseTime(v); err == nil {
+			return max(t.Sub(now), 0)
+		}
+	}
+	if !rateLimited {
+		return 0
+	}
+	var wait time.Duration
+	for _, name := range rateLimitResetHeaders {
+		if v := h.Get(name); v != "" {
+			wait = max(wait, parseReset(v, now))
+		}
+	}
+	return wait
+}
+
+// parseReset parses a rate-limit reset value: a Go duration, an RFC 3339
+// time, a Unix time in seconds or milliseconds, or a number of seconds.
+func parseReset(v string, now time.Time) time.Duration {
+	if n, err := strconv.ParseFloat(v, 64); err == nil {
+		switch {
+		case n > 1e12: // Unix milliseconds
+			return max(time.UnixMilli(int64(n)).Sub(now), 0)
+		case n > 1e9: // Unix seconds
+			return max(time.Unix(int64(n), 0).Sub(now), 0)
+		default:
+			return max(time.Duration(n*float64(time.Second)), 0)
+		}
+	}
+	if d, err := time.ParseDuration(v); err == nil {
+		return max(d, 0)
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// timeNow is the clock for Retry-After dates and reset times. Package-level
+// var for test injection.
+var timeNow = time.Now
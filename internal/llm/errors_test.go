@@ -0,0 +1,114 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/retry"
+)
+
+func TestOpenAIProvider_Generate_WhenRateLimited_ShouldReturnAPIErrorWithRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "7")
+		w.Header().Set("x-request-id", "req_123")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"type":"requests","message":"Rate limit reached for gpt-4o"}}`))
+	}))
+	defer server.Close()
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+
+	_, err := p.Generate(context.Background(), "hi")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.Provider != "openai" || apiErr.StatusCode != 429 || apiErr.RequestID != "req_123" || apiErr.RetryAfter != 7*time.Second {
+		t.Errorf("APIError = %+v", apiErr)
+	}
+	if apiErr.Message != "Rate limit reached for gpt-4o" || !strings.Contains(err.Error(), "Rate limit reached") {
+		t.Errorf("message not parsed: %v", err)
+	}
+	if !retry.IsRetryable(err) {
+		t.Error("429 APIError should be retryable")
+	}
+}
+
+func TestAPIError_WhenClientError_ShouldNotBeRetryable(t *testing.T) {
+	for _, code := range []int{400, 401, 403, 404} {
+		if retry.IsRetryable(&APIError{Provider: "anthropic", StatusCode: code}) {
+			t.Errorf("%d should not be retryable", code)
+		}
+	}
+	for _, code := range []int{429, 500, 502, 503, 504, 529} {
+		if !retry.IsRetryable(&APIError{Provider: "anthropic", StatusCode: code}) {
+			t.Errorf("%d should be retryable", code)
+		}
+	}
+}
+
+func TestNewAPIError_ShouldParseProviderErrorBodies(t *testing.T) {
+	for _, tc := range []struct {
+		body, typ, msg string
+	}{
+		{`{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`, "overloaded_error", "Overloaded"},
+		{`{"error":{"code":400,"message":"API key not valid","status":"INVALID_ARGUMENT"}}`, "INVALID_ARGUMENT", "API key not valid"},
+		{`{"error":"model 'llama9' not found"}`, "", "model 'llama9' not found"},
+		{`<html>bad gateway</html>`, "", ""},
+	} {
+		rec := httptest.NewRecorder()
+		rec.WriteHeader(http.StatusBadGateway)
+		rec.WriteString(tc.body)
+		e := newAPIError("p", rec.Result())
+		if e.Type != tc.typ || e.Message != tc.msg || e.Body != tc.body {
+			t.Errorf("body %s: got type %q message %q body %q", tc.body, e.Type, e.Message, e.Body)
+		}
+	}
+}
+
+func TestRetryAfter_ShouldParseHeaderFormats(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name        string
+		header      http.Header
+		rateLimited bool
+		want        time.Duration
+	}{
+		{"seconds", http.Header{"Retry-After": {"30"}}, false, 30 * time.Second},
+		{"http date", http.Header{"Retry-After": {now.Add(time.Minute).Format(http.TimeFormat)}}, false, time.Minute},
+		{"milliseconds", http.Header{"Retry-After-Ms": {"1500"}}, false, 1500 * time.Millisecond},
+		{"openai reset", http.Header{"X-Ratelimit-Reset-Requests": {"1s"}, "X-Ratelimit-Reset-Tokens": {"6m0s"}}, true, 6 * time.Minute},
+		{"anthropic reset", http.Header{"Anthropic-Ratelimit-Tokens-Reset": {now.Add(20 * time.Second).Format(time.RFC3339)}}, true, 20 * time.Second},
+		{"openrouter reset", http.Header{"X-Ratelimit-Reset": {"1772366410000"}}, true, 10 * time.Second},
+		{"reset ignored unless rate limited", http.Header{"X-Ratelimit-Reset-Tokens": {"6m0s"}}, false, 0},
+		{"none", http.Header{}, true, 0},
+	} {
+		if got := retryAfter(tc.header, now, tc.rateLimited); got != tc.want {
+			t.Errorf("%s: retryAfter = %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestAnthropicProvider_ChatStream_WhenOverloadedEvent_ShouldReturnRetryableAPIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n"))
+	}))
+	defer server.Close()
+	p := NewAnthropicProvider("key", "claude-3")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.ChatStream(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}}, func(string) {})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 529 || !retry.IsRetryable(err) {
+		t.Errorf("err = %v, want retryable 529 APIError", err)
+	}
+}
@@ -5,8 +5,12 @@ import (
 	"strings"
 	"time"
 
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/ratelimit"
 	"ironclaw/internal/retry"
+	"ironclaw/internal/usage"
 )
 
 // defaultCooldownDuration is the time a rate-limited key stays in cooldown.
@@ -16,21 +20,33 @@ const defaultCooldownDuration = 60 * time.Second
 type SecretGetter func(name string) (string, error)
 
 // NewProvider returns an LLMProvider for the given agents config, optionally wrapped with retry logic.
-// Provider may be "local", "openai", "anthropic", "openrouter", "ollama", or "gemini". Empty provider defaults to "local".
-// getSecret is used to resolve API keys for openai/anthropic/openrouter/gemini.
+// Provider may be "local", "openai", "anthropic", "openrouter", "ollama", "gemini", "replay" (see
+// agents.Replay), or the name of an entry in agents.Providers. Empty provider defaults to "local".
+// getSecret is used to resolve API keys for openai/anthropic/openrouter/gemini and named providers.
 // retryCfg, if non-nil, wraps the provider with exponential-backoff retry on transient errors.
 func NewProvider(agents *domain.AgentsConfig, getSecret SecretGetter, retryCfg ...*domain.RetryConfig) (domain.LLMProvider, error) {
-	base, err := newBaseProvider(agents, getSecret)
+	return newProvider(agents, getSecret, nil, retryCfg...)
+}
+
+// newProvider is NewProvider with the limits of limits applied inside the
+// retries, so every attempt waits for capacity. A nil limits sets none.
+func newProvider(agents *domain.AgentsConfig, getSecret SecretGetter, limits *ratelimit.Set, retryCfg ...*domain.RetryConfig) (domain.LLMProvider, error) {
+	base, err := newBaseProvider(agents, getSecret, limits)
 	if err != nil {
 		return nil, err
 	}
-	return wrapWithRetry(base, retryCfg...), nil
+	name := "local"
+	if agents != nil && agents.Provider != "" {
+		name = agents.Provider
+	}
+	return wrapWithRetry(ratelimit.Wrap(limits.Provider(name), base), retryCfg...), nil
 }
 
 // newBaseProvider creates the raw LLM provider without retry wrapping.
 // When a secret contains comma-separated keys, a KeyPoolProvider is created with
-// round-robin rotation and 429-cooldown support.
-func newBaseProvider(agents *domain.AgentsConfig, getSecret SecretGetter) (domain.LLMProvider, error) {
+// round-robin rotation and 429-cooldown support; each key gets the per-key
+// limits of limits.
+func newBaseProvider(agents *domain.AgentsConfig, getSecret SecretGetter, limits *ratelimit.Set) (domain.LLMProvider, error) {
 	if agents == nil {
 		return NewLocalProvider("Local: "), nil
 	}
@@ -42,26 +58,74 @@ func newBaseProvider(agents *domain.AgentsConfig, getSecret SecretGetter) (domai
 	case "local":
 		return NewLocalProvider("Local: "), nil
 	case "openai":
-		return resolveKeyedProvider("openai", "openai_api_key", getSecret, func(key string) domain.LLMProvider {
+		return resolveKeyedProvider("openai", "openai_api_key", getSecret, limits, func(key string) domain.LLMProvider {
 			return NewOpenAIProvider(key, agents.DefaultModel)
 		})
 	case "anthropic":
-		return resolveKeyedProvider("anthropic", "anthropic_api_key", getSecret, func(key string) domain.LLMProvider {
+		return resolveKeyedProvider("anthropic", "anthropic_api_key", getSecret, limits, func(key string) domain.LLMProvider {
 			return NewAnthropicProvider(key, agents.DefaultModel)
 		})
 	case "openrouter":
-		return resolveKeyedProvider("openrouter", "openrouter_api_key", getSecret, func(key string) domain.LLMProvider {
+		return resolveKeyedProvider("openrouter", "openrouter_api_key", getSecret, limits, func(key string) domain.LLMProvider {
 			return NewOpenRouterProvider(key, agents.DefaultModel)
 		})
 	case "ollama":
 		return NewOllamaProvider(agents.DefaultModel), nil
 	case "gemini":
-		return resolveKeyedProvider("gemini", "gemini_api_key", getSecret, func(key string) domain.LLMProvider {
+		return resolveKeyedProvider("gemini", "gemini_api_key", getSecret, limits, func(key string) domain.LLMProvider {
 			return NewGeminiProvider(key, agents.DefaultModel)
 		})
+	case "replay":
+		return newReplayProvider(agents, getSecret, limits)
 	default:
-		return nil, fmt.Errorf("unknown LLM provider %q (use: local, openai, anthropic, openrouter, ollama, gemini)", provider)
+		if cfg, ok := agents.Providers[provider]; ok {
+			return newNamedProvider(provider, cfg, agents.DefaultModel, getSecret, limits)
+		}
+		return nil, fmt.Errorf("unknown LLM provider %q (use: local, openai, anthropic, openrouter, ollama, gemini, replay, or a name from agents.providers)", provider)
+	}
+}
+
+// newReplayProvider creates the "replay" provider from agents.Replay. When it
+// names a provider to record, that provider is built from the rest of agents.
+func newReplayProvider(agents *domain.AgentsConfig, getSecret SecretGetter, limits *ratelimit.Set) (domain.LLMProvider, error) {
+	cfg := agents.Replay
+	if cfg == nil {
+		return nil, fmt.Errorf("replay provider: agents.replay not set")
+	}
+	var upstream domain.LLMProvider
+	if cfg.Record != "" {
+		if cfg.Record == "replay" {
+			return nil, fmt.Errorf("replay provider: cannot record from itself")
+		}
+		rec := *agents
+		rec.Provider = cfg.Record
+		p, err := newBaseProvider(&rec, getSecret, limits)
+		if err != nil {
+			return nil, fmt.Errorf("replay provider: record: %w", err)
+		}
+		upstream = ratelimit.Wrap(limits.Provider(cfg.Record), p)
 	}
+	p, err := NewReplayProvider(*cfg, agents.DefaultModel, upstream)
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// newNamedProvider creates the provider for a named agents.Providers entry.
+func newNamedProvider(name string, cfg domain.ProviderConfig, model string, getSecret SecretGetter, limits *ratelimit.Set) (domain.LLMProvider, error) {
+	if cfg.Type != domain.ProviderTypeOpenAICompatible {
+		return nil, fmt.Errorf("provider %q: unknown type %q (use: %s)", name, cfg.Type, domain.ProviderTypeOpenAICompatible)
+	}
+	if cfg.BaseURL == "" {
+		return nil, fmt.Errorf("provider %q: baseUrl not set", name)
+	}
+	if cfg.APIKeySecret == "" {
+		return NewOpenAICompatibleProvider(name, cfg, "", model), nil
+	}
+	return resolveKeyedProvider(name, cfg.APIKeySecret, getSecret, limits, func(key string) domain.LLMProvider {
+		return NewOpenAICompatibleProvider(name, cfg, key, model)
+	})
 }
 
 // splitKeys splits a raw secret value by commas, trims whitespace, and filters empty entries.
@@ -83,8 +147,9 @@ var newKeyPoolFunc = NewKeyPool
 // resolveKeyedProvider fetches the secret, splits it into one or more keys, and returns either
 // a single provider (one key) or a KeyPoolProvider (multiple keys).
 // providerName is used in error messages. secretName is the key to fetch from secrets.
-// makeProvider is a factory function that creates a provider for a single API key.
-func resolveKeyedProvider(providerName, secretName string, getSecret SecretGetter, makeProvider func(key string) domain.LLMProvider) (domain.LLMProvider, error) {
+// makeProvider is a factory function that creates a provider for a single API key; each
+// is wrapped with the per-key limits limits sets for providerName.
+func resolveKeyedProvider(providerName, secretName string, getSecret SecretGetter, limits *ratelimit.Set, makeProvider func(key string) domain.LLMProvider) (domain.LLMProvider, error) {
 	raw, err := getSecret(secretName)
 	if err != nil {
 		return nil, err
@@ -93,8 +158,11 @@ func resolveKeyedProvider(providerName, secretName string, getSecret SecretGette
 	if len(keys) == 0 {
 		return nil, fmt.Errorf("%s provider: API key not set (store with: ironclaw secrets set %s <key>)", providerName, secretName)
 	}
+	keyed := func(key string) domain.LLMProvider {
+		return ratelimit.Wrap(limits.Key(providerName, usage.KeyID(key)), makeProvider(key))
+	}
 	if len(keys) == 1 {
-		return makeProvider(keys[0]), nil
+		return keyed(keys[0]), nil
 	}
 	// Multiple keys: create a KeyPoolProvider
 	pool, err := newKeyPoolFunc(keys, defaultCooldownDuration)
@@ -103,13 +171,14 @@ func resolveKeyedProvider(providerName, secretName string, getSecret SecretGette
 	}
 	providers := make([]domain.LLMProvider, len(keys))
 	for i, k := range keys {
-		providers[i] = makeProvider(k)
+		providers[i] = keyed(k)
 	}
 	return NewKeyPoolProvider(pool, providers)
 }
 
 // NewFallbackProviders creates LLM providers for each fallback config entry.
 // Failed fallback configurations are silently skipped (logged but not fatal).
+// Named providers are not known here; NewGuardedProviders resolves them.
 func NewFallbackProviders(fallbacks []domain.FallbackConfig, getSecret SecretGetter, retryCfg ...*domain.RetryConfig) []domain.LLMProvider {
 	var providers []domain.LLMProvider
 	for _, fb := range fallbacks {
@@ -127,6 +196,64 @@ func NewFallbackProviders(fallbacks []domain.FallbackConfig, getSecret SecretGet
 	return providers
 }
 
+// NewGuardedProviders builds the primary provider of agents and its fallbacks
+// like NewProvider and NewFallbackProviders, then wraps each, outside its
+// retries, with its circuit in breakers (named by ProviderName) so failover
+// skips providers that keep failing. A nil breakers leaves them unwrapped.
+// Inside the retries, each provider and API key waits for the capacity its
+// limiter in limits allows; a nil limits sets no limits. Outside everything,
+// responses answers the requests it has cached; a nil responses caches
+// nothing. For a non-nil agents the primary is a *ModelRouter, so calls may
+// select another configured model or profile with domain.WithModel.
+func NewGuardedProviders(agents *domain.AgentsConfig, getSecret SecretGetter, breakers *breaker.Set, limits *ratelimit.Set, responses *cache.Cache, retryCfg ...*domain.RetryConfig) (domain.LLMProvider, []domain.LLMProvider, error) {
+	if agents == nil {
+		primary, err := newProvider(nil, getSecret, limits, retryCfg...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return responses.Wrap(ProviderName("", ""), primary), nil, nil
+	}
+	guard := func(provider, model string, p domain.LLMProvider) domain.LLMProvider {
+		name := ProviderName(provider, model)
+		if breakers != nil {
+			p = breakers.Wrap(name, p)
+		}
+		return responses.Wrap(name, p)
+	}
+	primary, err := NewModelRouter(*agents, func(mp domain.ModelProfile) (domain.LLMProvider, error) {
+		p, err := newProvider(&domain.AgentsConfig{Provider: mp.Provider, DefaultModel: mp.Model, Providers: agents.Providers, Replay: agents.Replay}, getSecret, limits, retryCfg...)
+		if err != nil {
+			return nil, err
+		}
+		return guard(mp.Provider, mp.Model, p), nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	var fallbacks []domain.LLMProvider
+	for _, fb := range agents.Fallbacks {
+		p, err := newProvider(&domain.AgentsConfig{Provider: fb.Provider, DefaultModel: fb.DefaultModel, Providers: agents.Providers, Replay: agents.Replay}, getSecret, limits, retryCfg...)
+		if err != nil {
+			// Skip failed fallback configs — they are best-effort.
+			continue
+		}
+		fallbacks = append(fallbacks, guard(fb.Provider, fb.DefaultModel, p))
+	}
+	return primary, fallbacks, nil
+}
+
+// ProviderName identifies a provider and model for circuit breakers and
+// logs, e.g. "anthropic/claude-3-5-sonnet". An empty provider is "local".
+func ProviderName(provider, model string) string {
+	if provider == "" {
+		provider = "local"
+	}
+	if model == "" {
+		return provider
+	}
+	return provider + "/" + model
+}
+
 // wrapWithRetry decorates a provider with retry logic when config is supplied.
 func wrapWithRetry(provider domain.LLMProvider, retryCfg ...*domain.RetryConfig) domain.LLMProvider {
 	if len(retryCfg) == 0 || retryCfg[0] == nil || retryCfg[0].MaxRetries <= 0 {
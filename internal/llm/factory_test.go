@@ -8,7 +8,10 @@ import (
 	"testing"
 	"time"
 
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/cache"
 	"ironclaw/internal/domain"
+	"ironclaw/internal/ratelimit"
 )
 
 func TestNewProvider_WhenConfigIsNil_ShouldReturnLocalProvider(t *testing.T) {
@@ -624,3 +627,99 @@ func TestNewProvider_WhenKeyPoolCreationFails_ShouldReturnError(t *testing.T) {
 		t.Errorf("error should mention key pool, got %q", err.Error())
 	}
 }
+
+func TestNewGuardedProviders_ShouldWrapPrimaryAndFallbacksInNamedCircuits(t *testing.T) {
+	getSecret := func(name string) (string, error) { return "", nil }
+	agents := &domain.AgentsConfig{
+		Provider:     "local",
+		DefaultModel: "m1",
+		Fallbacks: []domain.FallbackConfig{
+			{Provider: "openai", DefaultModel: "gpt-4o"}, // skipped: no API key
+			{Provider: "local", DefaultModel: "m2"},
+		},
+	}
+	breakers := breaker.NewSet(breaker.DefaultConfig())
+
+	primary, fallbacks, err := NewGuardedProviders(agents, getSecret, breakers, nil, nil)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	if len(fallbacks) != 1 {
+		t.Fatalf("fallbacks = %d, want 1", len(fallbacks))
+	}
+	if _, err := primary.Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	_, _ = fallbacks[0].Generate(context.Background(), "hi")
+	st := breakers.Status()
+	if len(st) != 2 || st[0].Name != "local/m1" || st[1].Name != "local/m2" {
+		t.Errorf("circuits = %+v, want local/m1 and local/m2", st)
+	}
+}
+
+func TestNewGuardedProviders_WhenNoBreakers_ShouldNotWrap(t *testing.T) {
+	primary, _, err := NewGuardedProviders(&domain.AgentsConfig{Provider: "local"}, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	if _, ok := primary.(*breaker.Provider); ok {
+		t.Error("expected unwrapped provider")
+	}
+}
+
+func TestNewGuardedProviders_WhenLimitsSet_ShouldLimitProvidersAndKeys(t *testing.T) {
+	getSecret := func(name string) (string, error) { return "sk-first-key-0001,sk-second-key-0002", nil }
+	agents := &domain.AgentsConfig{
+		Provider:  "local",
+		Fallbacks: []domain.FallbackConfig{{Provider: "openai", DefaultModel: "gpt-4o"}},
+	}
+	limits := ratelimit.NewSet(map[string]domain.RateLimitConfig{
+		"local":  {MaxConcurrent: 1},
+		"openai": {PerKey: &domain.RateLimitConfig{RequestsPerMinute: 60}},
+	})
+
+	primary, _, err := NewGuardedProviders(agents, getSecret, nil, limits, nil)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	if _, err := primary.Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	st := limits.Status()
+	if len(st) != 3 || st[0].Name != "local" || st[0].Requests != 1 || st[1].Name != "openai key …0001" || st[2].Name != "openai key …0002" {
+		t.Errorf("limiters = %+v, want local and one per openai key", st)
+	}
+}
+
+func TestNewGuardedProviders_WhenCacheSet_ShouldAnswerRepeatsFromIt(t *testing.T) {
+	responses, closeFn, err := cache.Open(domain.CacheConfig{}, t.TempDir())
+	if err != nil {
+		t.Fatalf("cache.Open: %v", err)
+	}
+	defer closeFn()
+
+	primary, _, err := NewGuardedProviders(&domain.AgentsConfig{Provider: "local"}, nil, nil, nil, responses)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if out, err := primary.Generate(context.Background(), "hi"); err != nil || out != "Local: hi" {
+			t.Fatalf("Generate = %q, %v", out, err)
+		}
+	}
+	if st := responses.Stats(); st.Hits != 1 || st.Misses != 1 {
+		t.Errorf("cache stats = %+v, want 1 hit and 1 miss", st)
+	}
+}
+
+func TestProviderName_ShouldJoinProviderAndModel(t *testing.T) {
+	for _, tc := range []struct{ provider, model, want string }{
+		{"anthropic", "claude", "anthropic/claude"},
+		{"", "m", "local/m"},
+		{"ollama", "", "ollama"},
+	} {
+		if got := ProviderName(tc.provider, tc.model); got != tc.want {
+			t.Errorf("ProviderName(%q, %q) = %q, want %q", tc.provider, tc.model, got, tc.want)
+		}
+	}
+}
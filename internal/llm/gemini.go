@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 
+	ironctx "ironclaw/internal/context"
 	"ironclaw/internal/domain"
 )
 
@@ -33,25 +34,77 @@ func NewGeminiProvider(apiKey, model string) *GeminiProvider {
 }
 
 type geminiRequest struct {
-	Contents []geminiContent `json:"contents"`
+	SystemInstruction *geminiContent   `json:"systemInstruction,omitempty"`
+	Contents          []geminiContent  `json:"contents"`
+	Tools             []geminiTool     `json:"tools,omitempty"`
+	GenerationConfig  *geminiGenConfig `json:"generationConfig,omitempty"`
 }
 
 type geminiContent struct {
+	Role  string       `json:"role,omitempty"`
 	Parts []geminiPart `json:"parts"`
 }
 
+// geminiPart is the union of text, functionCall and functionResponse parts.
 type geminiPart struct {
-	Text string `json:"text"`
+	Text             string                  `json:"text,omitempty"`
+	InlineData       *geminiInlineData       `json:"inlineData,omitempty"`
+	FunctionCall     *geminiFunctionCall     `json:"functionCall,omitempty"`
+	FunctionResponse *geminiFunctionResponse `json:"functionResponse,omitempty"`
+}
+
+// geminiInlineData carries base64 media, such as an image, in a part.
+type geminiInlineData struct {
+	MimeType string `json:"mimeType"`
+	Data     string `json:"data"`
+}
+
+type geminiFunctionCall struct {
+	Name string          `json:"name"`
+	Args json.RawMessage `json:"args,omitempty"`
+}
+
+type geminiFunctionResponse struct {
+	Name     string         `json:"name"`
+	Response map[string]any `json:"response"`
+}
+
+type geminiTool struct {
+	FunctionDeclarations []geminiFunctionDecl `json:"functionDeclarations"`
+}
+
+type geminiFunctionDecl struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"`
+}
+
+type geminiGenConfig struct {
+	MaxOutputTokens int                   `json:"maxOutputTokens,omitempty"`
+	Temperature     *float64              `json:"temperature,omitempty"`
+	TopP            *float64              `json:"topP,omitempty"`
+	StopSequences   []string              `json:"stopSequences,omitempty"`
+	ThinkingConfig  *geminiThinkingConfig `json:"thinkingConfig,omitempty"`
+
+	ResponseMimeType   string          `json:"responseMimeType,omitempty"`
+	ResponseJSONSchema json.RawMessage `json:"responseJsonSchema,omitempty"`
+}
+
+type geminiThinkingConfig struct {
+	ThinkingBudget int `json:"thinkingBudget"`
 }
 
 type geminiResponse struct {
-	Candidates []struct {
-		Content struct {
-			Parts []struct {
-				Text string `json:"text"`
-			} `json:"parts"`
-		} `json:"content"`
-	} `json:"candidates"`
+	Candidates    []geminiCandidate `json:"candidates"`
+	UsageMetadata struct {
+		PromptTokenCount     int `json:"promptTokenCount"`
+		CandidatesTokenCount int `json:"candidatesTokenCount"`
+	} `json:"usageMetadata"`
+}
+
+type geminiCandidate struct {
+	Content      geminiContent `json:"content"`
+	FinishReason string        `json:"finishReason"`
 }
 
 // Generate implements domain.LLMProvider.
@@ -68,36 +121,256 @@ func (p *GeminiProvider) Generate(ctx context.Context, prompt string) (string, e
 			},
 		},
 	}
-	raw, err := p.marshalFunc(body)
+	out, err := p.post(ctx, body)
 	if err != nil {
-		return "", fmt.Errorf("gemini marshal: %w", err)
+		return "", err
 	}
-	url := fmt.Sprintf("%s/%s:generateContent?key=%s", p.baseURL, p.model, p.apiKey)
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	reportUsage(ctx, "gemini", p.model, p.apiKey, out.usage())
+	var text string
+	for _, part := range out.Candidates[0].Content.Parts {
+		text += part.Text
+	}
+	return text, nil
+}
+
+// Chat implements domain.ChatProvider. Roles map to "user"/"model", the system
+// prompt to systemInstruction, tools to functionDeclarations and tool results to
+// functionResponse parts (addressed by the name of the originating call).
+func (p *GeminiProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	out, err := p.post(ctx, buildGeminiRequest(req))
 	if err != nil {
-		return "", fmt.Errorf("gemini request: %w", err)
+		return domain.ChatResponse{}, err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	resp, err := p.client.Do(req)
+	reportUsage(ctx, "gemini", p.model, p.apiKey, out.usage())
+	return out.toChatResponse(len(req.Messages)), nil
+}
+
+// ChatStream implements domain.StreamingProvider via streamGenerateContent with
+// alt=sse. Each event carries a partial candidate; text parts are passed to
+// onDelta and function calls are collected for the final response.
+func (p *GeminiProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	resp, err := p.send(ctx, buildGeminiRequest(req), "streamGenerateContent", "alt=sse&")
 	if err != nil {
-		return "", fmt.Errorf("gemini do: %w", err)
+		return domain.ChatResponse{}, err
 	}
 	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("gemini api: %s", resp.Status)
+
+	var out geminiResponse
+	out.Candidates = make([]geminiCandidate, 1)
+	cand := &out.Candidates[0]
+	err = readSSE(resp.Body, func(_ string, data []byte) error {
+		var chunk geminiResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("gemini decode: %w", err)
+		}
+		if chunk.UsageMetadata.PromptTokenCount > 0 || chunk.UsageMetadata.CandidatesTokenCount > 0 {
+			out.UsageMetadata = chunk.UsageMetadata
+		}
+		if len(chunk.Candidates) == 0 {
+			return nil
+		}
+		c := chunk.Candidates[0]
+		if c.FinishReason != "" {
+			cand.FinishReason = c.FinishReason
+		}
+		for _, part := range c.Content.Parts {
+			switch {
+			case part.FunctionCall != nil:
+				cand.Content.Parts = append(cand.Content.Parts, part)
+			case part.Text != "":
+				onDelta(part.Text)
+				// Merge consecutive text so the reply is one TextBlock.
+				if n := len(cand.Content.Parts); n > 0 && cand.Content.Parts[n-1].FunctionCall == nil {
+					cand.Content.Parts[n-1].Text += part.Text
+				} else {
+					cand.Content.Parts = append(cand.Content.Parts, geminiPart{Text: part.Text})
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
 	}
+	reportUsage(ctx, "gemini", p.model, p.apiKey, out.usage())
+	return out.toChatResponse(len(req.Messages)), nil
+}
+
+// buildGeminiRequest maps a domain.ChatRequest onto a generateContent request.
+// A ResponseSchema switches the reply to JSON constrained by that schema.
+func buildGeminiRequest(req domain.ChatRequest) geminiRequest {
+	body := geminiRequest{}
+	if req.MaxTokens > 0 || req.Temperature != nil || req.TopP != nil || len(req.Stop) > 0 || req.ThinkingBudget > 0 || len(req.ResponseSchema) > 0 {
+		body.GenerationConfig = &geminiGenConfig{MaxOutputTokens: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP, StopSequences: req.Stop}
+		if req.ThinkingBudget > 0 {
+			body.GenerationConfig.ThinkingConfig = &geminiThinkingConfig{ThinkingBudget: req.ThinkingBudget}
+		}
+		if len(req.ResponseSchema) > 0 {
+			body.GenerationConfig.ResponseMimeType = "application/json"
+			body.GenerationConfig.ResponseJSONSchema = req.ResponseSchema
+		}
+	}
+	system := req.System
+	names := make(map[string]string) // tool_use ID -> function name
+	for _, msg := range req.Messages {
+		if msg.Role == domain.RoleSystem {
+			system = joinSystem(system, ironctx.MessageText(msg))
+			continue
+		}
+		role := "user"
+		if msg.Role == domain.RoleAssistant {
+			role = "model"
+		}
+		var parts []geminiPart
+		for _, block := range msg.Blocks() {
+			switch b := block.(type) {
+			case domain.TextBlock:
+				if b.Text != "" {
+					parts = append(parts, geminiPart{Text: b.Text})
+				}
+			case domain.ImageBlock:
+				parts = append(parts, geminiPart{InlineData: &geminiInlineData{MimeType: b.Source.MediaType, Data: b.Source.Data}})
+			case domain.ToolUseBlock:
+				names[b.ToolUseID] = b.Name
+				parts = append(parts, geminiPart{FunctionCall: &geminiFunctionCall{Name: b.Name, Args: objectOrEmpty(b.Input)}})
+			case domain.ToolResultBlock:
+				result := map[string]any{"content": b.Content}
+				if b.IsError {
+					result = map[string]any{"error": b.Content}
+				}
+				parts = append(parts, geminiPart{FunctionResponse: &geminiFunctionResponse{Name: names[b.ToolUseID], Response: result}})
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if n := len(body.Contents); n > 0 && body.Contents[n-1].Role == role {
+			body.Contents[n-1].Parts = append(body.Contents[n-1].Parts, parts...)
+			continue
+		}
+		body.Contents = append(body.Contents, geminiContent{Role: role, Parts: parts})
+	}
+	if system != "" {
+		body.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: system}}}
+	}
+	if len(req.Tools) > 0 {
+		decls := make([]geminiFunctionDecl, 0, len(req.Tools))
+		for _, t := range req.Tools {
+			decls = append(decls, geminiFunctionDecl{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  geminiSchema(t.InputSchema),
+			})
+		}
+		body.Tools = []geminiTool{{FunctionDeclarations: decls}}
+	}
+	return body
+}
+
+// toChatResponse converts the first candidate into a domain.ChatResponse. turn
+// seeds the synthetic tool_use IDs.
+func (out *geminiResponse) toChatResponse(turn int) domain.ChatResponse {
+	cand := out.Candidates[0]
+	resp := domain.ChatResponse{
+		StopReason: domain.StopEndTurn,
+		Usage:      out.usage(),
+	}
+	if cand.FinishReason == "MAX_TOKENS" {
+		resp.StopReason = domain.StopMaxTokens
+	}
+	for i, part := range cand.Content.Parts {
+		switch {
+		case part.FunctionCall != nil:
+			resp.Content = append(resp.Content, domain.ToolUseBlock{
+				ToolUseID: toolUseID(turn, i),
+				Name:      part.FunctionCall.Name,
+				Input:     objectOrEmpty(part.FunctionCall.Args),
+			})
+			resp.StopReason = domain.StopToolUse
+		case part.Text != "":
+			resp.Content = append(resp.Content, domain.TextBlock{Text: part.Text})
+		}
+	}
+	return resp
+}
+
+// usage returns the response's token counts.
+func (out *geminiResponse) usage() domain.Usage {
+	return domain.Usage{
+		InputTokens:  out.UsageMetadata.PromptTokenCount,
+		OutputTokens: out.UsageMetadata.CandidatesTokenCount,
+	}
+}
+
+// post sends body to generateContent and decodes the response. Returns an
+// error when the response has no candidates.
+func (p *GeminiProvider) post(ctx context.Context, body geminiRequest) (*geminiResponse, error) {
+	resp, err := p.send(ctx, body, "generateContent", "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
 	var out geminiResponse
 	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("gemini decode: %w", err)
+		return nil, fmt.Errorf("gemini decode: %w", err)
 	}
 	if len(out.Candidates) == 0 {
-		return "", fmt.Errorf("gemini: no candidates in response")
+		return nil, fmt.Errorf("gemini: no candidates in response")
 	}
-	var text string
-	for _, part := range out.Candidates[0].Content.Parts {
-		text += part.Text
+	return &out, nil
+}
+
+// send marshals body and POSTs it to the model's method endpoint; query is
+// prepended to the key parameter. On success the caller owns resp.Body.
+func (p *GeminiProvider) send(ctx context.Context, body geminiRequest, method, query string) (*http.Response, error) {
+	raw, err := p.marshalFunc(body)
+	if err != nil {
+		return nil, fmt.Errorf("gemini marshal: %w", err)
 	}
-	return text, nil
+	url := fmt.Sprintf("%s/%s:%s?%skey=%s", p.baseURL, p.model, method, query, p.apiKey)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("gemini request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("gemini do: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("gemini", resp)
+	}
+	return resp, nil
+}
+
+// geminiSchema strips JSON Schema keywords Gemini's OpenAPI subset rejects
+// ("$schema", "$id", "additionalProperties") from the top level of a tool schema.
+func geminiSchema(raw json.RawMessage) json.RawMessage {
+	var schema map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &schema); err != nil || schema == nil {
+		return nil
+	}
+	delete(schema, "$schema")
+	delete(schema, "$id")
+	delete(schema, "additionalProperties")
+	out, err := json.Marshal(schema)
+	if err != nil {
+		return nil
+	}
+	return out
 }
 
-var _ domain.LLMProvider = (*GeminiProvider)(nil)
\ No newline at end of file
+var (
+	_ domain.LLMProvider       = (*GeminiProvider)(nil)
+	_ domain.ChatProvider      = (*GeminiProvider)(nil)
+	_ domain.StreamingProvider = (*GeminiProvider)(nil)
+)
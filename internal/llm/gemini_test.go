@@ -3,6 +3,7 @@ package llm
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -213,4 +214,124 @@ func TestGeminiProvider_Generate_WhenAPINoCandidates_ShouldReturnError(t *testin
 	}
 }
 
-var _ domain.LLMProvider = (*GeminiProvider)(nil)
\ No newline at end of file
+var _ domain.LLMProvider = (*GeminiProvider)(nil)
+func TestGeminiProvider_Chat_ShouldMapRolesFunctionCallsAndResponses(t *testing.T) {
+	var got geminiRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"candidates":[{"content":{"role":"model","parts":[{"functionCall":{"name":"shell","args":{"command":"ls"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":7,"candidatesTokenCount":2}}`))
+	}))
+	defer server.Close()
+
+	p := NewGeminiProvider("key", "gemini-pro")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{
+		System: "sys",
+		Messages: []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "hi"),
+			domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: "t1", Name: "clock", Input: json.RawMessage(`{}`)}),
+			domain.NewMessage(domain.RoleUser, domain.ToolResultBlock{ToolUseID: "t1", Content: "noon"}),
+		},
+		Tools: []domain.ToolDefinition{{Name: "shell", InputSchema: json.RawMessage(`{"$schema":"x","type":"object","additionalProperties":false}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	if got.SystemInstruction == nil || got.SystemInstruction.Parts[0].Text != "sys" {
+		t.Errorf("expected systemInstruction, got %+v", got.SystemInstruction)
+	}
+	if len(got.Contents) != 3 || got.Contents[1].Role != "model" {
+		t.Fatalf("unexpected contents: %+v", got.Contents)
+	}
+	fr := got.Contents[2].Parts[0].FunctionResponse
+	if fr == nil || fr.Name != "clock" || fr.Response["content"] != "noon" {
+		t.Errorf("function response should be addressed by call name, got %+v", fr)
+	}
+	params := string(got.Tools[0].FunctionDeclarations[0].Parameters)
+	if strings.Contains(params, "$schema") || strings.Contains(params, "additionalProperties") {
+		t.Errorf("unsupported schema keywords should be stripped, got %s", params)
+	}
+
+	uses := resp.ToolUses()
+	if len(uses) != 1 || uses[0].Name != "shell" || uses[0].ToolUseID == "" {
+		t.Errorf("unexpected tool uses: %+v", uses)
+	}
+	if resp.StopReason != domain.StopToolUse || resp.Usage.InputTokens != 7 {
+		t.Errorf("unexpected response metadata: %+v", resp)
+	}
+}
+
+func TestGeminiProvider_ChatStream_ShouldUseSSEEndpointAndEmitDeltas(t *testing.T) {
+	var path, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, query = r.URL.Path, r.URL.RawQuery
+		w.Write([]byte(`data: {"candidates":[{"content":{"role":"model","parts":[{"text":"Hi "}]}}]}
+
+data: {"candidates":[{"content":{"role":"model","parts":[{"text":"there"},{"functionCall":{"name":"shell","args":{"command":"ls"}}}]},"finishReason":"STOP"}],"usageMetadata":{"promptTokenCount":4,"candidatesTokenCount":6}}
+
+`))
+	}))
+	defer server.Close()
+
+	p := NewGeminiProvider("key", "gemini-pro")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}},
+		func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if path != "/gemini-pro:streamGenerateContent" || !strings.Contains(query, "alt=sse") {
+		t.Errorf("unexpected endpoint %s?%s", path, query)
+	}
+	if len(deltas) != 2 || resp.Text() != "Hi there" {
+		t.Errorf("unexpected deltas %q / text %q", deltas, resp.Text())
+	}
+	if uses := resp.ToolUses(); len(uses) != 1 || uses[0].Name != "shell" || resp.StopReason != domain.StopToolUse {
+		t.Errorf("unexpected tool uses %+v stop %q", uses, resp.StopReason)
+	}
+	if resp.Usage.InputTokens != 4 || resp.Usage.OutputTokens != 6 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestBuildGeminiRequest_ShouldMapGenerationParams(t *testing.T) {
+	temp := 0.1
+	body := buildGeminiRequest(domain.ChatRequest{Temperature: &temp, Stop: []string{"END"}, ThinkingBudget: 512})
+
+	gen := body.GenerationConfig
+	if gen == nil || *gen.Temperature != 0.1 || gen.StopSequences[0] != "END" || gen.ThinkingConfig.ThinkingBudget != 512 || gen.MaxOutputTokens != 0 {
+		t.Errorf("unexpected generation config %+v", gen)
+	}
+	if buildGeminiRequest(domain.ChatRequest{}).GenerationConfig != nil {
+		t.Error("expected no generation config without params")
+	}
+}
+
+func TestBuildGeminiRequest_WhenResponseSchema_ShouldRequestJSON(t *testing.T) {
+	body := buildGeminiRequest(domain.ChatRequest{ResponseSchema: json.RawMessage(`{"type":"object"}`)})
+
+	gen := body.GenerationConfig
+	if gen == nil || gen.ResponseMimeType != "application/json" || string(gen.ResponseJSONSchema) != `{"type":"object"}` {
+		t.Errorf("unexpected generation config %+v", gen)
+	}
+}
+
+func TestBuildGeminiRequest_ShouldMapImageBlocksToInlineData(t *testing.T) {
+	img := domain.NewImageBlock("image/png", []byte("png"))
+	body := buildGeminiRequest(domain.ChatRequest{Messages: []domain.Message{
+		domain.NewMessage(domain.RoleUser, img, domain.TextBlock{Text: "what is it?"}),
+	}})
+
+	parts := body.Contents[0].Parts
+	if len(parts) != 2 || parts[0].InlineData == nil || *parts[0].InlineData != (geminiInlineData{MimeType: "image/png", Data: "cG5n"}) || parts[1].Text != "what is it?" {
+		t.Errorf("parts = %+v", parts)
+	}
+}
@@ -2,8 +2,9 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strings"
+	"net/http"
 	"sync"
 	"time"
 
@@ -62,13 +63,23 @@ func (kp *KeyPool) Next() (string, int, error) {
 // MarkCooldown puts the key at the given index into cooldown for the configured duration.
 // Out-of-range indices are silently ignored.
 func (kp *KeyPool) MarkCooldown(idx int) {
+	kp.MarkCooldownFor(idx, 0)
+}
+
+// MarkCooldownFor puts the key at the given index into cooldown for d, e.g.
+// until the provider's rate limit resets. d <= 0 uses the configured duration.
+// Out-of-range indices are silently ignored.
+func (kp *KeyPool) MarkCooldownFor(idx int, d time.Duration) {
 	kp.mu.Lock()
 	defer kp.mu.Unlock()
 
 	if idx < 0 || idx >= len(kp.keys) {
 		return
 	}
-	kp.cooldowns[idx] = kp.nowFunc().Add(kp.cooldownDur)
+	if d <= 0 {
+		d = kp.cooldownDur
+	}
+	kp.cooldowns[idx] = kp.nowFunc().Add(d)
 }
 
 // Len returns the total number of keys in the pool.
@@ -91,19 +102,6 @@ func (kp *KeyPool) Available() int {
 	return count
 }
 
-// =============================================================================
-// Rate-limit detection
-// =============================================================================
-
-// isRateLimitError returns true when the error indicates a 429 / rate-limit response.
-func isRateLimitError(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := strings.ToLower(err.Error())
-	return strings.Contains(msg, "429") || strings.Contains(msg, "rate limit")
-}
-
 // =============================================================================
 // KeyPoolProvider (LLMProvider decorator)
 // =============================================================================
@@ -137,37 +135,100 @@ func NewKeyPoolProvider(pool *KeyPool, providers []domain.LLMProvider) (*KeyPool
 // via round-robin, and on a 429 error marks the key as cooldown and retries once
 // with the next available key.
 func (kpp *KeyPoolProvider) Generate(ctx context.Context, prompt string) (string, error) {
-	if err := ctx.Err(); err != nil {
+	var result string
+	err := kpp.withKey(ctx, func(p domain.LLMProvider) error {
+		var genErr error
+		result, genErr = p.Generate(ctx, prompt)
+		return genErr
+	})
+	if err != nil {
 		return "", err
 	}
+	return result, nil
+}
+
+// Chat implements domain.ChatProvider with the same rotation and cooldown
+// behaviour as Generate. Returns domain.ErrChatNotSupported when the pooled
+// providers do not implement domain.ChatProvider.
+func (kpp *KeyPoolProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	var result domain.ChatResponse
+	err := kpp.withKey(ctx, func(p domain.LLMProvider) error {
+		cp, ok := p.(domain.ChatProvider)
+		if !ok {
+			return domain.ErrChatNotSupported
+		}
+		var chatErr error
+		result, chatErr = cp.Chat(ctx, req)
+		return chatErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// ChatStream implements domain.StreamingProvider with the same rotation and
+// cooldown behaviour as Generate. Rate-limit errors arrive before any delta, so
+// retrying with the next key cannot duplicate streamed text. Returns
+// domain.ErrStreamNotSupported when the pooled providers cannot stream.
+func (kpp *KeyPoolProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	var result domain.ChatResponse
+	err := kpp.withKey(ctx, func(p domain.LLMProvider) error {
+		sp, ok := p.(domain.StreamingProvider)
+		if !ok {
+			return domain.ErrStreamNotSupported
+		}
+		var streamErr error
+		result, streamErr = sp.ChatStream(ctx, req, onDelta)
+		return streamErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// withKey runs call with the next available provider. On a rate-limit error
+// (an APIError with status 429) the key is put into cooldown, for its
+// RetryAfter when the provider sent one, and call is retried once with the
+// next available key.
+func (kpp *KeyPoolProvider) withKey(ctx context.Context, call func(domain.LLMProvider) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
 	_, idx, err := kpp.pool.Next()
 	if err != nil {
-		return "", err
+		return err
 	}
 
-	result, genErr := kpp.providers[idx].Generate(ctx, prompt)
-	if genErr == nil {
-		return result, nil
+	callErr := call(kpp.providers[idx])
+	if callErr == nil {
+		return nil
 	}
 
 	// Only retry on rate-limit errors
-	if !isRateLimitError(genErr) {
-		return "", genErr
+	var apiErr *APIError
+	if !errors.As(callErr, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return callErr
 	}
 
-	// Mark the rate-limited key as cooldown
-	kpp.pool.MarkCooldown(idx)
+	// Cool the rate-limited key down until the provider says its limit resets
+	kpp.pool.MarkCooldownFor(idx, apiErr.RetryAfter)
 
 	// Try once more with the next available key
 	_, idx2, err := kpp.pool.Next()
 	if err != nil {
 		// All keys in cooldown
-		return "", fmt.Errorf("all keys in cooldown after rate limit: %w", genErr)
+		return fmt.Errorf("all keys in cooldown after rate limit: %w", callErr)
 	}
 
-	return kpp.providers[idx2].Generate(ctx, prompt)
+	return call(kpp.providers[idx2])
 }
 
-// Compile-time check that KeyPoolProvider implements LLMProvider.
-var _ domain.LLMProvider = (*KeyPoolProvider)(nil)
+// Compile-time check that KeyPoolProvider implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*KeyPoolProvider)(nil)
+	_ domain.ChatProvider      = (*KeyPoolProvider)(nil)
+	_ domain.StreamingProvider = (*KeyPoolProvider)(nil)
+)
@@ -2,6 +2,7 @@ package llm
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -331,47 +332,60 @@ func TestKeyPool_MarkCooldown_ShouldBeThreadSafe(t *testing.T) {
 }
 
 // =============================================================================
-// isRateLimitError
+// Rate limits
 // =============================================================================
 
-func TestIsRateLimitError_WhenContains429_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("openai api: 429 Too Many Requests")
-	if !isRateLimitError(err) {
-		t.Error("expected true for 429 error")
-	}
+// rateLimited returns the APIError a provider returns for a 429 response.
+func rateLimited(provider string, retryAfter time.Duration) *APIError {
+	return &APIError{Provider: provider, StatusCode: 429, Status: "429 Too Many Requests", RetryAfter: retryAfter}
 }
 
-func TestIsRateLimitError_WhenContainsRateLimit_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("rate limit exceeded")
-	if !isRateLimitError(err) {
-		t.Error("expected true for rate limit error")
-	}
-}
+func TestKeyPool_MarkCooldownFor_ShouldUseGivenDurationOrDefault(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"a", "b"}, time.Minute)
+	now := time.Now()
+	pool.nowFunc = func() time.Time { return now }
 
-func TestIsRateLimitError_WhenContainsRateLimitMixedCase_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("Rate Limit exceeded")
-	if !isRateLimitError(err) {
-		t.Error("expected true for Rate Limit error")
-	}
-}
+	pool.MarkCooldownFor(0, 5*time.Second)
+	pool.MarkCooldownFor(1, 0)
 
-func TestIsRateLimitError_WhenNormalError_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("openai api: 500 Internal Server Error")
-	if isRateLimitError(err) {
-		t.Error("expected false for 500 error")
+	now = now.Add(6 * time.Second)
+	if got := pool.Available(); got != 1 {
+		t.Fatalf("after 6s: available = %d, want 1 (key a's 5s cooldown over)", got)
+	}
+	now = now.Add(time.Minute)
+	if got := pool.Available(); got != 2 {
+		t.Errorf("after default cooldown: available = %d, want 2", got)
 	}
 }
 
-func TestIsRateLimitError_WhenNilError_ShouldReturnFalse(t *testing.T) {
-	if isRateLimitError(nil) {
-		t.Error("expected false for nil error")
+func TestKeyPoolProvider_Generate_WhenRateLimitedWithRetryAfter_ShouldCoolDownUntilReset(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, time.Hour)
+	now := time.Now()
+	pool.nowFunc = func() time.Time { return now }
+	mockA := &mockProvider{name: "a", err: rateLimited("openai", 10*time.Second)}
+	mockB := &mockProvider{name: "b", response: "resp-b"}
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{mockA, mockB})
+
+	if got, err := kpp.Generate(context.Background(), "hello"); err != nil || got != "resp-b: hello" {
+		t.Fatalf("Generate = %q, %v; want key b's reply", got, err)
+	}
+	now = now.Add(11 * time.Second)
+	if got := pool.Available(); got != 2 {
+		t.Errorf("available = %d, want 2 once the 10s reset has passed", got)
 	}
 }
 
-func TestIsRateLimitError_WhenAuthError_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("openai api: 401 Unauthorized")
-	if isRateLimitError(err) {
-		t.Error("expected false for 401 error")
+func TestKeyPoolProvider_Generate_WhenErrorOnlyMentions429_ShouldNotRotate(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, time.Minute)
+	mockA := &mockProvider{name: "a", err: fmt.Errorf("tool output: order #429 not found")}
+	mockB := &mockProvider{name: "b", response: "resp-b"}
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{mockA, mockB})
+
+	if _, err := kpp.Generate(context.Background(), "hello"); err == nil {
+		t.Fatal("expected the error to be returned")
+	}
+	if mockB.calls != 0 || pool.Available() != 2 {
+		t.Errorf("key rotated on an untyped error: mockB calls %d, available %d", mockB.calls, pool.Available())
 	}
 }
 
@@ -496,7 +510,7 @@ func TestKeyPoolProvider_Generate_WhenRateLimited_ShouldCooldownAndRetryNextKey(
 	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
 	pool.nowFunc = func() time.Time { return now }
 
-	mockA := &mockProvider{name: "a", err: fmt.Errorf("openai api: 429 Too Many Requests")}
+	mockA := &mockProvider{name: "a", err: rateLimited("openai", 0)}
 	mockB := &mockProvider{name: "b", response: "resp-b"}
 	providers := []domain.LLMProvider{mockA, mockB}
 
@@ -528,7 +542,7 @@ func TestKeyPoolProvider_Generate_WhenAllKeysRateLimited_ShouldReturnError(t *te
 	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
 	pool.nowFunc = func() time.Time { return now }
 
-	rateLimitErr := fmt.Errorf("openai api: 429 Too Many Requests")
+	rateLimitErr := rateLimited("openai", 0)
 	mockA := &mockProvider{name: "a", err: rateLimitErr}
 	mockB := &mockProvider{name: "b", err: rateLimitErr}
 	providers := []domain.LLMProvider{mockA, mockB}
@@ -544,7 +558,7 @@ func TestKeyPoolProvider_Generate_WhenAllKeysRateLimited_ShouldReturnError(t *te
 func TestKeyPoolProvider_Generate_WhenNon429Error_ShouldReturnErrorWithoutCooldown(t *testing.T) {
 	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
 
-	authErr := fmt.Errorf("openai api: 401 Unauthorized")
+	authErr := &APIError{Provider: "openai", StatusCode: 401, Status: "401 Unauthorized"}
 	mockA := &mockProvider{name: "a", err: authErr}
 	mockB := &mockProvider{name: "b", response: "resp-b"}
 	providers := []domain.LLMProvider{mockA, mockB}
@@ -600,7 +614,7 @@ func TestKeyPoolProvider_Generate_WhenRateLimitedAndRetryAlsoFails_ShouldReturnR
 	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
 	pool.nowFunc = func() time.Time { return now }
 
-	rateLimitErr := fmt.Errorf("openai api: 429 Too Many Requests")
+	rateLimitErr := rateLimited("openai", 0)
 	genericErr := fmt.Errorf("openai api: 500 Internal Server Error")
 	mockA := &mockProvider{name: "a", err: rateLimitErr}
 	mockB := &mockProvider{name: "b", err: genericErr}
@@ -623,7 +637,7 @@ func TestKeyPoolProvider_Generate_WhenRateLimitedAndAllOtherKeysAlreadyCooledDow
 	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
 	pool.nowFunc = func() time.Time { return now }
 
-	rateLimitErr := fmt.Errorf("openai api: 429 Too Many Requests")
+	rateLimitErr := rateLimited("openai", 0)
 	mockA := &mockProvider{name: "a", err: rateLimitErr}
 	mockB := &mockProvider{name: "b", response: "resp-b"}
 	providers := []domain.LLMProvider{mockA, mockB}
@@ -727,3 +741,83 @@ func TestSplitKeys_WhenEmptyString_ShouldReturnEmpty(t *testing.T) {
 		t.Fatalf("want 0 keys, got %d", len(keys))
 	}
 }
+
+// mockChatProvider is a mockProvider that also implements domain.ChatProvider.
+type mockChatProvider struct {
+	mockProvider
+}
+
+func (m *mockChatProvider) Chat(_ context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	m.calls++
+	if m.err != nil {
+		return domain.ChatResponse{}, m.err
+	}
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: m.response}}}, nil
+}
+
+func TestKeyPoolProvider_Chat_WhenRateLimited_ShouldRotateToNextKey(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
+	a := &mockChatProvider{mockProvider{name: "a", err: rateLimited("openai", 0)}}
+	b := &mockChatProvider{mockProvider{name: "b", response: "from-b"}}
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{a, b})
+
+	resp, err := kpp.Chat(context.Background(), domain.ChatRequest{})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "from-b" {
+		t.Errorf("expected response from second key, got %q", resp.Text())
+	}
+	if pool.Available() != 1 {
+		t.Errorf("expected rate-limited key in cooldown, available=%d", pool.Available())
+	}
+}
+
+func TestKeyPoolProvider_Chat_WhenProvidersLackChat_ShouldReturnErrChatNotSupported(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a"}, 60*time.Second)
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{&mockProvider{name: "a"}})
+
+	_, err := kpp.Chat(context.Background(), domain.ChatRequest{})
+	if !errors.Is(err, domain.ErrChatNotSupported) {
+		t.Errorf("expected ErrChatNotSupported, got %v", err)
+	}
+}
+
+// mockStreamProvider is a mockChatProvider that also implements domain.StreamingProvider.
+type mockStreamProvider struct {
+	mockChatProvider
+}
+
+func (m *mockStreamProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	resp, err := m.Chat(ctx, req)
+	if err == nil {
+		onDelta(resp.Text())
+	}
+	return resp, err
+}
+
+func TestKeyPoolProvider_ChatStream_WhenRateLimited_ShouldRotateToNextKey(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a", "key-b"}, 60*time.Second)
+	a := &mockStreamProvider{mockChatProvider{mockProvider{name: "a", err: rateLimited("anthropic", 0)}}}
+	b := &mockStreamProvider{mockChatProvider{mockProvider{name: "b", response: "from-b"}}}
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{a, b})
+
+	var deltas []string
+	resp, err := kpp.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if resp.Text() != "from-b" || len(deltas) != 1 || deltas[0] != "from-b" {
+		t.Errorf("unexpected response %q / deltas %q", resp.Text(), deltas)
+	}
+}
+
+func TestKeyPoolProvider_ChatStream_WhenProvidersCannotStream_ShouldReturnErrStreamNotSupported(t *testing.T) {
+	pool, _ := NewKeyPool([]string{"key-a"}, 60*time.Second)
+	kpp, _ := NewKeyPoolProvider(pool, []domain.LLMProvider{&mockChatProvider{mockProvider{name: "a"}}})
+
+	_, err := kpp.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {})
+	if !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("expected ErrStreamNotSupported, got %v", err)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	ironctx "ironclaw/internal/context"
 	"ironclaw/internal/domain"
 )
 
@@ -29,5 +30,25 @@ func (p *LocalProvider) Generate(ctx context.Context, prompt string) (string, er
 	return fmt.Sprintf("%s%s", p.Prefix, prompt), nil
 }
 
-// Ensure LocalProvider implements domain.LLMProvider at compile time.
-var _ domain.LLMProvider = (*LocalProvider)(nil)
+// Chat implements domain.ChatProvider by echoing the text of the last message
+// with the prefix. It never requests tools.
+func (p *LocalProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	var last string
+	if n := len(req.Messages); n > 0 {
+		last = ironctx.MessageText(req.Messages[n-1])
+	}
+	text, err := p.Generate(ctx, last)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return domain.ChatResponse{
+		Content:    []domain.ContentBlock{domain.TextBlock{Text: text}},
+		StopReason: domain.StopEndTurn,
+	}, nil
+}
+
+// Ensure LocalProvider implements domain.LLMProvider and domain.ChatProvider at compile time.
+var (
+	_ domain.LLMProvider  = (*LocalProvider)(nil)
+	_ domain.ChatProvider = (*LocalProvider)(nil)
+)
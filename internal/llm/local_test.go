@@ -3,6 +3,8 @@ package llm
 import (
 	"context"
 	"testing"
+
+	"ironclaw/internal/domain"
 )
 
 func TestLocalProvider_Generate_ShouldReturnEchoResponse(t *testing.T) {
@@ -41,3 +43,20 @@ func TestLocalProvider_Generate_WhenContextCanceled_ShouldReturnError(t *testing
 		t.Error("expected error when context canceled")
 	}
 }
+
+func TestLocalProvider_Chat_ShouldEchoLastMessage(t *testing.T) {
+	p := NewLocalProvider("Local: ")
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{
+		System: "ignored",
+		Messages: []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "first"),
+			domain.NewTextMessage(domain.RoleUser, "second"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "Local: second" || resp.StopReason != domain.StopEndTurn {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"ironclaw/internal/domain"
+)
+
+// ErrUnknownModel is matched (via errors.Is) by the error ModelRouter returns
+// for a call whose context names a model that is not configured. It is
+// domain.ErrUnknownModel, so the brain can tell it apart without importing llm.
+var ErrUnknownModel = domain.ErrUnknownModel
+
+// ProfileBuilder builds the provider serving a resolved model profile.
+type ProfileBuilder func(domain.ModelProfile) (domain.LLMProvider, error)
+
+// ModelRouter serves each call with the model named in its context (see
+// domain.WithModel), or with the default model when none is named. Names are
+// resolved to profiles with AgentsConfig.ResolveProfile; each profile's
+// provider is built on first use and reused, and its generation parameters
+// fill in those the request leaves unset.
+type ModelRouter struct {
+	agents domain.AgentsConfig
+	build  ProfileBuilder
+	def    profiled
+
+	mu    sync.Mutex
+	built map[string]profiled // resolved name -> provider
+}
+
+// profiled is a provider with the profile it serves.
+type profiled struct {
+	provider domain.LLMProvider
+	profile  domain.ModelProfile
+}
+
+// NewModelRouter returns a ModelRouter for agents. The default model's
+// provider is built immediately so configuration errors surface at startup;
+// its error is returned unchanged. build must not be nil.
+func NewModelRouter(agents domain.AgentsConfig, build ProfileBuilder) (*ModelRouter, error) {
+	if build == nil {
+		panic("llm: profile builder must not be nil")
+	}
+	profile := agents.ResolveProfile("")
+	provider, err := build(profile)
+	if err != nil {
+		return nil, err
+	}
+	return &ModelRouter{
+		agents: agents,
+		build:  build,
+		def:    profiled{provider: provider, profile: profile},
+		built:  make(map[string]profiled),
+	}, nil
+}
+
+// Profile returns the resolved profile of the default model.
+func (r *ModelRouter) Profile() domain.ModelProfile {
+	return r.def.profile
+}
+
+// route returns the provider for the model named in ctx.
+func (r *ModelRouter) route(ctx context.Context) (profiled, error) {
+	name := domain.ModelFrom(ctx)
+	if name == "" {
+		return r.def, nil
+	}
+	if !r.agents.HasModel(name) {
+		return profiled{}, fmt.Errorf("llm: %w %q", ErrUnknownModel, name)
+	}
+	key := r.agents.ResolveModel(name)
+	if key == r.agents.ResolveModel("") {
+		return r.def, nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.built[key]; ok {
+		return p, nil
+	}
+	profile := r.agents.ResolveProfile(key)
+	provider, err := r.build(profile)
+	if err != nil {
+		return profiled{}, fmt.Errorf("llm: model %q: %w", name, err)
+	}
+	p := profiled{provider: provider, profile: profile}
+	r.built[key] = p
+	return p, nil
+}
+
+// Generate implements domain.LLMProvider. When the profile sets generation
+// parameters the prompt is sent as a single-message chat so they apply;
+// providers that cannot chat fall back to plain Generate.
+func (r *ModelRouter) Generate(ctx context.Context, prompt string) (string, error) {
+	p, err := r.route(ctx)
+	if err != nil {
+		return "", err
+	}
+	if hasGenerationParams(p.profile) {
+		if cp, ok := p.provider.(domain.ChatProvider); ok {
+			req := domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)}}
+			resp, err := cp.Chat(ctx, withProfile(req, p.profile))
+			if !errors.Is(err, domain.ErrChatNotSupported) {
+				return resp.Text(), err
+			}
+		}
+	}
+	return p.provider.Generate(ctx, prompt)
+}
+
+// Chat implements domain.ChatProvider. Returns domain.ErrChatNotSupported
+// when the selected provider cannot chat.
+func (r *ModelRouter) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	p, err := r.route(ctx)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	cp, ok := p.provider.(domain.ChatProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	return cp.Chat(ctx, withProfile(req, p.profile))
+}
+
+// ChatStream implements domain.StreamingProvider. Returns
+// domain.ErrStreamNotSupported when the selected provider cannot stream.
+func (r *ModelRouter) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	p, err := r.route(ctx)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	sp, ok := p.provider.(domain.StreamingProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	return sp.ChatStream(ctx, withProfile(req, p.profile), onDelta)
+}
+
+// hasGenerationParams reports whether p sets any per-call parameter.
+func hasGenerationParams(p domain.ModelProfile) bool {
+	return p.MaxTokens > 0 || p.Temperature != nil || p.TopP != nil || len(p.Stop) > 0 || p.ThinkingBudget > 0
+}
+
+// withProfile fills the generation parameters req leaves unset from p.
+func withProfile(req domain.ChatRequest, p domain.ModelProfile) domain.ChatRequest {
+	if req.MaxTokens == 0 {
+		req.MaxTokens = p.MaxTokens
+	}
+	if req.Temperature == nil {
+		req.Temperature = p.Temperature
+	}
+	if req.TopP == nil {
+		req.TopP = p.TopP
+	}
+	if req.Stop == nil {
+		req.Stop = p.Stop
+	}
+	if req.ThinkingBudget == 0 {
+		req.ThinkingBudget = p.ThinkingBudget
+	}
+	return req
+}
+
+// Compile-time check that ModelRouter implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*ModelRouter)(nil)
+	_ domain.ChatProvider      = (*ModelRouter)(nil)
+	_ domain.StreamingProvider = (*ModelRouter)(nil)
+)
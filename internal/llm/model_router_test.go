@@ -0,0 +1,184 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/domain"
+)
+
+// capturingProvider is a streaming chat provider that records the requests
+// it receives and answers with its model name.
+type capturingProvider struct {
+	model string
+	reqs  []domain.ChatRequest
+	gens  int
+}
+
+func (p *capturingProvider) Generate(_ context.Context, prompt string) (string, error) {
+	p.gens++
+	return p.model + ": " + prompt, nil
+}
+
+func (p *capturingProvider) Chat(_ context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	p.reqs = append(p.reqs, req)
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: p.model}}}, nil
+}
+
+func (p *capturingProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	resp, err := p.Chat(ctx, req)
+	onDelta(resp.Text())
+	return resp, err
+}
+
+// profileBuilder builds capturingProviders and remembers every profile built.
+type profileBuilder struct {
+	built     []domain.ModelProfile
+	providers map[string]*capturingProvider
+}
+
+func (b *profileBuilder) build(p domain.ModelProfile) (domain.LLMProvider, error) {
+	if p.Provider == "broken" {
+		return nil, errors.New("no key")
+	}
+	b.built = append(b.built, p)
+	if b.providers == nil {
+		b.providers = make(map[string]*capturingProvider)
+	}
+	cp := &capturingProvider{model: p.Model}
+	b.providers[p.Model] = cp
+	return cp, nil
+}
+
+func ptr(f float64) *float64 { return &f }
+
+func testModels() domain.AgentsConfig {
+	return domain.AgentsConfig{
+		Provider:     "openai",
+		DefaultModel: "gpt-4o",
+		ModelAliases: map[string]string{"quick": "fast"},
+		Models: map[string]domain.ModelProfile{
+			"fast":  {Provider: "anthropic", Model: "claude-haiku", MaxTokens: 512, Temperature: ptr(0.2)},
+			"smart": {Provider: "anthropic", Model: "claude-opus", ThinkingBudget: 8000},
+			"bad":   {Provider: "broken"},
+		},
+	}
+}
+
+func TestModelRouter_WhenNoModelInContext_ShouldUseDefault(t *testing.T) {
+	b := &profileBuilder{}
+	r, err := NewModelRouter(testModels(), b.build)
+	if err != nil {
+		t.Fatalf("NewModelRouter: %v", err)
+	}
+
+	out, err := r.Generate(context.Background(), "hi")
+	if err != nil || out != "gpt-4o: hi" {
+		t.Errorf("Generate = %q, %v", out, err)
+	}
+	if got := r.Profile(); got.Provider != "openai" || got.Model != "gpt-4o" {
+		t.Errorf("Profile = %+v", got)
+	}
+}
+
+func TestModelRouter_WhenAliasInContext_ShouldBuildProfileOnceAndApplyParams(t *testing.T) {
+	b := &profileBuilder{}
+	r, _ := NewModelRouter(testModels(), b.build)
+	ctx := domain.WithModel(context.Background(), "quick")
+
+	for i := 0; i < 2; i++ {
+		resp, err := r.Chat(ctx, domain.ChatRequest{Temperature: ptr(0.9)})
+		if err != nil || resp.Text() != "claude-haiku" {
+			t.Fatalf("Chat = %q, %v", resp.Text(), err)
+		}
+	}
+	if len(b.built) != 2 || b.built[1].Provider != "anthropic" {
+		t.Fatalf("built = %+v, want default then fast once", b.built)
+	}
+	req := b.providers["claude-haiku"].reqs[0]
+	if req.MaxTokens != 512 || *req.Temperature != 0.9 {
+		t.Errorf("request = %+v, want profile max tokens and the request's own temperature", req)
+	}
+}
+
+func TestModelRouter_Generate_WhenProfileSetsParams_ShouldChat(t *testing.T) {
+	b := &profileBuilder{}
+	r, _ := NewModelRouter(testModels(), b.build)
+
+	out, err := r.Generate(domain.WithModel(context.Background(), "smart"), "think")
+	if err != nil || out != "claude-opus" {
+		t.Fatalf("Generate = %q, %v", out, err)
+	}
+	p := b.providers["claude-opus"]
+	if p.gens != 0 || len(p.reqs) != 1 || p.reqs[0].ThinkingBudget != 8000 || p.reqs[0].Messages[0].Role != domain.RoleUser {
+		t.Errorf("provider saw gens=%d reqs=%+v", p.gens, p.reqs)
+	}
+}
+
+func TestModelRouter_WhenUnknownModel_ShouldReturnErrUnknownModel(t *testing.T) {
+	r, _ := NewModelRouter(testModels(), (&profileBuilder{}).build)
+
+	_, err := r.Generate(domain.WithModel(context.Background(), "gpt-9"), "hi")
+	if !errors.Is(err, ErrUnknownModel) {
+		t.Errorf("err = %v, want ErrUnknownModel", err)
+	}
+}
+
+func TestModelRouter_WhenProfileCannotBeBuilt_ShouldReturnErrorAndRetryLater(t *testing.T) {
+	b := &profileBuilder{}
+	r, _ := NewModelRouter(testModels(), b.build)
+
+	_, err := r.Chat(domain.WithModel(context.Background(), "bad"), domain.ChatRequest{})
+	if err == nil || err.Error() != `llm: model "bad": no key` {
+		t.Errorf("err = %v", err)
+	}
+	if len(r.built) != 0 {
+		t.Errorf("failed build was cached")
+	}
+}
+
+func TestNewModelRouter_WhenDefaultCannotBeBuilt_ShouldReturnError(t *testing.T) {
+	_, err := NewModelRouter(domain.AgentsConfig{Provider: "broken"}, (&profileBuilder{}).build)
+	if err == nil {
+		t.Error("expected error")
+	}
+}
+
+func TestModelRouter_WhenProviderCannotChat_ShouldReturnNotSupported(t *testing.T) {
+	r, _ := NewModelRouter(domain.AgentsConfig{Models: map[string]domain.ModelProfile{"": {MaxTokens: 10}}}, func(domain.ModelProfile) (domain.LLMProvider, error) {
+		return &mockProvider{response: "plain"}, nil
+	})
+
+	if _, err := r.Chat(context.Background(), domain.ChatRequest{}); !errors.Is(err, domain.ErrChatNotSupported) {
+		t.Errorf("Chat err = %v", err)
+	}
+	if _, err := r.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("ChatStream err = %v", err)
+	}
+	if out, err := r.Generate(context.Background(), "hi"); err != nil || out != "plain: hi" {
+		t.Errorf("Generate = %q, %v", out, err)
+	}
+}
+
+func TestNewGuardedProviders_WhenModelSelected_ShouldUseItsOwnCircuit(t *testing.T) {
+	agents := &domain.AgentsConfig{
+		Provider:     "local",
+		DefaultModel: "m1",
+		Models:       map[string]domain.ModelProfile{"fast": {Model: "m-small"}},
+	}
+	breakers := breaker.NewSet(breaker.DefaultConfig())
+
+	primary, _, err := NewGuardedProviders(agents, nil, breakers, nil, nil)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	if _, err := primary.Generate(domain.WithModel(context.Background(), "fast"), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	st := breakers.Status()
+	if len(st) != 2 || st[0].Name != "local/m-small" || st[1].Name != "local/m1" {
+		t.Errorf("circuits = %+v", st)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 
+	ironctx "ironclaw/internal/context"
 	"ironclaw/internal/domain"
 )
 
@@ -47,7 +48,9 @@ type ollamaRequest struct {
 }
 
 type ollamaResponse struct {
-	Response string `json:"response"`
+	Response        string `json:"response"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
 }
 
 // Generate implements domain.LLMProvider.
@@ -80,7 +83,7 @@ func (p *OllamaProvider) Generate(ctx context.Context, prompt string) (string, e
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("ollama api: %s", resp.Status)
+		return "", newAPIError("ollama", resp)
 	}
 
 	var out ollamaResponse
@@ -91,9 +94,224 @@ func (p *OllamaProvider) Generate(ctx context.Context, prompt string) (string, e
 	if out.Response == "" {
 		return "", fmt.Errorf("ollama: empty response")
 	}
+	reportUsage(ctx, "ollama", p.model, "", domain.Usage{InputTokens: out.PromptEvalCount, OutputTokens: out.EvalCount})
 
 	return out.Response, nil
 }
 
-// Ensure OllamaProvider implements domain.LLMProvider at compile time.
-var _ domain.LLMProvider = (*OllamaProvider)(nil)
\ No newline at end of file
+type ollamaChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []ollamaChatMessage `json:"messages"`
+	Tools    []openAITool        `json:"tools,omitempty"`
+	Stream   bool                `json:"stream"`
+	Options  *ollamaOptions      `json:"options,omitempty"`
+	Format   json.RawMessage     `json:"format,omitempty"` // JSON Schema for structured output
+}
+
+type ollamaChatMessage struct {
+	Role      string           `json:"role"`
+	Content   string           `json:"content"`
+	Images    []string         `json:"images,omitempty"` // base64, for vision models
+	ToolCalls []ollamaToolCall `json:"tool_calls,omitempty"`
+}
+
+// ollamaToolCall carries arguments as a JSON object (unlike OpenAI's string).
+type ollamaToolCall struct {
+	Function struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	} `json:"function"`
+}
+
+type ollamaOptions struct {
+	NumPredict  int      `json:"num_predict,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	TopP        *float64 `json:"top_p,omitempty"`
+	Stop        []string `json:"stop,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message         ollamaChatMessage `json:"message"`
+	Done            bool              `json:"done"`
+	DoneReason      string            `json:"done_reason"`
+	PromptEvalCount int               `json:"prompt_eval_count"`
+	EvalCount       int               `json:"eval_count"`
+}
+
+// Chat implements domain.ChatProvider via Ollama's /api/chat endpoint. Tool
+// definitions use the OpenAI function format; tool results are sent as "tool"
+// role messages.
+func (p *OllamaProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+
+	resp, err := p.sendChat(ctx, p.buildChatRequest(req, false))
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ollamaChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return domain.ChatResponse{}, fmt.Errorf("ollama decode: %w", err)
+	}
+	chatResp := out.toChatResponse(len(req.Messages))
+	reportUsage(ctx, "ollama", p.model, "", chatResp.Usage)
+	return chatResp, nil
+}
+
+// ChatStream implements domain.StreamingProvider via /api/chat with
+// "stream": true. Ollama answers with newline-delimited JSON chunks; the last
+// one has "done": true and carries the token counts.
+func (p *OllamaProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+
+	resp, err := p.sendChat(ctx, p.buildChatRequest(req, true))
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	var out ollamaChatResponse
+	err = readNDJSON(resp.Body, func(line []byte) error {
+		var chunk ollamaChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("ollama decode: %w", err)
+		}
+		if chunk.Message.Content != "" {
+			out.Message.Content += chunk.Message.Content
+			onDelta(chunk.Message.Content)
+		}
+		out.Message.ToolCalls = append(out.Message.ToolCalls, chunk.Message.ToolCalls...)
+		if chunk.Done {
+			out.DoneReason = chunk.DoneReason
+			out.PromptEvalCount = chunk.PromptEvalCount
+			out.EvalCount = chunk.EvalCount
+			return errStreamDone
+		}
+		return nil
+	})
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
+	}
+	chatResp := out.toChatResponse(len(req.Messages))
+	reportUsage(ctx, "ollama", p.model, "", chatResp.Usage)
+	return chatResp, nil
+}
+
+// buildChatRequest maps a domain.ChatRequest onto an /api/chat request.
+func (p *OllamaProvider) buildChatRequest(req domain.ChatRequest, stream bool) ollamaChatRequest {
+	body := ollamaChatRequest{Model: p.model, Stream: stream, Format: req.ResponseSchema}
+	if req.MaxTokens > 0 || req.Temperature != nil || req.TopP != nil || len(req.Stop) > 0 {
+		body.Options = &ollamaOptions{NumPredict: req.MaxTokens, Temperature: req.Temperature, TopP: req.TopP, Stop: req.Stop}
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, ollamaChatMessage{Role: "system", Content: req.System})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, toOllamaMessages(msg)...)
+	}
+	body.Tools = openAITools(req.Tools)
+	return body
+}
+
+// sendChat marshals body and POSTs it to /api/chat. On success the caller owns
+// resp.Body; non-200 responses are closed and returned
+// as an *APIError.
+func (p *OllamaProvider) sendChat(ctx context.Context, body ollamaChatRequest) (*http.Response, error) {
+	raw, err := p.marshaller.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("ollama marshal: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/chat", bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("ollama request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("ollama do: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError("ollama", resp)
+	}
+	return resp, nil
+}
+
+// toChatResponse converts an /api/chat reply into a domain.ChatResponse. turn
+// seeds the synthetic tool_use IDs.
+func (out *ollamaChatResponse) toChatResponse(turn int) domain.ChatResponse {
+	result := domain.ChatResponse{
+		StopReason: domain.StopEndTurn,
+		Usage: domain.Usage{
+			InputTokens:  out.PromptEvalCount,
+			OutputTokens: out.EvalCount,
+		},
+	}
+	if out.DoneReason == "length" {
+		result.StopReason = domain.StopMaxTokens
+	}
+	if out.Message.Content != "" {
+		result.Content = append(result.Content, domain.TextBlock{Text: out.Message.Content})
+	}
+	for i, call := range out.Message.ToolCalls {
+		result.Content = append(result.Content, domain.ToolUseBlock{
+			ToolUseID: toolUseID(turn, i),
+			Name:      call.Function.Name,
+			Input:     objectOrEmpty(call.Function.Arguments),
+		})
+		result.StopReason = domain.StopToolUse
+	}
+	return result
+}
+
+// toOllamaMessages converts one domain message into one or more /api/chat messages.
+func toOllamaMessages(msg domain.Message) []ollamaChatMessage {
+	role := string(msg.Role)
+	if msg.Role == domain.RoleTool {
+		role = "user"
+	}
+	var out []ollamaChatMessage
+	var text []domain.ContentBlock
+	var calls []ollamaToolCall
+	var images []string
+	for _, block := range msg.Blocks() {
+		switch b := block.(type) {
+		case domain.ImageBlock:
+			images = append(images, b.Source.Data)
+		case domain.ToolUseBlock:
+			var call ollamaToolCall
+			call.Function.Name = b.Name
+			call.Function.Arguments = objectOrEmpty(b.Input)
+			calls = append(calls, call)
+		case domain.ToolResultBlock:
+			out = append(out, ollamaChatMessage{Role: "tool", Content: b.Content})
+		default:
+			text = append(text, block)
+		}
+	}
+	if len(text) > 0 || len(calls) > 0 || len(images) > 0 {
+		out = append(out, ollamaChatMessage{
+			Role:      role,
+			Content:   ironctx.MessageText(domain.Message{ContentBlocks: text}),
+			Images:    images,
+			ToolCalls: calls,
+		})
+	}
+	return out
+}
+
+// Ensure OllamaProvider implements the provider interfaces at compile time.
+var (
+	_ domain.LLMProvider       = (*OllamaProvider)(nil)
+	_ domain.ChatProvider      = (*OllamaProvider)(nil)
+	_ domain.StreamingProvider = (*OllamaProvider)(nil)
+)
@@ -228,4 +228,164 @@ func TestNewOllamaProvider_ShouldReturnProviderWithCorrectModel(t *testing.T) {
 
 	// And: Should implement LLMProvider interface
 	var _ domain.LLMProvider = provider
-}
\ No newline at end of file
+}
+func TestOllamaProvider_Chat_ShouldUseChatEndpointAndParseToolCalls(t *testing.T) {
+	var got ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected /api/chat, got %s", r.URL.Path)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"message":{"role":"assistant","content":"","tool_calls":[{"function":{"name":"shell","arguments":{"command":"ls"}}}]},"done_reason":"stop","prompt_eval_count":12,"eval_count":4}`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider("llama3")
+	provider.baseURL = server.URL + "/api"
+
+	resp, err := provider.Chat(context.Background(), domain.ChatRequest{
+		System: "sys",
+		Messages: []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "hi"),
+			domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: "x", Content: "out"}),
+		},
+		Tools: []domain.ToolDefinition{{Name: "shell", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if got.Stream {
+		t.Error("expected stream=false")
+	}
+	if len(got.Messages) != 3 || got.Messages[0].Role != "system" || got.Messages[2].Role != "tool" {
+		t.Errorf("unexpected messages: %+v", got.Messages)
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Function.Name != "shell" {
+		t.Errorf("unexpected tools: %+v", got.Tools)
+	}
+	uses := resp.ToolUses()
+	if len(uses) != 1 || string(uses[0].Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected tool uses: %+v", uses)
+	}
+	if resp.StopReason != domain.StopToolUse || resp.Usage.InputTokens != 12 || resp.Usage.OutputTokens != 4 {
+		t.Errorf("unexpected response metadata: %+v", resp)
+	}
+}
+
+func TestOllamaProvider_Chat_WhenServerReturnsError_ShouldReturnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider("llama3")
+	provider.baseURL = server.URL + "/api"
+
+	_, err := provider.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err == nil || !strings.Contains(err.Error(), "500") {
+		t.Errorf("expected 500 error, got %v", err)
+	}
+}
+
+func TestOllamaProvider_ChatStream_ShouldReadNDJSONChunks(t *testing.T) {
+	var got ollamaChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"message":{"role":"assistant","content":"Hel"},"done":false}
+{"message":{"role":"assistant","content":"lo"},"done":false}
+{"message":{"role":"assistant","content":""},"done":true,"done_reason":"length","prompt_eval_count":3,"eval_count":2}
+`))
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider("llama3")
+	provider.baseURL = server.URL + "/api"
+
+	var deltas []string
+	resp, err := provider.ChatStream(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}},
+		func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if !got.Stream {
+		t.Error("expected stream:true in request")
+	}
+	if len(deltas) != 2 || resp.Text() != "Hello" || resp.StopReason != domain.StopMaxTokens {
+		t.Errorf("unexpected deltas %q / response %+v", deltas, resp)
+	}
+	if resp.Usage.InputTokens != 3 || resp.Usage.OutputTokens != 2 {
+		t.Errorf("unexpected usage %+v", resp.Usage)
+	}
+}
+
+func TestOllamaProvider_ChatStream_WhenContextCanceled_ShouldReturnContextError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"message":{"content":"partial"},"done":false}` + "\n"))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	provider := NewOllamaProvider("llama3")
+	provider.baseURL = server.URL + "/api"
+
+	_, err := provider.ChatStream(ctx, domain.ChatRequest{}, func(string) { cancel() })
+	if err != context.Canceled {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestOllamaProvider_Generate_ShouldReportUsageWithoutKey(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"response":"hi","prompt_eval_count":5,"eval_count":6}`))
+	}))
+	defer server.Close()
+	p := NewOllamaProvider("llama3")
+	p.baseURL = server.URL
+	ctx, rec := recordingContext()
+
+	if _, err := p.Generate(ctx, "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(rec.calls) != 1 || rec.calls[0].Key != "" || rec.calls[0].InputTokens != 5 || rec.calls[0].OutputTokens != 6 {
+		t.Errorf("calls = %+v", rec.calls)
+	}
+}
+
+func TestOllamaProvider_buildChatRequest_ShouldMapGenerationParams(t *testing.T) {
+	p := NewOllamaProvider("llama3")
+	temp := 0.7
+
+	body := p.buildChatRequest(domain.ChatRequest{Temperature: &temp, Stop: []string{"</s>"}}, false)
+	if body.Options == nil || *body.Options.Temperature != 0.7 || body.Options.Stop[0] != "</s>" || body.Options.NumPredict != 0 {
+		t.Errorf("unexpected options %+v", body.Options)
+	}
+	if p.buildChatRequest(domain.ChatRequest{}, false).Options != nil {
+		t.Error("expected no options without params")
+	}
+}
+
+func TestOllamaProvider_buildChatRequest_WhenResponseSchema_ShouldSetFormat(t *testing.T) {
+	p := NewOllamaProvider("llama3")
+
+	raw, _ := json.Marshal(p.buildChatRequest(domain.ChatRequest{ResponseSchema: json.RawMessage(`{"type":"object"}`)}, false))
+	if !strings.Contains(string(raw), `"format":{"type":"object"}`) {
+		t.Errorf("request %s missing format", raw)
+	}
+}
+
+func TestOllamaProvider_buildChatRequest_ShouldAttachImages(t *testing.T) {
+	p := NewOllamaProvider("llava")
+	img := domain.NewImageBlock("image/png", []byte("png"))
+
+	body := p.buildChatRequest(domain.ChatRequest{Messages: []domain.Message{
+		domain.NewMessage(domain.RoleUser, img, domain.TextBlock{Text: "what is it?"}),
+	}}, false)
+	msg := body.Messages[len(body.Messages)-1]
+	if msg.Content != "what is it?" || len(msg.Images) != 1 || msg.Images[0] != "cG5n" {
+		t.Errorf("message = %+v", msg)
+	}
+}
@@ -44,6 +44,7 @@ type openAIResponse struct {
 	Choices []struct {
 		Message openAIMessage `json:"message"`
 	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
 }
 
 // Generate implements domain.LLMProvider.
@@ -57,32 +58,100 @@ func (p *OpenAIProvider) Generate(ctx context.Context, prompt string) (string, e
 			{Role: "user", Content: prompt},
 		},
 	}
+	var out openAIResponse
+	if err := p.post(ctx, body, &out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("openai: no choices in response")
+	}
+	reportUsage(ctx, "openai", p.model, p.apiKey, out.Usage.toUsage())
+	return out.Choices[0].Message.Content, nil
+}
+
+// Chat implements domain.ChatProvider using Chat Completions messages, tools
+// and tool_calls.
+func (p *OpenAIProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := buildOpenAIChatRequest(p.model, req)
+	var out openAIChatResponse
+	if err := p.post(ctx, body, &out); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	if len(out.Choices) == 0 {
+		return domain.ChatResponse{}, fmt.Errorf("openai: no choices in response")
+	}
+	reportUsage(ctx, "openai", p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(&out), nil
+}
+
+// ChatStream implements domain.StreamingProvider using Chat Completions with
+// "stream": true. Content deltas are passed to onDelta; fragmented tool_calls
+// are assembled into the final response.
+func (p *OpenAIProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := buildOpenAIChatRequest(p.model, req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+	out, err := readOpenAIStream(resp.Body, "openai", onDelta)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
+	}
+	reportUsage(ctx, "openai", p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(out), nil
+}
+
+// post sends body to the Chat Completions endpoint and decodes the response into out.
+func (p *OpenAIProvider) post(ctx context.Context, body any, out any) error {
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("openai decode: %w", err)
+	}
+	return nil
+}
+
+// send marshals body and POSTs it to the Chat Completions endpoint. On success
+// the caller owns resp.Body; non-200 responses are closed and returned
+// as an *APIError.
+func (p *OpenAIProvider) send(ctx context.Context, body any) (*http.Response, error) {
 	raw, err := p.marshalFunc(body)
 	if err != nil {
-		return "", fmt.Errorf("openai marshal: %w", err)
+		return nil, fmt.Errorf("openai marshal: %w", err)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL, bytes.NewReader(raw))
 	if err != nil {
-		return "", fmt.Errorf("openai request: %w", err)
+		return nil, fmt.Errorf("openai request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("openai do: %w", err)
+		return nil, fmt.Errorf("openai do: %w", err)
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("openai api: %s", resp.Status)
+		return nil, newAPIError("openai", resp)
 	}
-	var out openAIResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("openai decode: %w", err)
-	}
-	if len(out.Choices) == 0 {
-		return "", fmt.Errorf("openai: no choices in response")
-	}
-	return out.Choices[0].Message.Content, nil
+	return resp, nil
 }
 
-var _ domain.LLMProvider = (*OpenAIProvider)(nil)
+var (
+	_ domain.LLMProvider       = (*OpenAIProvider)(nil)
+	_ domain.ChatProvider      = (*OpenAIProvider)(nil)
+	_ domain.StreamingProvider = (*OpenAIProvider)(nil)
+)
@@ -0,0 +1,326 @@
+package llm
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	ironctx "ironclaw/internal/context"
+	"ironclaw/internal/domain"
+)
+
+// The Chat Completions wire format shared by OpenAI, OpenRouter and other
+// OpenAI-compatible endpoints.
+
+type openAIChatRequest struct {
+	Model          string                `json:"model"`
+	Messages       []openAIChatMessage   `json:"messages"`
+	Tools          []openAITool          `json:"tools,omitempty"`
+	MaxTokens      int                   `json:"max_tokens,omitempty"`
+	Temperature    *float64              `json:"temperature,omitempty"`
+	TopP           *float64              `json:"top_p,omitempty"`
+	Stop           []string              `json:"stop,omitempty"`
+	Stream         bool                  `json:"stream,omitempty"`
+	StreamOptions  *openAIStreamOptions  `json:"stream_options,omitempty"`
+	ResponseFormat *openAIResponseFormat `json:"response_format,omitempty"`
+}
+
+// openAIResponseFormat requests JSON output conforming to a schema.
+type openAIResponseFormat struct {
+	Type       string           `json:"type"` // "json_schema"
+	JSONSchema openAIJSONSchema `json:"json_schema"`
+}
+
+type openAIJSONSchema struct {
+	Name   string          `json:"name"`
+	Schema json.RawMessage `json:"schema"`
+}
+
+// openAIStreamOptions asks for a final usage chunk when streaming.
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
+}
+
+// openAIChatMessage carries either text content, assistant tool_calls, or a
+// tool result (role "tool" + tool_call_id). Messages with images carry their
+// content as Parts instead of Content.
+type openAIChatMessage struct {
+	Role       string              `json:"role"`
+	Content    *string             `json:"content"`
+	Parts      []openAIContentPart `json:"-"`
+	ToolCalls  []openAIToolCall    `json:"tool_calls,omitempty"`
+	ToolCallID string              `json:"tool_call_id,omitempty"`
+}
+
+// MarshalJSON sends Parts, when set, as the content array multimodal
+// messages use.
+func (m openAIChatMessage) MarshalJSON() ([]byte, error) {
+	type plain openAIChatMessage
+	if len(m.Parts) == 0 {
+		return json.Marshal(plain(m))
+	}
+	return json.Marshal(struct {
+		plain
+		Content []openAIContentPart `json:"content"`
+	}{plain(m), m.Parts})
+}
+
+// openAIContentPart is a text or image_url element of a content array.
+type openAIContentPart struct {
+	Type     string          `json:"type"`
+	Text     string          `json:"text,omitempty"`
+	ImageURL *openAIImageURL `json:"image_url,omitempty"`
+}
+
+// openAIImageURL holds an image as a URL; inline images use a data URL.
+type openAIImageURL struct {
+	URL string `json:"url"`
+}
+
+type openAITool struct {
+	Type     string             `json:"type"`
+	Function openAIFunctionDecl `json:"function"`
+}
+
+type openAIFunctionDecl struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters"`
+}
+
+type openAIToolCall struct {
+	ID       string             `json:"id"`
+	Type     string             `json:"type"`
+	Function openAIFunctionCall `json:"function"`
+}
+
+// openAIFunctionCall holds the call arguments as a JSON-encoded string, as the
+// Chat Completions API requires.
+type openAIFunctionCall struct {
+	Name      string `json:"name"`
+	Arguments string `json:"arguments"`
+}
+
+type openAIChatResponse struct {
+	Choices []openAIChoice `json:"choices"`
+	Usage   openAIUsage    `json:"usage"`
+}
+
+// openAIUsage is the token usage Chat Completions responses report.
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+}
+
+func (u openAIUsage) toUsage() domain.Usage {
+	return domain.Usage{InputTokens: u.PromptTokens, OutputTokens: u.CompletionTokens}
+}
+
+type openAIChoice struct {
+	Message      openAIChatMessage `json:"message"`
+	FinishReason string            `json:"finish_reason"`
+}
+
+// buildOpenAIChatRequest maps a domain.ChatRequest onto the Chat Completions
+// format. ToolResultBlocks become separate "tool" messages; ToolUseBlocks become
+// assistant tool_calls. A ResponseSchema becomes a json_schema response_format.
+// ThinkingBudget has no Chat Completions equivalent and is ignored.
+func buildOpenAIChatRequest(model string, req domain.ChatRequest) openAIChatRequest {
+	body := openAIChatRequest{
+		Model:       model,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		TopP:        req.TopP,
+		Stop:        req.Stop,
+	}
+	if req.System != "" {
+		body.Messages = append(body.Messages, openAIChatMessage{Role: "system", Content: strPtr(req.System)})
+	}
+	for _, msg := range req.Messages {
+		body.Messages = append(body.Messages, toOpenAIMessages(msg)...)
+	}
+	body.Tools = openAITools(req.Tools)
+	if len(req.ResponseSchema) > 0 {
+		body.ResponseFormat = &openAIResponseFormat{
+			Type:       "json_schema",
+			JSONSchema: openAIJSONSchema{Name: "response", Schema: req.ResponseSchema},
+		}
+	}
+	return body
+}
+
+// openAITools converts tool definitions to the OpenAI "function" tool format.
+func openAITools(defs []domain.ToolDefinition) []openAITool {
+	var out []openAITool
+	for _, t := range defs {
+		out = append(out, openAITool{
+			Type: "function",
+			Function: openAIFunctionDecl{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  objectOrEmpty(t.InputSchema),
+			},
+		})
+	}
+	return out
+}
+
+// toOpenAIMessages converts one domain message into one or more Chat
+// Completions messages.
+func toOpenAIMessages(msg domain.Message) []openAIChatMessage {
+	role := string(msg.Role)
+	if msg.Role == domain.RoleTool {
+		role = "user"
+	}
+	var out []openAIChatMessage
+	var text []domain.ContentBlock
+	var calls []openAIToolCall
+	for _, block := range msg.Blocks() {
+		switch b := block.(type) {
+		case domain.ToolUseBlock:
+			calls = append(calls, openAIToolCall{
+				ID:       b.ToolUseID,
+				Type:     "function",
+				Function: openAIFunctionCall{Name: b.Name, Arguments: string(objectOrEmpty(b.Input))},
+			})
+		case domain.ToolResultBlock:
+			out = append(out, openAIChatMessage{Role: "tool", ToolCallID: b.ToolUseID, Content: strPtr(b.Content)})
+		default:
+			text = append(text, block)
+		}
+	}
+	if len(text) > 0 || len(calls) > 0 {
+		m := openAIChatMessage{Role: role, ToolCalls: calls}
+		if hasImage(text) {
+			m.Parts = openAIContentParts(text)
+		} else if len(text) > 0 {
+			m.Content = strPtr(ironctx.MessageText(domain.Message{ContentBlocks: text}))
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// openAIContentParts converts text and image blocks to a content array.
+func openAIContentParts(blocks []domain.ContentBlock) []openAIContentPart {
+	var parts []openAIContentPart
+	for _, block := range blocks {
+		if img, ok := block.(domain.ImageBlock); ok {
+			url := "data:" + img.Source.MediaType + ";base64," + img.Source.Data
+			parts = append(parts, openAIContentPart{Type: "image_url", ImageURL: &openAIImageURL{URL: url}})
+			continue
+		}
+		if text := ironctx.MessageText(domain.Message{ContentBlocks: []domain.ContentBlock{block}}); text != "" {
+			parts = append(parts, openAIContentPart{Type: "text", Text: text})
+		}
+	}
+	return parts
+}
+
+// hasImage reports whether blocks contain an ImageBlock.
+func hasImage(blocks []domain.ContentBlock) bool {
+	for _, block := range blocks {
+		if _, ok := block.(domain.ImageBlock); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// parseOpenAIChatResponse converts the first choice into a domain.ChatResponse.
+func parseOpenAIChatResponse(out *openAIChatResponse) domain.ChatResponse {
+	resp := domain.ChatResponse{
+		Usage: out.Usage.toUsage(),
+	}
+	choice := out.Choices[0]
+	if choice.Message.Content != nil && *choice.Message.Content != "" {
+		resp.Content = append(resp.Content, domain.TextBlock{Text: *choice.Message.Content})
+	}
+	for _, call := range choice.Message.ToolCalls {
+		resp.Content = append(resp.Content, domain.ToolUseBlock{
+			ToolUseID: call.ID,
+			Name:      call.Function.Name,
+			Input:     objectOrEmpty(json.RawMessage(call.Function.Arguments)),
+		})
+	}
+	switch choice.FinishReason {
+	case "tool_calls", "function_call":
+		resp.StopReason = domain.StopToolUse
+	case "length":
+		resp.StopReason = domain.StopMaxTokens
+	default:
+		resp.StopReason = domain.StopEndTurn
+	}
+	if len(choice.Message.ToolCalls) > 0 {
+		resp.StopReason = domain.StopToolUse
+	}
+	return resp
+}
+
+// openAIStreamChunk is one "data:" payload of a streamed Chat Completions
+// response. Tool calls arrive in fragments keyed by index.
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content   string `json:"content"`
+			ToolCalls []struct {
+				Index    int                `json:"index"`
+				ID       string             `json:"id"`
+				Function openAIFunctionCall `json:"function"`
+			} `json:"tool_calls"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage"`
+}
+
+// readOpenAIStream consumes a streamed Chat Completions body, passing content
+// deltas to onDelta and assembling the complete reply (including fragmented
+// tool_calls) into a single-choice openAIChatResponse. label prefixes errors.
+func readOpenAIStream(body io.Reader, label string, onDelta func(string)) (*openAIChatResponse, error) {
+	out := openAIChatResponse{Choices: make([]openAIChoice, 1)}
+	choice := &out.Choices[0]
+	var content []byte
+	err := readSSE(body, func(_ string, data []byte) error {
+		if string(data) == "[DONE]" {
+			return errStreamDone
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("%s decode: %w", label, err)
+		}
+		if chunk.Usage != nil {
+			out.Usage = *chunk.Usage
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		c := chunk.Choices[0]
+		if c.Delta.Content != "" {
+			content = append(content, c.Delta.Content...)
+			onDelta(c.Delta.Content)
+		}
+		for _, tc := range c.Delta.ToolCalls {
+			for len(choice.Message.ToolCalls) <= tc.Index {
+				choice.Message.ToolCalls = append(choice.Message.ToolCalls, openAIToolCall{Type: "function"})
+			}
+			call := &choice.Message.ToolCalls[tc.Index]
+			if tc.ID != "" {
+				call.ID = tc.ID
+			}
+			call.Function.Name += tc.Function.Name
+			call.Function.Arguments += tc.Function.Arguments
+		}
+		if c.FinishReason != "" {
+			choice.FinishReason = c.FinishReason
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	choice.Message.Content = strPtr(string(content))
+	return &out, nil
+}
+
+func strPtr(s string) *string { return &s }
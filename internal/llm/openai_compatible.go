@@ -0,0 +1,171 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// OpenAICompatibleProvider calls a self-hosted or third-party endpoint that
+// speaks the OpenAI Chat Completions API, such as vLLM, LM Studio or the
+// llama.cpp server. Tool calling, streaming and image input are only used
+// when the endpoint's config declares them; without tool calling, a
+// request's tools are dropped, and without vision, images are replaced by a
+// note saying the model cannot see them.
+type OpenAICompatibleProvider struct {
+	name        string // config name, used in errors and usage reports
+	apiKey      string
+	model       string
+	client      *http.Client
+	baseURL     string
+	headers     map[string]string
+	tools       bool
+	streaming   bool
+	vision      bool
+	marshalFunc func(v interface{}) ([]byte, error) // for testing
+}
+
+// NewOpenAICompatibleProvider returns a provider for the endpoint cfg, named
+// name in config. An empty apiKey sends no Authorization header.
+func NewOpenAICompatibleProvider(name string, cfg domain.ProviderConfig, apiKey, model string) *OpenAICompatibleProvider {
+	return &OpenAICompatibleProvider{
+		name:        name,
+		apiKey:      apiKey,
+		model:       model,
+		client:      &http.Client{},
+		baseURL:     chatCompletionsURL(cfg.BaseURL),
+		headers:     cfg.Headers,
+		tools:       cfg.Tools,
+		streaming:   cfg.Streaming,
+		vision:      cfg.Vision,
+		marshalFunc: json.Marshal,
+	}
+}
+
+// chatCompletionsURL appends the Chat Completions path to a base URL such as
+// "http://host:8000/v1", unless it is already there.
+func chatCompletionsURL(base string) string {
+	base = strings.TrimRight(base, "/")
+	if strings.HasSuffix(base, "/chat/completions") {
+		return base
+	}
+	return base + "/chat/completions"
+}
+
+// Generate implements domain.LLMProvider.
+func (p *OpenAICompatibleProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := p.chat(ctx, domain.ChatRequest{
+		Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, prompt)},
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text(), nil
+}
+
+// Chat implements domain.ChatProvider.
+func (p *OpenAICompatibleProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	return p.chat(ctx, req)
+}
+
+// ChatStream implements domain.StreamingProvider. Returns
+// domain.ErrStreamNotSupported when the endpoint does not support streaming,
+// so callers fall back to Chat.
+func (p *OpenAICompatibleProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if !p.streaming {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := buildOpenAIChatRequest(p.model, p.supported(req))
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+	out, err := readOpenAIStream(resp.Body, p.name, onDelta)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
+	}
+	reportUsage(ctx, p.name, p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(out), nil
+}
+
+// chat sends req as a non-streamed Chat Completions request.
+func (p *OpenAICompatibleProvider) chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	resp, err := p.send(ctx, buildOpenAIChatRequest(p.model, p.supported(req)))
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+	var out openAIChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return domain.ChatResponse{}, fmt.Errorf("%s decode: %w", p.name, err)
+	}
+	if len(out.Choices) == 0 {
+		return domain.ChatResponse{}, fmt.Errorf("%s: no choices in response", p.name)
+	}
+	reportUsage(ctx, p.name, p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(&out), nil
+}
+
+// supported returns req without the tools the endpoint cannot call or the
+// images it cannot view.
+func (p *OpenAICompatibleProvider) supported(req domain.ChatRequest) domain.ChatRequest {
+	if !p.tools {
+		req.Tools = nil
+	}
+	if p.vision {
+		return req
+	}
+	return withoutImages(req)
+}
+
+// send marshals body and POSTs it to the Chat Completions endpoint with the
+// configured headers. On success the caller owns resp.Body; non-200
+// responses are closed and returned as an *APIError.
+func (p *OpenAICompatibleProvider) send(ctx context.Context, body any) (*http.Response, error) {
+	raw, err := p.marshalFunc(body)
+	if err != nil {
+		return nil, fmt.Errorf("%s marshal: %w", p.name, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL, bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("%s request: %w", p.name, err)
+	}
+	for k, v := range p.headers {
+		req.Header.Set(k, v)
+	}
+	if p.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+p.apiKey)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s do: %w", p.name, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(p.name, resp)
+	}
+	return resp, nil
+}
+
+var (
+	_ domain.LLMProvider       = (*OpenAICompatibleProvider)(nil)
+	_ domain.ChatProvider      = (*OpenAICompatibleProvider)(nil)
+	_ domain.StreamingProvider = (*OpenAICompatibleProvider)(nil)
+)
@@ -0,0 +1,206 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/breaker"
+	"ironclaw/internal/domain"
+)
+
+// compatibleServer answers Chat Completions requests with reply, recording
+// the last request's headers and body.
+func compatibleServer(t *testing.T, reply string) (*httptest.Server, *http.Header, *openAIChatRequest) {
+	t.Helper()
+	var header http.Header
+	var body openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		header = r.Header.Clone()
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if body.Stream {
+			io.WriteString(w, "data: {\"choices\":[{\"delta\":{\"content\":\""+reply+"\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+			return
+		}
+		io.WriteString(w, `{"choices":[{"message":{"role":"assistant","content":"`+reply+`"},"finish_reason":"stop"}],"usage":{"prompt_tokens":3,"completion_tokens":1}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &header, &body
+}
+
+func TestChatCompletionsURL_ShouldAppendPathOnce(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"http://h:8000/v1", "http://h:8000/v1/chat/completions"},
+		{"http://h:8000/v1/", "http://h:8000/v1/chat/completions"},
+		{"http://h:8000/v1/chat/completions", "http://h:8000/v1/chat/completions"},
+	} {
+		if got := chatCompletionsURL(tc.in); got != tc.want {
+			t.Errorf("chatCompletionsURL(%q) = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestOpenAICompatibleProvider_Generate_ShouldSendHeadersAndModel(t *testing.T) {
+	server, header, body := compatibleServer(t, "pong")
+	cfg := domain.ProviderConfig{BaseURL: server.URL + "/v1", Headers: map[string]string{"X-Team": "lab"}}
+	p := NewOpenAICompatibleProvider("vllm", cfg, "", "llama-3")
+
+	out, err := p.Generate(context.Background(), "ping")
+	if err != nil || out != "pong" {
+		t.Fatalf("Generate = %q, %v", out, err)
+	}
+	if header.Get("X-Team") != "lab" || header.Get("Authorization") != "" {
+		t.Errorf("headers = %v, want X-Team and no Authorization", *header)
+	}
+	if body.Model != "llama-3" || len(body.Messages) != 1 {
+		t.Errorf("body = %+v", *body)
+	}
+}
+
+func TestOpenAICompatibleProvider_WhenAPIKeySet_ShouldSendBearer(t *testing.T) {
+	server, header, _ := compatibleServer(t, "ok")
+	p := NewOpenAICompatibleProvider("studio", domain.ProviderConfig{BaseURL: server.URL + "/v1"}, "sk-1", "m")
+
+	if _, err := p.Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if got := header.Get("Authorization"); got != "Bearer sk-1" {
+		t.Errorf("Authorization = %q", got)
+	}
+}
+
+func TestOpenAICompatibleProvider_WhenToolsUnsupported_ShouldChatWithoutThem(t *testing.T) {
+	server, _, body := compatibleServer(t, "ok")
+	p := NewOpenAICompatibleProvider("llamacpp", domain.ProviderConfig{BaseURL: server.URL + "/v1", Streaming: true}, "", "m")
+	req := domain.ChatRequest{
+		Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")},
+		Tools:    []domain.ToolDefinition{{Name: "read_file"}},
+	}
+
+	resp, err := p.Chat(context.Background(), req)
+	if err != nil || resp.Text() != "ok" {
+		t.Fatalf("Chat = %q, %v", resp.Text(), err)
+	}
+	if len(body.Tools) != 0 || len(body.Messages) != 1 {
+		t.Errorf("body = %+v, want the message without tools", *body)
+	}
+	resp, err = p.ChatStream(context.Background(), req, func(string) {})
+	if err != nil || resp.Text() != "ok" {
+		t.Fatalf("ChatStream = %q, %v", resp.Text(), err)
+	}
+	if len(body.Tools) != 0 {
+		t.Errorf("streamed tools = %+v, want none", body.Tools)
+	}
+}
+
+func TestOpenAICompatibleProvider_WhenToolsSupported_ShouldSendThem(t *testing.T) {
+	server, _, body := compatibleServer(t, "ok")
+	p := NewOpenAICompatibleProvider("vllm", domain.ProviderConfig{BaseURL: server.URL + "/v1", Tools: true}, "", "m")
+	req := domain.ChatRequest{Tools: []domain.ToolDefinition{{Name: "read_file"}}}
+
+	if _, err := p.Chat(context.Background(), req); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if len(body.Tools) != 1 || body.Tools[0].Function.Name != "read_file" {
+		t.Errorf("tools = %+v", body.Tools)
+	}
+}
+
+func TestOpenAICompatibleProvider_ChatStream_ShouldRespectStreamingFlag(t *testing.T) {
+	server, _, _ := compatibleServer(t, "streamed")
+	off := NewOpenAICompatibleProvider("a", domain.ProviderConfig{BaseURL: server.URL + "/v1"}, "", "m")
+	on := NewOpenAICompatibleProvider("b", domain.ProviderConfig{BaseURL: server.URL + "/v1", Streaming: true}, "", "m")
+
+	if _, err := off.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("ChatStream err = %v, want ErrStreamNotSupported", err)
+	}
+	var deltas []string
+	resp, err := on.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil || resp.Text() != "streamed" || len(deltas) != 1 {
+		t.Errorf("ChatStream = %q, %v, deltas %q", resp.Text(), err, deltas)
+	}
+}
+
+func TestOpenAICompatibleProvider_WhenAPIError_ShouldNameProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "model not loaded", http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	p := NewOpenAICompatibleProvider("vllm", domain.ProviderConfig{BaseURL: server.URL}, "", "m")
+
+	_, err := p.Generate(context.Background(), "hi")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Provider != "vllm" || apiErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("err = %v, want vllm APIError 503", err)
+	}
+}
+
+func TestNewProvider_WhenNamedProvider_ShouldResolveKeyFromItsSecret(t *testing.T) {
+	var asked string
+	getSecret := func(name string) (string, error) { asked = name; return "k1,k2", nil }
+	agents := &domain.AgentsConfig{
+		Provider:     "lan",
+		DefaultModel: "qwen",
+		Providers: map[string]domain.ProviderConfig{
+			"lan": {Type: domain.ProviderTypeOpenAICompatible, BaseURL: "http://lan/v1", APIKeySecret: "lan_key"},
+		},
+	}
+
+	p, err := NewProvider(agents, getSecret)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if asked != "lan_key" {
+		t.Errorf("secret asked = %q, want lan_key", asked)
+	}
+	if _, ok := p.(*KeyPoolProvider); !ok {
+		t.Errorf("provider = %T, want *KeyPoolProvider", p)
+	}
+}
+
+func TestNewProvider_WhenNamedProviderInvalid_ShouldReturnError(t *testing.T) {
+	for name, cfg := range map[string]domain.ProviderConfig{
+		"unknown type": {Type: "grpc", BaseURL: "http://lan/v1"},
+		"no base url":  {Type: domain.ProviderTypeOpenAICompatible},
+	} {
+		agents := &domain.AgentsConfig{Provider: "lan", Providers: map[string]domain.ProviderConfig{"lan": cfg}}
+		if _, err := NewProvider(agents, nil); err == nil || !strings.Contains(err.Error(), `provider "lan"`) {
+			t.Errorf("%s: err = %v", name, err)
+		}
+	}
+}
+
+func TestNewGuardedProviders_WhenFallbackIsNamedProvider_ShouldUseIt(t *testing.T) {
+	server, _, _ := compatibleServer(t, "from lan")
+	agents := &domain.AgentsConfig{
+		Provider:  "local",
+		Fallbacks: []domain.FallbackConfig{{Provider: "lan", DefaultModel: "qwen"}},
+		Providers: map[string]domain.ProviderConfig{
+			"lan": {Type: domain.ProviderTypeOpenAICompatible, BaseURL: server.URL + "/v1"},
+		},
+	}
+	breakers := breaker.NewSet(breaker.DefaultConfig())
+
+	_, fallbacks, err := NewGuardedProviders(agents, nil, breakers, nil, nil)
+	if err != nil {
+		t.Fatalf("NewGuardedProviders: %v", err)
+	}
+	if len(fallbacks) != 1 {
+		t.Fatalf("fallbacks = %d, want 1", len(fallbacks))
+	}
+	if out, err := fallbacks[0].Generate(context.Background(), "hi"); err != nil || out != "from lan" {
+		t.Errorf("Generate = %q, %v", out, err)
+	}
+	if st := breakers.Status(); len(st) != 2 || st[0].Name != "lan/qwen" {
+		t.Errorf("circuits = %+v", st)
+	}
+}
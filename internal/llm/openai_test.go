@@ -2,12 +2,14 @@ package llm
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
 )
 
 // mockTransport returns a fixed response for testing.
@@ -207,4 +209,233 @@ func TestOpenAIProvider_Generate_WhenHTTPDoFails_ShouldReturnError(t *testing.T)
 	}
 }
 
-var _ domain.LLMProvider = (*OpenAIProvider)(nil)
\ No newline at end of file
+var _ domain.LLMProvider = (*OpenAIProvider)(nil)
+func TestOpenAIProvider_Chat_ShouldMapToolCallsAndToolResults(t *testing.T) {
+	var got openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":null,"tool_calls":[{"id":"call_1","type":"function","function":{"name":"shell","arguments":"{\"command\":\"ls\"}"}}]},"finish_reason":"tool_calls"}],"usage":{"prompt_tokens":10,"completion_tokens":5}}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{
+		System: "sys",
+		Messages: []domain.Message{
+			domain.NewTextMessage(domain.RoleUser, "hi"),
+			domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: "call_0", Name: "shell", Input: json.RawMessage(`{"command":"pwd"}`)}),
+			domain.NewMessage(domain.RoleUser, domain.ToolResultBlock{ToolUseID: "call_0", Content: "/home"}),
+		},
+		Tools: []domain.ToolDefinition{{Name: "shell", InputSchema: json.RawMessage(`{"type":"object"}`)}},
+	})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+
+	if len(got.Messages) != 4 {
+		t.Fatalf("expected system+user+assistant+tool messages, got %d", len(got.Messages))
+	}
+	if got.Messages[0].Role != "system" || *got.Messages[0].Content != "sys" {
+		t.Errorf("unexpected system message: %+v", got.Messages[0])
+	}
+	if len(got.Messages[2].ToolCalls) != 1 || got.Messages[2].ToolCalls[0].Function.Arguments != `{"command":"pwd"}` {
+		t.Errorf("unexpected assistant tool_calls: %+v", got.Messages[2])
+	}
+	if got.Messages[3].Role != "tool" || got.Messages[3].ToolCallID != "call_0" {
+		t.Errorf("unexpected tool message: %+v", got.Messages[3])
+	}
+	if len(got.Tools) != 1 || got.Tools[0].Type != "function" || got.Tools[0].Function.Name != "shell" {
+		t.Errorf("unexpected tools: %+v", got.Tools)
+	}
+
+	if resp.StopReason != domain.StopToolUse {
+		t.Errorf("expected tool_use stop reason, got %q", resp.StopReason)
+	}
+	uses := resp.ToolUses()
+	if len(uses) != 1 || uses[0].ToolUseID != "call_1" || string(uses[0].Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected tool uses: %+v", uses)
+	}
+	if resp.Usage.InputTokens != 10 || resp.Usage.OutputTokens != 5 {
+		t.Errorf("unexpected usage: %+v", resp.Usage)
+	}
+}
+
+func TestOpenAIProvider_Chat_WhenNoChoices_ShouldReturnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err == nil || !strings.Contains(err.Error(), "no choices") {
+		t.Errorf("expected no choices error, got %v", err)
+	}
+}
+
+func TestOpenAIProvider_ChatStream_ShouldEmitDeltasAndAssembleToolCalls(t *testing.T) {
+	var got openAIChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`data: {"choices":[{"delta":{"role":"assistant","content":"Let me "}}]}
+
+data: {"choices":[{"delta":{"content":"check."}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","type":"function","function":{"name":"shell","arguments":"{\"comm"}}]}}]}
+
+data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"and\":\"ls\"}"}}]},"finish_reason":"tool_calls"}]}
+
+data: {"choices":[],"usage":{"prompt_tokens":9,"completion_tokens":3}}
+
+data: [DONE]
+
+`))
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}},
+		func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if !got.Stream || got.StreamOptions == nil || !got.StreamOptions.IncludeUsage {
+		t.Errorf("expected stream with include_usage, got %+v", got)
+	}
+	if strings.Join(deltas, "|") != "Let me |check." {
+		t.Errorf("unexpected deltas %q", deltas)
+	}
+	uses := resp.ToolUses()
+	if resp.Text() != "Let me check." || len(uses) != 1 || uses[0].ToolUseID != "call_1" || string(uses[0].Input) != `{"command":"ls"}` {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.StopReason != domain.StopToolUse || resp.Usage.InputTokens != 9 || resp.Usage.OutputTokens != 3 {
+		t.Errorf("unexpected stop/usage: %q %+v", resp.StopReason, resp.Usage)
+	}
+}
+
+func TestOpenAIProvider_ChatStream_WhenAPIError_ShouldReturnError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	_, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) { t.Error("no delta expected") })
+	if err == nil || !strings.Contains(err.Error(), "429") {
+		t.Errorf("expected 429 error, got %v", err)
+	}
+}
+
+func TestOpenAIProvider_Generate_ShouldReportUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hi"}}],"usage":{"prompt_tokens":9,"completion_tokens":2}}`))
+	}))
+	defer server.Close()
+	p := NewOpenAIProvider("sk-0123456789abcdefWXYZ", "gpt-4o")
+	p.baseURL = server.URL
+	ctx, rec := recordingContext()
+
+	if _, err := p.Generate(ctx, "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want := usage.Call{Provider: "openai", Model: "gpt-4o", Key: "…WXYZ", InputTokens: 9, OutputTokens: 2}
+	if len(rec.calls) != 1 || rec.calls[0] != want {
+		t.Errorf("calls = %+v, want [%+v]", rec.calls, want)
+	}
+}
+
+func TestOpenAIProvider_Generate_WhenRequestFails_ShouldNotReportUsage(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+	p := NewOpenAIProvider("key", "gpt-4o")
+	p.baseURL = server.URL
+	ctx, rec := recordingContext()
+
+	if _, err := p.Generate(ctx, "hi"); err == nil {
+		t.Fatal("expected error")
+	}
+	if len(rec.calls) != 0 {
+		t.Errorf("calls = %+v, want none", rec.calls)
+	}
+}
+
+func TestBuildOpenAIChatRequest_ShouldMapGenerationParams(t *testing.T) {
+	temp, topP := 0.5, 0.9
+	raw, _ := json.Marshal(buildOpenAIChatRequest("gpt-4o", domain.ChatRequest{MaxTokens: 100, Temperature: &temp, TopP: &topP, Stop: []string{"\n\n"}, ThinkingBudget: 1000}))
+
+	got := string(raw)
+	for _, want := range []string{`"max_tokens":100`, `"temperature":0.5`, `"top_p":0.9`, `"stop":["\n\n"]`} {
+		if !strings.Contains(got, want) {
+			t.Errorf("request %s missing %s", got, want)
+		}
+	}
+	if strings.Contains(got, "1000") {
+		t.Errorf("thinking budget should not be sent: %s", got)
+	}
+}
+
+func TestBuildOpenAIChatRequest_WhenResponseSchema_ShouldSendJSONSchemaFormat(t *testing.T) {
+	schema := `{"type":"object"}`
+	raw, _ := json.Marshal(buildOpenAIChatRequest("gpt-4o", domain.ChatRequest{ResponseSchema: json.RawMessage(schema)}))
+
+	want := `"response_format":{"type":"json_schema","json_schema":{"name":"response","schema":{"type":"object"}}}`
+	if !strings.Contains(string(raw), want) {
+		t.Errorf("request %s missing %s", raw, want)
+	}
+	raw, _ = json.Marshal(buildOpenAIChatRequest("gpt-4o", domain.ChatRequest{}))
+	if strings.Contains(string(raw), "response_format") {
+		t.Errorf("request without schema should not set response_format: %s", raw)
+	}
+}
+
+func TestBuildOpenAIChatRequest_WhenImage_ShouldSendContentParts(t *testing.T) {
+	img := domain.NewImageBlock("image/jpeg", []byte("jpg"))
+	raw, _ := json.Marshal(buildOpenAIChatRequest("gpt-4o", domain.ChatRequest{Messages: []domain.Message{
+		domain.NewMessage(domain.RoleUser, domain.TextBlock{Text: "what is it?"}, img),
+		domain.NewTextMessage(domain.RoleAssistant, "a cat"),
+	}}))
+
+	want := `{"role":"user","content":[{"type":"text","text":"what is it?"},{"type":"image_url","image_url":{"url":"data:image/jpeg;base64,anBn"}}]}`
+	if !strings.Contains(string(raw), want) {
+		t.Errorf("request %s missing %s", raw, want)
+	}
+	if !strings.Contains(string(raw), `{"role":"assistant","content":"a cat"}`) {
+		t.Errorf("text-only message should keep string content: %s", raw)
+	}
+}
+
+func TestOpenAICompatibleProvider_WhenNoVision_ShouldReplaceImagesWithNote(t *testing.T) {
+	img := domain.NewImageBlock("image/jpeg", []byte("jpg"))
+	req := domain.ChatRequest{Messages: []domain.Message{domain.NewMessage(domain.RoleUser, img, domain.TextBlock{Text: "hi"})}}
+	blind := NewOpenAICompatibleProvider("a", domain.ProviderConfig{}, "", "m")
+	seeing := NewOpenAICompatibleProvider("b", domain.ProviderConfig{Vision: true}, "", "m")
+
+	got := blind.supported(req).Messages[0].Blocks()
+	if got[0] != (domain.TextBlock{Text: imageFallbackText}) || got[1] != (domain.TextBlock{Text: "hi"}) {
+		t.Errorf("blocks = %+v, want the fallback note and text", got)
+	}
+	if req.Messages[0].Blocks()[0] != img {
+		t.Error("original request was modified")
+	}
+	if seeing.supported(req).Messages[0].Blocks()[0] != img {
+		t.Error("vision endpoint should get the image")
+	}
+}
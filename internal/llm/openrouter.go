@@ -31,8 +31,8 @@ func NewOpenRouterProvider(apiKey, model string) *OpenRouterProvider {
 }
 
 type openRouterRequest struct {
-	Model    string                `json:"model"`
-	Messages []openRouterMessage   `json:"messages"`
+	Model    string              `json:"model"`
+	Messages []openRouterMessage `json:"messages"`
 }
 
 type openRouterMessage struct {
@@ -44,6 +44,7 @@ type openRouterResponse struct {
 	Choices []struct {
 		Message openRouterMessage `json:"message"`
 	} `json:"choices"`
+	Usage openAIUsage `json:"usage"`
 }
 
 // Generate implements domain.LLMProvider.
@@ -57,32 +58,100 @@ func (p *OpenRouterProvider) Generate(ctx context.Context, prompt string) (strin
 			{Role: "user", Content: prompt},
 		},
 	}
+	var out openRouterResponse
+	if err := p.post(ctx, body, &out); err != nil {
+		return "", err
+	}
+	if len(out.Choices) == 0 {
+		return "", fmt.Errorf("openrouter: no choices in response")
+	}
+	reportUsage(ctx, "openrouter", p.model, p.apiKey, out.Usage.toUsage())
+	return out.Choices[0].Message.Content, nil
+}
+
+// Chat implements domain.ChatProvider using Chat Completions messages, tools
+// and tool_calls.
+func (p *OpenRouterProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := buildOpenAIChatRequest(p.model, req)
+	var out openAIChatResponse
+	if err := p.post(ctx, body, &out); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	if len(out.Choices) == 0 {
+		return domain.ChatResponse{}, fmt.Errorf("openrouter: no choices in response")
+	}
+	reportUsage(ctx, "openrouter", p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(&out), nil
+}
+
+// ChatStream implements domain.StreamingProvider using Chat Completions with
+// "stream": true. Content deltas are passed to onDelta; fragmented tool_calls
+// are assembled into the final response.
+func (p *OpenRouterProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	body := buildOpenAIChatRequest(p.model, req)
+	body.Stream = true
+	body.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	defer resp.Body.Close()
+	out, err := readOpenAIStream(resp.Body, "openrouter", onDelta)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return domain.ChatResponse{}, ctxErr
+		}
+		return domain.ChatResponse{}, err
+	}
+	reportUsage(ctx, "openrouter", p.model, p.apiKey, out.Usage.toUsage())
+	return parseOpenAIChatResponse(out), nil
+}
+
+// post sends body to the Chat Completions endpoint and decodes the response into out.
+func (p *OpenRouterProvider) post(ctx context.Context, body any, out any) error {
+	resp, err := p.send(ctx, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("openrouter decode: %w", err)
+	}
+	return nil
+}
+
+// send marshals body and POSTs it to the Chat Completions endpoint. On success
+// the caller owns resp.Body; non-200 responses are closed and returned
+// as an *APIError.
+func (p *OpenRouterProvider) send(ctx context.Context, body any) (*http.Response, error) {
 	raw, err := p.marshalFunc(body)
 	if err != nil {
-		return "", fmt.Errorf("openrouter marshal: %w", err)
+		return nil, fmt.Errorf("openrouter marshal: %w", err)
 	}
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL, bytes.NewReader(raw))
 	if err != nil {
-		return "", fmt.Errorf("openrouter request: %w", err)
+		return nil, fmt.Errorf("openrouter request: %w", err)
 	}
 	req.Header.Set("Authorization", "Bearer "+p.apiKey)
 	req.Header.Set("Content-Type", "application/json")
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return "", fmt.Errorf("openrouter do: %w", err)
+		return nil, fmt.Errorf("openrouter do: %w", err)
 	}
-	defer resp.Body.Close()
 	if resp.StatusCode != http.StatusOK {
-		return "", fmt.Errorf("openrouter api: %s", resp.Status)
+		return nil, newAPIError("openrouter", resp)
 	}
-	var out openRouterResponse
-	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
-		return "", fmt.Errorf("openrouter decode: %w", err)
-	}
-	if len(out.Choices) == 0 {
-		return "", fmt.Errorf("openrouter: no choices in response")
-	}
-	return out.Choices[0].Message.Content, nil
+	return resp, nil
 }
 
-var _ domain.LLMProvider = (*OpenRouterProvider)(nil)
\ No newline at end of file
+var (
+	_ domain.LLMProvider       = (*OpenRouterProvider)(nil)
+	_ domain.ChatProvider      = (*OpenRouterProvider)(nil)
+	_ domain.StreamingProvider = (*OpenRouterProvider)(nil)
+)
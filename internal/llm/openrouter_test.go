@@ -4,6 +4,7 @@ import (
 	"context"
 	"io"
 	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 
@@ -248,4 +249,45 @@ func (m *openRouterMockTransport) RoundTrip(req *http.Request) (*http.Response,
 }
 
 
-var _ domain.LLMProvider = (*OpenRouterProvider)(nil)
\ No newline at end of file
+var _ domain.LLMProvider = (*OpenRouterProvider)(nil)
+func TestOpenRouterProvider_Chat_ShouldReturnTextResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			t.Errorf("missing bearer auth, got %q", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"hello"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	p := NewOpenRouterProvider("key", "openai/gpt-4")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{Messages: []domain.Message{domain.NewTextMessage(domain.RoleUser, "hi")}})
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if resp.Text() != "hello" || resp.StopReason != domain.StopEndTurn {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+}
+
+func TestOpenRouterProvider_ChatStream_ShouldEmitDeltas(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, ": OPENROUTER PROCESSING\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"a\"}}]}\n\ndata: {\"choices\":[{\"delta\":{\"content\":\"b\"},\"finish_reason\":\"stop\"}]}\n\ndata: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	p := NewOpenRouterProvider("key", "model")
+	p.baseURL = server.URL
+	p.client = server.Client()
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	if len(deltas) != 2 || resp.Text() != "ab" || resp.StopReason != domain.StopEndTurn {
+		t.Errorf("unexpected deltas %q / response %+v", deltas, resp)
+	}
+}
@@ -0,0 +1,404 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	ironctx "ironclaw/internal/context"
+	"ironclaw/internal/domain"
+)
+
+// ErrCassetteMiss is matched (via errors.Is) by the error ReplayProvider
+// returns for a call that has no recording in its cassette.
+var ErrCassetteMiss = errors.New("llm: no recording in cassette")
+
+// Interaction kinds, one per provider method.
+const (
+	kindGenerate = "generate"
+	kindChat     = "chat"
+	kindStream   = "stream"
+)
+
+// ReplayProvider answers calls from a cassette file of recorded request and
+// response pairs, so planner, skill generator and tool-loop scenarios run
+// deterministically without a network. With an upstream provider it records
+// instead: each call goes to upstream and its reply (streamed chunks and tool
+// calls included) is written to the cassette.
+//
+// A recorded call is matched by a hash of its kind, model and full request
+// (message timestamps and IDs excluded). In lenient mode an unmatched call
+// falls back to the first unused recording with the same last message, then
+// to the first unused recording of the same kind, which tolerates prompts
+// that embed dates or memory.
+type ReplayProvider struct {
+	cassette *cassette
+	upstream domain.LLMProvider // records when set, replays when nil
+	model    string
+	lenient  bool
+}
+
+// NewReplayProvider returns a provider replaying the cassette at cfg.Cassette,
+// or recording upstream's replies into it when upstream is non-nil. model is
+// part of each recording's key. Replaying requires the cassette to exist;
+// recording starts a new one.
+func NewReplayProvider(cfg domain.ReplayConfig, model string, upstream domain.LLMProvider) (*ReplayProvider, error) {
+	if cfg.Cassette == "" {
+		return nil, fmt.Errorf("replay provider: cassette not set")
+	}
+	switch cfg.Match {
+	case "", domain.ReplayMatchStrict, domain.ReplayMatchLenient:
+	default:
+		return nil, fmt.Errorf("replay provider: unknown match mode %q (use: %s, %s)", cfg.Match, domain.ReplayMatchStrict, domain.ReplayMatchLenient)
+	}
+	c, err := openCassette(cfg.Cassette, upstream != nil)
+	if err != nil {
+		return nil, err
+	}
+	return &ReplayProvider{
+		cassette: c,
+		upstream: upstream,
+		model:    model,
+		lenient:  cfg.Match == domain.ReplayMatchLenient,
+	}, nil
+}
+
+// Generate implements domain.LLMProvider.
+func (p *ReplayProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	rec := p.newInteraction(kindGenerate, prompt, prompt)
+	if p.upstream != nil {
+		text, err := p.upstream.Generate(ctx, prompt)
+		if err != nil {
+			return "", err
+		}
+		rec.Text = text
+		return text, p.cassette.add(rec)
+	}
+	it, err := p.replay(rec)
+	if err != nil {
+		return "", err
+	}
+	return it.Text, nil
+}
+
+// Chat implements domain.ChatProvider. It returns domain.ErrChatNotSupported
+// when recording from a provider without chat support, and when replaying a
+// cassette recorded from one, so callers fall back to Generate either way.
+func (p *ReplayProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	rec := p.newInteraction(kindChat, canonicalChatRequest(req), lastMessageText(req))
+	if p.upstream != nil {
+		cp, ok := p.upstream.(domain.ChatProvider)
+		if !ok {
+			return domain.ChatResponse{}, domain.ErrChatNotSupported
+		}
+		resp, err := cp.Chat(ctx, req)
+		if err != nil {
+			return domain.ChatResponse{}, err
+		}
+		return resp, p.record(rec, resp)
+	}
+	if !p.cassette.has(kindChat) {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	it, err := p.replay(rec)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return p.response(ctx, it), nil
+}
+
+// ChatStream implements domain.StreamingProvider, recording and replaying the
+// deltas passed to onDelta. Like Chat it returns domain.ErrStreamNotSupported
+// when the upstream, or the recording, did not stream.
+func (p *ReplayProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.ChatResponse{}, err
+	}
+	rec := p.newInteraction(kindStream, canonicalChatRequest(req), lastMessageText(req))
+	if p.upstream != nil {
+		sp, ok := p.upstream.(domain.StreamingProvider)
+		if !ok {
+			return domain.ChatResponse{}, domain.ErrStreamNotSupported
+		}
+		resp, err := sp.ChatStream(ctx, req, func(delta string) {
+			rec.Chunks = append(rec.Chunks, delta)
+			onDelta(delta)
+		})
+		if err != nil {
+			return domain.ChatResponse{}, err
+		}
+		return resp, p.record(rec, resp)
+	}
+	if !p.cassette.has(kindStream) {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	it, err := p.replay(rec)
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	for _, chunk := range it.Chunks {
+		onDelta(chunk)
+	}
+	return p.response(ctx, it), nil
+}
+
+// newInteraction returns the recording of a call of kind with the given
+// request, keyed for matching. last is the text lenient matching compares.
+func (p *ReplayProvider) newInteraction(kind string, request any, last string) interaction {
+	raw, _ := json.Marshal(request)
+	return interaction{
+		Kind:     kind,
+		Model:    p.model,
+		Key:      hashKey(kind, p.model, string(raw)),
+		LooseKey: hashKey(kind, last),
+		Request:  raw,
+	}
+}
+
+// record completes rec with resp and appends it to the cassette.
+func (p *ReplayProvider) record(rec interaction, resp domain.ChatResponse) error {
+	content, err := domain.EncodeContentBlocks(resp.Content)
+	if err != nil {
+		return fmt.Errorf("replay provider: encode response: %w", err)
+	}
+	rec.Content = content
+	rec.StopReason = resp.StopReason
+	rec.Usage = resp.Usage
+	return p.cassette.add(rec)
+}
+
+// replay returns the recording matching rec.
+func (p *ReplayProvider) replay(rec interaction) (interaction, error) {
+	it, ok := p.cassette.match(rec, p.lenient)
+	if !ok {
+		return interaction{}, fmt.Errorf("%w: %s call with key %s", ErrCassetteMiss, rec.Kind, rec.Key)
+	}
+	return it, nil
+}
+
+// response decodes the response of it and reports its recorded usage.
+func (p *ReplayProvider) response(ctx context.Context, it interaction) domain.ChatResponse {
+	reportUsage(ctx, "replay", p.model, "", it.Usage)
+	return domain.ChatResponse{
+		Content:    domain.Message{RawContent: it.Content}.Blocks(),
+		StopReason: it.StopReason,
+		Usage:      it.Usage,
+	}
+}
+
+// canonicalChatRequest returns req with the message fields that differ between
+// otherwise identical runs (IDs, timestamps) cleared, for hashing.
+func canonicalChatRequest(req domain.ChatRequest) domain.ChatRequest {
+	msgs := make([]domain.Message, len(req.Messages))
+	for i, m := range req.Messages {
+		raw, _ := domain.EncodeContentBlocks(m.Blocks())
+		msgs[i] = domain.Message{Role: m.Role, RawContent: raw}
+	}
+	req.Messages = msgs
+	return req
+}
+
+// lastMessageText returns the text of the last message in req.
+func lastMessageText(req domain.ChatRequest) string {
+	if n := len(req.Messages); n > 0 {
+		return ironctx.MessageText(req.Messages[n-1])
+	}
+	return ""
+}
+
+// hashKey returns the hex SHA-256 of parts, NUL-separated.
+func hashKey(parts ...string) string {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write([]byte(part))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// interaction is one recorded call in a cassette.
+type interaction struct {
+	Kind     string          `json:"kind"` // "generate" | "chat" | "stream"
+	Model    string          `json:"model,omitempty"`
+	Key      string          `json:"key"`      // Hash of kind, model and request
+	LooseKey string          `json:"looseKey"` // Hash of kind and last message text
+	Request  json.RawMessage `json:"request"`  // For readers; matching uses the keys
+
+	Text       string            `json:"text,omitempty"`    // Generate reply
+	Content    json.RawMessage   `json:"content,omitempty"` // Chat reply, in message content format
+	StopReason domain.StopReason `json:"stopReason,omitempty"`
+	Usage      domain.Usage      `json:"usage"`
+	Chunks     []string          `json:"chunks,omitempty"` // Streamed deltas, in order
+}
+
+// cassetteFile is the JSON layout of a cassette.
+type cassetteFile struct {
+	RecordedAt   time.Time     `json:"recordedAt"`
+	Interactions []interaction `json:"interactions"`
+}
+
+// cassette holds a cassette's recordings and which have been replayed.
+type cassette struct {
+	path string
+
+	mu           sync.Mutex
+	interactions []interaction
+	used         []bool
+}
+
+// cassettes shares one cassette per file and mode between the providers built
+// for it (one per model profile), so recordings are not overwritten and each
+// is replayed once.
+var (
+	cassettesMu sync.Mutex
+	cassettes   = make(map[string]*cassette)
+)
+
+// openCassette returns the shared cassette at path: empty when recording,
+// loaded from the file when replaying.
+func openCassette(path string, recording bool) (*cassette, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("replay provider: %w", err)
+	}
+	id := fmt.Sprintf("%t:%s", recording, abs)
+	cassettesMu.Lock()
+	defer cassettesMu.Unlock()
+	if c, ok := cassettes[id]; ok {
+		return c, nil
+	}
+	c := &cassette{path: abs}
+	if !recording {
+		raw, err := os.ReadFile(abs)
+		if err != nil {
+			return nil, fmt.Errorf("replay provider: read cassette: %w", err)
+		}
+		var file cassetteFile
+		if err := json.Unmarshal(raw, &file); err != nil {
+			return nil, fmt.Errorf("replay provider: parse cassette %s: %w", abs, err)
+		}
+		c.interactions = file.Interactions
+		c.used = make([]bool, len(file.Interactions))
+	}
+	cassettes[id] = c
+	return c, nil
+}
+
+// add appends it and rewrites the cassette file.
+func (c *cassette) add(it interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, it)
+	c.used = append(c.used, true)
+	raw, err := encodeCassette(cassetteFile{RecordedAt: time.Now().UTC(), Interactions: c.interactions})
+	if err != nil {
+		return fmt.Errorf("replay provider: encode cassette: %w", err)
+	}
+	if err := os.WriteFile(c.path, raw, 0o644); err != nil {
+		return fmt.Errorf("replay provider: write cassette: %w", err)
+	}
+	return nil
+}
+
+// encodeCassette writes f with one interaction per line. Interactions are
+// not indented, since that would reformat the recorded tool call inputs.
+func encodeCassette(f cassetteFile) ([]byte, error) {
+	var buf bytes.Buffer
+	at, _ := json.Marshal(f.RecordedAt)
+	fmt.Fprintf(&buf, "{\n  \"recordedAt\": %s,\n  \"interactions\": [", at)
+	for i, it := range f.Interactions {
+		raw, err := json.Marshal(it)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.WriteString("\n    ")
+		buf.Write(raw)
+	}
+	buf.WriteString("\n  ]\n}\n")
+	return buf.Bytes(), nil
+}
+
+// has reports whether the cassette holds a recording of kind.
+func (c *cassette) has(kind string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, it := range c.interactions {
+		if it.Kind == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the first unused recording with rec's key, or, when all of
+// them have been replayed, the last one again. In lenient mode it then tries
+// the first unused recording with rec's loose key, and the first unused one
+// of rec's kind.
+func (c *cassette) match(rec interaction, lenient bool) (interaction, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if i := c.find(func(it interaction) bool { return it.Key == rec.Key }); i >= 0 {
+		return c.interactions[i], true
+	}
+	if !lenient {
+		return interaction{}, false
+	}
+	for _, same := range []func(interaction) bool{
+		func(it interaction) bool { return it.Kind == rec.Kind && it.LooseKey == rec.LooseKey },
+		func(it interaction) bool { return it.Kind == rec.Kind },
+	} {
+		if i := c.findUnused(same); i >= 0 {
+			return c.interactions[i], true
+		}
+	}
+	return interaction{}, false
+}
+
+// find marks and returns the index of the first unused recording satisfying
+// same, or of the last used one when none is unused; -1 when none does.
+func (c *cassette) find(same func(interaction) bool) int {
+	if i := c.findUnused(same); i >= 0 {
+		return i
+	}
+	for i := len(c.interactions) - 1; i >= 0; i-- {
+		if same(c.interactions[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// findUnused marks and returns the index of the first unused recording
+// satisfying same, or -1.
+func (c *cassette) findUnused(same func(interaction) bool) int {
+	for i, it := range c.interactions {
+		if !c.used[i] && same(it) {
+			c.used[i] = true
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	_ domain.LLMProvider       = (*ReplayProvider)(nil)
+	_ domain.ChatProvider      = (*ReplayProvider)(nil)
+	_ domain.StreamingProvider = (*ReplayProvider)(nil)
+)
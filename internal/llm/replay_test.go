@@ -0,0 +1,199 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// upstreamProvider answers every call with fixed replies and counts calls.
+type upstreamProvider struct {
+	calls int
+}
+
+func (u *upstreamProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	u.calls++
+	return "generated: " + prompt, nil
+}
+
+func (u *upstreamProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	u.calls++
+	return domain.ChatResponse{
+		Content:    []domain.ContentBlock{domain.ToolUseBlock{ToolUseID: "t1", Name: "read_file", Input: json.RawMessage(`{"path":"a.txt"}`)}},
+		StopReason: domain.StopToolUse,
+		Usage:      domain.Usage{InputTokens: 5, OutputTokens: 2},
+	}, nil
+}
+
+func (u *upstreamProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	u.calls++
+	onDelta("Hel")
+	onDelta("lo")
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: "Hello"}}, StopReason: domain.StopEndTurn}, nil
+}
+
+// chatAt returns a one-message request whose message carries timestamp at.
+func chatAt(system, text string, at time.Time) domain.ChatRequest {
+	msg := domain.NewTextMessage(domain.RoleUser, text)
+	msg.Timestamp = at
+	return domain.ChatRequest{System: system, Messages: []domain.Message{msg}}
+}
+
+// recordCassette records one call of each kind into a new cassette and
+// returns its path.
+func recordCassette(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, err := NewReplayProvider(domain.ReplayConfig{Cassette: path}, "m", &upstreamProvider{})
+	if err != nil {
+		t.Fatalf("NewReplayProvider(record): %v", err)
+	}
+	ctx := context.Background()
+	if _, err := rec.Generate(ctx, "plan it"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if _, err := rec.Chat(ctx, chatAt("sys", "read a.txt", time.Unix(1, 0))); err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	if _, err := rec.ChatStream(ctx, chatAt("sys", "say hello", time.Unix(1, 0)), func(string) {}); err != nil {
+		t.Fatalf("ChatStream: %v", err)
+	}
+	return path
+}
+
+func TestReplayProvider_WhenRecorded_ShouldReplayExactly(t *testing.T) {
+	path := recordCassette(t)
+	p, err := NewReplayProvider(domain.ReplayConfig{Cassette: path}, "m", nil)
+	if err != nil {
+		t.Fatalf("NewReplayProvider: %v", err)
+	}
+	ctx := context.Background()
+
+	if out, err := p.Generate(ctx, "plan it"); err != nil || out != "generated: plan it" {
+		t.Errorf("Generate = %q, %v", out, err)
+	}
+	resp, err := p.Chat(ctx, chatAt("sys", "read a.txt", time.Now()))
+	if err != nil {
+		t.Fatalf("Chat: %v", err)
+	}
+	uses := resp.ToolUses()
+	if len(uses) != 1 || uses[0].Name != "read_file" || string(uses[0].Input) != `{"path":"a.txt"}` || resp.StopReason != domain.StopToolUse || resp.Usage.InputTokens != 5 {
+		t.Errorf("Chat = %+v", resp)
+	}
+	var deltas []string
+	resp, err = p.ChatStream(ctx, chatAt("sys", "say hello", time.Now()), func(d string) { deltas = append(deltas, d) })
+	if err != nil || resp.Text() != "Hello" || strings.Join(deltas, "|") != "Hel|lo" {
+		t.Errorf("ChatStream = %q, %v, deltas %q", resp.Text(), err, deltas)
+	}
+}
+
+func TestReplayProvider_WhenStrictAndRequestDiffers_ShouldReturnErrCassetteMiss(t *testing.T) {
+	p, err := NewReplayProvider(domain.ReplayConfig{Cassette: recordCassette(t)}, "m", nil)
+	if err != nil {
+		t.Fatalf("NewReplayProvider: %v", err)
+	}
+
+	if _, err := p.Chat(context.Background(), chatAt("other system", "read a.txt", time.Now())); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("Chat err = %v, want ErrCassetteMiss", err)
+	}
+	if _, err := p.Generate(context.Background(), "something else"); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("Generate err = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestReplayProvider_WhenLenient_ShouldMatchLastMessageThenOrder(t *testing.T) {
+	p, err := NewReplayProvider(domain.ReplayConfig{Cassette: recordCassette(t), Match: domain.ReplayMatchLenient}, "m", nil)
+	if err != nil {
+		t.Fatalf("NewReplayProvider: %v", err)
+	}
+	ctx := context.Background()
+
+	resp, err := p.Chat(ctx, chatAt("system with today's date", "read a.txt", time.Now()))
+	if err != nil || len(resp.ToolUses()) != 1 {
+		t.Errorf("Chat by last message = %+v, %v", resp, err)
+	}
+	if out, err := p.Generate(ctx, "a reworded plan prompt"); err != nil || out != "generated: plan it" {
+		t.Errorf("Generate by order = %q, %v", out, err)
+	}
+	if _, err := p.Generate(ctx, "one too many"); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("Generate after cassette used up: err = %v, want ErrCassetteMiss", err)
+	}
+}
+
+func TestReplayProvider_WhenCassetteHasNoChat_ShouldReportNotSupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	rec, _ := NewReplayProvider(domain.ReplayConfig{Cassette: path}, "", NewLocalProvider("Local: "))
+	if _, err := rec.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Fatalf("recording ChatStream err = %v, want ErrStreamNotSupported", err)
+	}
+	if _, err := rec.Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+
+	p, err := NewReplayProvider(domain.ReplayConfig{Cassette: path}, "", nil)
+	if err != nil {
+		t.Fatalf("NewReplayProvider: %v", err)
+	}
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{}); !errors.Is(err, domain.ErrChatNotSupported) {
+		t.Errorf("Chat err = %v, want ErrChatNotSupported", err)
+	}
+	if _, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("ChatStream err = %v, want ErrStreamNotSupported", err)
+	}
+}
+
+func TestNewReplayProvider_WhenConfigInvalid_ShouldReturnError(t *testing.T) {
+	dir := t.TempDir()
+	for name, cfg := range map[string]domain.ReplayConfig{
+		"no cassette":    {},
+		"unknown match":  {Cassette: filepath.Join(dir, "c.json"), Match: "fuzzy"},
+		"missing file":   {Cassette: filepath.Join(dir, "missing.json")},
+		"not a cassette": {Cassette: writeFile(t, dir, "bad.json", "not json")},
+	} {
+		if _, err := NewReplayProvider(cfg, "", nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestNewProvider_WhenReplay_ShouldRecordFromNamedProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	agents := &domain.AgentsConfig{Provider: "replay", Replay: &domain.ReplayConfig{Cassette: path, Record: "local"}}
+
+	p, err := NewProvider(agents, nil)
+	if err != nil {
+		t.Fatalf("NewProvider: %v", err)
+	}
+	if out, err := p.Generate(context.Background(), "hi"); err != nil || out != "Local: hi" {
+		t.Fatalf("Generate = %q, %v", out, err)
+	}
+	if raw, err := os.ReadFile(path); err != nil || !strings.Contains(string(raw), `"text":"Local: hi"`) {
+		t.Errorf("cassette = %s, %v", raw, err)
+	}
+
+	for name, a := range map[string]*domain.AgentsConfig{
+		"no replay config":  {Provider: "replay"},
+		"records itself":    {Provider: "replay", Replay: &domain.ReplayConfig{Cassette: path, Record: "replay"}},
+		"unknown recording": {Provider: "replay", Replay: &domain.ReplayConfig{Cassette: path, Record: "nope"}},
+	} {
+		if _, err := NewProvider(a, nil); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
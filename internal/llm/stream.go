@@ -0,0 +1,82 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// maxStreamLine bounds a single SSE or NDJSON line. Tool-call chunks and
+// Gemini candidates can exceed bufio.Scanner's 64KB default.
+const maxStreamLine = 1 << 20
+
+// errStreamDone is returned by stream handlers to stop reading early (e.g. on
+// OpenAI's "[DONE]" sentinel). readSSE and readNDJSON treat it as success.
+var errStreamDone = errors.New("stream done")
+
+// readSSE parses a text/event-stream body and calls fn once per event with the
+// event name (empty when absent) and its data lines joined by "\n". Comment
+// lines and unknown fields are ignored. Stops at EOF or the first fn error.
+func readSSE(r io.Reader, fn func(event string, data []byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	var event string
+	var data [][]byte
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := fn(event, bytes.Join(data, []byte("\n")))
+		event, data = "", nil
+		return err
+	}
+	for sc.Scan() {
+		line := sc.Bytes()
+		switch {
+		case len(line) == 0:
+			if err := dispatch(); err != nil {
+				return ignoreDone(err)
+			}
+		case line[0] == ':':
+			// comment / keep-alive
+		case bytes.HasPrefix(line, []byte("event:")):
+			event = string(bytes.TrimSpace(line[len("event:"):]))
+		case bytes.HasPrefix(line, []byte("data:")):
+			d := line[len("data:"):]
+			if len(d) > 0 && d[0] == ' ' {
+				d = d[1:]
+			}
+			data = append(data, append([]byte(nil), d...))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return err
+	}
+	return ignoreDone(dispatch())
+}
+
+// readNDJSON calls fn for every non-empty line of a newline-delimited JSON body.
+// Stops at EOF or the first fn error.
+func readNDJSON(r io.Reader, fn func(line []byte) error) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), maxStreamLine)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return ignoreDone(err)
+		}
+	}
+	return sc.Err()
+}
+
+func ignoreDone(err error) error {
+	if errors.Is(err, errStreamDone) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,49 @@
+package llm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestReadSSE_ShouldDispatchEventsAndJoinDataLines(t *testing.T) {
+	body := ": keep-alive\nevent: ping\ndata: {}\n\ndata: line1\ndata: line2\n\ndata: tail"
+	var events, datas []string
+	err := readSSE(strings.NewReader(body), func(event string, data []byte) error {
+		events = append(events, event)
+		datas = append(datas, string(data))
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("readSSE: %v", err)
+	}
+	if len(datas) != 3 || events[0] != "ping" || events[1] != "" || datas[1] != "line1\nline2" || datas[2] != "tail" {
+		t.Errorf("unexpected events %q / data %q", events, datas)
+	}
+}
+
+func TestReadSSE_WhenHandlerReturnsDone_ShouldStopWithoutError(t *testing.T) {
+	calls := 0
+	err := readSSE(strings.NewReader("data: a\n\ndata: b\n\n"), func(string, []byte) error {
+		calls++
+		return errStreamDone
+	})
+	if err != nil || calls != 1 {
+		t.Errorf("expected nil error after 1 call, got %v after %d calls", err, calls)
+	}
+}
+
+func TestReadNDJSON_WhenHandlerFails_ShouldReturnError(t *testing.T) {
+	boom := errors.New("boom")
+	var lines []string
+	err := readNDJSON(strings.NewReader("{\"a\":1}\n\n{\"b\":2}\n"), func(line []byte) error {
+		lines = append(lines, string(line))
+		if len(lines) == 2 {
+			return boom
+		}
+		return nil
+	})
+	if !errors.Is(err, boom) || len(lines) != 2 || lines[1] != `{"b":2}` {
+		t.Errorf("got err=%v lines=%q", err, lines)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	"ironclaw/internal/domain"
+	"ironclaw/internal/tooling"
 )
 
 // StepStatus represents the execution state of a plan step.
@@ -105,7 +106,7 @@ func (p *Planner) Execute(ctx context.Context, goal string) (*PlanResult, error)
 	planPrompt := BuildPlanPrompt(goal)
 	p.log().Info("generating plan", "goal", goal)
 
-	planResponse, err := p.llm.Generate(ctx, planPrompt)
+	planResponse, err := p.generatePlan(ctx, planPrompt)
 	if err != nil {
 		return nil, fmt.Errorf("planner: failed to generate plan: %w", err)
 	}
@@ -172,6 +173,46 @@ func (p *Planner) Execute(ctx context.Context, goal string) (*PlanResult, error)
 	}, nil
 }
 
+// structuredGenerator is implemented by providers (such as brain.Brain) that
+// can return JSON validated against a schema, repairing malformed replies.
+type structuredGenerator interface {
+	GenerateStructured(ctx context.Context, prompt string, schema string) (json.RawMessage, error)
+}
+
+// planSchema is the JSON Schema of the plan BuildPlanPrompt asks for.
+const planSchema = `{
+  "type": "object",
+  "properties": {
+    "steps": {
+      "type": "array",
+      "minItems": 1,
+      "items": {
+        "type": "object",
+        "properties": {
+          "id": {"type": "integer"},
+          "description": {"type": "string", "minLength": 1}
+        },
+        "required": ["id", "description"]
+      }
+    }
+  },
+  "required": ["steps"]
+}`
+
+// generatePlan asks the LLM for a plan, as schema-validated JSON when it
+// supports structured output and as free text for ParsePlan otherwise.
+func (p *Planner) generatePlan(ctx context.Context, prompt string) (string, error) {
+	sg, ok := p.llm.(structuredGenerator)
+	if !ok {
+		return p.llm.Generate(ctx, prompt)
+	}
+	raw, err := sg.GenerateStructured(ctx, prompt, planSchema)
+	if err != nil {
+		return "", err
+	}
+	return string(raw), nil
+}
+
 // ParsePlan extracts a Plan from an LLM response string.
 // Handles raw JSON, markdown-wrapped code blocks, and JSON embedded in prose.
 func ParsePlan(raw string, goal string) (*Plan, error) {
@@ -180,7 +221,7 @@ func ParsePlan(raw string, goal string) (*Plan, error) {
 		return nil, fmt.Errorf("planner: empty response")
 	}
 
-	jsonStr := extractJSON(raw)
+	jsonStr := tooling.ExtractJSON(raw)
 	if jsonStr == "" {
 		return nil, fmt.Errorf("planner: no valid JSON found in response")
 	}
@@ -215,35 +256,6 @@ func ParsePlan(raw string, goal string) (*Plan, error) {
 	return &Plan{Goal: goal, Steps: steps}, nil
 }
 
-// extractJSON tries to find a JSON object in the response. It checks for:
-// 1. Markdown code blocks (```json ... ``` or ``` ... ```)
-// 2. Raw JSON object
-// 3. JSON object embedded in prose text
-func extractJSON(raw string) string {
-	// Try markdown code block first.
-	if idx := strings.Index(raw, "```"); idx >= 0 {
-		start := strings.Index(raw[idx:], "\n")
-		if start < 0 {
-			return ""
-		}
-		start += idx + 1
-		end := strings.Index(raw[start:], "```")
-		if end < 0 {
-			return ""
-		}
-		return strings.TrimSpace(raw[start : start+end])
-	}
-
-	// Try raw JSON: find first { and last }.
-	braceStart := strings.Index(raw, "{")
-	braceEnd := strings.LastIndex(raw, "}")
-	if braceStart >= 0 && braceEnd > braceStart {
-		return raw[braceStart : braceEnd+1]
-	}
-
-	return ""
-}
-
 // BuildPlanPrompt creates the prompt that asks the LLM to decompose a goal into steps.
 func BuildPlanPrompt(goal string) string {
 	return fmt.Sprintf(`You are a task planner. Break down the following goal into clear, actionable steps.
@@ -2,6 +2,7 @@ package planner
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -420,6 +421,36 @@ func TestExecute_HappyPath_ShouldDecomposeAndExecuteAllSteps(t *testing.T) {
 	}
 }
 
+// structuredMock is a sequentialMock that also serves structured plan requests.
+type structuredMock struct {
+	sequentialMock
+	plan   string
+	schema string // schema passed to GenerateStructured
+}
+
+func (m *structuredMock) GenerateStructured(ctx context.Context, prompt string, schema string) (json.RawMessage, error) {
+	m.schema = schema
+	return json.RawMessage(m.plan), nil
+}
+
+func TestExecute_WhenLLMSupportsStructuredOutput_ShouldRequestPlanWithSchema(t *testing.T) {
+	mock := &structuredMock{
+		sequentialMock: sequentialMock{responses: []string{"done", "summary"}},
+		plan:           `{"steps": [{"id": 1, "description": "Only step"}]}`,
+	}
+
+	result, err := NewPlanner(mock).Execute(context.Background(), "goal")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if mock.schema != planSchema {
+		t.Errorf("schema = %q, want planSchema", mock.schema)
+	}
+	if len(result.Plan.Steps) != 1 || result.Plan.Steps[0].Result != "done" || result.FinalSummary != "summary" {
+		t.Errorf("result = %+v", result)
+	}
+}
+
 func TestExecute_ShouldSetGoalOnPlan(t *testing.T) {
 	mock := &sequentialMock{
 		responses: []string{
@@ -728,27 +759,6 @@ func (m *contextAwareMock) Generate(ctx context.Context, prompt string) (string,
 	return "ok", nil
 }
 
-func TestExtractJSON_WhenNoJSON_ShouldReturnEmpty(t *testing.T) {
-	result := extractJSON("no json here at all")
-	if result != "" {
-		t.Errorf("expected empty string, got %q", result)
-	}
-}
-
-func TestExtractJSON_WhenCodeBlockHasNoClosing_ShouldReturnEmpty(t *testing.T) {
-	result := extractJSON("```json\n{\"steps\": []}")
-	if result != "" {
-		t.Errorf("expected empty string for unclosed code block, got %q", result)
-	}
-}
-
-func TestExtractJSON_WhenCodeBlockHasNoNewline_ShouldReturnEmpty(t *testing.T) {
-	result := extractJSON("```")
-	if result != "" {
-		t.Errorf("expected empty string, got %q", result)
-	}
-}
-
 func TestParsePlan_WhenWhitespaceOnly_ShouldReturnError(t *testing.T) {
 	_, err := ParsePlan("   \n\t  ", "goal")
 	if err == nil {
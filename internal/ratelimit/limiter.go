@@ -0,0 +1,325 @@
+// Package ratelimit enforces client-side limits on provider calls: requests
+// in flight, requests per minute and tokens per minute, per provider and per
+// API key. Calls over a limit wait for capacity (or for their context to end)
+// rather than reaching the provider and coming back with a 429.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// Config is the set of limits of one provider or key. Zero fields are
+// unlimited.
+type Config struct {
+	MaxConcurrent     int // Requests in flight at once
+	RequestsPerMinute int // Requests started in any minute
+	TokensPerMinute   int // Input plus output tokens used in any minute
+}
+
+// FromConfig converts the JSON config.
+func FromConfig(c domain.RateLimitConfig) Config {
+	return Config{
+		MaxConcurrent:     c.MaxConcurrent,
+		RequestsPerMinute: c.RequestsPerMinute,
+		TokensPerMinute:   c.TokensPerMinute,
+	}
+}
+
+// Unlimited reports whether c sets no limit.
+func (c Config) Unlimited() bool {
+	return c.MaxConcurrent <= 0 && c.RequestsPerMinute <= 0 && c.TokensPerMinute <= 0
+}
+
+// Status is a snapshot of one limiter, as served by the gateway.
+type Status struct {
+	Name              string `json:"name"`
+	MaxConcurrent     int    `json:"maxConcurrent,omitempty"`
+	RequestsPerMinute int    `json:"requestsPerMinute,omitempty"`
+	TokensPerMinute   int    `json:"tokensPerMinute,omitempty"`
+
+	InFlight int `json:"inFlight"` // Requests currently running
+	Queued   int `json:"queued"`   // Callers currently waiting
+	Requests int `json:"requests"` // Requests started in the last minute
+	Tokens   int `json:"tokens"`   // Tokens used by requests started in the last minute
+	Waited   int `json:"waited"`   // Requests that had to wait, since startup
+	WaitMs   int `json:"waitMs"`   // Total time those requests waited, in milliseconds
+}
+
+// Limiter admits the calls to one provider or key. It is safe for concurrent
+// use.
+type Limiter struct {
+	name   string
+	cfg    Config
+	now    func() time.Time
+	window time.Duration
+
+	mu       sync.Mutex
+	inFlight int
+	queued   int
+	recent   []*start      // requests started within window, oldest first
+	freed    chan struct{} // closed (and replaced) when a request finishes
+	waited   int
+	waitTime time.Duration
+}
+
+// start is one admitted request and the tokens it has used.
+type start struct {
+	at     time.Time
+	tokens int
+}
+
+// Permit is an admitted request. Every Permit must be ended with Done or
+// Cancel.
+type Permit struct {
+	l     *Limiter
+	start *start
+}
+
+// Acquire waits until a request may start without exceeding the limits, or
+// until ctx ends, in which case it returns an error wrapping ctx.Err(). The
+// tokens limit admits requests while the last minute's usage is below it, so
+// a single large request can overshoot it.
+func (l *Limiter) Acquire(ctx context.Context) (*Permit, error) {
+	var began time.Time
+	l.mu.Lock()
+	for {
+		wait, ok := l.admit()
+		if ok {
+			break
+		}
+		if began.IsZero() {
+			began = l.now()
+			l.waited++
+		}
+		freed := l.freed
+		l.queued++
+		l.mu.Unlock()
+		err := sleep(ctx, wait, freed)
+		l.mu.Lock()
+		l.queued--
+		if err != nil {
+			l.waitTime += l.now().Sub(began)
+			l.mu.Unlock()
+			return nil, fmt.Errorf("ratelimit: %s: %w", l.name, err)
+		}
+	}
+	if !began.IsZero() {
+		l.waitTime += l.now().Sub(began)
+	}
+	s := &start{at: l.now()}
+	l.recent = append(l.recent, s)
+	l.inFlight++
+	l.mu.Unlock()
+	return &Permit{l: l, start: s}, nil
+}
+
+// admit reports whether a request may start now and, if not, how long until
+// the rate limits next change (0 when only a finishing request can help).
+// Callers hold mu.
+func (l *Limiter) admit() (time.Duration, bool) {
+	now := l.now()
+	l.prune(now)
+	if l.cfg.MaxConcurrent > 0 && l.inFlight >= l.cfg.MaxConcurrent {
+		return 0, false
+	}
+	if l.cfg.RequestsPerMinute > 0 && len(l.recent) >= l.cfg.RequestsPerMinute {
+		return l.recent[0].at.Add(l.window).Sub(now), false
+	}
+	if l.cfg.TokensPerMinute > 0 && l.tokens() >= l.cfg.TokensPerMinute {
+		return l.recent[0].at.Add(l.window).Sub(now), false
+	}
+	return 0, true
+}
+
+// prune drops the requests that started a window or more before now.
+// Callers hold mu.
+func (l *Limiter) prune(now time.Time) {
+	i := 0
+	for i < len(l.recent) && !now.Before(l.recent[i].at.Add(l.window)) {
+		i++
+	}
+	l.recent = l.recent[i:]
+}
+
+// tokens sums the tokens of the requests in the window. Callers hold mu.
+func (l *Limiter) tokens() int {
+	n := 0
+	for _, s := range l.recent {
+		n += s.tokens
+	}
+	return n
+}
+
+// sleep waits for d (forever when d is 0), a close of freed, or the end of
+// ctx, returning ctx.Err() in the last case.
+func sleep(ctx context.Context, d time.Duration, freed <-chan struct{}) error {
+	var timeout <-chan time.Time
+	if d > 0 {
+		t := time.NewTimer(d)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-freed:
+	case <-timeout:
+	}
+	return nil
+}
+
+// AddTokens counts n more tokens against the request. Providers report usage
+// once per call, so it is usually called once, before Done.
+func (p *Permit) AddTokens(n int) {
+	p.l.mu.Lock()
+	defer p.l.mu.Unlock()
+	p.start.tokens += n
+}
+
+// Done ends the request, letting a waiting caller start.
+func (p *Permit) Done() {
+	p.l.finish(nil)
+}
+
+// Cancel ends a request that never reached the provider (e.g. a decorator
+// reported it unsupported), so it does not count against the rate limits.
+func (p *Permit) Cancel() {
+	p.l.finish(p.start)
+}
+
+// finish releases an in-flight slot, removing unsent from the window, and
+// wakes the waiting callers.
+func (l *Limiter) finish(unsent *start) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	if unsent != nil {
+		for i, s := range l.recent {
+			if s == unsent {
+				l.recent = append(l.recent[:i], l.recent[i+1:]...)
+				break
+			}
+		}
+	}
+	close(l.freed)
+	l.freed = make(chan struct{})
+}
+
+// Status returns a snapshot of the limiter.
+func (l *Limiter) Status() Status {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.prune(l.now())
+	return Status{
+		Name:              l.name,
+		MaxConcurrent:     l.cfg.MaxConcurrent,
+		RequestsPerMinute: l.cfg.RequestsPerMinute,
+		TokensPerMinute:   l.cfg.TokensPerMinute,
+		InFlight:          l.inFlight,
+		Queued:            l.queued,
+		Requests:          len(l.recent),
+		Tokens:            l.tokens(),
+		Waited:            l.waited,
+		WaitMs:            int(l.waitTime / time.Millisecond),
+	}
+}
+
+// Option configures a Set.
+type Option func(*Set)
+
+// WithClock replaces time.Now, for tests.
+func WithClock(now func() time.Time) Option {
+	return func(s *Set) {
+		if now != nil {
+			s.now = now
+		}
+	}
+}
+
+// Set holds the limiters of all providers and keys, keyed by name, so brains
+// that share a provider (e.g. named agents) share its limits. A nil *Set
+// imposes no limits. It is safe for concurrent use.
+type Set struct {
+	limits map[string]domain.RateLimitConfig
+	now    func() time.Time
+	window time.Duration // rate limit window; a minute outside tests
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewSet returns a Set enforcing limits, keyed by provider name.
+func NewSet(limits map[string]domain.RateLimitConfig, opts ...Option) *Set {
+	s := &Set{
+		limits:   limits,
+		now:      time.Now,
+		window:   time.Minute,
+		limiters: make(map[string]*Limiter),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Provider returns the limiter of provider, or nil when it has no limits.
+func (s *Set) Provider(provider string) *Limiter {
+	if s == nil {
+		return nil
+	}
+	return s.get(provider, FromConfig(s.limits[provider]))
+}
+
+// Key returns the limiter of provider's API key, named by its
+// usage.KeyID, or nil when the provider sets no per-key limits.
+func (s *Set) Key(provider, keyID string) *Limiter {
+	if s == nil {
+		return nil
+	}
+	perKey := s.limits[provider].PerKey
+	if perKey == nil {
+		return nil
+	}
+	return s.get(provider+" key "+keyID, FromConfig(*perKey))
+}
+
+// get returns the limiter named name, creating it with cfg on first use, or
+// nil when cfg is unlimited.
+func (s *Set) get(name string, cfg Config) *Limiter {
+	if cfg.Unlimited() {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	l, ok := s.limiters[name]
+	if !ok {
+		l = &Limiter{name: name, cfg: cfg, now: s.now, window: s.window, freed: make(chan struct{})}
+		s.limiters[name] = l
+	}
+	return l
+}
+
+// Status returns a snapshot of every limiter in use, sorted by name.
+func (s *Set) Status() []Status {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	limiters := make([]*Limiter, 0, len(s.limiters))
+	for _, l := range s.limiters {
+		limiters = append(limiters, l)
+	}
+	s.mu.Unlock()
+	out := make([]Status, len(limiters))
+	for i, l := range limiters {
+		out[i] = l.Status()
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
@@ -0,0 +1,133 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// newTestSet returns a Set with a short window so rate limits clear quickly.
+func newTestSet(limits map[string]domain.RateLimitConfig) *Set {
+	s := NewSet(limits)
+	s.window = 50 * time.Millisecond
+	return s
+}
+
+func TestLimiter_WhenMaxConcurrentReached_ShouldWaitForDone(t *testing.T) {
+	l := newTestSet(map[string]domain.RateLimitConfig{"p": {MaxConcurrent: 1}}).Provider("p")
+	first, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	acquired := make(chan *Permit)
+	go func() {
+		p, _ := l.Acquire(context.Background())
+		acquired <- p
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second Acquire admitted while first in flight")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if st := l.Status(); st.InFlight != 1 || st.Queued != 1 {
+		t.Errorf("status = %+v, want 1 in flight and 1 queued", st)
+	}
+
+	first.Done()
+	select {
+	case p := <-acquired:
+		p.Done()
+	case <-time.After(time.Second):
+		t.Fatal("second Acquire not admitted after Done")
+	}
+	if st := l.Status(); st.InFlight != 0 || st.Waited != 1 {
+		t.Errorf("status = %+v, want 0 in flight and 1 waited", st)
+	}
+}
+
+func TestLimiter_WhenRequestsPerMinuteReached_ShouldWaitForWindow(t *testing.T) {
+	l := newTestSet(map[string]domain.RateLimitConfig{"p": {RequestsPerMinute: 2}}).Provider("p")
+	for i := 0; i < 2; i++ {
+		p, err := l.Acquire(context.Background())
+		if err != nil {
+			t.Fatalf("Acquire %d: %v", i, err)
+		}
+		p.Done()
+	}
+
+	began := time.Now()
+	p, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	p.Done()
+	if waited := time.Since(began); waited < 30*time.Millisecond {
+		t.Errorf("third request waited %v, want about the window", waited)
+	}
+}
+
+func TestLimiter_WhenTokensPerMinuteUsed_ShouldWait(t *testing.T) {
+	l := newTestSet(map[string]domain.RateLimitConfig{"p": {TokensPerMinute: 100}}).Provider("p")
+	p, _ := l.Acquire(context.Background())
+	p.AddTokens(150)
+	p.Done()
+	if st := l.Status(); st.Tokens != 150 {
+		t.Errorf("tokens = %d, want 150", st.Tokens)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := l.Acquire(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Acquire over token limit: err = %v, want deadline exceeded", err)
+	}
+	if p, err := l.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire after window: %v", err)
+	} else {
+		p.Done()
+	}
+}
+
+func TestLimiter_WhenCanceled_ShouldNotCountRequest(t *testing.T) {
+	l := newTestSet(map[string]domain.RateLimitConfig{"p": {RequestsPerMinute: 1}}).Provider("p")
+	p, _ := l.Acquire(context.Background())
+	p.Cancel()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	p, err := l.Acquire(ctx)
+	if err != nil {
+		t.Fatalf("Acquire after Cancel: %v", err)
+	}
+	p.Done()
+}
+
+func TestSet_WhenNoLimits_ShouldReturnNilLimiters(t *testing.T) {
+	var nilSet *Set
+	if nilSet.Provider("p") != nil || nilSet.Key("p", "k") != nil || nilSet.Status() != nil {
+		t.Error("nil Set should impose no limits")
+	}
+	s := NewSet(map[string]domain.RateLimitConfig{"p": {MaxConcurrent: 1}})
+	if s.Provider("other") != nil || s.Key("p", "k") != nil {
+		t.Error("unconfigured provider or key should have no limiter")
+	}
+}
+
+func TestSet_ShouldShareLimitersAndReportStatusByName(t *testing.T) {
+	s := NewSet(map[string]domain.RateLimitConfig{
+		"openai": {RequestsPerMinute: 10, PerKey: &domain.RateLimitConfig{MaxConcurrent: 1}},
+	})
+	if s.Provider("openai") != s.Provider("openai") {
+		t.Error("Provider should return the same limiter each time")
+	}
+	s.Key("openai", "sk-b")
+	s.Key("openai", "sk-a")
+
+	st := s.Status()
+	if len(st) != 3 || st[0].Name != "openai" || st[1].Name != "openai key sk-a" || st[2].Name != "openai key sk-b" || st[1].MaxConcurrent != 1 {
+		t.Errorf("status = %+v", st)
+	}
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+// Provider wraps an LLMProvider with a Limiter: each call waits for a permit
+// before reaching the provider, and the tokens the provider reports (see
+// usage.Report) are counted against the limiter's tokens per minute.
+type Provider struct {
+	inner   domain.LLMProvider
+	limiter *Limiter
+}
+
+// Wrap returns inner limited by l, or inner itself when l is nil (no
+// limits). inner must not be nil.
+func Wrap(l *Limiter, inner domain.LLMProvider) domain.LLMProvider {
+	if inner == nil {
+		panic("ratelimit: inner provider must not be nil")
+	}
+	if l == nil {
+		return inner
+	}
+	return &Provider{inner: inner, limiter: l}
+}
+
+// Generate implements domain.LLMProvider.
+func (p *Provider) Generate(ctx context.Context, prompt string) (string, error) {
+	var result string
+	err := p.do(ctx, func(ctx context.Context) error {
+		var genErr error
+		result, genErr = p.inner.Generate(ctx, prompt)
+		return genErr
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Chat implements domain.ChatProvider. Returns domain.ErrChatNotSupported,
+// without waiting, when the inner provider cannot chat.
+func (p *Provider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	cp, ok := p.inner.(domain.ChatProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	var result domain.ChatResponse
+	err := p.do(ctx, func(ctx context.Context) error {
+		var chatErr error
+		result, chatErr = cp.Chat(ctx, req)
+		return chatErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// ChatStream implements domain.StreamingProvider. Returns
+// domain.ErrStreamNotSupported, without waiting, when the inner provider
+// cannot stream.
+func (p *Provider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	sp, ok := p.inner.(domain.StreamingProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	var result domain.ChatResponse
+	err := p.do(ctx, func(ctx context.Context) error {
+		var streamErr error
+		result, streamErr = sp.ChatStream(ctx, req, onDelta)
+		return streamErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// do runs call once the limiter admits it, with a context that counts the
+// usage the provider reports before passing it on. A call a decorator
+// reports unsupported is not counted as a request.
+func (p *Provider) do(ctx context.Context, call func(context.Context) error) error {
+	permit, err := p.limiter.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	err = call(usage.WithRecorder(ctx, tokenCounter{parent: ctx, permit: permit}))
+	if errors.Is(err, domain.ErrChatNotSupported) || errors.Is(err, domain.ErrStreamNotSupported) {
+		permit.Cancel()
+	} else {
+		permit.Done()
+	}
+	return err
+}
+
+// tokenCounter adds reported usage to a permit, then reports it on to the
+// recorder of the caller's context.
+type tokenCounter struct {
+	parent context.Context
+	permit *Permit
+}
+
+// RecordCall implements usage.Recorder.
+func (t tokenCounter) RecordCall(ctx context.Context, call usage.Call) {
+	t.permit.AddTokens(call.InputTokens + call.OutputTokens)
+	usage.Report(t.parent, call)
+}
+
+// Compile-time check that Provider implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*Provider)(nil)
+	_ domain.ChatProvider      = (*Provider)(nil)
+	_ domain.StreamingProvider = (*Provider)(nil)
+	_ usage.Recorder           = tokenCounter{}
+)
@@ -0,0 +1,60 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+
+	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
+)
+
+// reportingProvider reports fixed usage for every call.
+type reportingProvider struct{}
+
+func (reportingProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	usage.Report(ctx, usage.Call{Provider: "fake", InputTokens: 30, OutputTokens: 12})
+	return "ok", nil
+}
+
+// recorder collects the calls reported to it.
+type recorder struct{ calls []usage.Call }
+
+func (r *recorder) RecordCall(ctx context.Context, call usage.Call) { r.calls = append(r.calls, call) }
+
+func TestWrap_WhenLimiterNil_ShouldReturnInner(t *testing.T) {
+	inner := reportingProvider{}
+	if got := Wrap(nil, inner); got != domain.LLMProvider(inner) {
+		t.Errorf("Wrap(nil) = %T, want inner", got)
+	}
+}
+
+func TestProvider_ShouldCountReportedTokensAndForwardUsage(t *testing.T) {
+	l := NewSet(map[string]domain.RateLimitConfig{"fake": {TokensPerMinute: 1000}}).Provider("fake")
+	rec := &recorder{}
+	ctx := usage.WithRecorder(context.Background(), rec)
+
+	if out, err := Wrap(l, reportingProvider{}).Generate(ctx, "hi"); err != nil || out != "ok" {
+		t.Fatalf("Generate = %q, %v", out, err)
+	}
+	if st := l.Status(); st.Tokens != 42 || st.Requests != 1 || st.InFlight != 0 {
+		t.Errorf("status = %+v, want 42 tokens in 1 finished request", st)
+	}
+	if len(rec.calls) != 1 || rec.calls[0].InputTokens != 30 {
+		t.Errorf("forwarded calls = %+v", rec.calls)
+	}
+}
+
+func TestProvider_WhenInnerCannotChat_ShouldNotCountRequest(t *testing.T) {
+	l := NewSet(map[string]domain.RateLimitConfig{"fake": {RequestsPerMinute: 1}}).Provider("fake")
+	p := Wrap(l, reportingProvider{}).(*Provider)
+
+	if _, err := p.Chat(context.Background(), domain.ChatRequest{}); err != domain.ErrChatNotSupported {
+		t.Errorf("Chat err = %v, want ErrChatNotSupported", err)
+	}
+	if _, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {}); err != domain.ErrStreamNotSupported {
+		t.Errorf("ChatStream err = %v, want ErrStreamNotSupported", err)
+	}
+	if st := l.Status(); st.Requests != 0 {
+		t.Errorf("requests = %d, want 0", st.Requests)
+	}
+}
@@ -4,8 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math/rand/v2"
 	"net"
-	"strings"
+	"net/http"
+	"syscall"
 	"time"
 
 	"ironclaw/internal/domain"
@@ -54,12 +57,38 @@ func (c Config) Validate() error {
 // Error Classification
 // =============================================================================
 
+// StatusError is implemented by errors that carry the HTTP status of a failed
+// API call, such as *llm.APIError.
+type StatusError interface {
+	error
+	HTTPStatus() int
+}
+
+// DelayError is implemented by errors that carry how long the server asked
+// the client to wait before trying again (Retry-After), such as *llm.APIError.
+type DelayError interface {
+	error
+	RetryDelay() time.Duration
+}
+
 // retryableStatusCodes are HTTP status codes that indicate a transient failure.
-var retryableStatusCodes = []string{"429", "500", "502", "503", "504", "529"}
+// Everything else, notably 400, 401 and 403, is the caller's problem.
+var retryableStatusCodes = map[int]bool{
+	http.StatusRequestTimeout:      true,
+	http.StatusTooManyRequests:     true,
+	http.StatusInternalServerError: true,
+	http.StatusBadGateway:          true,
+	http.StatusServiceUnavailable:  true,
+	http.StatusGatewayTimeout:      true,
+	529:                            true, // Anthropic: overloaded
+}
 
 // IsRetryable returns true when err represents a transient failure that may
-// succeed on retry (5xx, 429, timeout, connection refused, EOF).
-// Context errors (Canceled, DeadlineExceeded) are never retryable.
+// succeed on retry: a StatusError with a retryable status (408, 429, 5xx
+// gateway errors, 529), a network timeout, a refused or reset connection, or a
+// connection closed mid-response (EOF). Errors are classified by type, never
+// by their message. Context errors (Canceled, DeadlineExceeded) are never
+// retryable.
 func IsRetryable(err error) bool {
 	if err == nil {
 		return false
@@ -70,30 +99,32 @@ func IsRetryable(err error) bool {
 		return false
 	}
 
+	// API errors are classified by their HTTP status alone.
+	var statusErr StatusError
+	if errors.As(err, &statusErr) {
+		return retryableStatusCodes[statusErr.HTTPStatus()]
+	}
+
 	// net.Error timeout (wraps OS-level i/o timeout)
 	var netErr net.Error
 	if errors.As(err, &netErr) && netErr.Timeout() {
 		return true
 	}
 
-	msg := err.Error()
-
-	// HTTP status codes that are retryable
-	for _, code := range retryableStatusCodes {
-		if strings.Contains(msg, code) {
-			return true
-		}
-	}
-
 	// Connection-level transient failures
-	if strings.Contains(msg, "connection refused") {
-		return true
-	}
-	if strings.Contains(msg, "EOF") {
-		return true
-	}
+	return errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
 
-	return false
+// FullJitter returns a random duration in [0, d], spreading out clients that
+// failed at the same moment.
+func FullJitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
 }
 
 // =============================================================================
@@ -102,9 +133,10 @@ func IsRetryable(err error) bool {
 
 // RetryableProvider wraps an LLMProvider with retry-on-transient-error logic.
 type RetryableProvider struct {
-	inner     domain.LLMProvider
-	config    Config
-	sleepFunc func(time.Duration) // injectable for testing
+	inner      domain.LLMProvider
+	config     Config
+	sleepFunc  func(time.Duration)               // injectable for testing
+	jitterFunc func(time.Duration) time.Duration // injectable for testing
 }
 
 // NewRetryableProvider returns a decorator that retries Generate calls on transient errors.
@@ -114,29 +146,106 @@ func NewRetryableProvider(inner domain.LLMProvider, cfg Config) *RetryableProvid
 		panic("retry: inner provider must not be nil")
 	}
 	return &RetryableProvider{
-		inner:     inner,
-		config:    cfg,
-		sleepFunc: time.Sleep,
+		inner:      inner,
+		config:     cfg,
+		sleepFunc:  time.Sleep,
+		jitterFunc: FullJitter,
 	}
 }
 
 // Generate calls the inner provider and retries on transient errors with exponential backoff.
 // Returns the first successful result, or the last error after retries are exhausted.
 func (p *RetryableProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	var result string
+	err := p.do(ctx, func() error {
+		var genErr error
+		result, genErr = p.inner.Generate(ctx, prompt)
+		return genErr
+	})
+	if err != nil {
+		return "", err
+	}
+	return result, nil
+}
+
+// Chat calls the inner provider's Chat with the same retry policy as Generate.
+// Returns domain.ErrChatNotSupported (without retrying) when the inner provider
+// does not implement domain.ChatProvider.
+func (p *RetryableProvider) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	cp, ok := p.inner.(domain.ChatProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrChatNotSupported
+	}
+	var result domain.ChatResponse
+	err := p.do(ctx, func() error {
+		var chatErr error
+		result, chatErr = cp.Chat(ctx, req)
+		return chatErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// ChatStream calls the inner provider's ChatStream with the same retry policy
+// as Generate, but only until the first delta has been delivered: a stream that
+// fails part-way is returned as-is so onDelta never sees duplicated text.
+// Returns domain.ErrStreamNotSupported when the inner provider cannot stream.
+func (p *RetryableProvider) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	sp, ok := p.inner.(domain.StreamingProvider)
+	if !ok {
+		return domain.ChatResponse{}, domain.ErrStreamNotSupported
+	}
+	var result domain.ChatResponse
+	streamed := false
+	err := p.do(ctx, func() error {
+		var streamErr error
+		result, streamErr = sp.ChatStream(ctx, req, func(delta string) {
+			streamed = true
+			onDelta(delta)
+		})
+		if streamErr != nil && streamed {
+			return stopRetry{streamErr}
+		}
+		return streamErr
+	})
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return result, nil
+}
+
+// stopRetry marks an error that must be returned without further attempts.
+type stopRetry struct{ err error }
+
+func (s stopRetry) Error() string { return s.err.Error() }
+func (s stopRetry) Unwrap() error { return s.err }
+
+// do runs call and retries it on transient errors with exponential backoff and
+// full jitter. When the error carries a Retry-After delay, the next attempt
+// waits at least that long; a delay beyond MaxBackoff ends the retries so
+// failover can move on instead of blocking.
+func (p *RetryableProvider) do(ctx context.Context, call func() error) error {
 	var lastErr error
 	backoff := p.config.InitialBackoff
 
 	for attempt := 0; attempt <= p.config.MaxRetries; attempt++ {
-		result, err := p.inner.Generate(ctx, prompt)
+		err := call()
 		if err == nil {
-			return result, nil
+			return nil
 		}
 
 		lastErr = err
 
+		var stop stopRetry
+		if errors.As(err, &stop) {
+			return stop.err
+		}
+
 		// Don't retry non-retryable errors
 		if !IsRetryable(err) {
-			return "", err
+			return err
 		}
 
 		// Don't sleep after the last attempt
@@ -144,10 +253,20 @@ func (p *RetryableProvider) Generate(ctx context.Context, prompt string) (string
 			break
 		}
 
+		delay := p.jitterFunc(backoff)
+		var delayErr DelayError
+		if errors.As(err, &delayErr) && delayErr.RetryDelay() > 0 {
+			wait := delayErr.RetryDelay()
+			if wait > p.config.MaxBackoff {
+				return fmt.Errorf("server asked to retry after %s, beyond max backoff %s: %w", wait, p.config.MaxBackoff, err)
+			}
+			delay = max(delay, wait)
+		}
+
 		// Sleep with exponential backoff, checking context cancellation
-		p.sleepFunc(backoff)
+		p.sleepFunc(delay)
 		if ctx.Err() != nil {
-			return "", ctx.Err()
+			return ctx.Err()
 		}
 
 		// Increase backoff for next iteration, capped at MaxBackoff
@@ -158,8 +277,12 @@ func (p *RetryableProvider) Generate(ctx context.Context, prompt string) (string
 		backoff = next
 	}
 
-	return "", fmt.Errorf("retries exhausted after %d attempts: %w", p.config.MaxRetries+1, lastErr)
+	return fmt.Errorf("retries exhausted after %d attempts: %w", p.config.MaxRetries+1, lastErr)
 }
 
-// Compile-time check that RetryableProvider implements LLMProvider.
-var _ domain.LLMProvider = (*RetryableProvider)(nil)
+// Compile-time check that RetryableProvider implements the provider interfaces.
+var (
+	_ domain.LLMProvider       = (*RetryableProvider)(nil)
+	_ domain.ChatProvider      = (*RetryableProvider)(nil)
+	_ domain.StreamingProvider = (*RetryableProvider)(nil)
+)
@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
+	"os"
+	"strings"
 	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
@@ -94,70 +99,70 @@ func TestIsRetryable_WhenNilError_ShouldReturnFalse(t *testing.T) {
 }
 
 func TestIsRetryable_When500Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 500 Internal Server Error")
+	err := apiError(500)
 	if !IsRetryable(err) {
 		t.Error("500 error should be retryable")
 	}
 }
 
 func TestIsRetryable_When502Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("openai api: 502 Bad Gateway")
+	err := apiError(502)
 	if !IsRetryable(err) {
 		t.Error("502 error should be retryable")
 	}
 }
 
 func TestIsRetryable_When503Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 503 Service Unavailable")
+	err := apiError(503)
 	if !IsRetryable(err) {
 		t.Error("503 error should be retryable")
 	}
 }
 
 func TestIsRetryable_When504Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("gemini api: 504 Gateway Timeout")
+	err := apiError(504)
 	if !IsRetryable(err) {
 		t.Error("504 error should be retryable")
 	}
 }
 
 func TestIsRetryable_When529Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 529 Overloaded")
+	err := apiError(529)
 	if !IsRetryable(err) {
 		t.Error("529 (overloaded) error should be retryable")
 	}
 }
 
 func TestIsRetryable_When429Error_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 429 Too Many Requests")
+	err := apiError(429)
 	if !IsRetryable(err) {
 		t.Error("429 rate limit error should be retryable")
 	}
 }
 
 func TestIsRetryable_When400Error_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 400 Bad Request")
+	err := apiError(400)
 	if IsRetryable(err) {
 		t.Error("400 error should NOT be retryable")
 	}
 }
 
 func TestIsRetryable_When401Error_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("openai api: 401 Unauthorized")
+	err := apiError(401)
 	if IsRetryable(err) {
 		t.Error("401 error should NOT be retryable")
 	}
 }
 
 func TestIsRetryable_When403Error_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("anthropic api: 403 Forbidden")
+	err := apiError(403)
 	if IsRetryable(err) {
 		t.Error("403 error should NOT be retryable")
 	}
 }
 
 func TestIsRetryable_When404Error_ShouldReturnFalse(t *testing.T) {
-	err := fmt.Errorf("openai api: 404 Not Found")
+	err := apiError(404)
 	if IsRetryable(err) {
 		t.Error("404 error should NOT be retryable")
 	}
@@ -175,7 +180,7 @@ func TestIsRetryable_WhenTimeoutError_ShouldReturnTrue(t *testing.T) {
 }
 
 func TestIsRetryable_WhenConnectionRefused_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic do: dial tcp: connect: connection refused")
+	err := connRefused()
 	if !IsRetryable(err) {
 		t.Error("connection refused error should be retryable")
 	}
@@ -194,7 +199,7 @@ func TestIsRetryable_WhenContextDeadlineExceeded_ShouldReturnFalse(t *testing.T)
 }
 
 func TestIsRetryable_WhenWrappedRetryableError_ShouldReturnTrue(t *testing.T) {
-	inner := fmt.Errorf("anthropic api: 503 Service Unavailable")
+	inner := apiError(503)
 	wrapped := fmt.Errorf("brain generate: %w", inner)
 	if !IsRetryable(wrapped) {
 		t.Error("wrapped 503 error should be retryable")
@@ -209,7 +214,7 @@ func TestIsRetryable_WhenGenericError_ShouldReturnFalse(t *testing.T) {
 }
 
 func TestIsRetryable_WhenEOFError_ShouldReturnTrue(t *testing.T) {
-	err := fmt.Errorf("anthropic do: %w", fmt.Errorf("unexpected EOF"))
+	err := fmt.Errorf("anthropic do: %w", io.ErrUnexpectedEOF)
 	if !IsRetryable(err) {
 		t.Error("EOF error should be retryable (connection reset)")
 	}
@@ -247,6 +252,26 @@ func (t *timeoutErr) Temporary() bool { return true }
 // noopSleep replaces time.Sleep in tests to avoid real delays.
 func noopSleep(d time.Duration) {}
 
+// noJitter replaces FullJitter in tests that check exact backoff durations.
+func noJitter(d time.Duration) time.Duration { return d }
+
+// statusErr is a StatusError and DelayError, like *llm.APIError.
+type statusErr struct {
+	code  int
+	delay time.Duration
+}
+
+func (e *statusErr) Error() string             { return fmt.Sprintf("api: %d %s", e.code, http.StatusText(e.code)) }
+func (e *statusErr) HTTPStatus() int           { return e.code }
+func (e *statusErr) RetryDelay() time.Duration { return e.delay }
+
+func apiError(code int) error { return &statusErr{code: code} }
+
+// connRefused returns the error a dial to a closed port produces.
+func connRefused() error {
+	return &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+}
+
 func TestNewRetryableProvider_ShouldReturnProvider(t *testing.T) {
 	inner := &mockLLM{responses: []string{"ok"}}
 	cfg := DefaultConfig()
@@ -286,7 +311,7 @@ func TestRetryableProvider_Generate_WhenNoError_ShouldReturnResponseWithoutRetry
 func TestRetryableProvider_Generate_WhenRetryableErrorThenSuccess_ShouldRetryAndSucceed(t *testing.T) {
 	inner := &mockLLM{
 		responses: []string{"", "success"},
-		errs:      []error{fmt.Errorf("anthropic api: 503 Service Unavailable"), nil},
+		errs:      []error{apiError(503), nil},
 	}
 	cfg := DefaultConfig()
 	cfg.MaxRetries = 3
@@ -307,7 +332,7 @@ func TestRetryableProvider_Generate_WhenRetryableErrorThenSuccess_ShouldRetryAnd
 
 func TestRetryableProvider_Generate_WhenNonRetryableError_ShouldNotRetry(t *testing.T) {
 	inner := &mockLLM{
-		errs: []error{fmt.Errorf("anthropic api: 401 Unauthorized")},
+		errs: []error{apiError(401)},
 	}
 	cfg := DefaultConfig()
 	cfg.MaxRetries = 3
@@ -324,7 +349,7 @@ func TestRetryableProvider_Generate_WhenNonRetryableError_ShouldNotRetry(t *test
 }
 
 func TestRetryableProvider_Generate_WhenMaxRetriesExhausted_ShouldReturnLastError(t *testing.T) {
-	serverErr := fmt.Errorf("anthropic api: 500 Internal Server Error")
+	serverErr := apiError(500)
 	inner := &mockLLM{
 		errs: []error{serverErr, serverErr, serverErr, serverErr},
 	}
@@ -345,7 +370,7 @@ func TestRetryableProvider_Generate_WhenMaxRetriesExhausted_ShouldReturnLastErro
 
 func TestRetryableProvider_Generate_WhenMaxRetriesZero_ShouldNotRetry(t *testing.T) {
 	inner := &mockLLM{
-		errs: []error{fmt.Errorf("anthropic api: 503 Service Unavailable")},
+		errs: []error{apiError(503)},
 	}
 	cfg := DefaultConfig()
 	cfg.MaxRetries = 0
@@ -365,8 +390,8 @@ func TestRetryableProvider_Generate_WhenContextCanceledDuringRetry_ShouldReturnC
 	ctx, cancel := context.WithCancel(context.Background())
 	inner := &mockLLM{
 		errs: []error{
-			fmt.Errorf("anthropic api: 503 Service Unavailable"),
-			fmt.Errorf("anthropic api: 503 Service Unavailable"),
+			apiError(503),
+			apiError(503),
 		},
 	}
 	cfg := DefaultConfig()
@@ -387,7 +412,7 @@ func TestRetryableProvider_Generate_WhenContextCanceledDuringRetry_ShouldReturnC
 }
 
 func TestRetryableProvider_Generate_ShouldUseExponentialBackoff(t *testing.T) {
-	serverErr := fmt.Errorf("anthropic api: 500 Internal Server Error")
+	serverErr := apiError(500)
 	inner := &mockLLM{
 		errs: []error{serverErr, serverErr, serverErr, serverErr},
 	}
@@ -399,6 +424,7 @@ func TestRetryableProvider_Generate_ShouldUseExponentialBackoff(t *testing.T) {
 	}
 	p := NewRetryableProvider(inner, cfg)
 
+	p.jitterFunc = noJitter
 	var sleepDurations []time.Duration
 	p.sleepFunc = func(d time.Duration) {
 		sleepDurations = append(sleepDurations, d)
@@ -419,7 +445,7 @@ func TestRetryableProvider_Generate_ShouldUseExponentialBackoff(t *testing.T) {
 }
 
 func TestRetryableProvider_Generate_BackoffShouldCapAtMaxBackoff(t *testing.T) {
-	serverErr := fmt.Errorf("anthropic api: 500 Internal Server Error")
+	serverErr := apiError(500)
 	inner := &mockLLM{
 		errs: []error{serverErr, serverErr, serverErr, serverErr, serverErr, serverErr},
 	}
@@ -431,6 +457,7 @@ func TestRetryableProvider_Generate_BackoffShouldCapAtMaxBackoff(t *testing.T) {
 	}
 	p := NewRetryableProvider(inner, cfg)
 
+	p.jitterFunc = noJitter
 	var sleepDurations []time.Duration
 	p.sleepFunc = func(d time.Duration) {
 		sleepDurations = append(sleepDurations, d)
@@ -447,7 +474,7 @@ func TestRetryableProvider_Generate_BackoffShouldCapAtMaxBackoff(t *testing.T) {
 }
 
 func TestRetryableProvider_Generate_ShouldReturnClearErrorMessageAfterExhaustion(t *testing.T) {
-	serverErr := fmt.Errorf("anthropic api: 503 Service Unavailable")
+	serverErr := apiError(503)
 	inner := &mockLLM{
 		errs: []error{serverErr, serverErr, serverErr, serverErr},
 	}
@@ -496,7 +523,7 @@ func TestRetryableProvider_Generate_WhenTimeoutError_ShouldRetry(t *testing.T) {
 func TestRetryableProvider_Generate_WhenConnectionRefused_ShouldRetry(t *testing.T) {
 	inner := &mockLLM{
 		responses: []string{"", "connected"},
-		errs:      []error{fmt.Errorf("anthropic do: dial tcp: connect: connection refused"), nil},
+		errs:      []error{connRefused(), nil},
 	}
 	cfg := DefaultConfig()
 	p := NewRetryableProvider(inner, cfg)
@@ -512,7 +539,7 @@ func TestRetryableProvider_Generate_WhenConnectionRefused_ShouldRetry(t *testing
 }
 
 func TestRetryableProvider_Generate_SucceedsOnThirdAttempt_ShouldReturnSuccess(t *testing.T) {
-	serverErr := fmt.Errorf("anthropic api: 500 Internal Server Error")
+	serverErr := apiError(500)
 	inner := &mockLLM{
 		responses: []string{"", "", "third time lucky"},
 		errs:      []error{serverErr, serverErr, nil},
@@ -580,3 +607,174 @@ func containsAll(s string, substrs ...string) bool {
 	}
 	return true
 }
+
+// mockChatLLM is a mockLLM that also implements domain.ChatProvider.
+type mockChatLLM struct {
+	mockLLM
+}
+
+func (m *mockChatLLM) Chat(ctx context.Context, req domain.ChatRequest) (domain.ChatResponse, error) {
+	text, err := m.Generate(ctx, "")
+	if err != nil {
+		return domain.ChatResponse{}, err
+	}
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: text}}}, nil
+}
+
+func TestRetryableProvider_Chat_WhenRetryableErrorThenSuccess_ShouldRetryAndSucceed(t *testing.T) {
+	inner := &mockChatLLM{mockLLM{
+		responses: []string{"", "chat ok"},
+		errs:      []error{apiError(502), nil},
+	}}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	p.sleepFunc = noopSleep
+
+	resp, err := p.Chat(context.Background(), domain.ChatRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text() != "chat ok" {
+		t.Errorf("want 'chat ok', got %q", resp.Text())
+	}
+	if atomic.LoadInt32(&inner.calls) != 2 {
+		t.Errorf("expected 2 calls, got %d", atomic.LoadInt32(&inner.calls))
+	}
+}
+
+func TestRetryableProvider_Chat_WhenInnerLacksChat_ShouldReturnErrChatNotSupported(t *testing.T) {
+	inner := &mockLLM{responses: []string{"ok"}}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	p.sleepFunc = noopSleep
+
+	_, err := p.Chat(context.Background(), domain.ChatRequest{})
+	if !errors.Is(err, domain.ErrChatNotSupported) {
+		t.Errorf("expected ErrChatNotSupported, got %v", err)
+	}
+	if atomic.LoadInt32(&inner.calls) != 0 {
+		t.Errorf("expected no calls, got %d", atomic.LoadInt32(&inner.calls))
+	}
+}
+
+// mockStreamLLM streams each Generate result as one delta; when partial is set
+// it emits that delta before returning the attempt's error.
+type mockStreamLLM struct {
+	mockLLM
+	partial string
+}
+
+func (m *mockStreamLLM) ChatStream(ctx context.Context, req domain.ChatRequest, onDelta func(string)) (domain.ChatResponse, error) {
+	text, err := m.Generate(ctx, "")
+	if err != nil {
+		if m.partial != "" {
+			onDelta(m.partial)
+		}
+		return domain.ChatResponse{}, err
+	}
+	onDelta(text)
+	return domain.ChatResponse{Content: []domain.ContentBlock{domain.TextBlock{Text: text}}}, nil
+}
+
+func TestRetryableProvider_ChatStream_WhenErrorBeforeFirstDelta_ShouldRetry(t *testing.T) {
+	inner := &mockStreamLLM{mockLLM: mockLLM{
+		responses: []string{"", "streamed"},
+		errs:      []error{apiError(529), nil},
+	}}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	p.sleepFunc = noopSleep
+
+	var deltas []string
+	resp, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text() != "streamed" || len(deltas) != 1 {
+		t.Errorf("unexpected response %q / deltas %q", resp.Text(), deltas)
+	}
+}
+
+func TestRetryableProvider_ChatStream_WhenErrorAfterDelta_ShouldNotRetry(t *testing.T) {
+	inner := &mockStreamLLM{
+		mockLLM: mockLLM{
+			responses: []string{"", "never"},
+			errs:      []error{io.ErrUnexpectedEOF, nil},
+		},
+		partial: "half",
+	}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	p.sleepFunc = noopSleep
+
+	_, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {})
+	if err == nil || !strings.Contains(err.Error(), "EOF") {
+		t.Errorf("expected the mid-stream error, got %v", err)
+	}
+	if atomic.LoadInt32(&inner.calls) != 1 {
+		t.Errorf("expected 1 call, got %d", atomic.LoadInt32(&inner.calls))
+	}
+}
+
+func TestRetryableProvider_ChatStream_WhenInnerCannotStream_ShouldReturnErrStreamNotSupported(t *testing.T) {
+	p := NewRetryableProvider(&mockChatLLM{}, DefaultConfig())
+	_, err := p.ChatStream(context.Background(), domain.ChatRequest{}, func(string) {})
+	if !errors.Is(err, domain.ErrStreamNotSupported) {
+		t.Errorf("expected ErrStreamNotSupported, got %v", err)
+	}
+}
+
+func TestIsRetryable_WhenMessageOnlyMentionsStatusOrEOF_ShouldReturnFalse(t *testing.T) {
+	for _, msg := range []string{"tool output: order #503 not found", "parse config: unexpected EOF in line 3", "connection refused by policy"} {
+		if IsRetryable(errors.New(msg)) {
+			t.Errorf("%q should NOT be retryable", msg)
+		}
+	}
+}
+
+func TestIsRetryable_WhenConnectionReset_ShouldReturnTrue(t *testing.T) {
+	err := &net.OpError{Op: "read", Net: "tcp", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+	if !IsRetryable(fmt.Errorf("openai do: %w", err)) {
+		t.Error("connection reset should be retryable")
+	}
+}
+
+func TestFullJitter_ShouldStayWithinBackoff(t *testing.T) {
+	if FullJitter(0) != 0 {
+		t.Error("FullJitter(0) should be 0")
+	}
+	for i := 0; i < 100; i++ {
+		if d := FullJitter(time.Second); d < 0 || d > time.Second {
+			t.Fatalf("FullJitter(1s) = %v, want within [0, 1s]", d)
+		}
+	}
+}
+
+func TestRetryableProvider_Generate_WhenServerSendsRetryAfter_ShouldWaitAtLeastThatLong(t *testing.T) {
+	inner := &mockLLM{
+		responses: []string{"", "ok"},
+		errs:      []error{&statusErr{code: 429, delay: 2 * time.Second}, nil},
+	}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	var slept []time.Duration
+	p.sleepFunc = func(d time.Duration) { slept = append(slept, d) }
+
+	got, err := p.Generate(context.Background(), "hi")
+	if err != nil || got != "ok" {
+		t.Fatalf("Generate = %q, %v", got, err)
+	}
+	if len(slept) != 1 || slept[0] < 2*time.Second {
+		t.Errorf("slept %v, want at least the 2s Retry-After", slept)
+	}
+}
+
+func TestRetryableProvider_Generate_WhenRetryAfterExceedsMaxBackoff_ShouldStopRetrying(t *testing.T) {
+	limited := &statusErr{code: 429, delay: time.Hour}
+	inner := &mockLLM{errs: []error{limited, nil}}
+	p := NewRetryableProvider(inner, DefaultConfig())
+	p.sleepFunc = func(d time.Duration) { t.Errorf("unexpected sleep %v", d) }
+
+	_, err := p.Generate(context.Background(), "hi")
+	if !errors.Is(err, limited) {
+		t.Fatalf("err = %v, want the rate-limit error", err)
+	}
+	if atomic.LoadInt32(&inner.calls) != 1 {
+		t.Errorf("calls = %d, want 1", atomic.LoadInt32(&inner.calls))
+	}
+}
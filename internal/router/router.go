@@ -4,12 +4,15 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"ironclaw/internal/domain"
 	"ironclaw/internal/queue"
+	"ironclaw/internal/usage"
 )
 
 // Generator generates responses from prompts (implemented by brain.Brain).
@@ -17,6 +20,85 @@ type Generator interface {
 	Generate(ctx context.Context, prompt string) (string, error)
 }
 
+// ContextGenerator is a Generator that can also answer from a message history
+// and system prompt (implemented by brain.Brain). When the router's brain
+// implements it, Route replays the channel's recent history into the model.
+type ContextGenerator interface {
+	Generator
+	GenerateWithContext(ctx context.Context, messages []domain.Message, systemPrompt string) (string, error)
+}
+
+// StreamGenerator is a ContextGenerator that can stream reply text as it is
+// generated (implemented by brain.Brain). Used by RouteStream.
+type StreamGenerator interface {
+	ContextGenerator
+	GenerateStream(ctx context.Context, messages []domain.Message, systemPrompt string, onDelta func(string)) (string, error)
+}
+
+// Compactor is a ContextGenerator that can summarise a channel's history
+// into its rolling summary on request (implemented by brain.Brain).
+type Compactor interface {
+	ContextGenerator
+	Compact(ctx context.Context, messages []domain.Message) (int, error)
+}
+
+// Runner is a ContextGenerator that can also answer with the agentic
+// tool-use loop (implemented by brain.Brain). When the channel's brain
+// implements it, messages are answered by RunMessages, so the model can call
+// tools, and the tool calls and results it returns are recorded in history
+// together with the reply. reply text goes to onDelta, which may be nil.
+// Messages added before an error are returned with it and recorded too, so
+// the history keeps tool calls that already ran.
+type Runner interface {
+	ContextGenerator
+	RunMessages(ctx context.Context, channelID string, messages []domain.Message, system string, onDelta func(string)) (reply string, added []domain.Message, err error)
+}
+
+// CompactCommand is the message that makes Route compact the channel's
+// history into its summary instead of asking the brain.
+const CompactCommand = "/compact"
+
+// DefaultHistoryLimit is the number of past messages Route loads from a
+// channel's history when no WithHistoryLimit option is given.
+const DefaultHistoryLimit = 50
+
+// Option configures a Router.
+type Option func(*Router)
+
+// WithSystemPrompt sets the system prompt sent with every routed message.
+// Only used when the brain implements ContextGenerator.
+func WithSystemPrompt(prompt string) Option {
+	return func(r *Router) { r.systemPrompt = prompt }
+}
+
+// WithHistoryLimit sets how many past messages are replayed per Route call.
+// Non-positive values are ignored.
+func WithHistoryLimit(n int) Option {
+	return func(r *Router) {
+		if n > 0 {
+			r.historyLimit = n
+		}
+	}
+}
+
+// Agent is a persona a channel can be bound to: its own brain and system
+// prompt replace the router's for every message on that channel.
+type Agent struct {
+	Name         string
+	Brain        Generator
+	SystemPrompt string
+}
+
+// AgentResolver returns the agent bound to channelID, or false when the
+// channel uses the router's own brain and system prompt.
+type AgentResolver func(channelID string) (Agent, bool)
+
+// WithAgents binds channels to agents. resolve is consulted once per channel,
+// when the channel is created.
+func WithAgents(resolve AgentResolver) Option {
+	return func(r *Router) { r.resolveAgent = resolve }
+}
+
 // HistoryFactory creates a SessionHistoryStore for a given channel ID.
 type HistoryFactory func(channelID string) domain.SessionHistoryStore
 
@@ -25,11 +107,22 @@ type Channel struct {
 	ID      string
 	Session domain.Session
 	History domain.SessionHistoryStore
+	Agent   string // Name of the bound agent; empty when the router's brain is used
+
+	brain        Generator
+	systemPrompt string
 }
 
 // ErrEmptyChannelID is returned when Route is called with an empty channel ID.
 var ErrEmptyChannelID = errors.New("router: channel ID must not be empty")
 
+// ErrNoUserMessage is returned by RouteMessages when the conversation does not
+// end with a user message.
+var ErrNoUserMessage = errors.New("router: conversation must end with a user message")
+
+// ErrNoHistory is returned by Deliver when the router has no HistoryFactory.
+var ErrNoHistory = errors.New("router: channel history is not configured")
+
 // Router manages active channels and routes messages to the brain.
 // Each channel maintains its own session state and message history.
 // Route calls for the same channel are serialized in FIFO order via a LaneQueue.
@@ -39,6 +132,9 @@ type Router struct {
 	brain          Generator
 	historyFactory HistoryFactory
 	laneQueue      *queue.LaneQueue
+	systemPrompt   string
+	historyLimit   int
+	resolveAgent   AgentResolver
 
 	// afterReadMiss is a test hook called after a read-lock miss and before acquiring
 	// the write lock in getOrCreateChannel. Allows tests to deterministically exercise
@@ -48,23 +144,57 @@ type Router struct {
 
 // NewRouter creates a new Router. brain must not be nil.
 // historyFactory may be nil; if so, messages are not persisted to history.
-func NewRouter(brain Generator, factory HistoryFactory) *Router {
+func NewRouter(brain Generator, factory HistoryFactory, opts ...Option) *Router {
 	if brain == nil {
 		panic("router: brain must not be nil")
 	}
-	return &Router{
+	r := &Router{
 		channels:       make(map[string]*Channel),
 		brain:          brain,
 		historyFactory: factory,
 		laneQueue:      queue.NewLaneQueue(),
+		historyLimit:   DefaultHistoryLimit,
 	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
 }
 
 // Route sends a prompt to the brain in the context of the specified channel.
 // Creates the channel if it doesn't exist. Records user and assistant messages
 // in the channel's history (if a HistoryFactory was provided).
+// If the brain implements ContextGenerator, the channel's last historyLimit
+// messages plus the new prompt are sent together with the system prompt;
+// otherwise only the prompt is sent via Generate. A prompt of CompactCommand
+// summarises the channel's history instead, when the brain is a Compactor.
 // Route calls for the same channel are serialized in FIFO order.
 func (r *Router) Route(ctx context.Context, channelID, prompt string) (string, error) {
+	return r.route(ctx, channelID, newTextMessage(domain.RoleUser, prompt), nil)
+}
+
+// RouteContent is like Route for a user message made of content blocks, such
+// as a photo and its caption. Images are sent to the brain for this turn
+// only: history records an "[image]" placeholder in their place, so stored
+// history stays small and is not resent on every turn.
+func (r *Router) RouteContent(ctx context.Context, channelID string, blocks []domain.ContentBlock) (string, error) {
+	return r.route(ctx, channelID, domain.NewMessage(domain.RoleUser, blocks...), nil)
+}
+
+// RouteStream is like Route but passes reply text to onDelta as it is
+// generated. If the brain does not implement StreamGenerator, the complete
+// reply is delivered as a single delta. The full reply is also returned and,
+// on success, recorded in history; canceling ctx aborts generation.
+func (r *Router) RouteStream(ctx context.Context, channelID, prompt string, onDelta func(string)) (string, error) {
+	if onDelta == nil {
+		onDelta = func(string) {}
+	}
+	return r.route(ctx, channelID, newTextMessage(domain.RoleUser, prompt), onDelta)
+}
+
+// route implements Route, RouteContent and RouteStream; onDelta is nil
+// unless streaming.
+func (r *Router) route(ctx context.Context, channelID string, userMsg domain.Message, onDelta func(string)) (string, error) {
 	if channelID == "" {
 		return "", ErrEmptyChannelID
 	}
@@ -73,31 +203,183 @@ func (r *Router) Route(ctx context.Context, channelID, prompt string) (string, e
 	err := r.laneQueue.Do(ctx, channelID, func() error {
 		ch := r.getOrCreateChannel(channelID)
 
+		// Load prior turns before recording the new one so it is not replayed twice.
+		past, err := r.loadHistory(ch)
+		if err != nil {
+			return err
+		}
+
+		// Context fitting keeps the channel's summary next to its history.
+		ctx := ctx
+		if s, ok := ch.History.(domain.SummaryStore); ok {
+			ctx = domain.WithSummaryStore(ctx, s)
+		}
+		if strings.TrimSpace(textOf(userMsg)) == CompactCommand {
+			response, err = r.compact(ctx, ch, past)
+			if err == nil && onDelta != nil {
+				onDelta(response)
+			}
+			return err
+		}
+
 		// Record user message in history.
 		if ch.History != nil {
-			userMsg := newTextMessage(domain.RoleUser, prompt)
-			_ = ch.History.Append(userMsg)
+			_ = ch.History.Append(withoutImages(userMsg))
 		}
 
 		// Generate response via the brain.
-		resp, genErr := r.brain.Generate(ctx, prompt)
+		resp, added, genErr := r.generate(ctx, ch, ch.systemPrompt, append(past, userMsg), textOf(userMsg), onDelta)
+
+		// Record the assistant response and any tool calls in history, even
+		// those made before an error.
+		r.record(ch, added)
 		if genErr != nil {
 			return genErr
 		}
 
-		// Record assistant response in history.
+		response = resp
+		return nil
+	})
+
+	return response, err
+}
+
+// RouteMessages sends a caller-supplied conversation to the brain in the
+// channel's lane, for clients that send the full history with every request
+// (e.g. OpenAI-compatible APIs). Stored history is not replayed; the final
+// user message and the reply are still recorded. system is appended to the
+// router's system prompt. onDelta may be nil; otherwise it behaves as in
+// RouteStream. messages must end with a RoleUser message.
+func (r *Router) RouteMessages(ctx context.Context, channelID, system string, messages []domain.Message, onDelta func(string)) (string, error) {
+	if channelID == "" {
+		return "", ErrEmptyChannelID
+	}
+	if len(messages) == 0 || messages[len(messages)-1].Role != domain.RoleUser {
+		return "", ErrNoUserMessage
+	}
+	last := messages[len(messages)-1]
+	prompt := textOf(last)
+
+	var response string
+	err := r.laneQueue.Do(ctx, channelID, func() error {
+		ch := r.getOrCreateChannel(channelID)
 		if ch.History != nil {
-			assistantMsg := newTextMessage(domain.RoleAssistant, resp)
-			_ = ch.History.Append(assistantMsg)
+			_ = ch.History.Append(newTextMessage(domain.RoleUser, prompt))
+		}
+		sys := ch.systemPrompt
+		if sys != "" && system != "" {
+			sys += "\n\n" + system
+		} else if system != "" {
+			sys = system
+		}
+		resp, added, genErr := r.generate(ctx, ch, sys, messages, prompt, onDelta)
+		r.record(ch, added)
+		if genErr != nil {
+			return genErr
 		}
-
 		response = resp
 		return nil
 	})
-
 	return response, err
 }
 
+// Deliver records text as an assistant message in the channel's history
+// without calling the brain, so out-of-band output (e.g. a scheduled job's
+// response) becomes part of the conversation the channel's next turn sees.
+// It runs in the channel's lane, after any in-flight turn.
+func (r *Router) Deliver(ctx context.Context, channelID, text string) error {
+	if channelID == "" {
+		return ErrEmptyChannelID
+	}
+	if r.historyFactory == nil {
+		return ErrNoHistory
+	}
+	return r.laneQueue.Do(ctx, channelID, func() error {
+		ch := r.getOrCreateChannel(channelID)
+		if err := ch.History.Append(newTextMessage(domain.RoleAssistant, text)); err != nil {
+			return fmt.Errorf("router: deliver to channel %q: %w", channelID, err)
+		}
+		return nil
+	})
+}
+
+// compact summarises the channel's history past into its summary and returns
+// the reply to the CompactCommand. Neither the command nor the reply is
+// recorded in history.
+func (r *Router) compact(ctx context.Context, ch *Channel, past []domain.Message) (string, error) {
+	c, ok := ch.brain.(Compactor)
+	if !ok || ch.History == nil {
+		return "Compaction is not available for this channel.", nil
+	}
+	ctx = usage.WithAttribution(ctx, usage.Attribution{Channel: ch.ID, Agent: ch.Agent})
+	n, err := c.Compact(ctx, past)
+	switch {
+	case errors.Is(err, domain.ErrCompactionNotSupported):
+		return "Compaction is not enabled for this channel.", nil
+	case err != nil:
+		return "", fmt.Errorf("router: compact channel %q: %w", ch.ID, err)
+	case n == 0:
+		return "Nothing to compact.", nil
+	}
+	return fmt.Sprintf("Compacted %d messages into the conversation summary.", n), nil
+}
+
+// loadHistory returns the channel's most recent messages, or nil when the
+// channel has no history store or the brain cannot use history.
+func (r *Router) loadHistory(ch *Channel) ([]domain.Message, error) {
+	if ch.History == nil {
+		return nil, nil
+	}
+	if _, ok := ch.brain.(ContextGenerator); !ok {
+		return nil, nil
+	}
+	past, err := ch.History.LoadHistory(r.historyLimit)
+	if err != nil {
+		return nil, fmt.Errorf("router: load history for channel %q: %w", ch.ID, err)
+	}
+	return past, nil
+}
+
+// generate sends the conversation to the channel's brain, preferring
+// RunMessages, then GenerateStream when streaming (onDelta != nil), then
+// GenerateWithContext, then Generate (which only sees prompt). It returns the
+// reply and the messages to record after the user message. Token usage is
+// attributed to the channel and its agent.
+func (r *Router) generate(ctx context.Context, ch *Channel, system string, messages []domain.Message, prompt string, onDelta func(string)) (string, []domain.Message, error) {
+	ctx = usage.WithAttribution(ctx, usage.Attribution{Channel: ch.ID, Agent: ch.Agent})
+	if rn, ok := ch.brain.(Runner); ok {
+		return rn.RunMessages(ctx, ch.ID, messages, system, onDelta)
+	}
+	var resp string
+	var err error
+	if sg, ok := ch.brain.(StreamGenerator); ok && onDelta != nil {
+		resp, err = sg.GenerateStream(ctx, messages, system, onDelta)
+	} else {
+		if cg, ok := ch.brain.(ContextGenerator); ok {
+			resp, err = cg.GenerateWithContext(ctx, messages, system)
+		} else {
+			resp, err = ch.brain.Generate(ctx, prompt)
+		}
+		if err == nil && onDelta != nil && resp != "" {
+			onDelta(resp)
+		}
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	return resp, []domain.Message{newTextMessage(domain.RoleAssistant, resp)}, nil
+}
+
+// record appends messages to the channel's history, if it keeps one.
+func (r *Router) record(ch *Channel, messages []domain.Message) {
+	if ch.History == nil {
+		return
+	}
+	for _, msg := range messages {
+		_ = ch.History.Append(msg)
+	}
+}
+
 // ActiveChannels returns a sorted list of active channel IDs.
 func (r *Router) ActiveChannels() []string {
 	r.mu.RLock()
@@ -165,12 +447,52 @@ func (r *Router) getOrCreateChannel(channelID string) *Channel {
 			CreatedAt: now,
 			UpdatedAt: now,
 		},
-		History: hist,
+		History:      hist,
+		brain:        r.brain,
+		systemPrompt: r.systemPrompt,
+	}
+	if r.resolveAgent != nil {
+		if agent, ok := r.resolveAgent(channelID); ok && agent.Brain != nil {
+			ch.Agent = agent.Name
+			ch.brain = agent.Brain
+			ch.systemPrompt = agent.SystemPrompt
+		}
 	}
 	r.channels[channelID] = ch
 	return ch
 }
 
+// textOf returns the concatenated text blocks of msg.
+func textOf(msg domain.Message) string {
+	var sb strings.Builder
+	for _, block := range msg.Blocks() {
+		if tb, ok := block.(domain.TextBlock); ok {
+			sb.WriteString(tb.Text)
+		}
+	}
+	return sb.String()
+}
+
+// withoutImages returns msg with each ImageBlock replaced by an "[image]"
+// text placeholder, or msg itself when it has no images.
+func withoutImages(msg domain.Message) domain.Message {
+	blocks := msg.Blocks()
+	out := make([]domain.ContentBlock, len(blocks))
+	found := false
+	for i, block := range blocks {
+		if _, ok := block.(domain.ImageBlock); ok {
+			block, found = domain.TextBlock{Text: "[image]"}, true
+		}
+		out[i] = block
+	}
+	if !found {
+		return msg
+	}
+	stored := domain.NewMessage(msg.Role, out...)
+	stored.Timestamp = msg.Timestamp
+	return stored
+}
+
 // newTextMessage creates a Message with a text content block.
 func newTextMessage(role domain.MessageRole, text string) domain.Message {
 	raw, _ := json.Marshal(text)
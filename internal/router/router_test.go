@@ -2,6 +2,7 @@ package router
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"sort"
 	"sync"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"ironclaw/internal/domain"
+	"ironclaw/internal/usage"
 )
 
 // =============================================================================
@@ -46,6 +48,7 @@ type mockHistoryStore struct {
 	mu       sync.Mutex
 	messages []domain.Message
 	appendErr error
+	loadErr   error
 }
 
 func (m *mockHistoryStore) Append(msg domain.Message) error {
@@ -61,6 +64,9 @@ func (m *mockHistoryStore) Append(msg domain.Message) error {
 func (m *mockHistoryStore) LoadHistory(n int) ([]domain.Message, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
+	if m.loadErr != nil {
+		return nil, m.loadErr
+	}
 	if n <= 0 || len(m.messages) == 0 {
 		return nil, nil
 	}
@@ -849,3 +855,481 @@ func TestChannelCount_ShouldReturnNumberOfActiveChannels(t *testing.T) {
 		t.Errorf("expected 2 after routing to existing channel, got %d", r.ChannelCount())
 	}
 }
+
+// =============================================================================
+// History replay tests
+// =============================================================================
+
+// mockContextGenerator implements ContextGenerator and records the history and
+// system prompt it receives.
+type mockContextGenerator struct {
+	mockGenerator
+	histories [][]domain.Message
+	systems   []string
+}
+
+func (m *mockContextGenerator) GenerateWithContext(ctx context.Context, messages []domain.Message, systemPrompt string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.histories = append(m.histories, messages)
+	m.systems = append(m.systems, systemPrompt)
+	if m.err != nil {
+		return "", m.err
+	}
+	return m.response, nil
+}
+
+func TestRoute_WhenBrainSupportsContext_ShouldReplayHistoryAndSystemPrompt(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create, WithSystemPrompt("be nice"))
+
+	if _, err := r.Route(context.Background(), "general", "first"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if _, err := r.Route(context.Background(), "general", "second"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+
+	if len(brain.calls) != 0 {
+		t.Errorf("Generate should not be called, got %d calls", len(brain.calls))
+	}
+	if len(brain.histories) != 2 {
+		t.Fatalf("expected 2 GenerateWithContext calls, got %d", len(brain.histories))
+	}
+	second := brain.histories[1]
+	if len(second) != 3 {
+		t.Fatalf("second call: want user+assistant+user, got %d messages", len(second))
+	}
+	wantRoles := []domain.MessageRole{domain.RoleUser, domain.RoleAssistant, domain.RoleUser}
+	for i, role := range wantRoles {
+		if second[i].Role != role {
+			t.Errorf("message %d role: want %s, got %s", i, role, second[i].Role)
+		}
+	}
+	if brain.systems[1] != "be nice" {
+		t.Errorf("system prompt: want %q, got %q", "be nice", brain.systems[1])
+	}
+}
+
+func TestRoute_WhenHistoryLimitSet_ShouldLoadOnlyThatMany(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create, WithHistoryLimit(1))
+
+	for _, p := range []string{"a", "b", "c"} {
+		if _, err := r.Route(context.Background(), "general", p); err != nil {
+			t.Fatalf("Route: %v", err)
+		}
+	}
+	if got := len(brain.histories[2]); got != 2 {
+		t.Errorf("expected 1 replayed message + new prompt, got %d", got)
+	}
+}
+
+func TestRoute_WhenHistoryLoadFails_ShouldReturnError(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}
+	loadErr := errors.New("disk gone")
+	r := NewRouter(brain, func(string) domain.SessionHistoryStore {
+		return &mockHistoryStore{loadErr: loadErr}
+	})
+
+	_, err := r.Route(context.Background(), "general", "hi")
+	if !errors.Is(err, loadErr) {
+		t.Errorf("expected wrapped load error, got %v", err)
+	}
+	if len(brain.histories) != 0 {
+		t.Error("brain should not be called when history cannot be loaded")
+	}
+}
+
+func TestRoute_WhenBrainSupportsContextWithoutHistory_ShouldSendPromptOnly(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}
+	r := NewRouter(brain, nil)
+
+	if _, err := r.Route(context.Background(), "general", "hi"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if len(brain.histories) != 1 || len(brain.histories[0]) != 1 {
+		t.Fatalf("expected a single-message history, got %v", brain.histories)
+	}
+}
+
+func TestRouteContent_ShouldSendImageToBrainAndStorePlaceholder(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "a cat"}}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create)
+	img := domain.NewImageBlock("image/png", []byte("png"))
+
+	resp, err := r.RouteContent(context.Background(), "telegram-1", []domain.ContentBlock{img, domain.TextBlock{Text: "what is it?"}})
+	if err != nil || resp != "a cat" {
+		t.Fatalf("RouteContent = %q, %v", resp, err)
+	}
+	sent := brain.histories[0][0].Blocks()
+	if len(sent) != 2 || sent[0] != img {
+		t.Errorf("brain got blocks %+v, want the image and caption", sent)
+	}
+	stored := factory.Get("telegram-1").messages
+	if len(stored) != 2 {
+		t.Fatalf("history = %d messages, want 2", len(stored))
+	}
+	blocks := stored[0].Blocks()
+	if len(blocks) != 2 || blocks[0] != (domain.TextBlock{Text: "[image]"}) || blocks[1] != (domain.TextBlock{Text: "what is it?"}) {
+		t.Errorf("stored user message = %+v, want placeholder and caption", blocks)
+	}
+}
+
+// =============================================================================
+// Compaction tests
+// =============================================================================
+
+// mockCompactor implements Compactor, recording what it was asked to compact
+// and the summary store it was given.
+type mockCompactor struct {
+	mockContextGenerator
+	compacted int
+	err       error
+	past      [][]domain.Message
+	stores    []domain.SummaryStore
+}
+
+func (m *mockCompactor) Compact(ctx context.Context, messages []domain.Message) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.past = append(m.past, messages)
+	m.stores = append(m.stores, domain.SummaryStoreFrom(ctx))
+	return m.compacted, m.err
+}
+
+// summaryHistoryStore is a mockHistoryStore that also keeps a summary.
+type summaryHistoryStore struct {
+	mockHistoryStore
+	summary domain.ContextSummary
+}
+
+func (s *summaryHistoryStore) LoadSummary() (domain.ContextSummary, error) { return s.summary, nil }
+func (s *summaryHistoryStore) SaveSummary(sum domain.ContextSummary) error {
+	s.summary = sum
+	return nil
+}
+
+func TestRoute_WhenCompactCommand_ShouldCompactHistoryWithoutRecordingIt(t *testing.T) {
+	brain := &mockCompactor{mockContextGenerator: mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}, compacted: 2}
+	store := &summaryHistoryStore{}
+	r := NewRouter(brain, func(string) domain.SessionHistoryStore { return store })
+
+	if _, err := r.Route(context.Background(), "general", "hi"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	resp, err := r.Route(context.Background(), "general", " /compact ")
+	if err != nil {
+		t.Fatalf("Route /compact: %v", err)
+	}
+	if resp != "Compacted 2 messages into the conversation summary." {
+		t.Errorf("reply = %q", resp)
+	}
+	if len(brain.past) != 1 || len(brain.past[0]) != 2 || brain.stores[0] != domain.SummaryStore(store) {
+		t.Errorf("Compact got %d calls, past %v, stores %v", len(brain.past), brain.past, brain.stores)
+	}
+	if len(brain.histories) != 1 || len(store.messages) != 2 {
+		t.Errorf("generated %d times, stored %d messages; want the command neither sent nor recorded", len(brain.histories), len(store.messages))
+	}
+}
+
+func TestRoute_WhenCompactCommandAndNothingToCompact_ShouldSaySo(t *testing.T) {
+	brain := &mockCompactor{mockContextGenerator: mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}}
+	r := NewRouter(brain, func(string) domain.SessionHistoryStore { return &summaryHistoryStore{} })
+
+	if resp, err := r.Route(context.Background(), "general", CompactCommand); err != nil || resp != "Nothing to compact." {
+		t.Errorf("Route = %q, %v", resp, err)
+	}
+}
+
+func TestRoute_WhenCompactionNotEnabled_ShouldSaySo(t *testing.T) {
+	brain := &mockCompactor{
+		mockContextGenerator: mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}},
+		err:                  domain.ErrCompactionNotSupported,
+	}
+	r := NewRouter(brain, newTrackingHistoryFactory().Create)
+
+	if resp, err := r.Route(context.Background(), "general", CompactCommand); err != nil || resp != "Compaction is not enabled for this channel." {
+		t.Errorf("Route = %q, %v", resp, err)
+	}
+}
+
+func TestRoute_WhenCompactFails_ShouldReturnError(t *testing.T) {
+	compactErr := errors.New("summarizer down")
+	brain := &mockCompactor{mockContextGenerator: mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}, err: compactErr}
+	r := NewRouter(brain, newTrackingHistoryFactory().Create)
+
+	if _, err := r.Route(context.Background(), "general", CompactCommand); !errors.Is(err, compactErr) {
+		t.Errorf("expected wrapped compact error, got %v", err)
+	}
+}
+
+func TestRoute_WhenCompactCommandAndBrainCannotCompact_ShouldSaySo(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "ok"}}
+	r := NewRouter(brain, newTrackingHistoryFactory().Create)
+
+	resp, err := r.Route(context.Background(), "general", CompactCommand)
+	if err != nil || resp != "Compaction is not available for this channel." {
+		t.Errorf("Route = %q, %v", resp, err)
+	}
+	if len(brain.histories) != 0 {
+		t.Error("the command should not be sent to the brain")
+	}
+}
+
+func TestRoute_WhenHistoryKeepsSummary_ShouldPassItToBrain(t *testing.T) {
+	brain := &summaryCheckBrain{}
+	store := &summaryHistoryStore{}
+	r := NewRouter(brain, func(string) domain.SessionHistoryStore { return store })
+
+	if _, err := r.Route(context.Background(), "general", "hi"); err != nil {
+		t.Fatalf("Route: %v", err)
+	}
+	if brain.store != domain.SummaryStore(store) {
+		t.Errorf("brain saw summary store %v, want the channel's history", brain.store)
+	}
+}
+
+// summaryCheckBrain records the summary store of the context it is called with.
+type summaryCheckBrain struct {
+	store domain.SummaryStore
+}
+
+func (b *summaryCheckBrain) Generate(context.Context, string) (string, error) { return "ok", nil }
+func (b *summaryCheckBrain) GenerateWithContext(ctx context.Context, _ []domain.Message, _ string) (string, error) {
+	b.store = domain.SummaryStoreFrom(ctx)
+	return "ok", nil
+}
+
+// =============================================================================
+// Streaming tests
+// =============================================================================
+
+// mockStreamGenerator implements StreamGenerator, emitting fixed deltas.
+type mockStreamGenerator struct {
+	mockContextGenerator
+	deltas []string
+}
+
+func (m *mockStreamGenerator) GenerateStream(ctx context.Context, messages []domain.Message, systemPrompt string, onDelta func(string)) (string, error) {
+	for _, d := range m.deltas {
+		onDelta(d)
+	}
+	return m.GenerateWithContext(ctx, messages, systemPrompt)
+}
+
+func TestRouteStream_WhenBrainStreams_ShouldForwardDeltasAndRecordReply(t *testing.T) {
+	brain := &mockStreamGenerator{
+		mockContextGenerator: mockContextGenerator{mockGenerator: mockGenerator{response: "Hello"}},
+		deltas:               []string{"Hel", "lo"},
+	}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create)
+
+	var deltas []string
+	got, err := r.RouteStream(context.Background(), "general", "hi", func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("RouteStream: %v", err)
+	}
+	if got != "Hello" || len(deltas) != 2 {
+		t.Errorf("unexpected reply %q / deltas %q", got, deltas)
+	}
+	if store := factory.Get("general"); len(store.messages) != 2 {
+		t.Errorf("expected user+assistant in history, got %d", len(store.messages))
+	}
+}
+
+func TestRouteStream_WhenBrainCannotStream_ShouldDeliverReplyAsSingleDelta(t *testing.T) {
+	brain := &mockGenerator{response: "full"}
+	r := NewRouter(brain, nil)
+
+	var deltas []string
+	got, err := r.RouteStream(context.Background(), "general", "hi", func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("RouteStream: %v", err)
+	}
+	if got != "full" || len(deltas) != 1 || deltas[0] != "full" {
+		t.Errorf("unexpected reply %q / deltas %q", got, deltas)
+	}
+}
+
+// mockRunner implements Runner, answering with a tool call, its result and
+// a reply.
+type mockRunner struct {
+	mockContextGenerator
+	channels []string
+	err      error // when set, the loop fails after the tool call
+}
+
+func (m *mockRunner) RunMessages(ctx context.Context, channelID string, messages []domain.Message, system string, onDelta func(string)) (string, []domain.Message, error) {
+	m.channels = append(m.channels, channelID)
+	added := []domain.Message{
+		domain.NewMessage(domain.RoleAssistant, domain.ToolUseBlock{ToolUseID: "tu", Name: "calc", Input: json.RawMessage(`{}`)}),
+		domain.NewMessage(domain.RoleTool, domain.ToolResultBlock{ToolUseID: "tu", Content: "4"}),
+	}
+	if m.err != nil {
+		return "", added, m.err
+	}
+	if onDelta != nil {
+		onDelta("done")
+	}
+	return "done", append(added, domain.NewTextMessage(domain.RoleAssistant, "done")), nil
+}
+
+func TestRoute_WhenBrainIsRunner_ShouldRunLoopAndRecordToolCalls(t *testing.T) {
+	brain := &mockRunner{}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create)
+
+	var deltas []string
+	got, err := r.RouteStream(context.Background(), "general", "add", func(d string) { deltas = append(deltas, d) })
+	if err != nil {
+		t.Fatalf("RouteStream: %v", err)
+	}
+	if got != "done" || len(deltas) != 1 || len(brain.channels) != 1 || brain.channels[0] != "general" {
+		t.Errorf("reply %q, deltas %q, channels %q", got, deltas, brain.channels)
+	}
+	if len(brain.histories) != 0 {
+		t.Errorf("GenerateWithContext should not be called, got %d calls", len(brain.histories))
+	}
+	msgs := factory.Get("general").messages
+	if len(msgs) != 4 || msgs[1].Role != domain.RoleAssistant || msgs[2].Role != domain.RoleTool || textOf(msgs[3]) != "done" {
+		t.Errorf("expected user, tool call, tool result and reply in history, got %+v", msgs)
+	}
+}
+
+func TestRoute_WhenRunnerFails_ShouldRecordToolCallsMadeBeforeTheError(t *testing.T) {
+	brain := &mockRunner{err: errors.New("max turns")}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create)
+
+	if _, err := r.Route(context.Background(), "general", "add"); err == nil {
+		t.Fatal("expected error")
+	}
+	if _, err := r.RouteMessages(context.Background(), "api", "", []domain.Message{newTextMessage(domain.RoleUser, "add")}, nil); err == nil {
+		t.Fatal("expected error")
+	}
+	for _, id := range []string{"general", "api"} {
+		msgs := factory.Get(id).messages
+		if len(msgs) != 3 || msgs[1].Role != domain.RoleAssistant || msgs[2].Role != domain.RoleTool {
+			t.Errorf("%s: expected user, tool call and tool result in history, got %+v", id, msgs)
+		}
+	}
+}
+
+func TestRouteMessages_ShouldSendSuppliedConversationAndRecordLastTurn(t *testing.T) {
+	brain := &mockContextGenerator{mockGenerator: mockGenerator{response: "reply"}}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create, WithSystemPrompt("base"))
+
+	msgs := []domain.Message{
+		newTextMessage(domain.RoleUser, "one"),
+		newTextMessage(domain.RoleAssistant, "two"),
+		newTextMessage(domain.RoleUser, "three"),
+	}
+	got, err := r.RouteMessages(context.Background(), "api", "client", msgs, nil)
+	if err != nil {
+		t.Fatalf("RouteMessages: %v", err)
+	}
+	if got != "reply" || len(brain.histories[0]) != 3 || brain.systems[0] != "base\n\nclient" {
+		t.Errorf("unexpected reply %q / history %d / system %q", got, len(brain.histories[0]), brain.systems[0])
+	}
+	if store := factory.Get("api"); len(store.messages) != 2 {
+		t.Errorf("expected last user message + reply recorded, got %d", len(store.messages))
+	}
+}
+
+func TestRouteMessages_WhenLastMessageNotUser_ShouldReturnError(t *testing.T) {
+	r := NewRouter(&mockGenerator{}, nil)
+	_, err := r.RouteMessages(context.Background(), "api", "", []domain.Message{newTextMessage(domain.RoleAssistant, "x")}, nil)
+	if !errors.Is(err, ErrNoUserMessage) {
+		t.Errorf("expected ErrNoUserMessage, got %v", err)
+	}
+}
+
+func TestDeliver_ShouldAppendAssistantMessageWithoutCallingBrain(t *testing.T) {
+	brain := &mockGenerator{response: "ok"}
+	factory := newTrackingHistoryFactory()
+	r := NewRouter(brain, factory.Create)
+
+	if err := r.Deliver(context.Background(), "general", "daily briefing"); err != nil {
+		t.Fatalf("Deliver: %v", err)
+	}
+	store := factory.Get("general")
+	if store == nil || len(store.messages) != 1 {
+		t.Fatalf("expected one history entry, got %+v", store)
+	}
+	if msg := store.messages[0]; msg.Role != domain.RoleAssistant || textOf(msg) != "daily briefing" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if len(brain.calls) != 0 {
+		t.Errorf("expected no brain calls, got %d", len(brain.calls))
+	}
+}
+
+func TestDeliver_WhenNoHistoryFactory_ShouldReturnErrNoHistory(t *testing.T) {
+	r := NewRouter(&mockGenerator{}, nil)
+	if err := r.Deliver(context.Background(), "general", "x"); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+	if err := r.Deliver(context.Background(), "", "x"); !errors.Is(err, ErrEmptyChannelID) {
+		t.Errorf("expected ErrEmptyChannelID, got %v", err)
+	}
+}
+
+func TestRoute_WhenChannelBoundToAgent_ShouldUseAgentBrainAndSystemPrompt(t *testing.T) {
+	def := &mockContextGenerator{mockGenerator: mockGenerator{response: "default"}}
+	ops := &mockContextGenerator{mockGenerator: mockGenerator{response: "ops"}}
+	r := NewRouter(def, nil, WithSystemPrompt("default prompt"), WithAgents(func(channelID string) (Agent, bool) {
+		if channelID == "telegram-1" {
+			return Agent{Name: "ops", Brain: ops, SystemPrompt: "ops prompt"}, true
+		}
+		return Agent{}, false
+	}))
+
+	if got, _ := r.Route(context.Background(), "telegram-1", "status?"); got != "ops" {
+		t.Errorf("bound channel: want ops reply, got %q", got)
+	}
+	if got, _ := r.Route(context.Background(), "general", "hi"); got != "default" {
+		t.Errorf("unbound channel: want default reply, got %q", got)
+	}
+	if len(ops.systems) != 1 || ops.systems[0] != "ops prompt" {
+		t.Errorf("expected agent system prompt, got %v", ops.systems)
+	}
+	if ch, _ := r.GetChannel("telegram-1"); ch.Agent != "ops" {
+		t.Errorf("expected channel agent ops, got %q", ch.Agent)
+	}
+}
+
+// attributionBrain records the usage attribution of each call's context.
+type attributionBrain struct {
+	got []usage.Attribution
+}
+
+func (b *attributionBrain) Generate(ctx context.Context, _ string) (string, error) {
+	b.got = append(b.got, usage.AttributionFrom(ctx))
+	return "ok", nil
+}
+
+func TestRoute_ShouldAttributeUsageToChannelAndAgent(t *testing.T) {
+	def, ops := &attributionBrain{}, &attributionBrain{}
+	r := NewRouter(def, nil, WithAgents(func(channelID string) (Agent, bool) {
+		if channelID == "telegram-1" {
+			return Agent{Name: "ops", Brain: ops}, true
+		}
+		return Agent{}, false
+	}))
+
+	_, _ = r.Route(context.Background(), "telegram-1", "status?")
+	_, _ = r.Route(context.Background(), "general", "hi")
+
+	if len(ops.got) != 1 || ops.got[0] != (usage.Attribution{Channel: "telegram-1", Agent: "ops"}) {
+		t.Errorf("agent channel attribution = %+v", ops.got)
+	}
+	if len(def.got) != 1 || def.got[0] != (usage.Attribution{Channel: "general"}) {
+		t.Errorf("default channel attribution = %+v", def.got)
+	}
+}
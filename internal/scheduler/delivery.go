@@ -0,0 +1,138 @@
+package scheduler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// TargetKind identifies the adapter a job's response is delivered through.
+type TargetKind string
+
+const (
+	TargetChannel  TargetKind = "channel"  // Router channel; To is the channel ID
+	TargetTelegram TargetKind = "telegram" // Telegram chat; To is the chat ID
+	TargetWhatsApp TargetKind = "whatsapp" // WhatsApp chat; To is the JID
+	TargetWebhook  TargetKind = "webhook"  // HTTP POST; To is the URL
+	TargetMemory   TargetKind = "memory"   // Stored in the agent's long-term memory; To is unused
+)
+
+// Target is where a job's response is delivered.
+type Target struct {
+	Kind TargetKind `json:"kind"`
+	To   string     `json:"to,omitempty"`
+}
+
+// String renders the target as "kind:to" (or just "kind" when To is empty).
+func (t Target) String() string {
+	if t.To == "" {
+		return string(t.Kind)
+	}
+	return string(t.Kind) + ":" + t.To
+}
+
+// ErrBadTarget is returned for a delivery target with an unknown kind or a
+// missing destination.
+var ErrBadTarget = errors.New("scheduler: invalid delivery target")
+
+// ErrNoSender is returned when a job's target kind has no registered Sender.
+var ErrNoSender = errors.New("no sender registered for target kind")
+
+// ParseTarget parses "kind:to" (or just "kind" for memory), the format
+// Target.String produces. An empty string is the zero Target (no delivery).
+func ParseTarget(s string) (Target, error) {
+	if s == "" {
+		return Target{}, nil
+	}
+	kind, to, _ := strings.Cut(s, ":")
+	t := Target{Kind: TargetKind(kind), To: to}
+	return t, t.validate()
+}
+
+func (t Target) validate() error {
+	switch t.Kind {
+	case "":
+		if t.To != "" {
+			return fmt.Errorf("%w: destination %q without a kind", ErrBadTarget, t.To)
+		}
+	case TargetMemory:
+	case TargetChannel, TargetTelegram, TargetWhatsApp, TargetWebhook:
+		if t.To == "" {
+			return fmt.Errorf("%w: %s needs a destination", ErrBadTarget, t.Kind)
+		}
+	default:
+		return fmt.Errorf("%w: unknown kind %q", ErrBadTarget, t.Kind)
+	}
+	return nil
+}
+
+// Sender delivers a job's response to job.Deliver.To.
+type Sender interface {
+	Send(ctx context.Context, job Job, text string) error
+}
+
+// SenderFunc adapts a function to the Sender interface.
+type SenderFunc func(ctx context.Context, job Job, text string) error
+
+// Send implements Sender.
+func (f SenderFunc) Send(ctx context.Context, job Job, text string) error {
+	return f(ctx, job, text)
+}
+
+// WebhookPayload is the JSON body WebhookSender posts. "text" makes it
+// directly usable with Slack-style incoming webhooks.
+type WebhookPayload struct {
+	JobID   string `json:"jobId"`
+	JobName string `json:"jobName,omitempty"`
+	Text    string `json:"text"`
+}
+
+// WebhookSender posts job responses as JSON to the target URL.
+type WebhookSender struct {
+	client *http.Client
+}
+
+// defaultWebhookTimeout bounds a webhook request when NewWebhookSender is
+// given no client, so an unresponsive endpoint cannot hold a job's run open.
+const defaultWebhookTimeout = 30 * time.Second
+
+// NewWebhookSender returns a WebhookSender using client, or a client timing
+// out after 30 seconds if client is nil.
+func NewWebhookSender(client *http.Client) *WebhookSender {
+	if client == nil {
+		client = &http.Client{Timeout: defaultWebhookTimeout}
+	}
+	return &WebhookSender{client: client}
+}
+
+// Send implements Sender. Any non-2xx status is an error.
+func (w *WebhookSender) Send(ctx context.Context, job Job, text string) error {
+	body, err := json.Marshal(WebhookPayload{JobID: job.ID, JobName: job.Name, Text: text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Deliver.To, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook: %s", resp.Status)
+	}
+	return nil
+}
+
+var (
+	_ Sender = (*WebhookSender)(nil)
+	_ Sender = SenderFunc(nil)
+)
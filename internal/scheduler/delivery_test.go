@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSender_ShouldPostPayloadToTargetURL(t *testing.T) {
+	var got WebhookPayload
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("content type %q", r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer ts.Close()
+
+	job := Job{ID: "brief", Name: "Briefing", Deliver: Target{Kind: TargetWebhook, To: ts.URL}}
+	if err := NewWebhookSender(nil).Send(context.Background(), job, "good morning"); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if got.JobID != "brief" || got.JobName != "Briefing" || got.Text != "good morning" {
+		t.Errorf("unexpected payload %+v", got)
+	}
+}
+
+func TestWebhookSender_WhenNon2xx_ShouldReturnError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer ts.Close()
+
+	job := Job{ID: "j", Deliver: Target{Kind: TargetWebhook, To: ts.URL}}
+	if err := NewWebhookSender(ts.Client()).Send(context.Background(), job, "x"); err == nil {
+		t.Fatal("expected error for 502")
+	}
+}
+
+func TestNewWebhookSender_WhenClientNil_ShouldUseClientWithTimeout(t *testing.T) {
+	if got := NewWebhookSender(nil).client.Timeout; got != defaultWebhookTimeout {
+		t.Errorf("timeout = %v, want %v", got, defaultWebhookTimeout)
+	}
+}
+
+func TestTarget_String(t *testing.T) {
+	if got := (Target{Kind: TargetTelegram, To: "42"}).String(); got != "telegram:42" {
+		t.Errorf("got %q", got)
+	}
+	if got := (Target{Kind: TargetMemory}).String(); got != "memory" {
+		t.Errorf("got %q", got)
+	}
+}
+
+func TestParseTarget(t *testing.T) {
+	got, err := ParseTarget("webhook:https://example.com/hook")
+	if err != nil || got != (Target{Kind: TargetWebhook, To: "https://example.com/hook"}) {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	if got, err := ParseTarget("memory"); err != nil || got.Kind != TargetMemory {
+		t.Errorf("got %+v, %v", got, err)
+	}
+	for _, bad := range []string{"telegram", "pigeon:home"} {
+		if _, err := ParseTarget(bad); !errors.Is(err, ErrBadTarget) {
+			t.Errorf("%q: expected ErrBadTarget, got %v", bad, err)
+		}
+	}
+}
@@ -5,21 +5,42 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"sort"
 	"sync"
+	"time"
 )
 
 // Job represents a scheduled task that injects a prompt into the brain.
+// Exactly one of CronExpr and At must be set.
 type Job struct {
-	ID       string // Unique identifier for the job
-	Name     string // Human-readable name (optional)
-	CronExpr string // Cron expression (e.g. "*/5 * * * *")
-	Prompt   string // Prompt to inject as a system event
+	ID       string        `json:"id"`                // Unique identifier for the job
+	Name     string        `json:"name,omitempty"`    // Human-readable name (optional)
+	CronExpr string        `json:"cron,omitempty"`    // Cron expression or descriptor (e.g. "*/5 * * * *", "@every 1h")
+	At       time.Time     `json:"at,omitzero"`       // One-shot run time; the job is paused after it fires
+	Jitter   time.Duration `json:"jitter,omitempty"`  // Random delay in [0, Jitter) added to each scheduled firing
+	Overlap  OverlapPolicy `json:"overlap,omitempty"` // What to do when the job fires while still running
+	Prompt   string        `json:"prompt"`            // Prompt to inject as a system event
+	Model    string        `json:"model,omitempty"`   // Model, alias or profile the brain answers with; empty uses its default
+	Deliver  Target        `json:"deliver,omitzero"`  // Where the response is delivered; zero means nowhere
+	Paused   bool          `json:"paused,omitempty"`  // Paused jobs stay registered but do not fire
 }
 
+// OverlapPolicy decides what happens when a job fires (or is run with RunNow)
+// while a previous run of the same job has not finished.
+type OverlapPolicy string
+
+const (
+	OverlapSkip    OverlapPolicy = "skip"    // Drop the new run (default)
+	OverlapQueue   OverlapPolicy = "queue"   // Start the new run once the current one finishes
+	OverlapReplace OverlapPolicy = "replace" // Cancel the current run, then start the new one
+)
+
 // EventHandler is called when a scheduled job fires. The handler receives
-// the context and the job definition, and should inject the prompt into
-// the brain as a system event.
-type EventHandler func(ctx context.Context, job Job) error
+// the context and the job definition, should inject the prompt into the
+// brain as a system event, and returns the brain's response so it can be
+// recorded in the job's run history.
+type EventHandler func(ctx context.Context, job Job) (string, error)
 
 // CronEngine abstracts the cron scheduler for testability.
 // The real implementation wraps robfig/cron/v3.
@@ -43,18 +64,53 @@ func WithLogger(l *slog.Logger) Option {
 	}
 }
 
+// WithSender registers the Sender used to deliver responses of jobs whose
+// Deliver.Kind is kind. Jobs with a kind that has no sender fail delivery.
+func WithSender(kind TargetKind, sender Sender) Option {
+	return func(s *Scheduler) {
+		if sender != nil {
+			s.senders[kind] = sender
+		}
+	}
+}
+
+// WithStore persists jobs and their run history in store. AddJob, RemoveJob,
+// PauseJob and ResumeJob write through to it, and LoadJobs registers the jobs
+// it holds. If store is nil it is ignored and jobs live in memory only.
+func WithStore(store JobStore) Option {
+	return func(s *Scheduler) {
+		if store != nil {
+			s.store = store
+		}
+	}
+}
+
 // Sentinel errors for validation.
 var (
 	ErrEmptyJobID   = errors.New("scheduler: job ID must not be empty")
-	ErrEmptyCron    = errors.New("scheduler: cron expression must not be empty")
+	ErrEmptyCron    = errors.New("scheduler: cron expression or at time must be set")
+	ErrBothTriggers = errors.New("scheduler: only one of cron expression and at time may be set")
 	ErrEmptyPrompt  = errors.New("scheduler: prompt must not be empty")
 	ErrDuplicateJob = errors.New("scheduler: job with this ID already exists")
+	ErrInvalidCron  = errors.New("scheduler: invalid cron expression")
+	ErrJobNotFound  = errors.New("scheduler: job not found")
+	ErrNoStore      = errors.New("scheduler: no job store configured")
+	ErrBadOverlap   = errors.New("scheduler: overlap must be skip, queue or replace")
+	ErrJobRunning   = errors.New("scheduler: job is already running")
 )
 
-// jobEntry tracks a registered job and its cron entry ID.
+// jobEntry tracks a registered job and its trigger: a cron entry ID, or for
+// one-shot jobs a timer (nil while paused or after firing).
 type jobEntry struct {
 	job     Job
 	entryID int
+	timer   *time.Timer
+}
+
+// activeRun is an in-flight execution of a job.
+type activeRun struct {
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 // Scheduler manages cron-based scheduled jobs. When a job fires, it calls
@@ -63,8 +119,19 @@ type Scheduler struct {
 	engine  CronEngine
 	handler EventHandler
 	logger  *slog.Logger
+	store   JobStore
+	senders map[TargetKind]Sender
+	now     func() time.Time
+	jitter  func(max time.Duration) time.Duration
+	after   func(d time.Duration, f func()) *time.Timer
+
+	// ctx is canceled by Stop; it bounds jitter waits and scheduled runs.
+	ctx    context.Context
+	cancel context.CancelFunc
+
 	mu      sync.RWMutex
 	jobs    map[string]jobEntry
+	running map[string]*activeRun
 }
 
 // NewScheduler creates a new Scheduler. Both engine and handler must not be nil.
@@ -78,8 +145,14 @@ func NewScheduler(engine CronEngine, handler EventHandler, opts ...Option) *Sche
 	s := &Scheduler{
 		engine:  engine,
 		handler: handler,
+		senders: make(map[TargetKind]Sender),
+		now:     time.Now,
+		jitter:  func(max time.Duration) time.Duration { return rand.N(max) },
+		after:   time.AfterFunc,
 		jobs:    make(map[string]jobEntry),
+		running: make(map[string]*activeRun),
 	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
 	for _, opt := range opts {
 		opt(s)
 	}
@@ -95,17 +168,59 @@ func (s *Scheduler) log() *slog.Logger {
 }
 
 // AddJob registers a new scheduled job. Returns an error if the job fails
-// validation or if a job with the same ID already exists.
+// validation or if a job with the same ID already exists. With a store
+// configured the job is also persisted.
 func (s *Scheduler) AddJob(job Job) error {
+	return s.addJob(job, true)
+}
+
+// LoadJobs registers every job held by the store, e.g. on daemon start.
+// Jobs that fail to register are logged and reported in the returned error;
+// the rest are still registered.
+func (s *Scheduler) LoadJobs(ctx context.Context) error {
+	if s.store == nil {
+		return ErrNoStore
+	}
+	jobs, err := s.store.LoadJobs(ctx)
+	if err != nil {
+		return fmt.Errorf("scheduler: load jobs: %w", err)
+	}
+	var errs []error
+	for _, job := range jobs {
+		if err := s.addJob(job, false); err != nil {
+			s.log().Warn("stored job not registered", "job_id", job.ID, "error", err)
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// validate checks a job's fields before it is registered.
+func validate(job Job) error {
 	if job.ID == "" {
 		return ErrEmptyJobID
 	}
-	if job.CronExpr == "" {
+	if job.CronExpr == "" && job.At.IsZero() {
 		return ErrEmptyCron
 	}
+	if job.CronExpr != "" && !job.At.IsZero() {
+		return ErrBothTriggers
+	}
 	if job.Prompt == "" {
 		return ErrEmptyPrompt
 	}
+	switch job.Overlap {
+	case "", OverlapSkip, OverlapQueue, OverlapReplace:
+	default:
+		return fmt.Errorf("%w: %q", ErrBadOverlap, job.Overlap)
+	}
+	return job.Deliver.validate()
+}
+
+func (s *Scheduler) addJob(job Job, persist bool) error {
+	if err := validate(job); err != nil {
+		return err
+	}
 
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -114,42 +229,254 @@ func (s *Scheduler) AddJob(job Job) error {
 		return fmt.Errorf("%w: %s", ErrDuplicateJob, job.ID)
 	}
 
-	// Capture job for the closure.
-	capturedJob := job
-	entryID, err := s.engine.AddFunc(job.CronExpr, func() {
-		s.log().Info("job fired",
-			"job_id", capturedJob.ID,
-			"job_name", capturedJob.Name,
-			"cron_expr", capturedJob.CronExpr,
-		)
-		if handlerErr := s.handler(context.Background(), capturedJob); handlerErr != nil {
-			s.log().Warn("job handler failed",
-				"job_id", capturedJob.ID,
-				"error", handlerErr,
-			)
+	id := job.ID
+	entry := jobEntry{job: job, entryID: -1}
+	if job.CronExpr != "" {
+		entryID, err := s.engine.AddFunc(job.CronExpr, func() { s.fire(id) })
+		if err != nil {
+			return fmt.Errorf("%w for job %q: %w", ErrInvalidCron, job.ID, err)
 		}
-	})
-	if err != nil {
-		return fmt.Errorf("scheduler: failed to register cron job %q: %w", job.ID, err)
+		entry.entryID = entryID
+	}
+	if persist && s.store != nil {
+		if err := s.store.SaveJob(context.Background(), job); err != nil {
+			if entry.entryID >= 0 {
+				s.engine.Remove(entry.entryID)
+			}
+			return fmt.Errorf("scheduler: save job %q: %w", job.ID, err)
+		}
+	}
+	s.armLocked(&entry)
+
+	s.jobs[job.ID] = entry
+	attrs := []any{"job_id", job.ID, "job_name", job.Name}
+	if job.At.IsZero() {
+		attrs = append(attrs, "cron_expr", job.CronExpr)
+	} else {
+		attrs = append(attrs, "at", job.At)
+	}
+	s.log().Info("job registered", attrs...)
+	return nil
+}
+
+// armLocked starts the timer of an unpaused one-shot job; a past At fires
+// immediately. Callers hold s.mu.
+func (s *Scheduler) armLocked(entry *jobEntry) {
+	if entry.job.At.IsZero() || entry.job.Paused || entry.timer != nil {
+		return
 	}
+	id := entry.job.ID
+	entry.timer = s.after(max(entry.job.At.Sub(s.now()), 0), func() { s.fire(id) })
+}
 
-	s.jobs[job.ID] = jobEntry{job: job, entryID: entryID}
-	s.log().Info("job registered",
+// disarmLocked stops a one-shot job's timer. Callers hold s.mu.
+func (s *Scheduler) disarmLocked(entry *jobEntry) {
+	if entry.timer != nil {
+		entry.timer.Stop()
+		entry.timer = nil
+	}
+}
+
+// fire is the trigger callback for job id. It looks the job up at fire time so
+// pausing takes effect without re-registering the cron entry. One-shot jobs
+// are paused (and persisted) before running so they do not fire again after a
+// restart.
+func (s *Scheduler) fire(id string) {
+	job, ok := s.GetJob(id)
+	if !ok || job.Paused || s.ctx.Err() != nil {
+		return
+	}
+	if job.Jitter > 0 {
+		t := time.NewTimer(s.jitter(job.Jitter))
+		select {
+		case <-t.C:
+		case <-s.ctx.Done():
+			t.Stop()
+			return
+		}
+	}
+	if !job.At.IsZero() {
+		if err := s.setPaused(id, true); err != nil {
+			s.log().Warn("one-shot job not paused", "job_id", id, "error", err)
+		}
+	}
+	s.log().Info("job fired",
 		"job_id", job.ID,
 		"job_name", job.Name,
 		"cron_expr", job.CronExpr,
 	)
+	if _, err := s.execute(s.ctx, job); err != nil {
+		if errors.Is(err, ErrJobRunning) {
+			s.log().Info("job skipped: previous run still in progress", "job_id", job.ID)
+			return
+		}
+		s.log().Warn("job handler failed",
+			"job_id", job.ID,
+			"error", err,
+		)
+	}
+}
+
+// begin applies job's overlap policy. When the run may start it marks the job
+// running and returns a context canceled if the run is replaced, plus a func
+// that must be called when the run ends. With OverlapSkip it returns
+// ErrJobRunning while another run is in flight.
+func (s *Scheduler) begin(parent context.Context, job Job) (context.Context, func(), error) {
+	for {
+		s.mu.Lock()
+		cur := s.running[job.ID]
+		if cur == nil {
+			ctx, cancel := context.WithCancel(parent)
+			run := &activeRun{cancel: cancel, done: make(chan struct{})}
+			s.running[job.ID] = run
+			s.mu.Unlock()
+			return ctx, func() {
+				cancel()
+				s.mu.Lock()
+				delete(s.running, job.ID)
+				s.mu.Unlock()
+				close(run.done)
+			}, nil
+		}
+		s.mu.Unlock()
+
+		switch job.Overlap {
+		case OverlapQueue:
+		case OverlapReplace:
+			cur.cancel()
+		default:
+			return nil, nil, fmt.Errorf("%w: %s", ErrJobRunning, job.ID)
+		}
+		select {
+		case <-cur.done:
+		case <-parent.Done():
+			return nil, nil, parent.Err()
+		}
+	}
+}
+
+// execute runs the handler for job under its overlap policy, delivers the
+// response to job.Deliver and records the outcome in the store. The handler
+// or delivery error is returned; a failure to record the run is only logged.
+func (s *Scheduler) execute(ctx context.Context, job Job) (Run, error) {
+	ctx, end, err := s.begin(ctx, job)
+	if err != nil {
+		return Run{}, err
+	}
+	defer end()
+
+	start := s.now()
+	resp, err := s.handler(ctx, job)
+	if err == nil && job.Deliver.Kind != "" {
+		err = s.deliver(ctx, job, resp)
+	}
+	run := Run{
+		JobID:     job.ID,
+		StartedAt: start,
+		Duration:  s.now().Sub(start),
+		Response:  resp,
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+	if s.store != nil {
+		// Record even if the run was cancelled (replaced, stopped, or its
+		// RunNow caller gone): the cancellation is part of its history.
+		if recErr := s.store.RecordRun(context.WithoutCancel(ctx), run); recErr != nil {
+			s.log().Warn("job run not recorded", "job_id", job.ID, "error", recErr)
+		}
+	}
+	return run, err
+}
+
+// deliver sends resp to job.Deliver through the Sender registered for its kind.
+func (s *Scheduler) deliver(ctx context.Context, job Job, resp string) error {
+	sender, ok := s.senders[job.Deliver.Kind]
+	if !ok {
+		return fmt.Errorf("scheduler: deliver to %s: %w", job.Deliver, ErrNoSender)
+	}
+	if err := sender.Send(ctx, job, resp); err != nil {
+		return fmt.Errorf("scheduler: deliver to %s: %w", job.Deliver, err)
+	}
 	return nil
 }
 
+// RunNow runs the job immediately, whether or not it is paused, and returns
+// the recorded run together with the handler's (or delivery) error. The job's
+// overlap policy applies: with OverlapSkip, ErrJobRunning is returned while a
+// previous run is in flight.
+func (s *Scheduler) RunNow(ctx context.Context, id string) (Run, error) {
+	job, ok := s.GetJob(id)
+	if !ok {
+		return Run{}, fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	s.log().Info("job run requested", "job_id", id)
+	return s.execute(ctx, job)
+}
+
+// PauseJob stops a job from firing on its schedule until ResumeJob is called.
+func (s *Scheduler) PauseJob(id string) error {
+	return s.setPaused(id, true)
+}
+
+// ResumeJob lets a paused job fire on its schedule again. A one-shot job whose
+// At has passed fires immediately.
+func (s *Scheduler) ResumeJob(id string) error {
+	return s.setPaused(id, false)
+}
+
+func (s *Scheduler) setPaused(id string, paused bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.jobs[id]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if entry.job.Paused == paused {
+		return nil
+	}
+	entry.job.Paused = paused
+	if s.store != nil {
+		if err := s.store.SaveJob(context.Background(), entry.job); err != nil {
+			return fmt.Errorf("scheduler: save job %q: %w", id, err)
+		}
+	}
+	if paused {
+		s.disarmLocked(&entry)
+	} else {
+		s.armLocked(&entry)
+	}
+	s.jobs[id] = entry
+	s.log().Info("job paused state changed", "job_id", id, "paused", paused)
+	return nil
+}
+
+// Runs returns up to limit of the job's most recent runs, newest first.
+// A limit of zero or less returns all of them. Requires a store.
+func (s *Scheduler) Runs(ctx context.Context, id string, limit int) ([]Run, error) {
+	if s.store == nil {
+		return nil, ErrNoStore
+	}
+	return s.store.Runs(ctx, id, limit)
+}
+
 // Start begins the cron scheduler.
 func (s *Scheduler) Start() {
 	s.engine.Start()
 }
 
-// Stop halts the cron scheduler.
+// Stop halts the cron scheduler, stops one-shot timers and cancels runs in
+// progress and pending jitter waits.
 func (s *Scheduler) Stop() {
 	s.engine.Stop()
+	s.cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, entry := range s.jobs {
+		s.disarmLocked(&entry)
+		s.jobs[id] = entry
+	}
 }
 
 // RemoveJob unregisters a scheduled job by ID. Returns an error if the
@@ -164,17 +491,25 @@ func (s *Scheduler) RemoveJob(id string) error {
 
 	entry, exists := s.jobs[id]
 	if !exists {
-		return fmt.Errorf("scheduler: job %q not found", id)
+		return fmt.Errorf("%w: %s", ErrJobNotFound, id)
+	}
+	if s.store != nil {
+		if err := s.store.DeleteJob(context.Background(), id); err != nil {
+			return fmt.Errorf("scheduler: delete job %q: %w", id, err)
+		}
 	}
 
-	s.engine.Remove(entry.entryID)
+	if entry.entryID >= 0 {
+		s.engine.Remove(entry.entryID)
+	}
+	s.disarmLocked(&entry)
 	delete(s.jobs, id)
 	s.log().Info("job removed", "job_id", id)
 	return nil
 }
 
-// ListJobs returns a copy of all registered jobs. The returned slice is
-// never nil (empty slice when no jobs are registered).
+// ListJobs returns a copy of all registered jobs sorted by ID. The returned
+// slice is never nil (empty slice when no jobs are registered).
 func (s *Scheduler) ListJobs() []Job {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -183,6 +518,7 @@ func (s *Scheduler) ListJobs() []Job {
 	for _, entry := range s.jobs {
 		jobs = append(jobs, entry.job)
 	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
 	return jobs
 }
 
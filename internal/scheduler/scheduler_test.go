@@ -8,6 +8,7 @@ import (
 	"strings"
 	"sync"
 	"testing"
+	"time"
 )
 
 // =============================================================================
@@ -91,7 +92,7 @@ func (m *mockCronEngine) fireAll() {
 
 func TestNewScheduler_ShouldReturnNonNilScheduler(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 
 	s := NewScheduler(engine, handler)
 
@@ -116,7 +117,7 @@ func TestNewScheduler_WhenNilEngine_ShouldPanic(t *testing.T) {
 			t.Error("NewScheduler(nil, handler) should panic")
 		}
 	}()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	NewScheduler(nil, handler)
 }
 
@@ -126,7 +127,7 @@ func TestNewScheduler_WhenNilEngine_ShouldPanic(t *testing.T) {
 
 func TestScheduler_AddJob_ShouldReturnNoError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{
@@ -144,7 +145,7 @@ func TestScheduler_AddJob_ShouldReturnNoError(t *testing.T) {
 
 func TestScheduler_AddJob_WhenEmptyID_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{
@@ -161,7 +162,7 @@ func TestScheduler_AddJob_WhenEmptyID_ShouldReturnError(t *testing.T) {
 
 func TestScheduler_AddJob_WhenEmptyCronExpr_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{
@@ -178,7 +179,7 @@ func TestScheduler_AddJob_WhenEmptyCronExpr_ShouldReturnError(t *testing.T) {
 
 func TestScheduler_AddJob_WhenEmptyPrompt_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{
@@ -195,7 +196,7 @@ func TestScheduler_AddJob_WhenEmptyPrompt_ShouldReturnError(t *testing.T) {
 
 func TestScheduler_AddJob_WhenDuplicateID_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{ID: "job-1", CronExpr: "*/5 * * * *", Prompt: "test"}
@@ -213,7 +214,7 @@ func TestScheduler_AddJob_WhenDuplicateID_ShouldReturnError(t *testing.T) {
 func TestScheduler_AddJob_WhenCronEngineReturnsError_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
 	engine.addErr = errors.New("invalid cron expression")
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{ID: "job-1", CronExpr: "bad-cron", Prompt: "test"}
@@ -230,7 +231,7 @@ func TestScheduler_AddJob_WhenCronEngineReturnsError_ShouldReturnError(t *testin
 
 func TestScheduler_Start_ShouldStartCronEngine(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	s.Start()
@@ -242,7 +243,7 @@ func TestScheduler_Start_ShouldStartCronEngine(t *testing.T) {
 
 func TestScheduler_Stop_ShouldStopCronEngine(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	s.Start()
@@ -259,7 +260,7 @@ func TestScheduler_Stop_ShouldStopCronEngine(t *testing.T) {
 
 func TestScheduler_RemoveJob_ShouldRemoveExistingJob(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{ID: "job-1", CronExpr: "*/5 * * * *", Prompt: "test"}
@@ -278,7 +279,7 @@ func TestScheduler_RemoveJob_ShouldRemoveExistingJob(t *testing.T) {
 
 func TestScheduler_RemoveJob_WhenJobDoesNotExist_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	err := s.RemoveJob("nonexistent")
@@ -289,7 +290,7 @@ func TestScheduler_RemoveJob_WhenJobDoesNotExist_ShouldReturnError(t *testing.T)
 
 func TestScheduler_RemoveJob_ShouldAllowReAddingJobAfterRemoval(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	job := Job{ID: "job-1", CronExpr: "*/5 * * * *", Prompt: "test"}
@@ -309,7 +310,7 @@ func TestScheduler_RemoveJob_ShouldAllowReAddingJobAfterRemoval(t *testing.T) {
 
 func TestScheduler_ListJobs_ShouldReturnAllRegisteredJobs(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	_ = s.AddJob(Job{ID: "a", CronExpr: "*/1 * * * *", Prompt: "p1"})
@@ -332,7 +333,7 @@ func TestScheduler_ListJobs_ShouldReturnAllRegisteredJobs(t *testing.T) {
 
 func TestScheduler_ListJobs_WhenNoJobs_ShouldReturnEmptySlice(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	jobs := s.ListJobs()
@@ -346,7 +347,7 @@ func TestScheduler_ListJobs_WhenNoJobs_ShouldReturnEmptySlice(t *testing.T) {
 
 func TestScheduler_ListJobs_ShouldNotIncludeRemovedJobs(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	_ = s.AddJob(Job{ID: "a", CronExpr: "*/1 * * * *", Prompt: "p1"})
@@ -370,10 +371,10 @@ func TestScheduler_WhenCronFires_ShouldCallHandlerWithCorrectJob(t *testing.T) {
 	engine := newMockCronEngine()
 	var receivedJob Job
 	var handlerCalled bool
-	handler := func(ctx context.Context, job Job) error {
+	handler := func(ctx context.Context, job Job) (string, error) {
 		handlerCalled = true
 		receivedJob = job
-		return nil
+		return "", nil
 	}
 	s := NewScheduler(engine, handler)
 
@@ -400,9 +401,9 @@ func TestScheduler_WhenCronFires_ShouldCallHandlerWithCorrectJob(t *testing.T) {
 func TestScheduler_WhenCronFires_ShouldProvideNonNilContext(t *testing.T) {
 	engine := newMockCronEngine()
 	var receivedCtx context.Context
-	handler := func(ctx context.Context, job Job) error {
+	handler := func(ctx context.Context, job Job) (string, error) {
 		receivedCtx = ctx
-		return nil
+		return "", nil
 	}
 	s := NewScheduler(engine, handler)
 
@@ -418,11 +419,11 @@ func TestScheduler_WhenMultipleJobsFire_ShouldCallHandlerForEach(t *testing.T) {
 	engine := newMockCronEngine()
 	var mu sync.Mutex
 	receivedIDs := []string{}
-	handler := func(ctx context.Context, job Job) error {
+	handler := func(ctx context.Context, job Job) (string, error) {
 		mu.Lock()
 		receivedIDs = append(receivedIDs, job.ID)
 		mu.Unlock()
-		return nil
+		return "", nil
 	}
 	s := NewScheduler(engine, handler)
 
@@ -440,8 +441,8 @@ func TestScheduler_WhenMultipleJobsFire_ShouldCallHandlerForEach(t *testing.T) {
 
 func TestScheduler_WhenHandlerReturnsError_ShouldNotPanic(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error {
-		return errors.New("handler failed")
+	handler := func(ctx context.Context, job Job) (string, error) {
+		return "", errors.New("handler failed")
 	}
 	s := NewScheduler(engine, handler)
 
@@ -457,7 +458,7 @@ func TestScheduler_WhenHandlerReturnsError_ShouldNotPanic(t *testing.T) {
 
 func TestScheduler_RemoveJob_WhenEmptyID_ShouldReturnError(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	err := s.RemoveJob("")
@@ -475,7 +476,7 @@ func TestScheduler_AddJob_ShouldLogJobRegistration(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler, WithLogger(logger))
 
 	_ = s.AddJob(Job{ID: "job-1", Name: "Nightly", CronExpr: "0 0 * * *", Prompt: "run nightly"})
@@ -494,7 +495,7 @@ func TestScheduler_RemoveJob_ShouldLogJobRemoval(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler, WithLogger(logger))
 
 	_ = s.AddJob(Job{ID: "job-1", CronExpr: "0 0 * * *", Prompt: "test"})
@@ -515,7 +516,7 @@ func TestScheduler_WhenCronFires_ShouldLogExecution(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler, WithLogger(logger))
 
 	_ = s.AddJob(Job{ID: "job-1", CronExpr: "*/5 * * * *", Prompt: "test"})
@@ -536,8 +537,8 @@ func TestScheduler_WhenHandlerFails_ShouldLogError(t *testing.T) {
 	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
 
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error {
-		return errors.New("handler exploded")
+	handler := func(ctx context.Context, job Job) (string, error) {
+		return "", errors.New("handler exploded")
 	}
 	s := NewScheduler(engine, handler, WithLogger(logger))
 
@@ -552,7 +553,7 @@ func TestScheduler_WhenHandlerFails_ShouldLogError(t *testing.T) {
 
 func TestWithLogger_WhenNil_ShouldUseDefaultLogger(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	// Should not panic with nil logger option.
 	s := NewScheduler(engine, handler, WithLogger(nil))
 	if s == nil {
@@ -566,7 +567,7 @@ func TestWithLogger_WhenNil_ShouldUseDefaultLogger(t *testing.T) {
 
 func TestScheduler_GetJob_ShouldReturnExistingJob(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	original := Job{ID: "job-1", Name: "Test", CronExpr: "*/5 * * * *", Prompt: "hello"}
@@ -583,7 +584,7 @@ func TestScheduler_GetJob_ShouldReturnExistingJob(t *testing.T) {
 
 func TestScheduler_GetJob_WhenNotFound_ShouldReturnFalse(t *testing.T) {
 	engine := newMockCronEngine()
-	handler := func(ctx context.Context, job Job) error { return nil }
+	handler := func(ctx context.Context, job Job) (string, error) { return "", nil }
 	s := NewScheduler(engine, handler)
 
 	_, ok := s.GetJob("nonexistent")
@@ -600,11 +601,11 @@ func TestScheduler_FullLifecycle_AddStartFireStopRemove(t *testing.T) {
 	engine := newMockCronEngine()
 	var mu sync.Mutex
 	events := []string{}
-	handler := func(ctx context.Context, job Job) error {
+	handler := func(ctx context.Context, job Job) (string, error) {
 		mu.Lock()
 		events = append(events, "fired:"+job.ID)
 		mu.Unlock()
-		return nil
+		return "", nil
 	}
 	s := NewScheduler(engine, handler)
 
@@ -651,3 +652,375 @@ func TestScheduler_FullLifecycle_AddStartFireStopRemove(t *testing.T) {
 		t.Error("expected engine stopped")
 	}
 }
+
+// =============================================================================
+// Store, Pause and RunNow Tests
+// =============================================================================
+
+// memJobStore is an in-memory JobStore for scheduler tests.
+type memJobStore struct {
+	jobs    map[string]Job
+	runs    []Run
+	saveErr error
+}
+
+func newMemJobStore(jobs ...Job) *memJobStore {
+	m := &memJobStore{jobs: make(map[string]Job)}
+	for _, j := range jobs {
+		m.jobs[j.ID] = j
+	}
+	return m
+}
+
+func (m *memJobStore) LoadJobs(ctx context.Context) ([]Job, error) {
+	out := make([]Job, 0, len(m.jobs))
+	for _, j := range m.jobs {
+		out = append(out, j)
+	}
+	return out, nil
+}
+
+func (m *memJobStore) SaveJob(ctx context.Context, job Job) error {
+	if m.saveErr != nil {
+		return m.saveErr
+	}
+	m.jobs[job.ID] = job
+	return nil
+}
+
+func (m *memJobStore) DeleteJob(ctx context.Context, id string) error {
+	delete(m.jobs, id)
+	return nil
+}
+
+func (m *memJobStore) RecordRun(ctx context.Context, run Run) error {
+	// Like SQLiteJobStore's ExecContext, fail on a cancelled context.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.runs = append(m.runs, run)
+	return nil
+}
+
+func (m *memJobStore) Runs(ctx context.Context, jobID string, limit int) ([]Run, error) {
+	var out []Run
+	for i := len(m.runs) - 1; i >= 0; i-- {
+		if m.runs[i].JobID == jobID {
+			out = append(out, m.runs[i])
+		}
+	}
+	return out, nil
+}
+
+func TestScheduler_WithStore_ShouldPersistAddAndRemove(t *testing.T) {
+	store := newMemJobStore()
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { return "", nil }, WithStore(store))
+
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p"})
+	if _, ok := store.jobs["j"]; !ok {
+		t.Fatal("expected AddJob to persist the job")
+	}
+	_ = s.RemoveJob("j")
+	if _, ok := store.jobs["j"]; ok {
+		t.Fatal("expected RemoveJob to delete the stored job")
+	}
+}
+
+func TestScheduler_AddJob_WhenStoreFails_ShouldNotRegister(t *testing.T) {
+	engine := newMockCronEngine()
+	store := newMemJobStore()
+	store.saveErr = errors.New("disk full")
+	s := NewScheduler(engine, func(ctx context.Context, job Job) (string, error) { return "", nil }, WithStore(store))
+
+	if err := s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p"}); err == nil {
+		t.Fatal("expected store error")
+	}
+	if len(s.ListJobs()) != 0 || len(engine.removed) != 1 {
+		t.Errorf("expected cron entry rolled back, jobs=%v removed=%v", s.ListJobs(), engine.removed)
+	}
+}
+
+func TestScheduler_LoadJobs_ShouldRegisterStoredJobs(t *testing.T) {
+	store := newMemJobStore(
+		Job{ID: "a", CronExpr: "@daily", Prompt: "p"},
+		Job{ID: "bad", CronExpr: "@daily"},
+	)
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { return "", nil }, WithStore(store))
+
+	err := s.LoadJobs(context.Background())
+	if !errors.Is(err, ErrEmptyPrompt) {
+		t.Errorf("expected invalid stored job to be reported, got %v", err)
+	}
+	if _, ok := s.GetJob("a"); !ok {
+		t.Error("expected valid stored job to be registered")
+	}
+	if err := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { return "", nil }).LoadJobs(context.Background()); !errors.Is(err, ErrNoStore) {
+		t.Errorf("expected ErrNoStore without a store, got %v", err)
+	}
+}
+
+func TestScheduler_WhenPaused_ShouldNotFireUntilResumed(t *testing.T) {
+	engine := newMockCronEngine()
+	store := newMemJobStore()
+	calls := 0
+	s := NewScheduler(engine, func(ctx context.Context, job Job) (string, error) { calls++; return "", nil }, WithStore(store))
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p"})
+
+	if err := s.PauseJob("j"); err != nil {
+		t.Fatalf("PauseJob: %v", err)
+	}
+	engine.fire(1)
+	if calls != 0 || !store.jobs["j"].Paused {
+		t.Fatalf("paused job fired (calls=%d) or pause not persisted", calls)
+	}
+	_ = s.ResumeJob("j")
+	engine.fire(1)
+	if calls != 1 {
+		t.Errorf("expected resumed job to fire, calls=%d", calls)
+	}
+	if err := s.PauseJob("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestScheduler_RunNow_ShouldRecordRunWithResponseAndError(t *testing.T) {
+	store := newMemJobStore()
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) {
+		if job.ID == "bad" {
+			return "", errors.New("llm down")
+		}
+		return "done", nil
+	}, WithStore(store))
+	ticks := 0
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { ticks++; return base.Add(time.Duration(ticks) * time.Second) }
+	_ = s.AddJob(Job{ID: "ok", CronExpr: "@daily", Prompt: "p", Paused: true})
+	_ = s.AddJob(Job{ID: "bad", CronExpr: "@daily", Prompt: "p"})
+
+	run, err := s.RunNow(context.Background(), "ok")
+	if err != nil || run.Response != "done" || run.Duration != time.Second {
+		t.Fatalf("unexpected run %+v / err %v", run, err)
+	}
+	if _, err := s.RunNow(context.Background(), "bad"); err == nil {
+		t.Fatal("expected handler error")
+	}
+	runs, _ := s.Runs(context.Background(), "bad", 0)
+	if len(runs) != 1 || runs[0].Error != "llm down" {
+		t.Errorf("expected failed run recorded, got %+v", runs)
+	}
+	if _, err := s.RunNow(context.Background(), "missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("expected ErrJobNotFound, got %v", err)
+	}
+}
+
+func TestScheduler_RunNow_WhenCallerCancels_ShouldStillRecordRun(t *testing.T) {
+	store := newMemJobStore()
+	ctx, cancel := context.WithCancel(context.Background())
+	s := NewScheduler(newMockCronEngine(), func(runCtx context.Context, job Job) (string, error) {
+		cancel() // the RunNow client disconnects mid-run
+		<-runCtx.Done()
+		return "", runCtx.Err()
+	}, WithStore(store))
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p"})
+
+	if _, err := s.RunNow(ctx, "j"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("RunNow err = %v, want context.Canceled", err)
+	}
+	runs, _ := s.Runs(context.Background(), "j", 0)
+	if len(runs) != 1 || runs[0].Error != context.Canceled.Error() {
+		t.Errorf("expected cancelled run recorded, got %+v", runs)
+	}
+}
+
+// =============================================================================
+// Trigger, Overlap and Delivery Tests
+// =============================================================================
+
+func TestScheduler_AddJob_WhenTriggersInvalid_ShouldReturnError(t *testing.T) {
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { return "", nil })
+	at := time.Now().Add(time.Hour)
+
+	cases := map[error]Job{
+		ErrEmptyCron:    {ID: "a", Prompt: "p"},
+		ErrBothTriggers: {ID: "b", CronExpr: "@daily", At: at, Prompt: "p"},
+		ErrBadOverlap:   {ID: "c", CronExpr: "@daily", Prompt: "p", Overlap: "sometimes"},
+		ErrBadTarget:    {ID: "d", CronExpr: "@daily", Prompt: "p", Deliver: Target{Kind: TargetTelegram}},
+	}
+	for want, job := range cases {
+		if err := s.AddJob(job); !errors.Is(err, want) {
+			t.Errorf("job %s: want %v, got %v", job.ID, want, err)
+		}
+	}
+}
+
+func TestScheduler_WhenAtJobFires_ShouldRunOnceAndPause(t *testing.T) {
+	store := newMemJobStore()
+	calls := 0
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { calls++; return "", nil }, WithStore(store))
+	var armed time.Duration
+	var fn func()
+	s.after = func(d time.Duration, f func()) *time.Timer {
+		armed, fn = d, f
+		return time.NewTimer(time.Hour)
+	}
+	now := time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)
+	s.now = func() time.Time { return now }
+
+	if err := s.AddJob(Job{ID: "once", At: now.Add(90 * time.Minute), Prompt: "p"}); err != nil {
+		t.Fatalf("AddJob: %v", err)
+	}
+	if armed != 90*time.Minute {
+		t.Errorf("expected timer armed for 90m, got %v", armed)
+	}
+	fn()
+	fn()
+	if calls != 1 {
+		t.Errorf("expected one-shot job to run once, got %d", calls)
+	}
+	if job, _ := s.GetJob("once"); !job.Paused || !store.jobs["once"].Paused {
+		t.Error("expected one-shot job paused (and persisted) after firing")
+	}
+}
+
+func TestScheduler_WhenJitterSet_ShouldDelayFiring(t *testing.T) {
+	engine := newMockCronEngine()
+	done := make(chan struct{}, 1)
+	s := NewScheduler(engine, func(ctx context.Context, job Job) (string, error) { done <- struct{}{}; return "", nil })
+	var gotMax time.Duration
+	s.jitter = func(max time.Duration) time.Duration { gotMax = max; return time.Millisecond }
+
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@every 1h", Jitter: 5 * time.Minute, Prompt: "p"})
+	engine.fire(1)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("expected job to run after jitter")
+	}
+	if gotMax != 5*time.Minute {
+		t.Errorf("expected jitter bound 5m, got %v", gotMax)
+	}
+}
+
+func TestScheduler_Stop_ShouldAbortPendingJitter(t *testing.T) {
+	engine := newMockCronEngine()
+	calls := 0
+	s := NewScheduler(engine, func(ctx context.Context, job Job) (string, error) { calls++; return "", nil })
+	s.jitter = func(time.Duration) time.Duration { return time.Hour }
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@every 1h", Jitter: time.Hour, Prompt: "p"})
+
+	fired := make(chan struct{})
+	go func() { engine.fire(1); close(fired) }()
+	s.Stop()
+	select {
+	case <-fired:
+	case <-time.After(time.Second):
+		t.Fatal("Stop should abort the jitter wait")
+	}
+	if calls != 0 {
+		t.Errorf("expected no run after Stop, got %d", calls)
+	}
+}
+
+// blockingHandler blocks each run until released and reports whether the
+// run's context was canceled.
+type blockingHandler struct {
+	started  chan string
+	release  chan struct{}
+	canceled chan string
+}
+
+func newBlockingHandler() *blockingHandler {
+	return &blockingHandler{started: make(chan string, 4), release: make(chan struct{}), canceled: make(chan string, 4)}
+}
+
+func (b *blockingHandler) handle(ctx context.Context, job Job) (string, error) {
+	b.started <- job.Prompt
+	select {
+	case <-b.release:
+		return job.Prompt, nil
+	case <-ctx.Done():
+		b.canceled <- job.Prompt
+		return "", ctx.Err()
+	}
+}
+
+func TestScheduler_Overlap_Skip_ShouldRejectConcurrentRun(t *testing.T) {
+	h := newBlockingHandler()
+	s := NewScheduler(newMockCronEngine(), h.handle)
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p"})
+
+	go s.RunNow(context.Background(), "j")
+	<-h.started
+	if _, err := s.RunNow(context.Background(), "j"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("expected ErrJobRunning, got %v", err)
+	}
+	close(h.release)
+}
+
+func TestScheduler_Overlap_Queue_ShouldRunAfterCurrentFinishes(t *testing.T) {
+	h := newBlockingHandler()
+	s := NewScheduler(newMockCronEngine(), h.handle)
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p", Overlap: OverlapQueue})
+
+	go s.RunNow(context.Background(), "j")
+	<-h.started
+	second := make(chan error, 1)
+	go func() { _, err := s.RunNow(context.Background(), "j"); second <- err }()
+
+	select {
+	case <-h.started:
+		t.Fatal("queued run started before the first finished")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(h.release)
+	if err := <-second; err != nil {
+		t.Errorf("queued run: %v", err)
+	}
+}
+
+func TestScheduler_Overlap_Replace_ShouldCancelCurrentRun(t *testing.T) {
+	h := newBlockingHandler()
+	s := NewScheduler(newMockCronEngine(), h.handle)
+	_ = s.AddJob(Job{ID: "j", CronExpr: "@daily", Prompt: "p", Overlap: OverlapReplace})
+
+	first := make(chan error, 1)
+	go func() { _, err := s.RunNow(context.Background(), "j"); first <- err }()
+	<-h.started
+	second := make(chan error, 1)
+	go func() { _, err := s.RunNow(context.Background(), "j"); second <- err }()
+
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected first run canceled, got %v", err)
+	}
+	<-h.started
+	close(h.release)
+	if err := <-second; err != nil {
+		t.Errorf("replacement run: %v", err)
+	}
+}
+
+func TestScheduler_Deliver_ShouldSendResponseThroughSenderForKind(t *testing.T) {
+	var gotTo, gotText string
+	sender := SenderFunc(func(ctx context.Context, job Job, text string) error {
+		gotTo, gotText = job.Deliver.To, text
+		return nil
+	})
+	store := newMemJobStore()
+	s := NewScheduler(newMockCronEngine(), func(ctx context.Context, job Job) (string, error) { return "briefing", nil },
+		WithSender(TargetTelegram, sender), WithStore(store))
+	_ = s.AddJob(Job{ID: "tg", CronExpr: "@daily", Prompt: "p", Deliver: Target{Kind: TargetTelegram, To: "42"}})
+	_ = s.AddJob(Job{ID: "wa", CronExpr: "@daily", Prompt: "p", Deliver: Target{Kind: TargetWhatsApp, To: "x@s.whatsapp.net"}})
+
+	if _, err := s.RunNow(context.Background(), "tg"); err != nil {
+		t.Fatalf("RunNow: %v", err)
+	}
+	if gotTo != "42" || gotText != "briefing" {
+		t.Errorf("sender got to=%q text=%q", gotTo, gotText)
+	}
+	if _, err := s.RunNow(context.Background(), "wa"); !errors.Is(err, ErrNoSender) {
+		t.Errorf("expected ErrNoSender, got %v", err)
+	}
+	if runs, _ := s.Runs(context.Background(), "wa", 1); len(runs) != 1 || runs[0].Response != "briefing" || runs[0].Error == "" {
+		t.Errorf("expected failed delivery recorded with response, got %+v", runs)
+	}
+}
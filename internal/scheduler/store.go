@@ -0,0 +1,228 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Run is one execution of a job, scheduled or triggered with RunNow.
+type Run struct {
+	JobID     string        `json:"jobId"`
+	StartedAt time.Time     `json:"startedAt"`
+	Duration  time.Duration `json:"duration"`           // Wall time spent in the handler
+	Error     string        `json:"error,omitempty"`    // Handler error, empty on success
+	Response  string        `json:"response,omitempty"` // Handler (brain) response
+}
+
+// JobStore persists job definitions and their run history.
+type JobStore interface {
+	// LoadJobs returns every stored job.
+	LoadJobs(ctx context.Context) ([]Job, error)
+	// SaveJob inserts the job or replaces the stored job with the same ID.
+	SaveJob(ctx context.Context, job Job) error
+	// DeleteJob removes the job and its run history. Deleting an unknown ID is not an error.
+	DeleteJob(ctx context.Context, id string) error
+	// RecordRun appends a run to the job's history.
+	RecordRun(ctx context.Context, run Run) error
+	// Runs returns up to limit of the job's most recent runs, newest first (limit <= 0 means all).
+	Runs(ctx context.Context, jobID string, limit int) ([]Run, error)
+}
+
+// SQLiteJobStore stores jobs and run history in SQLite/libSQL tables. Open the
+// connection with db.Connect.
+type SQLiteJobStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteJobStore creates a job store and initializes the schema.
+// Returns an error if the db is nil or if the migration fails.
+func NewSQLiteJobStore(db *sql.DB) (*SQLiteJobStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db must not be nil")
+	}
+	s := &SQLiteJobStore{db: db}
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("scheduler migrate: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the scheduler_jobs and scheduler_runs tables if they don't exist.
+func (s *SQLiteJobStore) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS scheduler_jobs (
+			id TEXT PRIMARY KEY,
+			name TEXT NOT NULL DEFAULT '',
+			cron_expr TEXT NOT NULL,
+			prompt TEXT NOT NULL,
+			paused INTEGER NOT NULL DEFAULT 0,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	if err := s.addMissingColumns("scheduler_jobs", jobColumns); err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS scheduler_runs (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			job_id TEXT NOT NULL,
+			started_at INTEGER NOT NULL,
+			duration_ns INTEGER NOT NULL,
+			error TEXT NOT NULL DEFAULT '',
+			response TEXT NOT NULL DEFAULT ''
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`CREATE INDEX IF NOT EXISTS scheduler_runs_job ON scheduler_runs (job_id, started_at)`)
+	return err
+}
+
+// jobColumns are scheduler_jobs columns added after the table was introduced,
+// with their definitions. migrate adds any that an existing table lacks.
+var jobColumns = []struct{ name, def string }{
+	{"at_ns", "INTEGER NOT NULL DEFAULT 0"},
+	{"jitter_ns", "INTEGER NOT NULL DEFAULT 0"},
+	{"overlap", "TEXT NOT NULL DEFAULT ''"},
+	{"deliver_kind", "TEXT NOT NULL DEFAULT ''"},
+	{"deliver_to", "TEXT NOT NULL DEFAULT ''"},
+	{"model", "TEXT NOT NULL DEFAULT ''"},
+}
+
+// addMissingColumns adds each of columns that table does not have yet.
+func (s *SQLiteJobStore) addMissingColumns(table string, columns []struct{ name, def string }) error {
+	rows, err := s.db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return err
+		}
+		have[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, c := range columns {
+		if have[c.name] {
+			continue
+		}
+		if _, err := s.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, c.name, c.def)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadJobs implements JobStore.
+func (s *SQLiteJobStore) LoadJobs(ctx context.Context) ([]Job, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, name, cron_expr, at_ns, jitter_ns, overlap, prompt, model, deliver_kind, deliver_to, paused
+		FROM scheduler_jobs ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []Job
+	for rows.Next() {
+		var j Job
+		var at, jitter int64
+		if err := rows.Scan(&j.ID, &j.Name, &j.CronExpr, &at, &jitter, &j.Overlap, &j.Prompt, &j.Model, &j.Deliver.Kind, &j.Deliver.To, &j.Paused); err != nil {
+			return nil, err
+		}
+		if at != 0 {
+			j.At = time.Unix(0, at).UTC()
+		}
+		j.Jitter = time.Duration(jitter)
+		jobs = append(jobs, j)
+	}
+	return jobs, rows.Err()
+}
+
+// SaveJob implements JobStore.
+func (s *SQLiteJobStore) SaveJob(ctx context.Context, job Job) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO scheduler_jobs (id, name, cron_expr, at_ns, jitter_ns, overlap, prompt, model, deliver_kind, deliver_to, paused)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			name = excluded.name,
+			cron_expr = excluded.cron_expr,
+			at_ns = excluded.at_ns,
+			jitter_ns = excluded.jitter_ns,
+			overlap = excluded.overlap,
+			prompt = excluded.prompt,
+			model = excluded.model,
+			deliver_kind = excluded.deliver_kind,
+			deliver_to = excluded.deliver_to,
+			paused = excluded.paused
+	`, job.ID, job.Name, job.CronExpr, atNanos(job.At), int64(job.Jitter), job.Overlap, job.Prompt, job.Model, job.Deliver.Kind, job.Deliver.To, job.Paused)
+	return err
+}
+
+// DeleteJob implements JobStore.
+func (s *SQLiteJobStore) DeleteJob(ctx context.Context, id string) error {
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM scheduler_runs WHERE job_id = ?", id); err != nil {
+		return err
+	}
+	_, err := s.db.ExecContext(ctx, "DELETE FROM scheduler_jobs WHERE id = ?", id)
+	return err
+}
+
+// RecordRun implements JobStore.
+func (s *SQLiteJobStore) RecordRun(ctx context.Context, run Run) error {
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO scheduler_runs (job_id, started_at, duration_ns, error, response) VALUES (?, ?, ?, ?, ?)",
+		run.JobID, run.StartedAt.UnixNano(), int64(run.Duration), run.Error, run.Response)
+	return err
+}
+
+// Runs implements JobStore.
+func (s *SQLiteJobStore) Runs(ctx context.Context, jobID string, limit int) ([]Run, error) {
+	if limit <= 0 {
+		limit = -1 // SQLite: no limit
+	}
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT job_id, started_at, duration_ns, error, response FROM scheduler_runs
+		WHERE job_id = ? ORDER BY started_at DESC, id DESC LIMIT ?
+	`, jobID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var runs []Run
+	for rows.Next() {
+		var r Run
+		var startedAt, duration int64
+		if err := rows.Scan(&r.JobID, &startedAt, &duration, &r.Error, &r.Response); err != nil {
+			return nil, err
+		}
+		r.StartedAt = time.Unix(0, startedAt).UTC()
+		r.Duration = time.Duration(duration)
+		runs = append(runs, r)
+	}
+	return runs, rows.Err()
+}
+
+// atNanos stores a zero At as 0 rather than the zero time's negative UnixNano.
+func atNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+var _ JobStore = (*SQLiteJobStore)(nil)
@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ironclaw/internal/db"
+)
+
+func newTestJobStore(t *testing.T) *SQLiteJobStore {
+	t.Helper()
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "jobs.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	store, err := NewSQLiteJobStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteJobStore: %v", err)
+	}
+	return store
+}
+
+func TestNewSQLiteJobStore_WhenNilDB_ShouldReturnError(t *testing.T) {
+	if _, err := NewSQLiteJobStore(nil); err == nil {
+		t.Fatal("expected error for nil db")
+	}
+}
+
+func TestSQLiteJobStore_SaveJob_ShouldUpsertAndLoad(t *testing.T) {
+	store := newTestJobStore(t)
+	ctx := context.Background()
+
+	_ = store.SaveJob(ctx, Job{ID: "b", CronExpr: "@daily", Prompt: "p"})
+	_ = store.SaveJob(ctx, Job{ID: "a", Name: "A", CronExpr: "@hourly", Prompt: "old"})
+	if err := store.SaveJob(ctx, Job{ID: "a", Name: "A", CronExpr: "@hourly", Prompt: "new", Paused: true}); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+
+	jobs, err := store.LoadJobs(ctx)
+	if err != nil {
+		t.Fatalf("LoadJobs: %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].ID != "a" || jobs[1].ID != "b" {
+		t.Fatalf("expected jobs a, b; got %+v", jobs)
+	}
+	if jobs[0].Prompt != "new" || !jobs[0].Paused {
+		t.Errorf("expected upserted job, got %+v", jobs[0])
+	}
+}
+
+func TestSQLiteJobStore_Runs_ShouldReturnNewestFirstWithLimit(t *testing.T) {
+	store := newTestJobStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		run := Run{JobID: "j", StartedAt: base.Add(time.Duration(i) * time.Hour), Duration: 2 * time.Second, Response: string(rune('a' + i))}
+		if err := store.RecordRun(ctx, run); err != nil {
+			t.Fatalf("RecordRun: %v", err)
+		}
+	}
+	_ = store.RecordRun(ctx, Run{JobID: "other", StartedAt: base, Error: "boom"})
+
+	runs, err := store.Runs(ctx, "j", 2)
+	if err != nil {
+		t.Fatalf("Runs: %v", err)
+	}
+	if len(runs) != 2 || runs[0].Response != "c" || runs[1].Response != "b" {
+		t.Fatalf("expected runs c, b; got %+v", runs)
+	}
+	if !runs[0].StartedAt.Equal(base.Add(2*time.Hour)) || runs[0].Duration != 2*time.Second {
+		t.Errorf("timestamps not round-tripped: %+v", runs[0])
+	}
+	if all, _ := store.Runs(ctx, "j", 0); len(all) != 3 {
+		t.Errorf("limit 0 should return all runs, got %d", len(all))
+	}
+}
+
+func TestSQLiteJobStore_DeleteJob_ShouldRemoveJobAndRuns(t *testing.T) {
+	store := newTestJobStore(t)
+	ctx := context.Background()
+	_ = store.SaveJob(ctx, Job{ID: "j", CronExpr: "@daily", Prompt: "p"})
+	_ = store.RecordRun(ctx, Run{JobID: "j", StartedAt: time.Now()})
+
+	if err := store.DeleteJob(ctx, "j"); err != nil {
+		t.Fatalf("DeleteJob: %v", err)
+	}
+	jobs, _ := store.LoadJobs(ctx)
+	runs, _ := store.Runs(ctx, "j", 0)
+	if len(jobs) != 0 || len(runs) != 0 {
+		t.Errorf("expected job and runs removed, got %d jobs, %d runs", len(jobs), len(runs))
+	}
+}
+
+func TestSQLiteJobStore_ShouldRoundTripTriggersTargetAndModel(t *testing.T) {
+	store := newTestJobStore(t)
+	ctx := context.Background()
+	at := time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)
+	want := Job{ID: "j", At: at, Jitter: time.Minute, Overlap: OverlapQueue, Prompt: "p", Model: "fast", Deliver: Target{Kind: TargetWebhook, To: "https://example.com/hook"}}
+
+	if err := store.SaveJob(ctx, want); err != nil {
+		t.Fatalf("SaveJob: %v", err)
+	}
+	jobs, _ := store.LoadJobs(ctx)
+	if len(jobs) != 1 || !jobs[0].At.Equal(at) || jobs[0].Jitter != time.Minute || jobs[0].Overlap != OverlapQueue || jobs[0].Model != "fast" || jobs[0].Deliver != want.Deliver {
+		t.Errorf("round trip mismatch: %+v", jobs)
+	}
+}
+
+func TestNewSQLiteJobStore_WhenTableFromOlderSchema_ShouldAddColumns(t *testing.T) {
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "old.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.Exec(`CREATE TABLE scheduler_jobs (id TEXT PRIMARY KEY, name TEXT NOT NULL DEFAULT '', cron_expr TEXT NOT NULL, prompt TEXT NOT NULL, paused INTEGER NOT NULL DEFAULT 0, created_at DATETIME DEFAULT CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatalf("create old table: %v", err)
+	}
+	_, _ = conn.Exec(`INSERT INTO scheduler_jobs (id, cron_expr, prompt) VALUES ('old', '@daily', 'p')`)
+
+	store, err := NewSQLiteJobStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteJobStore: %v", err)
+	}
+	jobs, err := store.LoadJobs(context.Background())
+	if err != nil || len(jobs) != 1 || jobs[0].CronExpr != "@daily" || !jobs[0].At.IsZero() {
+		t.Errorf("expected old job readable after migration, got %+v / %v", jobs, err)
+	}
+}
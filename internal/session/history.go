@@ -4,7 +4,10 @@ import (
 	"bufio"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"ironclaw/internal/domain"
 )
@@ -103,5 +106,77 @@ func (h *HistoryStore) LoadHistory(n int) ([]domain.Message, error) {
 	return msgs, nil
 }
 
-// Ensure HistoryStore implements domain.SessionHistoryStore.
-var _ domain.SessionHistoryStore = (*HistoryStore)(nil)
+// summaryPath returns the file holding the rolling summary of the history:
+// the history path with ".summary.json" in place of ".jsonl".
+func (h *HistoryStore) summaryPath() string {
+	return strings.TrimSuffix(h.path, ".jsonl") + ".summary.json"
+}
+
+// LoadSummary reads the rolling summary stored next to the history file.
+// Returns a zero summary when there is none.
+func (h *HistoryStore) LoadSummary() (domain.ContextSummary, error) {
+	data, err := os.ReadFile(h.summaryPath())
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.ContextSummary{}, nil
+	}
+	if err != nil {
+		return domain.ContextSummary{}, err
+	}
+	var s domain.ContextSummary
+	if err := json.Unmarshal(data, &s); err != nil {
+		return domain.ContextSummary{}, fmt.Errorf("session: read summary %s: %w", h.summaryPath(), err)
+	}
+	return s, nil
+}
+
+// SaveSummary replaces the rolling summary stored next to the history file.
+// The file is written to a temporary name and renamed, so readers never see
+// a partial summary.
+func (h *HistoryStore) SaveSummary(s domain.ContextSummary) error {
+	marshal := json.Marshal
+	if h.marshalFn != nil {
+		marshal = h.marshalFn
+	}
+	data, err := marshal(s)
+	if err != nil {
+		return err
+	}
+	path := h.summaryPath()
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ChannelHistoryPath returns the JSONL history file for channelID under dir.
+// Characters outside [A-Za-z0-9_-] are replaced with '_' so channel IDs such as
+// "telegram:123" or "../x" always map to a single file inside dir.
+func ChannelHistoryPath(dir, channelID string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, channelID)
+	if name == "" {
+		name = "_"
+	}
+	return filepath.Join(dir, name+".jsonl")
+}
+
+// NewChannelHistoryFactory returns a factory (assignable to router.HistoryFactory)
+// that gives each channel its own HistoryStore under dir. dir is created on first use.
+func NewChannelHistoryFactory(dir string) func(channelID string) domain.SessionHistoryStore {
+	return func(channelID string) domain.SessionHistoryStore {
+		_ = os.MkdirAll(dir, 0755)
+		return NewHistoryStore(ChannelHistoryPath(dir, channelID))
+	}
+}
+
+// Ensure HistoryStore implements domain.SessionHistoryStore and domain.SummaryStore.
+var (
+	_ domain.SessionHistoryStore = (*HistoryStore)(nil)
+	_ domain.SummaryStore        = (*HistoryStore)(nil)
+)
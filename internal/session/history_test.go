@@ -660,3 +660,74 @@ func TestHistoryStore_LoadHistory_WhenFileHasOnlyInvalidJSON_ShouldReturnEmptySl
 		t.Errorf("expected 0 messages from all-invalid file, got %d", len(msgs))
 	}
 }
+
+func TestChannelHistoryPath_WhenChannelIDHasSeparators_ShouldStayInsideDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"telegram:123", "../escape", "a/b", ""} {
+		got := ChannelHistoryPath(dir, id)
+		if filepath.Dir(got) != dir {
+			t.Errorf("ChannelHistoryPath(%q) = %q, want a file directly inside %q", id, got, dir)
+		}
+	}
+	if got := ChannelHistoryPath(dir, "general"); got != filepath.Join(dir, "general.jsonl") {
+		t.Errorf("plain channel ID: got %q", got)
+	}
+}
+
+func TestNewChannelHistoryFactory_ShouldCreateDirAndPersistPerChannel(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "sessions")
+	factory := NewChannelHistoryFactory(dir)
+
+	a, b := factory("a"), factory("b")
+	if err := a.Append(domain.NewTextMessage(domain.RoleUser, "hello a")); err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	if msgs, _ := b.LoadHistory(10); len(msgs) != 0 {
+		t.Errorf("channel b should be empty, got %d messages", len(msgs))
+	}
+	msgs, err := factory("a").LoadHistory(10)
+	if err != nil || len(msgs) != 1 {
+		t.Fatalf("reopened channel a: got %d messages, err %v", len(msgs), err)
+	}
+}
+
+// =============================================================================
+// Summary tests
+// =============================================================================
+
+func TestHistoryStore_LoadSummary_WhenNoneSaved_ShouldReturnZeroSummary(t *testing.T) {
+	store := NewHistoryStore(filepath.Join(t.TempDir(), "history.jsonl"))
+
+	s, err := store.LoadSummary()
+	if err != nil {
+		t.Fatalf("LoadSummary: %v", err)
+	}
+	if s.Text != "" || !s.Through.IsZero() {
+		t.Errorf("expected zero summary, got %+v", s)
+	}
+}
+
+func TestHistoryStore_SaveSummary_ShouldRoundTripBesideHistory(t *testing.T) {
+	dir := t.TempDir()
+	store := NewHistoryStore(filepath.Join(dir, "history.jsonl"))
+	want := domain.ContextSummary{
+		Text:     "The user is planning a trip to Lisbon.",
+		Through:  time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Messages: 12,
+		Updated:  time.Date(2026, 3, 1, 12, 5, 0, 0, time.UTC),
+	}
+
+	if err := store.SaveSummary(want); err != nil {
+		t.Fatalf("SaveSummary: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "history.summary.json")); err != nil {
+		t.Errorf("summary file: %v", err)
+	}
+	got, err := store.LoadSummary()
+	if err != nil {
+		t.Fatalf("LoadSummary: %v", err)
+	}
+	if got.Text != want.Text || !got.Through.Equal(want.Through) || got.Messages != want.Messages || !got.Updated.Equal(want.Updated) {
+		t.Errorf("LoadSummary = %+v, want %+v", got, want)
+	}
+}
@@ -2,10 +2,19 @@ package telegram
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
 )
 
 // BotAPI abstracts the Telegram Bot API for testing.
@@ -20,50 +29,138 @@ type MessageRouter interface {
 	Route(ctx context.Context, channelID, prompt string) (string, error)
 }
 
+// ContentRouter is a MessageRouter that also accepts a user message made of
+// content blocks, such as a photo and its caption (implemented by
+// router.Router).
+type ContentRouter interface {
+	MessageRouter
+	RouteContent(ctx context.Context, channelID string, blocks []domain.ContentBlock) (string, error)
+}
+
+// FileURLGetter resolves a Telegram file ID to a download URL (implemented by
+// *tgbotapi.BotAPI).
+type FileURLGetter interface {
+	GetFileDirectURL(fileID string) (string, error)
+}
+
+var _ FileURLGetter = (*tgbotapi.BotAPI)(nil)
+
+// ImagePreparer checks and downscales downloaded image data for the model
+// (implemented by *tooling.RealImageProcessor).
+type ImagePreparer interface {
+	PrepareImage(data []byte) (domain.ImageBlock, error)
+}
+
 // Adapter bridges Telegram to IronClaw's multi-channel routing system.
 type Adapter struct {
-	bot    BotAPI
-	router MessageRouter
+	bot       BotAPI
+	router    MessageRouter
+	approvals *approval.Gate
+	files     FileURLGetter
+	images    ImagePreparer
+	client    *http.Client
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
 }
 
+// Option configures an Adapter.
+type Option func(*Adapter)
+
+// WithApprovals asks for approval of tool calls held by g with a message
+// carrying Approve/Deny/Always buttons; the buttons (or a reply such as
+// "approve <id>") resolve the request.
+func WithApprovals(g *approval.Gate) Option {
+	return func(a *Adapter) { a.approvals = g }
+}
+
+// WithPhotos downloads photos sent to the bot through files, prepares them
+// with images and attaches them, with their caption, to the user message.
+// It takes effect only when the router implements ContentRouter; otherwise
+// photos without text are ignored as before.
+func WithPhotos(files FileURLGetter, images ImagePreparer) Option {
+	return func(a *Adapter) {
+		a.files = files
+		a.images = images
+	}
+}
+
 // NewAdapter creates a new Telegram adapter. Both bot and router must be non-nil.
-func NewAdapter(bot BotAPI, router MessageRouter) *Adapter {
+func NewAdapter(bot BotAPI, router MessageRouter, opts ...Option) *Adapter {
 	if bot == nil {
 		panic("telegram: bot must not be nil")
 	}
 	if router == nil {
 		panic("telegram: router must not be nil")
 	}
-	return &Adapter{
+	a := &Adapter{
 		bot:    bot,
 		router: router,
+		client: http.DefaultClient,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
+// approvalCallbackPrefix starts the callback data of approval buttons:
+// "approval:<decision>:<id>".
+const approvalCallbackPrefix = "approval:"
+
+// maxPhotoBytes bounds photo downloads; the Bot API serves files up to 20 MB.
+const maxPhotoBytes = 20 << 20
+
+// updateQueueSize bounds how many updates wait while a message is being answered.
+const updateQueueSize = 32
+
 // ChatIDToChannelID converts a Telegram ChatID to an IronClaw ChannelID.
 func ChatIDToChannelID(chatID int64) string {
 	return "telegram-" + strconv.FormatInt(chatID, 10)
 }
 
+// SendText sends text to the chat identified by chatID (a decimal Telegram
+// chat ID, negative for groups). It is used to push messages that are not
+// replies, such as scheduled job output.
+func SendText(bot BotAPI, chatID, text string) error {
+	id, err := strconv.ParseInt(chatID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("telegram: invalid chat ID %q: %w", chatID, err)
+	}
+	if _, err := bot.Send(tgbotapi.NewMessage(id, text)); err != nil {
+		return fmt.Errorf("telegram: send to chat %d: %w", id, err)
+	}
+	return nil
+}
+
 // HandleUpdate processes a single Telegram update.
-// Ignores updates without a message or with empty text.
-// Routes the message text through the brain and sends the reply back to Telegram.
+// Ignores updates without a message, and messages with neither text nor a
+// photo the adapter can attach (see WithPhotos).
+// Routes the message through the brain and sends the reply back to Telegram.
 func (a *Adapter) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
 	if update.Message == nil {
 		return
 	}
 	text := update.Message.Text
-	if text == "" {
+	cr, photos := a.router.(ContentRouter)
+	photos = photos && a.files != nil && a.images != nil && len(update.Message.Photo) > 0
+	if text == "" && !photos {
 		return
 	}
 
 	chatID := update.Message.Chat.ID
 	channelID := ChatIDToChannelID(chatID)
 
-	reply, err := a.router.Route(ctx, channelID, text)
+	if a.approvals != nil {
+		ctx = approval.NewContext(ctx, channelID, a.prompter(chatID))
+	}
+	var reply string
+	var err error
+	if photos {
+		reply, err = a.routePhoto(ctx, cr, channelID, update.Message)
+	} else {
+		reply, err = a.router.Route(ctx, channelID, text)
+	}
 	if err != nil {
 		reply = "Error: " + err.Error()
 	}
@@ -73,7 +170,112 @@ func (a *Adapter) HandleUpdate(ctx context.Context, update tgbotapi.Update) {
 	_, _ = a.bot.Send(msg)
 }
 
+// routePhoto downloads the largest size of msg's photo and routes it with
+// the caption.
+func (a *Adapter) routePhoto(ctx context.Context, cr ContentRouter, channelID string, msg *tgbotapi.Message) (string, error) {
+	data, err := a.download(ctx, msg.Photo[len(msg.Photo)-1].FileID)
+	if err != nil {
+		return "", err
+	}
+	img, err := a.images.PrepareImage(data)
+	if err != nil {
+		return "", fmt.Errorf("telegram: photo: %w", err)
+	}
+	blocks := []domain.ContentBlock{img}
+	if msg.Caption != "" {
+		blocks = append(blocks, domain.TextBlock{Text: msg.Caption})
+	}
+	return cr.RouteContent(ctx, channelID, blocks)
+}
+
+// download fetches the file identified by fileID. Errors never include the
+// download URL, which embeds the bot token.
+func (a *Adapter) download(ctx context.Context, fileID string) ([]byte, error) {
+	fileURL, err := a.files.GetFileDirectURL(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: get file %s: %w", fileID, withoutURL(err))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fileURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: download file %s: invalid URL", fileID)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("telegram: download file %s: %w", fileID, withoutURL(err))
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("telegram: download file %s: %s", fileID, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPhotoBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("telegram: download file %s: %w", fileID, err)
+	}
+	if len(data) > maxPhotoBytes {
+		return nil, fmt.Errorf("telegram: file %s exceeds %d bytes", fileID, maxPhotoBytes)
+	}
+	return data, nil
+}
+
+// withoutURL unwraps a *url.Error to its cause, dropping the request URL.
+func withoutURL(err error) error {
+	var uerr *url.Error
+	if errors.As(err, &uerr) {
+		return uerr.Err
+	}
+	return err
+}
+
+// HandleApproval resolves update if it answers an approval request: an
+// approval button press, or a message like "approve <id>" while a request of
+// the chat is pending. It reports whether update was consumed. It must run
+// outside HandleUpdate, which blocks while a tool call awaits approval.
+func (a *Adapter) HandleApproval(update tgbotapi.Update) bool {
+	if a.approvals == nil {
+		return false
+	}
+	if cb := update.CallbackQuery; cb != nil && cb.Message != nil && strings.HasPrefix(cb.Data, approvalCallbackPrefix) {
+		decision, id, _ := strings.Cut(strings.TrimPrefix(cb.Data, approvalCallbackPrefix), ":")
+		by := ""
+		if cb.From != nil {
+			by = "telegram:" + strconv.FormatInt(cb.From.ID, 10)
+		}
+		status := "expired"
+		if d, ok := approval.ParseDecision(decision); ok && a.approvals.Resolve(ChatIDToChannelID(cb.Message.Chat.ID), id, d, by) == nil {
+			status = string(d)
+		}
+		_, _ = a.bot.Send(tgbotapi.NewEditMessageText(cb.Message.Chat.ID, cb.Message.MessageID, cb.Message.Text+"\n\n→ "+status))
+		return true
+	}
+	if m := update.Message; m != nil && m.Text != "" {
+		by := ""
+		if m.From != nil {
+			by = "telegram:" + strconv.FormatInt(m.From.ID, 10)
+		}
+		return a.approvals.ResolveReply(ChatIDToChannelID(m.Chat.ID), m.Text, by)
+	}
+	return false
+}
+
+// prompter sends approval requests to chatID with inline decision buttons.
+func (a *Adapter) prompter(chatID int64) approval.Prompter {
+	return approval.PrompterFunc(func(ctx context.Context, req approval.Request) error {
+		msg := tgbotapi.NewMessage(chatID, req.Text())
+		msg.ReplyMarkup = tgbotapi.NewInlineKeyboardMarkup(tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("Approve", approvalCallbackPrefix+string(approval.Approve)+":"+req.ID),
+			tgbotapi.NewInlineKeyboardButtonData("Deny", approvalCallbackPrefix+string(approval.Deny)+":"+req.ID),
+			tgbotapi.NewInlineKeyboardButtonData("Always (session)", approvalCallbackPrefix+string(approval.Always)+":"+req.ID),
+		))
+		if _, err := a.bot.Send(msg); err != nil {
+			return fmt.Errorf("telegram: send approval request to chat %d: %w", chatID, err)
+		}
+		return nil
+	})
+}
+
 // Start begins polling for Telegram updates and processing them.
+// Messages are answered one at a time by a worker goroutine, so approval
+// answers (see HandleApproval) are seen while a reply waits on them.
 // Blocks until ctx is canceled. When ctx is done, StopReceivingUpdates is called.
 func (a *Adapter) Start(ctx context.Context) {
 	ctx, cancel := context.WithCancel(ctx)
@@ -87,13 +289,33 @@ func (a *Adapter) Start(ctx context.Context) {
 
 	updates := a.bot.GetUpdatesChan(u)
 
+	work := make(chan tgbotapi.Update, updateQueueSize)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for update := range work {
+			a.HandleUpdate(ctx, update)
+		}
+	}()
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
 	for {
 		select {
 		case <-ctx.Done():
 			a.bot.StopReceivingUpdates()
 			return
 		case update := <-updates:
-			a.HandleUpdate(ctx, update)
+			if a.HandleApproval(update) {
+				continue
+			}
+			select {
+			case work <- update:
+			case <-ctx.Done():
+			}
 		}
 	}
 }
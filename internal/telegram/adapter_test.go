@@ -2,12 +2,19 @@ package telegram
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
 )
 
 // =============================================================================
@@ -274,6 +281,104 @@ func TestHandleUpdate_WhenRouterReturnsError_ShouldSendErrorMessage(t *testing.T
 	}
 }
 
+// contentRouter is a mockRouter that also records RouteContent calls.
+type contentRouter struct {
+	mockRouter
+	blocks [][]domain.ContentBlock
+}
+
+func (m *contentRouter) RouteContent(_ context.Context, _ string, blocks []domain.ContentBlock) (string, error) {
+	m.blocks = append(m.blocks, blocks)
+	return m.response, m.err
+}
+
+// fileURLs maps file IDs to download URLs.
+type fileURLs map[string]string
+
+func (f fileURLs) GetFileDirectURL(fileID string) (string, error) {
+	u, ok := f[fileID]
+	if !ok {
+		return "", errors.New("file not found")
+	}
+	return u, nil
+}
+
+// stubImages prepares any data beginning with "img" as a PNG.
+type stubImages struct{}
+
+func (stubImages) PrepareImage(data []byte) (domain.ImageBlock, error) {
+	if !strings.HasPrefix(string(data), "img") {
+		return domain.ImageBlock{}, errors.New("not an image")
+	}
+	return domain.NewImageBlock("image/png", data), nil
+}
+
+func makePhotoUpdate(chatID int64, caption string) tgbotapi.Update {
+	return tgbotapi.Update{Message: &tgbotapi.Message{
+		MessageID: 7,
+		Chat:      &tgbotapi.Chat{ID: chatID},
+		Caption:   caption,
+		Photo:     []tgbotapi.PhotoSize{{FileID: "small"}, {FileID: "large"}},
+	}}
+}
+
+func TestHandleUpdate_WhenPhoto_ShouldDownloadLargestSizeAndRouteWithCaption(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("img:" + r.URL.Path))
+	}))
+	defer server.Close()
+	bot := newMockBotAPI()
+	rtr := &contentRouter{mockRouter: mockRouter{response: "a cat"}}
+	files := fileURLs{"small": server.URL + "/small", "large": server.URL + "/large"}
+	adapter := NewAdapter(bot, rtr, WithPhotos(files, stubImages{}))
+
+	adapter.HandleUpdate(context.Background(), makePhotoUpdate(42, "what is it?"))
+
+	if len(rtr.blocks) != 1 || len(rtr.blocks[0]) != 2 {
+		t.Fatalf("RouteContent blocks = %+v", rtr.blocks)
+	}
+	want := domain.NewImageBlock("image/png", []byte("img:/large"))
+	if img, ok := rtr.blocks[0][0].(domain.ImageBlock); !ok || img != want {
+		t.Errorf("first block = %+v, want the large photo", rtr.blocks[0][0])
+	}
+	if tb, ok := rtr.blocks[0][1].(domain.TextBlock); !ok || tb.Text != "what is it?" {
+		t.Errorf("second block = %+v, want the caption", rtr.blocks[0][1])
+	}
+	if sent := bot.sentMessages(); len(sent) != 1 || sent[0].(tgbotapi.MessageConfig).Text != "a cat" {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
+func TestHandleUpdate_WhenPhotoDownloadFails_ShouldReplyWithoutURL(t *testing.T) {
+	bot := newMockBotAPI()
+	rtr := &contentRouter{mockRouter: mockRouter{response: "ok"}}
+	files := fileURLs{"large": "http://127.0.0.1:1/file/botSECRET-TOKEN/photo.jpg"}
+	adapter := NewAdapter(bot, rtr, WithPhotos(files, stubImages{}))
+
+	adapter.HandleUpdate(context.Background(), makePhotoUpdate(42, ""))
+
+	sent := bot.sentMessages()
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 sent message, got %d", len(sent))
+	}
+	text := sent[0].(tgbotapi.MessageConfig).Text
+	if !strings.HasPrefix(text, "Error: telegram: download file large:") || strings.Contains(text, "SECRET-TOKEN") {
+		t.Errorf("reply = %q, want a download error without the URL", text)
+	}
+}
+
+func TestHandleUpdate_WhenPhotoWithoutPhotosOption_ShouldIgnoreIt(t *testing.T) {
+	bot := newMockBotAPI()
+	rtr := &contentRouter{mockRouter: mockRouter{response: "ok"}}
+	adapter := NewAdapter(bot, rtr)
+
+	adapter.HandleUpdate(context.Background(), makePhotoUpdate(42, "caption"))
+
+	if len(rtr.getCalls()) != 0 || len(rtr.blocks) != 0 || len(bot.sentMessages()) != 0 {
+		t.Error("photo should be ignored without WithPhotos")
+	}
+}
+
 func TestHandleUpdate_WhenSendFails_ShouldNotPanic(t *testing.T) {
 	bot := newMockBotAPI()
 	bot.sendErr = errSendFailed
@@ -534,3 +639,106 @@ func TestStart_WhenUpdatesChannelClosed_ShouldReturn(t *testing.T) {
 		t.Fatal("Start did not return after updates channel closed and Stop called")
 	}
 }
+
+func TestSendText_ShouldSendMessageToChat(t *testing.T) {
+	bot := newMockBotAPI()
+	if err := SendText(bot, "-1001", "briefing"); err != nil {
+		t.Fatalf("SendText: %v", err)
+	}
+	sent := bot.sentMessages()
+	if len(sent) != 1 {
+		t.Fatalf("expected 1 message, got %d", len(sent))
+	}
+	msg := sent[0].(tgbotapi.MessageConfig)
+	if msg.ChatID != -1001 || msg.Text != "briefing" {
+		t.Errorf("unexpected message %+v", msg)
+	}
+}
+
+func TestSendText_WhenChatIDInvalid_ShouldReturnError(t *testing.T) {
+	bot := newMockBotAPI()
+	if err := SendText(bot, "@someone", "x"); err == nil {
+		t.Fatal("expected error for non-numeric chat ID")
+	}
+	if len(bot.sentMessages()) != 0 {
+		t.Error("expected nothing sent")
+	}
+}
+
+// gatedRouter asks gate to approve a shell call before replying, the way
+// brain.Run does for tool calls.
+type gatedRouter struct {
+	gate *approval.Gate
+}
+
+func (r *gatedRouter) Route(ctx context.Context, channelID, prompt string) (string, error) {
+	ch, p, _ := approval.FromContext(ctx)
+	if err := r.gate.Authorize(ctx, ch, "shell", json.RawMessage(`{"command":"uptime"}`), p); err != nil {
+		return "", err
+	}
+	return "up 3 days", nil
+}
+
+// waitSent polls until bot has sent n messages.
+func waitSent(t *testing.T, bot *mockBotAPI, n int) []tgbotapi.Chattable {
+	t.Helper()
+	deadline := time.After(2 * time.Second)
+	for len(bot.sentMessages()) < n {
+		select {
+		case <-deadline:
+			t.Fatalf("timed out waiting for %d sent messages, got %d", n, len(bot.sentMessages()))
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	return bot.sentMessages()
+}
+
+func TestStart_WhenToolNeedsApproval_ShouldPromptWithButtonsAndResumeOnCallback(t *testing.T) {
+	bot := newMockBotAPI()
+	gate := approval.NewGate()
+	adapter := NewAdapter(bot, &gatedRouter{gate: gate}, WithApprovals(gate))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go adapter.Start(ctx)
+
+	bot.updates <- makeTextUpdate(7, 1, "how long is the server up?")
+	prompt := waitSent(t, bot, 1)[0].(tgbotapi.MessageConfig)
+	keyboard, ok := prompt.ReplyMarkup.(tgbotapi.InlineKeyboardMarkup)
+	if !ok || len(keyboard.InlineKeyboard) != 1 || len(keyboard.InlineKeyboard[0]) != 3 {
+		t.Fatalf("want approval prompt with 3 buttons, got %+v", prompt.ReplyMarkup)
+	}
+	if !strings.Contains(prompt.Text, `"command": "uptime"`) {
+		t.Errorf("prompt should show the exact arguments:\n%s", prompt.Text)
+	}
+	approve := *keyboard.InlineKeyboard[0][0].CallbackData
+
+	bot.updates <- tgbotapi.Update{CallbackQuery: &tgbotapi.CallbackQuery{
+		From:    &tgbotapi.User{ID: 1},
+		Message: &tgbotapi.Message{MessageID: 5, Chat: &tgbotapi.Chat{ID: 7}, Text: prompt.Text},
+		Data:    approve,
+	}}
+	sent := waitSent(t, bot, 3)
+	var edited, replied bool
+	for _, c := range sent[1:] {
+		switch m := c.(type) {
+		case tgbotapi.EditMessageTextConfig:
+			edited = m.MessageID == 5 && strings.HasSuffix(m.Text, "approve")
+		case tgbotapi.MessageConfig:
+			replied = m.Text == "up 3 days"
+		}
+	}
+	if !edited || !replied {
+		t.Errorf("want prompt edited and reply sent, got %+v", sent)
+	}
+}
+
+func TestHandleApproval_WhenNoGateOrUnrelatedUpdate_ShouldNotConsume(t *testing.T) {
+	plain := NewAdapter(newMockBotAPI(), &mockRouter{})
+	if plain.HandleApproval(makeTextUpdate(1, 1, "approve")) {
+		t.Error("adapter without approvals should not consume updates")
+	}
+	gated := NewAdapter(newMockBotAPI(), &mockRouter{}, WithApprovals(approval.NewGate()))
+	if gated.HandleApproval(makeTextUpdate(1, 1, "hello")) {
+		t.Error("ordinary message should not be consumed")
+	}
+}
@@ -0,0 +1,97 @@
+package tokenizer
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+
+	tiktoken "github.com/pkoukk/tiktoken-go"
+)
+
+// embeddedBPE holds the BPE rank files compiled into the binary; nil unless
+// built with the tiktoken_embed tag (see embed.go).
+var embeddedBPE fs.FS
+
+// ErrBPENotFound is returned when an encoding's BPE rank file is neither
+// embedded nor in the configured directory and downloads are disabled.
+var ErrBPENotFound = errors.New("tokenizer: BPE file not available offline")
+
+func init() {
+	tiktoken.SetBpeLoader(&localLoader{fallback: tiktoken.NewDefaultBpeLoader()})
+}
+
+// UseBPEDir makes encodings load their BPE rank files (cl100k_base.tiktoken,
+// o200k_base.tiktoken) from the embedded data, then from dir, as served by
+// https://openaipublic.blob.core.windows.net/encodings/. Files found in
+// neither are downloaded, unless offline is set, in which case the encoding
+// fails with ErrBPENotFound. An empty dir is not searched. Call it before
+// the first tokenizer is created; encodings already loaded are kept.
+func UseBPEDir(dir string, offline bool) {
+	l := &localLoader{}
+	if dir != "" {
+		l.dir = os.DirFS(dir)
+	}
+	if !offline {
+		l.fallback = tiktoken.NewDefaultBpeLoader()
+	}
+	tiktoken.SetBpeLoader(l)
+}
+
+// localLoader implements tiktoken.BpeLoader, reading rank files by their
+// base name from the embedded data and dir before falling back.
+type localLoader struct {
+	dir      fs.FS              // nil: no directory configured
+	fallback tiktoken.BpeLoader // nil: offline
+}
+
+// LoadTiktokenBpe implements tiktoken.BpeLoader.
+func (l *localLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	name := path.Base(file)
+	for _, fsys := range []fs.FS{embeddedBPE, l.dir} {
+		if fsys == nil {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, name)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: read %s: %w", name, err)
+		}
+		return parseBPE(name, data)
+	}
+	if l.fallback == nil {
+		return nil, fmt.Errorf("%w: %s", ErrBPENotFound, name)
+	}
+	return l.fallback.LoadTiktokenBpe(file)
+}
+
+// parseBPE parses a tiktoken rank file: one base64 token and its rank per
+// line.
+func parseBPE(name string, data []byte) (map[string]int, error) {
+	ranks := make(map[string]int, bytes.Count(data, []byte("\n"))+1)
+	for i, line := range bytes.Split(data, []byte("\n")) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		tok, rank, ok := bytes.Cut(bytes.TrimSpace(line), []byte(" "))
+		if !ok {
+			return nil, fmt.Errorf("tokenizer: %s line %d: missing rank", name, i+1)
+		}
+		token, err := base64.StdEncoding.DecodeString(string(tok))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: %s line %d: %w", name, i+1, err)
+		}
+		n, err := strconv.Atoi(string(rank))
+		if err != nil {
+			return nil, fmt.Errorf("tokenizer: %s line %d: %w", name, i+1, err)
+		}
+		ranks[string(token)] = n
+	}
+	return ranks, nil
+}
@@ -0,0 +1,93 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestMain loads cl100k_base from testdata, a stand-in holding every byte
+// and the words the tests count, so no test downloads rank files.
+func TestMain(m *testing.M) {
+	UseBPEDir("testdata", true)
+	os.Exit(m.Run())
+}
+
+// byteRanks returns a rank file giving each single byte its own rank, with
+// no merges: every byte of a text is one token.
+func byteRanks() string {
+	var sb strings.Builder
+	for i := 0; i < 256; i++ {
+		fmt.Fprintf(&sb, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(i)}), i)
+	}
+	return sb.String()
+}
+
+// fakeLoader records the files it is asked for.
+type fakeLoader struct {
+	files []string
+}
+
+func (f *fakeLoader) LoadTiktokenBpe(file string) (map[string]int, error) {
+	f.files = append(f.files, file)
+	return map[string]int{"a": 0}, nil
+}
+
+const cl100kURL = "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken"
+
+func TestLocalLoader_WhenFileInDir_ShouldParseIt(t *testing.T) {
+	fallback := &fakeLoader{}
+	l := &localLoader{dir: fstest.MapFS{"cl100k_base.tiktoken": {Data: []byte(byteRanks())}}, fallback: fallback}
+
+	ranks, err := l.LoadTiktokenBpe(cl100kURL)
+	if err != nil {
+		t.Fatalf("LoadTiktokenBpe: %v", err)
+	}
+	if len(ranks) != 256 || ranks["A"] != 'A' || len(fallback.files) != 0 {
+		t.Errorf("got %d ranks (A=%d), %d downloads", len(ranks), ranks["A"], len(fallback.files))
+	}
+}
+
+func TestLocalLoader_WhenFileMissing_ShouldFallBackUnlessOffline(t *testing.T) {
+	fallback := &fakeLoader{}
+	l := &localLoader{dir: fstest.MapFS{}, fallback: fallback}
+	if _, err := l.LoadTiktokenBpe(cl100kURL); err != nil || len(fallback.files) != 1 || fallback.files[0] != cl100kURL {
+		t.Errorf("fallback got %v, err %v", fallback.files, err)
+	}
+
+	offline := &localLoader{dir: fstest.MapFS{}}
+	if _, err := offline.LoadTiktokenBpe(cl100kURL); !errors.Is(err, ErrBPENotFound) {
+		t.Errorf("expected ErrBPENotFound, got %v", err)
+	}
+}
+
+func TestLocalLoader_WhenFileMalformed_ShouldReturnError(t *testing.T) {
+	l := &localLoader{dir: fstest.MapFS{"cl100k_base.tiktoken": {Data: []byte("QQ== 0\nnot-base64! 1\n")}}}
+	if _, err := l.LoadTiktokenBpe(cl100kURL); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("expected error on line 2, got %v", err)
+	}
+}
+
+func TestUseBPEDir_WhenOffline_ShouldLoadEncodingFromDir(t *testing.T) {
+	// Loads a byte-level stand-in for o200k_base, which tiktoken-go then
+	// keeps for this test binary; no other test uses o200k_base.
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "o200k_base.tiktoken"), []byte(byteRanks()), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	UseBPEDir(dir, true)
+	defer UseBPEDir("testdata", true)
+
+	tok, err := NewTikToken(EncodingO200K)
+	if err != nil {
+		t.Fatalf("NewTikToken: %v", err)
+	}
+	if n, err := tok.CountTokens("hello"); err != nil || n != 5 {
+		t.Errorf("CountTokens = %d, %v, want 5 byte tokens", n, err)
+	}
+}
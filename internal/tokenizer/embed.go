@@ -0,0 +1,24 @@
+//go:build tiktoken_embed
+
+package tokenizer
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// bpeFiles embeds the BPE rank files for air-gapped builds. Put
+// cl100k_base.tiktoken and o200k_base.tiktoken, from
+// https://openaipublic.blob.core.windows.net/encodings/, in
+// internal/tokenizer/bpe/ and build with -tags tiktoken_embed.
+//
+//go:embed bpe/*.tiktoken
+var bpeFiles embed.FS
+
+func init() {
+	sub, err := fs.Sub(bpeFiles, "bpe")
+	if err != nil {
+		panic(err)
+	}
+	embeddedBPE = sub
+}
@@ -0,0 +1,177 @@
+package tokenizer
+
+import (
+	"math"
+	"strings"
+	"sync"
+
+	"ironclaw/internal/domain"
+)
+
+// Encodings of tiktoken.
+const (
+	EncodingCL100K = "cl100k_base"
+	EncodingO200K  = "o200k_base"
+)
+
+// ModelInfo describes how a model's tokens are counted and how many it
+// accepts.
+type ModelInfo struct {
+	Encoding      string  // Encoding counting the model's tokens exactly; "" when they are estimated
+	Factor        float64 // Estimated models: approximate ratio of their token counts to cl100k_base's
+	ContextWindow int     // Input tokens the model accepts; 0 when unknown
+}
+
+// Exact reports whether the model's tokens are counted exactly.
+func (m ModelInfo) Exact() bool { return m.Encoding != "" }
+
+// models maps model name prefixes to their info. Lookup picks the longest
+// matching prefix. OpenAI models are counted exactly; the others are
+// estimated from cl100k_base with ratios measured on English prose and code.
+var models = map[string]ModelInfo{
+	// OpenAI
+	"gpt-5":         {Encoding: EncodingO200K, ContextWindow: 400000},
+	"gpt-4.5":       {Encoding: EncodingO200K, ContextWindow: 128000},
+	"gpt-4.1":       {Encoding: EncodingO200K, ContextWindow: 1047576},
+	"gpt-4o":        {Encoding: EncodingO200K, ContextWindow: 128000},
+	"chatgpt-4o":    {Encoding: EncodingO200K, ContextWindow: 128000},
+	"o1":            {Encoding: EncodingO200K, ContextWindow: 200000},
+	"o1-mini":       {Encoding: EncodingO200K, ContextWindow: 128000},
+	"o3":            {Encoding: EncodingO200K, ContextWindow: 200000},
+	"o4-mini":       {Encoding: EncodingO200K, ContextWindow: 200000},
+	"gpt-4-turbo":   {Encoding: EncodingCL100K, ContextWindow: 128000},
+	"gpt-4-32k":     {Encoding: EncodingCL100K, ContextWindow: 32768},
+	"gpt-4":         {Encoding: EncodingCL100K, ContextWindow: 8192},
+	"gpt-3.5-turbo": {Encoding: EncodingCL100K, ContextWindow: 16385},
+
+	// Anthropic
+	"claude": {Factor: 1.2, ContextWindow: 200000},
+
+	// Google
+	"gemini":         {Factor: 1.05, ContextWindow: 1048576},
+	"gemini-1.5-pro": {Factor: 1.05, ContextWindow: 2097152},
+	"gemini-pro":     {Factor: 1.05, ContextWindow: 32760},
+	"gemini-1.0-pro": {Factor: 1.05, ContextWindow: 32760},
+
+	// Meta
+	"llama3":    {Factor: 1.0, ContextWindow: 8192},
+	"llama-3":   {Factor: 1.0, ContextWindow: 8192},
+	"llama3.1":  {Factor: 1.0, ContextWindow: 131072},
+	"llama-3.1": {Factor: 1.0, ContextWindow: 131072},
+	"llama3.2":  {Factor: 1.0, ContextWindow: 131072},
+	"llama-3.2": {Factor: 1.0, ContextWindow: 131072},
+	"llama3.3":  {Factor: 1.0, ContextWindow: 131072},
+	"llama-3.3": {Factor: 1.0, ContextWindow: 131072},
+	"llama2":    {Factor: 1.3, ContextWindow: 4096},
+	"llama-2":   {Factor: 1.3, ContextWindow: 4096},
+}
+
+// Lookup returns the info of model, matched by its longest known prefix
+// case-insensitively, ignoring a "vendor/" prefix (as in OpenRouter model
+// names). Reports false for unknown models.
+func Lookup(model string) (ModelInfo, bool) {
+	model = strings.ToLower(strings.TrimSpace(model))
+	if i := strings.LastIndex(model, "/"); i >= 0 {
+		model = model[i+1:]
+	}
+	best, found := "", false
+	for prefix := range models {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			best, found = prefix, true
+		}
+	}
+	return models[best], found
+}
+
+// newTikTokenFn loads an encoding; tests replace it.
+var newTikTokenFn = func(encoding string) (domain.Tokenizer, error) {
+	return NewTikToken(encoding)
+}
+
+// loaded caches the encodings loaded by ForModel, which are costly to build.
+var loaded struct {
+	sync.Mutex
+	encodings map[string]domain.Tokenizer
+}
+
+// encoding returns the tokenizer of encoding, loading it once.
+func encoding(name string) (domain.Tokenizer, error) {
+	loaded.Lock()
+	defer loaded.Unlock()
+	if tok, ok := loaded.encodings[name]; ok {
+		return tok, nil
+	}
+	tok, err := newTikTokenFn(name)
+	if err != nil {
+		return nil, err
+	}
+	if loaded.encodings == nil {
+		loaded.encodings = make(map[string]domain.Tokenizer)
+	}
+	loaded.encodings[name] = tok
+	return tok, nil
+}
+
+// ForModel returns a tokenizer for model with its info. Models with an
+// encoding are counted with it; the others, and models whose encoding
+// cannot be loaded, get an Estimator over cl100k_base, or over their
+// length when cl100k_base cannot be loaded either. Unknown models are
+// estimated as cl100k_base counts them.
+func ForModel(model string) (domain.Tokenizer, ModelInfo) {
+	info, ok := Lookup(model)
+	if !ok {
+		info = ModelInfo{Factor: 1}
+	}
+	if info.Exact() {
+		if tok, err := encoding(info.Encoding); err == nil {
+			return tok, info
+		}
+		info.Encoding, info.Factor = "", 1
+	}
+	base, err := encoding(EncodingCL100K)
+	if err != nil {
+		base = nil
+	}
+	return NewEstimator(base, info.Factor), info
+}
+
+// bytesPerToken is the estimate of UTF-8 bytes per cl100k_base token used
+// without a base tokenizer.
+const bytesPerToken = 4
+
+// Estimator approximates a model's token counts: a base tokenizer's count,
+// or len(text)/4 without one, scaled by a calibration factor.
+type Estimator struct {
+	base   domain.Tokenizer // nil: estimate from length
+	factor float64
+}
+
+// NewEstimator creates an Estimator scaling base's counts by factor. base
+// may be nil; a factor <= 0 is taken as 1.
+func NewEstimator(base domain.Tokenizer, factor float64) *Estimator {
+	if factor <= 0 {
+		factor = 1
+	}
+	return &Estimator{base: base, factor: factor}
+}
+
+// CountTokens implements domain.Tokenizer.
+func (e *Estimator) CountTokens(text string) (int, error) {
+	if text == "" {
+		return 0, nil
+	}
+	n := (len(text) + bytesPerToken - 1) / bytesPerToken
+	if e.base != nil {
+		var err error
+		if n, err = e.base.CountTokens(text); err != nil {
+			return 0, err
+		}
+	}
+	return int(math.Ceil(float64(n) * e.factor)), nil
+}
+
+// Compile-time checks that the tokenizers implement domain.Tokenizer.
+var (
+	_ domain.Tokenizer = (*TikToken)(nil)
+	_ domain.Tokenizer = (*Estimator)(nil)
+)
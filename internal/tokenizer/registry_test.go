@@ -0,0 +1,109 @@
+package tokenizer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+// wordTokenizer counts space-separated words.
+type wordTokenizer struct{ name string }
+
+func (w wordTokenizer) CountTokens(text string) (int, error) {
+	return len(strings.Fields(text)), nil
+}
+
+// stubEncodings makes ForModel load encodings with load, starting from an
+// empty cache.
+func stubEncodings(t *testing.T, load func(string) (domain.Tokenizer, error)) {
+	t.Helper()
+	old := newTikTokenFn
+	newTikTokenFn = load
+	loaded.encodings = nil
+	t.Cleanup(func() {
+		newTikTokenFn = old
+		loaded.encodings = nil
+	})
+}
+
+func TestLookup_ShouldMatchLongestPrefix(t *testing.T) {
+	tests := []struct {
+		model    string
+		encoding string
+		window   int
+	}{
+		{"gpt-4o-mini", EncodingO200K, 128000},
+		{"openai/gpt-4-turbo-preview", EncodingCL100K, 128000},
+		{"gpt-4-0613", EncodingCL100K, 8192},
+		{"anthropic/claude-3.5-sonnet", "", 200000},
+		{"llama3.1:8b", "", 131072},
+		{"llama3:8b", "", 8192},
+		{"Gemini-1.5-Pro-latest", "", 2097152},
+	}
+	for _, tt := range tests {
+		info, ok := Lookup(tt.model)
+		if !ok || info.Encoding != tt.encoding || info.ContextWindow != tt.window {
+			t.Errorf("Lookup(%q) = %+v, %v; want %q, %d", tt.model, info, ok, tt.encoding, tt.window)
+		}
+	}
+	if _, ok := Lookup("mystery-model"); ok {
+		t.Error("unknown model should not be found")
+	}
+}
+
+func TestForModel_WhenOpenAIModel_ShouldCountExactly(t *testing.T) {
+	stubEncodings(t, func(name string) (domain.Tokenizer, error) { return wordTokenizer{name}, nil })
+
+	tok, info := ForModel("gpt-4o")
+	if w, ok := tok.(wordTokenizer); !ok || w.name != EncodingO200K || !info.Exact() {
+		t.Errorf("ForModel(gpt-4o) = %#v, %+v; want the o200k_base encoding", tok, info)
+	}
+}
+
+func TestForModel_WhenEstimatedModel_ShouldScaleCL100K(t *testing.T) {
+	var names []string
+	stubEncodings(t, func(name string) (domain.Tokenizer, error) {
+		names = append(names, name)
+		return wordTokenizer{name}, nil
+	})
+
+	tok, info := ForModel("claude-sonnet-4")
+	if info.Exact() || info.ContextWindow != 200000 {
+		t.Errorf("info = %+v", info)
+	}
+	if n, _ := tok.CountTokens("one two three four five"); n != 6 {
+		t.Errorf("CountTokens = %d, want 5 words scaled by 1.2", n)
+	}
+	ForModel("claude-opus-4")
+	if len(names) != 1 || names[0] != EncodingCL100K {
+		t.Errorf("loaded %v, want cl100k_base once", names)
+	}
+}
+
+func TestForModel_WhenEncodingUnavailable_ShouldEstimateFromLength(t *testing.T) {
+	stubEncodings(t, func(string) (domain.Tokenizer, error) { return nil, ErrBPENotFound })
+
+	tok, info := ForModel("gpt-4o")
+	if info.Exact() || info.ContextWindow != 128000 {
+		t.Errorf("info = %+v, want an estimate keeping the window", info)
+	}
+	if n, _ := tok.CountTokens("abcdefghi"); n != 3 {
+		t.Errorf("CountTokens = %d, want 9 bytes / 4 rounded up", n)
+	}
+}
+
+func TestEstimator_WhenBaseFails_ShouldReturnError(t *testing.T) {
+	e := NewEstimator(failingTokenizer{}, 1.2)
+	if _, err := e.CountTokens("hi"); err == nil {
+		t.Error("expected error")
+	}
+	if n, _ := e.CountTokens(""); n != 0 {
+		t.Errorf("empty text = %d tokens", n)
+	}
+}
+
+type failingTokenizer struct{}
+
+func (failingTokenizer) CountTokens(string) (int, error) { return 0, errors.New("boom") }
@@ -1,7 +1,9 @@
 package tooling
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 	"image/color"
@@ -254,6 +256,51 @@ func (r *RealImageProcessor) ConvertToGrayscale(inputPath, outputPath string) er
 	return nil
 }
 
+// MaxImageSide is the longest side, in pixels, of images PrepareImage passes
+// to models; larger images are downscaled to fit. Providers resize larger
+// images themselves, at the cost of latency and tokens.
+const MaxImageSide = 1568
+
+// ErrNotImage is returned by PrepareImage for data that is not a JPEG, PNG,
+// GIF or WebP image.
+var ErrNotImage = errors.New("tooling: not a supported image")
+
+// PrepareImage checks that data is an image models accept and returns it as
+// a domain.ImageBlock. Images whose longest side exceeds MaxImageSide are
+// downscaled to fit and re-encoded (PNG stays PNG, others become JPEG);
+// smaller ones are passed through unchanged.
+func (r *RealImageProcessor) PrepareImage(data []byte) (domain.ImageBlock, error) {
+	kind, err := filetypeMatchFunc(data)
+	if err != nil {
+		return domain.ImageBlock{}, fmt.Errorf("filetype match error: %w", err)
+	}
+	switch kind.MIME.Value {
+	case "image/jpeg", "image/png", "image/gif", "image/webp":
+	default:
+		return domain.ImageBlock{}, fmt.Errorf("%w (detected %q)", ErrNotImage, kind.MIME.Value)
+	}
+	mediaType := kind.MIME.Value
+	if cfg, _, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && max(cfg.Width, cfg.Height) > MaxImageSide {
+		src, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+		if err != nil {
+			return domain.ImageBlock{}, fmt.Errorf("cannot decode image: %w", err)
+		}
+		format := imaging.JPEG
+		if mediaType == "image/png" {
+			format = imaging.PNG
+		}
+		var buf bytes.Buffer
+		if err := imaging.Encode(&buf, imaging.Fit(src, MaxImageSide, MaxImageSide, imaging.Lanczos), format); err != nil {
+			return domain.ImageBlock{}, fmt.Errorf("cannot encode image: %w", err)
+		}
+		data = buf.Bytes()
+		if format == imaging.JPEG {
+			mediaType = "image/jpeg"
+		}
+	}
+	return domain.NewImageBlock(mediaType, data), nil
+}
+
 // createTestPNG creates a minimal valid PNG image at the given path.
 // Exported for use in integration tests.
 func createTestPNG(path string, width, height int) error {
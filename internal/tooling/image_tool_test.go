@@ -1,12 +1,18 @@
 package tooling
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"image"
+	"image/color"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/disintegration/imaging"
 	ftypes "github.com/h2non/filetype/types"
 )
 
@@ -917,6 +923,56 @@ func TestImageTool_E2E_Grayscale_ShouldConvertRealImage(t *testing.T) {
 // Compile-time interface checks
 // =============================================================================
 
+// =============================================================================
+// RealImageProcessor — PrepareImage
+// =============================================================================
+
+func encodeTestImage(t *testing.T, width, height int, format imaging.Format) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	img := imaging.New(width, height, color.NRGBA{R: 0, G: 128, B: 255, A: 255})
+	if err := imaging.Encode(&buf, img, format); err != nil {
+		t.Fatalf("encode: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestRealImageProcessor_PrepareImage_WhenSmall_ShouldPassThrough(t *testing.T) {
+	data := encodeTestImage(t, 40, 30, imaging.JPEG)
+
+	block, err := (&RealImageProcessor{}).PrepareImage(data)
+	if err != nil {
+		t.Fatalf("PrepareImage: %v", err)
+	}
+	if block.Source.Type != "base64" || block.Source.MediaType != "image/jpeg" || block.Source.Data != base64.StdEncoding.EncodeToString(data) {
+		t.Errorf("block = %+v, want the original JPEG", block.Source.MediaType)
+	}
+}
+
+func TestRealImageProcessor_PrepareImage_WhenLarge_ShouldDownscaleToMaxSide(t *testing.T) {
+	data := encodeTestImage(t, 3*MaxImageSide, MaxImageSide, imaging.PNG)
+
+	block, err := (&RealImageProcessor{}).PrepareImage(data)
+	if err != nil {
+		t.Fatalf("PrepareImage: %v", err)
+	}
+	raw, _ := base64.StdEncoding.DecodeString(block.Source.Data)
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("decode prepared image: %v", err)
+	}
+	if block.Source.MediaType != "image/png" || format != "png" || cfg.Width != MaxImageSide || cfg.Height != MaxImageSide/3 {
+		t.Errorf("prepared %s %dx%d (%s), want png %dx%d", format, cfg.Width, cfg.Height, block.Source.MediaType, MaxImageSide, MaxImageSide/3)
+	}
+}
+
+func TestRealImageProcessor_PrepareImage_WhenNotImage_ShouldReturnErrNotImage(t *testing.T) {
+	_, err := (&RealImageProcessor{}).PrepareImage([]byte("%PDF-1.7 not an image"))
+	if !errors.Is(err, ErrNotImage) {
+		t.Errorf("err = %v, want ErrNotImage", err)
+	}
+}
+
 var _ SchemaTool = (*ImageTool)(nil)
 var _ ImageProcessor = (*mockImageProcessor)(nil)
 var _ ImageProcessor = (*spyImageProcessor)(nil)
@@ -0,0 +1,185 @@
+package tooling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// ErrToolDenied matches every *DeniedError via errors.Is.
+var ErrToolDenied = errors.New("tool denied by policy")
+
+// DeniedError reports a tool call refused by a Policy layer.
+type DeniedError struct {
+	Tool  string // Requested tool name
+	Layer string // Name of the refusing layer, e.g. "agent ops" or "external channel"
+}
+
+func (e *DeniedError) Error() string {
+	return fmt.Sprintf("tool %q is not permitted by the %s policy", e.Tool, e.Layer)
+}
+
+// Is makes errors.Is(err, ErrToolDenied) true for a *DeniedError.
+func (e *DeniedError) Is(target error) bool { return target == ErrToolDenied }
+
+// DefaultExternalRule applies to external channels when ToolsConfig.External is
+// unset: tools that run commands, touch the host filesystem, install code,
+// control devices or spawn sub-agents are denied.
+var DefaultExternalRule = domain.ToolRule{
+	Deny: []string{"shell", "docker_sandbox", "filesystem", "git", "install_skill", "generate_skill", "iot", "spawn_agent"},
+}
+
+type policyLayer struct {
+	name string
+	rule domain.ToolRule
+}
+
+// Policy is a stack of named allow/deny rules. A tool is allowed only when
+// every layer allows it. The zero Policy allows everything.
+type Policy struct {
+	layers []policyLayer
+}
+
+// With returns a copy of p with rule added as a layer named name.
+func (p Policy) With(name string, rule domain.ToolRule) Policy {
+	layers := make([]policyLayer, len(p.layers), len(p.layers)+1)
+	copy(layers, p.layers)
+	return Policy{layers: append(layers, policyLayer{name: name, rule: rule})}
+}
+
+// Merge returns a policy holding the layers of p followed by those of other.
+func (p Policy) Merge(other Policy) Policy {
+	for _, l := range other.layers {
+		p = p.With(l.name, l.rule)
+	}
+	return p
+}
+
+// Check returns a *DeniedError naming the first layer that refuses to list
+// tool, or nil. Patterns are matched against the bare name: a tool is listed
+// when an allow pattern matches it or one of its operations ("git.*" lists
+// git), and hidden only when a deny pattern matches its name. Calls are
+// checked per operation by CheckCall.
+func (p Policy) Check(tool string) error {
+	for _, l := range p.layers {
+		if matchAny(l.rule.Deny, tool) || len(l.rule.Allow) > 0 && !allowsSome(l.rule.Allow, tool) {
+			return &DeniedError{Tool: tool, Layer: l.name}
+		}
+	}
+	return nil
+}
+
+// Allows reports whether every layer lets tool be listed; see Check.
+func (p Policy) Allows(tool string) bool {
+	return p.Check(tool) == nil
+}
+
+// CheckCall returns a *DeniedError naming the first layer that refuses a call
+// of tool with args, or nil. Patterns match the tool name or
+// "tool.operation", the operation being the call's "operation" or "action"
+// argument, so "git.*" allows or denies every git operation.
+func (p Policy) CheckCall(tool string, args json.RawMessage) error {
+	name := CallName(tool, args)
+	for _, l := range p.layers {
+		denied := matchAny(l.rule.Deny, tool) || matchAny(l.rule.Deny, name)
+		allowed := len(l.rule.Allow) == 0 || matchAny(l.rule.Allow, tool) || matchAny(l.rule.Allow, name)
+		if denied || !allowed {
+			return &DeniedError{Tool: name, Layer: l.name}
+		}
+	}
+	return nil
+}
+
+// CallName returns "tool.operation" for a call whose args carry an
+// "operation" (or else "action") string, and tool otherwise.
+func CallName(tool string, args json.RawMessage) string {
+	var v struct {
+		Operation string `json:"operation"`
+		Action    string `json:"action"`
+	}
+	if json.Unmarshal(args, &v) != nil {
+		return tool
+	}
+	if v.Operation != "" {
+		return tool + "." + v.Operation
+	}
+	if v.Action != "" {
+		return tool + "." + v.Action
+	}
+	return tool
+}
+
+// allowsSome reports whether a pattern matches tool or could match one of
+// its operations.
+func allowsSome(patterns []string, tool string) bool {
+	for _, p := range patterns {
+		if MatchTool(p, tool) || strings.HasPrefix(p, tool+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchTool reports whether name matches the glob pattern (path.Match syntax,
+// so "git.*" matches "git.commit"). Malformed patterns match nothing.
+func MatchTool(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if MatchTool(p, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// ChannelPolicy builds the policy for channelID from config: the channel's rule
+// (exact ID, else the longest matching glob) and, for channels of an external
+// platform listed in auth.ExternalChannels, the external rule.
+func ChannelPolicy(cfg domain.ToolsConfig, auth domain.AuthConfig, channelID string) Policy {
+	var p Policy
+	if pattern, ok := channelRule(cfg.Channels, channelID); ok {
+		p = p.With("channel "+pattern, cfg.Channels[pattern])
+	}
+	if IsExternalChannel(auth, channelID) {
+		rule := DefaultExternalRule
+		if cfg.External != nil {
+			rule = *cfg.External
+		}
+		p = p.With("external channel", rule)
+	}
+	return p
+}
+
+// channelRule returns the key of rules that applies to channelID.
+func channelRule(rules map[string]domain.ToolRule, channelID string) (string, bool) {
+	if _, ok := rules[channelID]; ok {
+		return channelID, true
+	}
+	best := ""
+	for pattern := range rules {
+		if MatchTool(pattern, channelID) && (len(pattern) > len(best) || len(pattern) == len(best) && pattern < best) {
+			best = pattern
+		}
+	}
+	return best, best != ""
+}
+
+// IsExternalChannel reports whether channelID belongs to a platform listed in
+// auth.ExternalChannels: it equals the platform name or starts with "<name>-",
+// the prefix the adapters use (e.g. "whatsapp-<jid>", "telegram-<chat>").
+func IsExternalChannel(auth domain.AuthConfig, channelID string) bool {
+	for _, platform := range auth.ExternalChannels {
+		if platform != "" && (channelID == platform || strings.HasPrefix(channelID, platform+"-")) {
+			return true
+		}
+	}
+	return false
+}
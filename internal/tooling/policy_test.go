@@ -0,0 +1,161 @@
+package tooling
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+func TestPolicy_ZeroValue_ShouldAllowEverything(t *testing.T) {
+	var p Policy
+	if !p.Allows("shell") || p.Check("anything") != nil {
+		t.Error("zero policy should allow every tool")
+	}
+}
+
+func TestPolicy_Check_ShouldRequireEveryLayerAndPreferDeny(t *testing.T) {
+	git, fs := (&GitTool{}).Name(), (&FileSystemTool{}).Name()
+	p := Policy{}.
+		With("agent ops", domain.ToolRule{Allow: []string{git + ".*", "shell", "web"}}).
+		With("channel telegram-*", domain.ToolRule{Deny: []string{"shell"}})
+
+	cases := map[string]string{
+		git:     "",
+		"web":   "",
+		"shell": "channel telegram-*",
+		fs:      "agent ops",
+	}
+	for tool, layer := range cases {
+		err := p.Check(tool)
+		if layer == "" {
+			if err != nil {
+				t.Errorf("%s: expected allowed, got %v", tool, err)
+			}
+			continue
+		}
+		var denied *DeniedError
+		if !errors.As(err, &denied) || denied.Tool != tool || denied.Layer != layer {
+			t.Errorf("%s: want denial by %q, got %v", tool, layer, err)
+		}
+		if !errors.Is(err, ErrToolDenied) {
+			t.Errorf("%s: error should match ErrToolDenied", tool)
+		}
+	}
+}
+
+func TestPolicy_CheckCall_ShouldMatchToolAndOperation(t *testing.T) {
+	git, fs := (&GitTool{}).Name(), (&FileSystemTool{}).Name()
+	p := Policy{}.
+		With("agent ops", domain.ToolRule{Allow: []string{git + ".*", fs}}).
+		With("channel telegram-*", domain.ToolRule{Deny: []string{git + ".push", fs + ".write_file"}})
+
+	cases := []struct {
+		tool, args, denied, layer string
+	}{
+		{git, `{"operation":"status"}`, "", ""},
+		{git, `{"operation":"push"}`, "git.push", "channel telegram-*"},
+		{git, `{}`, "git", "agent ops"},
+		{fs, `{"operation":"read_file","path":"a"}`, "", ""},
+		{fs, `{"operation":"write_file","path":"a"}`, "filesystem.write_file", "channel telegram-*"},
+		{"shell", `{"command":"ls"}`, "shell", "agent ops"},
+	}
+	for _, tc := range cases {
+		err := p.CheckCall(tc.tool, json.RawMessage(tc.args))
+		if tc.denied == "" {
+			if err != nil {
+				t.Errorf("%s %s: expected allowed, got %v", tc.tool, tc.args, err)
+			}
+			continue
+		}
+		var denied *DeniedError
+		if !errors.As(err, &denied) || denied.Tool != tc.denied || denied.Layer != tc.layer {
+			t.Errorf("%s %s: want %s denied by %q, got %v", tc.tool, tc.args, tc.denied, tc.layer, err)
+		}
+	}
+
+	denyAll := Policy{}.With("channel", domain.ToolRule{Deny: []string{git + ".*"}})
+	if err := denyAll.CheckCall(git, json.RawMessage(`{"operation":"commit"}`)); !errors.Is(err, ErrToolDenied) {
+		t.Errorf("git.* deny should refuse git commit, got %v", err)
+	}
+}
+
+func TestCallName_ShouldAppendOperationOrAction(t *testing.T) {
+	for args, want := range map[string]string{
+		`{"operation":"clone"}`: "git.clone",
+		`{"action":"on"}`:       "git.on",
+		`{"url":"x"}`:           "git",
+		`not json`:              "git",
+	} {
+		if got := CallName("git", json.RawMessage(args)); got != want {
+			t.Errorf("%s: got %q, want %q", args, got, want)
+		}
+	}
+}
+
+func TestPolicy_With_ShouldNotModifyReceiver(t *testing.T) {
+	base := Policy{}.With("a", domain.ToolRule{Deny: []string{"x"}})
+	_ = base.With("b", domain.ToolRule{Deny: []string{"y"}})
+	if !base.Allows("y") {
+		t.Error("With must return a copy")
+	}
+}
+
+func TestChannelPolicy_ShouldPickMostSpecificRuleAndAddExternalTier(t *testing.T) {
+	cfg := domain.ToolsConfig{Channels: map[string]domain.ToolRule{
+		"telegram-42": {Allow: []string{"web"}},
+		"telegram-*":  {Deny: []string{"web"}},
+		"*":           {Deny: []string{"iot"}},
+	}}
+	auth := domain.AuthConfig{ExternalChannels: []string{"whatsapp"}}
+
+	if p := ChannelPolicy(cfg, auth, "telegram-42"); !p.Allows("web") || p.Allows("iot") {
+		t.Error("exact channel rule should win")
+	}
+	if p := ChannelPolicy(cfg, auth, "telegram-7"); p.Allows("web") || !p.Allows("iot") {
+		t.Error("longest glob should win over *")
+	}
+	p := ChannelPolicy(cfg, auth, "whatsapp-123@s.whatsapp.net")
+	for _, tool := range []string{"shell", "docker_sandbox", "iot"} {
+		if p.Allows(tool) {
+			t.Errorf("external channel should deny %s", tool)
+		}
+	}
+	if !p.Allows("web") {
+		t.Error("external channel should still allow web")
+	}
+
+	cfg.External = &domain.ToolRule{Allow: []string{"web"}}
+	if p := ChannelPolicy(cfg, auth, "whatsapp"); p.Allows("calculator") || !p.Allows("web") {
+		t.Error("configured external rule should replace the default")
+	}
+}
+
+func TestChannelPolicy_WhenExternalRuleUnset_ShouldDenyHostDeviceAndSubAgentTools(t *testing.T) {
+	p := ChannelPolicy(domain.ToolsConfig{}, domain.AuthConfig{ExternalChannels: []string{"whatsapp"}}, "whatsapp-1")
+	for _, tool := range []string{"shell", "filesystem", "git", "iot", "spawn_agent"} {
+		if p.Allows(tool) {
+			t.Errorf("default external rule should deny %s", tool)
+		}
+	}
+	if !p.Allows("scrape") {
+		t.Error("default external rule should allow scrape")
+	}
+}
+
+func TestIsExternalChannel_ShouldMatchPlatformNameAndPrefix(t *testing.T) {
+	auth := domain.AuthConfig{ExternalChannels: []string{"whatsapp", "telegram"}}
+	for id, want := range map[string]bool{
+		"whatsapp":     true,
+		"whatsapp-jid": true,
+		"telegram-42":  true,
+		"whatsappish":  false,
+		"ws-1":         false,
+		"general":      false,
+	} {
+		if got := IsExternalChannel(auth, id); got != want {
+			t.Errorf("%s: want %v, got %v", id, want, got)
+		}
+	}
+}
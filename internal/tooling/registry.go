@@ -41,6 +41,17 @@ func (r *ToolRegistry) Get(name string) (SchemaTool, error) {
 	return tool, nil
 }
 
+// Filter returns a new registry holding the tools whose name keep accepts.
+func (r *ToolRegistry) Filter(keep func(name string) bool) *ToolRegistry {
+	out := NewToolRegistry()
+	for name, t := range r.tools {
+		if keep(name) {
+			out.tools[name] = t
+		}
+	}
+	return out
+}
+
 // List returns all registered tools (order is non-deterministic).
 func (r *ToolRegistry) List() []SchemaTool {
 	out := make([]SchemaTool, 0, len(r.tools))
@@ -163,3 +163,20 @@ func TestToolRegistry_Definitions_ShouldReturnEmptyForEmptyRegistry(t *testing.T
 		t.Errorf("Expected 0 definitions, got %d", len(defs))
 	}
 }
+
+func TestToolRegistry_Filter_ShouldKeepOnlyAcceptedTools(t *testing.T) {
+	reg := NewToolRegistry()
+	_ = reg.Register(newStub("shell", "run"))
+	_ = reg.Register(newStub("web", "fetch"))
+
+	sub := reg.Filter(func(name string) bool { return name == "web" })
+	if len(sub.List()) != 1 {
+		t.Fatalf("expected 1 tool, got %d", len(sub.List()))
+	}
+	if _, err := sub.Get("shell"); err == nil {
+		t.Error("expected shell filtered out")
+	}
+	if len(reg.List()) != 2 {
+		t.Error("Filter must not modify the original registry")
+	}
+}
@@ -0,0 +1,67 @@
+package tooling
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// RememberInput is the JSON Schema input for the remember tool.
+type RememberInput struct {
+	Content string `json:"content" jsonschema:"description=The fact or note to keep in long-term memory (e.g. 'The user prefers metric units')"`
+}
+
+// RememberTool lets the agent store a fact in its long-term memory, so later
+// conversations load or retrieve it.
+type RememberTool struct {
+	memory domain.Rememberer
+}
+
+// NewRememberTool creates a RememberTool storing memories in memory. Panics if
+// memory is nil.
+func NewRememberTool(memory domain.Rememberer) *RememberTool {
+	if memory == nil {
+		panic("remember_tool: memory must not be nil")
+	}
+	return &RememberTool{memory: memory}
+}
+
+// Name returns the tool name used in function-calling.
+func (r *RememberTool) Name() string { return "remember" }
+
+// Description returns a human-readable description for the LLM.
+func (r *RememberTool) Description() string {
+	return "Stores a fact or note in long-term memory so it is available in later conversations."
+}
+
+// Definition returns the JSON Schema string for the tool's input struct.
+func (r *RememberTool) Definition() string {
+	return GenerateSchema(RememberInput{})
+}
+
+// Call executes the remember tool without a caller context; see CallContext.
+func (r *RememberTool) Call(args json.RawMessage) (*domain.ToolResult, error) {
+	return r.CallContext(context.Background(), args)
+}
+
+// CallContext executes the remember tool: validates input and stores the
+// content under ctx.
+func (r *RememberTool) CallContext(ctx context.Context, args json.RawMessage) (*domain.ToolResult, error) {
+	if err := ValidateAgainstSchema(args, r.Definition()); err != nil {
+		return nil, fmt.Errorf("remember input validation failed: %w", err)
+	}
+	var input RememberInput
+	if err := json.Unmarshal(args, &input); err != nil {
+		return nil, fmt.Errorf("remember: failed to parse input: %w", err)
+	}
+	if strings.TrimSpace(input.Content) == "" {
+		return nil, fmt.Errorf("remember: content must not be empty")
+	}
+	if err := r.memory.Remember(ctx, input.Content); err != nil {
+		return nil, fmt.Errorf("remember: %w", err)
+	}
+	return &domain.ToolResult{Data: "Remembered."}, nil
+}
@@ -0,0 +1,63 @@
+package tooling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// mockRememberer records the memories it is asked to store.
+type mockRememberer struct {
+	stored []string
+	err    error
+}
+
+func (m *mockRememberer) Remember(_ context.Context, content string) error {
+	m.stored = append(m.stored, content)
+	return m.err
+}
+
+func TestNewRememberTool_WhenMemoryIsNil_ShouldPanic(t *testing.T) {
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("NewRememberTool(nil) should panic")
+		}
+	}()
+	NewRememberTool(nil)
+}
+
+func TestRememberTool_CallContext_ShouldStoreContent(t *testing.T) {
+	mem := &mockRememberer{}
+	tool := NewRememberTool(mem)
+
+	res, err := tool.CallContext(context.Background(), json.RawMessage(`{"content":"Prefers metric units"}`))
+	if err != nil {
+		t.Fatalf("CallContext: %v", err)
+	}
+	if len(mem.stored) != 1 || mem.stored[0] != "Prefers metric units" || res.Data == "" {
+		t.Errorf("stored %v, result %+v", mem.stored, res)
+	}
+}
+
+func TestRememberTool_CallContext_WhenInputInvalid_ShouldNotStore(t *testing.T) {
+	mem := &mockRememberer{}
+	tool := NewRememberTool(mem)
+
+	for _, args := range []string{`{}`, `{"content":"  "}`, `{"content":1}`} {
+		if _, err := tool.CallContext(context.Background(), json.RawMessage(args)); err == nil {
+			t.Errorf("%s: expected error", args)
+		}
+	}
+	if len(mem.stored) != 0 {
+		t.Errorf("stored %v, want nothing", mem.stored)
+	}
+}
+
+func TestRememberTool_CallContext_WhenMemoryFails_ShouldReturnError(t *testing.T) {
+	tool := NewRememberTool(&mockRememberer{err: errors.New("disk full")})
+
+	if _, err := tool.Call(json.RawMessage(`{"content":"x"}`)); err == nil {
+		t.Error("expected error")
+	}
+}
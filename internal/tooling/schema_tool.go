@@ -2,7 +2,9 @@ package tooling
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 
 	invopopSchema "github.com/invopop/jsonschema"
 	"github.com/santhosh-tekuri/jsonschema/v5"
@@ -43,11 +45,15 @@ func GenerateSchema(input interface{}) string {
 	return string(schemaBytes)
 }
 
+// ErrInvalidSchema is returned by ValidateAgainstSchema when the schema itself
+// does not compile, as opposed to the input failing validation.
+var ErrInvalidSchema = errors.New("invalid schema")
+
 // ValidateAgainstSchema validates JSON input against a JSON Schema string.
 func ValidateAgainstSchema(input json.RawMessage, schemaStr string) error {
 	schema, err := jsonschema.CompileString("", schemaStr)
 	if err != nil {
-		return fmt.Errorf("invalid schema: %w", err)
+		return fmt.Errorf("%w: %w", ErrInvalidSchema, err)
 	}
 
 	var inputData interface{}
@@ -62,6 +68,38 @@ func ValidateAgainstSchema(input json.RawMessage, schemaStr string) error {
 	return nil
 }
 
+// ExtractJSON returns the JSON value in an LLM reply: the whole reply when it
+// is JSON, else the content of its first markdown code block, else the
+// outermost object or array embedded in prose. Returns "" when none is found
+// or the code block is not closed.
+func ExtractJSON(text string) string {
+	text = strings.TrimSpace(text)
+	if json.Valid([]byte(text)) {
+		return text
+	}
+	if idx := strings.Index(text, "```"); idx >= 0 {
+		body := text[idx+3:]
+		nl := strings.Index(body, "\n")
+		if nl < 0 {
+			return ""
+		}
+		body = body[nl+1:]
+		end := strings.Index(body, "```")
+		if end < 0 {
+			return ""
+		}
+		return strings.TrimSpace(body[:end])
+	}
+	for _, delims := range []string{"{}", "[]"} {
+		start := strings.IndexByte(text, delims[0])
+		end := strings.LastIndexByte(text, delims[1])
+		if start >= 0 && end > start && json.Valid([]byte(text[start:end+1])) {
+			return text[start : end+1]
+		}
+	}
+	return ""
+}
+
 // calculate performs the arithmetic and is separated from Call so every branch
 // (including the default case) can be unit-tested without bypassing schema
 // validation.
@@ -1,6 +1,7 @@
 package tooling
 
 import (
+	"context"
 	"encoding/json"
 
 	"ironclaw/internal/domain"
@@ -20,3 +21,21 @@ type SchemaTool interface {
 	// Implementations must validate args against the schema before execution.
 	Call(args json.RawMessage) (*domain.ToolResult, error)
 }
+
+// ContextTool is a SchemaTool whose call depends on the caller's context:
+// its cancellation, deadline and values such as the channel and budget
+// scope usage is attributed to.
+type ContextTool interface {
+	SchemaTool
+	// CallContext executes the tool as Call does, under ctx.
+	CallContext(ctx context.Context, args json.RawMessage) (*domain.ToolResult, error)
+}
+
+// CallTool calls tool under ctx when it is a ContextTool, and with Call
+// otherwise.
+func CallTool(ctx context.Context, tool SchemaTool, args json.RawMessage) (*domain.ToolResult, error) {
+	if ct, ok := tool.(ContextTool); ok {
+		return ct.CallContext(ctx, args)
+	}
+	return tool.Call(args)
+}
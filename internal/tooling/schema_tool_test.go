@@ -2,6 +2,7 @@ package tooling
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 	"testing"
@@ -202,6 +203,9 @@ func TestValidateAgainstSchema_ShouldReturnInvalidSchemaError(t *testing.T) {
 	if !strings.Contains(err.Error(), "invalid schema") {
 		t.Errorf("Expected 'invalid schema' in error, got: %v", err)
 	}
+	if !errors.Is(err, ErrInvalidSchema) {
+		t.Errorf("Expected ErrInvalidSchema, got: %v", err)
+	}
 }
 
 func TestValidateAgainstSchema_ShouldReturnInvalidJSONInputError(t *testing.T) {
@@ -482,3 +486,38 @@ var _ SchemaTool = (*CalculatorTool)(nil)
 var _ SchemaTool = (*toolWithBadField)(nil)
 var _ SchemaTool = (*toolWithLooseSchema)(nil)
 var _ SchemaTool = (*badUnmarshalTool)(nil)
+
+func TestExtractJSON_ShouldFindJSONInReply(t *testing.T) {
+	cases := map[string]string{
+		`{"a":1}`:                               `{"a":1}`,
+		"Here:\n```json\n{\"a\":1}\n```\nDone.": `{"a":1}`,
+		`The result is {"a":1}, as asked.`:      `{"a":1}`,
+		`Items: [1, 2] in order.`:               `[1, 2]`,
+	}
+	for reply, want := range cases {
+		if got := ExtractJSON(reply); got != want {
+			t.Errorf("ExtractJSON(%q) = %q, want %q", reply, got, want)
+		}
+	}
+}
+
+func TestExtractJSON_WhenNoJSON_ShouldReturnEmpty(t *testing.T) {
+	result := ExtractJSON("no json here at all")
+	if result != "" {
+		t.Errorf("expected empty string, got %q", result)
+	}
+}
+
+func TestExtractJSON_WhenCodeBlockHasNoClosing_ShouldReturnEmpty(t *testing.T) {
+	result := ExtractJSON("```json\n{\"steps\": []}")
+	if result != "" {
+		t.Errorf("expected empty string for unclosed code block, got %q", result)
+	}
+}
+
+func TestExtractJSON_WhenCodeBlockHasNoNewline_ShouldReturnEmpty(t *testing.T) {
+	result := ExtractJSON("```")
+	if result != "" {
+		t.Errorf("expected empty string, got %q", result)
+	}
+}
@@ -18,10 +18,10 @@ import (
 
 // SkillArg describes a single argument for a Markdown-defined skill.
 type SkillArg struct {
-	Name        string `yaml:"name"`
-	Type        string `yaml:"type"`
-	Description string `yaml:"description"`
-	Required    bool   `yaml:"required"`
+	Name        string `yaml:"name" json:"name" jsonschema:"minLength=1"`
+	Type        string `yaml:"type" json:"type" jsonschema:"enum=string,enum=number,enum=boolean"`
+	Description string `yaml:"description" json:"description"`
+	Required    bool   `yaml:"required" json:"required"`
 }
 
 // SkillFrontmatter holds the YAML frontmatter parsed from a skill .md file.
@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"strings"
 
+	"gopkg.in/yaml.v3"
+
 	"ironclaw/internal/domain"
 )
 
@@ -87,6 +89,54 @@ func BuildSkillPrompt(description string, skillName string) string {
 	return sb.String()
 }
 
+// =============================================================================
+// Structured generation
+// =============================================================================
+
+// structuredGenerator is implemented by providers (such as brain.Brain) that
+// can return JSON validated against a schema, repairing malformed replies.
+type structuredGenerator interface {
+	GenerateStructured(ctx context.Context, prompt string, schema string) (json.RawMessage, error)
+}
+
+// generatedSkill is the skill a structured-output LLM returns: the
+// frontmatter fields and the Markdown body.
+type generatedSkill struct {
+	Name        string     `json:"name" jsonschema:"pattern=^[a-z][a-z0-9_]*$"`
+	Description string     `json:"description" jsonschema:"minLength=1"`
+	Args        []SkillArg `json:"args"`
+	Body        string     `json:"body" jsonschema:"minLength=1"`
+}
+
+// markdown renders the skill as a Markdown file with YAML frontmatter.
+func (g generatedSkill) markdown() (string, error) {
+	fm, err := yaml.Marshal(SkillFrontmatter{Name: g.Name, Description: g.Description, Args: g.Args})
+	if err != nil {
+		return "", fmt.Errorf("failed to render skill frontmatter: %w", err)
+	}
+	return "---\n" + string(fm) + "---\n" + strings.TrimSpace(g.Body) + "\n", nil
+}
+
+// BuildStructuredSkillPrompt constructs the prompt for an LLM with structured
+// output, which replies with the skill's fields as JSON instead of a Markdown
+// file. If skillName is provided, the LLM is instructed to use that name.
+func BuildStructuredSkillPrompt(description string, skillName string) string {
+	var sb strings.Builder
+	sb.WriteString(`You are a skill definition generator for the Ironclaw agent framework.
+Describe the skill with:
+- name: snake_case (e.g. fetch_weather, translate_text)
+- description: one concise sentence
+- args: every argument the skill needs, each of type "string", "number" or "boolean"
+- body: Markdown with a clear system prompt for the LLM, its rules and optional few-shot examples
+
+`)
+	if skillName != "" {
+		sb.WriteString(fmt.Sprintf("Use the name: %s\n\n", skillName))
+	}
+	sb.WriteString(fmt.Sprintf("Generate a skill for: %s\n", description))
+	return sb.String()
+}
+
 // =============================================================================
 // ExtractSkillMarkdown — parse LLM output
 // =============================================================================
@@ -241,19 +291,10 @@ func (sg *SkillGenerator) Generate(ctx context.Context, description string, skil
 		return nil, fmt.Errorf("description must not be empty")
 	}
 
-	// 1. Build the prompt
-	prompt := BuildSkillPrompt(description, skillName)
-
-	// 2. Call the LLM
-	response, err := sg.llm.Generate(ctx, prompt)
+	// 1-3. Ask the LLM for the skill markdown
+	markdown, err := sg.generateMarkdown(ctx, description, skillName)
 	if err != nil {
-		return nil, fmt.Errorf("LLM generation failed: %w", err)
-	}
-
-	// 3. Extract the skill markdown from the LLM response
-	markdown, err := ExtractSkillMarkdown(response)
-	if err != nil {
-		return nil, fmt.Errorf("failed to extract skill from LLM response: %w", err)
+		return nil, err
 	}
 
 	// 4. Parse and validate the frontmatter
@@ -286,6 +327,33 @@ func (sg *SkillGenerator) Generate(ctx context.Context, description string, skil
 	return skill, nil
 }
 
+// generateMarkdown asks the LLM for a skill and returns its Markdown file:
+// rendered from schema-validated JSON when the LLM supports structured output,
+// extracted from its free-text reply otherwise.
+func (sg *SkillGenerator) generateMarkdown(ctx context.Context, description string, skillName string) (string, error) {
+	gen, ok := sg.llm.(structuredGenerator)
+	if !ok {
+		response, err := sg.llm.Generate(ctx, BuildSkillPrompt(description, skillName))
+		if err != nil {
+			return "", fmt.Errorf("LLM generation failed: %w", err)
+		}
+		markdown, err := ExtractSkillMarkdown(response)
+		if err != nil {
+			return "", fmt.Errorf("failed to extract skill from LLM response: %w", err)
+		}
+		return markdown, nil
+	}
+	raw, err := gen.GenerateStructured(ctx, BuildStructuredSkillPrompt(description, skillName), GenerateSchema(generatedSkill{}))
+	if err != nil {
+		return "", fmt.Errorf("LLM generation failed: %w", err)
+	}
+	var skill generatedSkill
+	if err := json.Unmarshal(raw, &skill); err != nil {
+		return "", fmt.Errorf("failed to parse generated skill: %w", err)
+	}
+	return skill.markdown()
+}
+
 // =============================================================================
 // GenerateToFile — convenience for external callers (saves using os.WriteFile)
 // =============================================================================
@@ -298,16 +366,9 @@ func (sg *SkillGenerator) GenerateToFile(ctx context.Context, description string
 		return "", fmt.Errorf("description must not be empty")
 	}
 
-	prompt := BuildSkillPrompt(description, skillName)
-
-	response, err := sg.llm.Generate(ctx, prompt)
-	if err != nil {
-		return "", fmt.Errorf("LLM generation failed: %w", err)
-	}
-
-	markdown, err := ExtractSkillMarkdown(response)
+	markdown, err := sg.generateMarkdown(ctx, description, skillName)
 	if err != nil {
-		return "", fmt.Errorf("failed to extract skill from LLM response: %w", err)
+		return "", err
 	}
 
 	fm, _, err := ParseFrontmatter(markdown)
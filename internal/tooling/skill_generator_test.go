@@ -3,6 +3,7 @@ package tooling
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -38,6 +39,31 @@ func (s *spyLLMProvider) Generate(ctx context.Context, prompt string) (string, e
 	return s.response, s.err
 }
 
+// structuredLLMProvider is a test double for an LLM with structured output.
+// Its reply is validated against the requested schema, as brain.Brain does.
+type structuredLLMProvider struct {
+	reply     string
+	err       error
+	prompt    string
+	generated bool // whether the free-text Generate was called
+}
+
+func (s *structuredLLMProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	s.generated = true
+	return "", errors.New("generate should not be called")
+}
+
+func (s *structuredLLMProvider) GenerateStructured(ctx context.Context, prompt string, schema string) (json.RawMessage, error) {
+	s.prompt = prompt
+	if s.err != nil {
+		return nil, s.err
+	}
+	if err := ValidateAgainstSchema(json.RawMessage(s.reply), schema); err != nil {
+		return nil, err
+	}
+	return json.RawMessage(s.reply), nil
+}
+
 // captureWriteFS records the path and content of WriteFile calls.
 type captureWriteFS struct {
 	writtenPath    string
@@ -546,6 +572,40 @@ func TestGenerate_ShouldReturnSkillWithBody(t *testing.T) {
 	}
 }
 
+func TestGenerate_WhenLLMHasStructuredOutput_ShouldBuildSkillFromJSON(t *testing.T) {
+	llm := &structuredLLMProvider{reply: `{"name":"fetch_weather","description":"Fetch current weather data for a location",` +
+		`"args":[{"name":"location","type":"string","description":"City name","required":true}],"body":"# Weather Skill\n\nReport the weather."}`}
+	fs := &captureWriteFS{}
+	gen := NewSkillGenerator(t.TempDir(), NewToolRegistry(), llm, fs)
+
+	skill, err := gen.Generate(context.Background(), "fetch weather", "fetch_weather")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if llm.generated || !strings.Contains(llm.prompt, "fetch weather") || !strings.Contains(llm.prompt, "Use the name: fetch_weather") {
+		t.Errorf("generated %v, structured prompt %q", llm.generated, llm.prompt)
+	}
+	if skill.Name() != "fetch_weather" || !strings.Contains(skill.Body(), "Report the weather.") || !strings.Contains(skill.Definition(), `"required":["location"]`) {
+		t.Errorf("unexpected skill %q %q %s", skill.Name(), skill.Body(), skill.Definition())
+	}
+	fm, body, err := ParseFrontmatter(fs.writtenContent)
+	if err != nil || fm.Name != "fetch_weather" || len(fm.Args) != 1 || !fm.Args[0].Required || body != "# Weather Skill\n\nReport the weather." {
+		t.Errorf("written file %q parses to %+v, %q, %v", fs.writtenContent, fm, body, err)
+	}
+}
+
+func TestGenerate_WhenStructuredOutputFails_ShouldReturnError(t *testing.T) {
+	for _, llm := range []*structuredLLMProvider{
+		{err: errors.New("provider down")},
+		{reply: `{"name":"Not Snake","description":"d","args":[],"body":"b"}`},
+	} {
+		gen := NewSkillGenerator(t.TempDir(), NewToolRegistry(), llm, &captureWriteFS{})
+		if _, err := gen.Generate(context.Background(), "fetch weather", ""); err == nil {
+			t.Errorf("reply %q: expected error", llm.reply)
+		}
+	}
+}
+
 // =============================================================================
 // SchemaTool Interface Tests — Name, Description, Definition, Call
 // =============================================================================
@@ -10,8 +10,9 @@ import (
 
 // SpawnAgentInput is the JSON Schema input for the spawn_agent tool.
 type SpawnAgentInput struct {
-	Role string `json:"role" jsonschema:"description=The specialist role / system prompt for the sub-agent (e.g. 'You are a Python Expert')"`
-	Task string `json:"task" jsonschema:"description=The task to delegate to the sub-agent"`
+	Role  string `json:"role" jsonschema:"description=The specialist role / system prompt for the sub-agent (e.g. 'You are a Python Expert')"`
+	Task  string `json:"task" jsonschema:"description=The task to delegate to the sub-agent"`
+	Model string `json:"model,omitempty" jsonschema:"description=Optional model or alias for the sub-agent (e.g. 'fast' or 'smart'); defaults to the parent's model"`
 }
 
 // spawnUnmarshalFunc is the JSON unmarshaler used by Call. Package-level so
@@ -48,9 +49,18 @@ func (s *SpawnAgentTool) Definition() string {
 	return GenerateSchema(SpawnAgentInput{})
 }
 
-// Call executes the spawn_agent tool: validates input, delegates to the
-// SubAgentRunner with the given role as system prompt, and returns the result.
+// Call executes the spawn_agent tool without a caller context; see
+// CallContext.
 func (s *SpawnAgentTool) Call(args json.RawMessage) (*domain.ToolResult, error) {
+	return s.CallContext(context.Background(), args)
+}
+
+// CallContext executes the spawn_agent tool: validates input, delegates to
+// the SubAgentRunner with the given role as system prompt, and returns the
+// result. The sub-agent runs under ctx, so it is cancelled with the parent
+// turn and its usage is attributed and budgeted like the parent's. A model in
+// the input is passed to the runner with domain.WithModel.
+func (s *SpawnAgentTool) CallContext(ctx context.Context, args json.RawMessage) (*domain.ToolResult, error) {
 	// Validate against schema first.
 	schema := s.Definition()
 	if err := ValidateAgainstSchema(args, schema); err != nil {
@@ -63,15 +73,14 @@ func (s *SpawnAgentTool) Call(args json.RawMessage) (*domain.ToolResult, error)
 	}
 
 	// Run the task via the sub-agent runner (isolated execution).
-	result, err := s.runner.RunSubAgent(context.Background(), input.Role, input.Task)
+	result, err := s.runner.RunSubAgent(domain.WithModel(ctx, input.Model), input.Role, input.Task)
 	if err != nil {
 		return nil, fmt.Errorf("spawn_agent: sub-agent failed: %w", err)
 	}
 
-	return &domain.ToolResult{
-		Data: result,
-		Metadata: map[string]string{
-			"role": input.Role,
-		},
-	}, nil
+	md := map[string]string{"role": input.Role}
+	if input.Model != "" {
+		md["model"] = input.Model
+	}
+	return &domain.ToolResult{Data: result, Metadata: md}, nil
 }
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"strings"
 	"testing"
+
+	"ironclaw/internal/domain"
 )
 
 // =============================================================================
@@ -17,11 +19,13 @@ type mockSubAgentRunner struct {
 	err         error
 	gotSystem   string // last system prompt passed
 	gotTask     string // last task passed
+	gotModel    string // model named in the last call's context
 }
 
 func (m *mockSubAgentRunner) RunSubAgent(ctx context.Context, systemPrompt string, task string) (string, error) {
 	m.gotSystem = systemPrompt
 	m.gotTask = task
+	m.gotModel = domain.ModelFrom(ctx)
 	if err := ctx.Err(); err != nil {
 		return "", err
 	}
@@ -188,6 +192,23 @@ func TestSpawnAgentTool_Call_ShouldIncludeRoleInMetadata(t *testing.T) {
 	}
 }
 
+func TestSpawnAgentTool_Call_WhenModelGiven_ShouldPassItInContextAndMetadata(t *testing.T) {
+	runner := &mockSubAgentRunner{response: "ok"}
+	tool := NewSpawnAgentTool(runner)
+
+	args := json.RawMessage(`{"role": "Reviewer", "task": "review", "model": "fast"}`)
+	result, err := tool.Call(args)
+	if err != nil {
+		t.Fatalf("Call: %v", err)
+	}
+	if runner.gotModel != "fast" {
+		t.Errorf("runner context model = %q, want %q", runner.gotModel, "fast")
+	}
+	if result.Metadata["model"] != "fast" {
+		t.Errorf("metadata model = %q, want %q", result.Metadata["model"], "fast")
+	}
+}
+
 // =============================================================================
 // SpawnAgentTool — Call (error cases)
 // =============================================================================
@@ -256,6 +277,36 @@ func TestSpawnAgentTool_ShouldImplementSchemaToolInterface(t *testing.T) {
 	var _ SchemaTool = NewSpawnAgentTool(runner)
 }
 
+type ctxKey struct{}
+
+func TestSpawnAgentTool_CallTool_ShouldRunSubAgentUnderCallerContext(t *testing.T) {
+	var got context.Context
+	runner := &ctxRecordingRunner{got: &got}
+	tool := NewSpawnAgentTool(runner)
+	ctx := context.WithValue(context.Background(), ctxKey{}, "channel-1")
+
+	if _, err := CallTool(ctx, tool, json.RawMessage(`{"role":"Expert","task":"t","model":"fast"}`)); err != nil {
+		t.Fatalf("CallTool: %v", err)
+	}
+	if got.Value(ctxKey{}) != "channel-1" || domain.ModelFrom(got) != "fast" {
+		t.Errorf("sub-agent context lost the caller's values: channel %v, model %q", got.Value(ctxKey{}), domain.ModelFrom(got))
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := CallTool(cancelled, NewSpawnAgentTool(&mockSubAgentRunner{response: "ok"}), json.RawMessage(`{"role":"Expert","task":"t"}`)); !errors.Is(err, context.Canceled) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
+
+// ctxRecordingRunner records the context it is called with.
+type ctxRecordingRunner struct{ got *context.Context }
+
+func (r *ctxRecordingRunner) RunSubAgent(ctx context.Context, systemPrompt, task string) (string, error) {
+	*r.got = ctx
+	return "ok", nil
+}
+
 // =============================================================================
 // SpawnAgentTool — Registration in ToolRegistry
 // =============================================================================
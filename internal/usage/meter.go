@@ -0,0 +1,323 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sync"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+// DefaultWarnAt is the fraction of a budget cap at which a warning is raised
+// when the budget does not set one.
+const DefaultWarnAt = 0.8
+
+// ErrBudgetExceeded is matched (via errors.Is) by the *BudgetError Check
+// returns once a channel or agent has spent its cap.
+var ErrBudgetExceeded = errors.New("usage: budget exceeded")
+
+// Budget periods.
+const (
+	Daily   = "daily"
+	Monthly = "monthly"
+)
+
+// BudgetStatus is the spend of one channel or agent against one capped period.
+type BudgetStatus struct {
+	Scope  string  // "channel" or "agent"
+	Name   string  // Channel ID or agent name
+	Period string  // Daily or Monthly
+	Spent  float64 // USD spent in the current period
+	Limit  float64 // USD cap
+	WarnAt float64 // Fraction of Limit at which a warning is raised
+}
+
+// Warning reports whether the spend has reached the warning threshold.
+func (s BudgetStatus) Warning() bool { return s.Spent >= s.WarnAt*s.Limit }
+
+// Exceeded reports whether the cap is spent.
+func (s BudgetStatus) Exceeded() bool { return s.Spent >= s.Limit }
+
+// BudgetError reports a spent budget; further calls are refused until the
+// period rolls over.
+type BudgetError struct {
+	BudgetStatus
+}
+
+func (e *BudgetError) Error() string {
+	return fmt.Sprintf("usage: %s budget of %s %s exceeded ($%.2f of $%.2f)", e.Period, e.Scope, e.Name, e.Spent, e.Limit)
+}
+
+// Is reports whether target is ErrBudgetExceeded.
+func (e *BudgetError) Is(target error) bool { return target == ErrBudgetExceeded }
+
+// Prices maps model names or globs to their price. Exact names win over
+// globs; among globs the longest pattern wins.
+type Prices map[string]domain.ModelPrice
+
+// Cost returns the USD cost of a call to model, or 0 when model is unpriced.
+func (p Prices) Cost(model string, inputTokens, outputTokens int) float64 {
+	price, ok := p[model]
+	if !ok {
+		best := -1
+		for pattern, pr := range p {
+			if m, err := path.Match(pattern, model); err == nil && m && len(pattern) > best {
+				price, best = pr, len(pattern)
+			}
+		}
+	}
+	return (float64(inputTokens)*price.Input + float64(outputTokens)*price.Output) / 1e6
+}
+
+// Option configures a Meter.
+type Option func(*Meter)
+
+// WithPrices prices recorded calls with p.
+func WithPrices(p Prices) Option {
+	return func(m *Meter) { m.prices = p }
+}
+
+// WithBudgets sets the spend caps Check enforces.
+func WithBudgets(budgets ...domain.BudgetConfig) Option {
+	return func(m *Meter) { m.budgets = budgets }
+}
+
+// WithWarnFunc calls fn, once per budget and period, when a channel's or
+// agent's spend reaches the budget's warning threshold. If fn is nil it is
+// ignored and warnings are logged with slog.
+func WithWarnFunc(fn func(BudgetStatus)) Option {
+	return func(m *Meter) {
+		if fn != nil {
+			m.onWarn = fn
+		}
+	}
+}
+
+// Meter records the calls providers report, priced, in a Store and enforces
+// spend budgets. It implements Recorder; the brain places it in the context
+// of every provider call (see WithRecorder) and consults Check first.
+type Meter struct {
+	store   Store
+	prices  Prices
+	budgets []domain.BudgetConfig
+	onWarn  func(BudgetStatus)
+
+	// Injectable for tests.
+	now func() time.Time
+
+	mu     sync.Mutex
+	warned map[string]bool // scope/name/period/period start -> true
+}
+
+// NewMeter returns a Meter storing records in store. store must not be nil.
+func NewMeter(store Store, opts ...Option) *Meter {
+	if store == nil {
+		panic("usage: store must not be nil")
+	}
+	m := &Meter{
+		store:  store,
+		onWarn: logWarning,
+		now:    time.Now,
+		warned: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// NewMeterFromConfig builds a Meter from cfg's prices and budgets, storing
+// records in store. Returns an error for a budget that does not name exactly
+// one of a channel and an agent glob.
+func NewMeterFromConfig(cfg domain.UsageConfig, store Store, opts ...Option) (*Meter, error) {
+	for i, b := range cfg.Budgets {
+		if (b.Channel == "") == (b.Agent == "") {
+			return nil, fmt.Errorf("usage: budget %d: set exactly one of channel and agent", i)
+		}
+		if _, err := path.Match(b.Channel+b.Agent, ""); err != nil {
+			return nil, fmt.Errorf("usage: budget %d: %w", i, err)
+		}
+	}
+	opts = append([]Option{WithPrices(cfg.Prices), WithBudgets(cfg.Budgets...)}, opts...)
+	return NewMeter(store, opts...), nil
+}
+
+// RecordCall implements Recorder: the call is priced, attributed to ctx's
+// channel and agent and stored, then budget warnings are raised. Storage
+// errors are logged; accounting never fails the call that was made.
+func (m *Meter) RecordCall(ctx context.Context, call Call) {
+	a := AttributionFrom(ctx)
+	rec := Record{
+		Time:         m.now(),
+		Channel:      a.Channel,
+		Agent:        a.Agent,
+		Provider:     call.Provider,
+		Model:        call.Model,
+		Key:          call.Key,
+		InputTokens:  call.InputTokens,
+		OutputTokens: call.OutputTokens,
+		Cost:         m.prices.Cost(call.Model, call.InputTokens, call.OutputTokens),
+	}
+	// Record even if the caller gave up: the tokens were spent.
+	ctx = context.WithoutCancel(ctx)
+	if err := m.store.Add(ctx, rec); err != nil {
+		slog.Warn("usage: record failed", "error", err)
+		return
+	}
+	if len(m.budgets) == 0 {
+		return
+	}
+	statuses, err := m.status(ctx, a)
+	if err != nil {
+		slog.Warn("usage: budget status failed", "error", err)
+		return
+	}
+	for _, st := range statuses {
+		if st.Warning() && m.markWarned(st) {
+			m.onWarn(st.BudgetStatus)
+		}
+	}
+}
+
+// Check returns a *BudgetError when the channel or agent ctx is attributed to
+// has spent one of its caps for the current day or month.
+func (m *Meter) Check(ctx context.Context) error {
+	if len(m.budgets) == 0 {
+		return nil
+	}
+	statuses, err := m.status(ctx, AttributionFrom(ctx))
+	if err != nil {
+		return fmt.Errorf("usage: check budgets: %w", err)
+	}
+	for _, st := range statuses {
+		if st.Exceeded() {
+			return &BudgetError{st.BudgetStatus}
+		}
+	}
+	return nil
+}
+
+// budgetStatus is a BudgetStatus with the start of its period.
+type budgetStatus struct {
+	BudgetStatus
+	start time.Time
+}
+
+// status returns the spend of a's channel and agent against every cap that
+// applies to them.
+func (m *Meter) status(ctx context.Context, a Attribution) ([]budgetStatus, error) {
+	day, month := periodStarts(m.now())
+
+	var out []budgetStatus
+	for _, b := range m.budgets {
+		var scope, name string
+		var f Filter
+		switch {
+		case b.Channel != "" && a.Channel != "" && match(b.Channel, a.Channel):
+			scope, name, f.Channel = "channel", a.Channel, a.Channel
+		case b.Agent != "" && a.Agent != "" && match(b.Agent, a.Agent):
+			scope, name, f.Agent = "agent", a.Agent, a.Agent
+		default:
+			continue
+		}
+		warnAt := b.WarnAt
+		if warnAt <= 0 {
+			warnAt = DefaultWarnAt
+		}
+		for _, p := range []struct {
+			period string
+			limit  float64
+			start  time.Time
+		}{{Daily, b.Daily, day}, {Monthly, b.Monthly, month}} {
+			if p.limit <= 0 {
+				continue
+			}
+			f.Since = p.start
+			spent, err := m.spent(ctx, f)
+			if err != nil {
+				return nil, err
+			}
+			out = append(out, budgetStatus{
+				BudgetStatus: BudgetStatus{Scope: scope, Name: name, Period: p.period, Spent: spent, Limit: p.limit, WarnAt: warnAt},
+				start:        p.start,
+			})
+		}
+	}
+	return out, nil
+}
+
+// Budgets returns the spend of every channel and agent with usage this month
+// against each cap that applies to it.
+func (m *Meter) Budgets(ctx context.Context) ([]BudgetStatus, error) {
+	if len(m.budgets) == 0 {
+		return nil, nil
+	}
+	_, month := periodStarts(m.now())
+	var out []BudgetStatus
+	for _, by := range []Dimension{ByChannel, ByAgent} {
+		sums, err := m.store.Summarize(ctx, Filter{Since: month}, by)
+		if err != nil {
+			return nil, err
+		}
+		for _, sum := range sums {
+			if sum.Group == "" {
+				continue
+			}
+			a := Attribution{Channel: sum.Group}
+			if by == ByAgent {
+				a = Attribution{Agent: sum.Group}
+			}
+			statuses, err := m.status(ctx, a)
+			if err != nil {
+				return nil, err
+			}
+			for _, st := range statuses {
+				out = append(out, st.BudgetStatus)
+			}
+		}
+	}
+	return out, nil
+}
+
+// periodStarts returns the start of now's day and month, in now's location.
+func periodStarts(now time.Time) (day, month time.Time) {
+	day = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	month = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return day, month
+}
+
+func (m *Meter) spent(ctx context.Context, f Filter) (float64, error) {
+	sums, err := m.store.Summarize(ctx, f, "")
+	if err != nil || len(sums) == 0 {
+		return 0, err
+	}
+	return sums[0].Cost, nil
+}
+
+// markWarned reports whether st has not been warned about in its period yet,
+// and remembers that it now has.
+func (m *Meter) markWarned(st budgetStatus) bool {
+	key := fmt.Sprintf("%s\x00%s\x00%s\x00%d", st.Scope, st.Name, st.Period, st.start.Unix())
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.warned[key] {
+		return false
+	}
+	m.warned[key] = true
+	return true
+}
+
+func logWarning(st BudgetStatus) {
+	slog.Warn("usage: budget nearly spent", "scope", st.Scope, "name", st.Name, "period", st.Period, "spent", st.Spent, "limit", st.Limit)
+}
+
+func match(pattern, name string) bool {
+	ok, err := path.Match(pattern, name)
+	return err == nil && ok
+}
+
+var _ Recorder = (*Meter)(nil)
@@ -0,0 +1,161 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"ironclaw/internal/domain"
+)
+
+func newTestMeter(t *testing.T, opts ...Option) *Meter {
+	t.Helper()
+	m := NewMeter(newTestStore(t), opts...)
+	m.now = func() time.Time { return time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC) }
+	return m
+}
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestPrices_Cost_ShouldPreferExactThenLongestGlob(t *testing.T) {
+	p := Prices{
+		"gpt-4o":       {Input: 2.5, Output: 10},
+		"gpt-4o*":      {Input: 1, Output: 1},
+		"gpt-4o-mini*": {Input: 0.15, Output: 0.6},
+	}
+	if got := p.Cost("gpt-4o", 1_000_000, 1_000_000); !approx(got, 12.5) {
+		t.Errorf("exact = %v, want 12.5", got)
+	}
+	if got := p.Cost("gpt-4o-mini-2024", 1_000_000, 0); !approx(got, 0.15) {
+		t.Errorf("longest glob = %v, want 0.15", got)
+	}
+	if got := p.Cost("claude", 1_000_000, 0); got != 0 {
+		t.Errorf("unpriced = %v, want 0", got)
+	}
+}
+
+func TestNewMeter_WhenNilStore_ShouldPanic(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("expected panic for nil store")
+		}
+	}()
+	NewMeter(nil)
+}
+
+func TestNewMeterFromConfig_WhenBudgetScopeInvalid_ShouldReturnError(t *testing.T) {
+	store := newTestStore(t)
+	for _, b := range []domain.BudgetConfig{
+		{Daily: 1},
+		{Channel: "a", Agent: "b", Daily: 1},
+		{Channel: "[", Daily: 1},
+	} {
+		if _, err := NewMeterFromConfig(domain.UsageConfig{Budgets: []domain.BudgetConfig{b}}, store); err == nil {
+			t.Errorf("budget %+v: expected error", b)
+		}
+	}
+}
+
+func TestMeter_RecordCall_ShouldStorePricedAttributedRecord(t *testing.T) {
+	m := newTestMeter(t, WithPrices(Prices{"gpt-4o": {Input: 2, Output: 8}}))
+	ctx := WithAttribution(context.Background(), Attribution{Channel: "telegram-1", Agent: "helper"})
+
+	m.RecordCall(ctx, Call{Provider: "openai", Model: "gpt-4o", Key: "…WXYZ", InputTokens: 500_000, OutputTokens: 250_000})
+
+	sums, err := m.store.Summarize(context.Background(), Filter{Channel: "telegram-1", Agent: "helper"}, ByKey)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if len(sums) != 1 || sums[0].Group != "…WXYZ" || !approx(sums[0].Cost, 3) {
+		t.Errorf("sums = %+v, want one …WXYZ record costing $3", sums)
+	}
+}
+
+func TestMeter_Check_WhenChannelBudgetSpent_ShouldReturnBudgetError(t *testing.T) {
+	m := newTestMeter(t,
+		WithPrices(Prices{"*": {Input: 1}}),
+		WithBudgets(domain.BudgetConfig{Channel: "telegram-*", Daily: 1}),
+	)
+	ctx := WithAttribution(context.Background(), Attribution{Channel: "telegram-1"})
+	other := WithAttribution(context.Background(), Attribution{Channel: "whatsapp-1"})
+
+	if err := m.Check(ctx); err != nil {
+		t.Fatalf("Check before spending: %v", err)
+	}
+	m.RecordCall(ctx, Call{Model: "m", InputTokens: 1_000_000})
+
+	err := m.Check(ctx)
+	if !errors.Is(err, ErrBudgetExceeded) {
+		t.Fatalf("Check = %v, want ErrBudgetExceeded", err)
+	}
+	var be *BudgetError
+	if !errors.As(err, &be) || be.Scope != "channel" || be.Name != "telegram-1" || be.Period != Daily {
+		t.Errorf("BudgetError = %+v", be)
+	}
+	if err := m.Check(other); err != nil {
+		t.Errorf("unbudgeted channel: Check = %v, want nil", err)
+	}
+}
+
+func TestMeter_Check_WhenAgentMonthlyBudgetSpent_ShouldReturnBudgetError(t *testing.T) {
+	m := newTestMeter(t,
+		WithPrices(Prices{"*": {Output: 1}}),
+		WithBudgets(domain.BudgetConfig{Agent: "helper", Monthly: 2}),
+	)
+	ctx := WithAttribution(context.Background(), Attribution{Channel: "c1", Agent: "helper"})
+	m.RecordCall(ctx, Call{Model: "m", OutputTokens: 1_000_000})
+	m.RecordCall(WithAttribution(context.Background(), Attribution{Channel: "c2", Agent: "helper"}), Call{Model: "m", OutputTokens: 1_000_000})
+
+	var be *BudgetError
+	if err := m.Check(ctx); !errors.As(err, &be) || be.Scope != "agent" || be.Period != Monthly {
+		t.Fatalf("Check = %v, want monthly agent BudgetError", err)
+	}
+}
+
+func TestMeter_RecordCall_WhenWarnThresholdCrossed_ShouldWarnOnce(t *testing.T) {
+	var warnings []BudgetStatus
+	m := newTestMeter(t,
+		WithPrices(Prices{"*": {Input: 1}}),
+		WithBudgets(domain.BudgetConfig{Channel: "c", Daily: 10, WarnAt: 0.5}),
+		WithWarnFunc(func(st BudgetStatus) { warnings = append(warnings, st) }),
+	)
+	ctx := WithAttribution(context.Background(), Attribution{Channel: "c"})
+
+	m.RecordCall(ctx, Call{Model: "m", InputTokens: 4_000_000})
+	if len(warnings) != 0 {
+		t.Fatalf("warned below threshold: %+v", warnings)
+	}
+	m.RecordCall(ctx, Call{Model: "m", InputTokens: 2_000_000})
+	m.RecordCall(ctx, Call{Model: "m", InputTokens: 1_000_000})
+	if len(warnings) != 1 {
+		t.Fatalf("warnings = %d, want 1", len(warnings))
+	}
+	if w := warnings[0]; w.Name != "c" || !approx(w.Spent, 6) || w.Limit != 10 {
+		t.Errorf("warning = %+v", w)
+	}
+}
+
+func TestMeter_Budgets_ShouldListBudgetedUsage(t *testing.T) {
+	m := newTestMeter(t,
+		WithPrices(Prices{"*": {Input: 1}}),
+		WithBudgets(domain.BudgetConfig{Channel: "c", Monthly: 10}, domain.BudgetConfig{Agent: "helper", Daily: 1}),
+	)
+	m.RecordCall(WithAttribution(context.Background(), Attribution{Channel: "c", Agent: "helper"}), Call{Model: "m", InputTokens: 1_000_000})
+	m.RecordCall(WithAttribution(context.Background(), Attribution{Channel: "unbudgeted"}), Call{Model: "m", InputTokens: 1_000_000})
+
+	got, err := m.Budgets(context.Background())
+	if err != nil {
+		t.Fatalf("Budgets: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("Budgets = %+v, want channel c and agent helper", got)
+	}
+	if got[0].Scope != "channel" || got[0].Name != "c" || got[0].Exceeded() {
+		t.Errorf("channel budget = %+v", got[0])
+	}
+	if got[1].Scope != "agent" || got[1].Name != "helper" || !got[1].Exceeded() {
+		t.Errorf("agent budget = %+v", got[1])
+	}
+}
@@ -0,0 +1,68 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ironclaw/internal/db"
+	"ironclaw/internal/domain"
+)
+
+// ErrNoDatabase is returned by Open when neither usage.dbUrl nor a memory
+// directory is configured.
+var ErrNoDatabase = errors.New("usage: no database configured (set usage.dbUrl or agents.paths.memory)")
+
+// DBURL returns cfg.DBURL, or a file: URL for usage.db in memoryDir when it
+// is unset. Returns "" when both are empty.
+func DBURL(cfg domain.UsageConfig, memoryDir string) string {
+	if cfg.DBURL != "" {
+		return cfg.DBURL
+	}
+	if memoryDir == "" {
+		return ""
+	}
+	return "file:" + filepath.Join(memoryDir, "usage.db")
+}
+
+// OpenStore connects to dbURL, creating the directory of a local file:
+// database, and returns the store with a func that closes the connection.
+func OpenStore(dbURL string) (*SQLiteStore, func() error, error) {
+	if path, ok := strings.CutPrefix(dbURL, "file:"); ok {
+		path, _, _ = strings.Cut(path, "?")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("usage: create store dir: %w", err)
+		}
+	}
+	conn, err := db.Connect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	store, err := NewSQLiteStore(conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	return store, conn.Close, nil
+}
+
+// Open builds the Meter described by cfg on the store at DBURL(cfg,
+// memoryDir) and returns it with a func that closes the store.
+func Open(cfg domain.UsageConfig, memoryDir string, opts ...Option) (*Meter, func() error, error) {
+	dbURL := DBURL(cfg, memoryDir)
+	if dbURL == "" {
+		return nil, nil, ErrNoDatabase
+	}
+	store, closeFn, err := OpenStore(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	m, err := NewMeterFromConfig(cfg, store, opts...)
+	if err != nil {
+		closeFn()
+		return nil, nil, err
+	}
+	return m, closeFn, nil
+}
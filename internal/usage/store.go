@@ -0,0 +1,180 @@
+package usage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Record is one priced provider call.
+type Record struct {
+	Time         time.Time `json:"time"`
+	Channel      string    `json:"channel,omitempty"`
+	Agent        string    `json:"agent,omitempty"`
+	Provider     string    `json:"provider"`
+	Model        string    `json:"model"`
+	Key          string    `json:"key,omitempty"`
+	InputTokens  int       `json:"inputTokens"`
+	OutputTokens int       `json:"outputTokens"`
+	Cost         float64   `json:"cost"` // USD
+}
+
+// Dimension is what a Summary is grouped by.
+type Dimension string
+
+const (
+	ByChannel  Dimension = "channel"
+	ByAgent    Dimension = "agent"
+	ByProvider Dimension = "provider"
+	ByModel    Dimension = "model"
+	ByKey      Dimension = "key"
+)
+
+// Dimensions lists the valid Summarize groupings.
+var Dimensions = []Dimension{ByChannel, ByAgent, ByProvider, ByModel, ByKey}
+
+// Filter selects records. Zero fields match everything.
+type Filter struct {
+	Since   time.Time // Inclusive
+	Until   time.Time // Exclusive
+	Channel string
+	Agent   string
+}
+
+// Summary totals the records of one group.
+type Summary struct {
+	Group        string  `json:"group"` // Value of the grouping dimension; "" for the overall total
+	Calls        int     `json:"calls"`
+	InputTokens  int64   `json:"inputTokens"`
+	OutputTokens int64   `json:"outputTokens"`
+	Cost         float64 `json:"cost"`
+}
+
+// Store persists usage records.
+type Store interface {
+	// Add appends a record.
+	Add(ctx context.Context, rec Record) error
+	// Summarize totals the records matching f, one Summary per value of by
+	// ordered by cost (most expensive first), or a single overall Summary
+	// when by is "" (none when no record matches).
+	Summarize(ctx context.Context, f Filter, by Dimension) ([]Summary, error)
+}
+
+// SQLiteStore stores usage records in a SQLite/libSQL table. Open the
+// connection with db.Connect.
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+// NewSQLiteStore creates a usage store and initializes the schema.
+// Returns an error if the db is nil or if the migration fails.
+func NewSQLiteStore(db *sql.DB) (*SQLiteStore, error) {
+	if db == nil {
+		return nil, fmt.Errorf("db must not be nil")
+	}
+	s := &SQLiteStore{db: db}
+	if err := s.migrate(); err != nil {
+		return nil, fmt.Errorf("usage migrate: %w", err)
+	}
+	return s, nil
+}
+
+// migrate creates the usage_records table if it doesn't exist.
+func (s *SQLiteStore) migrate() error {
+	_, err := s.db.Exec(`
+		CREATE TABLE IF NOT EXISTS usage_records (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			at INTEGER NOT NULL,
+			channel TEXT NOT NULL DEFAULT '',
+			agent TEXT NOT NULL DEFAULT '',
+			provider TEXT NOT NULL DEFAULT '',
+			model TEXT NOT NULL DEFAULT '',
+			key_id TEXT NOT NULL DEFAULT '',
+			input_tokens INTEGER NOT NULL DEFAULT 0,
+			output_tokens INTEGER NOT NULL DEFAULT 0,
+			cost REAL NOT NULL DEFAULT 0
+		)
+	`)
+	if err != nil {
+		return err
+	}
+	for _, idx := range []string{
+		`CREATE INDEX IF NOT EXISTS usage_records_channel ON usage_records (channel, at)`,
+		`CREATE INDEX IF NOT EXISTS usage_records_agent ON usage_records (agent, at)`,
+	} {
+		if _, err := s.db.Exec(idx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Add implements Store.
+func (s *SQLiteStore) Add(ctx context.Context, rec Record) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO usage_records (at, channel, agent, provider, model, key_id, input_tokens, output_tokens, cost)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.Time.UnixNano(), rec.Channel, rec.Agent, rec.Provider, rec.Model, rec.Key, rec.InputTokens, rec.OutputTokens, rec.Cost)
+	return err
+}
+
+// Summarize implements Store.
+func (s *SQLiteStore) Summarize(ctx context.Context, f Filter, by Dimension) ([]Summary, error) {
+	group := "''"
+	if by != "" {
+		col, ok := dimensionColumns[by]
+		if !ok {
+			return nil, fmt.Errorf("usage: unknown grouping %q", by)
+		}
+		group = col
+	}
+	where := "WHERE 1=1"
+	var args []any
+	if !f.Since.IsZero() {
+		where += " AND at >= ?"
+		args = append(args, f.Since.UnixNano())
+	}
+	if !f.Until.IsZero() {
+		where += " AND at < ?"
+		args = append(args, f.Until.UnixNano())
+	}
+	if f.Channel != "" {
+		where += " AND channel = ?"
+		args = append(args, f.Channel)
+	}
+	if f.Agent != "" {
+		where += " AND agent = ?"
+		args = append(args, f.Agent)
+	}
+	query := fmt.Sprintf(`
+		SELECT %[1]s, COUNT(*), COALESCE(SUM(input_tokens), 0), COALESCE(SUM(output_tokens), 0), COALESCE(SUM(cost), 0)
+		FROM usage_records %[2]s GROUP BY %[1]s ORDER BY 5 DESC, 1
+	`, group, where)
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []Summary
+	for rows.Next() {
+		var sum Summary
+		if err := rows.Scan(&sum.Group, &sum.Calls, &sum.InputTokens, &sum.OutputTokens, &sum.Cost); err != nil {
+			return nil, err
+		}
+		out = append(out, sum)
+	}
+	return out, rows.Err()
+}
+
+// dimensionColumns maps each Dimension to its usage_records column.
+var dimensionColumns = map[Dimension]string{
+	ByChannel:  "channel",
+	ByAgent:    "agent",
+	ByProvider: "provider",
+	ByModel:    "model",
+	ByKey:      "key_id",
+}
+
+var _ Store = (*SQLiteStore)(nil)
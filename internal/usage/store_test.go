@@ -0,0 +1,92 @@
+package usage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"ironclaw/internal/db"
+)
+
+func newTestStore(t *testing.T) *SQLiteStore {
+	t.Helper()
+	conn, err := db.Connect("file:" + filepath.Join(t.TempDir(), "usage.db"))
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	store, err := NewSQLiteStore(conn)
+	if err != nil {
+		t.Fatalf("NewSQLiteStore: %v", err)
+	}
+	return store
+}
+
+func TestNewSQLiteStore_WhenNilDB_ShouldReturnError(t *testing.T) {
+	if _, err := NewSQLiteStore(nil); err == nil {
+		t.Fatal("expected error for nil db")
+	}
+}
+
+func TestSQLiteStore_Summarize_ShouldGroupAndFilter(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, rec := range []Record{
+		{Time: base, Channel: "a", Agent: "x", Provider: "openai", Model: "gpt-4o", Key: "…AAAA", InputTokens: 10, OutputTokens: 5, Cost: 1},
+		{Time: base.Add(time.Hour), Channel: "a", Provider: "openai", Model: "gpt-4o", Key: "…BBBB", InputTokens: 20, OutputTokens: 10, Cost: 2},
+		{Time: base.Add(2 * time.Hour), Channel: "b", Agent: "x", Provider: "anthropic", Model: "claude", InputTokens: 1, OutputTokens: 1, Cost: 5},
+		{Time: base.AddDate(0, -1, 0), Channel: "a", Provider: "openai", Model: "gpt-4o", InputTokens: 100, Cost: 100},
+	} {
+		if err := store.Add(ctx, rec); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	since := Filter{Since: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+
+	byModel, err := store.Summarize(ctx, since, ByModel)
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if len(byModel) != 2 || byModel[0].Group != "claude" || byModel[1].Group != "gpt-4o" {
+		t.Fatalf("byModel = %+v, want claude then gpt-4o (by cost)", byModel)
+	}
+	if g := byModel[1]; g.Calls != 2 || g.InputTokens != 30 || g.OutputTokens != 15 || g.Cost != 3 {
+		t.Errorf("gpt-4o = %+v", g)
+	}
+
+	byKey, err := store.Summarize(ctx, Filter{Since: since.Since, Channel: "a"}, ByKey)
+	if err != nil {
+		t.Fatalf("Summarize by key: %v", err)
+	}
+	if len(byKey) != 2 || byKey[0].Group != "…BBBB" || byKey[1].Group != "…AAAA" {
+		t.Errorf("byKey = %+v", byKey)
+	}
+
+	total, err := store.Summarize(ctx, Filter{Since: since.Since, Agent: "x"}, "")
+	if err != nil {
+		t.Fatalf("Summarize total: %v", err)
+	}
+	if len(total) != 1 || total[0].Calls != 2 || total[0].Cost != 6 {
+		t.Errorf("total = %+v", total)
+	}
+}
+
+func TestSQLiteStore_Summarize_WhenNoRecords_ShouldReturnNoTotal(t *testing.T) {
+	store := newTestStore(t)
+	total, err := store.Summarize(context.Background(), Filter{}, "")
+	if err != nil {
+		t.Fatalf("Summarize: %v", err)
+	}
+	if len(total) != 0 {
+		t.Errorf("total = %+v, want none", total)
+	}
+}
+
+func TestSQLiteStore_Summarize_WhenUnknownGrouping_ShouldReturnError(t *testing.T) {
+	store := newTestStore(t)
+	if _, err := store.Summarize(context.Background(), Filter{}, "color"); err == nil {
+		t.Fatal("expected error for unknown grouping")
+	}
+}
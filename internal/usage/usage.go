@@ -0,0 +1,119 @@
+// Package usage accounts for the tokens every provider call consumes: calls
+// are attributed to a channel, agent, model and API key, priced, stored and
+// checked against spend budgets.
+package usage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// Call is the usage a provider reports for one successful request.
+type Call struct {
+	Provider     string // e.g. "anthropic", "openai"
+	Model        string
+	Key          string // KeyID of the API key used; empty for keyless providers
+	InputTokens  int
+	OutputTokens int
+}
+
+// Attribution identifies who a call is made for.
+type Attribution struct {
+	Channel string // Channel ID (e.g. "telegram-42", "job:digest")
+	Agent   string // Name of the bound agent; empty when the default brain answers
+}
+
+// Recorder receives the calls providers report.
+type Recorder interface {
+	RecordCall(ctx context.Context, call Call)
+}
+
+type attributionKey struct{}
+
+type recorderKey struct{}
+
+// WithAttribution returns a context attributing the calls made with it to a.
+// The router sets it before handing a message to the channel's brain.
+func WithAttribution(ctx context.Context, a Attribution) context.Context {
+	return context.WithValue(ctx, attributionKey{}, a)
+}
+
+// AttributionFrom returns the Attribution stored by WithAttribution, or the
+// zero value.
+func AttributionFrom(ctx context.Context) Attribution {
+	a, _ := ctx.Value(attributionKey{}).(Attribution)
+	return a
+}
+
+// WithRecorder returns a context whose provider calls are reported to r.
+func WithRecorder(ctx context.Context, r Recorder) context.Context {
+	return context.WithValue(ctx, recorderKey{}, r)
+}
+
+// WithChainedRecorder returns a context whose provider calls are reported to
+// r, then to the Recorder ctx already carries, if any, so a caller counting
+// the usage of a request still sees the calls a brain meters.
+func WithChainedRecorder(ctx context.Context, r Recorder) context.Context {
+	parent, _ := ctx.Value(recorderKey{}).(Recorder)
+	if parent == nil {
+		return WithRecorder(ctx, r)
+	}
+	return WithRecorder(ctx, chain{r, parent})
+}
+
+// chain reports calls to each of its recorders in turn.
+type chain []Recorder
+
+// RecordCall implements Recorder.
+func (c chain) RecordCall(ctx context.Context, call Call) {
+	for _, r := range c {
+		r.RecordCall(ctx, call)
+	}
+}
+
+// Tally is a Recorder adding up the tokens of the calls reported to it, e.g.
+// to tell an API client what its request used. It is safe for concurrent use.
+type Tally struct {
+	mu            sync.Mutex
+	input, output int
+}
+
+// RecordCall implements Recorder.
+func (t *Tally) RecordCall(ctx context.Context, call Call) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.input += call.InputTokens
+	t.output += call.OutputTokens
+}
+
+// Tokens returns the input and output tokens recorded so far.
+func (t *Tally) Tokens() (input, output int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.input, t.output
+}
+
+// Report passes call to the Recorder stored in ctx, if any. Providers call it
+// after every successful request.
+func Report(ctx context.Context, call Call) {
+	if r, ok := ctx.Value(recorderKey{}).(Recorder); ok && r != nil {
+		r.RecordCall(ctx, call)
+	}
+}
+
+// KeyID identifies an API key without revealing it: "…" and its last four
+// characters, or a short hash for keys too short to truncate safely. Empty
+// keys give "".
+func KeyID(key string) string {
+	switch {
+	case key == "":
+		return ""
+	case len(key) >= 16:
+		return "…" + key[len(key)-4:]
+	default:
+		sum := sha256.Sum256([]byte(key))
+		return "#" + hex.EncodeToString(sum[:4])
+	}
+}
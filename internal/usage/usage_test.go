@@ -0,0 +1,66 @@
+package usage
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type recorderFunc func(context.Context, Call)
+
+func (f recorderFunc) RecordCall(ctx context.Context, c Call) { f(ctx, c) }
+
+func TestReport_WhenRecorderInContext_ShouldPassCallWithAttribution(t *testing.T) {
+	var got Call
+	var gotAttr Attribution
+	ctx := WithAttribution(context.Background(), Attribution{Channel: "telegram-1", Agent: "helper"})
+	ctx = WithRecorder(ctx, recorderFunc(func(ctx context.Context, c Call) {
+		got, gotAttr = c, AttributionFrom(ctx)
+	}))
+
+	Report(ctx, Call{Provider: "openai", Model: "gpt-4o", InputTokens: 3, OutputTokens: 4})
+
+	if got.Provider != "openai" || got.InputTokens != 3 || got.OutputTokens != 4 {
+		t.Errorf("call = %+v", got)
+	}
+	if gotAttr.Channel != "telegram-1" || gotAttr.Agent != "helper" {
+		t.Errorf("attribution = %+v", gotAttr)
+	}
+}
+
+func TestReport_WhenNoRecorder_ShouldDoNothing(t *testing.T) {
+	Report(context.Background(), Call{Provider: "openai"}) // must not panic
+}
+
+func TestKeyID_ShouldNotRevealKey(t *testing.T) {
+	if got := KeyID(""); got != "" {
+		t.Errorf("KeyID(\"\") = %q, want empty", got)
+	}
+	if got := KeyID("sk-0123456789abcdefWXYZ"); got != "…WXYZ" {
+		t.Errorf("long key = %q, want …WXYZ", got)
+	}
+	short := KeyID("short-key")
+	if !strings.HasPrefix(short, "#") || strings.Contains(short, "key") {
+		t.Errorf("short key = %q, want hash", short)
+	}
+	if short == KeyID("other-key") {
+		t.Error("different short keys should have different IDs")
+	}
+}
+
+func TestWithChainedRecorder_ShouldReportToBothRecorders(t *testing.T) {
+	var parent int
+	ctx := WithRecorder(context.Background(), recorderFunc(func(_ context.Context, c Call) { parent += c.InputTokens }))
+	tally := &Tally{}
+	ctx = WithChainedRecorder(ctx, tally)
+
+	Report(ctx, Call{InputTokens: 3, OutputTokens: 4})
+	Report(ctx, Call{InputTokens: 2, OutputTokens: 1})
+
+	if in, out := tally.Tokens(); in != 5 || out != 5 {
+		t.Errorf("tally = %d/%d, want 5/5", in, out)
+	}
+	if parent != 5 {
+		t.Errorf("parent recorder saw %d input tokens, want 5", parent)
+	}
+}
@@ -0,0 +1,511 @@
+package vectorstore
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+// HNSW defaults, used when no option is given.
+const (
+	DefaultM              = 16
+	DefaultEfConstruction = 200
+	DefaultEfSearch       = 64
+)
+
+// hnswMagic starts every index written by WriteTo; the last byte is the
+// format version.
+var hnswMagic = [8]byte{'I', 'C', 'H', 'N', 'S', 'W', 0, 1}
+
+// Bounds ReadHNSW checks an index against before allocating for it.
+const (
+	maxIndexDim   = 1 << 16
+	maxIndexLevel = 64
+)
+
+// ErrIndexFormat is returned by ReadHNSW for data that is not an index
+// written by WriteTo, or is truncated.
+var ErrIndexFormat = errors.New("vectorstore: malformed index")
+
+// IndexOption configures an HNSW index.
+type IndexOption func(*HNSW)
+
+// WithM sets the links each node keeps per layer, twice that on the bottom
+// layer (default DefaultM). Values below 2 keep the default.
+func WithM(m int) IndexOption {
+	return func(h *HNSW) {
+		if m >= 2 {
+			h.m = m
+		}
+	}
+}
+
+// WithEfConstruction sets how many candidates are considered when a node is
+// linked in (default DefaultEfConstruction). Non-positive values keep the
+// default.
+func WithEfConstruction(ef int) IndexOption {
+	return func(h *HNSW) {
+		if ef > 0 {
+			h.efConstruction = ef
+		}
+	}
+}
+
+// WithEfSearch sets how many candidates a search considers (default
+// DefaultEfSearch); searches for more results consider that many.
+// Non-positive values keep the default.
+func WithEfSearch(ef int) IndexOption {
+	return func(h *HNSW) {
+		if ef > 0 {
+			h.efSearch = ef
+		}
+	}
+}
+
+// HNSW is an in-memory Hierarchical Navigable Small World graph over
+// embeddings, answering cosine similarity queries approximately in time
+// logarithmic in its size. Embeddings are kept normalised as float32. All
+// embeddings must have the dimension of the first one added. HNSW is safe
+// for concurrent use.
+type HNSW struct {
+	mu             sync.RWMutex
+	m              int
+	efConstruction int
+	efSearch       int
+	levelMult      float64
+	rng            *rand.Rand
+
+	dim      int
+	nodes    []hnswNode
+	ids      map[int64]int32 // memory ID -> node
+	entry    int32           // -1 when empty
+	maxLevel int
+	maxID    int64 // highest memory ID added
+}
+
+// hnswNode is a memory in the graph with its links on each layer it is on.
+type hnswNode struct {
+	id    int64
+	vec   []float32
+	links [][]int32 // links[layer]
+}
+
+// NewHNSW creates an empty index configured by opts.
+func NewHNSW(opts ...IndexOption) *HNSW {
+	h := &HNSW{
+		m:              DefaultM,
+		efConstruction: DefaultEfConstruction,
+		efSearch:       DefaultEfSearch,
+		ids:            make(map[int64]int32),
+		entry:          -1,
+		rng:            rand.New(rand.NewSource(1)),
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	h.levelMult = 1 / math.Log(float64(h.m))
+	return h
+}
+
+// Len returns the number of embeddings in the index.
+func (h *HNSW) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.nodes)
+}
+
+// Dim returns the dimension of the indexed embeddings, 0 while empty.
+func (h *HNSW) Dim() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.dim
+}
+
+// MaxID returns the highest memory ID added, 0 while empty.
+func (h *HNSW) MaxID() int64 {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.maxID
+}
+
+// Add indexes vec as memory id. Reports false, leaving the index unchanged,
+// when id is already indexed or vec is empty or of another dimension than
+// the indexed embeddings.
+func (h *HNSW) Add(id int64, vec []float64) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if len(vec) == 0 || (h.dim != 0 && len(vec) != h.dim) {
+		return false
+	}
+	if _, ok := h.ids[id]; ok {
+		return false
+	}
+	h.dim = len(vec)
+	h.insert(id, normalize(vec))
+	return true
+}
+
+// insert links a new node for id into the graph.
+func (h *HNSW) insert(id int64, q []float32) {
+	level := int(-math.Log(1-h.rng.Float64()) * h.levelMult)
+	n := int32(len(h.nodes))
+	h.nodes = append(h.nodes, hnswNode{id: id, vec: q, links: make([][]int32, level+1)})
+	h.ids[id] = n
+	h.maxID = max(h.maxID, id)
+	if h.entry < 0 {
+		h.entry, h.maxLevel = n, level
+		return
+	}
+
+	ep := h.entry
+	for l := h.maxLevel; l > level; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	for l := min(level, h.maxLevel); l >= 0; l-- {
+		candidates := h.searchLayer(q, ep, h.efConstruction, l)
+		neighbours := h.selectNeighbours(candidates, h.maxLinks(l))
+		h.nodes[n].links[l] = neighbours
+		for _, nb := range neighbours {
+			h.link(nb, n, l)
+		}
+		ep = candidates[0].node
+	}
+	if level > h.maxLevel {
+		h.entry, h.maxLevel = n, level
+	}
+}
+
+// maxLinks returns the links a node keeps on layer l.
+func (h *HNSW) maxLinks(l int) int {
+	if l == 0 {
+		return 2 * h.m
+	}
+	return h.m
+}
+
+// link adds a link from node to target on layer l, pruning node's links
+// back to the most diverse when it has too many.
+func (h *HNSW) link(node, target int32, l int) {
+	links := append(h.nodes[node].links[l], target)
+	if len(links) > h.maxLinks(l) {
+		q := h.nodes[node].vec
+		candidates := make([]hnswCandidate, len(links))
+		for i, nb := range links {
+			candidates[i] = hnswCandidate{node: nb, dist: distance(q, h.nodes[nb].vec)}
+		}
+		sort.Slice(candidates, func(i, j int) bool { return candidates[i].dist < candidates[j].dist })
+		links = h.selectNeighbours(candidates, h.maxLinks(l))
+	}
+	h.nodes[node].links[l] = links
+}
+
+// selectNeighbours picks up to m of candidates, sorted nearest first, with
+// the HNSW heuristic: a candidate is kept when it is nearer to the query than
+// to every candidate already kept, so links spread in every direction. The
+// rest are filled with the nearest of those passed over.
+func (h *HNSW) selectNeighbours(candidates []hnswCandidate, m int) []int32 {
+	selected := make([]int32, 0, m)
+	var skipped []int32
+	for _, c := range candidates {
+		if len(selected) == m {
+			break
+		}
+		diverse := true
+		for _, s := range selected {
+			if distance(h.nodes[c.node].vec, h.nodes[s].vec) < c.dist {
+				diverse = false
+				break
+			}
+		}
+		if diverse {
+			selected = append(selected, c.node)
+		} else {
+			skipped = append(skipped, c.node)
+		}
+	}
+	for _, s := range skipped {
+		if len(selected) == m {
+			break
+		}
+		selected = append(selected, s)
+	}
+	return selected
+}
+
+// greedy walks layer l from ep to the node nearest q it can reach.
+func (h *HNSW) greedy(q []float32, ep int32, l int) int32 {
+	best := distance(q, h.nodes[ep].vec)
+	for changed := true; changed; {
+		changed = false
+		for _, nb := range h.nodes[ep].links[l] {
+			if d := distance(q, h.nodes[nb].vec); d < best {
+				ep, best, changed = nb, d, true
+			}
+		}
+	}
+	return ep
+}
+
+// searchLayer returns the ef nodes nearest q found on layer l from ep,
+// nearest first.
+func (h *HNSW) searchLayer(q []float32, ep int32, ef, l int) []hnswCandidate {
+	visited := map[int32]struct{}{ep: {}}
+	start := hnswCandidate{node: ep, dist: distance(q, h.nodes[ep].vec)}
+	candidates := &nearestHeap{start}
+	results := &farthestHeap{start}
+	for candidates.Len() > 0 {
+		c := heap.Pop(candidates).(hnswCandidate)
+		if c.dist > (*results)[0].dist && results.Len() >= ef {
+			break
+		}
+		for _, nb := range h.nodes[c.node].links[l] {
+			if _, ok := visited[nb]; ok {
+				continue
+			}
+			visited[nb] = struct{}{}
+			d := distance(q, h.nodes[nb].vec)
+			if results.Len() < ef || d < (*results)[0].dist {
+				heap.Push(candidates, hnswCandidate{node: nb, dist: d})
+				heap.Push(results, hnswCandidate{node: nb, dist: d})
+				if results.Len() > ef {
+					heap.Pop(results)
+				}
+			}
+		}
+	}
+	out := make([]hnswCandidate, results.Len())
+	for i := len(out) - 1; i >= 0; i-- {
+		out[i] = heap.Pop(results).(hnswCandidate)
+	}
+	return out
+}
+
+// Neighbour is a memory found by HNSW.Search with its cosine similarity to
+// the query.
+type Neighbour struct {
+	ID    int64
+	Score float64
+}
+
+// Search returns up to k memories whose embeddings are most similar to vec,
+// most similar first, considering max(k, efSearch) candidates. Returns nil
+// when the index is empty or vec has another dimension than the indexed
+// embeddings.
+func (h *HNSW) Search(vec []float64, k int) []Neighbour {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.entry < 0 || len(vec) != h.dim || k <= 0 {
+		return nil
+	}
+	q := normalize(vec)
+	ep := h.entry
+	for l := h.maxLevel; l > 0; l-- {
+		ep = h.greedy(q, ep, l)
+	}
+	found := h.searchLayer(q, ep, max(k, h.efSearch), 0)
+	if len(found) > k {
+		found = found[:k]
+	}
+	out := make([]Neighbour, len(found))
+	for i, c := range found {
+		out[i] = Neighbour{ID: h.nodes[c.node].id, Score: 1 - float64(c.dist)}
+	}
+	return out
+}
+
+// WriteTo writes the index in a binary format ReadHNSW reads back.
+func (h *HNSW) WriteTo(w io.Writer) (int64, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	put := func(v any) { _ = binary.Write(cw, binary.LittleEndian, v) }
+	put(hnswMagic)
+	put([]uint32{uint32(h.m), uint32(h.efConstruction), uint32(h.efSearch), uint32(h.dim), uint32(len(h.nodes))})
+	put([]int64{int64(h.entry), int64(h.maxLevel)})
+	for _, n := range h.nodes {
+		put(n.id)
+		put(uint32(len(n.links)))
+		put(n.vec)
+		for _, links := range n.links {
+			put(uint32(len(links)))
+			put(links)
+		}
+	}
+	if cw.err != nil {
+		return cw.n, fmt.Errorf("vectorstore: write index: %w", cw.err)
+	}
+	if err := cw.w.(*bufio.Writer).Flush(); err != nil {
+		return cw.n, fmt.Errorf("vectorstore: write index: %w", err)
+	}
+	return cw.n, nil
+}
+
+// ReadHNSW reads an index written by WriteTo. Returns ErrIndexFormat if the
+// data is not one.
+func ReadHNSW(r io.Reader) (*HNSW, error) {
+	br := bufio.NewReader(r)
+	var err error
+	get := func(v any) {
+		if err == nil {
+			err = binary.Read(br, binary.LittleEndian, v)
+		}
+	}
+	var magic [8]byte
+	get(&magic)
+	if err != nil || magic != hnswMagic {
+		return nil, ErrIndexFormat
+	}
+	var header [5]uint32
+	var entry [2]int64
+	get(&header)
+	get(&entry)
+	if err != nil {
+		return nil, ErrIndexFormat
+	}
+	h := NewHNSW(WithM(int(header[0])), WithEfConstruction(int(header[1])), WithEfSearch(int(header[2])))
+	h.dim = int(header[3])
+	count := int(header[4])
+	if h.dim > maxIndexDim || count > math.MaxInt32 || entry[1] < 0 || entry[1] > maxIndexLevel ||
+		entry[0] >= int64(count) || (count > 0) != (entry[0] >= 0) || (count > 0) != (h.dim > 0) {
+		return nil, ErrIndexFormat
+	}
+	h.entry, h.maxLevel = int32(entry[0]), int(entry[1])
+	h.nodes = make([]hnswNode, 0, min(count, 1<<20))
+	for i := 0; i < count && err == nil; i++ {
+		var n hnswNode
+		var levels uint32
+		get(&n.id)
+		get(&levels)
+		if err != nil || levels == 0 || int(levels) > h.maxLevel+1 {
+			return nil, ErrIndexFormat
+		}
+		n.vec = make([]float32, h.dim)
+		get(n.vec)
+		n.links = make([][]int32, levels)
+		for l := range n.links {
+			var size uint32
+			get(&size)
+			if err != nil || int(size) > h.maxLinks(l) {
+				return nil, ErrIndexFormat
+			}
+			n.links[l] = make([]int32, size)
+			get(n.links[l])
+			for _, nb := range n.links[l] {
+				if nb < 0 || int(nb) >= count {
+					return nil, ErrIndexFormat
+				}
+			}
+		}
+		if _, dup := h.ids[n.id]; dup {
+			return nil, ErrIndexFormat
+		}
+		h.ids[n.id] = int32(i)
+		h.maxID = max(h.maxID, n.id)
+		h.nodes = append(h.nodes, n)
+	}
+	if err != nil {
+		return nil, ErrIndexFormat
+	}
+	// Searches start on the entry node's top layer, maxLevel.
+	if count > 0 && len(h.nodes[h.entry].links) != h.maxLevel+1 {
+		return nil, ErrIndexFormat
+	}
+	// Links are only checked to be in range while reading; those on layers
+	// their target is not on would make a search index out of range.
+	for _, n := range h.nodes {
+		for l, links := range n.links {
+			for _, nb := range links {
+				if len(h.nodes[nb].links) <= l {
+					return nil, ErrIndexFormat
+				}
+			}
+		}
+	}
+	return h, nil
+}
+
+// countingWriter counts the bytes written to w and keeps the first error.
+type countingWriter struct {
+	w   io.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
+
+// normalize returns vec scaled to unit length as float32, so the cosine
+// similarity of two normalised vectors is their dot product. A zero vector
+// stays zero.
+func normalize(vec []float64) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	norm = math.Sqrt(norm)
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out
+}
+
+// distance is the cosine distance of normalised vectors a and b.
+func distance(a, b []float32) float32 {
+	var dot float32
+	for i := range a {
+		dot += a[i] * b[i]
+	}
+	return 1 - dot
+}
+
+// hnswCandidate is a node with its distance to the query.
+type hnswCandidate struct {
+	node int32
+	dist float32
+}
+
+// nearestHeap is a min-heap of candidates by distance.
+type nearestHeap []hnswCandidate
+
+func (h nearestHeap) Len() int           { return len(h) }
+func (h nearestHeap) Less(i, j int) bool { return h[i].dist < h[j].dist }
+func (h nearestHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *nearestHeap) Push(x any)        { *h = append(*h, x.(hnswCandidate)) }
+func (h *nearestHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// farthestHeap is a max-heap of candidates by distance.
+type farthestHeap []hnswCandidate
+
+func (h farthestHeap) Len() int           { return len(h) }
+func (h farthestHeap) Less(i, j int) bool { return h[i].dist > h[j].dist }
+func (h farthestHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *farthestHeap) Push(x any)        { *h = append(*h, x.(hnswCandidate)) }
+func (h *farthestHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
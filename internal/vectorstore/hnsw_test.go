@@ -0,0 +1,211 @@
+package vectorstore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// randomVectors returns n random vectors of dim dimensions, clustered
+// around a few centres as real embeddings are.
+func randomVectors(rng *rand.Rand, n, dim int) [][]float64 {
+	centres := make([][]float64, 16)
+	for i := range centres {
+		centres[i] = make([]float64, dim)
+		for j := range centres[i] {
+			centres[i][j] = rng.NormFloat64()
+		}
+	}
+	vecs := make([][]float64, n)
+	for i := range vecs {
+		c := centres[rng.Intn(len(centres))]
+		vecs[i] = make([]float64, dim)
+		for j := range vecs[i] {
+			vecs[i][j] = c[j] + 0.5*rng.NormFloat64()
+		}
+	}
+	return vecs
+}
+
+// bruteForce returns the IDs (index + 1) of the k vectors most similar to q.
+func bruteForce(vecs [][]float64, q []float64, k int) []int64 {
+	ids := make([]int64, len(vecs))
+	scores := make([]float64, len(vecs))
+	for i, v := range vecs {
+		ids[i], scores[i] = int64(i+1), CosineSimilarity(q, v)
+	}
+	sort.Sort(byScore{ids, scores})
+	return ids[:k]
+}
+
+type byScore struct {
+	ids    []int64
+	scores []float64
+}
+
+func (b byScore) Len() int           { return len(b.ids) }
+func (b byScore) Less(i, j int) bool { return b.scores[i] > b.scores[j] }
+func (b byScore) Swap(i, j int) {
+	b.ids[i], b.ids[j] = b.ids[j], b.ids[i]
+	b.scores[i], b.scores[j] = b.scores[j], b.scores[i]
+}
+
+// buildHNSW indexes vecs with IDs index + 1.
+func buildHNSW(vecs [][]float64, opts ...IndexOption) *HNSW {
+	h := NewHNSW(opts...)
+	for i, v := range vecs {
+		h.Add(int64(i+1), v)
+	}
+	return h
+}
+
+// recall returns the share of the exact top k found by h, over queries.
+func recall(h *HNSW, vecs, queries [][]float64, k int) float64 {
+	found := 0
+	for _, q := range queries {
+		want := map[int64]bool{}
+		for _, id := range bruteForce(vecs, q, k) {
+			want[id] = true
+		}
+		for _, n := range h.Search(q, k) {
+			if want[n.ID] {
+				found++
+			}
+		}
+	}
+	return float64(found) / float64(k*len(queries))
+}
+
+func TestHNSW_Search_ShouldMatchBruteForceRecall(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	vecs := randomVectors(rng, 5000, 32)
+	queries := randomVectors(rng, 50, 32)
+	h := buildHNSW(vecs)
+
+	if got := recall(h, vecs, queries, 10); got < 0.9 {
+		t.Errorf("recall@10 = %.3f, want >= 0.9", got)
+	}
+	// A wider search trades speed for recall.
+	WithEfSearch(256)(h)
+	if got := recall(h, vecs, queries, 10); got < 0.98 {
+		t.Errorf("recall@10 with efSearch 256 = %.3f, want >= 0.98", got)
+	}
+}
+
+func TestHNSW_Search_ShouldReturnMostSimilarFirstWithCosineScores(t *testing.T) {
+	h := NewHNSW()
+	h.Add(1, []float64{1, 0, 0})
+	h.Add(2, []float64{0.9, 0.1, 0})
+	h.Add(3, []float64{0, 0, 1})
+
+	got := h.Search([]float64{2, 0, 0}, 2)
+	if len(got) != 2 || got[0].ID != 1 || got[1].ID != 2 {
+		t.Fatalf("Search = %+v, want IDs 1, 2", got)
+	}
+	if got[0].Score < 0.999 || got[0].Score > 1.001 {
+		t.Errorf("score = %v, want 1", got[0].Score)
+	}
+}
+
+func TestHNSW_Add_WhenDuplicateOrOtherDimension_ShouldRefuse(t *testing.T) {
+	h := NewHNSW()
+	if !h.Add(1, []float64{1, 0}) {
+		t.Fatal("first Add refused")
+	}
+	if h.Add(1, []float64{0, 1}) {
+		t.Error("duplicate ID added")
+	}
+	if h.Add(2, []float64{1, 0, 0}) {
+		t.Error("other dimension added")
+	}
+	if h.Add(3, nil) {
+		t.Error("empty vector added")
+	}
+	if h.Len() != 1 || h.Dim() != 2 || h.MaxID() != 1 {
+		t.Errorf("len %d, dim %d, maxID %d", h.Len(), h.Dim(), h.MaxID())
+	}
+	if got := h.Search([]float64{1, 0, 0}, 1); got != nil {
+		t.Errorf("Search of other dimension = %v, want nil", got)
+	}
+}
+
+func TestHNSW_Search_WhenEmpty_ShouldReturnNil(t *testing.T) {
+	if got := NewHNSW().Search([]float64{1}, 3); got != nil {
+		t.Errorf("Search = %v, want nil", got)
+	}
+}
+
+func TestHNSW_WriteTo_ShouldRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	vecs := randomVectors(rng, 500, 8)
+	h := buildHNSW(vecs, WithM(8), WithEfSearch(32))
+
+	var buf bytes.Buffer
+	n, err := h.WriteTo(&buf)
+	if err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("WriteTo = %d bytes, wrote %d", n, buf.Len())
+	}
+	got, err := ReadHNSW(&buf)
+	if err != nil {
+		t.Fatalf("ReadHNSW: %v", err)
+	}
+	if got.Len() != h.Len() || got.Dim() != 8 || got.MaxID() != 500 || got.m != 8 || got.efSearch != 32 {
+		t.Errorf("read len %d, dim %d, maxID %d, m %d, efSearch %d", got.Len(), got.Dim(), got.MaxID(), got.m, got.efSearch)
+	}
+	q := randomVectors(rng, 1, 8)[0]
+	want, have := h.Search(q, 5), got.Search(q, 5)
+	for i := range want {
+		if want[i].ID != have[i].ID {
+			t.Fatalf("read index searches differently: %v, want %v", have, want)
+		}
+	}
+	// A read index keeps growing.
+	if !got.Add(501, q) || got.Search(q, 1)[0].ID != 501 {
+		t.Error("read index did not take a new vector")
+	}
+}
+
+func TestReadHNSW_WhenMalformed_ShouldReturnErrIndexFormat(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := buildHNSW(randomVectors(rand.New(rand.NewSource(1)), 50, 4)).WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	data := buf.Bytes()
+	// The header's maxLevel follows the magic, five uint32s and the entry node.
+	entryBelowTop := bytes.Clone(data)
+	binary.LittleEndian.PutUint64(entryBelowTop[36:], binary.LittleEndian.Uint64(data[36:])+1)
+	for name, in := range map[string][]byte{
+		"empty":           nil,
+		"bad magic":       append([]byte("NOTHNSW!"), data[8:]...),
+		"truncated":       data[:len(data)/2],
+		"entry below top": entryBelowTop,
+	} {
+		if _, err := ReadHNSW(bytes.NewReader(in)); err != ErrIndexFormat {
+			t.Errorf("%s: err = %v, want ErrIndexFormat", name, err)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	rng := rand.New(rand.NewSource(11))
+	vecs := randomVectors(rng, 20000, 64)
+	queries := randomVectors(rng, 100, 64)
+	h := buildHNSW(vecs)
+	b.Logf("recall@10 = %.3f", recall(h, vecs, queries[:20], 10))
+
+	b.Run("BruteForce", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			bruteForce(vecs, queries[i%len(queries)], 10)
+		}
+	})
+	b.Run("HNSW", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			h.Search(queries[i%len(queries)], 10)
+		}
+	})
+}
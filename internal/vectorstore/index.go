@@ -0,0 +1,160 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// ErrNoIndex is returned by Rebuild when the store was created without
+// WithIndex.
+var ErrNoIndex = errors.New("vectorstore: store has no index")
+
+// IndexLen returns the number of memories in the index, 0 without one.
+func (s *SQLiteVectorStore) IndexLen() int {
+	if idx := s.index.Load(); idx != nil {
+		return idx.Len()
+	}
+	return 0
+}
+
+// Rebuild builds the index afresh from every stored memory, replacing the
+// one in use once it is complete, and saves it. Returns ErrNoIndex for a
+// store without an index.
+func (s *SQLiteVectorStore) Rebuild(ctx context.Context) error {
+	if !s.indexed {
+		return ErrNoIndex
+	}
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	idx := NewHNSW(s.indexOpts...)
+	if err := s.indexRows(ctx, idx, 0); err != nil {
+		return err
+	}
+	s.index.Store(idx)
+	// Memories stored while the index was built went to the old one.
+	if err := s.indexRows(ctx, idx, idx.MaxID()); err != nil {
+		return err
+	}
+	s.dirty.Store(true)
+	return s.saveIndex()
+}
+
+// SaveIndex writes the index to its file if it changed since it was last
+// saved. It is a no-op for a store without an index file.
+func (s *SQLiteVectorStore) SaveIndex() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	return s.saveIndex()
+}
+
+// Close saves the index, as SaveIndex. It does not close the database.
+func (s *SQLiteVectorStore) Close() error {
+	return s.SaveIndex()
+}
+
+// saveIndex writes the index to a temporary file renamed over the index
+// file, so a crash never leaves a partial index behind. Callers hold saveMu.
+func (s *SQLiteVectorStore) saveIndex() error {
+	idx := s.index.Load()
+	if idx == nil || s.indexPath == "" || !s.dirty.Swap(false) {
+		return nil
+	}
+	err := writeIndexFile(s.indexPath, idx)
+	if err != nil {
+		s.dirty.Store(true)
+	}
+	return err
+}
+
+// writeIndexFile writes idx to path through a temporary file.
+func writeIndexFile(path string, idx *HNSW) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("vectorstore: create index dir: %w", err)
+	}
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return fmt.Errorf("vectorstore: save index: %w", err)
+	}
+	if _, err := idx.WriteTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("vectorstore: save index: %w", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// loadIndex reads the index file and adds the memories stored after it was
+// saved. It builds the index from every memory instead when there is no
+// file, or the file cannot be read, was built with other links per node, or
+// holds memories the database does not (a replaced database).
+func (s *SQLiteVectorStore) loadIndex(ctx context.Context) error {
+	var maxStored int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(id), 0) FROM memories").Scan(&maxStored); err != nil {
+		return err
+	}
+	want := NewHNSW(s.indexOpts...)
+	idx := s.readIndexFile()
+	if idx == nil || idx.m != want.m || idx.MaxID() > maxStored {
+		idx = want
+	} else {
+		// Search settings come from the options, not the file.
+		for _, opt := range s.indexOpts {
+			opt(idx)
+		}
+	}
+	before := idx.Len()
+	if err := s.indexRows(ctx, idx, idx.MaxID()); err != nil {
+		return err
+	}
+	s.index.Store(idx)
+	s.dirty.Store(idx == want || idx.Len() != before)
+	return nil
+}
+
+// readIndexFile returns the index saved at the index path, or nil when
+// there is none or it cannot be read.
+func (s *SQLiteVectorStore) readIndexFile() *HNSW {
+	if s.indexPath == "" {
+		return nil
+	}
+	f, err := os.Open(s.indexPath)
+	if err != nil {
+		return nil
+	}
+	defer f.Close()
+	idx, err := ReadHNSW(f)
+	if err != nil {
+		return nil
+	}
+	return idx
+}
+
+// indexRows adds the memories with IDs above after to idx, in ID order.
+func (s *SQLiteVectorStore) indexRows(ctx context.Context, idx *HNSW, after int64) error {
+	rows, err := s.db.QueryContext(ctx, "SELECT id, embedding FROM memories WHERE id > ? ORDER BY id", after)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var blob []byte
+		if err := rows.Scan(&id, &blob); err != nil {
+			return err
+		}
+		idx.Add(id, DecodeEmbedding(blob))
+	}
+	rowsErr := rows.Err()
+	if s.rowsErr != nil {
+		rowsErr = s.rowsErr()
+	}
+	return rowsErr
+}
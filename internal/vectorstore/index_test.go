@@ -0,0 +1,243 @@
+package vectorstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+// openFileDB opens a SQLite database in a file under dir.
+func openFileDB(t *testing.T, dir string) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite", filepath.Join(dir, "memories.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// storeVectors stores vecs as memories "m<i>".
+func storeVectors(t *testing.T, s *SQLiteVectorStore, vecs [][]float64) {
+	t.Helper()
+	for i, v := range vecs {
+		if err := s.Store(context.Background(), fmt.Sprintf("m%d", i), v); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+}
+
+func TestSQLiteVectorStore_WithIndex_ShouldSearchThroughIndex(t *testing.T) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(5))
+	vecs := randomVectors(rng, 300, 8)
+	s, err := NewSQLiteVectorStore(openTestDB(t), WithIndex(""))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	storeVectors(t, s, vecs)
+	if s.IndexLen() != 300 {
+		t.Fatalf("IndexLen = %d, want 300", s.IndexLen())
+	}
+
+	q := vecs[42]
+	got, err := s.Search(ctx, q, 3)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(got) != 3 || got[0].Content != "m42" || got[0].Score < 0.9999 {
+		t.Errorf("Search = %+v, want m42 first with its exact score", got)
+	}
+	hybrid, err := s.HybridSearch(ctx, "m42", q, 3)
+	if err != nil || len(hybrid) == 0 || hybrid[0].Content != "m42" {
+		t.Errorf("HybridSearch = %+v, %v", hybrid, err)
+	}
+}
+
+func TestSQLiteVectorStore_WithIndex_WhenIndexFindsTooFew_ShouldFallBackToExactSearch(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewSQLiteVectorStore(openTestDB(t), WithIndex(""))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	// The second embedding has another dimension and is not indexed.
+	if err := s.Store(ctx, "indexed", []float64{1, 0}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+	if err := s.Store(ctx, "not indexed", []float64{1, 0, 0}); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	got, err := s.Search(ctx, []float64{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	if len(got) != 2 || got[0].Content != "not indexed" {
+		t.Errorf("Search = %+v, want both memories, exact match first", got)
+	}
+}
+
+func TestSQLiteVectorStore_WithIndex_ShouldPersistAndCatchUp(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memories.db.hnsw")
+	db := openFileDB(t, dir)
+	vecs := randomVectors(rand.New(rand.NewSource(9)), 60, 4)
+
+	s, err := NewSQLiteVectorStore(db, WithIndex(path))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	storeVectors(t, s, vecs[:50])
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+
+	// Memories stored without the index are added when it is next loaded.
+	plain, err := NewSQLiteVectorStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	storeVectors(t, plain, vecs[50:])
+
+	reopened, err := NewSQLiteVectorStore(db, WithIndex(path))
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	if reopened.IndexLen() != 60 {
+		t.Errorf("IndexLen = %d, want 60", reopened.IndexLen())
+	}
+	got, err := reopened.Search(ctx, vecs[55], 1)
+	if err != nil || len(got) != 1 || got[0].ID != 56 {
+		t.Errorf("Search = %+v, %v; want memory 56", got, err)
+	}
+}
+
+func TestSQLiteVectorStore_WithIndex_WhenIndexFileDoesNotMatch_ShouldRebuild(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memories.db.hnsw")
+	for name, write := range map[string]func() error{
+		"corrupt": func() error { return os.WriteFile(path, []byte("garbage"), 0o644) },
+		"from another database": func() error {
+			idx := NewHNSW()
+			idx.Add(1000, []float64{1, 0})
+			return writeIndexFile(path, idx)
+		},
+	} {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			t.Fatalf("open sqlite: %v", err)
+		}
+		defer db.Close()
+		plain, err := NewSQLiteVectorStore(db)
+		if err != nil {
+			t.Fatalf("NewSQLiteVectorStore: %v", err)
+		}
+		storeVectors(t, plain, [][]float64{{1, 0}, {0, 1}})
+		if err := write(); err != nil {
+			t.Fatalf("%s: write index: %v", name, err)
+		}
+
+		s, err := NewSQLiteVectorStore(db, WithIndex(path))
+		if err != nil {
+			t.Fatalf("%s: NewSQLiteVectorStore: %v", name, err)
+		}
+		if s.IndexLen() != 2 || s.index.Load().MaxID() != 2 {
+			t.Errorf("%s: IndexLen = %d, want the 2 stored memories", name, s.IndexLen())
+		}
+	}
+}
+
+func TestSQLiteVectorStore_Rebuild_ShouldIndexEveryMemoryAndSave(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "memories.db.hnsw")
+	db := openFileDB(t, dir)
+	plain, err := NewSQLiteVectorStore(db)
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	if err := plain.Rebuild(ctx); err != ErrNoIndex {
+		t.Errorf("Rebuild without index: err = %v, want ErrNoIndex", err)
+	}
+	storeVectors(t, plain, randomVectors(rand.New(rand.NewSource(2)), 20, 4))
+
+	s, err := NewSQLiteVectorStore(db, WithIndex(path, WithM(4)))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	if err := s.Rebuild(ctx); err != nil {
+		t.Fatalf("Rebuild: %v", err)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("index not saved: %v", err)
+	}
+	defer f.Close()
+	saved, err := ReadHNSW(f)
+	if err != nil || saved.Len() != 20 || saved.m != 4 {
+		t.Errorf("saved index: %v, %v", saved, err)
+	}
+}
+
+func TestIndexPath_ShouldPreferConfigThenDatabaseFile(t *testing.T) {
+	cfg := domain.RetrievalConfig{Index: &domain.VectorIndexConfig{Path: "/idx/memories.hnsw"}}
+	if got := IndexPath(cfg, "file:/mem/memories.db"); got != "/idx/memories.hnsw" {
+		t.Errorf("IndexPath = %q", got)
+	}
+	cfg.Index.Path = ""
+	for url, want := range map[string]string{
+		"file:/mem/memories.db?_pragma=busy_timeout(5000)": "/mem/memories.db.hnsw",
+		"file::memory:":       "",
+		"libsql://x.turso.io": "",
+	} {
+		if got := IndexPath(cfg, url); got != want {
+			t.Errorf("IndexPath(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
+
+func BenchmarkSQLiteVectorStore_Search(b *testing.B) {
+	ctx := context.Background()
+	rng := rand.New(rand.NewSource(13))
+	vecs := randomVectors(rng, 10000, 64)
+	queries := randomVectors(rng, 100, 64)
+	for _, bc := range []struct {
+		name string
+		opts []StoreOption
+	}{
+		{"Exact", nil},
+		{"Index", []StoreOption{WithIndex("")}},
+	} {
+		db, err := sql.Open("sqlite", ":memory:")
+		if err != nil {
+			b.Fatalf("open sqlite: %v", err)
+		}
+		s, err := NewSQLiteVectorStore(db, bc.opts...)
+		if err != nil {
+			b.Fatalf("NewSQLiteVectorStore: %v", err)
+		}
+		for i, v := range vecs {
+			if err := s.Store(ctx, fmt.Sprintf("m%d", i), v); err != nil {
+				b.Fatalf("Store: %v", err)
+			}
+		}
+		b.Run(bc.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := s.Search(ctx, queries[i%len(queries)], 10); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		db.Close()
+	}
+}
@@ -0,0 +1,107 @@
+package vectorstore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"ironclaw/internal/db"
+	"ironclaw/internal/domain"
+	"ironclaw/internal/embedding"
+)
+
+// ErrNoDatabase is returned by Open when neither retrieval.dbUrl nor a
+// memory directory is configured.
+var ErrNoDatabase = errors.New("vectorstore: no database configured (set retrieval.dbUrl or agents.paths.memory)")
+
+// DefaultEmbeddingModel is the Ollama model memories are embedded with when
+// none is configured.
+const DefaultEmbeddingModel = "nomic-embed-text"
+
+// newEmbedder builds the embedder of a Retriever; tests replace it.
+var newEmbedder = func(model string) domain.Embedder {
+	return embedding.NewOllamaEmbedder(model)
+}
+
+// DBURL returns cfg.DBURL, or a file: URL for memories.db in memoryDir when
+// it is unset. Returns "" when both are empty.
+func DBURL(cfg domain.RetrievalConfig, memoryDir string) string {
+	if cfg.DBURL != "" {
+		return cfg.DBURL
+	}
+	if memoryDir == "" {
+		return ""
+	}
+	return "file:" + filepath.Join(memoryDir, "memories.db")
+}
+
+// IndexPath returns the file the index of cfg is kept in: cfg.Index.Path,
+// or the database file of dbURL plus ".hnsw". Returns "" when the index is
+// kept in memory only, for a remote database without an index path.
+func IndexPath(cfg domain.RetrievalConfig, dbURL string) string {
+	if cfg.Index != nil && cfg.Index.Path != "" {
+		return cfg.Index.Path
+	}
+	path, ok := strings.CutPrefix(dbURL, "file:")
+	if !ok {
+		return ""
+	}
+	path, _, _ = strings.Cut(path, "?")
+	if path == "" || path == ":memory:" {
+		return ""
+	}
+	return path + ".hnsw"
+}
+
+// Open builds the Retriever described by cfg on the store at DBURL(cfg,
+// memoryDir) and returns it with a func that closes the store. It does not
+// check cfg.Enabled; callers that find retrieval disabled should not open it.
+func Open(cfg domain.RetrievalConfig, memoryDir string) (*Retriever, func() error, error) {
+	if cfg.MinScore < 0 || cfg.MinScore > 1 {
+		return nil, nil, fmt.Errorf("vectorstore: minScore %v is not between 0 and 1", cfg.MinScore)
+	}
+	store, closeFn, err := OpenStore(cfg, memoryDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	model := cfg.Model
+	if model == "" {
+		model = DefaultEmbeddingModel
+	}
+	return NewRetriever(store, newEmbedder(model), WithTopK(cfg.TopK), WithMinScore(cfg.MinScore)), closeFn, nil
+}
+
+// OpenStore opens the store at DBURL(cfg, memoryDir), with the index of
+// cfg.Index when set, and returns it with a func that saves the index and
+// closes the database.
+func OpenStore(cfg domain.RetrievalConfig, memoryDir string) (*SQLiteVectorStore, func() error, error) {
+	dbURL := DBURL(cfg, memoryDir)
+	if dbURL == "" {
+		return nil, nil, ErrNoDatabase
+	}
+	if path, ok := strings.CutPrefix(dbURL, "file:"); ok {
+		path, _, _ = strings.Cut(path, "?")
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, nil, fmt.Errorf("vectorstore: create store dir: %w", err)
+		}
+	}
+	conn, err := db.Connect(dbURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	var opts []StoreOption
+	if ic := cfg.Index; ic != nil {
+		opts = append(opts, WithIndex(IndexPath(cfg, dbURL), WithM(ic.M), WithEfConstruction(ic.EfConstruction), WithEfSearch(ic.EfSearch)))
+	}
+	store, err := NewSQLiteVectorStore(conn, opts...)
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	closeFn := func() error {
+		return errors.Join(store.Close(), conn.Close())
+	}
+	return store, closeFn, nil
+}
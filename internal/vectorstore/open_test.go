@@ -0,0 +1,49 @@
+package vectorstore
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+func TestDBURL_ShouldPreferConfigThenMemoryDir(t *testing.T) {
+	if got := DBURL(domain.RetrievalConfig{DBURL: "libsql://x"}, "/mem"); got != "libsql://x" {
+		t.Errorf("DBURL = %q", got)
+	}
+	if got := DBURL(domain.RetrievalConfig{}, "/mem"); got != "file:/mem/memories.db" {
+		t.Errorf("DBURL = %q", got)
+	}
+	if got := DBURL(domain.RetrievalConfig{}, ""); got != "" {
+		t.Errorf("DBURL = %q, want empty", got)
+	}
+}
+
+func TestOpen_ShouldApplyConfig(t *testing.T) {
+	var model string
+	orig := newEmbedder
+	newEmbedder = func(m string) domain.Embedder { model = m; return topicEmbedder{} }
+	t.Cleanup(func() { newEmbedder = orig })
+
+	r, closeFn, err := Open(domain.RetrievalConfig{TopK: 3, MinScore: 0.7}, filepath.Join(t.TempDir(), "memory"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer closeFn()
+	if r.topK != 3 || r.minScore != 0.7 || model != DefaultEmbeddingModel {
+		t.Errorf("retriever = topK %d, minScore %v, model %q", r.topK, r.minScore, model)
+	}
+	if err := r.Store(context.Background(), "coffee"); err != nil {
+		t.Errorf("Store: %v", err)
+	}
+}
+
+func TestOpen_WhenConfigInvalid_ShouldReturnError(t *testing.T) {
+	if _, _, err := Open(domain.RetrievalConfig{MinScore: 1.5}, t.TempDir()); err == nil {
+		t.Error("expected error for minScore 1.5")
+	}
+	if _, _, err := Open(domain.RetrievalConfig{}, ""); err != ErrNoDatabase {
+		t.Errorf("err = %v, want ErrNoDatabase", err)
+	}
+}
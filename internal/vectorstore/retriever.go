@@ -0,0 +1,110 @@
+package vectorstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"ironclaw/internal/domain"
+)
+
+// Retrieval defaults, used when no option is given.
+const (
+	DefaultTopK     = 5
+	DefaultMinScore = 0.5
+)
+
+// RetrieverOption configures a Retriever.
+type RetrieverOption func(*Retriever)
+
+// WithTopK sets how many memories Retrieve returns at most (default
+// DefaultTopK). Non-positive values keep the default.
+func WithTopK(k int) RetrieverOption {
+	return func(r *Retriever) {
+		if k > 0 {
+			r.topK = k
+		}
+	}
+}
+
+// WithMinScore sets the cosine similarity a memory needs to be retrieved
+// for its meaning (default DefaultMinScore). Memories matching the query's
+// keywords are retrieved regardless. Values outside (0, 1] keep the default.
+func WithMinScore(s float64) RetrieverOption {
+	return func(r *Retriever) {
+		if s > 0 && s <= 1 {
+			r.minScore = s
+		}
+	}
+}
+
+// Retriever implements domain.Retriever over a SQLiteVectorStore, embedding
+// queries and memories with an Embedder. Retrieval is a hybrid search, as
+// HybridSearch, whose semantic results must reach the minimum score.
+type Retriever struct {
+	store    *SQLiteVectorStore
+	embedder domain.Embedder
+	topK     int
+	minScore float64
+}
+
+// NewRetriever returns a Retriever searching store with embeddings from
+// embedder. Panics if store or embedder is nil.
+func NewRetriever(store *SQLiteVectorStore, embedder domain.Embedder, opts ...RetrieverOption) *Retriever {
+	if store == nil {
+		panic("vectorstore: store must not be nil")
+	}
+	if embedder == nil {
+		panic("vectorstore: embedder must not be nil")
+	}
+	r := &Retriever{store: store, embedder: embedder, topK: DefaultTopK, minScore: DefaultMinScore}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Retrieve implements domain.Retriever. Results are ranked by Reciprocal
+// Rank Fusion, so their Score is the fused score rather than a similarity.
+// An empty query retrieves nothing.
+func (r *Retriever) Retrieve(ctx context.Context, query string) ([]domain.SemanticMemory, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, nil
+	}
+	vec, err := r.embedder.Embed(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("vectorstore: embed query: %w", err)
+	}
+	semantic, err := r.store.Search(ctx, vec, r.topK)
+	if err != nil {
+		return nil, fmt.Errorf("vectorstore: semantic search: %w", err)
+	}
+	relevant := semantic[:0]
+	for _, m := range semantic {
+		if m.Score >= r.minScore {
+			relevant = append(relevant, m)
+		}
+	}
+	// As in HybridSearch, queries FTS5 cannot parse find no keyword matches.
+	keyword, err := r.store.KeywordSearch(ctx, query, r.topK)
+	if err != nil {
+		keyword = nil
+	}
+	return mergeAndRank(relevant, keyword, r.topK), nil
+}
+
+// Store implements domain.Retriever.
+func (r *Retriever) Store(ctx context.Context, content string) error {
+	vec, err := r.embedder.Embed(ctx, content)
+	if err != nil {
+		return fmt.Errorf("vectorstore: embed memory: %w", err)
+	}
+	if err := r.store.Store(ctx, content, vec); err != nil {
+		return fmt.Errorf("vectorstore: store memory: %w", err)
+	}
+	return nil
+}
+
+// Ensure Retriever implements domain.Retriever.
+var _ domain.Retriever = (*Retriever)(nil)
@@ -0,0 +1,141 @@
+package vectorstore
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"ironclaw/internal/domain"
+)
+
+// topicEmbedder embeds text as counts of a few topic words, so texts sharing
+// them are similar.
+type topicEmbedder struct{ err error }
+
+func (e topicEmbedder) Embed(ctx context.Context, text string) ([]float64, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	vec := make([]float64, 3)
+	for _, w := range strings.Fields(strings.ToLower(text)) {
+		switch strings.Trim(w, "?.!,") {
+		case "coffee":
+			vec[0]++
+		case "garden":
+			vec[1]++
+		case "music":
+			vec[2]++
+		}
+	}
+	return vec, nil
+}
+
+func newTestRetriever(t *testing.T, embedder domain.Embedder, opts ...RetrieverOption) *Retriever {
+	t.Helper()
+	store, err := NewSQLiteVectorStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	return NewRetriever(store, embedder, opts...)
+}
+
+func TestRetriever_Retrieve_ShouldReturnStoredMemoriesAboveMinScore(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, topicEmbedder{})
+	for _, m := range []string{"User drinks coffee black", "User keeps a vegetable garden", "User plays music on Sundays"} {
+		if err := r.Store(ctx, m); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	got, err := r.Retrieve(ctx, "How do I take my coffee?")
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(got) != 1 || got[0].Content != "User drinks coffee black" {
+		t.Errorf("Retrieve = %+v, want only the coffee memory", got)
+	}
+}
+
+func TestRetriever_Retrieve_WhenOnlyKeywordsMatch_ShouldReturnThem(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, topicEmbedder{})
+	if err := r.Store(ctx, "The dentist appointment is on Friday"); err != nil {
+		t.Fatalf("Store: %v", err)
+	}
+
+	got, err := r.Retrieve(ctx, "dentist")
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(got) != 1 || !strings.Contains(got[0].Content, "dentist") {
+		t.Errorf("Retrieve = %+v, want the keyword match", got)
+	}
+}
+
+func TestRetriever_Retrieve_ShouldReturnAtMostTopK(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, topicEmbedder{}, WithTopK(2))
+	for _, m := range []string{"coffee one", "coffee two", "coffee three"} {
+		if err := r.Store(ctx, m); err != nil {
+			t.Fatalf("Store: %v", err)
+		}
+	}
+
+	got, err := r.Retrieve(ctx, "coffee")
+	if err != nil {
+		t.Fatalf("Retrieve: %v", err)
+	}
+	if len(got) != 2 {
+		t.Errorf("len = %d, want 2", len(got))
+	}
+}
+
+func TestRetriever_Retrieve_WhenQueryEmpty_ShouldReturnNothing(t *testing.T) {
+	r := newTestRetriever(t, topicEmbedder{err: errors.New("must not embed")})
+	got, err := r.Retrieve(context.Background(), "  ")
+	if err != nil || got != nil {
+		t.Errorf("Retrieve = %v, %v; want nil, nil", got, err)
+	}
+}
+
+func TestRetriever_WhenEmbedderFails_ShouldReturnError(t *testing.T) {
+	ctx := context.Background()
+	r := newTestRetriever(t, topicEmbedder{err: errors.New("ollama down")})
+	if _, err := r.Retrieve(ctx, "coffee"); err == nil {
+		t.Error("Retrieve: expected error")
+	}
+	if err := r.Store(ctx, "coffee"); err == nil {
+		t.Error("Store: expected error")
+	}
+}
+
+func TestWithMinScore_WhenOutOfRange_ShouldKeepDefault(t *testing.T) {
+	for _, s := range []float64{0, -0.5, 1.5} {
+		r := newTestRetriever(t, topicEmbedder{}, WithMinScore(s))
+		if r.minScore != DefaultMinScore {
+			t.Errorf("WithMinScore(%v): minScore = %v, want %v", s, r.minScore, DefaultMinScore)
+		}
+	}
+}
+
+func TestNewRetriever_WhenArgsNil_ShouldPanic(t *testing.T) {
+	store, err := NewSQLiteVectorStore(openTestDB(t))
+	if err != nil {
+		t.Fatalf("NewSQLiteVectorStore: %v", err)
+	}
+	for name, fn := range map[string]func(){
+		"store":    func() { NewRetriever(nil, topicEmbedder{}) },
+		"embedder": func() { NewRetriever(store, nil) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("nil %s: expected panic", name)
+				}
+			}()
+			fn()
+		}()
+	}
+}
@@ -7,6 +7,9 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"ironclaw/internal/domain"
@@ -19,23 +22,55 @@ type rowsErrFunc func() error
 type lastInsertIDFunc func(sql.Result) (int64, error)
 
 // SQLiteVectorStore stores memories and their embeddings in SQLite.
-// Vector search is done via in-memory cosine similarity computation.
+// Vector search is done via in-memory cosine similarity computation, or
+// through an HNSW index when the store is created WithIndex.
 type SQLiteVectorStore struct {
 	db           *sql.DB
 	rowsErr      rowsErrFunc      // nil means use rows.Err(); for testing only
 	lastInsertID lastInsertIDFunc // nil means use res.LastInsertId(); for testing only
+
+	indexed   bool
+	indexPath string // "" keeps the index in memory only
+	indexOpts []IndexOption
+	index     atomic.Pointer[HNSW] // nil without an index
+	dirty     atomic.Bool          // index changed since it was saved
+	saveMu    sync.Mutex           // serialises saves and rebuilds
+}
+
+// StoreOption configures a SQLiteVectorStore.
+type StoreOption func(*SQLiteVectorStore)
+
+// WithIndex makes Search look memories up in an HNSW index built with opts
+// rather than comparing the query with every embedding. The index is kept
+// in the file at path, or in memory only when path is "", and updated as
+// memories are stored; see SaveIndex and Rebuild.
+func WithIndex(path string, opts ...IndexOption) StoreOption {
+	return func(s *SQLiteVectorStore) {
+		s.indexed, s.indexPath, s.indexOpts = true, path, opts
+	}
 }
 
 // NewSQLiteVectorStore creates a new vector store and initializes the schema.
-// Returns an error if the db is nil or if the migration fails.
-func NewSQLiteVectorStore(db *sql.DB) (*SQLiteVectorStore, error) {
+// With WithIndex, the index is read from its file and brought up to date with
+// the memories stored since it was saved, or built from every memory when the
+// file is missing or does not match the database.
+// Returns an error if the db is nil or if the migration or index build fails.
+func NewSQLiteVectorStore(db *sql.DB, opts ...StoreOption) (*SQLiteVectorStore, error) {
 	if db == nil {
 		return nil, fmt.Errorf("db must not be nil")
 	}
 	s := &SQLiteVectorStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
 	if err := s.migrate(); err != nil {
 		return nil, fmt.Errorf("vectorstore migrate: %w", err)
 	}
+	if s.indexed {
+		if err := s.loadIndex(context.Background()); err != nil {
+			return nil, fmt.Errorf("vectorstore index: %w", err)
+		}
+	}
 	return s, nil
 }
 
@@ -80,12 +115,20 @@ func (s *SQLiteVectorStore) Store(ctx context.Context, content string, embedding
 	if err != nil {
 		return fmt.Errorf("get last insert id: %w", err)
 	}
-	_, err = s.db.ExecContext(ctx, "INSERT INTO memories_fts(rowid, content) VALUES (?, ?)", id, content)
-	return err
+	if _, err = s.db.ExecContext(ctx, "INSERT INTO memories_fts(rowid, content) VALUES (?, ?)", id, content); err != nil {
+		return err
+	}
+	if idx := s.index.Load(); idx != nil && idx.Add(id, embedding) {
+		s.dirty.Store(true)
+	}
+	return nil
 }
 
 // Search finds the top K most similar memories to the query embedding.
-// Results are sorted by cosine similarity in descending order.
+// Results are sorted by cosine similarity in descending order. With an
+// index, the candidates it finds are scored exactly; when it finds fewer
+// than topK (a small store, or embeddings of other dimensions) every memory
+// is compared instead.
 func (s *SQLiteVectorStore) Search(ctx context.Context, embedding []float64, topK int) ([]domain.SemanticMemory, error) {
 	if len(embedding) == 0 {
 		return nil, fmt.Errorf("embedding must not be empty")
@@ -93,7 +136,17 @@ func (s *SQLiteVectorStore) Search(ctx context.Context, embedding []float64, top
 	if topK <= 0 {
 		return nil, fmt.Errorf("topK must be positive")
 	}
+	if idx := s.index.Load(); idx != nil {
+		result, err := s.indexSearch(ctx, idx, embedding, topK)
+		if err != nil || len(result) == topK {
+			return result, err
+		}
+	}
+	return s.exactSearch(ctx, embedding, topK)
+}
 
+// exactSearch compares embedding with every stored memory.
+func (s *SQLiteVectorStore) exactSearch(ctx context.Context, embedding []float64, topK int) ([]domain.SemanticMemory, error) {
 	rows, err := s.db.QueryContext(ctx, "SELECT id, content, embedding, created_at FROM memories")
 	if err != nil {
 		return nil, err
@@ -151,6 +204,46 @@ func (s *SQLiteVectorStore) Search(ctx context.Context, embedding []float64, top
 	return result, nil
 }
 
+// indexSearch looks up the topK memories nearest embedding in idx and
+// scores them exactly. Memories in the index but no longer stored are left
+// out.
+func (s *SQLiteVectorStore) indexSearch(ctx context.Context, idx *HNSW, embedding []float64, topK int) ([]domain.SemanticMemory, error) {
+	hits := idx.Search(embedding, topK)
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	args := make([]any, len(hits))
+	for i, h := range hits {
+		args[i] = h.ID
+	}
+	query := "SELECT id, content, embedding, created_at FROM memories WHERE id IN (?" + strings.Repeat(", ?", len(hits)-1) + ")"
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make([]domain.SemanticMemory, 0, len(hits))
+	for rows.Next() {
+		var m domain.SemanticMemory
+		var blob []byte
+		if err := rows.Scan(&m.ID, &m.Content, &blob, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		m.Score = CosineSimilarity(embedding, DecodeEmbedding(blob))
+		result = append(result, m)
+	}
+	rowsErr := rows.Err()
+	if s.rowsErr != nil {
+		rowsErr = s.rowsErr()
+	}
+	if rowsErr != nil {
+		return nil, rowsErr
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Score > result[j].Score })
+	return result, nil
+}
+
 // HybridSearch runs both a vector (semantic) search and an FTS5 (keyword) search,
 // merges the results, deduplicates by memory ID, and re-ranks using Reciprocal Rank
 // Fusion (RRF). Results that appear in both searches get a score boost.
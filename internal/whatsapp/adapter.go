@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"sync"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
 )
 
 // WAClient abstracts WhatsApp client operations for testing.
@@ -21,12 +24,30 @@ type MessageRouter interface {
 	Route(ctx context.Context, channelID, prompt string) (string, error)
 }
 
-// IncomingMessage represents a received WhatsApp text message.
+// ContentRouter is a MessageRouter that also accepts a user message made of
+// content blocks, such as a photo and its caption (implemented by
+// router.Router).
+type ContentRouter interface {
+	MessageRouter
+	RouteContent(ctx context.Context, channelID string, blocks []domain.ContentBlock) (string, error)
+}
+
+// ImagePreparer checks and downscales downloaded image data for the model
+// (implemented by *tooling.RealImageProcessor).
+type ImagePreparer interface {
+	PrepareImage(data []byte) (domain.ImageBlock, error)
+}
+
+// IncomingMessage represents a received WhatsApp text or image message.
 type IncomingMessage struct {
 	SenderJID string // e.g., "1234567890@s.whatsapp.net"
 	ChatJID   string // Same as SenderJID for DMs, or "groupid@g.us" for groups
-	Text      string
+	Text      string // Message text, or the image caption
 	MessageID string
+
+	// DownloadImage fetches the message's image; nil for text messages. The
+	// adapter calls it from its worker, not from the client's event loop.
+	DownloadImage func(ctx context.Context) ([]byte, error)
 }
 
 // QREvent represents a QR code event during the WhatsApp login flow.
@@ -44,27 +65,62 @@ type Adapter struct {
 	client    WAClient
 	router    MessageRouter
 	qrHandler QRHandler
+	approvals *approval.Gate
+	images    ImagePreparer
 
 	mu     sync.Mutex
 	cancel context.CancelFunc
 }
 
+// Option configures an Adapter.
+type Option func(*Adapter)
+
+// WithApprovals asks for approval of tool calls held by g with a text message;
+// the user answers with a reply keyword ("approve", "deny" or "always",
+// optionally followed by the request ID).
+func WithApprovals(g *approval.Gate) Option {
+	return func(a *Adapter) { a.approvals = g }
+}
+
+// WithImages prepares images sent to the bot with images and attaches them,
+// with their caption, to the user message. It takes effect only when the
+// router implements ContentRouter; otherwise images without a caption are
+// ignored and captions are routed as text.
+func WithImages(images ImagePreparer) Option {
+	return func(a *Adapter) { a.images = images }
+}
+
+// messageQueueSize bounds how many messages wait while one is being answered.
+const messageQueueSize = 32
+
 // JIDToChannelID converts a WhatsApp JID string to an IronClaw ChannelID.
 func JIDToChannelID(jid string) string {
 	return "whatsapp-" + jid
 }
 
 // HandleMessage processes a single incoming WhatsApp message.
-// Ignores messages with empty text.
-// Routes the message text through the brain and sends the reply back to WhatsApp.
+// Ignores messages with neither text nor an image the adapter can attach
+// (see WithImages).
+// Routes the message through the brain and sends the reply back to WhatsApp.
 func (a *Adapter) HandleMessage(ctx context.Context, msg IncomingMessage) {
-	if msg.Text == "" {
+	cr, images := a.router.(ContentRouter)
+	images = images && a.images != nil && msg.DownloadImage != nil
+	if msg.Text == "" && !images {
 		return
 	}
 
 	channelID := JIDToChannelID(msg.ChatJID)
 
-	reply, err := a.router.Route(ctx, channelID, msg.Text)
+	if a.approvals != nil {
+		ctx = approval.NewContext(ctx, channelID, a.prompter(msg.ChatJID))
+	}
+	var reply string
+	var err error
+	if images {
+		reply, err = a.routeImage(ctx, cr, channelID, msg)
+	} else {
+		reply, err = a.router.Route(ctx, channelID, msg.Text)
+	}
 	if err != nil {
 		reply = "Error: " + err.Error()
 	}
@@ -72,8 +128,46 @@ func (a *Adapter) HandleMessage(ctx context.Context, msg IncomingMessage) {
 	_ = a.client.SendText(ctx, msg.ChatJID, reply)
 }
 
+// routeImage downloads and prepares msg's image and routes it with the
+// caption.
+func (a *Adapter) routeImage(ctx context.Context, cr ContentRouter, channelID string, msg IncomingMessage) (string, error) {
+	data, err := msg.DownloadImage(ctx)
+	if err != nil {
+		return "", err
+	}
+	img, err := a.images.PrepareImage(data)
+	if err != nil {
+		return "", fmt.Errorf("whatsapp: image: %w", err)
+	}
+	blocks := []domain.ContentBlock{img}
+	if msg.Text != "" {
+		blocks = append(blocks, domain.TextBlock{Text: msg.Text})
+	}
+	return cr.RouteContent(ctx, channelID, blocks)
+}
+
+// HandleApproval resolves msg if it is a reply keyword answering a pending
+// approval request of its chat, and reports whether it was consumed. It must
+// run outside HandleMessage, which blocks while a tool call awaits approval.
+func (a *Adapter) HandleApproval(msg IncomingMessage) bool {
+	if a.approvals == nil || msg.Text == "" {
+		return false
+	}
+	return a.approvals.ResolveReply(JIDToChannelID(msg.ChatJID), msg.Text, "whatsapp:"+msg.SenderJID)
+}
+
+// prompter sends approval requests to chatJID as text.
+func (a *Adapter) prompter(chatJID string) approval.Prompter {
+	return approval.PrompterFunc(func(ctx context.Context, req approval.Request) error {
+		if err := a.client.SendText(ctx, chatJID, req.Text()); err != nil {
+			return fmt.Errorf("whatsapp: send approval request to %s: %w", chatJID, err)
+		}
+		return nil
+	})
+}
+
 // NewAdapter creates a new WhatsApp adapter. Both client and router must be non-nil.
-func NewAdapter(client WAClient, router MessageRouter, qrHandler QRHandler) *Adapter {
+func NewAdapter(client WAClient, router MessageRouter, qrHandler QRHandler, opts ...Option) *Adapter {
 	if client == nil {
 		panic("whatsapp: client must not be nil")
 	}
@@ -83,14 +177,20 @@ func NewAdapter(client WAClient, router MessageRouter, qrHandler QRHandler) *Ada
 	if qrHandler == nil {
 		qrHandler = func(string) {} // no-op default
 	}
-	return &Adapter{
+	a := &Adapter{
 		client:    client,
 		router:    router,
 		qrHandler: qrHandler,
 	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
 }
 
 // Start connects to WhatsApp and begins processing incoming messages.
+// Messages are answered one at a time by a worker goroutine, so approval
+// replies (see HandleApproval) are seen while a reply waits on them.
 // If the client is not logged in, it performs the QR code login flow first.
 // Blocks until ctx is canceled. When ctx is done, Disconnect is called.
 func (a *Adapter) Start(ctx context.Context) error {
@@ -125,6 +225,20 @@ func (a *Adapter) Start(ctx context.Context) error {
 	}
 
 	// Listen for incoming messages.
+	work := make(chan IncomingMessage, messageQueueSize)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for msg := range work {
+			a.HandleMessage(ctx, msg)
+		}
+	}()
+	defer func() {
+		close(work)
+		wg.Wait()
+	}()
+
 	msgCh := a.client.MessageChannel()
 	for {
 		select {
@@ -132,7 +246,13 @@ func (a *Adapter) Start(ctx context.Context) error {
 			a.client.Disconnect()
 			return nil
 		case msg := <-msgCh:
-			a.HandleMessage(ctx, msg)
+			if a.HandleApproval(msg) {
+				continue
+			}
+			select {
+			case work <- msg:
+			case <-ctx.Done():
+			}
 		}
 	}
 }
@@ -2,10 +2,15 @@ package whatsapp
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
+
+	"ironclaw/internal/approval"
+	"ironclaw/internal/domain"
 )
 
 // =============================================================================
@@ -331,6 +336,98 @@ func TestHandleMessage_WhenRouterReturnsError_ShouldSendErrorMessage(t *testing.
 	}
 }
 
+// contentRouter is a mockRouter that also records RouteContent calls.
+type contentRouter struct {
+	mockRouter
+	blocks [][]domain.ContentBlock
+}
+
+func (m *contentRouter) RouteContent(_ context.Context, _ string, blocks []domain.ContentBlock) (string, error) {
+	m.blocks = append(m.blocks, blocks)
+	return m.response, m.err
+}
+
+// stubImages prepares any data beginning with "img" as a PNG.
+type stubImages struct{}
+
+func (stubImages) PrepareImage(data []byte) (domain.ImageBlock, error) {
+	if !strings.HasPrefix(string(data), "img") {
+		return domain.ImageBlock{}, errors.New("not an image")
+	}
+	return domain.NewImageBlock("image/png", data), nil
+}
+
+// imageData returns a DownloadImage func yielding data.
+func imageData(data string) func(context.Context) ([]byte, error) {
+	return func(context.Context) ([]byte, error) { return []byte(data), nil }
+}
+
+func TestHandleMessage_WhenImageMessage_ShouldRouteImageWithCaption(t *testing.T) {
+	client := newMockWAClient(true)
+	rtr := &contentRouter{mockRouter: mockRouter{response: "a cat"}}
+	adapter := NewAdapter(client, rtr, nil, WithImages(stubImages{}))
+
+	adapter.HandleMessage(context.Background(), IncomingMessage{ChatJID: "1@s.whatsapp.net", Text: "what is it?", DownloadImage: imageData("img")})
+
+	if len(rtr.blocks) != 1 || len(rtr.blocks[0]) != 2 {
+		t.Fatalf("RouteContent blocks = %+v", rtr.blocks)
+	}
+	if img, ok := rtr.blocks[0][0].(domain.ImageBlock); !ok || img.Source.MediaType != "image/png" {
+		t.Errorf("first block = %+v, want the image", rtr.blocks[0][0])
+	}
+	if tb, ok := rtr.blocks[0][1].(domain.TextBlock); !ok || tb.Text != "what is it?" {
+		t.Errorf("second block = %+v, want the caption", rtr.blocks[0][1])
+	}
+	if sent := client.getSentMessages(); len(sent) != 1 || sent[0].text != "a cat" {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
+func TestHandleMessage_WhenImagesNotEnabled_ShouldRouteCaptionAsText(t *testing.T) {
+	client := newMockWAClient(true)
+	rtr := &contentRouter{mockRouter: mockRouter{response: "ok"}}
+	adapter := NewAdapter(client, rtr, nil)
+
+	adapter.HandleMessage(context.Background(), IncomingMessage{ChatJID: "1@s.whatsapp.net", Text: "caption", DownloadImage: imageData("img")})
+
+	if calls := rtr.getCalls(); len(calls) != 1 || calls[0].prompt != "caption" || len(rtr.blocks) != 0 {
+		t.Errorf("Route calls = %+v, RouteContent calls = %d", calls, len(rtr.blocks))
+	}
+}
+
+func TestHandleMessage_WhenImageInvalid_ShouldReplyWithError(t *testing.T) {
+	client := newMockWAClient(true)
+	rtr := &contentRouter{mockRouter: mockRouter{response: "ok"}}
+	adapter := NewAdapter(client, rtr, nil, WithImages(stubImages{}))
+
+	adapter.HandleMessage(context.Background(), IncomingMessage{ChatJID: "1@s.whatsapp.net", DownloadImage: imageData("pdf")})
+
+	if len(rtr.blocks) != 0 {
+		t.Errorf("invalid image was routed")
+	}
+	if sent := client.getSentMessages(); len(sent) != 1 || !strings.HasPrefix(sent[0].text, "Error: whatsapp: image:") {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
+func TestHandleMessage_WhenImageDownloadFails_ShouldReplyWithError(t *testing.T) {
+	client := newMockWAClient(true)
+	rtr := &contentRouter{mockRouter: mockRouter{response: "ok"}}
+	adapter := NewAdapter(client, rtr, nil, WithImages(stubImages{}))
+	download := func(context.Context) ([]byte, error) {
+		return nil, errors.New("whatsapp: download image: media expired")
+	}
+
+	adapter.HandleMessage(context.Background(), IncomingMessage{ChatJID: "1@s.whatsapp.net", DownloadImage: download})
+
+	if len(rtr.blocks) != 0 {
+		t.Errorf("image was routed despite the failed download")
+	}
+	if sent := client.getSentMessages(); len(sent) != 1 || sent[0].text != "Error: whatsapp: download image: media expired" {
+		t.Errorf("sent = %+v", sent)
+	}
+}
+
 func TestHandleMessage_WhenSendFails_ShouldNotPanic(t *testing.T) {
 	client := newMockWAClient(true)
 	client.sendErr = errSendFailed
@@ -691,3 +788,50 @@ func TestStart_WhenQRChannelFails_ShouldReturnError(t *testing.T) {
 		t.Errorf("expected errQRFailed, got %v", err)
 	}
 }
+
+// gatedRouter asks gate to approve a shell call before replying, the way
+// brain.Run does for tool calls.
+type gatedRouter struct {
+	gate *approval.Gate
+}
+
+func (r *gatedRouter) Route(ctx context.Context, channelID, prompt string) (string, error) {
+	ch, p, _ := approval.FromContext(ctx)
+	if err := r.gate.Authorize(ctx, ch, "shell", json.RawMessage(`{"command":"df -h"}`), p); err != nil {
+		return "", err
+	}
+	return "disk is fine", nil
+}
+
+func TestStart_WhenToolNeedsApproval_ShouldPromptAndResumeOnKeywordReply(t *testing.T) {
+	client := newMockWAClient(true)
+	gate := approval.NewGate()
+	adapter := NewAdapter(client, &gatedRouter{gate: gate}, nil, WithApprovals(gate))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go adapter.Start(ctx)
+
+	const chat = "123@s.whatsapp.net"
+	client.msgCh <- IncomingMessage{SenderJID: chat, ChatJID: chat, Text: "check the disk"}
+	waitFor := func(n int) []sentMsg {
+		t.Helper()
+		deadline := time.After(2 * time.Second)
+		for len(client.getSentMessages()) < n {
+			select {
+			case <-deadline:
+				t.Fatalf("timed out waiting for %d messages", n)
+			case <-time.After(10 * time.Millisecond):
+			}
+		}
+		return client.getSentMessages()
+	}
+	prompt := waitFor(1)[0]
+	if !strings.Contains(prompt.text, `"command": "df -h"`) || !strings.Contains(prompt.text, "approve ") {
+		t.Fatalf("unexpected approval prompt:\n%s", prompt.text)
+	}
+
+	client.msgCh <- IncomingMessage{SenderJID: chat, ChatJID: chat, Text: "yes"}
+	if reply := waitFor(2)[1]; reply.text != "disk is fine" {
+		t.Errorf("want reply after approval, got %q", reply.text)
+	}
+}
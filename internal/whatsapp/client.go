@@ -3,6 +3,7 @@ package whatsapp
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/proto/waE2E"
@@ -21,8 +22,15 @@ type rawClient interface {
 	SendMessage(ctx context.Context, to types.JID, message *waE2E.Message, extra ...whatsmeow.SendRequestExtra) (whatsmeow.SendResponse, error)
 	GetQRChannel(ctx context.Context) (<-chan whatsmeow.QRChannelItem, error)
 	AddEventHandler(handler whatsmeow.EventHandler) uint32
+	Download(ctx context.Context, msg whatsmeow.DownloadableMessage) ([]byte, error)
 }
 
+// imageDownloadTimeout bounds how long an incoming image may take to download.
+const imageDownloadTimeout = 30 * time.Second
+
+// maxImageBytes bounds image downloads, as the Telegram adapter bounds photos.
+const maxImageBytes = 20 << 20
+
 // WhatsmeowClient wraps a whatsmeow.Client to implement the WAClient interface.
 // This is the production implementation used in cmd/whatsapp-bridge.
 type WhatsmeowClient struct {
@@ -93,20 +101,46 @@ func (w *WhatsmeowClient) GetQRChannel(ctx context.Context) (<-chan QREvent, err
 	return out, nil
 }
 
-// eventHandler processes whatsmeow events and forwards text messages to msgCh.
+// eventHandler processes whatsmeow events and forwards text and image
+// messages to msgCh. Images are not downloaded here, which would hold up
+// whatsmeow's event loop; the message carries a DownloadImage func instead.
 func (w *WhatsmeowClient) eventHandler(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Message:
-		text := extractText(v.Message)
-		if text == "" {
-			return
-		}
-		w.msgCh <- IncomingMessage{
+		msg := IncomingMessage{
 			SenderJID: v.Info.Sender.String(),
 			ChatJID:   v.Info.Chat.String(),
-			Text:      text,
+			Text:      extractText(v.Message),
 			MessageID: string(v.Info.ID),
 		}
+		if img := v.Message.GetImageMessage(); img != nil {
+			msg.Text = img.GetCaption()
+			msg.DownloadImage = w.imageDownloader(img)
+		}
+		if msg.Text == "" && msg.DownloadImage == nil {
+			return
+		}
+		w.msgCh <- msg
+	}
+}
+
+// imageDownloader returns a func downloading img. Images whose declared size
+// exceeds maxImageBytes are refused without being downloaded.
+func (w *WhatsmeowClient) imageDownloader(img *waE2E.ImageMessage) func(context.Context) ([]byte, error) {
+	return func(ctx context.Context) ([]byte, error) {
+		if img.GetFileLength() > maxImageBytes {
+			return nil, fmt.Errorf("whatsapp: image of %d bytes exceeds %d bytes", img.GetFileLength(), maxImageBytes)
+		}
+		ctx, cancel := context.WithTimeout(ctx, imageDownloadTimeout)
+		defer cancel()
+		data, err := w.client.Download(ctx, img)
+		if err != nil {
+			return nil, fmt.Errorf("whatsapp: download image: %w", err)
+		}
+		if len(data) > maxImageBytes {
+			return nil, fmt.Errorf("whatsapp: image exceeds %d bytes", maxImageBytes)
+		}
+		return data, nil
 	}
 }
 
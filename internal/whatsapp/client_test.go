@@ -3,6 +3,7 @@ package whatsapp
 import (
 	"context"
 	"errors"
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -37,6 +38,10 @@ type mockRawClient struct {
 	disconnected bool
 	sentMsgs     []rawSentMsg
 	handlers     []whatsmeow.EventHandler
+
+	downloadData  []byte
+	downloadErr   error
+	downloadCalls int
 }
 
 type rawSentMsg struct {
@@ -97,6 +102,13 @@ func (m *mockRawClient) AddEventHandler(handler whatsmeow.EventHandler) uint32 {
 	return uint32(len(m.handlers))
 }
 
+func (m *mockRawClient) Download(_ context.Context, _ whatsmeow.DownloadableMessage) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.downloadCalls++
+	return m.downloadData, m.downloadErr
+}
+
 func (m *mockRawClient) getSentMsgs() []rawSentMsg {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -233,6 +245,58 @@ func TestEventHandler_WhenEmptyText_ShouldNotSendToChannel(t *testing.T) {
 	}
 }
 
+func TestEventHandler_WhenImageMessage_ShouldDeferDownloadAndKeepCaption(t *testing.T) {
+	mock := newMockRawClient()
+	mock.downloadData = []byte("jpeg-bytes")
+	wc := &WhatsmeowClient{client: mock, msgCh: make(chan IncomingMessage, 10)}
+
+	wc.eventHandler(&events.Message{
+		Info:    types.MessageInfo{ID: "msg-img"},
+		Message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{Caption: proto.String("what is this?")}},
+	})
+
+	var msg IncomingMessage
+	select {
+	case msg = <-wc.msgCh:
+	default:
+		t.Fatal("expected message on channel")
+	}
+	if msg.Text != "what is this?" || msg.DownloadImage == nil || mock.downloadCalls != 0 {
+		t.Fatalf("message = %+v, downloads = %d; want the caption and a deferred download", msg, mock.downloadCalls)
+	}
+	if data, err := msg.DownloadImage(context.Background()); err != nil || string(data) != "jpeg-bytes" {
+		t.Errorf("DownloadImage = %q, %v", data, err)
+	}
+}
+
+func TestEventHandler_WhenImageTooLarge_ShouldRefuseToDownloadIt(t *testing.T) {
+	mock := newMockRawClient()
+	wc := &WhatsmeowClient{client: mock, msgCh: make(chan IncomingMessage, 10)}
+
+	wc.eventHandler(&events.Message{Message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{FileLength: proto.Uint64(maxImageBytes + 1)}}})
+
+	msg := <-wc.msgCh
+	if _, err := msg.DownloadImage(context.Background()); err == nil || !strings.Contains(err.Error(), "exceeds") {
+		t.Errorf("DownloadImage err = %v, want the size limit", err)
+	}
+	if mock.downloadCalls != 0 {
+		t.Errorf("downloads = %d, want none", mock.downloadCalls)
+	}
+}
+
+func TestEventHandler_WhenImageDownloadFails_ShouldReturnError(t *testing.T) {
+	mock := newMockRawClient()
+	mock.downloadErr = errors.New("media expired")
+	wc := &WhatsmeowClient{client: mock, msgCh: make(chan IncomingMessage, 10)}
+
+	wc.eventHandler(&events.Message{Message: &waE2E.Message{ImageMessage: &waE2E.ImageMessage{}}})
+
+	msg := <-wc.msgCh
+	if _, err := msg.DownloadImage(context.Background()); !errors.Is(err, mock.downloadErr) {
+		t.Errorf("DownloadImage err = %v, want the download error", err)
+	}
+}
+
 func TestEventHandler_WhenNonMessageEvent_ShouldIgnore(t *testing.T) {
 	wc := &WhatsmeowClient{
 		msgCh: make(chan IncomingMessage, 10),